@@ -20,12 +20,14 @@ func (t NetworkHabitatCollectionsDefsCollectionView) MarshalJSON() ([]byte, erro
 
 // NetworkHabitatCollectionsDefsRecordView represents a recordView object
 type NetworkHabitatCollectionsDefsRecordView struct {
-	LexiconTypeID string `json:"$type"`
-	Collection    string `json:"collection"`
-	Repo          string `json:"repo"`
-	Rkey          string `json:"rkey"`
-	Space         string `json:"space"`
-	Uri           string `json:"uri"`
+	LexiconTypeID string      `json:"$type"`
+	Collection    string      `json:"collection"`
+	Repo          string      `json:"repo"`
+	Rkey          string      `json:"rkey"`
+	Space         string      `json:"space"`
+	UpdatedAt     string      `json:"updatedAt,omitempty"`
+	Uri           string      `json:"uri"`
+	Value         interface{} `json:"value,omitempty"`
 }
 
 // MarshalJSON sets $type to "network.habitat.collections.defs#recordView" before encoding.
@@ -5,9 +5,12 @@ package habitat
 // NetworkHabitatCollectionsListRecordsParams represents the input parameters for network.habitat.collections.listRecords
 type NetworkHabitatCollectionsListRecordsParams struct {
 	Collection string `json:"collection"`
+	Cursor     string `json:"cursor,omitempty"`
+	Limit      int64  `json:"limit,omitempty"`
 }
 
 // NetworkHabitatCollectionsListRecordsOutput represents the output for network.habitat.collections.listRecords
 type NetworkHabitatCollectionsListRecordsOutput struct {
+	Cursor  string                                    `json:"cursor,omitempty"`
 	Records []NetworkHabitatCollectionsDefsRecordView `json:"records"`
 }
@@ -0,0 +1,20 @@
+package habitat
+
+// Code generated by lexgen. DO NOT EDIT.
+
+import "encoding/json"
+
+// NetworkHabitatGroupJoinRequest represents a network.habitat.group.joinRequest record
+type NetworkHabitatGroupJoinRequest struct {
+	LexiconTypeID string `json:"$type"`
+	CreatedAt     string `json:"createdAt"`
+	Message       string `json:"message,omitempty"`
+	Subject       string `json:"subject"`
+}
+
+// MarshalJSON sets $type to "network.habitat.group.joinRequest" before encoding.
+func (t NetworkHabitatGroupJoinRequest) MarshalJSON() ([]byte, error) {
+	t.LexiconTypeID = "network.habitat.group.joinRequest"
+	type alias NetworkHabitatGroupJoinRequest
+	return json.Marshal(alias(t))
+}
@@ -9,6 +9,7 @@ type NetworkHabitatGroupProfile struct {
 	LexiconTypeID string `json:"$type"`
 	CreatedAt     string `json:"createdAt,omitempty"`
 	Description   string `json:"description,omitempty"`
+	JoinPolicy    string `json:"joinPolicy,omitempty"`
 	Name          string `json:"name"`
 }
 
@@ -5,6 +5,7 @@ package habitat
 // NetworkHabitatGroupsCreateGroupInput represents the input for network.habitat.groups.createGroup
 type NetworkHabitatGroupsCreateGroupInput struct {
 	Description string `json:"description,omitempty"`
+	JoinPolicy  string `json:"joinPolicy,omitempty"`
 	Name        string `json:"name"`
 }
 
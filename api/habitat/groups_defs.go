@@ -26,6 +26,7 @@ type NetworkHabitatGroupsDefsGroupView struct {
 	Description     string                               `json:"description,omitempty"`
 	InheritedGroups []NetworkHabitatGroupsDefsGroupRef   `json:"inheritedGroups,omitempty"`
 	IsMember        bool                                 `json:"isMember"`
+	JoinPolicy      string                               `json:"joinPolicy,omitempty"`
 	MemberCount     int64                                `json:"memberCount,omitempty"`
 	Members         []NetworkHabitatGroupsDefsMemberView `json:"members,omitempty"`
 	Name            string                               `json:"name"`
@@ -39,6 +40,22 @@ func (t NetworkHabitatGroupsDefsGroupView) MarshalJSON() ([]byte, error) {
 	return json.Marshal(alias(t))
 }
 
+// NetworkHabitatGroupsDefsJoinRequestView represents a joinRequestView object
+type NetworkHabitatGroupsDefsJoinRequestView struct {
+	LexiconTypeID string `json:"$type"`
+	CreatedAt     string `json:"createdAt"`
+	Message       string `json:"message,omitempty"`
+	Subject       string `json:"subject"`
+	Uri           string `json:"uri"`
+}
+
+// MarshalJSON sets $type to "network.habitat.groups.defs#joinRequestView" before encoding.
+func (t NetworkHabitatGroupsDefsJoinRequestView) MarshalJSON() ([]byte, error) {
+	t.LexiconTypeID = "network.habitat.groups.defs#joinRequestView"
+	type alias NetworkHabitatGroupsDefsJoinRequestView
+	return json.Marshal(alias(t))
+}
+
 // NetworkHabitatGroupsDefsMemberView represents a memberView object
 type NetworkHabitatGroupsDefsMemberView struct {
 	LexiconTypeID string `json:"$type"`
@@ -54,3 +71,19 @@ func (t NetworkHabitatGroupsDefsMemberView) MarshalJSON() ([]byte, error) {
 	type alias NetworkHabitatGroupsDefsMemberView
 	return json.Marshal(alias(t))
 }
+
+// NetworkHabitatGroupsDefsMembershipView represents a membershipView object
+type NetworkHabitatGroupsDefsMembershipView struct {
+	LexiconTypeID string                           `json:"$type"`
+	Direct        bool                             `json:"direct"`
+	Group         NetworkHabitatGroupsDefsGroupRef `json:"group"`
+	Role          string                           `json:"role,omitempty"`
+	ViaGroup      string                           `json:"viaGroup,omitempty"`
+}
+
+// MarshalJSON sets $type to "network.habitat.groups.defs#membershipView" before encoding.
+func (t NetworkHabitatGroupsDefsMembershipView) MarshalJSON() ([]byte, error) {
+	t.LexiconTypeID = "network.habitat.groups.defs#membershipView"
+	type alias NetworkHabitatGroupsDefsMembershipView
+	return json.Marshal(alias(t))
+}
@@ -0,0 +1,15 @@
+package habitat
+
+// Code generated by lexgen. DO NOT EDIT.
+
+// NetworkHabitatGroupsGetMembershipPathParams represents the input parameters for network.habitat.groups.getMembershipPath
+type NetworkHabitatGroupsGetMembershipPathParams struct {
+	Did   string `json:"did,omitempty"`
+	Group string `json:"group"`
+}
+
+// NetworkHabitatGroupsGetMembershipPathOutput represents the output for network.habitat.groups.getMembershipPath
+type NetworkHabitatGroupsGetMembershipPathOutput struct {
+	Path []NetworkHabitatGroupsDefsGroupRef `json:"path"`
+	Role string                             `json:"role,omitempty"`
+}
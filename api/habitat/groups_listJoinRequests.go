@@ -0,0 +1,13 @@
+package habitat
+
+// Code generated by lexgen. DO NOT EDIT.
+
+// NetworkHabitatGroupsListJoinRequestsParams represents the input parameters for network.habitat.groups.listJoinRequests
+type NetworkHabitatGroupsListJoinRequestsParams struct {
+	Group string `json:"group"`
+}
+
+// NetworkHabitatGroupsListJoinRequestsOutput represents the output for network.habitat.groups.listJoinRequests
+type NetworkHabitatGroupsListJoinRequestsOutput struct {
+	Requests []NetworkHabitatGroupsDefsJoinRequestView `json:"requests"`
+}
@@ -0,0 +1,13 @@
+package habitat
+
+// Code generated by lexgen. DO NOT EDIT.
+
+// NetworkHabitatGroupsListMyGroupsParams represents the input parameters for network.habitat.groups.listMyGroups
+type NetworkHabitatGroupsListMyGroupsParams struct {
+	Did string `json:"did,omitempty"`
+}
+
+// NetworkHabitatGroupsListMyGroupsOutput represents the output for network.habitat.groups.listMyGroups
+type NetworkHabitatGroupsListMyGroupsOutput struct {
+	Groups []NetworkHabitatGroupsDefsMembershipView `json:"groups"`
+}
@@ -0,0 +1,15 @@
+package habitat
+
+// Code generated by lexgen. DO NOT EDIT.
+
+// NetworkHabitatGroupsRequestToJoinInput represents the input for network.habitat.groups.requestToJoin
+type NetworkHabitatGroupsRequestToJoinInput struct {
+	Group   string `json:"group"`
+	Message string `json:"message,omitempty"`
+}
+
+// NetworkHabitatGroupsRequestToJoinOutput represents the output for network.habitat.groups.requestToJoin
+type NetworkHabitatGroupsRequestToJoinOutput struct {
+	Status string `json:"status"`
+	Uri    string `json:"uri"`
+}
@@ -0,0 +1,15 @@
+package habitat
+
+// Code generated by lexgen. DO NOT EDIT.
+
+// NetworkHabitatGroupsResolveJoinRequestInput represents the input for network.habitat.groups.resolveJoinRequest
+type NetworkHabitatGroupsResolveJoinRequestInput struct {
+	Approve bool   `json:"approve"`
+	Group   string `json:"group"`
+	Subject string `json:"subject"`
+}
+
+// NetworkHabitatGroupsResolveJoinRequestOutput represents the output for network.habitat.groups.resolveJoinRequest
+type NetworkHabitatGroupsResolveJoinRequestOutput struct {
+	Uri string `json:"uri,omitempty"`
+}
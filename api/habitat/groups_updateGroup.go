@@ -6,6 +6,7 @@ package habitat
 type NetworkHabitatGroupsUpdateGroupInput struct {
 	Description string `json:"description,omitempty"`
 	Group       string `json:"group"`
+	JoinPolicy  string `json:"joinPolicy,omitempty"`
 	Name        string `json:"name,omitempty"`
 }
 
@@ -0,0 +1,9 @@
+package habitat
+
+// Code generated by lexgen. DO NOT EDIT.
+
+// NetworkHabitatOrgAssignRoleInput represents the input for network.habitat.org.assignRole
+type NetworkHabitatOrgAssignRoleInput struct {
+	Did  string `json:"did"`
+	Role string `json:"role"`
+}
@@ -0,0 +1,8 @@
+package habitat
+
+// Code generated by lexgen. DO NOT EDIT.
+
+// NetworkHabitatOrgDeleteRoleInput represents the input for network.habitat.org.deleteRole
+type NetworkHabitatOrgDeleteRoleInput struct {
+	Name string `json:"name"`
+}
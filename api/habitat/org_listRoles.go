@@ -0,0 +1,25 @@
+package habitat
+
+// Code generated by lexgen. DO NOT EDIT.
+
+import "encoding/json"
+
+// NetworkHabitatOrgListRolesOutput represents the output for network.habitat.org.listRoles
+type NetworkHabitatOrgListRolesOutput struct {
+	Roles []NetworkHabitatOrgListRolesRole `json:"roles"`
+}
+
+// NetworkHabitatOrgListRolesRole represents a role object
+type NetworkHabitatOrgListRolesRole struct {
+	LexiconTypeID string   `json:"$type"`
+	Assignees     []string `json:"assignees"`
+	Capabilities  []string `json:"capabilities"`
+	Name          string   `json:"name"`
+}
+
+// MarshalJSON sets $type to "network.habitat.org.listRoles#role" before encoding.
+func (t NetworkHabitatOrgListRolesRole) MarshalJSON() ([]byte, error) {
+	t.LexiconTypeID = "network.habitat.org.listRoles#role"
+	type alias NetworkHabitatOrgListRolesRole
+	return json.Marshal(alias(t))
+}
@@ -0,0 +1,51 @@
+package habitat
+
+// Code generated by lexgen. DO NOT EDIT.
+
+import "encoding/json"
+
+// NetworkHabitatOrgProvisionMembersInput represents the input for network.habitat.org.provisionMembers
+type NetworkHabitatOrgProvisionMembersInput struct {
+	Csv     string                                    `json:"csv,omitempty"`
+	DryRun  bool                                      `json:"dryRun,omitempty"`
+	Members []NetworkHabitatOrgProvisionMembersMember `json:"members,omitempty"`
+}
+
+// NetworkHabitatOrgProvisionMembersOutput represents the output for network.habitat.org.provisionMembers
+type NetworkHabitatOrgProvisionMembersOutput struct {
+	Committed bool                                      `json:"committed"`
+	Results   []NetworkHabitatOrgProvisionMembersResult `json:"results"`
+}
+
+// NetworkHabitatOrgProvisionMembersMember represents a member object
+type NetworkHabitatOrgProvisionMembersMember struct {
+	LexiconTypeID string   `json:"$type"`
+	Groups        []string `json:"groups,omitempty"`
+	Handle        string   `json:"handle"`
+	LoginID       string   `json:"loginID,omitempty"`
+}
+
+// MarshalJSON sets $type to "network.habitat.org.provisionMembers#member" before encoding.
+func (t NetworkHabitatOrgProvisionMembersMember) MarshalJSON() ([]byte, error) {
+	t.LexiconTypeID = "network.habitat.org.provisionMembers#member"
+	type alias NetworkHabitatOrgProvisionMembersMember
+	return json.Marshal(alias(t))
+}
+
+// NetworkHabitatOrgProvisionMembersResult represents a result object
+type NetworkHabitatOrgProvisionMembersResult struct {
+	LexiconTypeID   string `json:"$type"`
+	Did             string `json:"did,omitempty"`
+	Error           string `json:"error,omitempty"`
+	Handle          string `json:"handle"`
+	InitialPassword string `json:"initialPassword,omitempty"`
+	Row             int64  `json:"row"`
+	Status          string `json:"status"`
+}
+
+// MarshalJSON sets $type to "network.habitat.org.provisionMembers#result" before encoding.
+func (t NetworkHabitatOrgProvisionMembersResult) MarshalJSON() ([]byte, error) {
+	t.LexiconTypeID = "network.habitat.org.provisionMembers#result"
+	type alias NetworkHabitatOrgProvisionMembersResult
+	return json.Marshal(alias(t))
+}
@@ -0,0 +1,9 @@
+package habitat
+
+// Code generated by lexgen. DO NOT EDIT.
+
+// NetworkHabitatOrgPutRoleInput represents the input for network.habitat.org.putRole
+type NetworkHabitatOrgPutRoleInput struct {
+	Capabilities []string `json:"capabilities"`
+	Name         string   `json:"name"`
+}
@@ -0,0 +1,9 @@
+package habitat
+
+// Code generated by lexgen. DO NOT EDIT.
+
+// NetworkHabitatOrgUnassignRoleInput represents the input for network.habitat.org.unassignRole
+type NetworkHabitatOrgUnassignRoleInput struct {
+	Did  string `json:"did"`
+	Role string `json:"role"`
+}
@@ -17,6 +17,23 @@ type NetworkHabitatRelationshipListRelationsOutput struct {
 	Relations []interface{} `json:"relations"`
 }
 
+// NetworkHabitatRelationshipListRelationsOrgRoleRelationView represents a orgRoleRelationView object
+type NetworkHabitatRelationshipListRelationsOrgRoleRelationView struct {
+	LexiconTypeID string `json:"$type"`
+	Object        string `json:"object"`
+	Org           string `json:"org"`
+	Relation      string `json:"relation"`
+	Role          string `json:"role"`
+	Uri           string `json:"uri"`
+}
+
+// MarshalJSON sets $type to "network.habitat.relationship.listRelations#orgRoleRelationView" before encoding.
+func (t NetworkHabitatRelationshipListRelationsOrgRoleRelationView) MarshalJSON() ([]byte, error) {
+	t.LexiconTypeID = "network.habitat.relationship.listRelations#orgRoleRelationView"
+	type alias NetworkHabitatRelationshipListRelationsOrgRoleRelationView
+	return json.Marshal(alias(t))
+}
+
 // NetworkHabitatRelationshipListRelationsSpaceRelationView represents a spaceRelationView object
 type NetworkHabitatRelationshipListRelationsSpaceRelationView struct {
 	LexiconTypeID string `json:"$type"`
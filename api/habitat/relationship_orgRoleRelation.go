@@ -0,0 +1,21 @@
+package habitat
+
+// Code generated by lexgen. DO NOT EDIT.
+
+import "encoding/json"
+
+// NetworkHabitatRelationshipOrgRoleRelation represents a network.habitat.relationship.orgRoleRelation record
+type NetworkHabitatRelationshipOrgRoleRelation struct {
+	LexiconTypeID string `json:"$type"`
+	CreatedAt     string `json:"createdAt,omitempty"`
+	Org           string `json:"org"`
+	Relation      string `json:"relation"`
+	Role          string `json:"role"`
+}
+
+// MarshalJSON sets $type to "network.habitat.relationship.orgRoleRelation" before encoding.
+func (t NetworkHabitatRelationshipOrgRoleRelation) MarshalJSON() ([]byte, error) {
+	t.LexiconTypeID = "network.habitat.relationship.orgRoleRelation"
+	type alias NetworkHabitatRelationshipOrgRoleRelation
+	return json.Marshal(alias(t))
+}
@@ -0,0 +1,16 @@
+package habitat
+
+// Code generated by lexgen. DO NOT EDIT.
+
+// NetworkHabitatRelationshipSetOrgRoleRelationInput represents the input for network.habitat.relationship.setOrgRoleRelation
+type NetworkHabitatRelationshipSetOrgRoleRelationInput struct {
+	Org      string `json:"org"`
+	Relation string `json:"relation"`
+	Role     string `json:"role"`
+	Space    string `json:"space"`
+}
+
+// NetworkHabitatRelationshipSetOrgRoleRelationOutput represents the output for network.habitat.relationship.setOrgRoleRelation
+type NetworkHabitatRelationshipSetOrgRoleRelationOutput struct {
+	Uri string `json:"uri"`
+}
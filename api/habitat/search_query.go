@@ -4,16 +4,51 @@ package habitat
 
 import "encoding/json"
 
+// NetworkHabitatSearchQueryFacetCount represents a facetCount object
+type NetworkHabitatSearchQueryFacetCount struct {
+	LexiconTypeID string `json:"$type"`
+	Count         int64  `json:"count"`
+	Value         string `json:"value"`
+}
+
+// MarshalJSON sets $type to "network.habitat.search.query#facetCount" before encoding.
+func (t NetworkHabitatSearchQueryFacetCount) MarshalJSON() ([]byte, error) {
+	t.LexiconTypeID = "network.habitat.search.query#facetCount"
+	type alias NetworkHabitatSearchQueryFacetCount
+	return json.Marshal(alias(t))
+}
+
+// NetworkHabitatSearchQueryFacets represents a facets object
+type NetworkHabitatSearchQueryFacets struct {
+	LexiconTypeID string                                `json:"$type"`
+	Collections   []NetworkHabitatSearchQueryFacetCount `json:"collections"`
+	Spaces        []NetworkHabitatSearchQueryFacetCount `json:"spaces"`
+}
+
+// MarshalJSON sets $type to "network.habitat.search.query#facets" before encoding.
+func (t NetworkHabitatSearchQueryFacets) MarshalJSON() ([]byte, error) {
+	t.LexiconTypeID = "network.habitat.search.query#facets"
+	type alias NetworkHabitatSearchQueryFacets
+	return json.Marshal(alias(t))
+}
+
 // NetworkHabitatSearchQueryParams represents the input parameters for network.habitat.search.query
 type NetworkHabitatSearchQueryParams struct {
-	Cursor string `json:"cursor,omitempty"`
-	Limit  int64  `json:"limit,omitempty"`
-	Q      string `json:"q"`
+	Authors     []string `json:"authors,omitempty"`
+	Collections []string `json:"collections,omitempty"`
+	Cursor      string   `json:"cursor,omitempty"`
+	Lang        string   `json:"lang,omitempty"`
+	Limit       int64    `json:"limit,omitempty"`
+	Q           string   `json:"q"`
+	Since       string   `json:"since,omitempty"`
+	Spaces      []string `json:"spaces,omitempty"`
+	Until       string   `json:"until,omitempty"`
 }
 
 // NetworkHabitatSearchQueryOutput represents the output for network.habitat.search.query
 type NetworkHabitatSearchQueryOutput struct {
 	Cursor  string                                `json:"cursor,omitempty"`
+	Facets  *NetworkHabitatSearchQueryFacets      `json:"facets,omitempty"`
 	Results []NetworkHabitatSearchQueryResultView `json:"results"`
 }
 
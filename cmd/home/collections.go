@@ -2,7 +2,8 @@ package main
 
 import (
 	"context"
-	"fmt"
+	"encoding/json"
+	"time"
 
 	"github.com/bluesky-social/indigo/atproto/auth/oauth"
 	"github.com/bluesky-social/indigo/atproto/syntax"
@@ -23,21 +24,17 @@ func NewCollectionService(store *Store, oauthApp *oauth.ClientApp) *CollectionSe
 	return &CollectionService{store: store, oauthApp: oauthApp}
 }
 
-// readableSpaces returns the spaces the caller is allowed to read, resolved
-// authoritatively by pear's FGA. Records outside this set are never surfaced.
+// readableSpaces returns the spaces of org the caller is allowed to read,
+// resolved authoritatively by pear's FGA. Records outside this set are never
+// surfaced.
 func (c *CollectionService) readableSpaces(
 	ctx context.Context,
-	caller syntax.DID,
+	org, caller syntax.DID,
 ) ([]string, error) {
-	orgDID, sessionID, err := c.store.OrgSession(ctx)
+	pear, err := orgPear(ctx, c.store, c.oauthApp, org)
 	if err != nil {
 		return nil, err
 	}
-	session, err := c.oauthApp.ResumeSession(ctx, orgDID, sessionID)
-	if err != nil {
-		return nil, fmt.Errorf("build org client: %w", err)
-	}
-	pear := &pearClient{session: session}
 	return pear.listRelatedSpaces(ctx, caller, "reader")
 }
 
@@ -45,13 +42,13 @@ func (c *CollectionService) readableSpaces(
 // records in each (counted once per space a record belongs to).
 func (c *CollectionService) ListCollections(
 	ctx context.Context,
-	caller syntax.DID,
+	org, caller syntax.DID,
 ) (habitat.NetworkHabitatCollectionsListCollectionsOutput, error) {
-	spaces, err := c.readableSpaces(ctx, caller)
+	spaces, err := c.readableSpaces(ctx, org, caller)
 	if err != nil {
 		return habitat.NetworkHabitatCollectionsListCollectionsOutput{}, err
 	}
-	counts, err := c.store.CountCollections(ctx, spaces)
+	counts, err := c.store.CountCollections(ctx, org, spaces)
 	if err != nil {
 		return habitat.NetworkHabitatCollectionsListCollectionsOutput{}, err
 	}
@@ -70,25 +67,29 @@ func (c *CollectionService) ListCollections(
 	return out, nil
 }
 
-// ListRecords lists the records in a collection the caller can see. Each record
-// is scoped to a single space: the same repo/collection/rkey in a different
-// space is a distinct record (each space holds its own version), so it appears
-// as its own entry.
+// ListRecords lists a page of the records in a collection the caller can see,
+// with their bodies, most recently updated first. Each record is scoped to a
+// single space: the same repo/collection/rkey in a different space is a
+// distinct record (each space holds its own version), so it appears as its own
+// entry.
 func (c *CollectionService) ListRecords(
 	ctx context.Context,
-	caller syntax.DID,
+	org, caller syntax.DID,
 	collection string,
+	limit int,
+	cursor string,
 ) (habitat.NetworkHabitatCollectionsListRecordsOutput, error) {
-	spaces, err := c.readableSpaces(ctx, caller)
+	spaces, err := c.readableSpaces(ctx, org, caller)
 	if err != nil {
 		return habitat.NetworkHabitatCollectionsListRecordsOutput{}, err
 	}
-	rows, err := c.store.ListRecordsInSpaces(ctx, spaces, collection)
+	rows, next, err := c.store.ListRecordsInSpaces(ctx, org, spaces, collection, limit, cursor)
 	if err != nil {
 		return habitat.NetworkHabitatCollectionsListRecordsOutput{}, err
 	}
 	return habitat.NetworkHabitatCollectionsListRecordsOutput{
 		Records: recordViews(rows),
+		Cursor:  next,
 	}, nil
 }
 
@@ -97,13 +98,18 @@ func (c *CollectionService) ListRecords(
 func recordViews(rows []recordRow) []habitat.NetworkHabitatCollectionsDefsRecordView {
 	views := make([]habitat.NetworkHabitatCollectionsDefsRecordView, 0, len(rows))
 	for _, row := range rows {
-		views = append(views, habitat.NetworkHabitatCollectionsDefsRecordView{
+		view := habitat.NetworkHabitatCollectionsDefsRecordView{
 			Uri:        row.RecordURI,
 			Space:      row.SpaceURI,
 			Repo:       row.Repo,
 			Collection: row.Collection,
 			Rkey:       row.Rkey,
-		})
+			UpdatedAt:  row.UpdatedAt.UTC().Format(time.RFC3339Nano),
+		}
+		if row.Value != "" {
+			view.Value = json.RawMessage(row.Value)
+		}
+		views = append(views, view)
 	}
 	return views
 }
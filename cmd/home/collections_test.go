@@ -2,7 +2,9 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"testing"
+	"time"
 
 	"github.com/bluesky-social/indigo/atproto/syntax"
 	habitat_syntax "github.com/habitat-network/habitat/internal/syntax"
@@ -21,8 +23,12 @@ func recordURI(space, repo, collection, rkey string) habitat_syntax.SpaceRecordU
 }
 
 const (
+	// orgDID owns space1 and space2.
+	orgDID = syntax.DID("did:web:org")
 	space1 = "at://did:web:org/space/network.habitat.space/spaceone"
 	space2 = "at://did:web:org/space/network.habitat.space/spacetwo"
+
+	seedRev = syntax.TID("3jzfcijpj2z2a")
 )
 
 // seedRecords indexes a small fixture: record A lives in both spaces, B only in
@@ -36,7 +42,7 @@ func seedRecords(t *testing.T, ctx context.Context, store *Store) {
 		recordURI(space2, "did:web:alice", "app.bsky.feed.like", "r3"), // C
 	}
 	for _, u := range uris {
-		require.NoError(t, store.UpsertRecord(ctx, u))
+		require.NoError(t, store.UpsertRecord(ctx, u, seedRev, nil))
 	}
 }
 
@@ -45,7 +51,7 @@ func TestCountCollectionsCountsPerSpace(t *testing.T) {
 	store := setupStore(t)
 	seedRecords(t, ctx, store)
 
-	counts, err := store.CountCollections(ctx, []string{space1, space2})
+	counts, err := store.CountCollections(ctx, orgDID, []string{space1, space2})
 	require.NoError(t, err)
 
 	got := map[string]int64{}
@@ -65,7 +71,7 @@ func TestCountCollectionsFiltersBySpace(t *testing.T) {
 	store := setupStore(t)
 	seedRecords(t, ctx, store)
 
-	counts, err := store.CountCollections(ctx, []string{space1})
+	counts, err := store.CountCollections(ctx, orgDID, []string{space1})
 	require.NoError(t, err)
 	got := map[string]int64{}
 	for _, c := range counts {
@@ -75,7 +81,7 @@ func TestCountCollectionsFiltersBySpace(t *testing.T) {
 	require.Equal(t, map[string]int64{"app.bsky.feed.post": 2}, got)
 
 	// No readable spaces means nothing is visible.
-	empty, err := store.CountCollections(ctx, nil)
+	empty, err := store.CountCollections(ctx, orgDID, nil)
 	require.NoError(t, err)
 	require.Empty(t, empty)
 }
@@ -85,7 +91,10 @@ func TestListRecordsInSpacesFiltersByCollectionAndSpace(t *testing.T) {
 	store := setupStore(t)
 	seedRecords(t, ctx, store)
 
-	rows, err := store.ListRecordsInSpaces(ctx, []string{space1, space2}, "app.bsky.feed.post")
+	rows, _, err := store.ListRecordsInSpaces(
+		ctx,
+		orgDID, []string{space1, space2}, "app.bsky.feed.post", 100, "",
+	)
 	require.NoError(t, err)
 	// A (x2 spaces) + B (x1) = 3 rows, none from the like collection.
 	require.Len(t, rows, 3)
@@ -94,13 +103,15 @@ func TestListRecordsInSpacesFiltersByCollectionAndSpace(t *testing.T) {
 	}
 
 	// Restricting to space2 drops record B, which only lives in space1.
-	rows, err = store.ListRecordsInSpaces(ctx, []string{space2}, "app.bsky.feed.post")
+	rows, _, err = store.ListRecordsInSpaces(
+		ctx, orgDID, []string{space2}, "app.bsky.feed.post", 100, "",
+	)
 	require.NoError(t, err)
 	require.Len(t, rows, 1)
 	require.Equal(t, "at://did:web:alice/app.bsky.feed.post/r1", rows[0].AtURI)
 
 	// No readable spaces yields nothing.
-	rows, err = store.ListRecordsInSpaces(ctx, nil, "app.bsky.feed.post")
+	rows, _, err = store.ListRecordsInSpaces(ctx, orgDID, nil, "app.bsky.feed.post", 100, "")
 	require.NoError(t, err)
 	require.Empty(t, rows)
 }
@@ -114,7 +125,10 @@ func TestDeleteRecordRemovesOnlyOneSpaceCopy(t *testing.T) {
 	require.NoError(t, store.DeleteRecord(
 		ctx, recordURI(space1, "did:web:alice", "app.bsky.feed.post", "r1"),
 	))
-	rows, err := store.ListRecordsInSpaces(ctx, []string{space1, space2}, "app.bsky.feed.post")
+	rows, _, err := store.ListRecordsInSpaces(
+		ctx,
+		orgDID, []string{space1, space2}, "app.bsky.feed.post", 100, "",
+	)
 	require.NoError(t, err)
 	require.Len(t, rows, 2) // A in space2, B in space1
 }
@@ -124,8 +138,10 @@ func TestUpsertRecordIgnoresMalformedURI(t *testing.T) {
 	store := setupStore(t)
 
 	// Missing the repo/collection/rkey suffix: not a space-record URI.
-	require.NoError(t, store.UpsertRecord(ctx, habitat_syntax.SpaceRecordURI(space1)))
-	rows, err := store.ListRecordsInSpaces(ctx, []string{space1}, "app.bsky.feed.post")
+	require.NoError(t, store.UpsertRecord(ctx, habitat_syntax.SpaceRecordURI(space1), seedRev, nil))
+	rows, _, err := store.ListRecordsInSpaces(
+		ctx, orgDID, []string{space1}, "app.bsky.feed.post", 100, "",
+	)
 	require.NoError(t, err)
 	require.Empty(t, rows)
 }
@@ -167,3 +183,61 @@ func TestRecordViewsAreScopedPerSpace(t *testing.T) {
 	require.Equal(t, space2, spaceByURI[space2+"/did:web:alice/c/r1"])
 	require.Equal(t, space1, spaceByURI[space1+"/did:web:bob/c/r2"])
 }
+
+func TestListRecordsInSpacesPagesNewestFirstWithValues(t *testing.T) {
+	ctx := context.Background()
+	store := setupStore(t)
+
+	clock := syntax.NewTIDClock(0)
+	var uris []habitat_syntax.SpaceRecordURI
+	for _, rkey := range []string{"r1", "r2", "r3"} {
+		uri := recordURI(space1, "did:web:alice", "app.bsky.feed.post", rkey)
+		value := []byte(`{"text":"` + rkey + `"}`)
+		require.NoError(t, store.UpsertRecord(ctx, uri, clock.Next(), value))
+		uris = append(uris, uri)
+	}
+	// Rewriting r1 makes it the most recently updated.
+	require.NoError(
+		t,
+		store.UpsertRecord(ctx, uris[0], clock.Next(), []byte(`{"text":"edited"}`)),
+	)
+
+	rows, cursor, err := store.ListRecordsInSpaces(
+		ctx,
+		orgDID, []string{space1}, "app.bsky.feed.post", 2, "",
+	)
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	require.Equal(t, uris[0].String(), rows[0].RecordURI)
+	require.JSONEq(t, `{"text":"edited"}`, rows[0].Value)
+	require.Equal(t, uris[2].String(), rows[1].RecordURI)
+	require.NotEmpty(t, cursor)
+
+	rows, cursor, err = store.ListRecordsInSpaces(
+		ctx,
+		orgDID, []string{space1}, "app.bsky.feed.post", 2, cursor,
+	)
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	require.Equal(t, uris[1].String(), rows[0].RecordURI)
+	require.Empty(t, cursor, "the last page has no cursor")
+
+	_, _, err = store.ListRecordsInSpaces(
+		ctx, orgDID, []string{space1}, "app.bsky.feed.post", 2, "bogus",
+	)
+	require.ErrorIs(t, err, ErrInvalidCursor)
+}
+
+func TestRecordViewsCarryValueAndUpdatedAt(t *testing.T) {
+	updatedAt := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	views := recordViews([]recordRow{
+		{RecordURI: space1 + "/did:web:alice/c/r1", Value: `{"text":"hi"}`, UpdatedAt: updatedAt},
+		{RecordURI: space1 + "/did:web:alice/c/r2", UpdatedAt: updatedAt},
+	})
+
+	out, err := json.Marshal(views[0])
+	require.NoError(t, err)
+	require.Contains(t, string(out), `"value":{"text":"hi"}`)
+	require.Equal(t, "2026-10-01T12:00:00Z", views[0].UpdatedAt)
+	require.Nil(t, views[1].Value, "a record synced without its body has no value")
+}
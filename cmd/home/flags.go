@@ -3,12 +3,15 @@ package main
 import "github.com/urfave/cli/v3"
 
 var (
-	fDB        = "db"
-	fPort      = "port"
-	fDomain    = "domain"
-	fSecret    = "secret"
-	fOrgHandle = "org-handle"
-	fLogLevel  = "log-level"
+	fDB           = "db"
+	fPort         = "port"
+	fInternalPort = "internal-port"
+	fDomain       = "domain"
+	fSecret       = "secret"
+	fOrgHandle    = "org-handle"
+	fLogLevel     = "log-level"
+
+	fOutboxMaxAttempts = "outbox-max-attempts"
 )
 
 func getFlags() []cli.Flag {
@@ -25,6 +28,12 @@ func getFlags() []cli.Flag {
 			Value:   "2600",
 			Sources: cli.EnvVars("HOME_PORT"),
 		},
+		&cli.StringFlag{
+			Name:    fInternalPort,
+			Usage:   "Internal port serving the operator endpoints, such as org enrollment",
+			Value:   "2601",
+			Sources: cli.EnvVars("HOME_INTERNAL_PORT"),
+		},
 		&cli.StringFlag{
 			Name:    fDomain,
 			Usage:   "Publicly-accessible domain of this home instance (its did:web host)",
@@ -39,7 +48,7 @@ func getFlags() []cli.Flag {
 		},
 		&cli.StringFlag{
 			Name:    fOrgHandle,
-			Usage:   "Handle of the org this home server manages groups for",
+			Usage:   "Handle of the org /oauth/login authorizes when no handle is given",
 			Value:   "acmecorp.pear.local.habitat.network",
 			Sources: cli.EnvVars("HOME_ORG_HANDLE"),
 		},
@@ -49,5 +58,11 @@ func getFlags() []cli.Flag {
 			Value:   "info",
 			Sources: cli.EnvVars("HOME_LOG_LEVEL"),
 		},
+		&cli.IntFlag{
+			Name:    fOutboxMaxAttempts,
+			Usage:   "Attempts to index a synced record before it is dead-lettered (0 = never)",
+			Value:   10,
+			Sources: cli.EnvVars("HOME_OUTBOX_MAX_ATTEMPTS"),
+		},
 	}
 }
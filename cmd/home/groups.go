@@ -20,13 +20,22 @@ var ErrForbidden = errors.New("not allowed to manage this group")
 // exactly one of a user or a group subject.
 var ErrInvalidSubject = errors.New("exactly one of subjectDid or subjectGroup must be provided")
 
+// ErrMembershipCycle indicates an addMember call would make a group inherit
+// members from a group that already inherits from it.
+var ErrMembershipCycle = errors.New("subject group already inherits members from this group")
+
+// ErrNotMember indicates a getMembershipPath call named a user who is not a
+// member of the group.
+var ErrNotMember = errors.New("user is not a member of this group")
+
 // ErrMemberNotFound indicates a deleteMember call named a subject that is not a
 // direct member of the group.
 var ErrMemberNotFound = errors.New("subject is not a direct member of this group")
 
 // GroupService implements the network.habitat.groups.* endpoints. It reads
 // group membership from the sap-fed index (Store) and performs writes against
-// pear using the org credential held by the oauth client.
+// pear using the org credential held by the oauth client. Every call is for
+// one org, resolved by the server from the caller, and only sees its groups.
 type GroupService struct {
 	store    *Store
 	oauthApp *oauth.ClientApp
@@ -36,25 +45,21 @@ func NewGroupService(store *Store, oauthApp *oauth.ClientApp) *GroupService {
 	return &GroupService{store: store, oauthApp: oauthApp}
 }
 
-// orgPear builds a pear client authenticated as the managed org.
-func (g *GroupService) orgPear(ctx context.Context) (*pearClient, syntax.DID, error) {
-	orgDID, sessionID, err := g.store.OrgSession(ctx)
-	if err != nil {
-		return nil, "", err
-	}
-	session, err := g.oauthApp.ResumeSession(ctx, orgDID, sessionID)
-	if err != nil {
-		return nil, "", fmt.Errorf("build org client: %w", err)
-	}
-	return &pearClient{session: session}, orgDID, nil
+// orgPear builds a pear client authenticated as org.
+func (g *GroupService) orgPear(ctx context.Context, org syntax.DID) (*pearClient, error) {
+	return orgPear(ctx, g.store, g.oauthApp, org)
 }
 
 func (g *GroupService) CreateGroup(
 	ctx context.Context,
-	caller syntax.DID,
+	org, caller syntax.DID,
 	in habitat.NetworkHabitatGroupsCreateGroupInput,
 ) (habitat.NetworkHabitatGroupsCreateGroupOutput, error) {
-	pear, _, err := g.orgPear(ctx)
+	joinPolicy, err := parseJoinPolicy(in.JoinPolicy)
+	if err != nil {
+		return habitat.NetworkHabitatGroupsCreateGroupOutput{}, err
+	}
+	pear, err := g.orgPear(ctx, org)
 	if err != nil {
 		return habitat.NetworkHabitatGroupsCreateGroupOutput{}, err
 	}
@@ -69,6 +74,7 @@ func (g *GroupService) CreateGroup(
 		Name:        in.Name,
 		Description: in.Description,
 		CreatedAt:   createdAt,
+		JoinPolicy:  joinPolicy,
 	})
 	if err != nil {
 		return habitat.NetworkHabitatGroupsCreateGroupOutput{}, err
@@ -83,7 +89,7 @@ func (g *GroupService) CreateGroup(
 
 	// Prime the index so the creator sees the new group immediately; sap will
 	// reconcile the same rows when the records sync.
-	_ = g.store.UpsertProfile(ctx, profileURI, in.Name, in.Description, createdAt)
+	_ = g.store.UpsertProfile(ctx, profileURI, in.Name, in.Description, createdAt, joinPolicy)
 	_ = g.store.UpsertTuple(ctx, tupleRow{
 		RecordURI:   tupleURI.String(),
 		ObjectSpace: space.String(),
@@ -97,9 +103,14 @@ func (g *GroupService) CreateGroup(
 
 func (g *GroupService) UpdateGroup(
 	ctx context.Context,
-	caller syntax.DID,
+	org, caller syntax.DID,
 	in habitat.NetworkHabitatGroupsUpdateGroupInput,
 ) (habitat.NetworkHabitatGroupsUpdateGroupOutput, error) {
+	if in.JoinPolicy != "" {
+		if _, err := parseJoinPolicy(in.JoinPolicy); err != nil {
+			return habitat.NetworkHabitatGroupsUpdateGroupOutput{}, err
+		}
+	}
 	space, err := habitat_syntax.ParseSpaceURI(in.Group)
 	if err != nil {
 		return habitat.NetworkHabitatGroupsUpdateGroupOutput{}, fmt.Errorf(
@@ -107,12 +118,12 @@ func (g *GroupService) UpdateGroup(
 			err,
 		)
 	}
-	existing, err := g.store.GetGroup(ctx, space)
+	existing, err := g.store.GetGroup(ctx, org, space)
 	if err != nil {
 		return habitat.NetworkHabitatGroupsUpdateGroupOutput{}, err
 	}
 
-	pear, _, err := g.orgPear(ctx)
+	pear, err := g.orgPear(ctx, org)
 	if err != nil {
 		return habitat.NetworkHabitatGroupsUpdateGroupOutput{}, err
 	}
@@ -128,23 +139,30 @@ func (g *GroupService) UpdateGroup(
 	if in.Description != "" {
 		description = in.Description
 	}
+	joinPolicy := existing.JoinPolicy
+	if in.JoinPolicy != "" {
+		joinPolicy = in.JoinPolicy
+	}
 
 	profileURI, err := pear.putProfile(ctx, space, habitat.NetworkHabitatGroupProfile{
 		Name:        name,
 		Description: description,
 		CreatedAt:   existing.CreatedAt,
+		JoinPolicy:  joinPolicy,
 	})
 	if err != nil {
 		return habitat.NetworkHabitatGroupsUpdateGroupOutput{}, err
 	}
-	_ = g.store.UpsertProfile(ctx, profileURI, name, description, existing.CreatedAt)
+	_ = g.store.UpsertProfile(
+		ctx, profileURI, name, description, existing.CreatedAt, joinPolicy,
+	)
 
 	return habitat.NetworkHabitatGroupsUpdateGroupOutput{Uri: space.String()}, nil
 }
 
 func (g *GroupService) AddMember(
 	ctx context.Context,
-	caller syntax.DID,
+	org, caller syntax.DID,
 	in habitat.NetworkHabitatGroupsAddMemberInput,
 ) (habitat.NetworkHabitatGroupsAddMemberOutput, error) {
 	if (in.SubjectDid == "") == (in.SubjectGroup == "") {
@@ -154,11 +172,11 @@ func (g *GroupService) AddMember(
 	if err != nil {
 		return habitat.NetworkHabitatGroupsAddMemberOutput{}, fmt.Errorf("parse group uri: %w", err)
 	}
-	if _, err := g.store.GetGroup(ctx, space); err != nil {
+	if _, err := g.store.GetGroup(ctx, org, space); err != nil {
 		return habitat.NetworkHabitatGroupsAddMemberOutput{}, err
 	}
 
-	pear, _, err := g.orgPear(ctx)
+	pear, err := g.orgPear(ctx, org)
 	if err != nil {
 		return habitat.NetworkHabitatGroupsAddMemberOutput{}, err
 	}
@@ -194,6 +212,13 @@ func (g *GroupService) AddMember(
 				err,
 			)
 		}
+		// Groups only inherit members from groups of the same org.
+		if _, err := g.store.GetGroup(ctx, org, subjectGroup); err != nil {
+			return habitat.NetworkHabitatGroupsAddMemberOutput{}, err
+		}
+		if err := g.requireAcyclic(ctx, space, subjectGroup); err != nil {
+			return habitat.NetworkHabitatGroupsAddMemberOutput{}, err
+		}
 		tupleURI, err = pear.writeGroupTuple(ctx, subjectGroup, "writer", space)
 		if err != nil {
 			return habitat.NetworkHabitatGroupsAddMemberOutput{}, err
@@ -214,7 +239,7 @@ func (g *GroupService) AddMember(
 
 func (g *GroupService) DeleteMember(
 	ctx context.Context,
-	caller syntax.DID,
+	org, caller syntax.DID,
 	in habitat.NetworkHabitatGroupsDeleteMemberInput,
 ) error {
 	if (in.SubjectDid == "") == (in.SubjectGroup == "") {
@@ -224,11 +249,11 @@ func (g *GroupService) DeleteMember(
 	if err != nil {
 		return fmt.Errorf("parse group uri: %w", err)
 	}
-	if _, err := g.store.GetGroup(ctx, space); err != nil {
+	if _, err := g.store.GetGroup(ctx, org, space); err != nil {
 		return err
 	}
 
-	pear, _, err := g.orgPear(ctx)
+	pear, err := g.orgPear(ctx, org)
 	if err != nil {
 		return err
 	}
@@ -290,107 +315,247 @@ func (g *GroupService) requireManage(
 	return nil
 }
 
+// requireAcyclic refuses to make space inherit subjectGroup's members when
+// subjectGroup already inherits space's, which would form a cycle. Cycles
+// written elsewhere are still resolved, and reported as they are indexed.
+func (g *GroupService) requireAcyclic(
+	ctx context.Context,
+	space, subjectGroup habitat_syntax.SpaceURI,
+) error {
+	if space == subjectGroup {
+		return ErrMembershipCycle
+	}
+	tuples, err := g.store.InheritedTuples(ctx, subjectGroup.String())
+	if err != nil {
+		return err
+	}
+	if newGraph(tuples).inherits(subjectGroup.String(), space.String()) {
+		return ErrMembershipCycle
+	}
+	return nil
+}
+
 func (g *GroupService) ListGroups(
 	ctx context.Context,
-	caller syntax.DID,
+	org, caller syntax.DID,
 ) (habitat.NetworkHabitatGroupsListGroupsOutput, error) {
-	groups, gr, names, err := g.load(ctx)
+	// Only surface groups the caller belongs to.
+	memberships, err := g.store.ListMemberships(ctx, org, caller.String(), memberMinRole)
 	if err != nil {
 		return habitat.NetworkHabitatGroupsListGroupsOutput{}, err
 	}
-
-	out := habitat.NetworkHabitatGroupsListGroupsOutput{
-		Groups: []habitat.NetworkHabitatGroupsDefsGroupView{},
+	spaces := make([]string, len(memberships))
+	for i, m := range memberships {
+		spaces[i] = m.GroupSpace
 	}
-	for _, group := range groups {
-		// Only surface groups the caller belongs to.
-		if !gr.isMember(group.SpaceURI, caller.String()) {
-			continue
-		}
-		out.Groups = append(out.Groups, g.view(group, gr, names, caller, false))
+	groups, err := g.store.ListGroupsIn(ctx, spaces)
+	if err != nil {
+		return habitat.NetworkHabitatGroupsListGroupsOutput{}, err
 	}
-	return out, nil
+	views, err := g.views(ctx, org, groups, caller, false)
+	if err != nil {
+		return habitat.NetworkHabitatGroupsListGroupsOutput{}, err
+	}
+	return habitat.NetworkHabitatGroupsListGroupsOutput{Groups: views}, nil
 }
 
 func (g *GroupService) GetGroup(
 	ctx context.Context,
-	caller syntax.DID,
+	org, caller syntax.DID,
 	groupURI string,
 ) (habitat.NetworkHabitatGroupsDefsGroupView, error) {
 	space, err := habitat_syntax.ParseSpaceURI(groupURI)
 	if err != nil {
 		return habitat.NetworkHabitatGroupsDefsGroupView{}, fmt.Errorf("parse group uri: %w", err)
 	}
-	row, err := g.store.GetGroup(ctx, space)
+	row, err := g.store.GetGroup(ctx, org, space)
 	if err != nil {
 		return habitat.NetworkHabitatGroupsDefsGroupView{}, err
 	}
-	_, gr, names, err := g.load(ctx)
+	views, err := g.views(ctx, org, []groupRow{row}, caller, true)
 	if err != nil {
 		return habitat.NetworkHabitatGroupsDefsGroupView{}, err
 	}
-	return g.view(row, gr, names, caller, true), nil
+	return views[0], nil
 }
 
-// load reads all indexed groups and tuples and builds the membership graph and
-// a space-uri -> name lookup.
-func (g *GroupService) load(ctx context.Context) ([]groupRow, *graph, map[string]string, error) {
-	groups, err := g.store.ListGroups(ctx)
+// ListMyGroups lists every group did is a member of, read from the
+// materialized memberships.
+func (g *GroupService) ListMyGroups(
+	ctx context.Context,
+	org, did syntax.DID,
+) (habitat.NetworkHabitatGroupsListMyGroupsOutput, error) {
+	memberships, err := g.store.ListMemberships(ctx, org, did.String(), memberMinRole)
 	if err != nil {
-		return nil, nil, nil, err
+		return habitat.NetworkHabitatGroupsListMyGroupsOutput{}, err
+	}
+	spaces := make([]string, len(memberships))
+	for i, m := range memberships {
+		spaces[i] = m.GroupSpace
 	}
-	tuples, err := g.store.ListTuples(ctx)
+	names, err := g.names(ctx, spaces)
 	if err != nil {
-		return nil, nil, nil, err
+		return habitat.NetworkHabitatGroupsListMyGroupsOutput{}, err
+	}
+
+	out := habitat.NetworkHabitatGroupsListMyGroupsOutput{
+		Groups: []habitat.NetworkHabitatGroupsDefsMembershipView{},
+	}
+	for _, m := range memberships {
+		name, ok := names[m.GroupSpace]
+		if !ok {
+			// Tuples on a space with no indexed profile are not a group.
+			continue
+		}
+		out.Groups = append(out.Groups, habitat.NetworkHabitatGroupsDefsMembershipView{
+			Group:    habitat.NetworkHabitatGroupsDefsGroupRef{Uri: m.GroupSpace, Name: name},
+			Role:     m.Role,
+			Direct:   m.Direct,
+			ViaGroup: m.ViaGroup,
+		})
+	}
+	sort.SliceStable(out.Groups, func(i, j int) bool {
+		return out.Groups[i].Group.Name < out.Groups[j].Group.Name
+	})
+	return out, nil
+}
+
+// GetMembershipPath explains how did is a member of a group: the shortest
+// chain of inherited groups leading to it from one did is granted a role on.
+func (g *GroupService) GetMembershipPath(
+	ctx context.Context,
+	org, did syntax.DID,
+	groupURI string,
+) (habitat.NetworkHabitatGroupsGetMembershipPathOutput, error) {
+	space, err := habitat_syntax.ParseSpaceURI(groupURI)
+	if err != nil {
+		return habitat.NetworkHabitatGroupsGetMembershipPathOutput{}, fmt.Errorf(
+			"parse group uri: %w",
+			err,
+		)
+	}
+	if _, err := g.store.GetGroup(ctx, org, space); err != nil {
+		return habitat.NetworkHabitatGroupsGetMembershipPathOutput{}, err
+	}
+	tuples, err := g.store.InheritedTuples(ctx, space.String())
+	if err != nil {
+		return habitat.NetworkHabitatGroupsGetMembershipPathOutput{}, err
+	}
+	gr := newGraph(tuples)
+	path := gr.path(space.String(), did.String(), memberMinRole)
+	if path == nil {
+		return habitat.NetworkHabitatGroupsGetMembershipPathOutput{}, ErrNotMember
+	}
+	names, err := g.names(ctx, path)
+	if err != nil {
+		return habitat.NetworkHabitatGroupsGetMembershipPathOutput{}, err
+	}
+
+	out := habitat.NetworkHabitatGroupsGetMembershipPathOutput{}
+	for _, m := range gr.memberships(space.String()) {
+		if m.DID == did.String() {
+			out.Role = m.Role
+		}
+	}
+	for _, step := range path {
+		out.Path = append(out.Path, habitat.NetworkHabitatGroupsDefsGroupRef{
+			Uri:  step,
+			Name: names[step],
+		})
+	}
+	return out, nil
+}
+
+// names returns the names of the indexed groups among spaces, by space URI.
+func (g *GroupService) names(ctx context.Context, spaces []string) (map[string]string, error) {
+	groups, err := g.store.ListGroupsIn(ctx, spaces)
+	if err != nil {
+		return nil, err
 	}
 	names := make(map[string]string, len(groups))
 	for _, gr := range groups {
 		names[gr.SpaceURI] = gr.Name
 	}
-	return groups, newGraph(tuples), names, nil
+	return names, nil
 }
 
-// view builds the lexicon group view. When full is true the resolved member
-// list is included; the list endpoint omits it for brevity.
-func (g *GroupService) view(
-	row groupRow,
-	gr *graph,
-	names map[string]string,
+// views builds the lexicon group views of groups, reading their memberships
+// and inherited groups in a few queries for all of them. When full is true
+// the resolved member list is included; the list endpoint omits it for
+// brevity.
+func (g *GroupService) views(
+	ctx context.Context,
+	org syntax.DID,
+	groups []groupRow,
 	caller syntax.DID,
 	full bool,
-) habitat.NetworkHabitatGroupsDefsGroupView {
-	members := gr.holders(row.SpaceURI, memberMinRole)
-
-	view := habitat.NetworkHabitatGroupsDefsGroupView{
-		Uri:         row.SpaceURI,
-		Name:        row.Name,
-		Description: row.Description,
-		CreatedAt:   row.CreatedAt,
-		MemberCount: int64(len(members)),
-		IsMember:    gr.isMember(row.SpaceURI, caller.String()),
-		CanManage:   gr.canManage(row.SpaceURI, caller.String()),
-	}
-
-	for _, inherited := range gr.inheritedGroups(row.SpaceURI) {
-		view.InheritedGroups = append(
-			view.InheritedGroups,
-			habitat.NetworkHabitatGroupsDefsGroupRef{
-				Uri:  inherited,
-				Name: names[inherited],
-			},
-		)
+) ([]habitat.NetworkHabitatGroupsDefsGroupView, error) {
+	spaces := make([]string, len(groups))
+	for i, row := range groups {
+		spaces[i] = row.SpaceURI
 	}
-
-	if full {
-		sort.Slice(members, func(i, j int) bool { return members[i].DID < members[j].DID })
-		for _, m := range members {
-			view.Members = append(view.Members, habitat.NetworkHabitatGroupsDefsMemberView{
-				Did:      m.DID,
-				Role:     m.Role,
-				Direct:   m.Direct,
-				ViaGroup: m.ViaGroup,
-			})
+	counts, err := g.store.CountGroupMembers(ctx, spaces, memberMinRole)
+	if err != nil {
+		return nil, err
+	}
+	callerRoles := map[string]role{}
+	memberships, err := g.store.ListMemberships(ctx, org, caller.String(), roleReader)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range memberships {
+		callerRoles[m.GroupSpace] = parseRole(m.Role)
+	}
+	tuples, err := g.store.ListTuplesOn(ctx, spaces)
+	if err != nil {
+		return nil, err
+	}
+	gr := newGraph(tuples)
+	var inherited []string
+	for _, space := range spaces {
+		inherited = append(inherited, gr.inheritedGroups(space)...)
+	}
+	names, err := g.names(ctx, inherited)
+	if err != nil {
+		return nil, err
+	}
+
+	views := make([]habitat.NetworkHabitatGroupsDefsGroupView, 0, len(groups))
+	for _, row := range groups {
+		view := habitat.NetworkHabitatGroupsDefsGroupView{
+			Uri:         row.SpaceURI,
+			Name:        row.Name,
+			Description: row.Description,
+			CreatedAt:   row.CreatedAt,
+			JoinPolicy:  joinPolicyOf(row),
+			MemberCount: counts[row.SpaceURI],
+			IsMember:    callerRoles[row.SpaceURI] >= memberMinRole,
+			CanManage:   callerRoles[row.SpaceURI] >= manageMinRole,
+		}
+		for _, space := range gr.inheritedGroups(row.SpaceURI) {
+			view.InheritedGroups = append(
+				view.InheritedGroups,
+				habitat.NetworkHabitatGroupsDefsGroupRef{
+					Uri:  space,
+					Name: names[space],
+				},
+			)
+		}
+		if full {
+			members, err := g.store.ListGroupMembers(ctx, row.SpaceURI, memberMinRole)
+			if err != nil {
+				return nil, err
+			}
+			for _, m := range members {
+				view.Members = append(view.Members, habitat.NetworkHabitatGroupsDefsMemberView{
+					Did:      m.MemberDID,
+					Role:     m.Role,
+					Direct:   m.Direct,
+					ViaGroup: m.ViaGroup,
+				})
+			}
 		}
+		views = append(views, view)
 	}
-	return view
+	return views, nil
 }
@@ -13,7 +13,8 @@ import (
 const indexerBatchSize = 50
 
 // Indexer drains sap's outbox and maintains the home server's group index:
-// network.habitat.group.profile records become group rows and
+// network.habitat.group.profile records become group rows,
+// network.habitat.group.joinRequest records become join request rows, and
 // network.habitat.relationship.tuple records granting a role on a group-space
 // become tuple rows. Both backfill and live firehose updates flow through the
 // same outbox, so the index tracks every group the org syncs.
@@ -27,7 +28,9 @@ func NewIndexer(store *Store, outbox outbox.Outbox) *Indexer {
 }
 
 // Run drains the outbox until ctx is canceled, blocking on outbox.Watch() when
-// there are no pending messages.
+// there are no pending messages. A message that fails to index is nacked
+// with the error, so sap redelivers it until it runs out of attempts and is
+// dead-lettered.
 func (ix *Indexer) Run(ctx context.Context) error {
 	for {
 		msgs, err := ix.outbox.Poll(ctx, indexerBatchSize)
@@ -44,7 +47,12 @@ func (ix *Indexer) Run(ctx context.Context) error {
 		}
 		for _, msg := range msgs {
 			if err := ix.handle(ctx, msg); err != nil {
-				slog.ErrorContext(ctx, "failed to index message", "err", err, "uri", msg.URI)
+				slog.ErrorContext(ctx, "failed to index message",
+					"err", err, "uri", msg.URI, "attempts", msg.Attempts)
+				if err := ix.outbox.Nack(ctx, msg.ID, err.Error()); err != nil {
+					slog.ErrorContext(ctx, "failed to nack outbox message",
+						"err", err, "uri", msg.URI)
+				}
 				continue
 			}
 			if err := ix.outbox.Ack(ctx, msg.ID); err != nil {
@@ -55,7 +63,14 @@ func (ix *Indexer) Run(ctx context.Context) error {
 }
 
 func (ix *Indexer) handle(ctx context.Context, msg outbox.Message) error {
-	deleted := isDeleted(msg.Value)
+	switch msg.Action {
+	case outbox.ActionResync:
+		return ix.store.ResyncRepo(ctx, msg.Space, msg.Repo, msg.Rev)
+	case outbox.ActionBackfillComplete:
+		slog.InfoContext(ctx, "org backfill indexed", "did", msg.Repo)
+		return nil
+	}
+	deleted := msg.Action == outbox.ActionDelete
 
 	// Every synced record is indexed into the records table so the collections
 	// endpoints can browse the org's data by collection type, regardless of
@@ -64,7 +79,7 @@ func (ix *Indexer) handle(ctx context.Context, msg outbox.Message) error {
 		if err := ix.store.DeleteRecord(ctx, msg.URI); err != nil {
 			return err
 		}
-	} else if err := ix.store.UpsertRecord(ctx, msg.URI); err != nil {
+	} else if err := ix.store.UpsertRecord(ctx, msg.URI, msg.Rev, msg.Value); err != nil {
 		return err
 	}
 
@@ -74,6 +89,11 @@ func (ix *Indexer) handle(ctx context.Context, msg outbox.Message) error {
 			return ix.store.DeleteProfile(ctx, msg.URI)
 		}
 		return ix.indexProfile(ctx, msg)
+	case collectionJoinRequest:
+		if deleted {
+			return ix.store.DeleteJoinRequest(ctx, msg.URI)
+		}
+		return ix.indexJoinRequest(ctx, msg)
 	case collectionTuple:
 		if deleted {
 			return ix.store.DeleteTuple(ctx, msg.URI)
@@ -89,6 +109,7 @@ func (ix *Indexer) indexProfile(ctx context.Context, msg outbox.Message) error {
 		Name        string `json:"name"`
 		Description string `json:"description"`
 		CreatedAt   string `json:"createdAt"`
+		JoinPolicy  string `json:"joinPolicy"`
 	}
 	if err := json.Unmarshal(msg.Value, &profile); err != nil {
 		return fmt.Errorf("unmarshal group profile: %w", err)
@@ -99,6 +120,32 @@ func (ix *Indexer) indexProfile(ctx context.Context, msg outbox.Message) error {
 		profile.Name,
 		profile.Description,
 		profile.CreatedAt,
+		profile.JoinPolicy,
+	)
+}
+
+// indexJoinRequest indexes a join request record. Only records in the org's
+// own repo are requests: home writes them as the org after checking the
+// caller, whereas any member could write a joinRequest into their own repo
+// naming someone else as the subject.
+func (ix *Indexer) indexJoinRequest(ctx context.Context, msg outbox.Message) error {
+	if msg.URI.Repo() != msg.URI.SpaceOwner() {
+		return nil
+	}
+	var request struct {
+		Subject   string `json:"subject"`
+		Message   string `json:"message"`
+		CreatedAt string `json:"createdAt"`
+	}
+	if err := json.Unmarshal(msg.Value, &request); err != nil {
+		return fmt.Errorf("unmarshal join request: %w", err)
+	}
+	return ix.store.UpsertJoinRequest(
+		ctx,
+		msg.URI,
+		request.Subject,
+		request.Message,
+		request.CreatedAt,
 	)
 }
 
@@ -132,7 +179,7 @@ func parseTuple(uri habitat_syntax.SpaceRecordURI, value json.RawMessage) (tuple
 	if err != nil {
 		return tupleRow{}, false, nil
 	}
-	if object.SpaceType().String() != GroupSpaceType {
+	if object.SpaceType() != habitat_syntax.GroupSpaceType {
 		return tupleRow{}, false, nil
 	}
 
@@ -157,9 +204,3 @@ func parseTuple(uri habitat_syntax.SpaceRecordURI, value json.RawMessage) (tuple
 	}
 	return row, true, nil
 }
-
-// isDeleted reports whether an outbox message represents a deletion: sap
-// marshals a nil record value to JSON "null".
-func isDeleted(value json.RawMessage) bool {
-	return len(value) == 0 || string(value) == "null"
-}
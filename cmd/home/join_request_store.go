@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	habitat_syntax "github.com/habitat-network/habitat/internal/syntax"
+	"gorm.io/gorm/clause"
+)
+
+// joinRequestRow is one indexed network.habitat.group.joinRequest record: a
+// user's pending request to join a group. Keyed by the record URI so updates
+// and deletes are idempotent.
+type joinRequestRow struct {
+	RecordURI  string `gorm:"column:record_uri;primaryKey"`
+	GroupSpace string `gorm:"column:group_space;index"`
+	OrgDID     string `gorm:"column:org_did;index"`
+	SubjectDID string `gorm:"column:subject_did;index"`
+	Message    string `gorm:"column:message"`
+	CreatedAt  string `gorm:"column:created_at"`
+}
+
+func (joinRequestRow) TableName() string { return "group_join_requests" }
+
+// UpsertJoinRequest indexes a join request by its record URI. Requests outside
+// a group-space are ignored.
+func (s *Store) UpsertJoinRequest(
+	ctx context.Context,
+	recordURI habitat_syntax.SpaceRecordURI,
+	subject, message, createdAt string,
+) error {
+	space := recordURI.SpaceURI()
+	if space.SpaceType() != habitat_syntax.GroupSpaceType {
+		return nil
+	}
+	row := joinRequestRow{
+		RecordURI:  recordURI.String(),
+		GroupSpace: space.String(),
+		OrgDID:     space.SpaceOwner().String(),
+		SubjectDID: subject,
+		Message:    message,
+		CreatedAt:  createdAt,
+	}
+	return s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "record_uri"}},
+		UpdateAll: true,
+	}).Create(&row).Error
+}
+
+func (s *Store) DeleteJoinRequest(
+	ctx context.Context,
+	recordURI habitat_syntax.SpaceRecordURI,
+) error {
+	return s.db.WithContext(ctx).
+		Where("record_uri = ?", recordURI.String()).
+		Delete(&joinRequestRow{}).Error
+}
+
+// ListJoinRequests returns the pending requests to join space, oldest first.
+func (s *Store) ListJoinRequests(
+	ctx context.Context,
+	space habitat_syntax.SpaceURI,
+) ([]joinRequestRow, error) {
+	var rows []joinRequestRow
+	err := s.db.WithContext(ctx).
+		Where("group_space = ?", space.String()).
+		Order("created_at ASC, record_uri ASC").
+		Find(&rows).Error
+	return rows, err
+}
+
+// FindJoinRequests returns did's pending requests to join space.
+func (s *Store) FindJoinRequests(
+	ctx context.Context,
+	space habitat_syntax.SpaceURI,
+	did syntax.DID,
+) ([]joinRequestRow, error) {
+	var rows []joinRequestRow
+	err := s.db.WithContext(ctx).
+		Where("group_space = ? AND subject_did = ?", space.String(), did.String()).
+		Find(&rows).Error
+	return rows, err
+}
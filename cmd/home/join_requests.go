@@ -0,0 +1,296 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"github.com/habitat-network/habitat/api/habitat"
+	habitat_syntax "github.com/habitat-network/habitat/internal/syntax"
+)
+
+// Join policies of a group, set on its profile.
+const (
+	// joinPolicyOpen adds anyone who asks to join as a member straight away.
+	joinPolicyOpen = "open"
+	// joinPolicyRequest records a join request for a manager to resolve. It is
+	// the default, so groups stay closed unless their managers open them.
+	joinPolicyRequest = "request"
+)
+
+// Statuses of a requestToJoin call.
+const (
+	joinStatusJoined  = "joined"
+	joinStatusPending = "pending"
+)
+
+// ErrInvalidJoinPolicy indicates a createGroup/updateGroup call named an
+// unknown join policy.
+var ErrInvalidJoinPolicy = errors.New("joinPolicy must be 'open' or 'request'")
+
+// ErrAlreadyMember indicates a requestToJoin call by a member of the group.
+var ErrAlreadyMember = errors.New("caller is already a member of this group")
+
+// ErrJoinRequestNotFound indicates a resolveJoinRequest call named a user with
+// no pending request to join the group.
+var ErrJoinRequestNotFound = errors.New("user has no pending request to join this group")
+
+// parseJoinPolicy validates a joinPolicy input, defaulting to
+// joinPolicyRequest when it is empty.
+func parseJoinPolicy(policy string) (string, error) {
+	switch policy {
+	case "":
+		return joinPolicyRequest, nil
+	case joinPolicyOpen, joinPolicyRequest:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrInvalidJoinPolicy, policy)
+	}
+}
+
+// joinPolicyOf returns the join policy of an indexed group. Profiles written
+// before groups had one are request-only, as groups were then.
+func joinPolicyOf(row groupRow) string {
+	if row.JoinPolicy == joinPolicyOpen {
+		return joinPolicyOpen
+	}
+	return joinPolicyRequest
+}
+
+// RequestToJoin asks for the caller, who must be a member of the org, to join a
+// group. An open group grants the caller the writer role at once; a
+// request-only group gets a join request record, written into the group-space
+// as the org, for a manager to resolve.
+func (g *GroupService) RequestToJoin(
+	ctx context.Context,
+	org, caller syntax.DID,
+	in habitat.NetworkHabitatGroupsRequestToJoinInput,
+) (habitat.NetworkHabitatGroupsRequestToJoinOutput, error) {
+	space, err := habitat_syntax.ParseSpaceURI(in.Group)
+	if err != nil {
+		return habitat.NetworkHabitatGroupsRequestToJoinOutput{}, fmt.Errorf(
+			"parse group uri: %w",
+			err,
+		)
+	}
+	group, err := g.store.GetGroup(ctx, org, space)
+	if err != nil {
+		return habitat.NetworkHabitatGroupsRequestToJoinOutput{}, err
+	}
+
+	pear, err := g.orgPear(ctx, org)
+	if err != nil {
+		return habitat.NetworkHabitatGroupsRequestToJoinOutput{}, err
+	}
+	// Only the org's own members may join its groups, open or not.
+	inOrg, err := pear.isOrgMember(ctx, caller)
+	if err != nil {
+		return habitat.NetworkHabitatGroupsRequestToJoinOutput{}, err
+	}
+	if !inOrg {
+		return habitat.NetworkHabitatGroupsRequestToJoinOutput{}, fmt.Errorf(
+			"%w: caller is not a member of the org",
+			ErrForbidden,
+		)
+	}
+	member, err := pear.check(ctx, caller, "writer", space)
+	if err != nil {
+		return habitat.NetworkHabitatGroupsRequestToJoinOutput{}, err
+	}
+	if member {
+		return habitat.NetworkHabitatGroupsRequestToJoinOutput{}, ErrAlreadyMember
+	}
+	pending, err := g.store.FindJoinRequests(ctx, space, caller)
+	if err != nil {
+		return habitat.NetworkHabitatGroupsRequestToJoinOutput{}, err
+	}
+
+	if joinPolicyOf(group) == joinPolicyOpen {
+		tupleURI, err := g.admit(ctx, pear, space, caller)
+		if err != nil {
+			return habitat.NetworkHabitatGroupsRequestToJoinOutput{}, err
+		}
+		// Requests left from before the group was opened are moot now.
+		if err := g.deleteJoinRequests(ctx, pear, pending); err != nil {
+			return habitat.NetworkHabitatGroupsRequestToJoinOutput{}, err
+		}
+		return habitat.NetworkHabitatGroupsRequestToJoinOutput{
+			Status: joinStatusJoined,
+			Uri:    tupleURI.String(),
+		}, nil
+	}
+
+	if len(pending) > 0 {
+		return habitat.NetworkHabitatGroupsRequestToJoinOutput{
+			Status: joinStatusPending,
+			Uri:    pending[0].RecordURI,
+		}, nil
+	}
+	createdAt := time.Now().UTC().Format(time.RFC3339)
+	requestURI, err := pear.putJoinRequest(ctx, space, habitat.NetworkHabitatGroupJoinRequest{
+		Subject:   caller.String(),
+		Message:   in.Message,
+		CreatedAt: createdAt,
+	})
+	if err != nil {
+		return habitat.NetworkHabitatGroupsRequestToJoinOutput{}, err
+	}
+	// Prime the index so managers see the request immediately; sap will
+	// reconcile the same row when the record syncs.
+	if err := g.store.UpsertJoinRequest(
+		ctx,
+		requestURI,
+		caller.String(),
+		in.Message,
+		createdAt,
+	); err != nil {
+		slog.WarnContext(ctx, "index join request", "uri", requestURI, "err", err)
+	}
+
+	return habitat.NetworkHabitatGroupsRequestToJoinOutput{
+		Status: joinStatusPending,
+		Uri:    requestURI.String(),
+	}, nil
+}
+
+// ListJoinRequests lists a group's pending join requests for its managers.
+func (g *GroupService) ListJoinRequests(
+	ctx context.Context,
+	org, caller syntax.DID,
+	groupURI string,
+) (habitat.NetworkHabitatGroupsListJoinRequestsOutput, error) {
+	space, err := habitat_syntax.ParseSpaceURI(groupURI)
+	if err != nil {
+		return habitat.NetworkHabitatGroupsListJoinRequestsOutput{}, fmt.Errorf(
+			"parse group uri: %w",
+			err,
+		)
+	}
+	if _, err := g.store.GetGroup(ctx, org, space); err != nil {
+		return habitat.NetworkHabitatGroupsListJoinRequestsOutput{}, err
+	}
+	pear, err := g.orgPear(ctx, org)
+	if err != nil {
+		return habitat.NetworkHabitatGroupsListJoinRequestsOutput{}, err
+	}
+	if err := g.requireManage(ctx, pear, caller, space); err != nil {
+		return habitat.NetworkHabitatGroupsListJoinRequestsOutput{}, err
+	}
+
+	rows, err := g.store.ListJoinRequests(ctx, space)
+	if err != nil {
+		return habitat.NetworkHabitatGroupsListJoinRequestsOutput{}, err
+	}
+	out := habitat.NetworkHabitatGroupsListJoinRequestsOutput{
+		Requests: []habitat.NetworkHabitatGroupsDefsJoinRequestView{},
+	}
+	for _, row := range rows {
+		out.Requests = append(out.Requests, habitat.NetworkHabitatGroupsDefsJoinRequestView{
+			Uri:       row.RecordURI,
+			Subject:   row.SubjectDID,
+			Message:   row.Message,
+			CreatedAt: row.CreatedAt,
+		})
+	}
+	return out, nil
+}
+
+// ResolveJoinRequest approves or rejects a user's pending request to join a
+// group. Approving grants them the writer role through pear; either way their
+// request records are deleted.
+func (g *GroupService) ResolveJoinRequest(
+	ctx context.Context,
+	org, caller syntax.DID,
+	in habitat.NetworkHabitatGroupsResolveJoinRequestInput,
+) (habitat.NetworkHabitatGroupsResolveJoinRequestOutput, error) {
+	space, err := habitat_syntax.ParseSpaceURI(in.Group)
+	if err != nil {
+		return habitat.NetworkHabitatGroupsResolveJoinRequestOutput{}, fmt.Errorf(
+			"parse group uri: %w",
+			err,
+		)
+	}
+	subject, err := syntax.ParseDID(in.Subject)
+	if err != nil {
+		return habitat.NetworkHabitatGroupsResolveJoinRequestOutput{}, fmt.Errorf(
+			"parse subject did: %w",
+			err,
+		)
+	}
+	if _, err := g.store.GetGroup(ctx, org, space); err != nil {
+		return habitat.NetworkHabitatGroupsResolveJoinRequestOutput{}, err
+	}
+	pear, err := g.orgPear(ctx, org)
+	if err != nil {
+		return habitat.NetworkHabitatGroupsResolveJoinRequestOutput{}, err
+	}
+	if err := g.requireManage(ctx, pear, caller, space); err != nil {
+		return habitat.NetworkHabitatGroupsResolveJoinRequestOutput{}, err
+	}
+
+	pending, err := g.store.FindJoinRequests(ctx, space, subject)
+	if err != nil {
+		return habitat.NetworkHabitatGroupsResolveJoinRequestOutput{}, err
+	}
+	if len(pending) == 0 {
+		return habitat.NetworkHabitatGroupsResolveJoinRequestOutput{}, ErrJoinRequestNotFound
+	}
+	out := habitat.NetworkHabitatGroupsResolveJoinRequestOutput{}
+	if in.Approve {
+		tupleURI, err := g.admit(ctx, pear, space, subject)
+		if err != nil {
+			return habitat.NetworkHabitatGroupsResolveJoinRequestOutput{}, err
+		}
+		out.Uri = tupleURI.String()
+	}
+	if err := g.deleteJoinRequests(ctx, pear, pending); err != nil {
+		return habitat.NetworkHabitatGroupsResolveJoinRequestOutput{}, err
+	}
+	return out, nil
+}
+
+// admit grants did the writer role on space, making them a member, as
+// AddMember does for a user subject.
+func (g *GroupService) admit(
+	ctx context.Context,
+	pear *pearClient,
+	space habitat_syntax.SpaceURI,
+	did syntax.DID,
+) (habitat_syntax.SpaceRecordURI, error) {
+	tupleURI, err := pear.writeUserTuple(ctx, did, "writer", space)
+	if err != nil {
+		return "", err
+	}
+	if err := g.store.UpsertTuple(ctx, tupleRow{
+		RecordURI:   tupleURI.String(),
+		ObjectSpace: space.String(),
+		Relation:    "writer",
+		SubjectKind: "user",
+		SubjectDID:  did.String(),
+	}); err != nil {
+		slog.WarnContext(ctx, "index admitted member", "uri", tupleURI, "err", err)
+	}
+	return tupleURI, nil
+}
+
+// deleteJoinRequests deletes resolved join request records through pear and
+// prunes them from the index.
+func (g *GroupService) deleteJoinRequests(
+	ctx context.Context,
+	pear *pearClient,
+	requests []joinRequestRow,
+) error {
+	for _, request := range requests {
+		uri := habitat_syntax.SpaceRecordURI(request.RecordURI)
+		if err := pear.deleteRecord(ctx, uri); err != nil {
+			return err
+		}
+		if err := g.store.DeleteJoinRequest(ctx, uri); err != nil {
+			slog.WarnContext(ctx, "prune join request", "uri", uri, "err", err)
+		}
+	}
+	return nil
+}
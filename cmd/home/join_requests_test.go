@@ -0,0 +1,309 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bluesky-social/indigo/atproto/atcrypto"
+	"github.com/bluesky-social/indigo/atproto/auth/oauth"
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"github.com/habitat-network/habitat/api/habitat"
+	habitat_syntax "github.com/habitat-network/habitat/internal/syntax"
+	"github.com/habitat-network/habitat/pkg/sap/outbox"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseJoinPolicy(t *testing.T) {
+	for in, want := range map[string]string{
+		"":        joinPolicyRequest,
+		"open":    joinPolicyOpen,
+		"request": joinPolicyRequest,
+	} {
+		got, err := parseJoinPolicy(in)
+		require.NoError(t, err)
+		require.Equal(t, want, got)
+	}
+	_, err := parseJoinPolicy("invite-only")
+	require.ErrorIs(t, err, ErrInvalidJoinPolicy)
+
+	require.Equal(t, joinPolicyRequest, joinPolicyOf(groupRow{}))
+	require.Equal(t, joinPolicyOpen, joinPolicyOf(groupRow{JoinPolicy: joinPolicyOpen}))
+}
+
+func TestJoinRequests_IndexedPerGroup(t *testing.T) {
+	ctx := context.Background()
+	store := setupStore(t)
+
+	board := habitat_syntax.SpaceURI("at://did:web:org/space/network.habitat.group/board")
+	staff := habitat_syntax.SpaceURI("at://did:web:org/space/network.habitat.group/staff")
+	clock := syntax.NewTIDClock(0)
+	request := func(space habitat_syntax.SpaceURI) habitat_syntax.SpaceRecordURI {
+		return recordURI(space.String(), "did:web:org", collectionJoinRequest,
+			clock.Next().String())
+	}
+	alice, bob := request(board), request(board)
+	require.NoError(t, store.UpsertJoinRequest(ctx, bob, "did:web:bob", "",
+		"2026-01-02T00:00:00Z"))
+	require.NoError(t, store.UpsertJoinRequest(ctx, alice, "did:web:alice", "hi",
+		"2026-01-01T00:00:00Z"))
+	require.NoError(t, store.UpsertJoinRequest(ctx, request(staff), "did:web:alice", "",
+		"2026-01-01T00:00:00Z"))
+	// Join requests outside a group-space are not indexed.
+	require.NoError(t, store.UpsertJoinRequest(ctx, request(space1), "did:web:alice", "",
+		"2026-01-01T00:00:00Z"))
+
+	rows, err := store.ListJoinRequests(ctx, board)
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	require.Equal(t, alice.String(), rows[0].RecordURI, "oldest first")
+	require.Equal(t, "hi", rows[0].Message)
+	require.Equal(t, orgDID.String(), rows[0].OrgDID)
+	require.Equal(t, "did:web:bob", rows[1].SubjectDID)
+
+	mine, err := store.FindJoinRequests(ctx, board, "did:web:alice")
+	require.NoError(t, err)
+	require.Len(t, mine, 1)
+
+	require.NoError(t, store.DeleteJoinRequest(ctx, alice))
+	mine, err = store.FindJoinRequests(ctx, board, "did:web:alice")
+	require.NoError(t, err)
+	require.Empty(t, mine)
+	rows, err = store.ListJoinRequests(ctx, space1)
+	require.NoError(t, err)
+	require.Empty(t, rows)
+}
+
+func TestJoinRequests_ResyncDropsStaleRequests(t *testing.T) {
+	ctx := context.Background()
+	store := setupStore(t)
+
+	board := "at://did:web:org/space/network.habitat.group/board"
+	clock := syntax.NewTIDClock(0)
+	oldRev, snapshotRev := clock.Next(), clock.Next()
+	uri := recordURI(board, "did:web:org", collectionJoinRequest, oldRev.String())
+	require.NoError(t, store.UpsertRecord(ctx, uri, oldRev, nil))
+	require.NoError(t, store.UpsertJoinRequest(ctx, uri, "did:web:alice", "", ""))
+
+	require.NoError(t, store.ResyncRepo(ctx, habitat_syntax.SpaceURI(board), "did:web:org",
+		snapshotRev))
+	rows, err := store.ListJoinRequests(ctx, habitat_syntax.SpaceURI(board))
+	require.NoError(t, err)
+	require.Empty(t, rows)
+}
+
+// fakePear serves the pear endpoints the group service calls as the org,
+// recording the writes it is asked to make.
+type fakePear struct {
+	mu       sync.Mutex
+	members  []syntax.DID
+	roles    map[string]bool // "<did> <relation>" held on every space
+	granted  []syntax.DID
+	requests []habitat.NetworkHabitatGroupJoinRequest
+	deleted  []string
+}
+
+func (f *fakePear) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	q := r.URL.Query()
+	var out any
+	switch strings.TrimPrefix(r.URL.Path, "/xrpc/") {
+	case "network.habitat.org.getMembers":
+		members := habitat.NetworkHabitatOrgGetMembersOutput{}
+		for _, did := range f.members {
+			members.Members = append(members.Members,
+				habitat.NetworkHabitatOrgGetMembersMember{Did: did.String()})
+		}
+		out = members
+	case "network.habitat.relationship.checkUserRelation":
+		out = habitat.NetworkHabitatRelationshipCheckUserRelationOutput{
+			Allowed: f.roles[q.Get("subject")+" "+q.Get("relation")],
+		}
+	case "network.habitat.relationship.setUserRelation":
+		var in habitat.NetworkHabitatRelationshipSetUserRelationInput
+		_ = json.NewDecoder(r.Body).Decode(&in)
+		f.roles[in.Subject+" "+in.Relation] = true
+		f.granted = append(f.granted, syntax.DID(in.Subject))
+		out = habitat.NetworkHabitatRelationshipSetUserRelationOutput{
+			Uri: recordURI(in.Space, orgDID.String(), collectionTuple, "3jzfcijpj2z2b").String(),
+		}
+	case "network.habitat.space.putRecord":
+		var in struct {
+			habitat.NetworkHabitatSpacePutRecordInput
+			Record habitat.NetworkHabitatGroupJoinRequest `json:"record"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&in)
+		f.requests = append(f.requests, in.Record)
+		out = habitat.NetworkHabitatSpacePutRecordOutput{
+			Uri: recordURI(in.Space, in.Repo, in.Collection, in.Rkey).String(),
+		}
+	case "network.habitat.space.deleteRecord":
+		var in habitat.NetworkHabitatSpaceDeleteRecordInput
+		_ = json.NewDecoder(r.Body).Decode(&in)
+		f.deleted = append(f.deleted, in.Rkey)
+		out = struct{}{}
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(out)
+}
+
+// setupGroupService returns a GroupService whose saved session for orgDID
+// calls pear on a fake pear server.
+func setupGroupService(t *testing.T, pear *fakePear) (*GroupService, *Store) {
+	t.Helper()
+	ctx := context.Background()
+	srv := httptest.NewServer(pear)
+	t.Cleanup(srv.Close)
+
+	key, err := atcrypto.GeneratePrivateKeyP256()
+	require.NoError(t, err)
+	sessions := oauth.NewMemStore()
+	require.NoError(t, sessions.SaveSession(ctx, oauth.ClientSessionData{
+		AccountDID:              orgDID,
+		SessionID:               "s1",
+		HostURL:                 srv.URL,
+		AccessToken:             "token",
+		DPoPPrivateKeyMultibase: key.Multibase(),
+	}))
+	config := oauth.NewPublicConfig("https://home.example/client-metadata.json",
+		"https://home.example/oauth-callback", []string{})
+
+	store := setupStore(t)
+	require.NoError(t, store.SaveOrgSession(ctx, orgDID, "org.pear.example", "s1"))
+	return NewGroupService(store, oauth.NewClientApp(&config, sessions)), store
+}
+
+// indexGroup indexes a group-space of orgDID with the given join policy.
+func indexGroup(t *testing.T, store *Store, rkey, joinPolicy string) habitat_syntax.SpaceURI {
+	t.Helper()
+	space := "at://did:web:org/space/network.habitat.group/" + rkey
+	require.NoError(t, store.UpsertProfile(context.Background(),
+		recordURI(space, orgDID.String(), collectionGroupProfile, "self"),
+		rkey, "", "2026-01-01T00:00:00Z", joinPolicy))
+	return habitat_syntax.SpaceURI(space)
+}
+
+func TestRequestToJoin(t *testing.T) {
+	ctx := context.Background()
+	alice, mallory := syntax.DID("did:web:alice"), syntax.DID("did:web:mallory")
+	pear := &fakePear{members: []syntax.DID{alice}, roles: map[string]bool{}}
+	groups, store := setupGroupService(t, pear)
+	board := indexGroup(t, store, "board", joinPolicyOpen)
+	staff := indexGroup(t, store, "staff", joinPolicyRequest)
+
+	// Only members of the org may join its groups, whatever the policy.
+	for _, space := range []habitat_syntax.SpaceURI{board, staff} {
+		_, err := groups.RequestToJoin(ctx, orgDID, mallory,
+			habitat.NetworkHabitatGroupsRequestToJoinInput{Group: space.String()})
+		require.ErrorIs(t, err, ErrForbidden)
+	}
+	require.Empty(t, pear.granted)
+	require.Empty(t, pear.requests)
+
+	out, err := groups.RequestToJoin(ctx, orgDID, alice,
+		habitat.NetworkHabitatGroupsRequestToJoinInput{Group: board.String()})
+	require.NoError(t, err)
+	require.Equal(t, joinStatusJoined, out.Status)
+	require.Equal(t, []syntax.DID{alice}, pear.granted)
+	_, err = groups.RequestToJoin(ctx, orgDID, alice,
+		habitat.NetworkHabitatGroupsRequestToJoinInput{Group: board.String()})
+	require.ErrorIs(t, err, ErrAlreadyMember)
+
+	delete(pear.roles, alice.String()+" writer")
+	out, err = groups.RequestToJoin(ctx, orgDID, alice,
+		habitat.NetworkHabitatGroupsRequestToJoinInput{Group: staff.String(), Message: "hi"})
+	require.NoError(t, err)
+	require.Equal(t, joinStatusPending, out.Status)
+	require.Len(t, pear.requests, 1)
+	require.Equal(t, alice.String(), pear.requests[0].Subject)
+	require.Equal(t, "hi", pear.requests[0].Message)
+
+	// Asking again returns the pending request rather than writing another.
+	again, err := groups.RequestToJoin(ctx, orgDID, alice,
+		habitat.NetworkHabitatGroupsRequestToJoinInput{Group: staff.String()})
+	require.NoError(t, err)
+	require.Equal(t, out, again)
+	require.Len(t, pear.requests, 1)
+}
+
+func TestResolveJoinRequest(t *testing.T) {
+	ctx := context.Background()
+	alice, bob := syntax.DID("did:web:alice"), syntax.DID("did:web:bob")
+	manager := syntax.DID("did:web:manager")
+	pear := &fakePear{
+		members: []syntax.DID{alice, bob, manager},
+		roles:   map[string]bool{manager.String() + " manager": true},
+	}
+	groups, store := setupGroupService(t, pear)
+	staff := indexGroup(t, store, "staff", joinPolicyRequest)
+	for _, did := range []syntax.DID{alice, bob} {
+		_, err := groups.RequestToJoin(ctx, orgDID, did,
+			habitat.NetworkHabitatGroupsRequestToJoinInput{Group: staff.String()})
+		require.NoError(t, err)
+	}
+
+	listed, err := groups.ListJoinRequests(ctx, orgDID, manager, staff.String())
+	require.NoError(t, err)
+	require.Len(t, listed.Requests, 2)
+	_, err = groups.ResolveJoinRequest(ctx, orgDID, alice,
+		habitat.NetworkHabitatGroupsResolveJoinRequestInput{
+			Group: staff.String(), Subject: bob.String(), Approve: true,
+		})
+	require.ErrorIs(t, err, ErrForbidden, "only managers resolve requests")
+
+	approved, err := groups.ResolveJoinRequest(ctx, orgDID, manager,
+		habitat.NetworkHabitatGroupsResolveJoinRequestInput{
+			Group: staff.String(), Subject: alice.String(), Approve: true,
+		})
+	require.NoError(t, err)
+	require.NotEmpty(t, approved.Uri)
+	require.Equal(t, []syntax.DID{alice}, pear.granted)
+
+	denied, err := groups.ResolveJoinRequest(ctx, orgDID, manager,
+		habitat.NetworkHabitatGroupsResolveJoinRequestInput{
+			Group: staff.String(), Subject: bob.String(),
+		})
+	require.NoError(t, err)
+	require.Empty(t, denied.Uri)
+	require.Equal(t, []syntax.DID{alice}, pear.granted, "a denied user is not admitted")
+	require.Len(t, pear.deleted, 2)
+
+	listed, err = groups.ListJoinRequests(ctx, orgDID, manager, staff.String())
+	require.NoError(t, err)
+	require.Empty(t, listed.Requests)
+	_, err = groups.ResolveJoinRequest(ctx, orgDID, manager,
+		habitat.NetworkHabitatGroupsResolveJoinRequestInput{
+			Group: staff.String(), Subject: bob.String(),
+		})
+	require.ErrorIs(t, err, ErrJoinRequestNotFound)
+}
+
+func TestIndexJoinRequest_OnlyFromOrgRepo(t *testing.T) {
+	ctx := context.Background()
+	store := setupStore(t)
+	ix := NewIndexer(store, nil)
+
+	board := "at://did:web:org/space/network.habitat.group/board"
+	clock := syntax.NewTIDClock(0)
+	value := json.RawMessage(`{"subject":"did:web:alice","createdAt":"2026-01-01T00:00:00Z"}`)
+	for _, repo := range []string{"did:web:mallory", orgDID.String()} {
+		require.NoError(t, ix.handle(ctx, outbox.Message{
+			Action: outbox.ActionCreate,
+			URI:    recordURI(board, repo, collectionJoinRequest, clock.Next().String()),
+			Rev:    seedRev,
+			Value:  value,
+		}))
+	}
+
+	rows, err := store.ListJoinRequests(ctx, habitat_syntax.SpaceURI(board))
+	require.NoError(t, err)
+	require.Len(t, rows, 1, "a join request written by a member is ignored")
+	require.Equal(t, orgDID, habitat_syntax.SpaceRecordURI(rows[0].RecordURI).Repo())
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
@@ -83,9 +84,10 @@ func run(ctx context.Context, cmd *cli.Command) error {
 	oauthApp := oauth.NewClientApp(&config, oauthStore)
 
 	s, err := sap.New(sap.Config{
-		DB:          gormDB,
-		Directory:   dir,
-		OAuthClient: oauthApp,
+		DB:                gormDB,
+		Directory:         dir,
+		OAuthClient:       oauthApp,
+		OutboxMaxAttempts: cmd.Int(fOutboxMaxAttempts),
 	})
 	if err != nil {
 		return fmt.Errorf("create sap: %w", err)
@@ -95,6 +97,12 @@ func run(ctx context.Context, cmd *cli.Command) error {
 	if err != nil {
 		return fmt.Errorf("create store: %w", err)
 	}
+	if err := store.BackfillOrgs(ctx); err != nil {
+		return fmt.Errorf("backfill group orgs: %w", err)
+	}
+	if err := store.RebuildMemberships(ctx); err != nil {
+		return fmt.Errorf("rebuild group memberships: %w", err)
+	}
 
 	groups := NewGroupService(store, oauthApp)
 	collections := NewCollectionService(store, oauthApp)
@@ -102,6 +110,8 @@ func run(ctx context.Context, cmd *cli.Command) error {
 	server := NewServer(
 		domain,
 		cmd.String(fOrgHandle),
+		newOrgRouter(store, oauthApp, dir),
+		dir,
 		groups,
 		collections,
 		oauthApp,
@@ -116,16 +126,27 @@ func run(ctx context.Context, cmd *cli.Command) error {
 
 	mux := http.NewServeMux()
 	server.Routes(mux)
+	// Enrolling orgs is served on a separate internal port, like cmd/sap's
+	// org endpoints, so access can be restricted to operators.
+	internalMux := http.NewServeMux()
+	server.InternalRoutes(internalMux)
 
 	addr := ":" + cmd.String(fPort)
 	srv := &http.Server{Addr: addr, Handler: mux}
+	internalAddr := ":" + cmd.String(fInternalPort)
+	internalSrv := &http.Server{Addr: internalAddr, Handler: internalMux}
 
-	if _, _, err := store.OrgSession(ctx); err != nil {
+	orgs, err := store.ListOrgSessions(ctx)
+	if err != nil {
+		return fmt.Errorf("list orgs: %w", err)
+	}
+	if len(orgs) == 0 {
 		slog.WarnContext(
 			ctx,
-			"home server not yet authorized for an org; visit /oauth/login to grant the org credential",
-			"loginURL",
-			"https://"+domain+"/oauth/login",
+			"home server not yet authorized for an org; "+
+				"visit /oauth/login on the internal port to grant the org credential",
+			"internal_addr",
+			internalAddr,
 		)
 	}
 
@@ -134,15 +155,18 @@ func run(ctx context.Context, cmd *cli.Command) error {
 	eg.Go(func() error { return indexer.Run(egCtx) })
 	eg.Go(func() error {
 		slog.InfoContext(egCtx, "home server listening", "addr", addr, "did", "did:web:"+domain)
-		go func() {
-			if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-				slog.ErrorContext(egCtx, "server error", "err", err)
-			}
-		}()
+		slog.InfoContext(egCtx, "home internal server listening", "addr", internalAddr)
+		for _, server := range []*http.Server{srv, internalSrv} {
+			go func() {
+				if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+					slog.ErrorContext(egCtx, "server error", "addr", server.Addr, "err", err)
+				}
+			}()
+		}
 		<-egCtx.Done()
 		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 		defer cancel()
-		return srv.Shutdown(shutdownCtx)
+		return errors.Join(srv.Shutdown(shutdownCtx), internalSrv.Shutdown(shutdownCtx))
 	})
 
 	return eg.Wait()
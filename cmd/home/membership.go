@@ -7,6 +7,8 @@ package main
 // those tuples — indexed from sap — into concrete member sets, following the
 // built-in role implication owner > manager > writer > reader.
 
+import "slices"
+
 type role int
 
 const (
@@ -153,3 +155,128 @@ func (g *graph) inheritedGroups(space string) []string {
 	}
 	return groups
 }
+
+// rolesAtLeast lists the roles that imply min, for filtering stored role names.
+func rolesAtLeast(min role) []string {
+	var roles []string
+	for _, r := range []string{"owner", "manager", "writer", "reader"} {
+		if parseRole(r) >= min {
+			roles = append(roles, r)
+		}
+	}
+	return roles
+}
+
+// memberships returns every user holding any role on space, each with the
+// highest role they hold. Among grants of that role a direct one is preferred
+// over an inherited one, as in holders.
+func (g *graph) memberships(space string) []member {
+	out := map[string]member{}
+	for _, r := range []role{roleOwner, roleManager, roleWriter, roleReader} {
+		for _, m := range g.holders(space, r) {
+			if _, ok := out[m.DID]; !ok {
+				out[m.DID] = m
+			}
+		}
+	}
+	members := make([]member, 0, len(out))
+	for _, m := range out {
+		members = append(members, m)
+	}
+	return members
+}
+
+// inherits reports whether space inherits members from target, directly or
+// through other groups, whatever the roles involved.
+func (g *graph) inherits(space, target string) bool {
+	visited := map[string]bool{}
+	var visit func(string) bool
+	visit = func(space string) bool {
+		if visited[space] {
+			return false
+		}
+		visited[space] = true
+		for _, sub := range g.inheritedGroups(space) {
+			if sub == target || visit(sub) {
+				return true
+			}
+		}
+		return false
+	}
+	return visit(space)
+}
+
+// cycle returns a cycle of inherited groups reachable from space, as the
+// group-spaces along it with the first repeated last, or nil if there is
+// none. holders stops at a group already being expanded, so a cycle does not
+// break resolution, but it is almost always a mistake worth reporting.
+func (g *graph) cycle(space string) []string {
+	var stack []string
+	onStack := map[string]bool{}
+	done := map[string]bool{}
+	var visit func(string) []string
+	visit = func(space string) []string {
+		if onStack[space] {
+			start := slices.Index(stack, space)
+			return append(slices.Clone(stack[start:]), space)
+		}
+		if done[space] {
+			return nil
+		}
+		stack = append(stack, space)
+		onStack[space] = true
+		for _, sub := range g.inheritedGroups(space) {
+			if c := visit(sub); c != nil {
+				return c
+			}
+		}
+		stack = stack[:len(stack)-1]
+		onStack[space] = false
+		done[space] = true
+		return nil
+	}
+	return visit(space)
+}
+
+// path returns the shortest chain of groups through which did holds at least
+// minRole on space: the group did is granted a role on directly first and
+// space last. It returns nil when did holds no such role.
+func (g *graph) path(space, did string, minRole role) []string {
+	type step struct {
+		space   string
+		minRole role
+	}
+	parent := map[step]*step{}
+	start := step{space, minRole}
+	visited := map[step]bool{start: true}
+	queue := []step{start}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for _, t := range g.byObject[cur.space] {
+			if parseRole(t.Relation) < cur.minRole {
+				continue
+			}
+			switch t.SubjectKind {
+			case "user":
+				if t.SubjectDID != did {
+					continue
+				}
+				path := []string{cur.space}
+				for p := parent[cur]; p != nil; p = parent[*p] {
+					path = append(path, p.space)
+				}
+				return path
+			case "group":
+				next := step{t.SubjectGroup, parseRole(t.SubjectRole)}
+				if visited[next] {
+					continue
+				}
+				visited[next] = true
+				parent[next] = &cur
+				queue = append(queue, next)
+			}
+		}
+	}
+	return nil
+}
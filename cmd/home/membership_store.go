@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"gorm.io/gorm"
+)
+
+// membershipRow is one materialized membership: the highest role a user holds
+// on a group-space, directly or through inherited groups. The table is kept in
+// step with group_tuples as tuples are indexed, so reads need not expand the
+// tuple graph.
+type membershipRow struct {
+	GroupSpace string `gorm:"column:group_space;primaryKey"`
+	MemberDID  string `gorm:"column:member_did;primaryKey;index"`
+	OrgDID     string `gorm:"column:org_did;index"`
+	Role       string `gorm:"column:role"`
+	Direct     bool   `gorm:"column:direct"`
+	// ViaGroup is set when the membership is inherited: the group-space the
+	// role came through.
+	ViaGroup string `gorm:"column:via_group"`
+}
+
+func (membershipRow) TableName() string { return "group_memberships" }
+
+// membershipBatchSize is how many membership rows are inserted per statement.
+const membershipBatchSize = 200
+
+// RebuildMemberships recomputes every materialized membership from the
+// indexed tuples, reporting any cycles of inherited groups. It runs at
+// startup, so memberships indexed by an older release are caught up.
+func (s *Store) RebuildMemberships(ctx context.Context) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var tuples []tupleRow
+		if err := tx.Find(&tuples).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("1 = 1").Delete(&membershipRow{}).Error; err != nil {
+			return err
+		}
+		g := newGraph(tuples)
+		spaces := make([]string, 0, len(g.byObject))
+		for space := range g.byObject {
+			spaces = append(spaces, space)
+		}
+		slices.Sort(spaces)
+		reportCycles(ctx, g, spaces)
+		return writeMemberships(tx, g, spaces)
+	})
+}
+
+// refreshMemberships recomputes the memberships of the given group-spaces,
+// whose tuples changed, and of every group that inherits from them. Only the
+// tuples of those groups and the groups they inherit from are read.
+func refreshMemberships(ctx context.Context, tx *gorm.DB, changed []string) error {
+	affected, err := inheritingGroups(tx, changed)
+	if err != nil {
+		return err
+	}
+	tuples, err := inheritedTuples(tx, affected)
+	if err != nil {
+		return err
+	}
+	g := newGraph(tuples)
+	reportCycles(ctx, g, changed)
+	if err := tx.Where("group_space IN ?", affected).Delete(&membershipRow{}).Error; err != nil {
+		return err
+	}
+	return writeMemberships(tx, g, affected)
+}
+
+// writeMemberships inserts the memberships g resolves for spaces.
+func writeMemberships(tx *gorm.DB, g *graph, spaces []string) error {
+	var rows []membershipRow
+	for _, space := range spaces {
+		for _, m := range g.memberships(space) {
+			rows = append(rows, membershipRow{
+				GroupSpace: space,
+				MemberDID:  m.DID,
+				OrgDID:     spaceOrg(space),
+				Role:       m.Role,
+				Direct:     m.Direct,
+				ViaGroup:   m.ViaGroup,
+			})
+		}
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	return tx.CreateInBatches(rows, membershipBatchSize).Error
+}
+
+// reportCycles logs each distinct cycle of inherited groups reachable from
+// spaces.
+func reportCycles(ctx context.Context, g *graph, spaces []string) {
+	seen := map[string]bool{}
+	for _, space := range spaces {
+		cycle := g.cycle(space)
+		if cycle == nil {
+			continue
+		}
+		// The same cycle is found from each group on it, each time starting
+		// at a different one, so it is recognized by its set of groups.
+		groups := slices.Clone(cycle[:len(cycle)-1])
+		slices.Sort(groups)
+		key := strings.Join(groups, " ")
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		slog.WarnContext(ctx, "group membership cycle", "groups", cycle)
+	}
+}
+
+// inheritingGroups returns spaces and every group-space that inherits members
+// from one of them, directly or transitively.
+func inheritingGroups(tx *gorm.DB, spaces []string) ([]string, error) {
+	seen := map[string]bool{}
+	var out []string
+	frontier := spaces
+	for len(frontier) > 0 {
+		var next []string
+		for _, space := range frontier {
+			if space != "" && !seen[space] {
+				seen[space] = true
+				out = append(out, space)
+				next = append(next, space)
+			}
+		}
+		if len(next) == 0 {
+			break
+		}
+		frontier = nil
+		if err := tx.Model(&tupleRow{}).
+			Where("subject_kind = ? AND subject_group IN ?", "group", next).
+			Distinct().
+			Pluck("object_space", &frontier).Error; err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// inheritedTuples returns the tuples granting roles on spaces and on every
+// group-space they inherit members from: all a graph needs to resolve the
+// members of spaces.
+func inheritedTuples(tx *gorm.DB, spaces []string) ([]tupleRow, error) {
+	seen := map[string]bool{}
+	var out []tupleRow
+	frontier := spaces
+	for len(frontier) > 0 {
+		var next []string
+		for _, space := range frontier {
+			if !seen[space] {
+				seen[space] = true
+				next = append(next, space)
+			}
+		}
+		if len(next) == 0 {
+			break
+		}
+		var rows []tupleRow
+		if err := tx.Where("object_space IN ?", next).Find(&rows).Error; err != nil {
+			return nil, err
+		}
+		frontier = nil
+		for _, t := range rows {
+			if t.SubjectKind == "group" {
+				frontier = append(frontier, t.SubjectGroup)
+			}
+		}
+		out = append(out, rows...)
+	}
+	return out, nil
+}
+
+// InheritedTuples returns the tuples granting roles on space and on every
+// group-space it inherits members from.
+func (s *Store) InheritedTuples(ctx context.Context, space string) ([]tupleRow, error) {
+	return inheritedTuples(s.db.WithContext(ctx), []string{space})
+}
+
+// ListMemberships returns the groups of org did holds at least minRole on,
+// directly or through inherited groups.
+func (s *Store) ListMemberships(
+	ctx context.Context,
+	org syntax.DID,
+	did string,
+	minRole role,
+) ([]membershipRow, error) {
+	var rows []membershipRow
+	err := s.db.WithContext(ctx).
+		Where("org_did = ? AND member_did = ? AND role IN ?",
+			org.String(), did, rolesAtLeast(minRole)).
+		Order("group_space ASC").
+		Find(&rows).Error
+	return rows, err
+}
+
+// ListGroupMembers returns the users holding at least minRole on space.
+func (s *Store) ListGroupMembers(
+	ctx context.Context,
+	space string,
+	minRole role,
+) ([]membershipRow, error) {
+	var rows []membershipRow
+	err := s.db.WithContext(ctx).
+		Where("group_space = ? AND role IN ?", space, rolesAtLeast(minRole)).
+		Order("member_did ASC").
+		Find(&rows).Error
+	return rows, err
+}
+
+// CountGroupMembers returns, per group-space, the number of users holding at
+// least minRole on it. Groups without any are absent.
+func (s *Store) CountGroupMembers(
+	ctx context.Context,
+	spaces []string,
+	minRole role,
+) (map[string]int64, error) {
+	counts := make(map[string]int64, len(spaces))
+	if len(spaces) == 0 {
+		return counts, nil
+	}
+	var rows []struct {
+		GroupSpace string
+		Count      int64
+	}
+	err := s.db.WithContext(ctx).
+		Model(&membershipRow{}).
+		Select("group_space, COUNT(*) AS count").
+		Where("group_space IN ? AND role IN ?", spaces, rolesAtLeast(minRole)).
+		Group("group_space").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		counts[row.GroupSpace] = row.Count
+	}
+	return counts, nil
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	habitat_syntax "github.com/habitat-network/habitat/internal/syntax"
+	"github.com/stretchr/testify/require"
+)
+
+func membershipGroups(t *testing.T, store *Store, did string) []string {
+	t.Helper()
+	// The groups these tests name are bare keys, not space URIs, so they
+	// belong to no org.
+	rows, err := store.ListMemberships(context.Background(), "", did, memberMinRole)
+	require.NoError(t, err)
+	groups := make([]string, len(rows))
+	for i, row := range rows {
+		groups[i] = row.GroupSpace
+	}
+	return groups
+}
+
+func TestMemberships_FollowTupleChanges(t *testing.T) {
+	ctx := context.Background()
+	store := setupStore(t)
+
+	// groupA inherits groupB, which inherits groupC; alice writes groupC.
+	require.NoError(t, store.UpsertTuple(ctx, groupTuple("groupA", "groupB", "writer", "writer")))
+	require.NoError(t, store.UpsertTuple(ctx, userTuple("groupC", "did:web:alice", "writer")))
+	require.Equal(t, []string{"groupC"}, membershipGroups(t, store, "did:web:alice"))
+
+	link := groupTuple("groupB", "groupC", "writer", "writer")
+	require.NoError(t, store.UpsertTuple(ctx, link))
+	require.Equal(
+		t,
+		[]string{"groupA", "groupB", "groupC"},
+		membershipGroups(t, store, "did:web:alice"),
+		"linking groupC under groupB reaches every group above it",
+	)
+
+	members, err := store.ListGroupMembers(ctx, "groupA", memberMinRole)
+	require.NoError(t, err)
+	require.Len(t, members, 1)
+	require.False(t, members[0].Direct)
+	require.Equal(t, "groupB", members[0].ViaGroup)
+
+	// Rewriting the link to point at another group moves the memberships.
+	link.ObjectSpace = "groupD"
+	require.NoError(t, store.UpsertTuple(ctx, link))
+	require.Equal(
+		t,
+		[]string{"groupC", "groupD"},
+		membershipGroups(t, store, "did:web:alice"),
+	)
+
+	require.NoError(t, store.DeleteTuple(ctx, habitat_syntax.SpaceRecordURI(link.RecordURI)))
+	require.Equal(t, []string{"groupC"}, membershipGroups(t, store, "did:web:alice"))
+}
+
+func TestMemberships_CycleIsResolvedAndBroken(t *testing.T) {
+	ctx := context.Background()
+	store := setupStore(t)
+
+	require.NoError(t, store.UpsertTuple(ctx, userTuple("groupA", "did:web:alice", "writer")))
+	require.NoError(t, store.UpsertTuple(ctx, groupTuple("groupA", "groupB", "writer", "writer")))
+	back := groupTuple("groupB", "groupA", "writer", "writer")
+	require.NoError(t, store.UpsertTuple(ctx, back))
+	require.Equal(t, []string{"groupA", "groupB"}, membershipGroups(t, store, "did:web:alice"))
+
+	// The groups must not keep alice's membership alive through each other
+	// once the cycle is broken.
+	require.NoError(t, store.DeleteTuple(ctx, habitat_syntax.SpaceRecordURI(back.RecordURI)))
+	require.Equal(t, []string{"groupA"}, membershipGroups(t, store, "did:web:alice"))
+}
+
+func TestMemberships_ResyncDropsStaleGrants(t *testing.T) {
+	ctx := context.Background()
+	store := setupStore(t)
+
+	clock := syntax.NewTIDClock(0)
+	oldRev, snapshotRev := clock.Next(), clock.Next()
+	uri := recordURI(space1, "did:web:org", collectionTuple, "t1")
+	tuple := userTuple("groupA", "did:web:alice", "writer")
+	tuple.RecordURI = uri.String()
+	require.NoError(t, store.UpsertRecord(ctx, uri, oldRev, nil))
+	require.NoError(t, store.UpsertTuple(ctx, tuple))
+	require.Equal(t, []string{"groupA"}, membershipGroups(t, store, "did:web:alice"))
+
+	require.NoError(t, store.ResyncRepo(ctx, space1, "did:web:org", snapshotRev))
+	require.Empty(t, membershipGroups(t, store, "did:web:alice"))
+}
+
+func TestRebuildMemberships(t *testing.T) {
+	ctx := context.Background()
+	store := setupStore(t)
+
+	require.NoError(t, store.UpsertTuple(ctx, userTuple("groupB", "did:web:alice", "manager")))
+	require.NoError(t, store.UpsertTuple(ctx, groupTuple("groupA", "groupB", "writer", "writer")))
+	// Memberships indexed before the table existed are missing.
+	require.NoError(t, store.db.Where("1 = 1").Delete(&membershipRow{}).Error)
+
+	require.NoError(t, store.RebuildMemberships(ctx))
+	require.Equal(t, []string{"groupA", "groupB"}, membershipGroups(t, store, "did:web:alice"))
+	counts, err := store.CountGroupMembers(ctx, []string{"groupA", "groupB"}, manageMinRole)
+	require.NoError(t, err)
+	require.Equal(t, map[string]int64{"groupB": 1}, counts)
+}
+
+func TestGroupService_ListMyGroupsAndMembershipPath(t *testing.T) {
+	ctx := context.Background()
+	store := setupStore(t)
+	groups := NewGroupService(store, nil)
+
+	board := "at://did:web:org/space/network.habitat.group/board"
+	staff := "at://did:web:org/space/network.habitat.group/staff"
+	require.NoError(t, store.UpsertProfile(ctx, recordURI(board, "did:web:org",
+		collectionGroupProfile, "self"), "Board", "", "", ""))
+	require.NoError(t, store.UpsertProfile(ctx, recordURI(staff, "did:web:org",
+		collectionGroupProfile, "self"), "Staff", "", "", ""))
+	require.NoError(t, store.UpsertTuple(ctx, userTuple(board, "did:web:alice", "manager")))
+	require.NoError(t, store.UpsertTuple(ctx, groupTuple(staff, board, "writer", "writer")))
+
+	mine, err := groups.ListMyGroups(ctx, orgDID, "did:web:alice")
+	require.NoError(t, err)
+	require.Len(t, mine.Groups, 2)
+	require.Equal(t, "Board", mine.Groups[0].Group.Name)
+	require.True(t, mine.Groups[0].Direct)
+	require.Equal(t, "manager", mine.Groups[0].Role)
+	require.Equal(t, "Staff", mine.Groups[1].Group.Name)
+	require.Equal(t, board, mine.Groups[1].ViaGroup)
+
+	path, err := groups.GetMembershipPath(ctx, orgDID, "did:web:alice", staff)
+	require.NoError(t, err)
+	require.Equal(t, "writer", path.Role)
+	require.Len(t, path.Path, 2)
+	require.Equal(t, "Board", path.Path[0].Name)
+	require.Equal(t, "Staff", path.Path[1].Name)
+
+	_, err = groups.GetMembershipPath(ctx, orgDID, "did:web:bob", staff)
+	require.ErrorIs(t, err, ErrNotMember)
+}
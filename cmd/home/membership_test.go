@@ -107,3 +107,64 @@ func TestHolders_TransitiveInheritanceAndCycleSafe(t *testing.T) {
 		memberDIDs(g.holders("groupB", memberMinRole)),
 	)
 }
+
+func TestMemberships_HighestRoleWins(t *testing.T) {
+	// alice writes groupA directly and manages groupB, which groupA grants
+	// manager to.
+	g := newGraph([]tupleRow{
+		userTuple("groupA", "did:web:alice", "writer"),
+		userTuple("groupB", "did:web:alice", "manager"),
+		groupTuple("groupA", "groupB", "manager", "manager"),
+	})
+	members := g.memberships("groupA")
+	require.Len(t, members, 1)
+	require.Equal(t, "manager", members[0].Role)
+	require.False(t, members[0].Direct)
+	require.Equal(t, "groupB", members[0].ViaGroup)
+}
+
+func TestCycle(t *testing.T) {
+	g := newGraph([]tupleRow{
+		groupTuple("groupA", "groupB", "writer", "writer"),
+		groupTuple("groupB", "groupC", "writer", "writer"),
+		groupTuple("groupC", "groupA", "writer", "writer"),
+		groupTuple("groupD", "groupA", "writer", "writer"),
+	})
+	require.Equal(t, []string{"groupA", "groupB", "groupC", "groupA"}, g.cycle("groupA"))
+	require.Equal(
+		t,
+		[]string{"groupA", "groupB", "groupC", "groupA"},
+		g.cycle("groupD"),
+		"a cycle below a group is found from it",
+	)
+
+	acyclic := newGraph([]tupleRow{
+		groupTuple("groupA", "groupB", "writer", "writer"),
+		groupTuple("groupA", "groupC", "writer", "writer"),
+		groupTuple("groupB", "groupC", "writer", "writer"),
+	})
+	require.Nil(t, acyclic.cycle("groupA"), "a diamond is not a cycle")
+	require.True(t, acyclic.inherits("groupA", "groupC"))
+	require.False(t, acyclic.inherits("groupC", "groupA"))
+}
+
+func TestPath(t *testing.T) {
+	g := newGraph([]tupleRow{
+		userTuple("groupC", "did:web:alice", "writer"),
+		userTuple("groupC", "did:web:bob", "reader"),
+		groupTuple("groupB", "groupC", "writer", "writer"),
+		groupTuple("groupA", "groupB", "writer", "writer"),
+		userTuple("groupA", "did:web:carol", "writer"),
+	})
+	require.Equal(
+		t,
+		[]string{"groupC", "groupB", "groupA"},
+		g.path("groupA", "did:web:alice", memberMinRole),
+	)
+	require.Equal(t, []string{"groupA"}, g.path("groupA", "did:web:carol", memberMinRole))
+	require.Nil(
+		t,
+		g.path("groupA", "did:web:bob", memberMinRole),
+		"a reader of groupC does not receive groupB's writer grant",
+	)
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bluesky-social/indigo/atproto/auth/oauth"
+	"github.com/bluesky-social/indigo/atproto/identity"
+	"github.com/bluesky-social/indigo/atproto/syntax"
+)
+
+// ErrUnknownOrg indicates a request could not be routed to any of the orgs
+// the home server manages.
+var ErrUnknownOrg = errors.New("caller is not a member of any org this home server manages")
+
+// ErrNotOrg indicates an account that authorized the home server is not an
+// org registered with its pear.
+var ErrNotOrg = errors.New("account is not an org")
+
+// orgPear builds a pear client authenticated as org, from the session saved
+// when the org authorized the home server.
+func orgPear(
+	ctx context.Context,
+	store *Store,
+	oauthApp *oauth.ClientApp,
+	org syntax.DID,
+) (*pearClient, error) {
+	sessionID, err := store.OrgSession(ctx, org)
+	if err != nil {
+		return nil, err
+	}
+	session, err := oauthApp.ResumeSession(ctx, org, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("build org client: %w", err)
+	}
+	return &pearClient{session: session}, nil
+}
+
+// orgHeader names the org a request is for. Callers that belong to several
+// of the managed orgs set it to pick one; home checks they are a member.
+const orgHeader = "Habitat-Org"
+
+// membershipCacheTTL is how long a membership confirmed by asking an org's
+// pear is trusted before the pear is asked again.
+const membershipCacheTTL = 5 * time.Minute
+
+// orgRouter resolves which of the managed orgs a request is for. Callers
+// reach home through pear's service proxy, so unless the caller names the org
+// in the Habitat-Org header it is derived from the caller:
+//
+//   - a home server managing a single org routes everything to it;
+//   - hive mints member handles under their org's handle
+//     (alice.acmecorp.<member domain> for acmecorp.<member domain>), so a
+//     caller whose handle sits directly under an org's handle belongs to it;
+//   - otherwise each org is asked, as itself, whether the caller is a member.
+//     Memberships found this way are cached for membershipCacheTTL.
+type orgRouter struct {
+	store    *Store
+	oauthApp *oauth.ClientApp
+	dir      identity.Directory
+
+	mu sync.Mutex
+	// members maps an org and one of its members to when their membership
+	// should next be confirmed with the org's pear.
+	members map[orgMember]time.Time
+}
+
+type orgMember struct {
+	org    syntax.DID
+	caller syntax.DID
+}
+
+func newOrgRouter(store *Store, oauthApp *oauth.ClientApp, dir identity.Directory) *orgRouter {
+	return &orgRouter{
+		store:    store,
+		oauthApp: oauthApp,
+		dir:      dir,
+		members:  make(map[orgMember]time.Time),
+	}
+}
+
+// resolve returns the org caller's request is for: requested, when set and
+// caller belongs to it, or else the org derived from caller.
+func (o *orgRouter) resolve(
+	ctx context.Context,
+	caller syntax.DID,
+	requested syntax.DID,
+) (syntax.DID, error) {
+	orgs, err := o.store.ListOrgSessions(ctx)
+	if err != nil {
+		return "", err
+	}
+	if len(orgs) == 0 {
+		return "", ErrNotAuthorized
+	}
+	if requested != "" {
+		i := slices.IndexFunc(orgs, func(org orgSessionRow) bool {
+			return org.DID == requested.String()
+		})
+		if i < 0 {
+			return "", ErrUnknownOrg
+		}
+		org := orgs[i : i+1]
+		if o.cached(org[0], caller) {
+			return requested, nil
+		}
+		if _, ok := o.byHandle(ctx, caller, org); ok {
+			return requested, nil
+		}
+		return o.byMembership(ctx, caller, org)
+	}
+	if len(orgs) == 1 {
+		return syntax.DID(orgs[0].DID), nil
+	}
+	if org, ok := o.byHandle(ctx, caller, orgs); ok {
+		return org, nil
+	}
+	for _, org := range orgs {
+		if o.cached(org, caller) {
+			return syntax.DID(org.DID), nil
+		}
+	}
+	return o.byMembership(ctx, caller, orgs)
+}
+
+// cached reports whether caller's membership of org was confirmed recently.
+func (o *orgRouter) cached(org orgSessionRow, caller syntax.DID) bool {
+	key := orgMember{org: syntax.DID(org.DID), caller: caller}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	expires, ok := o.members[key]
+	if ok && time.Now().After(expires) {
+		delete(o.members, key)
+		return false
+	}
+	return ok
+}
+
+// byHandle routes caller to the org whose handle is the parent domain of the
+// caller's handle, as hive mints member handles.
+func (o *orgRouter) byHandle(
+	ctx context.Context,
+	caller syntax.DID,
+	orgs []orgSessionRow,
+) (syntax.DID, bool) {
+	ident, err := o.dir.LookupDID(ctx, caller)
+	if err != nil || ident.Handle == syntax.HandleInvalid {
+		return "", false
+	}
+	_, parent, ok := strings.Cut(ident.Handle.String(), ".")
+	if !ok {
+		return "", false
+	}
+	for _, org := range orgs {
+		if org.Handle != "" && strings.EqualFold(org.Handle, parent) {
+			return syntax.DID(org.DID), true
+		}
+	}
+	return "", false
+}
+
+// byMembership asks each org's pear whether caller is one of its members and
+// routes to the first that says so, caching the answer. An org whose session
+// fails is skipped so one lapsed org does not lock every caller out of the
+// others.
+func (o *orgRouter) byMembership(
+	ctx context.Context,
+	caller syntax.DID,
+	orgs []orgSessionRow,
+) (syntax.DID, error) {
+	for _, org := range orgs {
+		pear, err := orgPear(ctx, o.store, o.oauthApp, syntax.DID(org.DID))
+		if err != nil {
+			slog.WarnContext(ctx, "resume org session", "org", org.DID, "err", err)
+			continue
+		}
+		member, err := pear.isOrgMember(ctx, caller)
+		if err != nil {
+			slog.WarnContext(ctx, "list org members", "org", org.DID, "err", err)
+			continue
+		}
+		if member {
+			o.mu.Lock()
+			o.members[orgMember{org: syntax.DID(org.DID), caller: caller}] =
+				time.Now().Add(membershipCacheTTL)
+			o.mu.Unlock()
+			return syntax.DID(org.DID), nil
+		}
+	}
+	return "", ErrUnknownOrg
+}
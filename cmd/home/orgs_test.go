@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bluesky-social/indigo/atproto/auth/oauth"
+	"github.com/bluesky-social/indigo/atproto/identity"
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	habitat_syntax "github.com/habitat-network/habitat/internal/syntax"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrgRouter(t *testing.T) {
+	ctx := context.Background()
+	store := setupStore(t)
+	dir := identity.NewMockDirectory()
+	dir.Insert(identity.Identity{DID: "did:web:alice", Handle: "alice.acme.pear.example"})
+	dir.Insert(identity.Identity{DID: "did:web:bob", Handle: "bob.globex.pear.example"})
+	dir.Insert(identity.Identity{DID: "did:web:carol", Handle: "carol.example.com"})
+	// No org session is stored in the app, so asking an org about its members
+	// fails and that org is skipped.
+	config := oauth.NewPublicConfig("https://home.example/client-metadata.json",
+		"https://home.example/oauth-callback", []string{})
+	router := newOrgRouter(store, oauth.NewClientApp(&config, oauth.NewMemStore()), dir)
+
+	_, err := router.resolve(ctx, "did:web:alice", "")
+	require.ErrorIs(t, err, ErrNotAuthorized)
+
+	require.NoError(t, store.SaveOrgSession(ctx, "did:web:acme", "acme.pear.example", "s1"))
+	org, err := router.resolve(ctx, "did:web:carol", "")
+	require.NoError(t, err)
+	require.Equal(t, syntax.DID("did:web:acme"), org, "a single org serves every caller")
+
+	require.NoError(t, store.SaveOrgSession(ctx, "did:web:globex", "globex.pear.example", "s2"))
+	org, err = router.resolve(ctx, "did:web:alice", "")
+	require.NoError(t, err)
+	require.Equal(t, syntax.DID("did:web:acme"), org)
+	org, err = router.resolve(ctx, "did:web:bob", "")
+	require.NoError(t, err)
+	require.Equal(t, syntax.DID("did:web:globex"), org)
+
+	_, err = router.resolve(ctx, "did:web:carol", "")
+	require.ErrorIs(t, err, ErrUnknownOrg)
+	_, err = router.resolve(ctx, "did:web:unknown", "")
+	require.ErrorIs(t, err, ErrUnknownOrg)
+
+	// A caller may name its org, but only one it belongs to.
+	org, err = router.resolve(ctx, "did:web:alice", "did:web:acme")
+	require.NoError(t, err)
+	require.Equal(t, syntax.DID("did:web:acme"), org)
+	_, err = router.resolve(ctx, "did:web:alice", "did:web:globex")
+	require.ErrorIs(t, err, ErrUnknownOrg)
+	_, err = router.resolve(ctx, "did:web:alice", "did:web:initech")
+	require.ErrorIs(t, err, ErrUnknownOrg)
+
+	// Memberships confirmed with an org's pear are reused until they expire.
+	carolInGlobex := orgMember{org: "did:web:globex", caller: "did:web:carol"}
+	router.members[carolInGlobex] = time.Now().Add(time.Minute)
+	org, err = router.resolve(ctx, "did:web:carol", "")
+	require.NoError(t, err)
+	require.Equal(t, syntax.DID("did:web:globex"), org)
+	org, err = router.resolve(ctx, "did:web:carol", "did:web:globex")
+	require.NoError(t, err)
+	require.Equal(t, syntax.DID("did:web:globex"), org)
+	router.members[carolInGlobex] = time.Now().Add(-time.Minute)
+	_, err = router.resolve(ctx, "did:web:carol", "")
+	require.ErrorIs(t, err, ErrUnknownOrg)
+}
+
+func TestStore_PartitionsByOrg(t *testing.T) {
+	ctx := context.Background()
+	store := setupStore(t)
+
+	acme := "at://did:web:acme/space/network.habitat.group/board"
+	globex := "at://did:web:globex/space/network.habitat.group/board"
+	for _, space := range []string{acme, globex} {
+		uri := recordURI(space, "did:web:alice", collectionGroupProfile, "self")
+		require.NoError(t, store.UpsertRecord(ctx, uri, seedRev, nil))
+		require.NoError(t, store.UpsertProfile(ctx, uri, "Board", "", "", ""))
+		require.NoError(t, store.UpsertTuple(ctx, userTuple(space, "did:web:alice", "writer")))
+	}
+
+	_, err := store.GetGroup(ctx, "did:web:acme", habitat_syntax.SpaceURI(acme))
+	require.NoError(t, err)
+	_, err = store.GetGroup(ctx, "did:web:acme", habitat_syntax.SpaceURI(globex))
+	require.ErrorIs(t, err, ErrGroupNotFound)
+
+	memberships, err := store.ListMemberships(ctx, "did:web:globex", "did:web:alice", memberMinRole)
+	require.NoError(t, err)
+	require.Len(t, memberships, 1)
+	require.Equal(t, globex, memberships[0].GroupSpace)
+
+	counts, err := store.CountCollections(ctx, "did:web:acme", []string{acme, globex})
+	require.NoError(t, err)
+	require.Equal(t, []collectionCount{{Collection: collectionGroupProfile, Count: 1}}, counts)
+}
+
+func TestStore_BackfillOrgs(t *testing.T) {
+	ctx := context.Background()
+	store := setupStore(t)
+
+	uri := recordURI(space1, "did:web:alice", "app.bsky.feed.post", "a")
+	require.NoError(t, store.UpsertRecord(ctx, uri, seedRev, nil))
+	require.NoError(t, store.UpsertTuple(ctx, userTuple(space1, "did:web:alice", "writer")))
+	// Rows indexed by an older release carry no org.
+	require.NoError(t, store.db.Model(&recordRow{}).Where("1 = 1").Update("org_did", nil).Error)
+	require.NoError(t, store.db.Model(&tupleRow{}).Where("1 = 1").Update("org_did", "").Error)
+
+	require.NoError(t, store.BackfillOrgs(ctx))
+
+	rows, _, err := store.ListRecordsInSpaces(
+		ctx, orgDID, []string{space1}, "app.bsky.feed.post", 100, "",
+	)
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	tuples, err := store.ListTuplesOn(ctx, []string{space1})
+	require.NoError(t, err)
+	require.Len(t, tuples, 1)
+	require.Equal(t, orgDID.String(), tuples[0].OrgDID)
+}
@@ -8,6 +8,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"strings"
 
 	"github.com/bluesky-social/indigo/atproto/auth/oauth"
 	"github.com/bluesky-social/indigo/atproto/syntax"
@@ -16,30 +17,36 @@ import (
 	habitat_syntax "github.com/habitat-network/habitat/internal/syntax"
 )
 
-// GroupSpaceType is the space type every group is created under. The
-// network.habitat.group.profile self record holds the group's metadata.
-const GroupSpaceType = "network.habitat.group"
-
 const (
 	collectionGroupProfile = "network.habitat.group.profile"
+	collectionJoinRequest  = "network.habitat.group.joinRequest"
 	collectionTuple        = "network.habitat.relationship.tuple"
 )
 
-// pearClient wraps the network.habitat.* XRPC endpoints, calling them through
-// an org-credentialed http.Client (produced by oauth_client). The client
-// rewrites relative paths onto the org's pear host, so requests use "/xrpc/..."
-// paths.
+// pearClient wraps the network.habitat.* XRPC endpoints, calling them on the
+// org's pear host (the session's HostURL) with the org's OAuth session.
 type pearClient struct {
 	session *oauth.ClientSession
 }
 
+// repo is the DID of the org the client acts as. pear only lets a credential
+// write records to its own repo in a space.
+func (p *pearClient) repo() string {
+	return p.session.Data.AccountDID.String()
+}
+
+// xrpcURL is the URL of an XRPC endpoint on the org's pear host.
+func (p *pearClient) xrpcURL(nsid syntax.NSID) string {
+	return strings.TrimSuffix(p.session.Data.HostURL, "/") + "/xrpc/" + nsid.String()
+}
+
 func (p *pearClient) post(ctx context.Context, nsid syntax.NSID, input any, out any) error {
 	body, err := json.Marshal(input)
 	if err != nil {
 		return fmt.Errorf("marshal %s input: %w", nsid, err)
 	}
 	req, err := http.NewRequestWithContext(
-		ctx, http.MethodPost, "/xrpc/"+nsid.String(), bytes.NewReader(body),
+		ctx, http.MethodPost, p.xrpcURL(nsid), bytes.NewReader(body),
 	)
 	if err != nil {
 		return fmt.Errorf("build %s request: %w", nsid, err)
@@ -52,7 +59,7 @@ func (p *pearClient) get(ctx context.Context, nsid syntax.NSID, params url.Value
 	req, err := http.NewRequestWithContext(
 		ctx,
 		http.MethodGet,
-		"/xrpc/"+nsid.String()+"?"+params.Encode(),
+		p.xrpcURL(nsid)+"?"+params.Encode(),
 		http.NoBody,
 	)
 	if err != nil {
@@ -84,7 +91,9 @@ func (p *pearClient) do(req *http.Request, nsid syntax.NSID, out any) error {
 func (p *pearClient) createGroupSpace(ctx context.Context) (habitat_syntax.SpaceURI, error) {
 	var out habitat.NetworkHabitatSimplespaceCreateSpaceOutput
 	err := p.post(ctx, "network.habitat.simplespace.createSpace",
-		habitat.NetworkHabitatSimplespaceCreateSpaceInput{Type: GroupSpaceType}, &out)
+		habitat.NetworkHabitatSimplespaceCreateSpaceInput{
+			Type: habitat_syntax.GroupSpaceType.String(),
+		}, &out)
 	if err != nil {
 		return "", err
 	}
@@ -101,6 +110,7 @@ func (p *pearClient) putProfile(
 	err := p.post(ctx, "network.habitat.space.putRecord",
 		habitat.NetworkHabitatSpacePutRecordInput{
 			Space:      space.String(),
+			Repo:       p.repo(),
 			Collection: collectionGroupProfile,
 			Rkey:       "self",
 			Record:     profile,
@@ -111,6 +121,39 @@ func (p *pearClient) putProfile(
 	return habitat_syntax.SpaceRecordURI(out.Uri), nil
 }
 
+// putJoinRequest records a user's request to join a group-space, as a record of
+// the org's repo in that space.
+func (p *pearClient) putJoinRequest(
+	ctx context.Context,
+	space habitat_syntax.SpaceURI,
+	request habitat.NetworkHabitatGroupJoinRequest,
+) (habitat_syntax.SpaceRecordURI, error) {
+	var out habitat.NetworkHabitatSpacePutRecordOutput
+	err := p.post(ctx, "network.habitat.space.putRecord",
+		habitat.NetworkHabitatSpacePutRecordInput{
+			Space:      space.String(),
+			Repo:       p.repo(),
+			Collection: collectionJoinRequest,
+			Rkey:       syntax.NewTIDNow(0).String(),
+			Record:     request,
+		}, &out)
+	if err != nil {
+		return "", err
+	}
+	return habitat_syntax.SpaceRecordURI(out.Uri), nil
+}
+
+// deleteRecord removes a record the org wrote in a space.
+func (p *pearClient) deleteRecord(ctx context.Context, uri habitat_syntax.SpaceRecordURI) error {
+	return p.post(ctx, "network.habitat.space.deleteRecord",
+		habitat.NetworkHabitatSpaceDeleteRecordInput{
+			Space:      uri.SpaceURI().String(),
+			Repo:       uri.Repo().String(),
+			Collection: uri.Collection().String(),
+			Rkey:       uri.Rkey().String(),
+		}, nil)
+}
+
 // writeUserTuple grants a user a role on a group-space.
 func (p *pearClient) writeUserTuple(
 	ctx context.Context,
@@ -198,3 +241,28 @@ func (p *pearClient) check(
 	}
 	return out.Allowed, nil
 }
+
+// orgID returns the org the calling credential acts for: the org itself for an
+// org's own account, the member's org for anyone else.
+func (p *pearClient) orgID(ctx context.Context) (syntax.DID, error) {
+	var out habitat.NetworkHabitatOrgGetMetadataOutput
+	err := p.get(ctx, "network.habitat.org.getMetadata", url.Values{}, &out)
+	if err != nil {
+		return "", err
+	}
+	return syntax.DID(out.OrgId), nil
+}
+
+// isOrgMember reports whether did is a member of the calling credential's org.
+func (p *pearClient) isOrgMember(ctx context.Context, did syntax.DID) (bool, error) {
+	var out habitat.NetworkHabitatOrgGetMembersOutput
+	if err := p.get(ctx, "network.habitat.org.getMembers", url.Values{}, &out); err != nil {
+		return false, err
+	}
+	for _, m := range out.Members {
+		if m.Did == did.String() {
+			return true, nil
+		}
+	}
+	return false, nil
+}
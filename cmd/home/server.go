@@ -1,12 +1,16 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"strconv"
 
 	"github.com/bluesky-social/indigo/atproto/auth/oauth"
+	"github.com/bluesky-social/indigo/atproto/identity"
 	"github.com/bluesky-social/indigo/atproto/syntax"
 	"github.com/habitat-network/habitat/api/habitat"
 	"github.com/habitat-network/habitat/internal/authn"
@@ -20,9 +24,19 @@ import (
 // pear forwards network.habitat.groups.* calls here.
 const serviceID = "groups"
 
+// defaultRecordsLimit and maxRecordsLimit bound a listRecords page, as its
+// lexicon does.
+const (
+	defaultRecordsLimit = 50
+	maxRecordsLimit     = 100
+)
+
 type Server struct {
-	domain      string
+	domain string
+	// orgHandle is the org /oauth/login authorizes when no handle is given.
 	orgHandle   string
+	orgs        *orgRouter
+	dir         identity.Directory
 	groups      *GroupService
 	collections *CollectionService
 	oauthApp    *oauth.ClientApp
@@ -33,6 +47,8 @@ type Server struct {
 
 func NewServer(
 	domain, orgHandle string,
+	orgs *orgRouter,
+	dir identity.Directory,
 	groups *GroupService,
 	collections *CollectionService,
 	oauthApp *oauth.ClientApp,
@@ -43,6 +59,8 @@ func NewServer(
 	return &Server{
 		domain:      domain,
 		orgHandle:   orgHandle,
+		orgs:        orgs,
+		dir:         dir,
 		groups:      groups,
 		collections: collections,
 		oauthApp:    oauthApp,
@@ -59,28 +77,53 @@ func (s *Server) Routes(mux *http.ServeMux) {
 			Build(),
 	))
 	mux.HandleFunc("GET /client-metadata.json", s.handleClientMetadata)
-	mux.HandleFunc("GET /oauth/login", s.handleOAuthLogin)
 	mux.HandleFunc("GET /oauth-callback", s.handleOAuthCallback)
 
 	mux.HandleFunc("GET /xrpc/network.habitat.groups.listGroups", s.handleListGroups)
 	mux.HandleFunc("GET /xrpc/network.habitat.groups.getGroup", s.handleGetGroup)
+	mux.HandleFunc("GET /xrpc/network.habitat.groups.listMyGroups", s.handleListMyGroups)
+	mux.HandleFunc(
+		"GET /xrpc/network.habitat.groups.getMembershipPath",
+		s.handleGetMembershipPath,
+	)
 	mux.HandleFunc("POST /xrpc/network.habitat.groups.createGroup", s.handleCreateGroup)
 	mux.HandleFunc("POST /xrpc/network.habitat.groups.updateGroup", s.handleUpdateGroup)
 	mux.HandleFunc("POST /xrpc/network.habitat.groups.addMember", s.handleAddMember)
 	mux.HandleFunc("POST /xrpc/network.habitat.groups.deleteMember", s.handleDeleteMember)
+	mux.HandleFunc("POST /xrpc/network.habitat.groups.requestToJoin", s.handleRequestToJoin)
+	mux.HandleFunc(
+		"GET /xrpc/network.habitat.groups.listJoinRequests",
+		s.handleListJoinRequests,
+	)
+	mux.HandleFunc(
+		"POST /xrpc/network.habitat.groups.resolveJoinRequest",
+		s.handleResolveJoinRequest,
+	)
 
 	mux.HandleFunc("GET /xrpc/network.habitat.collections.listCollections", s.handleListCollections)
 	mux.HandleFunc("GET /xrpc/network.habitat.collections.listRecords", s.handleListRecords)
 }
 
+// InternalRoutes registers the operator endpoints, served on the internal
+// port so that only operators can enroll orgs.
+func (s *Server) InternalRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /oauth/login", s.handleOAuthLogin)
+}
+
 func (s *Server) handleClientMetadata(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, r, s.oauthApp.Config.ClientMetadata())
 }
 
-// handleOAuthLogin starts the one-time org credential bootstrap: an org admin
-// opens this to authorize the home server as the org.
+// handleOAuthLogin starts an org credential bootstrap: an operator opens this
+// on the internal port, with the org's handle, and the org admin authorizes
+// the home server as the org. Each org authorized this way is managed
+// alongside the others.
 func (s *Server) handleOAuthLogin(w http.ResponseWriter, r *http.Request) {
-	url, err := s.oauthApp.StartAuthFlow(r.Context(), s.orgHandle)
+	handle := r.URL.Query().Get("handle")
+	if handle == "" {
+		handle = s.orgHandle
+	}
+	url, err := s.oauthApp.StartAuthFlow(r.Context(), handle)
 	if err != nil {
 		http.Error(w, "start auth flow: "+err.Error(), http.StatusInternalServerError)
 		return
@@ -94,7 +137,34 @@ func (s *Server) handleOAuthCallback(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "process callback: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
-	if err := s.store.SaveOrgSession(r.Context(), sess.AccountDID, sess.SessionID); err != nil {
+	// Only an org's own account may be enrolled: its credential is what home
+	// acts as, and its DID partitions the index.
+	if err := s.checkIsOrg(r.Context(), sess); err != nil {
+		if logoutErr := s.oauthApp.Logout(
+			r.Context(),
+			sess.AccountDID,
+			sess.SessionID,
+		); logoutErr != nil {
+			slog.WarnContext(r.Context(), "drop rejected org session",
+				"did", sess.AccountDID, "err", logoutErr)
+		}
+		if errors.Is(err, ErrNotOrg) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		http.Error(w, "check org: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	// The org's handle routes the requests of the members hive mints under it.
+	var handle syntax.Handle
+	ident, err := s.dir.LookupDID(r.Context(), sess.AccountDID)
+	if err != nil {
+		slog.WarnContext(r.Context(), "resolve org handle", "did", sess.AccountDID, "err", err)
+	} else if ident.Handle != syntax.HandleInvalid {
+		handle = ident.Handle
+	}
+	err = s.store.SaveOrgSession(r.Context(), sess.AccountDID, handle, sess.SessionID)
+	if err != nil {
 		http.Error(w, "save org session: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -102,16 +172,34 @@ func (s *Server) handleOAuthCallback(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "add session: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
-	slog.InfoContext(r.Context(), "home server authorized for org", "did", sess.AccountDID)
+	slog.InfoContext(r.Context(), "home server authorized for org",
+		"did", sess.AccountDID, "handle", handle)
 	_, _ = w.Write([]byte("Home server authorized. You can close this tab."))
 }
 
+// checkIsOrg asks pear, as the newly authorized account, which org it acts
+// for; only an org's own account gets its own DID back.
+func (s *Server) checkIsOrg(ctx context.Context, sess *oauth.ClientSessionData) error {
+	session, err := s.oauthApp.ResumeSession(ctx, sess.AccountDID, sess.SessionID)
+	if err != nil {
+		return fmt.Errorf("resume session: %w", err)
+	}
+	org, err := (&pearClient{session: session}).orgID(ctx)
+	if err != nil {
+		return err
+	}
+	if org != sess.AccountDID {
+		return fmt.Errorf("%w: %s", ErrNotOrg, sess.AccountDID)
+	}
+	return nil
+}
+
 func (s *Server) handleListGroups(w http.ResponseWriter, r *http.Request) {
-	caller, ok := s.authCaller(w, r)
+	caller, org, ok := s.authOrg(w, r)
 	if !ok {
 		return
 	}
-	out, err := s.groups.ListGroups(r.Context(), caller)
+	out, err := s.groups.ListGroups(r.Context(), org, caller)
 	if err != nil {
 		s.writeError(w, r, err)
 		return
@@ -120,11 +208,28 @@ func (s *Server) handleListGroups(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) handleGetGroup(w http.ResponseWriter, r *http.Request) {
-	caller, ok := s.authCaller(w, r)
+	caller, org, ok := s.authOrg(w, r)
+	if !ok {
+		return
+	}
+	out, err := s.groups.GetGroup(r.Context(), org, caller, r.URL.Query().Get("group"))
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, out)
+}
+
+func (s *Server) handleListMyGroups(w http.ResponseWriter, r *http.Request) {
+	caller, org, ok := s.authOrg(w, r)
+	if !ok {
+		return
+	}
+	did, ok := didParam(w, r, caller)
 	if !ok {
 		return
 	}
-	out, err := s.groups.GetGroup(r.Context(), caller, r.URL.Query().Get("group"))
+	out, err := s.groups.ListMyGroups(r.Context(), org, did)
 	if err != nil {
 		s.writeError(w, r, err)
 		return
@@ -132,8 +237,39 @@ func (s *Server) handleGetGroup(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, r, out)
 }
 
+func (s *Server) handleGetMembershipPath(w http.ResponseWriter, r *http.Request) {
+	caller, org, ok := s.authOrg(w, r)
+	if !ok {
+		return
+	}
+	did, ok := didParam(w, r, caller)
+	if !ok {
+		return
+	}
+	out, err := s.groups.GetMembershipPath(r.Context(), org, did, r.URL.Query().Get("group"))
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, out)
+}
+
+// didParam reads the optional did query parameter, defaulting to the caller.
+func didParam(w http.ResponseWriter, r *http.Request, caller syntax.DID) (syntax.DID, bool) {
+	raw := r.URL.Query().Get("did")
+	if raw == "" {
+		return caller, true
+	}
+	did, err := syntax.ParseDID(raw)
+	if err != nil {
+		writeXRPCError(w, http.StatusBadRequest, "InvalidRequest", "invalid did: "+err.Error())
+		return "", false
+	}
+	return did, true
+}
+
 func (s *Server) handleCreateGroup(w http.ResponseWriter, r *http.Request) {
-	caller, ok := s.authCaller(w, r)
+	caller, org, ok := s.authOrg(w, r)
 	if !ok {
 		return
 	}
@@ -142,7 +278,7 @@ func (s *Server) handleCreateGroup(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "invalid body", http.StatusBadRequest)
 		return
 	}
-	out, err := s.groups.CreateGroup(r.Context(), caller, in)
+	out, err := s.groups.CreateGroup(r.Context(), org, caller, in)
 	if err != nil {
 		s.writeError(w, r, err)
 		return
@@ -151,7 +287,7 @@ func (s *Server) handleCreateGroup(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) handleUpdateGroup(w http.ResponseWriter, r *http.Request) {
-	caller, ok := s.authCaller(w, r)
+	caller, org, ok := s.authOrg(w, r)
 	if !ok {
 		return
 	}
@@ -160,7 +296,7 @@ func (s *Server) handleUpdateGroup(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "invalid body", http.StatusBadRequest)
 		return
 	}
-	out, err := s.groups.UpdateGroup(r.Context(), caller, in)
+	out, err := s.groups.UpdateGroup(r.Context(), org, caller, in)
 	if err != nil {
 		s.writeError(w, r, err)
 		return
@@ -169,7 +305,7 @@ func (s *Server) handleUpdateGroup(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) handleAddMember(w http.ResponseWriter, r *http.Request) {
-	caller, ok := s.authCaller(w, r)
+	caller, org, ok := s.authOrg(w, r)
 	if !ok {
 		return
 	}
@@ -178,7 +314,7 @@ func (s *Server) handleAddMember(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "invalid body", http.StatusBadRequest)
 		return
 	}
-	out, err := s.groups.AddMember(r.Context(), caller, in)
+	out, err := s.groups.AddMember(r.Context(), org, caller, in)
 	if err != nil {
 		s.writeError(w, r, err)
 		return
@@ -187,7 +323,7 @@ func (s *Server) handleAddMember(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) handleDeleteMember(w http.ResponseWriter, r *http.Request) {
-	caller, ok := s.authCaller(w, r)
+	caller, org, ok := s.authOrg(w, r)
 	if !ok {
 		return
 	}
@@ -196,19 +332,68 @@ func (s *Server) handleDeleteMember(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "invalid body", http.StatusBadRequest)
 		return
 	}
-	if err := s.groups.DeleteMember(r.Context(), caller, in); err != nil {
+	if err := s.groups.DeleteMember(r.Context(), org, caller, in); err != nil {
 		s.writeError(w, r, err)
 		return
 	}
 	writeJSON(w, r, map[string]any{})
 }
 
+func (s *Server) handleRequestToJoin(w http.ResponseWriter, r *http.Request) {
+	caller, org, ok := s.authOrg(w, r)
+	if !ok {
+		return
+	}
+	var in habitat.NetworkHabitatGroupsRequestToJoinInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	out, err := s.groups.RequestToJoin(r.Context(), org, caller, in)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, out)
+}
+
+func (s *Server) handleListJoinRequests(w http.ResponseWriter, r *http.Request) {
+	caller, org, ok := s.authOrg(w, r)
+	if !ok {
+		return
+	}
+	out, err := s.groups.ListJoinRequests(r.Context(), org, caller, r.URL.Query().Get("group"))
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, out)
+}
+
+func (s *Server) handleResolveJoinRequest(w http.ResponseWriter, r *http.Request) {
+	caller, org, ok := s.authOrg(w, r)
+	if !ok {
+		return
+	}
+	var in habitat.NetworkHabitatGroupsResolveJoinRequestInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	out, err := s.groups.ResolveJoinRequest(r.Context(), org, caller, in)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, out)
+}
+
 func (s *Server) handleListCollections(w http.ResponseWriter, r *http.Request) {
-	caller, ok := s.authCaller(w, r)
+	caller, org, ok := s.authOrg(w, r)
 	if !ok {
 		return
 	}
-	out, err := s.collections.ListCollections(r.Context(), caller)
+	out, err := s.collections.ListCollections(r.Context(), org, caller)
 	if err != nil {
 		s.writeError(w, r, err)
 		return
@@ -217,7 +402,7 @@ func (s *Server) handleListCollections(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) handleListRecords(w http.ResponseWriter, r *http.Request) {
-	caller, ok := s.authCaller(w, r)
+	caller, org, ok := s.authOrg(w, r)
 	if !ok {
 		return
 	}
@@ -226,7 +411,24 @@ func (s *Server) handleListRecords(w http.ResponseWriter, r *http.Request) {
 		writeXRPCError(w, http.StatusBadRequest, "InvalidRequest", "collection is required")
 		return
 	}
-	out, err := s.collections.ListRecords(r.Context(), caller, collection)
+	limit := defaultRecordsLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > maxRecordsLimit {
+			writeXRPCError(w, http.StatusBadRequest, "InvalidRequest",
+				fmt.Sprintf("limit must be between 1 and %d", maxRecordsLimit))
+			return
+		}
+		limit = n
+	}
+	out, err := s.collections.ListRecords(
+		r.Context(),
+		org,
+		caller,
+		collection,
+		limit,
+		r.URL.Query().Get("cursor"),
+	)
 	if err != nil {
 		s.writeError(w, r, err)
 		return
@@ -244,6 +446,31 @@ func (s *Server) authCaller(w http.ResponseWriter, r *http.Request) (syntax.DID,
 	return info.Subject, true
 }
 
+// authOrg authenticates the caller like authCaller and resolves the org their
+// request is for: the one named by the Habitat-Org header, if any.
+func (s *Server) authOrg(w http.ResponseWriter, r *http.Request) (syntax.DID, syntax.DID, bool) {
+	caller, ok := s.authCaller(w, r)
+	if !ok {
+		return "", "", false
+	}
+	var requested syntax.DID
+	if raw := r.Header.Get(orgHeader); raw != "" {
+		did, err := syntax.ParseDID(raw)
+		if err != nil {
+			writeXRPCError(w, http.StatusBadRequest, "InvalidRequest",
+				fmt.Sprintf("invalid %s header: %s", orgHeader, err))
+			return "", "", false
+		}
+		requested = did
+	}
+	org, err := s.orgs.resolve(r.Context(), caller, requested)
+	if err != nil {
+		s.writeError(w, r, err)
+		return "", "", false
+	}
+	return caller, org, true
+}
+
 func (s *Server) writeError(w http.ResponseWriter, r *http.Request, err error) {
 	switch {
 	case errors.Is(err, ErrGroupNotFound):
@@ -252,8 +479,22 @@ func (s *Server) writeError(w http.ResponseWriter, r *http.Request, err error) {
 		writeXRPCError(w, http.StatusForbidden, "Forbidden", err.Error())
 	case errors.Is(err, ErrInvalidSubject):
 		writeXRPCError(w, http.StatusBadRequest, "InvalidSubject", err.Error())
+	case errors.Is(err, ErrMembershipCycle):
+		writeXRPCError(w, http.StatusBadRequest, "MembershipCycle", err.Error())
 	case errors.Is(err, ErrMemberNotFound):
 		writeXRPCError(w, http.StatusNotFound, "MemberNotFound", err.Error())
+	case errors.Is(err, ErrNotMember):
+		writeXRPCError(w, http.StatusNotFound, "NotMember", err.Error())
+	case errors.Is(err, ErrInvalidJoinPolicy):
+		writeXRPCError(w, http.StatusBadRequest, "InvalidJoinPolicy", err.Error())
+	case errors.Is(err, ErrAlreadyMember):
+		writeXRPCError(w, http.StatusBadRequest, "AlreadyMember", err.Error())
+	case errors.Is(err, ErrJoinRequestNotFound):
+		writeXRPCError(w, http.StatusNotFound, "JoinRequestNotFound", err.Error())
+	case errors.Is(err, ErrInvalidCursor):
+		writeXRPCError(w, http.StatusBadRequest, "InvalidRequest", err.Error())
+	case errors.Is(err, ErrUnknownOrg):
+		writeXRPCError(w, http.StatusForbidden, "UnknownOrg", err.Error())
 	case errors.Is(err, ErrNotAuthorized):
 		writeXRPCError(w, http.StatusServiceUnavailable, "NotAuthorized", err.Error())
 	default:
@@ -2,7 +2,10 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/bluesky-social/indigo/atproto/syntax"
@@ -11,6 +14,9 @@ import (
 	"gorm.io/gorm/clause"
 )
 
+// ErrInvalidCursor is returned for a page cursor this store did not issue.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
 // ErrGroupNotFound is returned by GetGroup when no profile is indexed for a
 // space URI.
 var ErrGroupNotFound = errors.New("group not found")
@@ -19,9 +25,13 @@ var ErrGroupNotFound = errors.New("group not found")
 // network.habitat.group.profile self record is unique per space).
 type groupRow struct {
 	SpaceURI    string `gorm:"column:space_uri;primaryKey"`
+	OrgDID      string `gorm:"column:org_did;index"`
 	Name        string `gorm:"column:name"`
 	Description string `gorm:"column:description"`
 	CreatedAt   string `gorm:"column:created_at"`
+	// JoinPolicy is how users join the group (joinPolicyOpen or
+	// joinPolicyRequest); empty for profiles written before it was set.
+	JoinPolicy string `gorm:"column:join_policy"`
 	// RecordURI is the full record URI of the profile, kept so deletes that
 	// arrive keyed by record URI can find the row.
 	RecordURI string    `gorm:"column:record_uri;index"`
@@ -36,7 +46,9 @@ type tupleRow struct {
 	RecordURI string `gorm:"column:record_uri;primaryKey"`
 	// ObjectSpace is the group-space the role is granted on.
 	ObjectSpace string `gorm:"column:object_space;index"`
-	Relation    string `gorm:"column:relation"`
+	// OrgDID is the org owning ObjectSpace.
+	OrgDID   string `gorm:"column:org_did;index"`
+	Relation string `gorm:"column:relation"`
 	// SubjectKind is "user" or "group".
 	SubjectKind string `gorm:"column:subject_kind"`
 	// SubjectDID is set when SubjectKind == "user".
@@ -52,18 +64,27 @@ func (tupleRow) TableName() string { return "group_tuples" }
 // recordRow is one indexed record synced from a member's repo, keyed by its
 // full space-record URI (spaceURI/repo/collection/rkey). The same underlying
 // atproto record (repo+collection+rkey) can appear in several spaces, giving
-// one row per (space, record). The record body is not stored; the collections
-// endpoints only expose its identity and which spaces it belongs to.
+// one row per (space, record), each with the body that space holds.
 type recordRow struct {
 	RecordURI  string `gorm:"column:record_uri;primaryKey"`
 	SpaceURI   string `gorm:"column:space_uri;index"`
+	OrgDID     string `gorm:"column:org_did;index"`
 	Repo       string `gorm:"column:repo"`
 	Collection string `gorm:"column:collection;index"`
 	Rkey       string `gorm:"column:rkey"`
+	// Rev is the repo revision the record was synced at, compared against a
+	// resync marker's rev to find records the rebuilt repo no longer holds.
+	Rev string `gorm:"column:rev"`
 	// AtURI is the collection-scoped atproto URI (at://repo/collection/rkey),
 	// the identity shared by the same record across spaces.
-	AtURI     string    `gorm:"column:at_uri;index"`
-	UpdatedAt time.Time `gorm:"column:updated_at"`
+	AtURI string `gorm:"column:at_uri;index"`
+	// Value is the record body as JSON, served by the collections endpoints so
+	// clients need not fetch each record from pear. Empty for records indexed
+	// before bodies were kept, until their repo is resynced.
+	Value string `gorm:"column:value"`
+	// UpdatedAt is when the record was last written: the time of the commit
+	// it was synced at, or when it was indexed if that rev is not a TID.
+	UpdatedAt time.Time `gorm:"column:updated_at;index"`
 }
 
 func (recordRow) TableName() string { return "records" }
@@ -77,8 +98,12 @@ type collectionCount struct {
 
 // orgSessionRow records the OAuth session id obtained when the home server is
 // authorized for an org, so the org credential can be rebuilt after a restart.
+// One row per org the home server manages.
 type orgSessionRow struct {
-	DID       string    `gorm:"column:did;primaryKey"`
+	DID string `gorm:"column:did;primaryKey"`
+	// Handle is the org's handle when it authorized the home server. Members
+	// minted by hive get handles under it, which routes their requests here.
+	Handle    string    `gorm:"column:handle;index"`
 	SessionID string    `gorm:"column:session_id"`
 	UpdatedAt time.Time `gorm:"column:updated_at"`
 }
@@ -87,27 +112,81 @@ func (orgSessionRow) TableName() string { return "org_sessions" }
 
 // Store is the home server's index of group profiles and their membership
 // tuples, populated from sap's outbox and queried by the groups endpoints.
+// Every row is partitioned by the org owning its space (see spaceOrg), so one
+// home server can manage many orgs.
 type Store struct {
 	db *gorm.DB
 }
 
 func NewStore(db *gorm.DB) (*Store, error) {
-	if err := db.AutoMigrate(&groupRow{}, &tupleRow{}, &recordRow{}, &orgSessionRow{}); err != nil {
+	if err := db.AutoMigrate(
+		&groupRow{},
+		&tupleRow{},
+		&membershipRow{},
+		&joinRequestRow{},
+		&recordRow{},
+		&orgSessionRow{},
+	); err != nil {
 		return nil, err
 	}
 	return &Store{db: db}, nil
 }
 
+// spaceOrg returns the org a space belongs to: the DID owning it. home syncs
+// spaces through the sessions of the orgs it manages and each org owns its
+// group-spaces, so the owner partitions the index by org, as search's does.
+func spaceOrg(space string) string {
+	return habitat_syntax.SpaceURI(space).SpaceOwner().String()
+}
+
+// BackfillOrgs sets the org of the groups, tuples and records indexed before
+// the index was partitioned by org. It runs at startup, before memberships
+// are rebuilt, so rows from an older release are caught up.
+func (s *Store) BackfillOrgs(ctx context.Context) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, table := range []struct {
+			model any
+			space string
+		}{
+			{&groupRow{}, "space_uri"},
+			{&tupleRow{}, "object_space"},
+			{&recordRow{}, "space_uri"},
+		} {
+			var spaces []string
+			if err := tx.Model(table.model).
+				Where("org_did IS NULL OR org_did = ?", "").
+				Distinct().
+				Pluck(table.space, &spaces).Error; err != nil {
+				return err
+			}
+			for _, space := range spaces {
+				org := spaceOrg(space)
+				if org == "" {
+					continue
+				}
+				if err := tx.Model(table.model).
+					Where(table.space+" = ?", space).
+					Update("org_did", org).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
 func (s *Store) UpsertProfile(
 	ctx context.Context,
 	recordURI habitat_syntax.SpaceRecordURI,
-	name, description, createdAt string,
+	name, description, createdAt, joinPolicy string,
 ) error {
 	row := groupRow{
 		SpaceURI:    recordURI.SpaceURI().String(),
+		OrgDID:      recordURI.SpaceURI().SpaceOwner().String(),
 		Name:        name,
 		Description: description,
 		CreatedAt:   createdAt,
+		JoinPolicy:  joinPolicy,
 		RecordURI:   recordURI.String(),
 		UpdatedAt:   time.Now(),
 	}
@@ -123,23 +202,62 @@ func (s *Store) DeleteProfile(ctx context.Context, recordURI habitat_syntax.Spac
 		Delete(&groupRow{}).Error
 }
 
+// UpsertTuple indexes a tuple and refreshes the memberships it changes: those
+// of the group it grants a role on, and of the group it granted one on before
+// if the record was rewritten to point elsewhere.
 func (s *Store) UpsertTuple(ctx context.Context, t tupleRow) error {
-	return s.db.WithContext(ctx).Clauses(clause.OnConflict{
-		Columns:   []clause.Column{{Name: "record_uri"}},
-		UpdateAll: true,
-	}).Create(&t).Error
+	t.OrgDID = spaceOrg(t.ObjectSpace)
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		changed, err := tupleObjects(tx, []string{t.RecordURI})
+		if err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "record_uri"}},
+			UpdateAll: true,
+		}).Create(&t).Error; err != nil {
+			return err
+		}
+		return refreshMemberships(ctx, tx, append(changed, t.ObjectSpace))
+	})
 }
 
+// DeleteTuple drops a tuple and refreshes the memberships it granted.
 func (s *Store) DeleteTuple(ctx context.Context, recordURI habitat_syntax.SpaceRecordURI) error {
-	return s.db.WithContext(ctx).
-		Where("record_uri = ?", recordURI.String()).
-		Delete(&tupleRow{}).Error
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		changed, err := tupleObjects(tx, []string{recordURI.String()})
+		if err != nil {
+			return err
+		}
+		if err := tx.Where("record_uri = ?", recordURI.String()).
+			Delete(&tupleRow{}).Error; err != nil {
+			return err
+		}
+		return refreshMemberships(ctx, tx, changed)
+	})
 }
 
-// UpsertRecord indexes a synced record by its space-record URI. URIs that do
-// not parse into all four parts (space, repo, collection, rkey) are ignored so
-// a malformed message does not wedge the indexer.
-func (s *Store) UpsertRecord(ctx context.Context, uri habitat_syntax.SpaceRecordURI) error {
+// tupleObjects returns the group-spaces the tuples selected by recordURIs, a
+// list or a subquery, grant roles on.
+func tupleObjects(tx *gorm.DB, recordURIs any) ([]string, error) {
+	var spaces []string
+	err := tx.Model(&tupleRow{}).
+		Where("record_uri IN (?)", recordURIs).
+		Distinct().
+		Pluck("object_space", &spaces).Error
+	return spaces, err
+}
+
+// UpsertRecord indexes a synced record and its JSON value by its space-record
+// URI, at the repo rev it was synced at. URIs that do not parse into all four
+// parts (space, repo, collection, rkey) are ignored so a malformed message
+// does not wedge the indexer.
+func (s *Store) UpsertRecord(
+	ctx context.Context,
+	uri habitat_syntax.SpaceRecordURI,
+	rev syntax.TID,
+	value json.RawMessage,
+) error {
 	space := uri.SpaceURI()
 	repo := uri.Repo()
 	collection := uri.Collection()
@@ -150,11 +268,18 @@ func (s *Store) UpsertRecord(ctx context.Context, uri habitat_syntax.SpaceRecord
 	row := recordRow{
 		RecordURI:  uri.String(),
 		SpaceURI:   space.String(),
+		OrgDID:     space.SpaceOwner().String(),
 		Repo:       repo.String(),
 		Collection: collection.String(),
 		Rkey:       rkey.String(),
+		Rev:        rev.String(),
 		AtURI:      "at://" + repo.String() + "/" + collection.String() + "/" + rkey.String(),
-		UpdatedAt:  time.Now(),
+		Value:      string(value),
+		UpdatedAt:  time.Now().UTC(),
+	}
+	// Stored in UTC so pages compare times as they are stored.
+	if tid, err := syntax.ParseTID(rev.String()); err == nil {
+		row.UpdatedAt = tid.Time().UTC()
 	}
 	return s.db.WithContext(ctx).Clauses(clause.OnConflict{
 		Columns:   []clause.Column{{Name: "record_uri"}},
@@ -168,12 +293,51 @@ func (s *Store) DeleteRecord(ctx context.Context, uri habitat_syntax.SpaceRecord
 		Delete(&recordRow{}).Error
 }
 
-// CountCollections returns, for each collection with at least one record in the
-// given spaces, the number of records in that collection, counting a record
+// ResyncRepo drops everything indexed from a repo's records in a space at a
+// rev older than rev, after sap rebuilt the repo from a snapshot taken at rev:
+// the records themselves and any group profiles, join requests and tuples they
+// carried, along with the memberships those tuples granted.
+func (s *Store) ResyncRepo(
+	ctx context.Context,
+	space habitat_syntax.SpaceURI,
+	repo syntax.DID,
+	rev syntax.TID,
+) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		stale := tx.Model(&recordRow{}).
+			Select("record_uri").
+			Where("space_uri = ? AND repo = ? AND rev < ?",
+				space.String(), repo.String(), rev.String())
+		if err := tx.Where("record_uri IN (?)", stale).Delete(&groupRow{}).Error; err != nil {
+			return err
+		}
+		err := tx.Where("record_uri IN (?)", stale).Delete(&joinRequestRow{}).Error
+		if err != nil {
+			return err
+		}
+		changed, err := tupleObjects(tx, stale)
+		if err != nil {
+			return err
+		}
+		if err := tx.Where("record_uri IN (?)", stale).Delete(&tupleRow{}).Error; err != nil {
+			return err
+		}
+		if err := refreshMemberships(ctx, tx, changed); err != nil {
+			return err
+		}
+		return tx.Where("space_uri = ? AND repo = ? AND rev < ?",
+			space.String(), repo.String(), rev.String()).
+			Delete(&recordRow{}).Error
+	})
+}
+
+// CountCollections returns, for each collection with at least one record of org
+// in the given spaces, the number of records in that collection, counting a record
 // once per space it belongs to (each space holds its own version). Returns an
 // empty slice when spaces is empty.
 func (s *Store) CountCollections(
 	ctx context.Context,
+	org syntax.DID,
 	spaces []string,
 ) ([]collectionCount, error) {
 	if len(spaces) == 0 {
@@ -183,7 +347,7 @@ func (s *Store) CountCollections(
 	err := s.db.WithContext(ctx).
 		Model(&recordRow{}).
 		Select("collection, COUNT(*) AS count").
-		Where("space_uri IN ?", spaces).
+		Where("org_did = ? AND space_uri IN ?", org.String(), spaces).
 		Group("collection").
 		Order("collection ASC").
 		Scan(&counts).Error
@@ -193,21 +357,65 @@ func (s *Store) CountCollections(
 	return counts, nil
 }
 
-// ListRecordsInSpaces returns every indexed record row in the given collection
-// that belongs to one of the given spaces, one row per space-record. Returns
-// nil when spaces is empty.
+// ListRecordsInSpaces returns a page of org's indexed record rows in the given
+// collection that belong to one of the given spaces, one row per
+// space-record, most recently updated first. The cursor of the next page is
+// "" after the last. Returns nothing when spaces is empty.
 func (s *Store) ListRecordsInSpaces(
 	ctx context.Context,
+	org syntax.DID,
 	spaces []string,
 	collection string,
-) ([]recordRow, error) {
+	limit int,
+	cursor string,
+) ([]recordRow, string, error) {
 	if len(spaces) == 0 {
-		return nil, nil
+		return nil, "", nil
+	}
+	q := s.db.WithContext(ctx).
+		Where("org_did = ? AND collection = ? AND space_uri IN ?",
+			org.String(), collection, spaces)
+	if cursor != "" {
+		updatedAt, uri, err := parseRecordCursor(cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		q = q.Where("updated_at < ? OR (updated_at = ? AND record_uri < ?)",
+			updatedAt, updatedAt, uri)
 	}
 	var rows []recordRow
+	err := q.Order("updated_at DESC, record_uri DESC").Limit(limit).Find(&rows).Error
+	if err != nil {
+		return nil, "", err
+	}
+	next := ""
+	if len(rows) == limit {
+		last := rows[len(rows)-1]
+		next = last.UpdatedAt.UTC().Format(time.RFC3339Nano) + " " + last.RecordURI
+	}
+	return rows, next, nil
+}
+
+// parseRecordCursor reads a ListRecordsInSpaces cursor: the updated_at and
+// record URI of the last row of the previous page.
+func parseRecordCursor(cursor string) (time.Time, string, error) {
+	ts, uri, ok := strings.Cut(cursor, " ")
+	if !ok {
+		return time.Time{}, "", fmt.Errorf("%w: %q", ErrInvalidCursor, cursor)
+	}
+	updatedAt, err := time.Parse(time.RFC3339Nano, ts)
+	if err != nil {
+		return time.Time{}, "", fmt.Errorf("%w: %q", ErrInvalidCursor, cursor)
+	}
+	return updatedAt, uri, nil
+}
+
+// ListGroups returns every indexed group of org, by name.
+func (s *Store) ListGroups(ctx context.Context, org syntax.DID) ([]groupRow, error) {
+	var rows []groupRow
 	err := s.db.WithContext(ctx).
-		Where("collection = ? AND space_uri IN ?", collection, spaces).
-		Order("at_uri ASC, space_uri ASC").
+		Where("org_did = ?", org.String()).
+		Order("name ASC").
 		Find(&rows).Error
 	if err != nil {
 		return nil, err
@@ -215,26 +423,47 @@ func (s *Store) ListRecordsInSpaces(
 	return rows, nil
 }
 
-func (s *Store) ListGroups(ctx context.Context) ([]groupRow, error) {
+// ListGroupsIn returns the indexed groups among spaces, by name.
+func (s *Store) ListGroupsIn(ctx context.Context, spaces []string) ([]groupRow, error) {
+	if len(spaces) == 0 {
+		return nil, nil
+	}
 	var rows []groupRow
-	if err := s.db.WithContext(ctx).Order("name ASC").Find(&rows).Error; err != nil {
+	err := s.db.WithContext(ctx).
+		Where("space_uri IN ?", spaces).
+		Order("name ASC").
+		Find(&rows).Error
+	if err != nil {
 		return nil, err
 	}
 	return rows, nil
 }
 
-func (s *Store) GetGroup(ctx context.Context, space habitat_syntax.SpaceURI) (groupRow, error) {
+// GetGroup returns the indexed group at space. A group of another org is not
+// found.
+func (s *Store) GetGroup(
+	ctx context.Context,
+	org syntax.DID,
+	space habitat_syntax.SpaceURI,
+) (groupRow, error) {
 	var row groupRow
-	err := s.db.WithContext(ctx).Where("space_uri = ?", space.String()).First(&row).Error
+	err := s.db.WithContext(ctx).
+		Where("space_uri = ? AND org_did = ?", space.String(), org.String()).
+		First(&row).Error
 	if errors.Is(err, gorm.ErrRecordNotFound) {
 		return groupRow{}, ErrGroupNotFound
 	}
 	return row, err
 }
 
-func (s *Store) ListTuples(ctx context.Context) ([]tupleRow, error) {
+// ListTuplesOn returns the tuples granting roles on spaces.
+func (s *Store) ListTuplesOn(ctx context.Context, spaces []string) ([]tupleRow, error) {
+	if len(spaces) == 0 {
+		return nil, nil
+	}
 	var rows []tupleRow
-	if err := s.db.WithContext(ctx).Find(&rows).Error; err != nil {
+	err := s.db.WithContext(ctx).Where("object_space IN ?", spaces).Find(&rows).Error
+	if err != nil {
 		return nil, err
 	}
 	return rows, nil
@@ -271,33 +500,51 @@ func (s *Store) FindGroupTuples(
 	return rows, err
 }
 
-func (s *Store) SaveOrgSession(ctx context.Context, did syntax.DID, sessionID string) error {
+// SaveOrgSession records the session of an org that authorized the home
+// server, replacing any earlier one of the same org.
+func (s *Store) SaveOrgSession(
+	ctx context.Context,
+	did syntax.DID,
+	handle syntax.Handle,
+	sessionID string,
+) error {
 	return s.db.WithContext(ctx).Clauses(clause.OnConflict{
 		Columns:   []clause.Column{{Name: "did"}},
 		UpdateAll: true,
 	}).Create(&orgSessionRow{
 		DID:       did.String(),
+		Handle:    handle.String(),
 		SessionID: sessionID,
 		UpdatedAt: time.Now(),
 	}).Error
 }
 
-// OrgSession returns the single managed org's DID and session id. The home
-// server manages exactly one org, so it returns the most recently saved one.
-func (s *Store) OrgSession(ctx context.Context) (syntax.DID, string, error) {
+// OrgSession returns the session id of org, or ErrUnknownOrg if the org has
+// not authorized the home server.
+func (s *Store) OrgSession(ctx context.Context, org syntax.DID) (string, error) {
 	var row orgSessionRow
-	err := s.db.WithContext(ctx).Order("updated_at DESC").First(&row).Error
+	err := s.db.WithContext(ctx).Where("did = ?", org.String()).First(&row).Error
 	if errors.Is(err, gorm.ErrRecordNotFound) {
-		return "", "", ErrNotAuthorized
+		return "", ErrUnknownOrg
 	}
 	if err != nil {
-		return "", "", err
+		return "", err
 	}
-	return syntax.DID(row.DID), row.SessionID, nil
+	return row.SessionID, nil
+}
+
+// ListOrgSessions returns the session of every org the home server manages,
+// by DID.
+func (s *Store) ListOrgSessions(ctx context.Context) ([]orgSessionRow, error) {
+	var rows []orgSessionRow
+	if err := s.db.WithContext(ctx).Order("did ASC").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	return rows, nil
 }
 
 // ErrNotAuthorized indicates the home server has not yet completed the org
 // OAuth bootstrap, so it holds no org credential.
 var ErrNotAuthorized = errors.New(
-	"home server is not authorized for any org; complete /oauth/login",
+	"home server is not authorized for any org; complete /oauth/login on the internal port",
 )
@@ -81,3 +81,34 @@ func TestFindGroupTuples(t *testing.T) {
 	require.NoError(t, err)
 	require.Empty(t, rows)
 }
+
+func TestResyncRepoDropsRecordsOlderThanSnapshot(t *testing.T) {
+	ctx := context.Background()
+	store := setupStore(t)
+
+	clock := syntax.NewTIDClock(0)
+	oldRev, snapshotRev := clock.Next(), clock.Next()
+	stale := recordURI(space1, "did:web:alice", collectionGroupProfile, "self")
+	fresh := recordURI(space1, "did:web:alice", "app.bsky.feed.post", "r1")
+	otherRepo := recordURI(space1, "did:web:bob", "app.bsky.feed.post", "r2")
+
+	require.NoError(t, store.UpsertRecord(ctx, stale, oldRev, nil))
+	require.NoError(t, store.UpsertProfile(ctx, stale, "Stale", "", "", ""))
+	require.NoError(t, store.UpsertRecord(ctx, fresh, snapshotRev, nil))
+	require.NoError(t, store.UpsertRecord(ctx, otherRepo, oldRev, nil))
+
+	require.NoError(t, store.ResyncRepo(ctx, space1, "did:web:alice", snapshotRev))
+
+	rows, _, err := store.ListRecordsInSpaces(
+		ctx, orgDID, []string{space1}, "app.bsky.feed.post", 100, "",
+	)
+	require.NoError(t, err)
+	require.Len(t, rows, 2, "the fresh record and the other repo's are kept")
+	rows, _, err = store.ListRecordsInSpaces(
+		ctx, orgDID, []string{space1}, collectionGroupProfile, 100, "",
+	)
+	require.NoError(t, err)
+	require.Empty(t, rows)
+	_, err = store.GetGroup(ctx, orgDID, habitat_syntax.SpaceURI(space1))
+	require.ErrorIs(t, err, ErrGroupNotFound, "the stale record's profile is dropped too")
+}
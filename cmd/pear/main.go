@@ -396,6 +396,8 @@ func run(ctx context.Context, cmd *cli.Command) error {
 		domain,
 		hiveDir,
 		instanceAdminStore,
+		permStore,
+		spacesStore,
 	)
 	if err != nil {
 		return fmt.Errorf("setup org server for domain %q: %w", domain, err)
@@ -409,7 +411,13 @@ func run(ctx context.Context, cmd *cli.Command) error {
 	mux.HandleFunc("/xrpc/network.habitat.org.downgradeAdmin", orgServer.DowngradeAdmin)
 	mux.HandleFunc("/xrpc/network.habitat.org.issueInviteToken", orgServer.IssueInviteToken)
 	mux.HandleFunc("/xrpc/network.habitat.org.mintMemberIdentity", orgServer.MintMemberIdentity)
+	mux.HandleFunc("/xrpc/network.habitat.org.provisionMembers", orgServer.ProvisionMembers)
 	mux.HandleFunc("/xrpc/network.habitat.org.create", orgServer.CreateOrg)
+	mux.HandleFunc("/xrpc/network.habitat.org.putRole", orgServer.PutRole)
+	mux.HandleFunc("/xrpc/network.habitat.org.deleteRole", orgServer.DeleteRole)
+	mux.HandleFunc("/xrpc/network.habitat.org.listRoles", orgServer.ListRoles)
+	mux.HandleFunc("/xrpc/network.habitat.org.assignRole", orgServer.AssignRole)
+	mux.HandleFunc("/xrpc/network.habitat.org.unassignRole", orgServer.UnassignRole)
 
 	cliqueServer := clique.NewServer(cliqueStore, validator)
 	pearServer := pear.NewServer(
@@ -540,6 +548,8 @@ func run(ctx context.Context, cmd *cli.Command) error {
 		relationshipServer.SetUserRelation)
 	mux.HandleFunc("/xrpc/network.habitat.relationship.setSpaceRelation",
 		relationshipServer.SetSpaceRelation)
+	mux.HandleFunc("/xrpc/network.habitat.relationship.setOrgRoleRelation",
+		relationshipServer.SetOrgRoleRelation)
 	mux.HandleFunc("/xrpc/network.habitat.relationship.deleteRelation",
 		relationshipServer.DeleteRelation)
 	mux.HandleFunc("/xrpc/network.habitat.relationship.listRelations",
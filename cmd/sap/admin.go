@@ -0,0 +1,310 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"github.com/habitat-network/habitat/internal/httpx"
+	habitat_syntax "github.com/habitat-network/habitat/internal/syntax"
+	"github.com/habitat-network/habitat/pkg/sap/outbox"
+	"github.com/habitat-network/habitat/pkg/sap/syncer"
+)
+
+// repoStates are the sync states /admin/repos accepts as a filter.
+var repoStates = map[string]bool{
+	"pending": true, "syncing": true, "active": true, "desynced": true, "error": true,
+}
+
+// verificationOutcomes are the verification outcomes /admin/repos accepts as
+// a filter.
+var verificationOutcomes = map[string]bool{
+	string(syncer.OutcomeAuthorSigned): true,
+	string(syncer.OutcomeHostSigned):   true,
+	string(syncer.OutcomeHashOnly):     true,
+	string(syncer.OutcomeUnsigned):     true,
+	string(syncer.OutcomeInvalid):      true,
+	string(syncer.OutcomeUntrusted):    true,
+}
+
+type adminRepo struct {
+	Space      string     `json:"space"`
+	Repo       string     `json:"repo"`
+	Rev        string     `json:"rev,omitempty"`
+	State      string     `json:"state"`
+	Dirty      bool       `json:"dirty"`
+	Paused     bool       `json:"paused"`
+	RetryCount int        `json:"retryCount"`
+	RetryAfter *time.Time `json:"retryAfter,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	// Verification is how the repo's last commit was verified.
+	Verification string `json:"verification,omitempty"`
+}
+
+type adminDeadLetter struct {
+	ID             uint              `json:"id"`
+	Group          string            `json:"group"`
+	Message        outboxWireMessage `json:"message"`
+	Reason         string            `json:"reason"`
+	DeadLetteredAt time.Time         `json:"deadLetteredAt"`
+}
+
+type adminCrawl struct {
+	DID       string    `json:"did"`
+	SessionID string    `json:"sessionId"`
+	State     string    `json:"state"`
+	Cursor    string    `json:"cursor,omitempty"`
+	InFlight  bool      `json:"inFlight"`
+	Error     string    `json:"error,omitempty"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// handleAdminRepos lists tracked repos with their sync state, optionally
+// filtered by ?state=, ?verification= and ?space=, a page (?limit=, ?cursor=)
+// at a time.
+func (s *server) handleAdminRepos(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := syncer.RepoFilter{
+		Space:        habitat_syntax.SpaceURI(q.Get("space")),
+		State:        q.Get("state"),
+		Verification: q.Get("verification"),
+		Cursor:       q.Get("cursor"),
+	}
+	if filter.State != "" && !repoStates[filter.State] {
+		http.Error(w, fmt.Sprintf("unknown state %q", filter.State), http.StatusBadRequest)
+		return
+	}
+	if filter.Verification != "" && !verificationOutcomes[filter.Verification] {
+		http.Error(w, fmt.Sprintf("unknown verification outcome %q", filter.Verification),
+			http.StatusBadRequest)
+		return
+	}
+	if raw := q.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit <= 0 {
+			http.Error(w, "limit must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		filter.Limit = limit
+	}
+
+	statuses, cursor, err := s.sap.Repos(r.Context(), filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	repos := make([]adminRepo, len(statuses))
+	for i, st := range statuses {
+		repos[i] = adminRepo{
+			Space:      st.Space.String(),
+			Repo:       st.DID.String(),
+			Rev:        st.Rev.String(),
+			State:      st.State,
+			Dirty:      st.Dirty,
+			Paused:     st.Paused,
+			RetryCount: st.RetryCount,
+			Error:      st.ErrorMsg,
+
+			Verification: st.Verification,
+		}
+		if !st.RetryAfter.IsZero() {
+			repos[i].RetryAfter = &st.RetryAfter
+		}
+	}
+	httpx.WriteJSON(r.Context(), w, map[string]any{"repos": repos, "cursor": cursor})
+}
+
+// handleAdminResync forces a repo, or every repo in a space, to sync again
+// now. With full, the repos are rebuilt from a getRepo snapshot.
+func (s *server) handleAdminResync(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		Space string `json:"space"`
+		Repo  string `json:"repo"`
+		Full  bool   `json:"full"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	space, err := habitat_syntax.ParseSpaceURI(req.Space)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid space: %s", err), http.StatusBadRequest)
+		return
+	}
+	var repo syntax.DID
+	if req.Repo != "" {
+		if repo, err = syntax.ParseDID(req.Repo); err != nil {
+			http.Error(w, fmt.Sprintf("invalid repo: %s", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	requeued, err := s.sap.Resync(r.Context(), space, repo, req.Full)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	httpx.WriteJSON(r.Context(), w, map[string]any{"requeued": requeued})
+}
+
+// handleAdminPauseSpace and handleAdminResumeSpace stop and restart syncing
+// a space's repos.
+func (s *server) handleAdminPauseSpace(w http.ResponseWriter, r *http.Request) {
+	s.handleSpaceToggle(w, r, s.sap.PauseSpace)
+}
+
+func (s *server) handleAdminResumeSpace(w http.ResponseWriter, r *http.Request) {
+	s.handleSpaceToggle(w, r, s.sap.ResumeSpace)
+}
+
+func (s *server) handleSpaceToggle(
+	w http.ResponseWriter,
+	r *http.Request,
+	apply func(ctx context.Context, space habitat_syntax.SpaceURI) error,
+) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		Space string `json:"space"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	space, err := habitat_syntax.ParseSpaceURI(req.Space)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid space: %s", err), http.StatusBadRequest)
+		return
+	}
+	if err := apply(r.Context(), space); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	httpx.WriteJSON(r.Context(), w, map[string]string{"status": "ok"})
+}
+
+// handleAdminPausedSpaces lists the paused spaces.
+func (s *server) handleAdminPausedSpaces(w http.ResponseWriter, r *http.Request) {
+	spaces, err := s.sap.PausedSpaces(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if spaces == nil {
+		spaces = []habitat_syntax.SpaceURI{}
+	}
+	httpx.WriteJSON(r.Context(), w, map[string]any{"spaces": spaces})
+}
+
+// handleAdminCrawls reports each session's backfill crawl state and cursor.
+func (s *server) handleAdminCrawls(w http.ResponseWriter, r *http.Request) {
+	statuses, err := s.sap.Crawls(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	crawls := make([]adminCrawl, len(statuses))
+	for i, st := range statuses {
+		crawls[i] = adminCrawl{
+			DID:       st.DID.String(),
+			SessionID: st.SessionID,
+			State:     st.State,
+			Cursor:    st.Cursor,
+			InFlight:  st.InFlight,
+			Error:     st.ErrorMsg,
+			UpdatedAt: st.UpdatedAt,
+		}
+	}
+	httpx.WriteJSON(r.Context(), w, map[string]any{"crawls": crawls})
+}
+
+// handleAdminDeadLetters lists the messages the consumer group named by
+// ?group= (the default group when absent) gave up on.
+func (s *server) handleAdminDeadLetters(w http.ResponseWriter, r *http.Request) {
+	letters, err := s.sap.DeadLetters(r.Context(), r.URL.Query().Get("group"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	out := make([]adminDeadLetter, len(letters))
+	for i, l := range letters {
+		out[i] = adminDeadLetter{
+			ID:             l.ID,
+			Group:          l.Group,
+			Message:        toWireMessage(l.Message),
+			Reason:         l.Reason,
+			DeadLetteredAt: l.DeadLetteredAt,
+		}
+	}
+	httpx.WriteJSON(r.Context(), w, map[string]any{"deadLetters": out})
+}
+
+// handleAdminReplayDeadLetter and handleAdminDiscardDeadLetter hand a dead
+// letter back to its group or drop it.
+func (s *server) handleAdminReplayDeadLetter(w http.ResponseWriter, r *http.Request) {
+	s.handleDeadLetterAction(w, r, s.sap.ReplayDeadLetter)
+}
+
+func (s *server) handleAdminDiscardDeadLetter(w http.ResponseWriter, r *http.Request) {
+	s.handleDeadLetterAction(w, r, s.sap.DiscardDeadLetter)
+}
+
+func (s *server) handleDeadLetterAction(
+	w http.ResponseWriter,
+	r *http.Request,
+	apply func(ctx context.Context, id uint) error,
+) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		ID uint `json:"id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	if err := apply(r.Context(), req.ID); err != nil {
+		if errors.Is(err, outbox.ErrUnknownDeadLetter) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	httpx.WriteJSON(r.Context(), w, map[string]string{"status": "ok"})
+}
+
+// handleAdminMaxAttempts sets how many failed deliveries a consumer group gets
+// per message before it is dead-lettered.
+func (s *server) handleAdminMaxAttempts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		Group       string `json:"group"`
+		MaxAttempts int    `json:"maxAttempts"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	if err := s.sap.SetMaxAttempts(r.Context(), req.Group, req.MaxAttempts); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	httpx.WriteJSON(r.Context(), w, map[string]string{"status": "ok"})
+}
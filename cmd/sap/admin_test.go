@@ -0,0 +1,195 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bluesky-social/indigo/atproto/auth/oauth"
+	"github.com/habitat-network/habitat/internal/db/testutil"
+	"github.com/habitat-network/habitat/pkg/oauthclient"
+	"github.com/habitat-network/habitat/pkg/sap"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+const testAdminSpace = "at://did:plc:owner/space/network.habitat.space/s1"
+
+func openAdminTestServer(t *testing.T) (*httptest.Server, *gorm.DB) {
+	t.Helper()
+
+	db := testutil.NewDB(t)
+	store, err := oauthclient.NewGormStore(db)
+	require.NoError(t, err)
+	cfg := oauth.NewPublicConfig(
+		"https://example.com/client-metadata.json",
+		"https://example.com/oauth-callback",
+		[]string{"atproto"},
+	)
+	oauthApp := oauth.NewClientApp(&cfg, store)
+
+	s, err := sap.New(sap.Config{DB: db, OAuthClient: oauthApp})
+	require.NoError(t, err)
+
+	server := NewSapServer(s, oauthApp)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/admin/repos", server.handleAdminRepos)
+	mux.HandleFunc("/admin/repos/resync", server.handleAdminResync)
+	mux.HandleFunc("/admin/spaces/paused", server.handleAdminPausedSpaces)
+	mux.HandleFunc("/admin/spaces/pause", server.handleAdminPauseSpace)
+	mux.HandleFunc("/admin/spaces/resume", server.handleAdminResumeSpace)
+	mux.HandleFunc("/admin/crawls", server.handleAdminCrawls)
+	httpServer := httptest.NewServer(mux)
+	t.Cleanup(httpServer.Close)
+	return httpServer, db
+}
+
+func createRepoRow(t *testing.T, db *gorm.DB, did, state, errMsg string) {
+	t.Helper()
+	require.NoError(t, db.Table("repos").Create(map[string]any{
+		"space":     testAdminSpace,
+		"did":       did,
+		"state":     state,
+		"error_msg": errMsg,
+	}).Error)
+}
+
+func getJSON(t *testing.T, url string, out any) {
+	t.Helper()
+	resp, err := http.Get(url)
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(out))
+}
+
+func postJSON(t *testing.T, url, body string) *http.Response {
+	t.Helper()
+	resp, err := http.Post(url, "application/json", strings.NewReader(body))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = resp.Body.Close() })
+	return resp
+}
+
+func TestServerAdminReposListsByState(t *testing.T) {
+	t.Parallel()
+
+	httpServer, db := openAdminTestServer(t)
+	createRepoRow(t, db, "did:plc:alice", "active", "")
+	createRepoRow(t, db, "did:plc:bob", "error", "list repo ops: boom")
+
+	var out struct {
+		Repos []adminRepo `json:"repos"`
+	}
+	getJSON(t, httpServer.URL+"/admin/repos?state=error", &out)
+	require.Len(t, out.Repos, 1)
+	require.Equal(t, "did:plc:bob", out.Repos[0].Repo)
+	require.Equal(t, "error", out.Repos[0].State)
+	require.Equal(t, "list repo ops: boom", out.Repos[0].Error)
+
+	resp, err := http.Get(httpServer.URL + "/admin/repos?state=bogus")
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestServerAdminReposListsByVerification(t *testing.T) {
+	t.Parallel()
+
+	httpServer, db := openAdminTestServer(t)
+	createRepoRow(t, db, "did:plc:alice", "active", "")
+	createRepoRow(t, db, "did:plc:bob", "error", "commit not trusted by policy")
+	require.NoError(t, db.Table("repos").Where("did = ?", "did:plc:alice").
+		Update("verification", "host_signed").Error)
+	require.NoError(t, db.Table("repos").Where("did = ?", "did:plc:bob").
+		Update("verification", "untrusted").Error)
+
+	var out struct {
+		Repos []adminRepo `json:"repos"`
+	}
+	getJSON(t, httpServer.URL+"/admin/repos?verification=untrusted", &out)
+	require.Len(t, out.Repos, 1)
+	require.Equal(t, "did:plc:bob", out.Repos[0].Repo)
+	require.Equal(t, "untrusted", out.Repos[0].Verification)
+
+	resp, err := http.Get(httpServer.URL + "/admin/repos?verification=bogus")
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestServerAdminResyncRequeuesRepo(t *testing.T) {
+	t.Parallel()
+
+	httpServer, db := openAdminTestServer(t)
+	createRepoRow(t, db, "did:plc:alice", "active", "")
+	createRepoRow(t, db, "did:plc:bob", "active", "")
+
+	resp := postJSON(t, httpServer.URL+"/admin/repos/resync",
+		`{"space":"`+testAdminSpace+`","repo":"did:plc:alice","full":true}`)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var out struct {
+		Requeued int64 `json:"requeued"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+	require.Equal(t, int64(1), out.Requeued)
+
+	var state string
+	require.NoError(t, db.Table("repos").Select("state").
+		Where("did = ?", "did:plc:alice").Scan(&state).Error)
+	require.Equal(t, "desynced", state)
+
+	resp = postJSON(t, httpServer.URL+"/admin/repos/resync", `{"space":"not-a-space"}`)
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestServerAdminPauseAndResumeSpace(t *testing.T) {
+	t.Parallel()
+
+	httpServer, db := openAdminTestServer(t)
+	createRepoRow(t, db, "did:plc:alice", "active", "")
+
+	resp := postJSON(t, httpServer.URL+"/admin/spaces/pause", `{"space":"`+testAdminSpace+`"}`)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var paused struct {
+		Spaces []string `json:"spaces"`
+	}
+	getJSON(t, httpServer.URL+"/admin/spaces/paused", &paused)
+	require.Equal(t, []string{testAdminSpace}, paused.Spaces)
+	var repos struct {
+		Repos []adminRepo `json:"repos"`
+	}
+	getJSON(t, httpServer.URL+"/admin/repos", &repos)
+	require.Len(t, repos.Repos, 1)
+	require.True(t, repos.Repos[0].Paused)
+
+	resp = postJSON(t, httpServer.URL+"/admin/spaces/resume", `{"space":"`+testAdminSpace+`"}`)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	getJSON(t, httpServer.URL+"/admin/spaces/paused", &paused)
+	require.Empty(t, paused.Spaces)
+}
+
+func TestServerAdminCrawlsReportsCursors(t *testing.T) {
+	t.Parallel()
+
+	httpServer, db := openAdminTestServer(t)
+	require.NoError(t, db.Table("crawls").Create(map[string]any{
+		"did":        testProxyDID,
+		"session_id": "session-1",
+		"state":      "running",
+		"cursor":     "page-2",
+	}).Error)
+
+	var out struct {
+		Crawls []adminCrawl `json:"crawls"`
+	}
+	getJSON(t, httpServer.URL+"/admin/crawls", &out)
+	require.Len(t, out.Crawls, 1)
+	require.Equal(t, testProxyDID, out.Crawls[0].DID)
+	require.Equal(t, "running", out.Crawls[0].State)
+	require.Equal(t, "page-2", out.Crawls[0].Cursor)
+	require.False(t, out.Crawls[0].InFlight)
+}
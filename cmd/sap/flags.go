@@ -1,6 +1,10 @@
 package main
 
-import "github.com/urfave/cli/v3"
+import (
+	"time"
+
+	"github.com/urfave/cli/v3"
+)
 
 var (
 	fDB           = "db"
@@ -9,6 +13,28 @@ var (
 	fDomain       = "domain"
 	fLogLevel     = "log-level"
 	fSecret       = "secret"
+
+	fOutboxRetention   = "outbox-retention"
+	fOutboxCompaction  = "outbox-compaction"
+	fOutboxMaxAttempts = "outbox-max-attempts"
+
+	fSpaceTypes         = "space-types"
+	fCollections        = "collections"
+	fExcludeCollections = "exclude-collections"
+
+	fReplica = "replica"
+
+	fPollInterval        = "poll-interval"
+	fPollMaxInterval     = "poll-max-interval"
+	fPollHostConcurrency = "poll-host-concurrency"
+
+	fHostConcurrency = "host-concurrency"
+	fHostRate        = "host-rate"
+	fHostBurst       = "host-burst"
+
+	fRequireSignatures  = "require-signatures"
+	fTrustedHosts       = "trusted-hosts"
+	fStrictVerification = "strict-verification"
 )
 
 func getFlags() []cli.Flag {
@@ -49,5 +75,93 @@ func getFlags() []cli.Flag {
 			Value:   "secret",
 			Sources: cli.EnvVars("SAP_SECRET"),
 		},
+		&cli.DurationFlag{
+			Name:    fOutboxRetention,
+			Usage:   "How long to keep outbox messages after every consumer group acks them (0 = forever)",
+			Value:   7 * 24 * time.Hour,
+			Sources: cli.EnvVars("SAP_OUTBOX_RETENTION"),
+		},
+		&cli.BoolFlag{
+			Name:    fOutboxCompaction,
+			Usage:   "Collapse unread outbox messages for the same record down to the latest",
+			Sources: cli.EnvVars("SAP_OUTBOX_COMPACTION"),
+		},
+		&cli.IntFlag{
+			Name:    fOutboxMaxAttempts,
+			Usage:   "Failed deliveries of a message to a group before it is dead-lettered (0 = never)",
+			Value:   10,
+			Sources: cli.EnvVars("SAP_OUTBOX_MAX_ATTEMPTS"),
+		},
+		&cli.StringSliceFlag{
+			Name:    fSpaceTypes,
+			Usage:   "Only sync spaces of these types (NSIDs; empty = every type)",
+			Sources: cli.EnvVars("SAP_SPACE_TYPES"),
+		},
+		&cli.StringSliceFlag{
+			Name:    fCollections,
+			Usage:   "Only deliver records in these collections (NSIDs; empty = every collection)",
+			Sources: cli.EnvVars("SAP_COLLECTIONS"),
+		},
+		&cli.StringSliceFlag{
+			Name:    fExcludeCollections,
+			Usage:   "Never deliver records in these collections (NSIDs)",
+			Sources: cli.EnvVars("SAP_EXCLUDE_COLLECTIONS"),
+		},
+		&cli.BoolFlag{
+			Name:    fReplica,
+			Usage:   "Keep synced record values and serve space reads from them",
+			Sources: cli.EnvVars("SAP_REPLICA"),
+		},
+		&cli.DurationFlag{
+			Name:    fPollInterval,
+			Usage:   "Poll interval for active spaces without a notify registration (<0 disables)",
+			Value:   30 * time.Second,
+			Sources: cli.EnvVars("SAP_POLL_INTERVAL"),
+		},
+		&cli.DurationFlag{
+			Name:    fPollMaxInterval,
+			Usage:   "Longest poll interval an idle space backs off to",
+			Value:   15 * time.Minute,
+			Sources: cli.EnvVars("SAP_POLL_MAX_INTERVAL"),
+		},
+		&cli.IntFlag{
+			Name:    fPollHostConcurrency,
+			Usage:   "Maximum concurrent polls against one host",
+			Value:   2,
+			Sources: cli.EnvVars("SAP_POLL_HOST_CONCURRENCY"),
+		},
+		&cli.IntFlag{
+			Name:    fHostConcurrency,
+			Usage:   "Maximum concurrent requests to one host",
+			Value:   4,
+			Sources: cli.EnvVars("SAP_HOST_CONCURRENCY"),
+		},
+		&cli.Float64Flag{
+			Name:    fHostRate,
+			Usage:   "Sustained requests per second allowed to one host",
+			Value:   10,
+			Sources: cli.EnvVars("SAP_HOST_RATE"),
+		},
+		&cli.IntFlag{
+			Name:    fHostBurst,
+			Usage:   "Requests allowed to one host in a burst",
+			Value:   20,
+			Sources: cli.EnvVars("SAP_HOST_BURST"),
+		},
+		&cli.BoolFlag{
+			Name:    fRequireSignatures,
+			Usage:   "Refuse repos whose sync reaches no signed commit",
+			Sources: cli.EnvVars("SAP_REQUIRE_SIGNATURES"),
+		},
+		&cli.StringSliceFlag{
+			Name:    fTrustedHosts,
+			Usage:   "Only accept host-signed commits from these host DIDs (empty = every host)",
+			Sources: cli.EnvVars("SAP_TRUSTED_HOSTS"),
+		},
+		&cli.BoolFlag{
+			Name:    fStrictVerification,
+			Usage:   "Refuse commits whose signature could not be checked (hash-only verification)",
+			Sources: cli.EnvVars("SAP_STRICT_VERIFICATION"),
+		},
 	}
 }
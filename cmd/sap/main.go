@@ -11,6 +11,8 @@ import (
 
 	"github.com/bluesky-social/indigo/atproto/atcrypto"
 	"github.com/bluesky-social/indigo/atproto/auth/oauth"
+	"github.com/bluesky-social/indigo/atproto/identity"
+	"github.com/bluesky-social/indigo/atproto/syntax"
 	"github.com/habitat-network/habitat/internal/db"
 	"github.com/habitat-network/habitat/internal/log"
 	"github.com/habitat-network/habitat/internal/telemetry"
@@ -84,11 +86,58 @@ func runSap(ctx context.Context, cmd *cli.Command) error {
 
 	oauthApp := oauth.NewClientApp(&config, store)
 
+	spaceTypes, err := parseNSIDs(cmd.StringSlice(fSpaceTypes))
+	if err != nil {
+		return fmt.Errorf("parse %s: %w", fSpaceTypes, err)
+	}
+	collections, err := parseNSIDs(cmd.StringSlice(fCollections))
+	if err != nil {
+		return fmt.Errorf("parse %s: %w", fCollections, err)
+	}
+	excludeCollections, err := parseNSIDs(cmd.StringSlice(fExcludeCollections))
+	if err != nil {
+		return fmt.Errorf("parse %s: %w", fExcludeCollections, err)
+	}
+	trustedHosts, err := parseDIDs(cmd.StringSlice(fTrustedHosts))
+	if err != nil {
+		return fmt.Errorf("parse %s: %w", fTrustedHosts, err)
+	}
+	// Signatures are only checked when sap has a directory to resolve signer
+	// keys with, so any trust policy brings one in; without one, commits are
+	// verified by hash only.
+	var dir identity.Directory
+	if cmd.Bool(fRequireSignatures) || len(trustedHosts) > 0 || cmd.Bool(fStrictVerification) {
+		dir = identity.DefaultDirectory()
+	}
+
 	s, err := sap.New(sap.Config{
 		DB:          db,
 		OAuthClient: oauthApp,
+		Directory:   dir,
 		Meter:       otel.Meter("sap"),
 		Tracer:      otel.Tracer("sap"),
+
+		OutboxRetention:   cmd.Duration(fOutboxRetention),
+		OutboxCompaction:  cmd.Bool(fOutboxCompaction),
+		OutboxMaxAttempts: cmd.Int(fOutboxMaxAttempts),
+
+		SpaceTypes:         spaceTypes,
+		Collections:        collections,
+		ExcludeCollections: excludeCollections,
+
+		Replica: cmd.Bool(fReplica),
+
+		PollInterval:        cmd.Duration(fPollInterval),
+		PollMaxInterval:     cmd.Duration(fPollMaxInterval),
+		PollHostConcurrency: cmd.Int(fPollHostConcurrency),
+
+		HostConcurrency: cmd.Int(fHostConcurrency),
+		HostRate:        cmd.Float64(fHostRate),
+		HostBurst:       cmd.Int(fHostBurst),
+
+		RequireSignatures:  cmd.Bool(fRequireSignatures),
+		TrustedHosts:       trustedHosts,
+		StrictVerification: cmd.Bool(fStrictVerification),
 	})
 	if err != nil {
 		return fmt.Errorf("create sap: %w", err)
@@ -98,8 +147,9 @@ func runSap(ctx context.Context, cmd *cli.Command) error {
 
 	// The OAuth endpoints (callback and client metadata) must be publicly
 	// reachable since the user's PDS redirects to them, so they are served on
-	// their own port. The org and channel endpoints are served on a separate
-	// internal port so the user can restrict access to trusted services.
+	// their own port. The org, session, channel, admin and replica endpoints
+	// are served on a separate internal port so the user can restrict access
+	// to trusted services.
 	oauthMux := http.NewServeMux()
 	oauthMux.HandleFunc("/oauth-callback", server.handleOAuthCallback)
 	oauthMux.HandleFunc("/client-metadata.json", server.handleClientMetadata)
@@ -108,8 +158,34 @@ func runSap(ctx context.Context, cmd *cli.Command) error {
 	internalMux.HandleFunc("/health", server.handleHealth)
 	internalMux.HandleFunc("/org/add", server.handleAddOrg)
 	internalMux.HandleFunc("/org/list", server.handleListOrgs)
+	internalMux.HandleFunc("/session/remove", server.handleRemoveSession)
+	internalMux.HandleFunc("/session/progress", server.handleSessionProgress)
+	internalMux.HandleFunc("/admin/repos", server.handleAdminRepos)
+	internalMux.HandleFunc("/admin/repos/resync", server.handleAdminResync)
+	internalMux.HandleFunc("/admin/spaces/paused", server.handleAdminPausedSpaces)
+	internalMux.HandleFunc("/admin/spaces/pause", server.handleAdminPauseSpace)
+	internalMux.HandleFunc("/admin/spaces/resume", server.handleAdminResumeSpace)
+	internalMux.HandleFunc("/admin/crawls", server.handleAdminCrawls)
+	internalMux.HandleFunc("/admin/outbox/dead-letters", server.handleAdminDeadLetters)
+	internalMux.HandleFunc(
+		"/admin/outbox/dead-letters/replay",
+		server.handleAdminReplayDeadLetter,
+	)
+	internalMux.HandleFunc(
+		"/admin/outbox/dead-letters/discard",
+		server.handleAdminDiscardDeadLetter,
+	)
+	internalMux.HandleFunc("/admin/outbox/max-attempts", server.handleAdminMaxAttempts)
 	internalMux.HandleFunc("/channel", server.handleOutboxChannel)
 	internalMux.HandleFunc("/proxy/", server.handleProxy)
+	if cmd.Bool(fReplica) {
+		internalMux.HandleFunc("/xrpc/network.habitat.space.getRecord",
+			server.handleReplicaGetRecord)
+		internalMux.HandleFunc("/xrpc/network.habitat.space.listRecords",
+			server.handleReplicaListRecords)
+		internalMux.HandleFunc("/xrpc/network.habitat.space.listRepos",
+			server.handleReplicaListRepos)
+	}
 
 	slog.InfoContext(
 		ctx, "listening",
@@ -134,6 +210,30 @@ func runSap(ctx context.Context, cmd *cli.Command) error {
 	return err
 }
 
+func parseNSIDs(raw []string) ([]syntax.NSID, error) {
+	nsids := make([]syntax.NSID, 0, len(raw))
+	for _, r := range raw {
+		nsid, err := syntax.ParseNSID(r)
+		if err != nil {
+			return nil, err
+		}
+		nsids = append(nsids, nsid)
+	}
+	return nsids, nil
+}
+
+func parseDIDs(raw []string) ([]syntax.DID, error) {
+	dids := make([]syntax.DID, 0, len(raw))
+	for _, r := range raw {
+		did, err := syntax.ParseDID(r)
+		if err != nil {
+			return nil, err
+		}
+		dids = append(dids, did)
+	}
+	return dids, nil
+}
+
 func serve(ctx context.Context, addr string, handler http.Handler) error {
 	srv := http.Server{
 		Addr:    addr,
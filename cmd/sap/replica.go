@@ -0,0 +1,209 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/bluesky-social/indigo/atproto/atdata"
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"github.com/habitat-network/habitat/api/habitat"
+	"github.com/habitat-network/habitat/internal/httpx"
+	habitat_syntax "github.com/habitat-network/habitat/internal/syntax"
+	"github.com/habitat-network/habitat/pkg/sap"
+	"github.com/habitat-network/habitat/pkg/sap/syncer"
+)
+
+// The replica handlers serve network.habitat.space.getRecord, listRecords and
+// listRepos from what sap has synced, with pear's response shapes. The caller
+// names the DID it reads as in the Habitat-Did header, as for the proxy, and
+// sees only the spaces one of that DID's sessions can access.
+
+func (s *server) handleReplicaGetRecord(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	caller, ok := replicaCaller(w, r)
+	if !ok {
+		return
+	}
+	q := r.URL.Query()
+	space, ok := httpx.ParseSpaceURIInput(ctx, w, q.Get("space"), "space uri")
+	if !ok {
+		return
+	}
+	repo, ok := httpx.ParseDIDInput(ctx, w, q.Get("repo"), "repo")
+	if !ok {
+		return
+	}
+	collection, ok := httpx.ParseNSIDInput(ctx, w, q.Get("collection"), "collection")
+	if !ok {
+		return
+	}
+	rkey, err := syntax.ParseRecordKey(q.Get("rkey"))
+	if err != nil {
+		httpx.WriteInvalidRequest(ctx, w, "invalid rkey", err)
+		return
+	}
+
+	rec, err := s.sap.GetRecord(ctx, caller, space.URI(), repo, collection, rkey)
+	if errors.Is(err, syncer.ErrRecordNotFound) {
+		httpx.WriteRecordNotFound(ctx, w, err)
+		return
+	} else if err != nil {
+		writeReplicaError(w, r, err)
+		return
+	}
+	httpx.WriteJSON(ctx, w, habitat.NetworkHabitatSpaceGetRecordOutput{
+		Uri:   habitat_syntax.ConstructSpaceRecordURI(space, repo, collection, rkey).String(),
+		Cid:   rec.Cid,
+		Value: recordValue(rec.Value),
+	})
+}
+
+func (s *server) handleReplicaListRecords(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	caller, ok := replicaCaller(w, r)
+	if !ok {
+		return
+	}
+	q := r.URL.Query()
+	space, ok := httpx.ParseSpaceURIInput(ctx, w, q.Get("space"), "space uri")
+	if !ok {
+		return
+	}
+	repo, ok := httpx.ParseDIDInput(ctx, w, q.Get("repo"), "repo")
+	if !ok {
+		return
+	}
+	query := syncer.RecordQuery{Cursor: q.Get("cursor")}
+	if raw := q.Get("collection"); raw != "" {
+		if query.Collection, ok = httpx.ParseNSIDInput(ctx, w, raw, "collection"); !ok {
+			return
+		}
+	}
+	if query.Limit, ok = parseLimit(w, r); !ok {
+		return
+	}
+	var err error
+	if query.Reverse, err = parseBoolParam(q.Get("reverse")); err != nil {
+		httpx.WriteInvalidRequest(ctx, w, "invalid reverse", err)
+		return
+	}
+	excludeValues, err := parseBoolParam(q.Get("excludeValues"))
+	if err != nil {
+		httpx.WriteInvalidRequest(ctx, w, "invalid excludeValues", err)
+		return
+	}
+
+	records, cursor, err := s.sap.ListRecords(ctx, caller, space.URI(), repo, query)
+	if err != nil {
+		writeReplicaError(w, r, err)
+		return
+	}
+	views := make([]habitat.NetworkHabitatSpaceListRecordsRecord, len(records))
+	for i, rec := range records {
+		views[i] = habitat.NetworkHabitatSpaceListRecordsRecord{
+			Collection: rec.Collection.String(),
+			Rkey:       rec.Rkey.String(),
+			Cid:        rec.Cid,
+		}
+		if !excludeValues {
+			views[i].Value = recordValue(rec.Value)
+		}
+	}
+	httpx.WriteJSON(ctx, w, habitat.NetworkHabitatSpaceListRecordsOutput{
+		Cursor:  cursor,
+		Records: views,
+	})
+}
+
+func (s *server) handleReplicaListRepos(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	caller, ok := replicaCaller(w, r)
+	if !ok {
+		return
+	}
+	space, ok := httpx.ParseSpaceURIInput(ctx, w, r.URL.Query().Get("space"), "space uri")
+	if !ok {
+		return
+	}
+	limit, ok := parseLimit(w, r)
+	if !ok {
+		return
+	}
+
+	heads, cursor, err := s.sap.ListRepos(ctx, caller, space.URI(), limit,
+		r.URL.Query().Get("cursor"))
+	if err != nil {
+		writeReplicaError(w, r, err)
+		return
+	}
+	views := make([]habitat.NetworkHabitatSpaceListReposRepo, len(heads))
+	for i, h := range heads {
+		views[i] = habitat.NetworkHabitatSpaceListReposRepo{
+			Did:  h.DID.String(),
+			Rev:  h.Rev.String(),
+			Hash: atdata.Bytes(h.Hash),
+		}
+	}
+	httpx.WriteJSON(ctx, w, habitat.NetworkHabitatSpaceListReposOutput{
+		Cursor: cursor,
+		Repos:  views,
+	})
+}
+
+// replicaCaller reads the DID a replica read is made as.
+func replicaCaller(w http.ResponseWriter, r *http.Request) (syntax.DID, bool) {
+	didStr := r.Header.Get(habitatDIDHeader)
+	if didStr == "" {
+		httpx.WriteInvalidRequest(r.Context(), w, "missing "+habitatDIDHeader+" header", nil)
+		return "", false
+	}
+	return httpx.ParseDIDInput(r.Context(), w, didStr, habitatDIDHeader)
+}
+
+func writeReplicaError(w http.ResponseWriter, r *http.Request, err error) {
+	ctx := r.Context()
+	switch {
+	case errors.Is(err, sap.ErrNoAccess):
+		httpx.WriteError(ctx, w, "Forbidden", err.Error(), http.StatusForbidden)
+	case errors.Is(err, sap.ErrReplicaDisabled):
+		httpx.WriteNotSupported(ctx, w, err.Error())
+	default:
+		httpx.WriteServerError(ctx, w, err)
+	}
+}
+
+func parseLimit(w http.ResponseWriter, r *http.Request) (int, bool) {
+	raw := r.URL.Query().Get("limit")
+	if raw == "" {
+		return 0, true
+	}
+	limit, err := strconv.Atoi(raw)
+	if err != nil || limit <= 0 {
+		httpx.WriteInvalidRequest(r.Context(), w, "limit must be a positive integer", err)
+		return 0, false
+	}
+	return limit, true
+}
+
+func parseBoolParam(raw string) (bool, error) {
+	if raw == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(raw)
+	if err != nil {
+		return false, fmt.Errorf("parse bool %q: %w", raw, err)
+	}
+	return b, nil
+}
+
+// recordValue passes a held record's JSON through as is, or nothing for a
+// record whose value is not filled in yet.
+func recordValue(value []byte) any {
+	if value == nil {
+		return nil
+	}
+	return json.RawMessage(value)
+}
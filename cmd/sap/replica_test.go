@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/bluesky-social/indigo/atproto/auth/oauth"
+	"github.com/habitat-network/habitat/api/habitat"
+	"github.com/habitat-network/habitat/internal/db/testutil"
+	"github.com/habitat-network/habitat/pkg/oauthclient"
+	"github.com/habitat-network/habitat/pkg/sap"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testReplicaSpace = "at://did:plc:owner/space/network.habitat.space/s1"
+	testReplicaRepo  = "did:plc:alice"
+	testReplicaOrg   = "did:plc:org"
+)
+
+func openReplicaTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	db := testutil.NewDB(t)
+	store, err := oauthclient.NewGormStore(db)
+	require.NoError(t, err)
+	cfg := oauth.NewPublicConfig(
+		"https://example.com/client-metadata.json",
+		"https://example.com/oauth-callback",
+		[]string{"atproto"},
+	)
+	oauthApp := oauth.NewClientApp(&cfg, store)
+
+	s, err := sap.New(sap.Config{DB: db, OAuthClient: oauthApp, Replica: true})
+	require.NoError(t, err)
+
+	require.NoError(t, db.Table("space_accesses").Create(map[string]any{
+		"space": testReplicaSpace, "did": testReplicaOrg, "session_id": "sess",
+	}).Error)
+	require.NoError(t, db.Table("repos").Create(map[string]any{
+		"space": testReplicaSpace, "did": testReplicaRepo,
+		"rev": "3kaaaaaaaaaaa", "state": "active",
+	}).Error)
+	for _, rkey := range []string{"k1", "k2"} {
+		require.NoError(t, db.Table("repo_records").Create(map[string]any{
+			"space": testReplicaSpace, "did": testReplicaRepo,
+			"collection": "network.habitat.test", "rkey": rkey,
+			"cid": "bafy" + rkey, "value": []byte(`{"text":"` + rkey + `"}`),
+		}).Error)
+	}
+
+	server := NewSapServer(s, oauthApp)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/xrpc/network.habitat.space.getRecord", server.handleReplicaGetRecord)
+	mux.HandleFunc("/xrpc/network.habitat.space.listRecords", server.handleReplicaListRecords)
+	mux.HandleFunc("/xrpc/network.habitat.space.listRepos", server.handleReplicaListRepos)
+	httpServer := httptest.NewServer(mux)
+	t.Cleanup(httpServer.Close)
+	return httpServer
+}
+
+func getReplica(
+	t *testing.T,
+	srv *httptest.Server,
+	method, caller string,
+	params url.Values,
+) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet,
+		srv.URL+"/xrpc/"+method+"?"+params.Encode(), nil)
+	require.NoError(t, err)
+	if caller != "" {
+		req.Header.Set(habitatDIDHeader, caller)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = resp.Body.Close() })
+	return resp
+}
+
+func TestServerReplicaGetRecord(t *testing.T) {
+	t.Parallel()
+
+	srv := openReplicaTestServer(t)
+	params := url.Values{
+		"space": {testReplicaSpace}, "repo": {testReplicaRepo},
+		"collection": {"network.habitat.test"}, "rkey": {"k2"},
+	}
+
+	resp := getReplica(t, srv, "network.habitat.space.getRecord", testReplicaOrg, params)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var out habitat.NetworkHabitatSpaceGetRecordOutput
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+	require.Equal(t, "bafyk2", out.Cid)
+	require.Equal(t, map[string]any{"text": "k2"}, out.Value)
+	require.Equal(t, testReplicaSpace+"/"+testReplicaRepo+"/network.habitat.test/k2", out.Uri)
+
+	params.Set("rkey", "k9")
+	resp = getReplica(t, srv, "network.habitat.space.getRecord", testReplicaOrg, params)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestServerReplicaScopesToCallerAccess(t *testing.T) {
+	t.Parallel()
+
+	srv := openReplicaTestServer(t)
+	params := url.Values{"space": {testReplicaSpace}}
+
+	resp := getReplica(t, srv, "network.habitat.space.listRepos", "", params)
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp = getReplica(t, srv, "network.habitat.space.listRepos", "did:plc:stranger", params)
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	resp = getReplica(t, srv, "network.habitat.space.listRepos", testReplicaOrg, params)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var out habitat.NetworkHabitatSpaceListReposOutput
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+	require.Len(t, out.Repos, 1)
+	require.Equal(t, testReplicaRepo, out.Repos[0].Did)
+	require.Equal(t, "3kaaaaaaaaaaa", out.Repos[0].Rev)
+}
+
+func TestServerReplicaListRecordsPages(t *testing.T) {
+	t.Parallel()
+
+	srv := openReplicaTestServer(t)
+	params := url.Values{
+		"space": {testReplicaSpace}, "repo": {testReplicaRepo},
+		"limit": {"1"}, "excludeValues": {"true"},
+	}
+
+	resp := getReplica(t, srv, "network.habitat.space.listRecords", testReplicaOrg, params)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var first habitat.NetworkHabitatSpaceListRecordsOutput
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&first))
+	require.Len(t, first.Records, 1)
+	require.Equal(t, "k1", first.Records[0].Rkey)
+	require.Nil(t, first.Records[0].Value)
+	require.NotEmpty(t, first.Cursor)
+
+	params.Set("cursor", first.Cursor)
+	params.Del("excludeValues")
+	resp = getReplica(t, srv, "network.habitat.space.listRecords", testReplicaOrg, params)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var second habitat.NetworkHabitatSpaceListRecordsOutput
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&second))
+	require.Len(t, second.Records, 1)
+	require.Equal(t, "k2", second.Records[0].Rkey)
+	require.Equal(t, map[string]any{"text": "k2"}, second.Records[0].Value)
+}
@@ -2,15 +2,18 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/bluesky-social/indigo/atproto/auth/oauth"
 	"github.com/bluesky-social/indigo/atproto/syntax"
 	"github.com/habitat-network/habitat/internal/httpx"
+	habitat_syntax "github.com/habitat-network/habitat/internal/syntax"
 	"github.com/habitat-network/habitat/pkg/sap"
 )
 
@@ -82,6 +85,101 @@ func (s *server) handleListOrgs(w http.ResponseWriter, r *http.Request) {
 	httpx.WriteJSON(r.Context(), w, map[string]any{"orgs": orgs})
 }
 
+// handleRemoveSession stops syncing on behalf of a DID and drops every space
+// no other session can access. With emitDeletes, consumers are sent a delete
+// for each record those spaces held.
+func (s *server) handleRemoveSession(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		DID         string `json:"did"`
+		EmitDeletes bool   `json:"emitDeletes"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	did, err := syntax.ParseDID(req.DID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid did: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	dropped, err := s.sap.RemoveSession(r.Context(), did, req.EmitDeletes)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("remove session: %s", err), http.StatusInternalServerError)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "session removed", "did", did, "dropped_spaces", len(dropped))
+	if dropped == nil {
+		dropped = []habitat_syntax.SpaceURI{}
+	}
+	httpx.WriteJSON(r.Context(), w, map[string]any{"droppedSpaces": dropped})
+}
+
+// sessionProgress is the JSON form of sap.BackfillProgress.
+type sessionProgress struct {
+	DID              string     `json:"did"`
+	CrawlState       string     `json:"crawlState,omitempty"`
+	SpacesDiscovered int        `json:"spacesDiscovered"`
+	SpacesTracked    int        `json:"spacesTracked"`
+	ReposTracked     int64      `json:"reposTracked"`
+	ReposActive      int64      `json:"reposActive"`
+	ReposPending     int64      `json:"reposPending"`
+	ReposSyncing     int64      `json:"reposSyncing"`
+	ReposDesynced    int64      `json:"reposDesynced"`
+	ReposErrored     int64      `json:"reposErrored"`
+	ReposUnsynced    int64      `json:"reposUnsynced"`
+	ReposFailed      int64      `json:"reposFailed"`
+	RecordsSynced    int64      `json:"recordsSynced"`
+	RecordsRemaining int64      `json:"recordsRemaining"`
+	Complete         bool       `json:"complete"`
+	CompletedAt      *time.Time `json:"completedAt,omitempty"`
+}
+
+// handleSessionProgress reports how far a session's backfill has got
+// (?did=), so a caller that just added it can tell when sap has caught up.
+func (s *server) handleSessionProgress(w http.ResponseWriter, r *http.Request) {
+	did, err := syntax.ParseDID(r.URL.Query().Get("did"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid did: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	p, err := s.sap.BackfillProgress(r.Context(), did)
+	if errors.Is(err, sap.ErrUnknownSession) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	out := sessionProgress{
+		DID:              p.DID.String(),
+		CrawlState:       p.CrawlState,
+		SpacesDiscovered: p.SpacesDiscovered,
+		SpacesTracked:    p.SpacesTracked,
+		ReposTracked:     p.ReposTracked,
+		ReposActive:      p.ReposActive,
+		ReposPending:     p.ReposPending,
+		ReposSyncing:     p.ReposSyncing,
+		ReposDesynced:    p.ReposDesynced,
+		ReposErrored:     p.ReposErrored,
+		ReposUnsynced:    p.ReposUnsynced,
+		ReposFailed:      p.ReposFailed,
+		RecordsSynced:    p.RecordsSynced,
+		RecordsRemaining: p.RecordsRemaining,
+		Complete:         p.Complete,
+	}
+	if p.Complete {
+		out.CompletedAt = &p.CompletedAt
+	}
+	httpx.WriteJSON(r.Context(), w, out)
+}
+
 func (s *server) handleOAuthCallback(w http.ResponseWriter, r *http.Request) {
 	sessionData, err := s.oauthClient.ProcessCallback(r.Context(), r.URL.Query())
 	if err != nil {
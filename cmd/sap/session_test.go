@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bluesky-social/indigo/atproto/auth/oauth"
+	"github.com/habitat-network/habitat/internal/db/testutil"
+	"github.com/habitat-network/habitat/pkg/oauthclient"
+	"github.com/habitat-network/habitat/pkg/sap"
+	"github.com/stretchr/testify/require"
+)
+
+// openSessionTestServer wires up a sap server tracking testProxyDID and
+// returns an httptest server exposing /session/remove, /session/progress and
+// /org/list.
+func openSessionTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	db := testutil.NewDB(t)
+	store, err := oauthclient.NewGormStore(db)
+	require.NoError(t, err)
+	cfg := oauth.NewPublicConfig(
+		"https://example.com/client-metadata.json",
+		"https://example.com/oauth-callback",
+		[]string{"atproto"},
+	)
+	oauthApp := oauth.NewClientApp(&cfg, store)
+
+	s, err := sap.New(sap.Config{DB: db, OAuthClient: oauthApp})
+	require.NoError(t, err)
+	require.NoError(t, s.AddSession(t.Context(), testProxyDID, "session-1"))
+
+	server := NewSapServer(s, oauthApp)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/session/remove", server.handleRemoveSession)
+	mux.HandleFunc("/org/list", server.handleListOrgs)
+	mux.HandleFunc("/session/progress", server.handleSessionProgress)
+	httpServer := httptest.NewServer(mux)
+	t.Cleanup(httpServer.Close)
+	return httpServer
+}
+
+func TestServerRemoveSessionStopsTrackingDID(t *testing.T) {
+	t.Parallel()
+
+	httpServer := openSessionTestServer(t)
+
+	resp, err := http.Post(
+		httpServer.URL+"/session/remove",
+		"application/json",
+		strings.NewReader(`{"did":"`+testProxyDID+`","emitDeletes":true}`),
+	)
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var out struct {
+		DroppedSpaces []string `json:"droppedSpaces"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+	require.NotNil(t, out.DroppedSpaces)
+	require.Empty(t, out.DroppedSpaces)
+
+	list, err := http.Get(httpServer.URL + "/org/list")
+	require.NoError(t, err)
+	defer func() { _ = list.Body.Close() }()
+	var orgs struct {
+		Orgs []string `json:"orgs"`
+	}
+	require.NoError(t, json.NewDecoder(list.Body).Decode(&orgs))
+	require.Empty(t, orgs.Orgs)
+}
+
+func TestServerRemoveSessionRejectsInvalidDID(t *testing.T) {
+	t.Parallel()
+
+	httpServer := openSessionTestServer(t)
+
+	resp, err := http.Post(
+		httpServer.URL+"/session/remove",
+		"application/json",
+		strings.NewReader(`{"did":"not-a-did"}`),
+	)
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestServerSessionProgress(t *testing.T) {
+	t.Parallel()
+
+	httpServer := openSessionTestServer(t)
+
+	var out sessionProgress
+	getJSON(t, httpServer.URL+"/session/progress?did="+testProxyDID, &out)
+	require.Equal(t, testProxyDID, out.DID)
+	require.False(t, out.Complete)
+	require.Nil(t, out.CompletedAt)
+	require.Zero(t, out.ReposTracked)
+
+	resp, err := http.Get(httpServer.URL + "/session/progress?did=did:plc:stranger")
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp, err = http.Get(httpServer.URL + "/session/progress?did=not-a-did")
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
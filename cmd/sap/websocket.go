@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"log/slog"
 	"net/http"
 
@@ -17,25 +18,70 @@ var outboxUpgrader = websocket.Upgrader{
 }
 
 // outboxWireMessage is the JSON wire format for a single outbox event sent
-// over the channel websocket.
+// over the channel websocket. value is null for a delete or a resync; uri is
+// empty for a resync, which covers the whole repo, and for a backfillComplete,
+// whose repo is the DID of the session that finished backfilling.
 type outboxWireMessage struct {
-	ID    uint            `json:"id"`
-	URI   string          `json:"uri"`
-	Value json.RawMessage `json:"value"`
+	ID        uint            `json:"id"`
+	Action    string          `json:"action,omitempty"`
+	Space     string          `json:"space,omitempty"`
+	Repo      string          `json:"repo,omitempty"`
+	URI       string          `json:"uri"`
+	Rev       string          `json:"rev,omitempty"`
+	Cid       string          `json:"cid,omitempty"`
+	Prev      string          `json:"prev,omitempty"`
+	Value     json.RawMessage `json:"value"`
+	PrevValue json.RawMessage `json:"prevValue,omitempty"`
+	// Attempts counts the group's failed deliveries of the message, plus this
+	// one.
+	Attempts int `json:"attempts"`
+}
+
+func toWireMessage(msg outbox.Message) outboxWireMessage {
+	return outboxWireMessage{
+		ID:        msg.ID,
+		Action:    string(msg.Action),
+		Space:     msg.Space.String(),
+		Repo:      msg.Repo.String(),
+		URI:       msg.URI.String(),
+		Rev:       msg.Rev.String(),
+		Cid:       msg.Cid,
+		Prev:      msg.PrevCid,
+		Value:     msg.Value,
+		PrevValue: msg.PrevValue,
+		Attempts:  msg.Attempts,
+	}
 }
 
 // outboxAck is the JSON wire format a client sends back to acknowledge a
-// delivered message.
+// delivered message, or with nack set, to report that processing it failed
+// and why. A nacked message is redelivered until it runs out of attempts and
+// is dead-lettered.
 type outboxAck struct {
-	ID uint `json:"id"`
+	ID     uint   `json:"id"`
+	Nack   bool   `json:"nack,omitempty"`
+	Reason string `json:"reason,omitempty"`
 }
 
 // handleOutboxChannel streams outbox events to a connected websocket client
 // in delivery order. A message is held until the client acks it by ID; only
 // once acked is it marked processed so [sap.Sap.Poll] stops redelivering
 // it. Unacked messages (e.g. the client disconnects) are redelivered on the
-// next connection.
+// next connection. A client that cannot process a message nacks it with a
+// reason; it is redelivered once the rest of the batch is settled, or
+// dead-lettered when it has used up the group's attempts.
+//
+// The optional "group" query parameter names the consumer group the client
+// reads as. Each group acks independently, so several services can each
+// receive the full stream from one sap; connections without it share the
+// default group.
 func (s *server) handleOutboxChannel(w http.ResponseWriter, r *http.Request) {
+	ob, err := s.sap.ConsumerGroup(r.Context(), r.URL.Query().Get("group"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("consumer group: %s", err), http.StatusInternalServerError)
+		return
+	}
+
 	conn, err := outboxUpgrader.Upgrade(w, r, nil)
 	if err != nil {
 		slog.ErrorContext(r.Context(), "upgrade outbox websocket", "err", err)
@@ -46,7 +92,7 @@ func (s *server) handleOutboxChannel(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithCancel(r.Context())
 	defer cancel()
 
-	acks := make(chan uint)
+	acks := make(chan outboxAck)
 	go func() {
 		defer cancel()
 		for {
@@ -55,7 +101,7 @@ func (s *server) handleOutboxChannel(w http.ResponseWriter, r *http.Request) {
 				return
 			}
 			select {
-			case acks <- ack.ID:
+			case acks <- ack:
 			case <-ctx.Done():
 				return
 			}
@@ -65,17 +111,13 @@ func (s *server) handleOutboxChannel(w http.ResponseWriter, r *http.Request) {
 	pending := map[uint]outbox.Message{}
 	for {
 		if len(pending) == 0 {
-			msgs, err := s.sap.Outbox().Poll(ctx, outboxPollLimit)
+			msgs, err := ob.Poll(ctx, outboxPollLimit)
 			if err != nil {
 				slog.ErrorContext(ctx, "poll outbox", "err", err)
 				return
 			}
 			for _, msg := range msgs {
-				if err := conn.WriteJSON(outboxWireMessage{
-					ID:    msg.ID,
-					URI:   msg.URI.String(),
-					Value: msg.Value,
-				}); err != nil {
+				if err := conn.WriteJSON(toWireMessage(msg)); err != nil {
 					slog.InfoContext(ctx, "write outbox message", "err", err)
 					return
 				}
@@ -86,16 +128,22 @@ func (s *server) handleOutboxChannel(w http.ResponseWriter, r *http.Request) {
 		select {
 		case <-ctx.Done():
 			return
-		case id := <-acks:
-			if _, ok := pending[id]; !ok {
+		case ack := <-acks:
+			if _, ok := pending[ack.ID]; !ok {
 				continue
 			}
-			if err := s.sap.Outbox().Ack(ctx, id); err != nil {
-				slog.ErrorContext(ctx, "ack outbox message", "id", id, "err", err)
+			if ack.Nack {
+				err = ob.Nack(ctx, ack.ID, ack.Reason)
+			} else {
+				err = ob.Ack(ctx, ack.ID)
+			}
+			if err != nil {
+				slog.ErrorContext(ctx, "settle outbox message",
+					"id", ack.ID, "nack", ack.Nack, "err", err)
 				continue
 			}
-			delete(pending, id)
-		case <-s.sap.Outbox().Watch():
+			delete(pending, ack.ID)
+		case <-ob.Watch():
 		}
 	}
 }
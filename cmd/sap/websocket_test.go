@@ -1,6 +1,7 @@
 package main
 
 import (
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -40,6 +41,10 @@ func openOutboxTestServer(t *testing.T) (*httptest.Server, *sap.Sap, *gorm.DB) {
 	server := NewSapServer(s, oauthApp)
 	mux := http.NewServeMux()
 	mux.HandleFunc("/channel", server.handleOutboxChannel)
+	mux.HandleFunc("/admin/outbox/dead-letters", server.handleAdminDeadLetters)
+	mux.HandleFunc("/admin/outbox/dead-letters/replay", server.handleAdminReplayDeadLetter)
+	mux.HandleFunc("/admin/outbox/dead-letters/discard", server.handleAdminDiscardDeadLetter)
+	mux.HandleFunc("/admin/outbox/max-attempts", server.handleAdminMaxAttempts)
 	httpServer := httptest.NewServer(mux)
 	t.Cleanup(httpServer.Close)
 
@@ -49,8 +54,9 @@ func openOutboxTestServer(t *testing.T) (*httptest.Server, *sap.Sap, *gorm.DB) {
 func createOutboxRow(t *testing.T, db *gorm.DB, uri, value string) uint {
 	t.Helper()
 	require.NoError(t, db.Table("outbox_messages").Create(map[string]any{
-		"uri":   uri,
-		"value": []byte(value),
+		"action": "create",
+		"uri":    uri,
+		"value":  []byte(value),
 	}).Error)
 
 	var id uint
@@ -88,6 +94,7 @@ func TestServer_OutboxChannelDeliversAndAcks(t *testing.T) {
 	require.NoError(t, conn.ReadJSON(&msg))
 	require.Equal(t, id, msg.ID)
 	require.Equal(t, uri, msg.URI)
+	require.Equal(t, "create", msg.Action)
 	require.JSONEq(t, `{"text":"hello"}`, string(msg.Value))
 
 	require.NoError(t, conn.WriteJSON(outboxAck{ID: msg.ID}))
@@ -128,3 +135,87 @@ func TestServer_OutboxChannelRedeliversUnackedAfterReconnect(t *testing.T) {
 	require.NoError(t, conn2.ReadJSON(&msg2))
 	require.Equal(t, id, msg2.ID)
 }
+
+func TestServer_OutboxChannelGroupsAckIndependently(t *testing.T) {
+	t.Parallel()
+	httpServer, s, db := openOutboxTestServer(t)
+
+	uri := "at://did:plc:org/space/network.habitat.space/my-space/did:plc:member/network.habitat.note/k1"
+	id := createOutboxRow(t, db, uri, `{"text":"hello"}`)
+
+	wsURL := "ws" + strings.TrimPrefix(httpServer.URL, "http") + "/channel?group=search"
+	conn, resp, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	require.NoError(t, err)
+	if resp != nil {
+		t.Cleanup(func() { _ = resp.Body.Close() })
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	var msg outboxWireMessage
+	require.NoError(t, conn.ReadJSON(&msg))
+	require.Equal(t, id, msg.ID)
+	require.NoError(t, conn.WriteJSON(outboxAck{ID: msg.ID}))
+
+	search, err := s.ConsumerGroup(t.Context(), "search")
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		remaining, err := search.Poll(t.Context(), 10)
+		require.NoError(t, err)
+		return len(remaining) == 0
+	}, 5*time.Second, 50*time.Millisecond, "expected message to be acked for the group")
+
+	// The search group's ack leaves the message pending for the default group.
+	remaining, err := s.Outbox().Poll(t.Context(), 10)
+	require.NoError(t, err)
+	require.Len(t, remaining, 1)
+}
+
+func TestServer_OutboxChannelNackDeadLetters(t *testing.T) {
+	t.Parallel()
+
+	httpServer, s, db := openOutboxTestServer(t)
+	resp := postJSON(t, httpServer.URL+"/admin/outbox/max-attempts", `{"maxAttempts":2}`)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	uri := "at://did:plc:org/space/network.habitat.space/my-space/did:plc:member/network.habitat.note/k1"
+	id := createOutboxRow(t, db, uri, `{"text":"hello"}`)
+
+	conn := dialOutboxChannel(t, httpServer)
+
+	// A nacked message comes straight back, counting the attempt.
+	var msg outboxWireMessage
+	require.NoError(t, conn.ReadJSON(&msg))
+	require.Equal(t, id, msg.ID)
+	require.Equal(t, 1, msg.Attempts)
+	require.NoError(t, conn.WriteJSON(outboxAck{ID: msg.ID, Nack: true, Reason: "first"}))
+	require.NoError(t, conn.ReadJSON(&msg))
+	require.Equal(t, id, msg.ID)
+	require.Equal(t, 2, msg.Attempts)
+
+	// Nacking the last attempt dead-letters it.
+	require.NoError(t, conn.WriteJSON(outboxAck{ID: msg.ID, Nack: true, Reason: "bad value"}))
+	var out struct {
+		DeadLetters []adminDeadLetter `json:"deadLetters"`
+	}
+	require.Eventually(t, func() bool {
+		getJSON(t, httpServer.URL+"/admin/outbox/dead-letters", &out)
+		return len(out.DeadLetters) == 1
+	}, 5*time.Second, 50*time.Millisecond, "expected message to be dead-lettered")
+	require.Equal(t, id, out.DeadLetters[0].Message.ID)
+	require.Equal(t, 2, out.DeadLetters[0].Message.Attempts)
+	require.Equal(t, "bad value", out.DeadLetters[0].Reason)
+	require.NoError(t, conn.Close())
+
+	// Replay hands it back to the group; discarding an unknown letter is a 404.
+	resp = postJSON(t, httpServer.URL+"/admin/outbox/dead-letters/replay",
+		fmt.Sprintf(`{"id":%d}`, out.DeadLetters[0].ID))
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	remaining, err := s.Outbox().Poll(t.Context(), 10)
+	require.NoError(t, err)
+	require.Len(t, remaining, 1)
+	require.Equal(t, id, remaining[0].ID)
+
+	resp = postJSON(t, httpServer.URL+"/admin/outbox/dead-letters/discard",
+		fmt.Sprintf(`{"id":%d}`, out.DeadLetters[0].ID))
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
@@ -1,29 +1,406 @@
 package main
 
-import "strings"
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
 
-// ExtractContent flattens every string value found anywhere in a record
-// (nested objects and arrays included) into a single space-separated string
-// suitable for full-text indexing. Non-string values (numbers, booleans,
-// null) are ignored.
-func ExtractContent(value map[string]any) string {
-	var sb strings.Builder
-	walkStrings(value, &sb)
-	return strings.TrimSpace(sb.String())
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"github.com/habitat-network/habitat/api/habitat"
+	habitat_syntax "github.com/habitat-network/habitat/internal/syntax"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// renderSchemaNSID is the collection of render schema records, which
+// describe the display fields of another collection.
+const renderSchemaNSID = syntax.NSID("network.habitat.render.schema")
+
+// Weight ranks where a field's text lands in a document. Title text counts
+// most in ranking, metadata least; body is everything in between.
+type Weight string
+
+const (
+	WeightTitle    Weight = "title"
+	WeightBody     Weight = "body"
+	WeightMetadata Weight = "metadata"
+)
+
+// FieldRule extracts the string (or list of strings) at Path, a dot-notation
+// path into the record value, at Weight. A path that crosses an array applies
+// to each of its elements.
+type FieldRule struct {
+	Path   string `json:"path"`
+	Weight Weight `json:"weight"`
+}
+
+// ExtractionRule says which fields of a collection's records are searchable.
+// Only the listed fields are extracted.
+type ExtractionRule struct {
+	Fields []FieldRule `json:"fields"`
+}
+
+// ExtractionRules maps a collection to its rule.
+type ExtractionRules map[syntax.NSID]ExtractionRule
+
+// LoadExtractionRules reads rules from a JSON file of the form
+// {"<collection>": {"fields": [{"path": "name", "weight": "title"}]}}.
+func LoadExtractionRules(path string) (ExtractionRules, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read extraction rules: %w", err)
+	}
+	var rules ExtractionRules
+	if err := json.Unmarshal(raw, &rules); err != nil {
+		return nil, fmt.Errorf("parse extraction rules: %w", err)
+	}
+	for collection, rule := range rules {
+		if _, err := syntax.ParseNSID(collection.String()); err != nil {
+			return nil, fmt.Errorf("extraction rules: invalid collection %q: %w", collection, err)
+		}
+		for _, field := range rule.Fields {
+			switch field.Weight {
+			case WeightTitle, WeightBody, WeightMetadata:
+			default:
+				return nil, fmt.Errorf(
+					"extraction rules: %s.%s: unknown weight %q",
+					collection, field.Path, field.Weight,
+				)
+			}
+		}
+	}
+	return rules, nil
+}
+
+// Extracted is a record's searchable text, split by weight.
+type Extracted struct {
+	Title    string
+	Body     string
+	Metadata string
+}
+
+// renderSchemaRule is the extraction rule an org's render schema record
+// implies for its target collection.
+type renderSchemaRule struct {
+	URI        string `gorm:"column:uri;primaryKey"`
+	OrgDID     string `gorm:"column:org_did;index:idx_render_schema_target"`
+	Collection string `gorm:"column:collection;index:idx_render_schema_target"`
+	SpaceURI   string `gorm:"column:space_uri"`
+	Repo       string `gorm:"column:repo"`
+	Rev        string `gorm:"column:rev"`
+	Fields     string `gorm:"column:fields"`
+	UpdatedAt  time.Time
+}
+
+func (renderSchemaRule) TableName() string { return "search_render_schema_rules" }
+
+// Extractor turns record values into weighted searchable text. A
+// collection's fields come from, in order of precedence: the configured
+// rules, the render schema the record's org publishes for the collection,
+// and failing both a walk over every string in the record that skips
+// identifiers, blobs and enum values.
+//
+// Render schemas are learned from the records the indexer drains, so a
+// schema change applies to records indexed after it, not to those already
+// in the index.
+type Extractor struct {
+	db         *gorm.DB
+	configured ExtractionRules
+
+	mu      sync.RWMutex
+	schemas map[schemaKey]ExtractionRule
+}
+
+type schemaKey struct {
+	org        syntax.DID
+	collection syntax.NSID
+}
+
+func NewExtractor(db *gorm.DB, configured ExtractionRules) (*Extractor, error) {
+	if err := db.AutoMigrate(&renderSchemaRule{}); err != nil {
+		return nil, fmt.Errorf("migrate search_render_schema_rules: %w", err)
+	}
+	e := &Extractor{db: db, configured: configured}
+	if err := e.loadSchemas(context.Background()); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// loadSchemas rebuilds the in-memory schema rules from the database. When an
+// org has several schemas for one collection, the latest wins.
+func (e *Extractor) loadSchemas(ctx context.Context) error {
+	var rows []renderSchemaRule
+	if err := e.db.WithContext(ctx).Order("updated_at ASC").Find(&rows).Error; err != nil {
+		return fmt.Errorf("load render schema rules: %w", err)
+	}
+	schemas := make(map[schemaKey]ExtractionRule, len(rows))
+	for _, row := range rows {
+		var fields []FieldRule
+		if err := json.Unmarshal([]byte(row.Fields), &fields); err != nil {
+			return fmt.Errorf("decode render schema rule %s: %w", row.URI, err)
+		}
+		key := schemaKey{org: syntax.DID(row.OrgDID), collection: syntax.NSID(row.Collection)}
+		schemas[key] = ExtractionRule{Fields: fields}
+	}
+	e.mu.Lock()
+	e.schemas = schemas
+	e.mu.Unlock()
+	return nil
+}
+
+// Extract returns the searchable text of a record in collection, owned by
+// org.
+func (e *Extractor) Extract(
+	org syntax.DID,
+	collection syntax.NSID,
+	value map[string]any,
+) Extracted {
+	rule, ok := e.configured[collection]
+	if !ok {
+		e.mu.RLock()
+		rule, ok = e.schemas[schemaKey{org: org, collection: collection}]
+		e.mu.RUnlock()
+	}
+	var x extraction
+	if ok {
+		for _, field := range rule.Fields {
+			for _, s := range lookupStrings(value, strings.Split(field.Path, ".")) {
+				x.add(field.Weight, s)
+			}
+		}
+	} else {
+		walkFields(value, true, &x)
+	}
+	return x.result()
+}
+
+// LearnSchema records the rule a render schema record implies for its target
+// collection: text and list fields are extracted at the weight their
+// display priority suggests, and url, datetime and badge fields, which hold
+// identifiers, timestamps and enum values, are left out.
+func (e *Extractor) LearnSchema(
+	ctx context.Context,
+	uri habitat_syntax.SpaceRecordURI,
+	rev syntax.TID,
+	raw json.RawMessage,
+) error {
+	var schema habitat.NetworkHabitatRenderSchema
+	if err := json.Unmarshal(raw, &schema); err != nil {
+		return fmt.Errorf("unmarshal render schema: %w", err)
+	}
+	target, err := syntax.ParseNSID(schema.TargetLexicon)
+	if err != nil {
+		return fmt.Errorf("render schema target: %w", err)
+	}
+	var fields []FieldRule
+	for _, f := range schema.Fields {
+		switch f.DisplayType {
+		case "network.habitat.render.schema#text", "network.habitat.render.schema#list":
+		default:
+			continue
+		}
+		weight := WeightBody
+		switch f.Priority {
+		case "network.habitat.render.schema#primary":
+			weight = WeightTitle
+		case "network.habitat.render.schema#metadata":
+			weight = WeightMetadata
+		}
+		fields = append(fields, FieldRule{Path: f.Path, Weight: weight})
+	}
+	encoded, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	row := renderSchemaRule{
+		URI:        uri.String(),
+		OrgDID:     uri.SpaceOwner().String(),
+		Collection: target.String(),
+		SpaceURI:   uri.SpaceURI().String(),
+		Repo:       uri.Repo().String(),
+		Rev:        rev.String(),
+		Fields:     string(encoded),
+		UpdatedAt:  time.Now(),
+	}
+	if err := e.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "uri"}},
+		UpdateAll: true,
+	}).Create(&row).Error; err != nil {
+		return fmt.Errorf("save render schema rule: %w", err)
+	}
+	return e.loadSchemas(ctx)
+}
+
+// ForgetSchema drops the rule learned from a deleted render schema record.
+func (e *Extractor) ForgetSchema(ctx context.Context, uri habitat_syntax.SpaceRecordURI) error {
+	res := e.db.WithContext(ctx).Delete(&renderSchemaRule{}, "uri = ?", uri.String())
+	if res.Error != nil {
+		return fmt.Errorf("delete render schema rule: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return nil
+	}
+	return e.loadSchemas(ctx)
+}
+
+// ResyncSchemas drops the rules learned from a repo's render schemas older
+// than rev, after sap rebuilt the repo from a snapshot taken at rev.
+func (e *Extractor) ResyncSchemas(
+	ctx context.Context,
+	space habitat_syntax.SpaceURI,
+	repo syntax.DID,
+	rev syntax.TID,
+) error {
+	res := e.db.WithContext(ctx).Delete(
+		&renderSchemaRule{},
+		"space_uri = ? AND repo = ? AND rev < ?",
+		space.String(), repo.String(), rev.String(),
+	)
+	if res.Error != nil {
+		return fmt.Errorf("resync render schema rules: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return nil
+	}
+	return e.loadSchemas(ctx)
 }
 
-func walkStrings(v any, sb *strings.Builder) {
+// titleKeys are the top-level fields taken as a record's title when no rule
+// covers its collection.
+var titleKeys = map[string]bool{
+	"title":       true,
+	"name":        true,
+	"displayName": true,
+	"subject":     true,
+	"headline":    true,
+}
+
+type extraction struct {
+	title, body, metadata []string
+}
+
+func (x *extraction) add(weight Weight, s string) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return
+	}
+	switch weight {
+	case WeightTitle:
+		x.title = append(x.title, s)
+	case WeightMetadata:
+		x.metadata = append(x.metadata, s)
+	default:
+		x.body = append(x.body, s)
+	}
+}
+
+func (x *extraction) result() Extracted {
+	return Extracted{
+		Title:    strings.Join(x.title, " "),
+		Body:     strings.Join(x.body, " "),
+		Metadata: strings.Join(x.metadata, " "),
+	}
+}
+
+// walkFields collects every string in v that reads as text. Keys starting
+// with "$" ($type, $link, $bytes), a record's langs and blob objects are
+// skipped whole.
+func walkFields(v any, top bool, x *extraction) {
 	switch val := v.(type) {
-	case string:
-		sb.WriteString(val)
-		sb.WriteString(" ")
 	case map[string]any:
+		if val["$type"] == "blob" {
+			return
+		}
+		for key, child := range val {
+			if strings.HasPrefix(key, "$") || (top && key == "langs") {
+				continue
+			}
+			if s, ok := child.(string); ok {
+				if !isText(s) {
+					continue
+				}
+				if top && titleKeys[key] {
+					x.add(WeightTitle, s)
+				} else {
+					x.add(WeightBody, s)
+				}
+				continue
+			}
+			walkFields(child, false, x)
+		}
+	case []any:
 		for _, child := range val {
-			walkStrings(child, sb)
+			if s, ok := child.(string); ok {
+				if isText(s) {
+					x.add(WeightBody, s)
+				}
+				continue
+			}
+			walkFields(child, false, x)
 		}
+	}
+}
+
+// lookupStrings returns the strings at path in v, fanning out over arrays.
+func lookupStrings(v any, path []string) []string {
+	switch val := v.(type) {
 	case []any:
+		var out []string
 		for _, child := range val {
-			walkStrings(child, sb)
+			out = append(out, lookupStrings(child, path)...)
+		}
+		return out
+	case map[string]any:
+		if len(path) == 0 {
+			return nil
+		}
+		return lookupStrings(val[path[0]], path[1:])
+	case string:
+		if len(path) == 0 {
+			return []string{val}
 		}
 	}
+	return nil
+}
+
+// isText reports whether s is prose rather than an identifier, link,
+// timestamp or enum value, none of which are worth matching a query against.
+func isText(s string) bool {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return false
+	}
+	if strings.ContainsAny(s, " \t\n") {
+		return true
+	}
+	if strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://") {
+		return false
+	}
+	if _, err := syntax.ParseDID(s); err == nil {
+		return false
+	}
+	if _, err := syntax.ParseATURI(s); err == nil {
+		return false
+	}
+	if _, err := habitat_syntax.ParseSpaceURI(s); err == nil {
+		return false
+	}
+	if _, err := syntax.ParseDatetime(s); err == nil {
+		return false
+	}
+	// Lexicon tokens and known values: an NSID, optionally with a #fragment.
+	nsid, _, _ := strings.Cut(s, "#")
+	if _, err := syntax.ParseNSID(nsid); err == nil {
+		return false
+	}
+	// CIDv1 in the base32 form atproto writes them in.
+	if strings.HasPrefix(s, "baf") && len(s) >= 40 {
+		return false
+	}
+	return true
 }
@@ -1,31 +1,148 @@
 package main
 
 import (
-	"strings"
+	"context"
+	"os"
+	"path/filepath"
 	"testing"
+
+	"github.com/habitat-network/habitat/api/habitat"
+	"github.com/habitat-network/habitat/internal/db/testutil"
+	"github.com/stretchr/testify/require"
 )
 
-func TestExtractContent_FlattensNestedStrings(t *testing.T) {
+const testCID = "bafkreibme22gw2h7y2h7tg2fhqotaqjucnbc24deqo72b6mkl2egezxhvy"
+
+func newTestExtractor(t *testing.T) *Extractor {
+	t.Helper()
+	extractor, err := NewExtractor(testutil.NewDB(t), nil)
+	require.NoError(t, err)
+	return extractor
+}
+
+func TestExtractor_FallbackWeightsTitlesAndSkipsIdentifiers(t *testing.T) {
 	value := map[string]any{
-		"title": "Quarterly Budget",
-		"body":  "Review the numbers",
-		"tags":  []any{"finance", "q3"},
+		"$type":     "network.habitat.meeting",
+		"title":     "Quarterly Budget",
+		"body":      "Review the numbers",
+		"tags":      []any{"finance", "q3"},
+		"status":    "network.habitat.meeting#scheduled",
+		"organizer": "did:plc:alice",
+		"link":      "https://example.com/agenda",
+		"startsAt":  "2026-03-01T10:00:00Z",
+		"ref":       "at://did:plc:alice/network.habitat.note/3jzfcijpj2z2a",
+		"cid":       testCID,
 		"meta": map[string]any{
 			"author": "alice",
 		},
+		"attachment": map[string]any{
+			"$type":    "blob",
+			"ref":      map[string]any{"$link": testCID},
+			"mimeType": "image/png",
+		},
 		"count": 42, // non-string values are ignored
+		"langs": []any{"en-US"},
 	}
 
-	got := ExtractContent(value)
-	for _, want := range []string{"Quarterly Budget", "Review the numbers", "finance", "q3", "alice"} {
-		if !strings.Contains(got, want) {
-			t.Errorf("ExtractContent() = %q, want to contain %q", got, want)
-		}
+	got := newTestExtractor(t).Extract("did:plc:org1", "network.habitat.meeting", value)
+
+	require.Equal(t, "Quarterly Budget", got.Title)
+	for _, want := range []string{"Review the numbers", "finance", "q3", "alice"} {
+		require.Contains(t, got.Body, want)
 	}
+	for _, skipped := range []string{
+		"meeting", "did:plc", "example.com", "2026", "bafk", "image", "en-US",
+	} {
+		require.NotContains(t, got.Body, skipped)
+	}
+}
+
+func TestExtractor_EmptyRecord(t *testing.T) {
+	require.Equal(t, Extracted{}, newTestExtractor(t).Extract("", "", map[string]any{}))
 }
 
-func TestExtractContent_EmptyRecord(t *testing.T) {
-	if got := ExtractContent(map[string]any{}); got != "" {
-		t.Errorf("ExtractContent(empty) = %q, want empty string", got)
+func TestExtractor_ConfiguredRulesWin(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{
+		"network.habitat.event": {"fields": [
+			{"path": "name", "weight": "title"},
+			{"path": "sessions.title", "weight": "body"}
+		]}
+	}`), 0o600))
+	rules, err := LoadExtractionRules(path)
+	require.NoError(t, err)
+	extractor, err := NewExtractor(testutil.NewDB(t), rules)
+	require.NoError(t, err)
+
+	got := extractor.Extract("did:plc:org1", "network.habitat.event", map[string]any{
+		"name":        "Board meeting",
+		"description": "not listed, so not extracted",
+		"sessions": []any{
+			map[string]any{"title": "Opening remarks"},
+			map[string]any{"title": "Budget vote"},
+		},
+	})
+	require.Equal(t, Extracted{Title: "Board meeting", Body: "Opening remarks Budget vote"}, got)
+}
+
+func TestLoadExtractionRules_RejectsUnknownWeight(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{
+		"network.habitat.event": {"fields": [{"path": "name", "weight": "heading"}]}
+	}`), 0o600))
+	_, err := LoadExtractionRules(path)
+	require.ErrorContains(t, err, "unknown weight")
+}
+
+func TestExtractor_LearnsRenderSchemaPerOrg(t *testing.T) {
+	ctx := context.Background()
+	db := testutil.NewDB(t)
+	extractor, err := NewExtractor(db, nil)
+	require.NoError(t, err)
+
+	schemaURI := recordURIFor("did:plc:org1", "network.habitat.render.schema", "events")
+	require.NoError(t, extractor.LearnSchema(ctx, schemaURI, "3jzfcijpj2z2a", mustMarshal(t,
+		habitat.NetworkHabitatRenderSchema{
+			TargetLexicon: "network.habitat.event",
+			Title:         "Event",
+			Fields: []habitat.NetworkHabitatRenderSchemaFieldSchema{
+				{
+					Path:        "name",
+					DisplayType: "network.habitat.render.schema#text",
+					Priority:    "network.habitat.render.schema#primary",
+				},
+				{
+					Path:        "location",
+					DisplayType: "network.habitat.render.schema#text",
+					Priority:    "network.habitat.render.schema#metadata",
+				},
+				{
+					Path:        "kind",
+					DisplayType: "network.habitat.render.schema#badge",
+					Priority:    "network.habitat.render.schema#secondary",
+				},
+			},
+		},
+	)))
+
+	value := map[string]any{
+		"name":     "Board meeting",
+		"location": "Room 4",
+		"kind":     "network.habitat.event#inPerson",
+		"notes":    "not in the schema",
 	}
+	want := Extracted{Title: "Board meeting", Metadata: "Room 4"}
+	require.Equal(t, want, extractor.Extract("did:plc:org1", "network.habitat.event", value))
+	// Another org's records of the same collection are not governed by it.
+	require.Contains(t,
+		extractor.Extract("did:plc:org2", "network.habitat.event", value).Body, "not in the schema")
+
+	// Rules survive a restart.
+	reloaded, err := NewExtractor(db, nil)
+	require.NoError(t, err)
+	require.Equal(t, want, reloaded.Extract("did:plc:org1", "network.habitat.event", value))
+
+	require.NoError(t, extractor.ForgetSchema(ctx, schemaURI))
+	require.Contains(t,
+		extractor.Extract("did:plc:org1", "network.habitat.event", value).Body, "not in the schema")
 }
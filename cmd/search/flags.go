@@ -3,14 +3,27 @@ package main
 import "github.com/urfave/cli/v3"
 
 var (
-	fPort     = "port"
-	fDB       = "db"
-	fPearHost = "pear-host"
-	fDomain   = "domain"
-	fSecret   = "secret"
-	fLogLevel = "log-level"
+	fPort         = "port"
+	fInternalPort = "internal-port"
+	fDB           = "db"
+	fPearHost     = "pear-host"
+	fDomain       = "domain"
+	fSecret       = "secret"
+	fLogLevel     = "log-level"
+
+	fOutboxMaxAttempts = "outbox-max-attempts"
+	fExtractionRules   = "extraction-rules"
+	fReplica           = "replica"
+
+	fInternalURL       = "internal-url"
+	fReindexOrg        = "org"
+	fReindexCollection = "collection"
+	fReindexResync     = "resync"
 )
 
+// getFlags returns the server's flags. The ones it cannot run without are
+// checked by run rather than marked Required, which urfave/cli would also
+// enforce on the reindex subcommand.
 func getFlags() []cli.Flag {
 	return []cli.Flag{
 		&cli.IntFlag{
@@ -19,17 +32,21 @@ func getFlags() []cli.Flag {
 			Value:   8091,
 			Sources: cli.NewValueSourceChain(cli.EnvVar("SEARCH_PORT")),
 		},
+		&cli.IntFlag{
+			Name:    fInternalPort,
+			Usage:   "Internal port serving the org and admin endpoints",
+			Value:   8092,
+			Sources: cli.NewValueSourceChain(cli.EnvVar("SEARCH_INTERNAL_PORT")),
+		},
 		&cli.StringFlag{
-			Name:     fDB,
-			Usage:    "Postgres connection string for the search index database",
-			Required: true,
-			Sources:  cli.NewValueSourceChain(cli.EnvVar("SEARCH_PGURL")),
+			Name:    fDB,
+			Usage:   "Database for the search index: a postgres:// DSN or sqlite://<path>",
+			Sources: cli.EnvVars("SEARCH_DB", "SEARCH_PGURL"),
 		},
 		&cli.StringFlag{
-			Name:     fPearHost,
-			Usage:    "Base URL of the pear instance to index, e.g. https://pear.example.com",
-			Required: true,
-			Sources:  cli.NewValueSourceChain(cli.EnvVar("SEARCH_PEAR_HOST")),
+			Name:    fPearHost,
+			Usage:   "Base URL of the pear instance to index, e.g. https://pear.example.com",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("SEARCH_PEAR_HOST")),
 		},
 		&cli.StringFlag{
 			Name:    fDomain,
@@ -38,10 +55,9 @@ func getFlags() []cli.Flag {
 			Sources: cli.NewValueSourceChain(cli.EnvVar("SEARCH_DOMAIN")),
 		},
 		&cli.StringFlag{
-			Name:     fSecret,
-			Usage:    "Secret used in sap's OAuth client-metadata signing",
-			Required: true,
-			Sources:  cli.NewValueSourceChain(cli.EnvVar("SEARCH_SECRET")),
+			Name:    fSecret,
+			Usage:   "Secret used in sap's OAuth client-metadata signing",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("SEARCH_SECRET")),
 		},
 		&cli.StringFlag{
 			Name:    fLogLevel,
@@ -49,5 +65,44 @@ func getFlags() []cli.Flag {
 			Value:   "info",
 			Sources: cli.NewValueSourceChain(cli.EnvVar("SEARCH_LOG_LEVEL")),
 		},
+		&cli.IntFlag{
+			Name:    fOutboxMaxAttempts,
+			Usage:   "Attempts to index a synced record before it is dead-lettered (0 = never)",
+			Value:   10,
+			Sources: cli.NewValueSourceChain(cli.EnvVar("SEARCH_OUTBOX_MAX_ATTEMPTS")),
+		},
+		&cli.StringFlag{
+			Name:    fExtractionRules,
+			Usage:   "JSON file of per-collection extraction rules (overrides render schemas)",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("SEARCH_EXTRACTION_RULES")),
+		},
+		&cli.BoolFlag{
+			Name:    fReplica,
+			Usage:   "Have sap keep synced record values, which reindex rebuilds documents from",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("SEARCH_REPLICA")),
+		},
+	}
+}
+
+func getReindexFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:    fInternalURL,
+			Usage:   "Base URL of the running search server's internal port",
+			Value:   "http://localhost:8092",
+			Sources: cli.NewValueSourceChain(cli.EnvVar("SEARCH_INTERNAL_URL")),
+		},
+		&cli.StringFlag{
+			Name:  fReindexOrg,
+			Usage: "Only reindex this org's documents (DID)",
+		},
+		&cli.StringFlag{
+			Name:  fReindexCollection,
+			Usage: "Only reindex documents of this collection (NSID)",
+		},
+		&cli.BoolFlag{
+			Name:  fReindexResync,
+			Usage: "Also have sap resync every repo in scope from a full snapshot",
+		},
 	}
 }
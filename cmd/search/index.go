@@ -2,10 +2,14 @@ package main
 
 import (
 	"context"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/bluesky-social/indigo/atproto/syntax"
+	"github.com/habitat-network/habitat/internal/db"
 	habitat_syntax "github.com/habitat-network/habitat/internal/syntax"
+	"gorm.io/gorm"
 )
 
 // Document is a single indexed record.
@@ -13,18 +17,82 @@ type Document struct {
 	URI        habitat_syntax.SpaceRecordURI // record URI, primary identity of a document
 	SpaceURI   habitat_syntax.SpaceURI
 	OrgDID     syntax.DID
+	Repo       syntax.DID  // DID of the record's author
+	Rev        syntax.TID  // repo revision the record was indexed at
 	Collection syntax.NSID // NSID of the record
-	Content    string      // extracted searchable text
-	UpdatedAt  time.Time
+	// Searchable text extracted from the record, by weight: Title ranks
+	// above Content, Content above Metadata.
+	Title    string
+	Content  string
+	Metadata string
+	// Language is the ISO 639 code of the language the text is written in,
+	// which the index stems it by; "" when it is not known.
+	Language  string
+	UpdatedAt time.Time
 }
 
 type QueryParams struct {
-	OrgDID    syntax.DID
+	OrgDID syntax.DID
+	// Spaces limits results to documents in these spaces, the ones the caller
+	// can read. The filter is applied before paging, so a cursor walks the
+	// caller's own results. A nil Spaces does not filter; an empty one
+	// matches nothing.
+	Spaces []habitat_syntax.SpaceURI
+	// Collections and Authors limit results to records of these collections
+	// and by these repos; empty does not filter.
+	Collections []syntax.NSID
+	Authors     []syntax.DID
+	// Since and Until bound UpdatedAt: Since inclusive, Until exclusive. A
+	// zero time leaves that end open.
+	Since time.Time
+	Until time.Time
+	// Language is the ISO 639 code of the language QueryText is written in.
+	// Without it the query is stemmed as every language the index knows.
+	Language  string
 	QueryText string
 	Limit     int
 	Cursor    string
 }
 
+// filters returns the SQL conditions, ANDed, and their arguments for every
+// filter in p other than the query text, on search_documents columns
+// qualified by prefix. Times are compared in UTC, the zone documents are
+// stored in, since SQLite compares them as text.
+func (p QueryParams) filters(prefix string) (string, []any) {
+	conds := []string{prefix + "org_did = ?"}
+	args := []any{p.OrgDID.String()}
+	in := func(column string, values []string) {
+		conds = append(conds, prefix+column+" IN ?")
+		args = append(args, values)
+	}
+	if p.Spaces != nil {
+		in("space_uri", stringsOf(p.Spaces))
+	}
+	if len(p.Collections) > 0 {
+		in("collection", stringsOf(p.Collections))
+	}
+	if len(p.Authors) > 0 {
+		in("repo", stringsOf(p.Authors))
+	}
+	if !p.Since.IsZero() {
+		conds = append(conds, prefix+"updated_at >= ?")
+		args = append(args, p.Since.UTC())
+	}
+	if !p.Until.IsZero() {
+		conds = append(conds, prefix+"updated_at < ?")
+		args = append(args, p.Until.UTC())
+	}
+	return strings.Join(conds, " AND "), args
+}
+
+func stringsOf[T ~string](values []T) []string {
+	out := make([]string, len(values))
+	for i, v := range values {
+		out[i] = string(v)
+	}
+	return out
+}
+
 type Result struct {
 	URI        habitat_syntax.SpaceRecordURI
 	SpaceURI   habitat_syntax.SpaceURI
@@ -33,17 +101,65 @@ type Result struct {
 	Rank       float64
 }
 
+// FacetCount is the number of matching documents with one facet value.
+type FacetCount struct {
+	Value string
+	Count int64
+}
+
+// Facets count every document a query matches, across all pages, by
+// collection and by space, most common first.
+type Facets struct {
+	Collections []FacetCount
+	Spaces      []FacetCount
+}
+
 type QueryResult struct {
 	Results    []Result
 	NextCursor string
+	Facets     Facets
 }
 
 // Index is the storage/query backend for indexed records. postgresFTSIndex
-// (full-text search) is the only implementation today; a future
-// pgvector+Ollama implementation satisfies the same interface so the
-// indexing pipeline and HTTP handler never change.
+// and sqliteFTSIndex implement full-text search on each database pear runs
+// on; a future pgvector+Ollama implementation satisfies the same interface
+// so the indexing pipeline and HTTP handler never change.
 type Index interface {
 	Upsert(ctx context.Context, doc Document) error
 	Delete(ctx context.Context, uri habitat_syntax.SpaceRecordURI) error
+	// Resync drops every document in a repo indexed at a rev older than rev,
+	// after sap rebuilt the repo from a snapshot taken at rev.
+	Resync(
+		ctx context.Context,
+		space habitat_syntax.SpaceURI,
+		repo syntax.DID,
+		rev syntax.TID,
+	) error
 	Query(ctx context.Context, params QueryParams) (QueryResult, error)
+	// Rebuild replaces every document in scope with the documents fill puts.
+	// fill writes to a shadow copy while queries keep reading the live
+	// documents, and other writes keep changing them. Once fill returns,
+	// catchUp is called and reports those changes, which are carried over to
+	// the shadow copy before it is swapped in atomically; the caller holds
+	// off further writes from then until Rebuild returns. If fill fails the
+	// index is left as it was and catchUp is not called.
+	Rebuild(
+		ctx context.Context,
+		scope RebuildScope,
+		fill func(put func(Document) error) error,
+		catchUp func() RebuildCatchUp,
+	) error
+}
+
+// openIndex returns the full-text Index for the database dsn names, picked by
+// dialect the way internal/db opens it.
+func openIndex(gormDB *gorm.DB, dsn string) (Index, error) {
+	switch db.ParseDialect(dsn) {
+	case db.Postgres:
+		return newPostgresFTSIndex(gormDB)
+	case db.Sqlite:
+		return newSQLiteFTSIndex(gormDB)
+	default:
+		return nil, fmt.Errorf("no search index for database %q", dsn)
+	}
 }
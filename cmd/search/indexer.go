@@ -5,27 +5,57 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"sync"
 	"time"
 
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	habitat_syntax "github.com/habitat-network/habitat/internal/syntax"
 	"github.com/habitat-network/habitat/pkg/sap/outbox"
 )
 
 const indexerBatchSize = 50
 
 // Indexer drains an outbox.Outbox and feeds an Index, so the search index
-// tracks every record sap has synced for the org (backfill and live
-// updates alike, since both flow through the same outbox).
+// tracks every record sap has synced for each org search indexes (backfill
+// and live updates alike, since both flow through the same outbox).
 type Indexer struct {
-	index  Index
-	outbox outbox.Outbox
+	index     Index
+	outbox    outbox.Outbox
+	extractor *Extractor
+
+	// mu is held while a batch is indexed, and by Reindex while it swaps
+	// the rebuilt documents in, which holds up the outbox only for the swap.
+	mu sync.Mutex
+	// changed, while a Reindex fills its shadow copy, collects the changes
+	// the outbox applies to the live documents, for the swap to carry over.
+	// Guarded by mu.
+	changed *rebuildChanges
+	// reindexing is held by the one Reindex allowed at a time.
+	reindexing sync.Mutex
+}
+
+// rebuildChanges collects a RebuildCatchUp.
+type rebuildChanges struct {
+	uris    map[habitat_syntax.SpaceRecordURI]struct{}
+	resyncs []RebuildResync
+}
+
+func (c *rebuildChanges) catchUp() RebuildCatchUp {
+	out := RebuildCatchUp{Resyncs: c.resyncs}
+	for uri := range c.uris {
+		out.URIs = append(out.URIs, uri)
+	}
+	return out
 }
 
-func NewIndexer(index Index, outbox outbox.Outbox) *Indexer {
-	return &Indexer{index: index, outbox: outbox}
+func NewIndexer(index Index, outbox outbox.Outbox, extractor *Extractor) *Indexer {
+	return &Indexer{index: index, outbox: outbox, extractor: extractor}
 }
 
 // Run drains the outbox until ctx is canceled, blocking on outbox.Watch()
-// whenever there are no pending messages.
+// whenever there are no pending messages. A message that fails to index is
+// nacked with the error, so sap redelivers it until it runs out of attempts
+// and is dead-lettered.
 func (ix *Indexer) Run(ctx context.Context) error {
 	for {
 		msgs, err := ix.outbox.Poll(ctx, indexerBatchSize)
@@ -40,40 +70,109 @@ func (ix *Indexer) Run(ctx context.Context) error {
 			}
 			continue
 		}
+		ix.mu.Lock()
 		for _, msg := range msgs {
 			if err := ix.handleMessage(ctx, msg); err != nil {
-				slog.ErrorContext(ctx, "failed to index message", "err", err, "uri", msg.URI)
+				slog.ErrorContext(ctx, "failed to index message",
+					"err", err, "uri", msg.URI, "attempts", msg.Attempts)
+				if err := ix.outbox.Nack(ctx, msg.ID, err.Error()); err != nil {
+					slog.ErrorContext(ctx, "failed to nack outbox message",
+						"err", err, "uri", msg.URI)
+				}
 				continue
 			}
 			if err := ix.outbox.Ack(ctx, msg.ID); err != nil {
 				slog.ErrorContext(ctx, "failed to ack outbox message", "err", err, "uri", msg.URI)
 			}
 		}
+		ix.mu.Unlock()
 	}
 }
 
 func (ix *Indexer) handleMessage(ctx context.Context, msg outbox.Message) error {
-	if isDeleted(msg.Value) {
+	switch msg.Action {
+	case outbox.ActionDelete:
+		if msg.URI.Collection() == renderSchemaNSID {
+			if err := ix.extractor.ForgetSchema(ctx, msg.URI); err != nil {
+				return err
+			}
+		}
+		ix.noteChanged(msg.URI)
 		return ix.index.Delete(ctx, msg.URI)
+	case outbox.ActionResync:
+		if err := ix.extractor.ResyncSchemas(ctx, msg.Space, msg.Repo, msg.Rev); err != nil {
+			return err
+		}
+		if ix.changed != nil {
+			ix.changed.resyncs = append(ix.changed.resyncs, RebuildResync{
+				Space: msg.Space,
+				Repo:  msg.Repo,
+				Rev:   msg.Rev,
+			})
+		}
+		return ix.index.Resync(ctx, msg.Space, msg.Repo, msg.Rev)
+	case outbox.ActionBackfillComplete:
+		slog.InfoContext(ctx, "org backfill indexed", "did", msg.Repo)
+		return nil
 	}
-	var value map[string]any
-	if err := json.Unmarshal(msg.Value, &value); err != nil {
-		return fmt.Errorf("unmarshal record value: %w", err)
+	if msg.URI.Collection() == renderSchemaNSID {
+		if err := ix.extractor.LearnSchema(ctx, msg.URI, msg.Rev, msg.Value); err != nil {
+			return err
+		}
 	}
-	doc := Document{
-		URI:        msg.URI,
-		SpaceURI:   msg.URI.SpaceURI(),
-		OrgDID:     msg.URI.SpaceOwner(),
-		Collection: msg.URI.Collection(),
-		Content:    ExtractContent(value),
-		UpdatedAt:  time.Now(),
+	doc, err := ix.document(msg.URI, msg.Rev, msg.Value)
+	if err != nil {
+		return err
 	}
+	ix.noteChanged(msg.URI)
 	return ix.index.Upsert(ctx, doc)
 }
 
-// isDeleted reports whether an outbox message's value represents a
-// deletion: sap's writeEventOps marshals a nil record value (set on delete
-// ops) to JSON "null".
-func isDeleted(value json.RawMessage) bool {
-	return len(value) == 0 || string(value) == "null"
+// noteChanged records that the live document at uri changes, if a Reindex
+// is filling its shadow copy. Callers hold mu.
+func (ix *Indexer) noteChanged(uri habitat_syntax.SpaceRecordURI) {
+	if ix.changed != nil {
+		ix.changed.uris[uri] = struct{}{}
+	}
+}
+
+// document extracts the searchable text of the record at uri, at rev, in the
+// language the record declares or, failing that, the one its text reads as.
+func (ix *Indexer) document(
+	uri habitat_syntax.SpaceRecordURI,
+	rev syntax.TID,
+	raw json.RawMessage,
+) (Document, error) {
+	var value map[string]any
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return Document{}, fmt.Errorf("unmarshal record value: %w", err)
+	}
+	text := ix.extractor.Extract(uri.SpaceOwner(), uri.Collection(), value)
+	lang := recordLanguage(value)
+	if lang == "" {
+		lang = detectLanguage(text.Title + " " + text.Body)
+	}
+	return Document{
+		URI:        uri,
+		SpaceURI:   uri.SpaceURI(),
+		OrgDID:     uri.SpaceOwner(),
+		Repo:       uri.Repo(),
+		Rev:        rev,
+		Collection: uri.Collection(),
+		Title:      text.Title,
+		Content:    text.Body,
+		Metadata:   text.Metadata,
+		Language:   lang,
+		UpdatedAt:  revTime(rev),
+	}, nil
+}
+
+// revTime is when the commit at rev was made, so a record indexed during
+// backfill keeps the date it last changed rather than the date it was
+// indexed. A missing or malformed rev counts as now.
+func revTime(rev syntax.TID) time.Time {
+	if rev.Integer() == 0 {
+		return time.Now()
+	}
+	return rev.Time()
 }
@@ -6,6 +6,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"github.com/habitat-network/habitat/api/habitat"
 	habitat_syntax "github.com/habitat-network/habitat/internal/syntax"
 	"github.com/habitat-network/habitat/pkg/sap/outbox"
 	"github.com/stretchr/testify/require"
@@ -17,6 +19,7 @@ import (
 type fakeOutbox struct {
 	pending []outbox.Message
 	acked   []uint
+	nacked  []uint
 	watchCh chan struct{}
 }
 
@@ -38,6 +41,11 @@ func (f *fakeOutbox) Ack(ctx context.Context, id uint) error {
 	return nil
 }
 
+func (f *fakeOutbox) Nack(ctx context.Context, id uint, reason string) error {
+	f.nacked = append(f.nacked, id)
+	return nil
+}
+
 func (f *fakeOutbox) Watch() <-chan struct{} {
 	return f.watchCh
 }
@@ -46,6 +54,12 @@ func (f *fakeOutbox) Watch() <-chan struct{} {
 type fakeIndex struct {
 	upserted []Document
 	deleted  []string
+	resynced []syntax.TID
+	// rebuiltScope, rebuilt and caughtUp are the scope, documents and
+	// catch-up of the last Rebuild.
+	rebuiltScope RebuildScope
+	rebuilt      []Document
+	caughtUp     RebuildCatchUp
 }
 
 func (f *fakeIndex) Upsert(ctx context.Context, doc Document) error {
@@ -58,10 +72,37 @@ func (f *fakeIndex) Delete(ctx context.Context, uri habitat_syntax.SpaceRecordUR
 	return nil
 }
 
+func (f *fakeIndex) Resync(
+	ctx context.Context,
+	space habitat_syntax.SpaceURI,
+	repo syntax.DID,
+	rev syntax.TID,
+) error {
+	f.resynced = append(f.resynced, rev)
+	return nil
+}
+
 func (f *fakeIndex) Query(ctx context.Context, params QueryParams) (QueryResult, error) {
 	return QueryResult{}, nil
 }
 
+func (f *fakeIndex) Rebuild(
+	ctx context.Context,
+	scope RebuildScope,
+	fill func(put func(Document) error) error,
+	catchUp func() RebuildCatchUp,
+) error {
+	var docs []Document
+	if err := fill(func(doc Document) error {
+		docs = append(docs, doc)
+		return nil
+	}); err != nil {
+		return err
+	}
+	f.rebuiltScope, f.rebuilt, f.caughtUp = scope, docs, catchUp()
+	return nil
+}
+
 func mustMarshal(t *testing.T, v any) json.RawMessage {
 	t.Helper()
 	b, err := json.Marshal(v)
@@ -75,10 +116,16 @@ func TestIndexer_UpsertsMessageWithValue(t *testing.T) {
 	)
 	index := &fakeIndex{}
 	outbox := newFakeOutbox([]outbox.Message{
-		{ID: 1, URI: recordURI, Value: mustMarshal(t, map[string]any{"title": "Budget"})},
+		{
+			ID:     1,
+			Action: outbox.ActionCreate,
+			URI:    recordURI,
+			Rev:    "3jzfcijpj2z2a",
+			Value:  mustMarshal(t, map[string]any{"title": "Budget"}),
+		},
 	})
 
-	indexer := NewIndexer(index, outbox)
+	indexer := NewIndexer(index, outbox, newTestExtractor(t))
 	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
 	defer cancel()
 	_ = indexer.Run(ctx)
@@ -91,21 +138,71 @@ func TestIndexer_UpsertsMessageWithValue(t *testing.T) {
 		index.upserted[0].SpaceURI,
 	)
 	require.Equal(t, "did:plc:org1", index.upserted[0].OrgDID.String())
+	require.Equal(t, "did:plc:user1", index.upserted[0].Repo.String())
+	require.Equal(t, syntax.TID("3jzfcijpj2z2a"), index.upserted[0].Rev)
+	require.Equal(
+		t,
+		syntax.TID("3jzfcijpj2z2a").Time(),
+		index.upserted[0].UpdatedAt,
+		"a record is dated by its commit, not by when it was indexed",
+	)
 	require.Equal(t, "network.habitat.note", index.upserted[0].Collection.String())
-	require.Contains(t, index.upserted[0].Content, "Budget")
+	require.Equal(t, "Budget", index.upserted[0].Title)
 	require.Equal(t, []uint{1}, outbox.acked, "message should be acked after a successful upsert")
 }
 
-func TestIndexer_DeletesOnNullValue(t *testing.T) {
+func TestIndexer_DocumentLanguage(t *testing.T) {
+	uri := habitat_syntax.SpaceRecordURI(
+		"at://did:plc:org1/space/network.habitat.space/skey1/did:plc:user1/network.habitat.note/rkey1",
+	)
+	indexer := NewIndexer(&fakeIndex{}, newFakeOutbox(nil), newTestExtractor(t))
+
+	for _, tc := range []struct {
+		name  string
+		value map[string]any
+		want  string
+	}{
+		{
+			name: "declared",
+			value: map[string]any{
+				"title": "Presupuesto",
+				"body":  "The numbers for the year",
+				"langs": []any{"es-MX", "en"},
+			},
+			want: "es",
+		},
+		{
+			name: "detected",
+			value: map[string]any{
+				"title": "Presupuesto",
+				"body":  "Los números del año son para la junta y no para los socios",
+			},
+			want: "es",
+		},
+		{
+			name:  "unknown",
+			value: map[string]any{"title": "Presupuesto"},
+			want:  "",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			doc, err := indexer.document(uri, "3jzfcijpj2z2a", mustMarshal(t, tc.value))
+			require.NoError(t, err)
+			require.Equal(t, tc.want, doc.Language)
+		})
+	}
+}
+
+func TestIndexer_DeletesOnDeleteAction(t *testing.T) {
 	recordURI := habitat_syntax.SpaceRecordURI(
 		"at://did:plc:org1/space/network.habitat.space/skey1/did:plc:user1/network.habitat.note/rkey1",
 	)
 	index := &fakeIndex{}
 	outbox := newFakeOutbox([]outbox.Message{
-		{ID: 1, URI: recordURI, Value: json.RawMessage("null")},
+		{ID: 1, Action: outbox.ActionDelete, URI: recordURI},
 	})
 
-	indexer := NewIndexer(index, outbox)
+	indexer := NewIndexer(index, outbox, newTestExtractor(t))
 	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
 	defer cancel()
 	_ = indexer.Run(ctx)
@@ -113,6 +210,43 @@ func TestIndexer_DeletesOnNullValue(t *testing.T) {
 	require.Equal(t, []string{recordURI.String()}, index.deleted)
 }
 
+func TestIndexer_ResyncsRepoOnResyncAction(t *testing.T) {
+	index := &fakeIndex{}
+	outbox := newFakeOutbox([]outbox.Message{
+		{
+			ID:     1,
+			Action: outbox.ActionResync,
+			Space:  "at://did:plc:org1/space/network.habitat.space/skey1",
+			Repo:   "did:plc:user1",
+			Rev:    "3jzfcijpj2z2a",
+		},
+	})
+
+	indexer := NewIndexer(index, outbox, newTestExtractor(t))
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_ = indexer.Run(ctx)
+
+	require.Equal(t, []syntax.TID{"3jzfcijpj2z2a"}, index.resynced)
+	require.Empty(t, index.upserted, "a resync marker carries no record to index")
+	require.Equal(t, []uint{1}, outbox.acked)
+}
+
+func TestIndexer_AcksBackfillComplete(t *testing.T) {
+	index := &fakeIndex{}
+	outbox := newFakeOutbox([]outbox.Message{
+		{ID: 1, Action: outbox.ActionBackfillComplete, Repo: "did:plc:org1"},
+	})
+
+	indexer := NewIndexer(index, outbox, newTestExtractor(t))
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_ = indexer.Run(ctx)
+
+	require.Empty(t, index.upserted, "a backfill marker carries no record to index")
+	require.Equal(t, []uint{1}, outbox.acked)
+}
+
 func TestIndexer_DoesNotAckOnHandleFailure(t *testing.T) {
 	recordURI := habitat_syntax.SpaceRecordURI(
 		"at://did:plc:org1/space/network.habitat.space/skey1/did:plc:user1/network.habitat.note/rkey1",
@@ -121,14 +255,62 @@ func TestIndexer_DoesNotAckOnHandleFailure(t *testing.T) {
 	// Malformed JSON: handleMessage fails to unmarshal it, so it must be
 	// left unacked for redelivery on the next Poll.
 	outbox := newFakeOutbox([]outbox.Message{
-		{ID: 1, URI: recordURI, Value: json.RawMessage("not-json")},
+		{ID: 1, Action: outbox.ActionCreate, URI: recordURI, Value: json.RawMessage("not-json")},
 	})
 
-	indexer := NewIndexer(index, outbox)
+	indexer := NewIndexer(index, outbox, newTestExtractor(t))
 	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
 	defer cancel()
 	_ = indexer.Run(ctx)
 
 	require.Empty(t, index.upserted)
 	require.Empty(t, outbox.acked, "message should not be acked when indexing fails")
+	require.Equal(t, []uint{1}, outbox.nacked, "a failed message should be nacked")
+}
+
+func TestIndexer_LearnsRenderSchemas(t *testing.T) {
+	schemaURI := recordURIFor("did:plc:org1", "network.habitat.render.schema", "events")
+	eventURI := recordURIFor("did:plc:org1", "network.habitat.event", "rkey1")
+	index := &fakeIndex{}
+	outbox := newFakeOutbox([]outbox.Message{
+		{
+			ID:     1,
+			Action: outbox.ActionCreate,
+			URI:    schemaURI,
+			Rev:    "3jzfcijpj2z2a",
+			Value: mustMarshal(t, habitat.NetworkHabitatRenderSchema{
+				TargetLexicon: "network.habitat.event",
+				Title:         "Event",
+				Fields: []habitat.NetworkHabitatRenderSchemaFieldSchema{{
+					Path:        "name",
+					DisplayType: "network.habitat.render.schema#text",
+					Priority:    "network.habitat.render.schema#primary",
+				}},
+			}),
+		},
+		{
+			ID:     2,
+			Action: outbox.ActionCreate,
+			URI:    eventURI,
+			Rev:    "3jzfcijpj2z2b",
+			Value:  mustMarshal(t, map[string]any{"name": "Board meeting", "room": "Room 4"}),
+		},
+	})
+
+	indexer := NewIndexer(index, outbox, newTestExtractor(t))
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_ = indexer.Run(ctx)
+
+	require.Len(t, index.upserted, 2)
+	require.Equal(t, eventURI, index.upserted[1].URI)
+	require.Equal(t, "Board meeting", index.upserted[1].Title)
+	require.Empty(t, index.upserted[1].Content, "fields outside the render schema are not indexed")
+	require.Equal(t, []uint{1, 2}, outbox.acked)
+}
+
+// recordURIFor builds the URI of a record in org's test space.
+func recordURIFor(org, collection, rkey string) habitat_syntax.SpaceRecordURI {
+	space := "at://" + org + "/space/network.habitat.space/skey1"
+	return habitat_syntax.SpaceRecordURI(space + "/did:plc:user1/" + collection + "/" + rkey)
 }
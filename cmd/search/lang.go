@@ -0,0 +1,131 @@
+package main
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// simpleTSConfig is the text search configuration for a language Postgres
+// has no stemmer for: words are lowercased but not stemmed.
+const simpleTSConfig = "simple"
+
+// tsConfigs maps the languages Postgres' built-in configurations stem, by
+// ISO 639 code, to the configuration's name.
+var tsConfigs = map[string]string{
+	"da": "danish",
+	"de": "german",
+	"en": "english",
+	"es": "spanish",
+	"fi": "finnish",
+	"fr": "french",
+	"hu": "hungarian",
+	"it": "italian",
+	"nb": "norwegian",
+	"nl": "dutch",
+	"no": "norwegian",
+	"pt": "portuguese",
+	"ro": "romanian",
+	"ru": "russian",
+	"sv": "swedish",
+	"tr": "turkish",
+}
+
+// tsConfig returns the text search configuration for lang, or simple when
+// Postgres cannot stem it.
+func tsConfig(lang string) string {
+	if config, ok := tsConfigs[lang]; ok {
+		return config
+	}
+	return simpleTSConfig
+}
+
+// stemmedTSConfigs lists the configurations of tsConfigs once each, sorted.
+func stemmedTSConfigs() []string {
+	return slices.Compact(slices.Sorted(maps.Values(tsConfigs)))
+}
+
+// primaryLanguage returns the primary language subtag of a BCP-47 tag,
+// lowercased ("pt-BR" is "pt"), or "" when tag does not start with one.
+func primaryLanguage(tag string) string {
+	primary, _, _ := strings.Cut(strings.ReplaceAll(tag, "_", "-"), "-")
+	if len(primary) < 2 || len(primary) > 3 {
+		return ""
+	}
+	for _, r := range primary {
+		if r > unicode.MaxASCII || !unicode.IsLetter(r) {
+			return ""
+		}
+	}
+	return strings.ToLower(primary)
+}
+
+// recordLanguage returns the language a record declares in its langs field,
+// the first of them when it lists several, or "" when it declares none.
+func recordLanguage(value map[string]any) string {
+	langs, _ := value["langs"].([]any)
+	for _, lang := range langs {
+		if s, ok := lang.(string); ok {
+			if primary := primaryLanguage(s); primary != "" {
+				return primary
+			}
+		}
+	}
+	return ""
+}
+
+// stopwords are frequent function words of the languages detectLanguage
+// tells apart. Words common to several of them still count for each; it is
+// the words they do not share that decide.
+var stopwords = map[string][]string{
+	"en": strings.Fields("the and of to is in that it for with was on are this be have " +
+		"from will at by not or an you we they our"),
+	"es": strings.Fields("el la los las del y que en es por con para una un se no su al lo " +
+		"como más pero sus le ya este está son también muy hay"),
+	"pt": strings.Fields("o os as da do das dos e que em é para com uma um não no na nos se " +
+		"por mais mas como ao seu sua também são está muito há"),
+	"de": strings.Fields("der die das und ist nicht ein eine zu den mit von sich des auf für " +
+		"im dem auch es an werden aus er hat dass sie nach wird bei noch wie über"),
+	"fr": strings.Fields("le la les et des est un une du en que qui dans pour pas sur au avec " +
+		"ce il ne se plus par sont aux cette mais nous vous très"),
+	"it": strings.Fields("il lo la gli le di e che è per una un non con del della sono al nel " +
+		"si da ma come anche più questo ha alla dei molto"),
+}
+
+var stopwordLanguages = func() map[string][]string {
+	byWord := map[string][]string{}
+	for lang, words := range stopwords {
+		for _, w := range words {
+			byWord[w] = append(byWord[w], lang)
+		}
+	}
+	return byWord
+}()
+
+// detectLanguage guesses the language of text from the stopwords it uses.
+// It returns "" when text has too few of them, or when two languages are
+// equally likely, since a wrong stemmer does more harm than none.
+func detectLanguage(text string) string {
+	counts := map[string]int{}
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	}) {
+		for _, lang := range stopwordLanguages[word] {
+			counts[lang]++
+		}
+	}
+	best, bestCount, runnerUp := "", 0, 0
+	for lang, n := range counts {
+		switch {
+		case n > bestCount:
+			best, bestCount, runnerUp = lang, n, bestCount
+		case n > runnerUp:
+			runnerUp = n
+		}
+	}
+	if bestCount < 2 || bestCount == runnerUp {
+		return ""
+	}
+	return best
+}
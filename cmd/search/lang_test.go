@@ -0,0 +1,60 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	for text, want := range map[string]string{
+		"The minutes of the board meeting are in the shared folder":          "en",
+		"Las actas de la reunión están en la carpeta compartida del grupo":   "es",
+		"As atas da reunião estão na pasta compartilhada do grupo":           "pt",
+		"Das Protokoll der Sitzung ist im gemeinsamen Ordner und nicht hier": "de",
+		"Le compte rendu de la réunion est dans le dossier partagé":          "fr",
+		"Quarterly budget": "",
+		"la de":            "",
+		"":                 "",
+	} {
+		require.Equal(t, want, detectLanguage(text), text)
+	}
+}
+
+func TestPrimaryLanguage(t *testing.T) {
+	for tag, want := range map[string]string{
+		"en":         "en",
+		"pt-BR":      "pt",
+		"zh_Hant_TW": "zh",
+		"FIL":        "fil",
+		"e":          "",
+		"english":    "",
+		"42":         "",
+		"":           "",
+	} {
+		require.Equal(t, want, primaryLanguage(tag), tag)
+	}
+}
+
+func TestRecordLanguage(t *testing.T) {
+	require.Equal(t, "de", recordLanguage(map[string]any{"langs": []any{"x", "de-AT", "en"}}))
+	require.Equal(t, "", recordLanguage(map[string]any{"langs": "de"}))
+	require.Equal(t, "", recordLanguage(map[string]any{}))
+}
+
+func TestTSQuery(t *testing.T) {
+	query, args := tsQuery("es")
+	require.Equal(
+		t,
+		"(websearch_to_tsquery('spanish', ?) || websearch_to_tsquery('simple', ?))",
+		query,
+	)
+	require.Equal(t, 2, args)
+
+	query, args = tsQuery("ja")
+	require.Equal(t, "(websearch_to_tsquery('simple', ?))", query)
+	require.Equal(t, 1, args)
+
+	_, args = tsQuery("")
+	require.Equal(t, len(stemmedTSConfigs())+1, args, "every stemmer and simple")
+}
@@ -2,41 +2,39 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
-	"slices"
 	"strings"
 	"syscall"
 	"time"
 
 	"github.com/bluesky-social/indigo/atproto/atcrypto"
 	"github.com/bluesky-social/indigo/atproto/auth/oauth"
-	"github.com/bluesky-social/indigo/atproto/syntax"
 	"github.com/gorilla/mux"
-	"github.com/habitat-network/habitat/internal/httpx"
+	"github.com/habitat-network/habitat/internal/db"
 	"github.com/habitat-network/habitat/pkg/oauthclient"
 	"github.com/habitat-network/habitat/pkg/sap"
 	"github.com/urfave/cli/v3"
 	"golang.org/x/sync/errgroup"
-	"gorm.io/driver/postgres"
-	"gorm.io/gorm"
 )
 
-// hardcodedOrgHandle is the single org this prototype indexes. Replace
-// with the handle of the org you want to index locally. Multi-org indexing
-// is out of scope until search moves to instance-wide indexing.
-const hardcodedOrgDID = syntax.DID("did:web:ewzw89.pear.local.habitat.network")
-
 func main() {
 	cmd := &cli.Command{
 		Name:   "search",
 		Usage:  "Habitat Search Server",
 		Flags:  getFlags(),
 		Action: run,
+		Commands: []*cli.Command{{
+			Name:   "reindex",
+			Usage:  "Rebuild the search index from the records sap holds",
+			Flags:  getReindexFlags(),
+			Action: runReindex,
+		}},
 	}
 	if err := cmd.Run(context.Background(), os.Args); err != nil {
 		log.Fatalf("Error running command: %v", err)
@@ -49,13 +47,19 @@ func run(ctx context.Context, cmd *cli.Command) error {
 		return fmt.Errorf("unmarshal log level: %w", err)
 	}
 	slog.SetLogLoggerLevel(logLevel)
+	for _, name := range []string{fDB, fPearHost, fSecret} {
+		if cmd.String(name) == "" {
+			return fmt.Errorf("required flag %q not set", name)
+		}
+	}
 
-	db, err := gorm.Open(postgres.Open(cmd.String(fDB)), &gorm.Config{})
+	dsn := cmd.String(fDB)
+	gormDB, err := db.New(dsn)
 	if err != nil {
 		return fmt.Errorf("failed to open db: %w", err)
 	}
 
-	index, err := newPostgresFTSIndex(db)
+	index, err := openIndex(gormDB, dsn)
 	if err != nil {
 		return fmt.Errorf("failed to set up index: %w", err)
 	}
@@ -67,7 +71,7 @@ func run(ctx context.Context, cmd *cli.Command) error {
 	}
 
 	domain := cmd.String(fDomain)
-	store, err := oauthclient.NewGormStore(db)
+	store, err := oauthclient.NewGormStore(gormDB)
 	if err != nil {
 		return fmt.Errorf("create oauth store: %w", err)
 	}
@@ -84,8 +88,10 @@ func run(ctx context.Context, cmd *cli.Command) error {
 	oauthApp := oauth.NewClientApp(&config, store)
 
 	s, err := sap.New(sap.Config{
-		DB:          db,
-		OAuthClient: oauthApp,
+		DB:                gormDB,
+		OAuthClient:       oauthApp,
+		OutboxMaxAttempts: cmd.Int(fOutboxMaxAttempts),
+		Replica:           cmd.Bool(fReplica),
 	})
 	if err != nil {
 		return fmt.Errorf("create sap: %w", err)
@@ -94,46 +100,52 @@ func run(ctx context.Context, cmd *cli.Command) error {
 	if err != nil {
 		return fmt.Errorf("failed to list orgs: %w", err)
 	}
-	if !slices.Contains(orgs, hardcodedOrgDID) {
-		url, err := oauthApp.StartAuthFlow(ctx, hardcodedOrgDID.String())
+	if len(orgs) == 0 {
+		slog.WarnContext(
+			ctx,
+			"search is not indexing any org yet; add one through the internal /org/add endpoint",
+			"internal_port", cmd.Int(fInternalPort),
+		)
+	}
+
+	server := NewServer(cmd.String(fPearHost), index, s)
+	orgServer := newOrgServer(s, oauthApp)
+	var rules ExtractionRules
+	if path := cmd.String(fExtractionRules); path != "" {
+		rules, err = LoadExtractionRules(path)
 		if err != nil {
-			return fmt.Errorf("start auth flow: %w", err)
+			return err
 		}
-		fmt.Printf("add org via url: %s\n", url)
 	}
-
-	server := NewServer(cmd.String(fPearHost), index)
-	indexer := NewIndexer(index, s.Outbox())
+	extractor, err := NewExtractor(gormDB, rules)
+	if err != nil {
+		return fmt.Errorf("set up extractor: %w", err)
+	}
+	indexer := NewIndexer(index, s.Outbox(), extractor)
 
 	router := mux.NewRouter()
 	router.HandleFunc("/xrpc/network.habitat.search.query", server.HandleQuery).Methods("GET")
-	router.Handle("/oauth-callback", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		sessionData, err := oauthApp.ProcessCallback(r.Context(), r.URL.Query())
-		if err != nil {
-			http.Error(w, fmt.Sprintf("process callback: %s", err), http.StatusInternalServerError)
-			return
-		}
-		if err := s.AddSession(
-			r.Context(),
-			sessionData.AccountDID,
-			sessionData.SessionID,
-		); err != nil {
-			http.Error(w, fmt.Sprintf("save org: %s", err), http.StatusInternalServerError)
-			return
-		}
-		slog.InfoContext(r.Context(), "org oauth complete", "did", sessionData.AccountDID)
-		w.WriteHeader(http.StatusOK)
-	}))
-	router.Handle(
-		"/client-metadata.json",
-		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			httpx.WriteJSON(r.Context(), w, oauthApp.Config.ClientMetadata())
-		}),
-	)
+	router.HandleFunc("/oauth-callback", orgServer.handleOAuthCallback)
+	router.HandleFunc("/client-metadata.json", orgServer.handleClientMetadata)
+
+	// Adding and removing orgs, and reindexing, are served on a separate
+	// internal port, like cmd/sap's org and admin endpoints, so access can
+	// be restricted to operators.
+	internalMux := http.NewServeMux()
+	internalMux.HandleFunc("/org/add", orgServer.handleAddOrg)
+	internalMux.HandleFunc("/org/list", orgServer.handleListOrgs)
+	internalMux.HandleFunc("/org/remove", orgServer.handleRemoveOrg)
+	reindexServer := &reindexServer{indexer: indexer}
+	if cmd.Bool(fReplica) {
+		reindexServer.replica = s
+	}
+	internalMux.HandleFunc("/admin/reindex", reindexServer.handleReindex)
 
 	port := cmd.Int(fPort)
 	addr := fmt.Sprintf(":%d", port)
 	srv := &http.Server{Addr: addr, Handler: router}
+	internalAddr := fmt.Sprintf(":%d", cmd.Int(fInternalPort))
+	internalSrv := &http.Server{Addr: internalAddr, Handler: internalMux}
 
 	sigCtx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
@@ -146,16 +158,18 @@ func run(ctx context.Context, cmd *cli.Command) error {
 		return indexer.Run(egCtx)
 	})
 	eg.Go(func() error {
-		log.Printf("Starting search server on %s", addr)
-		go func() {
-			if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-				slog.ErrorContext(egCtx, "server error", "err", err)
-			}
-		}()
+		log.Printf("Starting search server on %s (internal %s)", addr, internalAddr)
+		for _, hs := range []*http.Server{srv, internalSrv} {
+			go func() {
+				if err := hs.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+					slog.ErrorContext(egCtx, "server error", "err", err, "addr", hs.Addr)
+				}
+			}()
+		}
 		<-egCtx.Done()
 		shutdownCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
 		defer cancel()
-		return srv.Shutdown(shutdownCtx)
+		return errors.Join(srv.Shutdown(shutdownCtx), internalSrv.Shutdown(shutdownCtx))
 	})
 
 	return eg.Wait()
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/bluesky-social/indigo/atproto/auth/oauth"
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"github.com/habitat-network/habitat/internal/httpx"
+	"github.com/habitat-network/habitat/pkg/oauthclient"
+	"github.com/habitat-network/habitat/pkg/sap"
+)
+
+// grantJWTBearer selects the JWT-bearer grant in an /org/add request.
+const grantJWTBearer = "jwt-bearer"
+
+// orgServer manages the orgs search indexes. Each org is a sap session added
+// through the OAuth browser flow or the JWT-bearer grant, as with cmd/sap;
+// its records reach the index through the shared outbox, partitioned by the
+// org that owns them.
+type orgServer struct {
+	sap         *sap.Sap
+	oauthClient *oauthclient.Client
+}
+
+func newOrgServer(s *sap.Sap, oauthApp *oauth.ClientApp) *orgServer {
+	return &orgServer{sap: s, oauthClient: oauthclient.NewJWTBearerClient(oauthApp)}
+}
+
+// handleAddOrg adds the org named by handle. By default it starts the OAuth
+// browser flow: a POST answers with the URL to send the org admin to, and a
+// GET redirects there. A POST with grant "jwt-bearer" instead gets the org's
+// session straight from its pear through the JWT-bearer grant, which the pear
+// must allow for search's client, and adds it at once.
+func (s *orgServer) handleAddOrg(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Handle string `json:"handle"`
+		Grant  string `json:"grant"`
+	}
+	if r.Method == http.MethodPost {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid JSON body", http.StatusBadRequest)
+			return
+		}
+	} else {
+		req.Handle = r.URL.Query().Get("handle")
+	}
+	if req.Handle == "" {
+		http.Error(w, "missing required parameter: handle", http.StatusBadRequest)
+		return
+	}
+	switch req.Grant {
+	case "":
+	case grantJWTBearer:
+		s.addOrgWithJWTBearer(w, r, req.Handle)
+		return
+	default:
+		http.Error(w, fmt.Sprintf("unknown grant %q", req.Grant), http.StatusBadRequest)
+		return
+	}
+
+	redirectURL, err := s.oauthClient.StartAuthFlow(r.Context(), req.Handle)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("start auth flow: %s", err), http.StatusInternalServerError)
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		httpx.WriteJSON(r.Context(), w, map[string]string{"redirect_url": redirectURL})
+		return
+	}
+	w.Header().Set("Location", redirectURL)
+	w.WriteHeader(http.StatusSeeOther)
+}
+
+func (s *orgServer) addOrgWithJWTBearer(w http.ResponseWriter, r *http.Request, handle string) {
+	sessionData, err := s.oauthClient.SendJWTTokenRequest(r.Context(), handle)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("jwt bearer grant: %s", err), http.StatusBadGateway)
+		return
+	}
+	if err := s.sap.AddSession(
+		r.Context(),
+		sessionData.AccountDID,
+		sessionData.SessionID,
+	); err != nil {
+		http.Error(w, fmt.Sprintf("save org: %s", err), http.StatusInternalServerError)
+		return
+	}
+	slog.InfoContext(r.Context(), "org added with jwt bearer grant", "did", sessionData.AccountDID)
+	httpx.WriteJSON(r.Context(), w, map[string]string{"did": sessionData.AccountDID.String()})
+}
+
+func (s *orgServer) handleListOrgs(w http.ResponseWriter, r *http.Request) {
+	orgs, err := s.sap.Sessions(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if orgs == nil {
+		orgs = []syntax.DID{}
+	}
+	httpx.WriteJSON(r.Context(), w, map[string]any{"orgs": orgs})
+}
+
+// handleRemoveOrg stops indexing an org. sap emits a delete for every record
+// in the spaces it drops, so the indexer clears the org's documents out of
+// the index as it drains them.
+func (s *orgServer) handleRemoveOrg(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		DID string `json:"did"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	did, err := syntax.ParseDID(req.DID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid did: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	dropped, err := s.sap.RemoveSession(r.Context(), did, true)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("remove org: %s", err), http.StatusInternalServerError)
+		return
+	}
+	slog.InfoContext(r.Context(), "org removed", "did", did, "dropped_spaces", len(dropped))
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *orgServer) handleOAuthCallback(w http.ResponseWriter, r *http.Request) {
+	sessionData, err := s.oauthClient.ProcessCallback(r.Context(), r.URL.Query())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("process callback: %s", err), http.StatusInternalServerError)
+		return
+	}
+	if err := s.sap.AddSession(
+		r.Context(),
+		sessionData.AccountDID,
+		sessionData.SessionID,
+	); err != nil {
+		http.Error(w, fmt.Sprintf("save org: %s", err), http.StatusInternalServerError)
+		return
+	}
+	slog.InfoContext(r.Context(), "org oauth complete", "did", sessionData.AccountDID)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *orgServer) handleClientMetadata(w http.ResponseWriter, r *http.Request) {
+	httpx.WriteJSON(r.Context(), w, s.oauthClient.Config.ClientMetadata())
+}
@@ -3,7 +3,10 @@ package main
 import (
 	"context"
 	"fmt"
+	"maps"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/bluesky-social/indigo/atproto/syntax"
@@ -16,13 +19,34 @@ type searchDocument struct {
 	URI        string    `gorm:"column:uri;primaryKey"`
 	SpaceURI   string    `gorm:"column:space_uri;index"`
 	OrgDID     string    `gorm:"column:org_did;index"`
+	Repo       string    `gorm:"column:repo;index"`
+	Rev        string    `gorm:"column:rev"`
 	Collection string    `gorm:"column:collection"`
+	Title      string    `gorm:"column:title"`
 	Content    string    `gorm:"column:content"`
+	Metadata   string    `gorm:"column:metadata"`
+	Language   string    `gorm:"column:language"`
 	UpdatedAt  time.Time `gorm:"column:updated_at"`
 }
 
 func (searchDocument) TableName() string { return "search_documents" }
 
+func newSearchDocument(doc Document) searchDocument {
+	return searchDocument{
+		URI:        doc.URI.String(),
+		SpaceURI:   doc.SpaceURI.String(),
+		OrgDID:     doc.OrgDID.String(),
+		Repo:       doc.Repo.String(),
+		Rev:        doc.Rev.String(),
+		Collection: doc.Collection.String(),
+		Title:      doc.Title,
+		Content:    doc.Content,
+		Metadata:   doc.Metadata,
+		Language:   doc.Language,
+		UpdatedAt:  doc.UpdatedAt.UTC(),
+	}
+}
+
 type postgresFTSIndex struct {
 	db *gorm.DB
 }
@@ -33,31 +57,79 @@ func newPostgresFTSIndex(db *gorm.DB) (*postgresFTSIndex, error) {
 	if err := db.AutoMigrate(&searchDocument{}); err != nil {
 		return nil, fmt.Errorf("migrate search_documents: %w", err)
 	}
+	if err := db.Exec(tsConfigFunction()).Error; err != nil {
+		return nil, fmt.Errorf("create search_ts_config: %w", err)
+	}
+	// localized_tsv replaced weighted_tsv, which stemmed every document as
+	// English, and weighted_tsv in turn replaced tsv, which indexed content
+	// alone. Documents indexed before language was stored are stemmed with
+	// the simple configuration until a reindex fills their language in.
+	for _, column := range []string{"tsv", "weighted_tsv"} {
+		if err := db.Exec(
+			`ALTER TABLE search_documents DROP COLUMN IF EXISTS ` + column,
+		).Error; err != nil {
+			return nil, fmt.Errorf("drop %s column: %w", column, err)
+		}
+	}
+	// Title text is weighted A, content B and metadata D, so ts_rank scores a
+	// match in a title well above the same match further down a record.
 	if err := db.Exec(`
 		ALTER TABLE search_documents
-		  ADD COLUMN IF NOT EXISTS tsv tsvector
-		  GENERATED ALWAYS AS (to_tsvector('english', content)) STORED
+		  ADD COLUMN IF NOT EXISTS localized_tsv tsvector
+		  GENERATED ALWAYS AS (
+		    setweight(to_tsvector(search_ts_config(language), coalesce(title, '')), 'A') ||
+		    setweight(to_tsvector(search_ts_config(language), coalesce(content, '')), 'B') ||
+		    setweight(to_tsvector(search_ts_config(language), coalesce(metadata, '')), 'D')
+		  ) STORED
 	`).Error; err != nil {
 		return nil, fmt.Errorf("add tsv column: %w", err)
 	}
 	if err := db.Exec(`
-		CREATE INDEX IF NOT EXISTS search_documents_tsv_idx
-		  ON search_documents USING GIN (tsv)
+		CREATE INDEX IF NOT EXISTS search_documents_localized_tsv_idx
+		  ON search_documents USING GIN (localized_tsv)
 	`).Error; err != nil {
 		return nil, fmt.Errorf("create tsv index: %w", err)
 	}
 	return &postgresFTSIndex{db: db}, nil
 }
 
-func (idx *postgresFTSIndex) Upsert(ctx context.Context, doc Document) error {
-	row := searchDocument{
-		URI:        doc.URI.String(),
-		SpaceURI:   doc.SpaceURI.String(),
-		OrgDID:     doc.OrgDID.String(),
-		Collection: doc.Collection.String(),
-		Content:    doc.Content,
-		UpdatedAt:  doc.UpdatedAt,
+// tsConfigFunction returns the statement that (re)defines search_ts_config,
+// which maps a document's language to its text search configuration as
+// tsConfig does. It is immutable, so a generated column can call it; a
+// changed mapping applies to documents as they are next written.
+func tsConfigFunction() string {
+	var b strings.Builder
+	b.WriteString("CREATE OR REPLACE FUNCTION search_ts_config(language text) RETURNS regconfig\n")
+	b.WriteString("LANGUAGE sql IMMUTABLE AS $$ SELECT CASE language\n")
+	for _, lang := range slices.Sorted(maps.Keys(tsConfigs)) {
+		fmt.Fprintf(&b, "  WHEN '%s' THEN '%s'::regconfig\n", lang, tsConfigs[lang])
+	}
+	fmt.Fprintf(&b, "  ELSE '%s'::regconfig END $$", simpleTSConfig)
+	return b.String()
+}
+
+// tsQuery returns the SQL expression for the query text, the expression's
+// only argument, as a tsquery: stemmed as lang, or as every language the
+// index stems when lang is "", and always also unstemmed, to match documents
+// in languages Postgres cannot stem. Documents are stemmed by their own
+// language, so the query matches a document when one of its stemmings does.
+func tsQuery(lang string) (string, int) {
+	configs := stemmedTSConfigs()
+	if lang != "" {
+		configs = []string{tsConfig(lang)}
+	}
+	if !slices.Contains(configs, simpleTSConfig) {
+		configs = append(configs, simpleTSConfig)
+	}
+	parts := make([]string, len(configs))
+	for i, config := range configs {
+		parts[i] = fmt.Sprintf("websearch_to_tsquery('%s', ?)", config)
 	}
+	return "(" + strings.Join(parts, " || ") + ")", len(parts)
+}
+
+func (idx *postgresFTSIndex) Upsert(ctx context.Context, doc Document) error {
+	row := newSearchDocument(doc)
 	return idx.db.WithContext(ctx).Clauses(clause.OnConflict{
 		Columns:   []clause.Column{{Name: "uri"}},
 		UpdateAll: true,
@@ -68,6 +140,19 @@ func (idx *postgresFTSIndex) Delete(ctx context.Context, uri habitat_syntax.Spac
 	return idx.db.WithContext(ctx).Delete(&searchDocument{}, "uri = ?", uri.String()).Error
 }
 
+func (idx *postgresFTSIndex) Resync(
+	ctx context.Context,
+	space habitat_syntax.SpaceURI,
+	repo syntax.DID,
+	rev syntax.TID,
+) error {
+	return idx.db.WithContext(ctx).Delete(
+		&searchDocument{},
+		"space_uri = ? AND repo = ? AND rev < ?",
+		space.String(), repo.String(), rev.String(),
+	).Error
+}
+
 type ftsRow struct {
 	URI        string
 	SpaceURI   string
@@ -76,6 +161,43 @@ type ftsRow struct {
 	Rank       float64
 }
 
+// queryFacets counts the documents selected FROM from WHERE where by
+// collection and by space, with columns qualified by prefix.
+func queryFacets(db *gorm.DB, from, where, prefix string, args []any) (Facets, error) {
+	count := func(column string) ([]FacetCount, error) {
+		var counts []FacetCount
+		err := db.Raw(`
+			SELECT `+prefix+column+` AS value, count(*) AS count
+			FROM `+from+`
+			WHERE `+where+`
+			GROUP BY `+prefix+column+`
+			ORDER BY count DESC, value ASC
+		`, args...).Scan(&counts).Error
+		if err != nil {
+			return nil, fmt.Errorf("count %s facet: %w", column, err)
+		}
+		return counts, nil
+	}
+	collections, err := count("collection")
+	if err != nil {
+		return Facets{}, err
+	}
+	spaces, err := count("space_uri")
+	if err != nil {
+		return Facets{}, err
+	}
+	return Facets{Collections: collections, Spaces: spaces}, nil
+}
+
+func (idx *postgresFTSIndex) Rebuild(
+	ctx context.Context,
+	scope RebuildScope,
+	fill func(put func(Document) error) error,
+	catchUp func() RebuildCatchUp,
+) error {
+	return rebuildSearchDocuments(ctx, idx.db, scope, fill, catchUp)
+}
+
 func (idx *postgresFTSIndex) Query(ctx context.Context, params QueryParams) (QueryResult, error) {
 	limit := params.Limit
 	if limit <= 0 {
@@ -89,20 +211,39 @@ func (idx *postgresFTSIndex) Query(ctx context.Context, params QueryParams) (Que
 		}
 		offset = parsed
 	}
+	if params.Spaces != nil && len(params.Spaces) == 0 {
+		return QueryResult{}, nil
+	}
+
+	query, queryArgs := tsQuery(primaryLanguage(params.Language))
+	from := "search_documents, " + query + " query"
+	filters, filterArgs := params.filters("")
+	where := "localized_tsv @@ query AND " + filters
+	var args []any
+	for range queryArgs {
+		args = append(args, params.QueryText)
+	}
+	args = append(args, filterArgs...)
 
 	var rows []ftsRow
 	err := idx.db.WithContext(ctx).Raw(`
 		SELECT uri, space_uri, collection,
-		       ts_rank(tsv, query) AS rank,
-		       ts_headline('english', content, query) AS snippet
-		FROM search_documents, websearch_to_tsquery('english', ?) query
-		WHERE org_did = ? AND tsv @@ query
+		       ts_rank(localized_tsv, query) AS rank,
+		       ts_headline(
+		         search_ts_config(language), concat_ws(' ', title, content), query
+		       ) AS snippet
+		FROM `+from+`
+		WHERE `+where+`
 		ORDER BY rank DESC, uri ASC
 		LIMIT ? OFFSET ?
-	`, params.QueryText, params.OrgDID.String(), limit, offset).Scan(&rows).Error
+	`, append(args, limit, offset)...).Scan(&rows).Error
 	if err != nil {
 		return QueryResult{}, fmt.Errorf("query search_documents: %w", err)
 	}
+	facets, err := queryFacets(idx.db.WithContext(ctx), from, where, "", args)
+	if err != nil {
+		return QueryResult{}, err
+	}
 
 	results := make([]Result, len(rows))
 	for i, row := range rows {
@@ -118,5 +259,5 @@ func (idx *postgresFTSIndex) Query(ctx context.Context, params QueryParams) (Que
 	if len(rows) == limit {
 		nextCursor = strconv.Itoa(offset + limit)
 	}
-	return QueryResult{Results: results, NextCursor: nextCursor}, nil
+	return QueryResult{Results: results, NextCursor: nextCursor, Facets: facets}, nil
 }
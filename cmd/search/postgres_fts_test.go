@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"testing"
 	"time"
@@ -102,6 +103,126 @@ func TestPostgresFTSIndex_QueryFiltersByOrg(t *testing.T) {
 	require.Empty(t, result.Results)
 }
 
+func TestPostgresFTSIndex_StemsEachDocumentInItsLanguage(t *testing.T) {
+	index := setupPostgresFTSIndex(t)
+	ctx := context.Background()
+
+	spanish := doc(
+		"at://did:plc:org1/space/app.space/skey1/did:plc:user1/network.habitat.note/rkey1",
+		"did:plc:org1",
+		"las canciones del coro",
+	)
+	spanish.Language = "es"
+	unknown := doc(
+		"at://did:plc:org1/space/app.space/skey1/did:plc:user1/network.habitat.note/rkey2",
+		"did:plc:org1",
+		"kōrasu no uta",
+	)
+	unknown.Language = "ja"
+	require.NoError(t, index.Upsert(ctx, spanish))
+	require.NoError(t, index.Upsert(ctx, unknown))
+
+	// "canción" only matches "canciones" once both are stemmed as Spanish.
+	for _, lang := range []string{"es", ""} {
+		result, err := index.Query(ctx, QueryParams{
+			OrgDID:    "did:plc:org1",
+			QueryText: "canción",
+			Language:  lang,
+			Limit:     10,
+		})
+		require.NoError(t, err)
+		require.Len(t, result.Results, 1, "lang %q", lang)
+		require.Equal(t, spanish.URI, result.Results[0].URI)
+	}
+
+	// Text Postgres cannot stem is still matched word for word.
+	result, err := index.Query(
+		ctx,
+		QueryParams{OrgDID: "did:plc:org1", QueryText: "uta", Language: "ja", Limit: 10},
+	)
+	require.NoError(t, err)
+	require.Len(t, result.Results, 1)
+	require.Equal(t, unknown.URI, result.Results[0].URI)
+}
+
+func TestPostgresFTSIndex_TitleMatchesRankFirst(t *testing.T) {
+	index := setupPostgresFTSIndex(t)
+	ctx := context.Background()
+
+	inBody := doc(
+		"at://did:plc:org1/space/app.space/skey1/did:plc:user1/network.habitat.event/rkey1",
+		"did:plc:org1",
+		"quarterly planning, the meeting room is booked",
+	)
+	inBody.Title = "Planning day"
+	titled := doc(
+		"at://did:plc:org1/space/app.space/skey1/did:plc:user1/network.habitat.event/rkey2",
+		"did:plc:org1",
+		"agenda to follow",
+	)
+	titled.Title = "Board meeting"
+	require.NoError(t, index.Upsert(ctx, inBody))
+	require.NoError(t, index.Upsert(ctx, titled))
+
+	result, err := index.Query(
+		ctx,
+		QueryParams{OrgDID: "did:plc:org1", QueryText: "meeting", Limit: 10},
+	)
+	require.NoError(t, err)
+	require.Len(t, result.Results, 2)
+	require.Equal(t, titled.URI, result.Results[0].URI)
+	require.Greater(t, result.Results[0].Rank, result.Results[1].Rank)
+}
+
+func TestPostgresFTSIndex_QueryFiltersBySpaces(t *testing.T) {
+	index := setupPostgresFTSIndex(t)
+	ctx := context.Background()
+
+	readable := doc(
+		"at://did:plc:org1/space/app.space/skey1/did:plc:user1/network.habitat.note/rkey1",
+		"did:plc:org1",
+		"budget notes anyone can read",
+	)
+	hidden := doc(
+		"at://did:plc:org1/space/app.space/skey2/did:plc:user1/network.habitat.note/rkey1",
+		"did:plc:org1",
+		"budget notes for the board",
+	)
+	hidden.SpaceURI = "at://did:plc:org1/space/app.space/skey2"
+	require.NoError(t, index.Upsert(ctx, readable))
+	require.NoError(t, index.Upsert(ctx, hidden))
+
+	result, err := index.Query(ctx, QueryParams{
+		OrgDID:    "did:plc:org1",
+		Spaces:    []habitat_syntax.SpaceURI{readable.SpaceURI},
+		QueryText: "budget",
+		Limit:     1,
+	})
+	require.NoError(t, err)
+	require.Len(t, result.Results, 1)
+	require.Equal(t, readable.URI, result.Results[0].URI)
+
+	// The hidden document does not take up a page: there is no next one.
+	next, err := index.Query(ctx, QueryParams{
+		OrgDID:    "did:plc:org1",
+		Spaces:    []habitat_syntax.SpaceURI{readable.SpaceURI},
+		QueryText: "budget",
+		Limit:     1,
+		Cursor:    result.NextCursor,
+	})
+	require.NoError(t, err)
+	require.Empty(t, next.Results)
+
+	none, err := index.Query(ctx, QueryParams{
+		OrgDID:    "did:plc:org1",
+		Spaces:    []habitat_syntax.SpaceURI{},
+		QueryText: "budget",
+		Limit:     10,
+	})
+	require.NoError(t, err)
+	require.Empty(t, none.Results)
+}
+
 func TestPostgresFTSIndex_Delete(t *testing.T) {
 	index := setupPostgresFTSIndex(t)
 	ctx := context.Background()
@@ -122,6 +243,38 @@ func TestPostgresFTSIndex_Delete(t *testing.T) {
 	require.Empty(t, result.Results)
 }
 
+func TestPostgresFTSIndex_ResyncDropsOlderRevs(t *testing.T) {
+	index := setupPostgresFTSIndex(t)
+	ctx := context.Background()
+	clock := syntax.NewTIDClock(0)
+	oldRev, snapshotRev := clock.Next(), clock.Next()
+
+	stale := doc(
+		"at://did:plc:org1/space/app.space/skey1/did:plc:user1/network.habitat.note/rkey1",
+		"did:plc:org1",
+		"budget notes deleted while sap was desynced",
+	)
+	stale.Repo, stale.Rev = "did:plc:user1", oldRev
+	fresh := doc(
+		"at://did:plc:org1/space/app.space/skey1/did:plc:user1/network.habitat.note/rkey2",
+		"did:plc:org1",
+		"budget notes from the snapshot",
+	)
+	fresh.Repo, fresh.Rev = "did:plc:user1", snapshotRev
+	require.NoError(t, index.Upsert(ctx, stale))
+	require.NoError(t, index.Upsert(ctx, fresh))
+
+	require.NoError(t, index.Resync(ctx, fresh.SpaceURI, fresh.Repo, snapshotRev))
+
+	result, err := index.Query(
+		ctx,
+		QueryParams{OrgDID: "did:plc:org1", QueryText: "budget", Limit: 10},
+	)
+	require.NoError(t, err)
+	require.Len(t, result.Results, 1)
+	require.Equal(t, fresh.URI, result.Results[0].URI)
+}
+
 func TestPostgresFTSIndex_QueryRespectsLimitAndCursor(t *testing.T) {
 	index := setupPostgresFTSIndex(t)
 	ctx := context.Background()
@@ -153,3 +306,241 @@ func TestPostgresFTSIndex_QueryRespectsLimitAndCursor(t *testing.T) {
 	require.NoError(t, err)
 	require.Len(t, second.Results, 1)
 }
+
+func TestPostgresFTSIndex_QueryFiltersAndFacets(t *testing.T) {
+	testQueryFiltersAndFacets(t, setupPostgresFTSIndex(t))
+}
+
+// testQueryFiltersAndFacets checks an Index applies every QueryParams filter
+// and counts facets over all matches rather than the page returned.
+func testQueryFiltersAndFacets(t *testing.T, index Index) {
+	t.Helper()
+	ctx := context.Background()
+	lastMonth := time.Date(2026, 9, 15, 12, 0, 0, 0, time.UTC)
+
+	gardening := habitat_syntax.SpaceURI("at://did:plc:org1/space/app.space/gardening")
+	recordURI := func(
+		repo syntax.DID,
+		collection syntax.NSID,
+		rkey syntax.RecordKey,
+	) habitat_syntax.SpaceRecordURI {
+		return habitat_syntax.ConstructSpaceRecordURI(gardening, repo, collection, rkey)
+	}
+	event := doc(
+		recordURI("did:plc:user1", "network.habitat.event", "rkey1"),
+		"did:plc:org1",
+		"compost workshop",
+	)
+	event.SpaceURI, event.Repo = gardening, "did:plc:user1"
+	event.Collection = "network.habitat.event"
+	event.UpdatedAt = lastMonth
+	note := event
+	note.URI = recordURI("did:plc:user1", "network.habitat.note", "rkey1")
+	note.Collection = "network.habitat.note"
+	oldEvent := event
+	oldEvent.URI = recordURI("did:plc:user1", "network.habitat.event", "rkey2")
+	oldEvent.UpdatedAt = lastMonth.AddDate(-1, 0, 0)
+	otherAuthor := event
+	otherAuthor.URI = recordURI("did:plc:user2", "network.habitat.event", "rkey1")
+	otherAuthor.Repo = "did:plc:user2"
+	elsewhere := doc(
+		"at://did:plc:org1/space/app.space/skey1/did:plc:user1/network.habitat.event/rkey1",
+		"did:plc:org1",
+		"compost bins for sale",
+	)
+	elsewhere.Collection = "network.habitat.event"
+	for _, d := range []Document{event, note, oldEvent, otherAuthor, elsewhere} {
+		require.NoError(t, index.Upsert(ctx, d))
+	}
+
+	result, err := index.Query(ctx, QueryParams{
+		OrgDID:      "did:plc:org1",
+		Spaces:      []habitat_syntax.SpaceURI{gardening},
+		Collections: []syntax.NSID{"network.habitat.event"},
+		Authors:     []syntax.DID{"did:plc:user1"},
+		Since:       time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC),
+		Until:       time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC),
+		QueryText:   "compost",
+	})
+	require.NoError(t, err)
+	require.Len(t, result.Results, 1)
+	require.Equal(t, event.URI, result.Results[0].URI)
+
+	result, err = index.Query(
+		ctx,
+		QueryParams{OrgDID: "did:plc:org1", QueryText: "compost", Limit: 1},
+	)
+	require.NoError(t, err)
+	require.Len(t, result.Results, 1)
+	require.Equal(t, []FacetCount{
+		{Value: "network.habitat.event", Count: 4},
+		{Value: "network.habitat.note", Count: 1},
+	}, result.Facets.Collections)
+	require.Equal(t, []FacetCount{
+		{Value: gardening.String(), Count: 4},
+		{Value: elsewhere.SpaceURI.String(), Count: 1},
+	}, result.Facets.Spaces)
+}
+
+func TestPostgresFTSIndex_RebuildSwapsScope(t *testing.T) {
+	testRebuildSwapsScope(t, setupPostgresFTSIndex(t))
+}
+
+func TestPostgresFTSIndex_RebuildCatchesUp(t *testing.T) {
+	testRebuildCatchesUp(t, setupPostgresFTSIndex(t))
+}
+
+// noChanges is a Rebuild catch-up for when nothing changed during the fill.
+func noChanges() RebuildCatchUp { return RebuildCatchUp{} }
+
+// testRebuildSwapsScope checks an Index's Rebuild replaces the documents in
+// scope, and only those, and leaves the index alone when fill fails.
+func testRebuildSwapsScope(t *testing.T, index Index) {
+	t.Helper()
+	ctx := context.Background()
+	indexedAt := time.Date(2026, 9, 15, 12, 0, 0, 0, time.UTC)
+
+	kept := doc(
+		"at://did:plc:org1/space/app.space/skey1/did:plc:user1/network.habitat.note/rkey1",
+		"did:plc:org1",
+		"budget notes",
+	)
+	kept.UpdatedAt = indexedAt
+	gone := doc(
+		"at://did:plc:org1/space/app.space/skey1/did:plc:user1/network.habitat.note/rkey2",
+		"did:plc:org1",
+		"budget notes since deleted",
+	)
+	other := doc(
+		"at://did:plc:org2/space/app.space/skey1/did:plc:user1/network.habitat.note/rkey1",
+		"did:plc:org2",
+		"budget notes of org2",
+	)
+	for _, d := range []Document{kept, gone, other} {
+		require.NoError(t, index.Upsert(ctx, d))
+	}
+
+	failed := errors.New("replica unavailable")
+	require.ErrorIs(t, index.Rebuild(
+		ctx,
+		RebuildScope{OrgDID: "did:plc:org1"},
+		func(put func(Document) error) error { return failed },
+		noChanges,
+	), failed)
+	require.Len(t, queryURIs(t, index, QueryParams{OrgDID: "did:plc:org1", QueryText: "budget"}), 2)
+
+	rebuilt := kept
+	rebuilt.Content = "budget notes, re-extracted"
+	rebuilt.UpdatedAt = time.Now()
+	require.NoError(t, index.Rebuild(
+		ctx,
+		RebuildScope{OrgDID: "did:plc:org1", Collection: "network.habitat.note"},
+		func(put func(Document) error) error { return put(rebuilt) },
+		noChanges,
+	))
+
+	require.Equal(
+		t,
+		[]habitat_syntax.SpaceRecordURI{kept.URI},
+		queryURIs(t, index, QueryParams{OrgDID: "did:plc:org1", QueryText: "extracted"}),
+	)
+	require.Empty(t, queryURIs(t, index, QueryParams{OrgDID: "did:plc:org1", QueryText: "deleted"}))
+	require.Equal(
+		t,
+		[]habitat_syntax.SpaceRecordURI{kept.URI},
+		queryURIs(t, index, QueryParams{
+			OrgDID:    "did:plc:org1",
+			QueryText: "budget",
+			Until:     indexedAt.Add(time.Second),
+		}),
+		"a rebuilt document keeps the updated-at of the one it replaced",
+	)
+	require.Equal(
+		t,
+		[]habitat_syntax.SpaceRecordURI{other.URI},
+		queryURIs(t, index, QueryParams{OrgDID: "did:plc:org2", QueryText: "budget"}),
+	)
+}
+
+// testRebuildCatchesUp checks an Index's Rebuild carries over the changes
+// made to the live documents while fill ran, rather than the rebuilt ones.
+func testRebuildCatchesUp(t *testing.T, index Index) {
+	t.Helper()
+	ctx := context.Background()
+	clock := syntax.NewTIDClock(0)
+	oldRev, snapshotRev := clock.Next(), clock.Next()
+
+	edited := doc(
+		"at://did:plc:org1/space/app.space/skey1/did:plc:user1/network.habitat.note/rkey1",
+		"did:plc:org1",
+		"budget notes",
+	)
+	deleted := doc(
+		"at://did:plc:org1/space/app.space/skey1/did:plc:user1/network.habitat.note/rkey2",
+		"did:plc:org1",
+		"budget notes since deleted",
+	)
+	created := doc(
+		"at://did:plc:org1/space/app.space/skey1/did:plc:user1/network.habitat.note/rkey3",
+		"did:plc:org1",
+		"budget notes created during the rebuild",
+	)
+	stale := doc(
+		"at://did:plc:org1/space/app.space/skey1/did:plc:user2/network.habitat.note/rkey1",
+		"did:plc:org1",
+		"budget notes gone from a resynced repo",
+	)
+	stale.Repo, stale.Rev = "did:plc:user2", oldRev
+	for _, d := range []Document{edited, deleted, stale} {
+		require.NoError(t, index.Upsert(ctx, d))
+	}
+
+	live := edited
+	live.Content = "budget notes, edited"
+	require.NoError(t, index.Rebuild(
+		ctx,
+		RebuildScope{OrgDID: "did:plc:org1"},
+		func(put func(Document) error) error {
+			for _, d := range []Document{edited, deleted, stale} {
+				d.Content += ", re-extracted"
+				if err := put(d); err != nil {
+					return err
+				}
+			}
+			// The outbox keeps applying changes while fill runs.
+			if err := index.Upsert(ctx, live); err != nil {
+				return err
+			}
+			if err := index.Delete(ctx, deleted.URI); err != nil {
+				return err
+			}
+			if err := index.Upsert(ctx, created); err != nil {
+				return err
+			}
+			return index.Resync(ctx, stale.SpaceURI, stale.Repo, snapshotRev)
+		},
+		func() RebuildCatchUp {
+			return RebuildCatchUp{
+				URIs: []habitat_syntax.SpaceRecordURI{live.URI, deleted.URI, created.URI},
+				Resyncs: []RebuildResync{
+					{Space: stale.SpaceURI, Repo: stale.Repo, Rev: snapshotRev},
+				},
+			}
+		},
+	))
+
+	require.ElementsMatch(
+		t,
+		[]habitat_syntax.SpaceRecordURI{edited.URI, created.URI},
+		queryURIs(t, index, QueryParams{OrgDID: "did:plc:org1", QueryText: "budget"}),
+	)
+	require.Equal(
+		t,
+		[]habitat_syntax.SpaceRecordURI{edited.URI},
+		queryURIs(t, index, QueryParams{OrgDID: "did:plc:org1", QueryText: "edited"}),
+	)
+	require.Empty(
+		t,
+		queryURIs(t, index, QueryParams{OrgDID: "did:plc:org1", QueryText: "extracted"}),
+	)
+}
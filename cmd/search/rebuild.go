@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	habitat_syntax "github.com/habitat-network/habitat/internal/syntax"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RebuildScope selects the documents a rebuild replaces: an org's, a
+// collection's, or both. The zero scope is the whole index.
+type RebuildScope struct {
+	OrgDID     syntax.DID
+	Collection syntax.NSID
+}
+
+// filter returns the SQL condition and arguments matching the documents in
+// scope.
+func (s RebuildScope) filter() (string, []any) {
+	conds := []string{"1 = 1"}
+	var args []any
+	if s.OrgDID != "" {
+		conds = append(conds, "org_did = ?")
+		args = append(args, s.OrgDID.String())
+	}
+	if s.Collection != "" {
+		conds = append(conds, "collection = ?")
+		args = append(args, s.Collection.String())
+	}
+	return strings.Join(conds, " AND "), args
+}
+
+// RebuildCatchUp lists the changes applied to the live documents while a
+// rebuild filled its shadow copy, for the swap to carry over rather than
+// discard.
+type RebuildCatchUp struct {
+	// URIs were upserted or deleted: their live documents, or their absence,
+	// replace the rebuilt ones.
+	URIs []habitat_syntax.SpaceRecordURI
+	// Resyncs dropped the documents of a repo indexed before a rev.
+	Resyncs []RebuildResync
+}
+
+// RebuildResync is an Index.Resync applied while a rebuild filled.
+type RebuildResync struct {
+	Space habitat_syntax.SpaceURI
+	Repo  syntax.DID
+	Rev   syntax.TID
+}
+
+// catchUpBatchSize bounds the URIs one catch-up statement names.
+const catchUpBatchSize = 500
+
+// rebuildDocument is a row of the shadow table a rebuild fills. It has the
+// columns of searchDocument but none of its indexes, whose names would clash
+// with the live table's.
+type rebuildDocument struct {
+	URI        string    `gorm:"column:uri;primaryKey"`
+	SpaceURI   string    `gorm:"column:space_uri"`
+	OrgDID     string    `gorm:"column:org_did"`
+	Repo       string    `gorm:"column:repo"`
+	Rev        string    `gorm:"column:rev"`
+	Collection string    `gorm:"column:collection"`
+	Title      string    `gorm:"column:title"`
+	Content    string    `gorm:"column:content"`
+	Metadata   string    `gorm:"column:metadata"`
+	Language   string    `gorm:"column:language"`
+	UpdatedAt  time.Time `gorm:"column:updated_at"`
+}
+
+func (rebuildDocument) TableName() string { return "search_documents_rebuild" }
+
+// rebuildColumns are the search_documents columns a rebuild copies; the
+// Postgres index's localized_tsv is generated from them.
+const rebuildColumns = "uri, space_uri, org_did, repo, rev, collection, " +
+	"title, content, metadata, language, updated_at"
+
+// rebuildSearchDocuments implements Index.Rebuild for the indexes on the
+// search_documents table. fill writes into search_documents_rebuild; the swap
+// then carries the catch-up over to it, deletes the live documents in scope
+// and copies the rebuilt ones in, in one transaction, so readers see either
+// the old documents or the new.
+func rebuildSearchDocuments(
+	ctx context.Context,
+	db *gorm.DB,
+	scope RebuildScope,
+	fill func(put func(Document) error) error,
+	catchUp func() RebuildCatchUp,
+) error {
+	db = db.WithContext(ctx)
+	// A rebuild that was interrupted may have left its shadow table behind.
+	if err := db.Migrator().DropTable(&rebuildDocument{}); err != nil {
+		return fmt.Errorf("drop stale search_documents_rebuild: %w", err)
+	}
+	if err := db.Migrator().CreateTable(&rebuildDocument{}); err != nil {
+		return fmt.Errorf("create search_documents_rebuild: %w", err)
+	}
+	defer func() {
+		_ = db.WithContext(context.WithoutCancel(ctx)).Migrator().DropTable(&rebuildDocument{})
+	}()
+
+	put := func(doc Document) error {
+		row := rebuildDocument(newSearchDocument(doc))
+		return db.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "uri"}},
+			UpdateAll: true,
+		}).Create(&row).Error
+	}
+	if err := fill(put); err != nil {
+		return err
+	}
+
+	changed := catchUp()
+	where, args := scope.filter()
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := catchUpRebuild(tx, changed); err != nil {
+			return err
+		}
+		// Nothing sap holds dates a record, so a rebuilt document keeps the
+		// updated_at of the document it replaces.
+		if err := tx.Exec(`
+			UPDATE search_documents_rebuild
+			SET updated_at = (
+			  SELECT d.updated_at FROM search_documents d
+			  WHERE d.uri = search_documents_rebuild.uri
+			)
+			WHERE EXISTS (
+			  SELECT 1 FROM search_documents d
+			  WHERE d.uri = search_documents_rebuild.uri
+			)
+		`).Error; err != nil {
+			return fmt.Errorf("carry over updated_at: %w", err)
+		}
+		if err := tx.Exec("DELETE FROM search_documents WHERE "+where, args...).Error; err != nil {
+			return fmt.Errorf("clear rebuilt documents: %w", err)
+		}
+		if err := tx.Exec(
+			"INSERT INTO search_documents ("+rebuildColumns+") "+
+				"SELECT "+rebuildColumns+" FROM search_documents_rebuild WHERE "+where,
+			args...,
+		).Error; err != nil {
+			return fmt.Errorf("swap in rebuilt documents: %w", err)
+		}
+		return nil
+	})
+}
+
+// catchUpRebuild carries c over to search_documents_rebuild: the live row of
+// every changed URI replaces the rebuilt one, or removes it if there is none,
+// and each resync drops the same stale documents the live one did.
+func catchUpRebuild(tx *gorm.DB, c RebuildCatchUp) error {
+	uris := stringsOf(c.URIs)
+	for start := 0; start < len(uris); start += catchUpBatchSize {
+		batch := uris[start:min(start+catchUpBatchSize, len(uris))]
+		if err := tx.Exec(
+			"DELETE FROM search_documents_rebuild WHERE uri IN ?",
+			batch,
+		).Error; err != nil {
+			return fmt.Errorf("catch up changed documents: %w", err)
+		}
+		if err := tx.Exec(
+			"INSERT INTO search_documents_rebuild ("+rebuildColumns+") "+
+				"SELECT "+rebuildColumns+" FROM search_documents WHERE uri IN ?",
+			batch,
+		).Error; err != nil {
+			return fmt.Errorf("catch up changed documents: %w", err)
+		}
+	}
+	for _, r := range c.Resyncs {
+		if err := tx.Exec(
+			"DELETE FROM search_documents_rebuild "+
+				"WHERE space_uri = ? AND repo = ? AND rev < ?",
+			r.Space.String(), r.Repo.String(), r.Rev.String(),
+		).Error; err != nil {
+			return fmt.Errorf("catch up resync: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,361 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"github.com/habitat-network/habitat/internal/httpx"
+	habitat_syntax "github.com/habitat-network/habitat/internal/syntax"
+	"github.com/habitat-network/habitat/pkg/sap/syncer"
+	"github.com/urfave/cli/v3"
+)
+
+// replicaPageSize is the page size Reindex reads repos and records in.
+const replicaPageSize = 100
+
+var (
+	// ErrReindexRunning is returned by Reindex while another is in progress.
+	ErrReindexRunning = errors.New("a reindex is already running")
+	// ErrUnknownOrg is returned by Reindex for an org search does not index.
+	ErrUnknownOrg = errors.New("org is not indexed by this search instance")
+	// ErrNoReplica is returned by Reindex when search runs without sap's
+	// replica, the records it rebuilds documents from.
+	ErrNoReplica = errors.New("reindex needs sap's replica; start search with --replica")
+)
+
+// Replica reads back the records sap holds. Satisfied by *sap.Sap built with
+// Config.Replica; nil when search runs without it.
+type Replica interface {
+	Sessions(ctx context.Context) ([]syntax.DID, error)
+	Repos(ctx context.Context, filter syncer.RepoFilter) ([]syncer.RepoStatus, string, error)
+	ListRecords(
+		ctx context.Context,
+		caller syntax.DID,
+		spaceURI syntax.URI,
+		repo syntax.DID,
+		query syncer.RecordQuery,
+	) ([]syncer.Record, string, error)
+	Resync(
+		ctx context.Context,
+		space habitat_syntax.SpaceURI,
+		repo syntax.DID,
+		full bool,
+	) (int64, error)
+}
+
+// ReindexOptions selects what Reindex rebuilds.
+type ReindexOptions struct {
+	Scope RebuildScope
+	// Resync also has sap rebuild every repo in scope from a full snapshot
+	// once the index is rebuilt, for when the records sap holds are in doubt
+	// too. sap delivers the snapshots through the outbox as usual.
+	Resync bool
+}
+
+// ReindexStats reports what a Reindex did.
+type ReindexStats struct {
+	Repos   int `json:"repos"`
+	Records int `json:"records"`
+	// Pending counts records sap holds no value for yet; their repos are
+	// resynced so the records reach the index through the outbox.
+	Pending int `json:"pending"`
+	// Resynced counts the repos sap was asked to resync.
+	Resynced int64 `json:"resynced"`
+}
+
+// Reindex rebuilds the documents in scope from the records sap holds,
+// extracting them afresh, for when extraction, the text-search configuration
+// or the index's schema changed under documents already indexed. Render
+// schemas are learned first so every record is extracted by the latest.
+//
+// The outbox keeps draining while the rebuilt documents are extracted. What
+// it changes meanwhile is carried over to them, and it is held up only while
+// they are swapped in, so the swap discards none of its changes.
+func (ix *Indexer) Reindex(
+	ctx context.Context,
+	replica Replica,
+	opts ReindexOptions,
+) (ReindexStats, error) {
+	if replica == nil {
+		return ReindexStats{}, ErrNoReplica
+	}
+	if !ix.reindexing.TryLock() {
+		return ReindexStats{}, ErrReindexRunning
+	}
+	defer ix.reindexing.Unlock()
+
+	repos, err := reposInScope(ctx, replica, opts.Scope.OrgDID)
+	if err != nil {
+		return ReindexStats{}, err
+	}
+	stats := ReindexStats{Repos: len(repos)}
+
+	ix.mu.Lock()
+	ix.changed = &rebuildChanges{uris: make(map[habitat_syntax.SpaceRecordURI]struct{})}
+	ix.mu.Unlock()
+	swapping := false
+	catchUp := func() RebuildCatchUp {
+		ix.mu.Lock()
+		swapping = true
+		return ix.changed.catchUp()
+	}
+
+	var pending []syncer.RepoStatus
+	err = ix.index.Rebuild(ctx, opts.Scope, func(put func(Document) error) error {
+		for _, repo := range repos {
+			if err := eachRecord(ctx, replica, repo, renderSchemaNSID, func(
+				uri habitat_syntax.SpaceRecordURI,
+				rec syncer.Record,
+			) error {
+				if rec.Value == nil {
+					return nil
+				}
+				return ix.extractor.LearnSchema(ctx, uri, repo.Rev, rec.Value)
+			}); err != nil {
+				return err
+			}
+		}
+		for _, repo := range repos {
+			missing := false
+			if err := eachRecord(ctx, replica, repo, opts.Scope.Collection, func(
+				uri habitat_syntax.SpaceRecordURI,
+				rec syncer.Record,
+			) error {
+				if rec.Value == nil {
+					stats.Pending++
+					missing = true
+					return nil
+				}
+				doc, err := ix.document(uri, repo.Rev, rec.Value)
+				if err != nil {
+					return err
+				}
+				stats.Records++
+				return put(doc)
+			}); err != nil {
+				return err
+			}
+			if missing {
+				pending = append(pending, repo)
+			}
+		}
+		return nil
+	}, catchUp)
+	if !swapping {
+		ix.mu.Lock()
+	}
+	ix.changed = nil
+	ix.mu.Unlock()
+	if err != nil {
+		return stats, fmt.Errorf("rebuild index: %w", err)
+	}
+
+	if opts.Resync {
+		pending = repos
+	}
+	for _, repo := range pending {
+		n, err := replica.Resync(ctx, repo.Space, repo.DID, true)
+		if err != nil {
+			return stats, fmt.Errorf("resync %s in %s: %w", repo.DID, repo.Space, err)
+		}
+		stats.Resynced += n
+	}
+	slog.InfoContext(ctx, "reindex complete",
+		"org", opts.Scope.OrgDID, "collection", opts.Scope.Collection,
+		"repos", stats.Repos, "records", stats.Records,
+		"pending", stats.Pending, "resynced", stats.Resynced)
+	return stats, nil
+}
+
+// reposInScope lists the repos sap tracks in the spaces of org, or of every
+// org search indexes when org is empty.
+func reposInScope(
+	ctx context.Context,
+	replica Replica,
+	org syntax.DID,
+) ([]syncer.RepoStatus, error) {
+	orgs, err := replica.Sessions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list orgs: %w", err)
+	}
+	if org != "" {
+		if !slices.Contains(orgs, org) {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownOrg, org)
+		}
+		orgs = []syntax.DID{org}
+	}
+
+	var repos []syncer.RepoStatus
+	cursor := ""
+	for {
+		page, next, err := replica.Repos(ctx, syncer.RepoFilter{
+			Limit:  replicaPageSize,
+			Cursor: cursor,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("list repos: %w", err)
+		}
+		for _, repo := range page {
+			if slices.Contains(orgs, repo.Space.SpaceOwner()) {
+				repos = append(repos, repo)
+			}
+		}
+		if next == "" {
+			return repos, nil
+		}
+		cursor = next
+	}
+}
+
+// eachRecord calls fn with every record sap holds for repo, or only those of
+// collection when it is set. Records are read as the org that owns the
+// space, whose session gives sap its access.
+func eachRecord(
+	ctx context.Context,
+	replica Replica,
+	repo syncer.RepoStatus,
+	collection syntax.NSID,
+	fn func(habitat_syntax.SpaceRecordURI, syncer.Record) error,
+) error {
+	cursor := ""
+	for {
+		records, next, err := replica.ListRecords(
+			ctx,
+			repo.Space.SpaceOwner(),
+			repo.Space.URI(),
+			repo.DID,
+			syncer.RecordQuery{Collection: collection, Limit: replicaPageSize, Cursor: cursor},
+		)
+		if err != nil {
+			return fmt.Errorf("list records of %s in %s: %w", repo.DID, repo.Space, err)
+		}
+		for _, rec := range records {
+			uri := habitat_syntax.ConstructSpaceRecordURI(
+				repo.Space,
+				repo.DID,
+				rec.Collection,
+				rec.Rkey,
+			)
+			if err := fn(uri, rec); err != nil {
+				return err
+			}
+		}
+		if next == "" {
+			return nil
+		}
+		cursor = next
+	}
+}
+
+// reindexServer serves the admin endpoint that runs Reindex.
+type reindexServer struct {
+	indexer *Indexer
+	replica Replica
+}
+
+// handleReindex rebuilds the index, or the part of it an optional org and
+// collection select, and answers once the rebuilt documents are swapped in.
+func (s *reindexServer) handleReindex(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req reindexRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	opts := ReindexOptions{Resync: req.Resync}
+	if req.Org != "" {
+		org, err := syntax.ParseDID(req.Org)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid org: %s", err), http.StatusBadRequest)
+			return
+		}
+		opts.Scope.OrgDID = org
+	}
+	if req.Collection != "" {
+		collection, err := syntax.ParseNSID(req.Collection)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid collection: %s", err), http.StatusBadRequest)
+			return
+		}
+		opts.Scope.Collection = collection
+	}
+
+	stats, err := s.indexer.Reindex(r.Context(), s.replica, opts)
+	switch {
+	case errors.Is(err, ErrReindexRunning), errors.Is(err, ErrNoReplica):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case errors.Is(err, ErrUnknownOrg):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	httpx.WriteJSON(r.Context(), w, stats)
+}
+
+type reindexRequest struct {
+	Org        string `json:"org,omitempty"`
+	Collection string `json:"collection,omitempty"`
+	Resync     bool   `json:"resync,omitempty"`
+}
+
+// runReindex is the reindex subcommand: it asks a running search server to
+// reindex through its internal endpoint, since only the server can hold up
+// its own indexer while the rebuilt documents are swapped in.
+func runReindex(ctx context.Context, cmd *cli.Command) error {
+	body, err := json.Marshal(reindexRequest{
+		Org:        cmd.String(fReindexOrg),
+		Collection: cmd.String(fReindexCollection),
+		Resync:     cmd.Bool(fReindexResync),
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodPost,
+		strings.TrimSuffix(cmd.String(fInternalURL), "/")+"/admin/reindex",
+		bytes.NewReader(body),
+	)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := httpx.NewClient().Do(req)
+	if err != nil {
+		return fmt.Errorf("reindex: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("reindex: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	var stats ReindexStats
+	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		return fmt.Errorf("decode reindex response: %w", err)
+	}
+	fmt.Printf("reindexed %d records from %d repos", stats.Records, stats.Repos)
+	if stats.Pending > 0 {
+		fmt.Printf("; %d records sap holds no value for will follow through the outbox",
+			stats.Pending)
+	}
+	if stats.Resynced > 0 {
+		fmt.Printf("; %d repos resyncing", stats.Resynced)
+	}
+	fmt.Println()
+	return nil
+}
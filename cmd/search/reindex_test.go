@@ -0,0 +1,244 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"github.com/habitat-network/habitat/api/habitat"
+	habitat_syntax "github.com/habitat-network/habitat/internal/syntax"
+	"github.com/habitat-network/habitat/pkg/sap/outbox"
+	"github.com/habitat-network/habitat/pkg/sap/syncer"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeReplica holds records per space and repo, as sap's replica would.
+type fakeReplica struct {
+	orgs    []syntax.DID
+	repos   []syncer.RepoStatus
+	records map[habitat_syntax.SpaceURI]map[syntax.DID][]syncer.Record
+	// resynced lists the repos Resync was asked to rebuild in full.
+	resynced []syntax.DID
+	// onList, if set, runs at each ListRecords.
+	onList func()
+}
+
+func (f *fakeReplica) Sessions(ctx context.Context) ([]syntax.DID, error) {
+	return f.orgs, nil
+}
+
+func (f *fakeReplica) Repos(
+	ctx context.Context,
+	filter syncer.RepoFilter,
+) ([]syncer.RepoStatus, string, error) {
+	return f.repos, "", nil
+}
+
+func (f *fakeReplica) ListRecords(
+	ctx context.Context,
+	caller syntax.DID,
+	spaceURI syntax.URI,
+	repo syntax.DID,
+	query syncer.RecordQuery,
+) ([]syncer.Record, string, error) {
+	if f.onList != nil {
+		f.onList()
+	}
+	space := habitat_syntax.SpaceURI(spaceURI)
+	if caller != space.SpaceOwner() {
+		return nil, "", ErrUnknownOrg
+	}
+	var records []syncer.Record
+	for _, rec := range f.records[space][repo] {
+		if query.Collection == "" || rec.Collection == query.Collection {
+			records = append(records, rec)
+		}
+	}
+	return records, "", nil
+}
+
+func (f *fakeReplica) Resync(
+	ctx context.Context,
+	space habitat_syntax.SpaceURI,
+	repo syntax.DID,
+	full bool,
+) (int64, error) {
+	if full {
+		f.resynced = append(f.resynced, repo)
+	}
+	return 1, nil
+}
+
+func newFakeReplica(t *testing.T) *fakeReplica {
+	t.Helper()
+	org1 := habitat_syntax.SpaceURI("at://did:plc:org1/space/network.habitat.space/skey1")
+	org2 := habitat_syntax.SpaceURI("at://did:plc:org2/space/network.habitat.space/skey1")
+	return &fakeReplica{
+		orgs: []syntax.DID{"did:plc:org1", "did:plc:org2"},
+		repos: []syncer.RepoStatus{
+			{Space: org1, DID: "did:plc:user1", Rev: "3jzfcijpj2z2a"},
+			{Space: org1, DID: "did:plc:user2", Rev: "3jzfcijpj2z2a"},
+			{Space: org2, DID: "did:plc:user1", Rev: "3jzfcijpj2z2a"},
+		},
+		records: map[habitat_syntax.SpaceURI]map[syntax.DID][]syncer.Record{
+			org1: {
+				// Listed in path order, so the event comes before the render
+				// schema that says how to extract it.
+				"did:plc:user1": {
+					{
+						Collection: "network.habitat.event",
+						Rkey:       "rkey1",
+						Value: mustMarshal(t, map[string]any{
+							"name": "Board meeting",
+							"room": "Room 4",
+						}),
+					},
+					{
+						Collection: renderSchemaNSID,
+						Rkey:       "events",
+						Value: mustMarshal(t, habitat.NetworkHabitatRenderSchema{
+							TargetLexicon: "network.habitat.event",
+							Title:         "Event",
+							Fields: []habitat.NetworkHabitatRenderSchemaFieldSchema{{
+								Path:        "name",
+								DisplayType: "network.habitat.render.schema#text",
+								Priority:    "network.habitat.render.schema#primary",
+							}},
+						}),
+					},
+				},
+				"did:plc:user2": {
+					{
+						Collection: "network.habitat.note",
+						Rkey:       "rkey1",
+						Value:      mustMarshal(t, map[string]any{"title": "Minutes"}),
+					},
+					// Synced before the replica kept values.
+					{Collection: "network.habitat.note", Rkey: "rkey2"},
+				},
+			},
+			org2: {
+				"did:plc:user1": {{
+					Collection: "network.habitat.note",
+					Rkey:       "rkey1",
+					Value:      mustMarshal(t, map[string]any{"title": "Elsewhere"}),
+				}},
+			},
+		},
+	}
+}
+
+func TestIndexer_ReindexRebuildsOrgFromReplica(t *testing.T) {
+	index := &fakeIndex{}
+	replica := newFakeReplica(t)
+	indexer := NewIndexer(index, newFakeOutbox(nil), newTestExtractor(t))
+
+	stats, err := indexer.Reindex(context.Background(), replica, ReindexOptions{
+		Scope: RebuildScope{OrgDID: "did:plc:org1"},
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, RebuildScope{OrgDID: "did:plc:org1"}, index.rebuiltScope)
+	titles := map[string]string{}
+	for _, doc := range index.rebuilt {
+		require.Equal(t, syntax.DID("did:plc:org1"), doc.OrgDID)
+		titles[doc.URI.String()] = doc.Title
+	}
+	require.Len(t, titles, 3)
+	require.Equal(
+		t,
+		"Board meeting",
+		titles[recordURIFor("did:plc:org1", "network.habitat.event", "rkey1").String()],
+		"records are extracted by render schemas listed after them",
+	)
+	require.Equal(t, ReindexStats{Repos: 2, Records: 3, Pending: 1, Resynced: 1}, stats)
+	require.Equal(t, []syntax.DID{"did:plc:user2"}, replica.resynced)
+}
+
+func TestIndexer_ReindexCollectionWithResync(t *testing.T) {
+	index := &fakeIndex{}
+	replica := newFakeReplica(t)
+	indexer := NewIndexer(index, newFakeOutbox(nil), newTestExtractor(t))
+
+	stats, err := indexer.Reindex(context.Background(), replica, ReindexOptions{
+		Scope:  RebuildScope{Collection: "network.habitat.note"},
+		Resync: true,
+	})
+	require.NoError(t, err)
+
+	require.Len(t, index.rebuilt, 2)
+	for _, doc := range index.rebuilt {
+		require.Equal(t, syntax.NSID("network.habitat.note"), doc.Collection)
+	}
+	require.Equal(t, int64(3), stats.Resynced, "every repo in scope is resynced")
+}
+
+func TestIndexer_ReindexKeepsIndexingWhileFilling(t *testing.T) {
+	ctx := context.Background()
+	index := &fakeIndex{}
+	replica := newFakeReplica(t)
+	indexer := NewIndexer(index, newFakeOutbox(nil), newTestExtractor(t))
+	deleted := recordURIFor("did:plc:org1", "network.habitat.note", "rkey1")
+	replica.onList = func() {
+		// Run takes mu for each batch, so it would block here if Reindex
+		// held mu while reading the replica.
+		require.True(t, indexer.mu.TryLock(), "the outbox is not held up while filling")
+		defer indexer.mu.Unlock()
+		require.NoError(t, indexer.handleMessage(ctx, outbox.Message{
+			Action: outbox.ActionDelete,
+			URI:    deleted,
+		}))
+	}
+
+	_, err := indexer.Reindex(ctx, replica, ReindexOptions{
+		Scope: RebuildScope{OrgDID: "did:plc:org1"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []habitat_syntax.SpaceRecordURI{deleted}, index.caughtUp.URIs)
+	require.Nil(t, indexer.changed, "changes are only collected during a Reindex")
+}
+
+func TestIndexer_ReindexUnknownOrg(t *testing.T) {
+	indexer := NewIndexer(&fakeIndex{}, newFakeOutbox(nil), newTestExtractor(t))
+
+	_, err := indexer.Reindex(context.Background(), newFakeReplica(t), ReindexOptions{
+		Scope: RebuildScope{OrgDID: "did:plc:stranger"},
+	})
+	require.ErrorIs(t, err, ErrUnknownOrg)
+}
+
+func TestReindexServer_HandleReindex(t *testing.T) {
+	index := &fakeIndex{}
+	server := &reindexServer{
+		indexer: NewIndexer(index, newFakeOutbox(nil), newTestExtractor(t)),
+		replica: newFakeReplica(t),
+	}
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/admin/reindex", bytes.NewBufferString(body))
+		rec := httptest.NewRecorder()
+		server.handleReindex(rec, req)
+		return rec
+	}
+
+	rec := post(`{"org": "did:plc:org2", "collection": "network.habitat.note"}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.JSONEq(t, `{"repos": 1, "records": 1, "pending": 0, "resynced": 0}`, rec.Body.String())
+	require.Equal(
+		t,
+		RebuildScope{OrgDID: "did:plc:org2", Collection: "network.habitat.note"},
+		index.rebuiltScope,
+	)
+
+	require.Equal(t, http.StatusBadRequest, post(`{"org": "bob"}`).Code)
+	require.Equal(t, http.StatusBadRequest, post(`{"collection": "notes"}`).Code)
+	require.Equal(t, http.StatusNotFound, post(`{"org": "did:plc:stranger"}`).Code)
+
+	// Without sap's replica there is nothing to rebuild from.
+	server.replica = nil
+	rec = post(`{}`)
+	require.Equal(t, http.StatusConflict, rec.Code)
+	require.Contains(t, rec.Body.String(), "--replica")
+}
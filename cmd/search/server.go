@@ -5,83 +5,194 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/bluesky-social/indigo/atproto/syntax"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/habitat-network/habitat/api/habitat"
 	"github.com/habitat-network/habitat/internal/httpx"
+	habitat_syntax "github.com/habitat-network/habitat/internal/syntax"
 )
 
+// Orgs lists the orgs search indexes. Satisfied by *sap.Sap, whose sessions
+// are the orgs added through /org/add.
+type Orgs interface {
+	Sessions(ctx context.Context) ([]syntax.DID, error)
+}
+
 type Server struct {
 	// For calling out to pear instance
 	pearHost   string
 	httpClient *http.Client
 
 	index Index
+	orgs  Orgs
 }
 
-func NewServer(host string, index Index) *Server {
+func NewServer(host string, index Index, orgs Orgs) *Server {
 	return &Server{
 		pearHost:   host,
 		httpClient: httpx.NewClient(),
 		index:      index,
+		orgs:       orgs,
 	}
 }
 
-func (s *Server) resolveCallerOrg(
+// callPear makes an XRPC query to pear as the caller, with their own bearer
+// token, so pear answers only what the caller may see.
+func (s *Server) callPear(
 	ctx context.Context,
 	callerBearerToken string,
-) (syntax.DID, error) {
-	req, err := http.NewRequestWithContext(
-		ctx,
-		http.MethodGet,
-		s.pearHost+"/xrpc/network.habitat.org.getMetadata",
-		http.NoBody,
-	)
+	method string,
+	params url.Values,
+	out any,
+) error {
+	target := s.pearHost + "/xrpc/" + method
+	if len(params) > 0 {
+		target += "?" + params.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, http.NoBody)
 	if err != nil {
-		return "", fmt.Errorf("build getMetadata request: %w", err)
+		return fmt.Errorf("build %s request: %w", method, err)
 	}
 	req.Header.Set("Authorization", "Bearer "+callerBearerToken)
 	req.Header.Set("Habitat-Auth-Method", "oauth")
 
 	resp, err := s.httpClient.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("call getMetadata: %w", err)
+		return fmt.Errorf("call %s: %w", method, err)
 	}
 	defer func() { _ = resp.Body.Close() }()
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("getMetadata returned status %d", resp.StatusCode)
+		return fmt.Errorf("%s returned status %d", method, resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode %s response: %w", method, err)
 	}
+	return nil
+}
+
+func (s *Server) resolveCallerOrg(
+	ctx context.Context,
+	callerBearerToken string,
+) (syntax.DID, error) {
 	var out habitat.NetworkHabitatOrgGetMetadataOutput
-	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
-		return "", fmt.Errorf("decode getMetadata response: %w", err)
+	if err := s.callPear(
+		ctx,
+		callerBearerToken,
+		"network.habitat.org.getMetadata",
+		nil,
+		&out,
+	); err != nil {
+		return "", err
 	}
 	return syntax.DID(out.OrgId), nil
 }
 
-func (s *Server) HandleQuery(w http.ResponseWriter, r *http.Request) {
-	/*
-		bearer := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
-		if bearer == "" {
-			http.Error(w, "missing authorization", http.StatusUnauthorized)
-			return
+// callerDID reads the caller's DID from the subject of their bearer token:
+// pear's access tokens are JWTs issued to the authenticated user. The
+// signature is not checked here, so the DID is only trusted once pear has
+// accepted the same token.
+func callerDID(callerBearerToken string) (syntax.DID, error) {
+	token, _, err := jwt.NewParser().ParseUnverified(callerBearerToken, jwt.MapClaims{})
+	if err != nil {
+		return "", fmt.Errorf("parse bearer token: %w", err)
+	}
+	sub, err := token.Claims.GetSubject()
+	if err != nil {
+		return "", fmt.Errorf("read bearer token subject: %w", err)
+	}
+	return syntax.ParseDID(sub)
+}
+
+// readableSpaces lists the spaces the caller holds the reader role on, as
+// pear resolves it through groups and role implications. It never returns
+// nil, so the result always filters a query.
+func (s *Server) readableSpaces(
+	ctx context.Context,
+	callerBearerToken string,
+	did syntax.DID,
+) ([]habitat_syntax.SpaceURI, error) {
+	var out habitat.NetworkHabitatRelationshipListRelatedSpacesOutput
+	if err := s.callPear(
+		ctx,
+		callerBearerToken,
+		"network.habitat.relationship.listRelatedSpaces",
+		url.Values{"did": {did.String()}, "relation": {"reader"}},
+		&out,
+	); err != nil {
+		return nil, err
+	}
+	spaces := make([]habitat_syntax.SpaceURI, 0, len(out.Spaces))
+	for _, raw := range out.Spaces {
+		space, err := habitat_syntax.ParseSpaceURI(raw)
+		if err != nil {
+			continue
 		}
-	*/
+		spaces = append(spaces, space)
+	}
+	return spaces, nil
+}
+
+// HandleQuery searches the index partition of the caller's org, as pear
+// reports it for the caller's bearer token, limited to the spaces the caller
+// can read. Callers from an org search does not index are refused rather
+// than shown an empty result.
+func (s *Server) HandleQuery(w http.ResponseWriter, r *http.Request) {
+	bearer := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if bearer == "" {
+		http.Error(w, "missing authorization", http.StatusUnauthorized)
+		return
+	}
 
 	q := r.URL.Query().Get("q")
 	if q == "" {
 		http.Error(w, "missing required parameter: q", http.StatusBadRequest)
 		return
 	}
+	params, err := parseQueryFilters(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	/*
-		orgDID, err := s.resolveCallerOrg(r.Context(), bearer)
-		if err != nil {
-			http.Error(w, "unauthorized", http.StatusUnauthorized)
-			return
-		}
-	*/
+	orgDID, err := s.resolveCallerOrg(r.Context(), bearer)
+	if err != nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	did, err := callerDID(bearer)
+	if err != nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	orgs, err := s.orgs.Sessions(r.Context())
+	if err != nil {
+		http.Error(w, "list indexed orgs", http.StatusInternalServerError)
+		return
+	}
+	if !slices.Contains(orgs, orgDID) {
+		http.Error(w, "org is not indexed by this search instance", http.StatusForbidden)
+		return
+	}
+
+	spaces, err := s.readableSpaces(r.Context(), bearer, did)
+	if err != nil {
+		http.Error(w, "resolve readable spaces", http.StatusBadGateway)
+		return
+	}
+	if params.Spaces != nil {
+		// Asking for a space the caller cannot read narrows the search to
+		// nothing rather than widening it.
+		spaces = slices.DeleteFunc(params.Spaces, func(space habitat_syntax.SpaceURI) bool {
+			return !slices.Contains(spaces, space)
+		})
+	}
 
 	limit := 25
 	if l := r.URL.Query().Get("limit"); l != "" {
@@ -90,18 +201,24 @@ func (s *Server) HandleQuery(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	result, err := s.index.Query(r.Context(), QueryParams{
-		OrgDID:    hardcodedOrgDID, // TODO: update
-		QueryText: q,
-		Limit:     limit,
-		Cursor:    r.URL.Query().Get("cursor"),
-	})
+	params.OrgDID = orgDID
+	params.Spaces = spaces
+	params.QueryText = q
+	params.Limit = limit
+	params.Cursor = r.URL.Query().Get("cursor")
+	result, err := s.index.Query(r.Context(), params)
 	if err != nil {
 		http.Error(w, "search failed", http.StatusInternalServerError)
 		return
 	}
 
-	out := habitat.NetworkHabitatSearchQueryOutput{Cursor: result.NextCursor}
+	out := habitat.NetworkHabitatSearchQueryOutput{
+		Cursor: result.NextCursor,
+		Facets: &habitat.NetworkHabitatSearchQueryFacets{
+			Collections: facetCountViews(result.Facets.Collections),
+			Spaces:      facetCountViews(result.Facets.Spaces),
+		},
+	}
 	for _, res := range result.Results {
 		out.Results = append(out.Results, habitat.NetworkHabitatSearchQueryResultView{
 			Uri:        res.URI.String(),
@@ -115,3 +232,65 @@ func (s *Server) HandleQuery(w http.ResponseWriter, r *http.Request) {
 	}
 	httpx.WriteJSON(r.Context(), w, out)
 }
+
+// parseQueryFilters reads the collections, spaces, authors, since, until and
+// lang parameters of a query. Spaces is left nil when none were asked for.
+func parseQueryFilters(query url.Values) (QueryParams, error) {
+	var params QueryParams
+	for _, raw := range query["collections"] {
+		collection, err := syntax.ParseNSID(raw)
+		if err != nil {
+			return QueryParams{}, fmt.Errorf("invalid collection %q: %w", raw, err)
+		}
+		params.Collections = append(params.Collections, collection)
+	}
+	for _, raw := range query["spaces"] {
+		space, err := habitat_syntax.ParseSpaceURI(raw)
+		if err != nil {
+			return QueryParams{}, fmt.Errorf("invalid space %q: %w", raw, err)
+		}
+		params.Spaces = append(params.Spaces, space)
+	}
+	for _, raw := range query["authors"] {
+		author, err := syntax.ParseDID(raw)
+		if err != nil {
+			return QueryParams{}, fmt.Errorf("invalid author %q: %w", raw, err)
+		}
+		params.Authors = append(params.Authors, author)
+	}
+	var err error
+	if params.Since, err = parseTimeParam(query, "since"); err != nil {
+		return QueryParams{}, err
+	}
+	if params.Until, err = parseTimeParam(query, "until"); err != nil {
+		return QueryParams{}, err
+	}
+	if raw := query.Get("lang"); raw != "" {
+		if params.Language = primaryLanguage(raw); params.Language == "" {
+			return QueryParams{}, fmt.Errorf("invalid lang %q", raw)
+		}
+	}
+	return params, nil
+}
+
+// parseTimeParam reads the datetime parameter name, or the zero time when it
+// is absent.
+func parseTimeParam(query url.Values, name string) (time.Time, error) {
+	raw := query.Get(name)
+	if raw == "" {
+		return time.Time{}, nil
+	}
+	dt, err := syntax.ParseDatetime(raw)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s %q: %w", name, raw, err)
+	}
+	return dt.Time(), nil
+}
+
+func facetCountViews(counts []FacetCount) []habitat.NetworkHabitatSearchQueryFacetCount {
+	views := make([]habitat.NetworkHabitatSearchQueryFacetCount, len(counts))
+	for i, c := range counts {
+		views[i] = habitat.NetworkHabitatSearchQueryFacetCount{Value: c.Value, Count: c.Count}
+	}
+	return views
+}
@@ -2,10 +2,16 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"testing"
+	"time"
 
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/habitat-network/habitat/api/habitat"
 	habitat_syntax "github.com/habitat-network/habitat/internal/syntax"
 	"github.com/stretchr/testify/require"
 )
@@ -19,12 +25,68 @@ func (s *stubIndex) Upsert(ctx context.Context, doc Document) error { return nil
 func (s *stubIndex) Delete(ctx context.Context, uri habitat_syntax.SpaceRecordURI) error {
 	return nil
 }
+func (s *stubIndex) Resync(
+	ctx context.Context,
+	space habitat_syntax.SpaceURI,
+	repo syntax.DID,
+	rev syntax.TID,
+) error {
+	return nil
+}
 func (s *stubIndex) Query(ctx context.Context, params QueryParams) (QueryResult, error) {
 	s.gotParams = params
 	return s.result, nil
 }
+func (s *stubIndex) Rebuild(
+	ctx context.Context,
+	scope RebuildScope,
+	fill func(put func(Document) error) error,
+	catchUp func() RebuildCatchUp,
+) error {
+	return nil
+}
+
+// staticOrgs is an Orgs that indexes a fixed set of orgs.
+type staticOrgs []syntax.DID
+
+func (o staticOrgs) Sessions(ctx context.Context) ([]syntax.DID, error) { return o, nil }
+
+// newPearStub serves getMetadata and listRelatedSpaces as pear would for a
+// caller in orgDID who can read spaces.
+func newPearStub(t *testing.T, orgDID string, spaces ...string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/xrpc/network.habitat.org.getMetadata":
+			_ = json.NewEncoder(w).Encode(habitat.NetworkHabitatOrgGetMetadataOutput{
+				OrgId:           orgDID,
+				LoginMethod:     "password",
+				HandleSubdomain: "org",
+			})
+		case "/xrpc/network.habitat.relationship.listRelatedSpaces":
+			require.Equal(t, "did:plc:caller", r.URL.Query().Get("did"))
+			require.Equal(t, "reader", r.URL.Query().Get("relation"))
+			_ = json.NewEncoder(w).Encode(habitat.NetworkHabitatRelationshipListRelatedSpacesOutput{
+				Spaces: append([]string{}, spaces...),
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// callerToken is a bearer token for did, shaped like pear's JWT access tokens.
+func callerToken(t *testing.T, did string) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": did}).
+		SignedString([]byte("test"))
+	require.NoError(t, err)
+	return token
+}
 
-/*
 func TestServer_HandleQuery_FiltersByResolvedOrg(t *testing.T) {
 	index := &stubIndex{result: QueryResult{
 		Results: []Result{{
@@ -35,16 +97,22 @@ func TestServer_HandleQuery_FiltersByResolvedOrg(t *testing.T) {
 			Rank:       0.5,
 		}},
 	}}
-	server := NewServer("pear.example.com", index)
+	pear := newPearStub(t, "did:plc:org1", "at://did:plc:org1/space/network.habitat.space/skey1")
+	server := NewServer(pear.URL, index, staticOrgs{"did:plc:org2", "did:plc:org1"})
 
 	req := httptest.NewRequest(http.MethodGet, "/xrpc/network.habitat.search.query?q=budget", nil)
-	req.Header.Set("Authorization", "Bearer caller-token")
+	req.Header.Set("Authorization", "Bearer "+callerToken(t, "did:plc:caller"))
 	rec := httptest.NewRecorder()
 
 	server.HandleQuery(rec, req)
 
 	require.Equal(t, http.StatusOK, rec.Code)
 	require.Equal(t, syntax.DID("did:plc:org1"), index.gotParams.OrgDID)
+	require.Equal(
+		t,
+		[]habitat_syntax.SpaceURI{"at://did:plc:org1/space/network.habitat.space/skey1"},
+		index.gotParams.Spaces,
+	)
 	require.Equal(t, "budget", index.gotParams.QueryText)
 
 	var out habitat.NetworkHabitatSearchQueryOutput
@@ -54,8 +122,138 @@ func TestServer_HandleQuery_FiltersByResolvedOrg(t *testing.T) {
 	require.Equal(t, int64(500000), out.Results[0].Rank)
 }
 
+func TestServer_HandleQuery_NoReadableSpacesStillFilters(t *testing.T) {
+	index := &stubIndex{}
+	pear := newPearStub(t, "did:plc:org1")
+	server := NewServer(pear.URL, index, staticOrgs{"did:plc:org1"})
+
+	req := httptest.NewRequest(http.MethodGet, "/xrpc/network.habitat.search.query?q=budget", nil)
+	req.Header.Set("Authorization", "Bearer "+callerToken(t, "did:plc:caller"))
+	rec := httptest.NewRecorder()
+
+	server.HandleQuery(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NotNil(t, index.gotParams.Spaces, "a caller who reads nothing must not see everything")
+	require.Empty(t, index.gotParams.Spaces)
+}
+
+func TestServer_HandleQuery_PassesFiltersAndReturnsFacets(t *testing.T) {
+	index := &stubIndex{result: QueryResult{Facets: Facets{
+		Collections: []FacetCount{{Value: "network.habitat.event", Count: 3}},
+		Spaces: []FacetCount{
+			{Value: "at://did:plc:org1/space/network.habitat.space/gardening", Count: 3},
+		},
+	}}}
+	pear := newPearStub(t, "did:plc:org1",
+		"at://did:plc:org1/space/network.habitat.space/gardening",
+		"at://did:plc:org1/space/network.habitat.space/board",
+	)
+	server := NewServer(pear.URL, index, staticOrgs{"did:plc:org1"})
+
+	req := httptest.NewRequest(http.MethodGet, "/xrpc/network.habitat.search.query?q=compost"+
+		"&collections=network.habitat.event"+
+		"&spaces=at://did:plc:org1/space/network.habitat.space/gardening"+
+		"&authors=did:plc:author"+
+		"&since=2026-09-01T00:00:00Z&until=2026-10-01T00:00:00Z"+
+		"&lang=pt-BR", nil)
+	req.Header.Set("Authorization", "Bearer "+callerToken(t, "did:plc:caller"))
+	rec := httptest.NewRecorder()
+
+	server.HandleQuery(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, []syntax.NSID{"network.habitat.event"}, index.gotParams.Collections)
+	require.Equal(
+		t,
+		[]habitat_syntax.SpaceURI{"at://did:plc:org1/space/network.habitat.space/gardening"},
+		index.gotParams.Spaces,
+	)
+	require.Equal(t, []syntax.DID{"did:plc:author"}, index.gotParams.Authors)
+	require.Equal(t, time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC), index.gotParams.Since)
+	require.Equal(t, time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC), index.gotParams.Until)
+	require.Equal(t, "pt", index.gotParams.Language)
+
+	var out habitat.NetworkHabitatSearchQueryOutput
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&out))
+	require.NotNil(t, out.Facets)
+	require.Len(t, out.Facets.Collections, 1)
+	require.Equal(t, "network.habitat.event", out.Facets.Collections[0].Value)
+	require.Equal(t, int64(3), out.Facets.Collections[0].Count)
+	require.Len(t, out.Facets.Spaces, 1)
+}
+
+func TestServer_HandleQuery_UnreadableSpaceFilterMatchesNothing(t *testing.T) {
+	index := &stubIndex{}
+	pear := newPearStub(
+		t,
+		"did:plc:org1",
+		"at://did:plc:org1/space/network.habitat.space/gardening",
+	)
+	server := NewServer(pear.URL, index, staticOrgs{"did:plc:org1"})
+
+	req := httptest.NewRequest(http.MethodGet, "/xrpc/network.habitat.search.query?q=budget"+
+		"&spaces=at://did:plc:org1/space/network.habitat.space/board", nil)
+	req.Header.Set("Authorization", "Bearer "+callerToken(t, "did:plc:caller"))
+	rec := httptest.NewRecorder()
+
+	server.HandleQuery(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NotNil(t, index.gotParams.Spaces)
+	require.Empty(t, index.gotParams.Spaces)
+}
+
+func TestServer_HandleQuery_InvalidFilterIs400(t *testing.T) {
+	server := NewServer("pear.example.com", &stubIndex{}, staticOrgs{})
+
+	for _, filter := range []string{
+		"collections=not an nsid", "authors=bob", "since=last month", "lang=42",
+	} {
+		req := httptest.NewRequest(
+			http.MethodGet,
+			"/xrpc/network.habitat.search.query?q=budget&"+url.PathEscape(filter),
+			nil,
+		)
+		req.Header.Set("Authorization", "Bearer "+callerToken(t, "did:plc:caller"))
+		rec := httptest.NewRecorder()
+
+		server.HandleQuery(rec, req)
+
+		require.Equal(t, http.StatusBadRequest, rec.Code, filter)
+	}
+}
+
+func TestServer_HandleQuery_OpaqueTokenIs401(t *testing.T) {
+	pear := newPearStub(t, "did:plc:org1")
+	server := NewServer(pear.URL, &stubIndex{}, staticOrgs{"did:plc:org1"})
+
+	req := httptest.NewRequest(http.MethodGet, "/xrpc/network.habitat.search.query?q=budget", nil)
+	req.Header.Set("Authorization", "Bearer caller-token")
+	rec := httptest.NewRecorder()
+
+	server.HandleQuery(rec, req)
+
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestServer_HandleQuery_UnindexedOrgIs403(t *testing.T) {
+	index := &stubIndex{}
+	pear := newPearStub(t, "did:plc:elsewhere")
+	server := NewServer(pear.URL, index, staticOrgs{"did:plc:org1"})
+
+	req := httptest.NewRequest(http.MethodGet, "/xrpc/network.habitat.search.query?q=budget", nil)
+	req.Header.Set("Authorization", "Bearer "+callerToken(t, "did:plc:caller"))
+	rec := httptest.NewRecorder()
+
+	server.HandleQuery(rec, req)
+
+	require.Equal(t, http.StatusForbidden, rec.Code)
+	require.Empty(t, index.gotParams.OrgDID, "an unindexed org must not reach the index")
+}
+
 func TestServer_HandleQuery_MissingAuthorizationIs401(t *testing.T) {
-	server := NewServer("pear.example.com", &stubIndex{})
+	server := NewServer("pear.example.com", &stubIndex{}, staticOrgs{})
 
 	req := httptest.NewRequest(http.MethodGet, "/xrpc/network.habitat.search.query?q=budget", nil)
 	rec := httptest.NewRecorder()
@@ -66,7 +264,7 @@ func TestServer_HandleQuery_MissingAuthorizationIs401(t *testing.T) {
 }
 
 func TestServer_HandleQuery_MissingQIs400(t *testing.T) {
-	server := NewServer("pear.example.com", &stubIndex{})
+	server := NewServer("pear.example.com", &stubIndex{}, staticOrgs{})
 
 	req := httptest.NewRequest(http.MethodGet, "/xrpc/network.habitat.search.query", nil)
 	req.Header.Set("Authorization", "Bearer caller-token")
@@ -78,7 +276,11 @@ func TestServer_HandleQuery_MissingQIs400(t *testing.T) {
 }
 
 func TestServer_HandleQuery_ResolverErrorIs401(t *testing.T) {
-	server := NewServer("pear.example.com", &stubIndex{})
+	pear := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer pear.Close()
+	server := NewServer(pear.URL, &stubIndex{}, staticOrgs{"did:plc:org1"})
 
 	req := httptest.NewRequest(http.MethodGet, "/xrpc/network.habitat.search.query?q=budget", nil)
 	req.Header.Set("Authorization", "Bearer caller-token")
@@ -103,7 +305,7 @@ func TestPearClient_ResolveCallerOrg_ForwardsCallerToken(t *testing.T) {
 	}))
 	defer server.Close()
 
-	s := NewServer("pear.example.com", &stubIndex{})
+	s := NewServer(server.URL, &stubIndex{}, staticOrgs{})
 	orgDID, err := s.resolveCallerOrg(context.Background(), "callers-own-token")
 
 	require.NoError(t, err)
@@ -111,15 +313,13 @@ func TestPearClient_ResolveCallerOrg_ForwardsCallerToken(t *testing.T) {
 	require.Equal(t, "Bearer callers-own-token", gotAuth)
 	require.Equal(t, "oauth", gotMethod)
 }
-*/
-
 func TestPearClient_ResolveCallerOrg_NonOKStatusIsError(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusUnauthorized)
 	}))
 	defer server.Close()
 
-	s := NewServer("pear.example.com", &stubIndex{})
+	s := NewServer(server.URL, &stubIndex{}, staticOrgs{})
 	_, err := s.resolveCallerOrg(context.Background(), "callers-own-token")
 	require.Error(t, err)
 }
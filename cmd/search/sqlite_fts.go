@@ -0,0 +1,267 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	habitat_syntax "github.com/habitat-network/habitat/internal/syntax"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// errFTS5Unavailable is returned by newSQLiteFTSIndex when the SQLite driver
+// was compiled without the FTS5 extension.
+var errFTS5Unavailable = errors.New(
+	"sqlite was built without FTS5; build search with -tags sqlite_fts5",
+)
+
+// sqliteFTSIndex is an Index on SQLite's FTS5 extension, for deployments that
+// run on SQLite and should not need a Postgres server just for search.
+// Documents live in the same search_documents table the Postgres index uses;
+// an external-content FTS5 table over their title, content and metadata is
+// kept in step with it by triggers. Words are stemmed with the Porter
+// (English) stemmer whatever a document's language, since FTS5 has no other,
+// and ranked with bm25, title matches weighted highest.
+type sqliteFTSIndex struct {
+	db *gorm.DB
+}
+
+var _ Index = (*sqliteFTSIndex)(nil)
+
+// sqliteSearchDocument is the search_documents table as the SQLite index lays
+// it out. FTS5 points at documents by an integer key, so id, an INTEGER
+// PRIMARY KEY, aliases the rowid and keeps it stable: the implicit rowid of a
+// table keyed by uri is one VACUUM may renumber. uri is unique instead.
+type sqliteSearchDocument struct {
+	ID         int64     `gorm:"column:id;primaryKey;autoIncrement"`
+	URI        string    `gorm:"column:uri;uniqueIndex;not null"`
+	SpaceURI   string    `gorm:"column:space_uri;index"`
+	OrgDID     string    `gorm:"column:org_did;index"`
+	Repo       string    `gorm:"column:repo;index"`
+	Rev        string    `gorm:"column:rev"`
+	Collection string    `gorm:"column:collection"`
+	Title      string    `gorm:"column:title"`
+	Content    string    `gorm:"column:content"`
+	Metadata   string    `gorm:"column:metadata"`
+	Language   string    `gorm:"column:language"`
+	UpdatedAt  time.Time `gorm:"column:updated_at"`
+}
+
+func (sqliteSearchDocument) TableName() string { return "search_documents" }
+
+func newSQLiteFTSIndex(db *gorm.DB) (*sqliteFTSIndex, error) {
+	if err := db.AutoMigrate(&sqliteSearchDocument{}); err != nil {
+		return nil, fmt.Errorf("migrate search_documents: %w", err)
+	}
+	if err := db.Exec(`
+		CREATE VIRTUAL TABLE IF NOT EXISTS search_documents_fts USING fts5(
+		  title, content, metadata,
+		  content='search_documents',
+		  content_rowid='id',
+		  tokenize='porter unicode61'
+		)
+	`).Error; err != nil {
+		if strings.Contains(err.Error(), "no such module: fts5") {
+			return nil, errFTS5Unavailable
+		}
+		return nil, fmt.Errorf("create search_documents_fts: %w", err)
+	}
+	for _, trigger := range []string{
+		`CREATE TRIGGER IF NOT EXISTS search_documents_fts_insert
+		   AFTER INSERT ON search_documents BEGIN
+		     INSERT INTO search_documents_fts(rowid, title, content, metadata)
+		       VALUES (new.id, new.title, new.content, new.metadata);
+		   END`,
+		`CREATE TRIGGER IF NOT EXISTS search_documents_fts_delete
+		   AFTER DELETE ON search_documents BEGIN
+		     INSERT INTO search_documents_fts(search_documents_fts, rowid, title, content, metadata)
+		       VALUES ('delete', old.id, old.title, old.content, old.metadata);
+		   END`,
+		`CREATE TRIGGER IF NOT EXISTS search_documents_fts_update
+		   AFTER UPDATE ON search_documents BEGIN
+		     INSERT INTO search_documents_fts(search_documents_fts, rowid, title, content, metadata)
+		       VALUES ('delete', old.id, old.title, old.content, old.metadata);
+		     INSERT INTO search_documents_fts(rowid, title, content, metadata)
+		       VALUES (new.id, new.title, new.content, new.metadata);
+		   END`,
+	} {
+		if err := db.Exec(trigger).Error; err != nil {
+			return nil, fmt.Errorf("create search_documents_fts trigger: %w", err)
+		}
+	}
+	return &sqliteFTSIndex{db: db}, nil
+}
+
+func (idx *sqliteFTSIndex) Upsert(ctx context.Context, doc Document) error {
+	row := newSearchDocument(doc)
+	return idx.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "uri"}},
+		UpdateAll: true,
+	}).Create(&row).Error
+}
+
+func (idx *sqliteFTSIndex) Delete(ctx context.Context, uri habitat_syntax.SpaceRecordURI) error {
+	return idx.db.WithContext(ctx).Delete(&searchDocument{}, "uri = ?", uri.String()).Error
+}
+
+func (idx *sqliteFTSIndex) Resync(
+	ctx context.Context,
+	space habitat_syntax.SpaceURI,
+	repo syntax.DID,
+	rev syntax.TID,
+) error {
+	return idx.db.WithContext(ctx).Delete(
+		&searchDocument{},
+		"space_uri = ? AND repo = ? AND rev < ?",
+		space.String(), repo.String(), rev.String(),
+	).Error
+}
+
+func (idx *sqliteFTSIndex) Rebuild(
+	ctx context.Context,
+	scope RebuildScope,
+	fill func(put func(Document) error) error,
+	catchUp func() RebuildCatchUp,
+) error {
+	return rebuildSearchDocuments(ctx, idx.db, scope, fill, catchUp)
+}
+
+func (idx *sqliteFTSIndex) Query(ctx context.Context, params QueryParams) (QueryResult, error) {
+	limit := params.Limit
+	if limit <= 0 {
+		limit = 25
+	}
+	offset := 0
+	if params.Cursor != "" {
+		parsed, err := strconv.Atoi(params.Cursor)
+		if err != nil {
+			return QueryResult{}, fmt.Errorf("invalid cursor %q: %w", params.Cursor, err)
+		}
+		offset = parsed
+	}
+	match := ftsMatchQuery(params.QueryText)
+	if match == "" || (params.Spaces != nil && len(params.Spaces) == 0) {
+		return QueryResult{}, nil
+	}
+
+	const from = "search_documents_fts " +
+		"JOIN search_documents d ON d.id = search_documents_fts.rowid"
+	filters, filterArgs := params.filters("d.")
+	where := "search_documents_fts MATCH ? AND " + filters
+	args := append([]any{match}, filterArgs...)
+
+	// bm25 scores better matches lower; negate it so rank, like ts_rank,
+	// grows with relevance. Column weights follow the Postgres index's A/B/D.
+	var rows []ftsRow
+	err := idx.db.WithContext(ctx).Raw(`
+		SELECT d.uri, d.space_uri, d.collection,
+		       -bm25(search_documents_fts, 10.0, 4.0, 1.0) AS rank,
+		       snippet(search_documents_fts, -1, '<b>', '</b>', '...', 16) AS snippet
+		FROM `+from+`
+		WHERE `+where+`
+		ORDER BY rank DESC, d.uri ASC
+		LIMIT ? OFFSET ?
+	`, append(args, limit, offset)...).Scan(&rows).Error
+	if err != nil {
+		return QueryResult{}, fmt.Errorf("query search_documents_fts: %w", err)
+	}
+	facets, err := queryFacets(idx.db.WithContext(ctx), from, where, "d.", args)
+	if err != nil {
+		return QueryResult{}, err
+	}
+
+	results := make([]Result, len(rows))
+	for i, row := range rows {
+		results[i] = Result{
+			URI:        habitat_syntax.SpaceRecordURI(row.URI),
+			SpaceURI:   habitat_syntax.SpaceURI(row.SpaceURI),
+			Collection: syntax.NSID(row.Collection),
+			Snippet:    row.Snippet,
+			Rank:       row.Rank,
+		}
+	}
+	nextCursor := ""
+	if len(rows) == limit {
+		nextCursor = strconv.Itoa(offset + limit)
+	}
+	return QueryResult{Results: results, NextCursor: nextCursor, Facets: facets}, nil
+}
+
+// ftsMatchQuery translates a web-search style query, as Postgres'
+// websearch_to_tsquery reads it, into an FTS5 MATCH expression: words are
+// ANDed, "quoted text" is a phrase, OR between terms is a disjunction, and a
+// leading - excludes a term. Every term is quoted, so punctuation in user
+// input cannot break FTS5's query syntax. It returns "" for a query with
+// nothing to match.
+func ftsMatchQuery(q string) string {
+	var (
+		include []string
+		exclude []string
+		orNext  bool
+	)
+	for _, term := range splitSearchTerms(q) {
+		negated := strings.HasPrefix(term, "-")
+		term = strings.TrimPrefix(term, "-")
+		phrase := strings.HasPrefix(term, `"`)
+		term = strings.Trim(term, `"`)
+		if !phrase && strings.EqualFold(term, "or") {
+			orNext = len(include) > 0
+			continue
+		}
+		if strings.TrimSpace(term) == "" {
+			continue
+		}
+		quoted := `"` + strings.ReplaceAll(term, `"`, `""`) + `"`
+		switch {
+		case negated:
+			exclude = append(exclude, quoted)
+		case orNext:
+			last := len(include) - 1
+			include[last] = "(" + include[last] + " OR " + quoted + ")"
+		default:
+			include = append(include, quoted)
+		}
+		orNext = false
+	}
+	if len(include) == 0 {
+		return ""
+	}
+	match := strings.Join(include, " ")
+	if len(exclude) > 0 {
+		match = "(" + match + ") NOT " + strings.Join(exclude, " NOT ")
+	}
+	return match
+}
+
+// splitSearchTerms splits q on whitespace, keeping "quoted phrases" (and a
+// leading - before one) together.
+func splitSearchTerms(q string) []string {
+	var (
+		terms   []string
+		current strings.Builder
+		inQuote bool
+	)
+	for _, r := range q {
+		switch {
+		case r == '"':
+			inQuote = !inQuote
+			current.WriteRune(r)
+		case !inQuote && (r == ' ' || r == '\t' || r == '\n'):
+			if current.Len() > 0 {
+				terms = append(terms, current.String())
+				current.Reset()
+			}
+		default:
+			current.WriteRune(r)
+		}
+	}
+	if current.Len() > 0 {
+		terms = append(terms, current.String())
+	}
+	return terms
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"github.com/habitat-network/habitat/internal/db/testutil"
+	habitat_syntax "github.com/habitat-network/habitat/internal/syntax"
+	"github.com/stretchr/testify/require"
+)
+
+// setupSQLiteFTSIndex returns an index on a fresh SQLite database, skipping
+// the test when the driver was built without FTS5 (go test -tags sqlite_fts5).
+func setupSQLiteFTSIndex(t *testing.T) *sqliteFTSIndex {
+	t.Helper()
+	index, err := newSQLiteFTSIndex(testutil.NewDB(t))
+	if errors.Is(err, errFTS5Unavailable) {
+		t.Skip(err.Error())
+	}
+	require.NoError(t, err)
+	return index
+}
+
+func queryURIs(t *testing.T, index Index, params QueryParams) []habitat_syntax.SpaceRecordURI {
+	t.Helper()
+	result, err := index.Query(context.Background(), params)
+	require.NoError(t, err)
+	uris := make([]habitat_syntax.SpaceRecordURI, len(result.Results))
+	for i, r := range result.Results {
+		uris[i] = r.URI
+	}
+	return uris
+}
+
+func TestSQLiteFTSIndex_UpsertAndQueryStems(t *testing.T) {
+	index := setupSQLiteFTSIndex(t)
+	ctx := context.Background()
+
+	budget := doc(
+		"at://did:plc:org1/space/app.space/skey1/did:plc:user1/network.habitat.note/rkey1",
+		"did:plc:org1",
+		"the quarterly budget review notes",
+	)
+	require.NoError(t, index.Upsert(ctx, budget))
+	require.NoError(t, index.Upsert(ctx, doc(
+		"at://did:plc:org1/space/app.space/skey1/did:plc:user1/network.habitat.note/rkey2",
+		"did:plc:org1",
+		"unrelated grocery list",
+	)))
+
+	result, err := index.Query(
+		ctx,
+		QueryParams{OrgDID: "did:plc:org1", QueryText: "budgets reviewed", Limit: 10},
+	)
+	require.NoError(t, err)
+	require.Len(t, result.Results, 1)
+	require.Equal(t, budget.URI, result.Results[0].URI)
+	require.Contains(t, result.Results[0].Snippet, "<b>budget</b>")
+	require.Positive(t, result.Results[0].Rank)
+}
+
+func TestSQLiteFTSIndex_UpsertReplacesContent(t *testing.T) {
+	index := setupSQLiteFTSIndex(t)
+	ctx := context.Background()
+
+	d := doc(
+		"at://did:plc:org1/space/app.space/skey1/did:plc:user1/network.habitat.note/rkey1",
+		"did:plc:org1",
+		"budget notes",
+	)
+	require.NoError(t, index.Upsert(ctx, d))
+	d.Content = "holiday plans"
+	require.NoError(t, index.Upsert(ctx, d))
+
+	require.Empty(t, queryURIs(t, index, QueryParams{OrgDID: "did:plc:org1", QueryText: "budget"}))
+	require.Equal(
+		t,
+		[]habitat_syntax.SpaceRecordURI{d.URI},
+		queryURIs(t, index, QueryParams{OrgDID: "did:plc:org1", QueryText: "holiday"}),
+	)
+}
+
+func TestSQLiteFTSIndex_QueryFiltersByOrgAndSpaces(t *testing.T) {
+	index := setupSQLiteFTSIndex(t)
+	ctx := context.Background()
+
+	readable := doc(
+		"at://did:plc:org1/space/app.space/skey1/did:plc:user1/network.habitat.note/rkey1",
+		"did:plc:org1",
+		"budget notes anyone can read",
+	)
+	hidden := doc(
+		"at://did:plc:org1/space/app.space/skey2/did:plc:user1/network.habitat.note/rkey1",
+		"did:plc:org1",
+		"budget notes for the board",
+	)
+	hidden.SpaceURI = "at://did:plc:org1/space/app.space/skey2"
+	require.NoError(t, index.Upsert(ctx, readable))
+	require.NoError(t, index.Upsert(ctx, hidden))
+	require.NoError(t, index.Upsert(ctx, doc(
+		"at://did:plc:org2/space/app.space/skey1/did:plc:user1/network.habitat.note/rkey1",
+		"did:plc:org2",
+		"budget notes for org2",
+	)))
+
+	require.Len(t, queryURIs(t, index, QueryParams{OrgDID: "did:plc:org1", QueryText: "budget"}), 2)
+	require.Equal(t, []habitat_syntax.SpaceRecordURI{readable.URI}, queryURIs(t, index, QueryParams{
+		OrgDID:    "did:plc:org1",
+		Spaces:    []habitat_syntax.SpaceURI{readable.SpaceURI},
+		QueryText: "budget",
+	}))
+	require.Empty(t, queryURIs(t, index, QueryParams{
+		OrgDID:    "did:plc:org1",
+		Spaces:    []habitat_syntax.SpaceURI{},
+		QueryText: "budget",
+	}))
+}
+
+func TestSQLiteFTSIndex_TitleMatchesRankFirst(t *testing.T) {
+	index := setupSQLiteFTSIndex(t)
+	ctx := context.Background()
+
+	inBody := doc(
+		"at://did:plc:org1/space/app.space/skey1/did:plc:user1/network.habitat.event/rkey1",
+		"did:plc:org1",
+		"quarterly planning, the meeting room is booked",
+	)
+	inBody.Title = "Planning day"
+	titled := doc(
+		"at://did:plc:org1/space/app.space/skey1/did:plc:user1/network.habitat.event/rkey2",
+		"did:plc:org1",
+		"agenda to follow",
+	)
+	titled.Title = "Board meeting"
+	require.NoError(t, index.Upsert(ctx, inBody))
+	require.NoError(t, index.Upsert(ctx, titled))
+
+	require.Equal(
+		t,
+		[]habitat_syntax.SpaceRecordURI{titled.URI, inBody.URI},
+		queryURIs(t, index, QueryParams{OrgDID: "did:plc:org1", QueryText: "meeting"}),
+	)
+}
+
+func TestSQLiteFTSIndex_DeleteAndResync(t *testing.T) {
+	index := setupSQLiteFTSIndex(t)
+	ctx := context.Background()
+	clock := syntax.NewTIDClock(0)
+	oldRev, snapshotRev := clock.Next(), clock.Next()
+
+	deleted := doc(
+		"at://did:plc:org1/space/app.space/skey1/did:plc:user1/network.habitat.note/rkey1",
+		"did:plc:org1",
+		"budget notes deleted",
+	)
+	stale := doc(
+		"at://did:plc:org1/space/app.space/skey1/did:plc:user1/network.habitat.note/rkey2",
+		"did:plc:org1",
+		"budget notes deleted while sap was desynced",
+	)
+	stale.Repo, stale.Rev = "did:plc:user1", oldRev
+	fresh := doc(
+		"at://did:plc:org1/space/app.space/skey1/did:plc:user1/network.habitat.note/rkey3",
+		"did:plc:org1",
+		"budget notes from the snapshot",
+	)
+	fresh.Repo, fresh.Rev = "did:plc:user1", snapshotRev
+	for _, d := range []Document{deleted, stale, fresh} {
+		require.NoError(t, index.Upsert(ctx, d))
+	}
+
+	require.NoError(t, index.Delete(ctx, deleted.URI))
+	require.NoError(t, index.Resync(ctx, fresh.SpaceURI, fresh.Repo, snapshotRev))
+
+	require.Equal(
+		t,
+		[]habitat_syntax.SpaceRecordURI{fresh.URI},
+		queryURIs(t, index, QueryParams{OrgDID: "did:plc:org1", QueryText: "budget"}),
+	)
+}
+
+func TestSQLiteFTSIndex_KeysDocumentsByIntegerPrimaryKey(t *testing.T) {
+	index := setupSQLiteFTSIndex(t)
+
+	// Only an INTEGER PRIMARY KEY aliases the rowid FTS5 points at; VACUUM
+	// may renumber any other.
+	var columns []struct {
+		Name string
+		Type string
+		PK   int
+	}
+	require.NoError(t, index.db.Raw("PRAGMA table_info(search_documents)").Scan(&columns).Error)
+	var pk []string
+	for _, c := range columns {
+		if c.PK > 0 {
+			pk = append(pk, c.Name+" "+strings.ToUpper(c.Type))
+		}
+	}
+	require.Equal(t, []string{"id INTEGER"}, pk)
+
+	var ddl string
+	require.NoError(t, index.db.Raw(
+		"SELECT sql FROM sqlite_master WHERE name = 'search_documents_fts'",
+	).Scan(&ddl).Error)
+	require.Contains(t, ddl, "content_rowid='id'")
+}
+
+func TestSQLiteFTSIndex_QueryRespectsLimitAndCursor(t *testing.T) {
+	index := setupSQLiteFTSIndex(t)
+	ctx := context.Background()
+
+	for i := range 3 {
+		require.NoError(t, index.Upsert(ctx, doc(
+			habitat_syntax.ConstructSpaceRecordURI(
+				habitat_syntax.ConstructSpaceURI("did:plc:org1", "app.space", "skey1"),
+				"did:plc:user1",
+				"network.habitat.note",
+				syntax.RecordKey(fmt.Sprintf("rkey-%d", i)),
+			),
+			"did:plc:org1",
+			"budget notes page",
+		)))
+	}
+
+	first, err := index.Query(
+		ctx,
+		QueryParams{OrgDID: "did:plc:org1", QueryText: "budget", Limit: 2},
+	)
+	require.NoError(t, err)
+	require.Len(t, first.Results, 2)
+	require.NotEmpty(t, first.NextCursor)
+
+	second, err := index.Query(ctx, QueryParams{
+		OrgDID: "did:plc:org1", QueryText: "budget", Limit: 2, Cursor: first.NextCursor,
+	})
+	require.NoError(t, err)
+	require.Len(t, second.Results, 1)
+	require.NotContains(t, []habitat_syntax.SpaceRecordURI{
+		first.Results[0].URI, first.Results[1].URI,
+	}, second.Results[0].URI)
+}
+
+func TestSQLiteFTSIndex_QueryFiltersAndFacets(t *testing.T) {
+	testQueryFiltersAndFacets(t, setupSQLiteFTSIndex(t))
+}
+
+func TestSQLiteFTSIndex_RebuildSwapsScope(t *testing.T) {
+	testRebuildSwapsScope(t, setupSQLiteFTSIndex(t))
+}
+
+func TestSQLiteFTSIndex_RebuildCatchesUp(t *testing.T) {
+	testRebuildCatchesUp(t, setupSQLiteFTSIndex(t))
+}
+
+func TestFTSMatchQuery(t *testing.T) {
+	for q, want := range map[string]string{
+		"budget review":           `"budget" "review"`,
+		`"board meeting" notes`:   `"board meeting" "notes"`,
+		"budget or finance":       `("budget" OR "finance")`,
+		"budget -draft":           `("budget") NOT "draft"`,
+		`o'brien's (draft) AND *`: `"o'brien's" "(draft)" "AND" "*"`,
+		"-draft":                  "",
+		"  ":                      "",
+	} {
+		require.Equal(t, want, ftsMatchQuery(q), "query %q", q)
+	}
+}
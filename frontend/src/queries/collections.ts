@@ -1,6 +1,6 @@
 import type { AuthManager } from "internal";
 import { query } from "internal";
-import { queryOptions } from "@tanstack/react-query";
+import { infiniteQueryOptions, queryOptions } from "@tanstack/react-query";
 import type {
   CollectionView,
   RecordView,
@@ -26,28 +26,29 @@ export function collectionsListQueryOptions(authManager: AuthManager) {
   });
 }
 
-// collectionRecordsQueryOptions lists the records in a collection the calling
-// user can see, each with the spaces (they can read) it belongs to. Record
-// bodies are fetched separately, on demand, from pear.
+// collectionRecordsQueryOptions pages through the records in a collection the
+// calling user can see, most recently updated first, each with its body as the
+// home server last synced it.
 export function collectionRecordsQueryOptions(
   collection: string,
   authManager: AuthManager,
 ) {
-  return queryOptions({
+  return infiniteQueryOptions({
     queryKey: ["collection", collection],
-    queryFn: async (): Promise<RecordView[]> => {
-      const { records } = await query(
+    queryFn: async ({ pageParam }) =>
+      query(
         "network.habitat.collections.listRecords",
-        { collection },
+        { collection, cursor: pageParam },
         { authManager, headers: homeProxyHeaders() },
-      );
-      return records;
-    },
+      ),
+    initialPageParam: undefined as string | undefined,
+    getNextPageParam: (page) => page.cursor,
   });
 }
 
 // recordBodyQueryOptions fetches a single record's body directly from pear,
-// from the space it belongs to. The collections index never stores bodies.
+// from the space it belongs to, for a record the home server indexed before it
+// kept bodies.
 export function recordBodyQueryOptions(
   record: RecordView,
   authManager: AuthManager,
@@ -67,5 +68,6 @@ export function recordBodyQueryOptions(
       );
       return value;
     },
+    enabled: record.value === undefined,
   });
 }
@@ -1,6 +1,6 @@
 import { createFileRoute } from "@tanstack/react-router";
-import { useQuery } from "@tanstack/react-query";
-import type { AuthManager } from "internal";
+import { useQuery, useSuspenseInfiniteQuery } from "@tanstack/react-query";
+import { Button, type AuthManager } from "internal";
 import {
   Badge,
   Table,
@@ -20,7 +20,7 @@ import {
 
 export const Route = createFileRoute("/_requireAuth/collections/$collection")({
   loader: ({ context, params }) =>
-    context.queryClient.ensureQueryData(
+    context.queryClient.ensureInfiniteQueryData(
       collectionRecordsQueryOptions(params.collection, context.authManager),
     ),
   component: CollectionRecords,
@@ -34,16 +34,21 @@ function spaceLabel(uri: string): string {
 
 function CollectionRecords() {
   const { collection } = Route.useParams();
-  const records = Route.useLoaderData();
   const { authManager } = Route.useRouteContext();
+  const { data, hasNextPage, fetchNextPage, isFetchingNextPage } =
+    useSuspenseInfiniteQuery(
+      collectionRecordsQueryOptions(collection, authManager),
+    );
+  const records = data.pages.flatMap((page) => page.records);
 
   return (
     <div className="flex flex-col gap-4 py-6">
       <div>
         <h1 className="text-2xl font-semibold break-all">{collection}</h1>
         <p className="text-muted-foreground text-sm">
+          {hasNextPage ? "The latest " : ""}
           {records.length} {records.length === 1 ? "record" : "records"} you can
-          see.
+          see, most recently updated first.
         </p>
       </div>
       {records.length === 0 ? (
@@ -74,12 +79,22 @@ function CollectionRecords() {
           </TableBody>
         </Table>
       )}
+      {hasNextPage && (
+        <Button
+          variant="outline"
+          className="self-center"
+          disabled={isFetchingNextPage}
+          onClick={() => fetchNextPage()}
+        >
+          {isFetchingNextPage ? "Loading…" : "Load more"}
+        </Button>
+      )}
     </div>
   );
 }
 
-// RecordBody fetches the record's body on demand from pear and renders it. The
-// collections index only stores record identity, not bodies.
+// RecordBody renders the record's body as listed, falling back to fetching it
+// on demand from pear for a record indexed before the home server kept bodies.
 function RecordBody({
   record,
   collection,
@@ -89,9 +104,9 @@ function RecordBody({
   collection: string;
   authManager: AuthManager;
 }) {
-  const { data, isLoading, error } = useQuery(
-    recordBodyQueryOptions(record, authManager),
-  );
+  const fetched = useQuery(recordBodyQueryOptions(record, authManager));
+  const data = record.value ?? fetched.data;
+  const { isLoading, error } = fetched;
 
   return (
     <div className="flex flex-col gap-2">
@@ -10,15 +10,17 @@ import (
 	"testing"
 
 	"github.com/bluesky-social/indigo/atproto/identity"
-	"github.com/bluesky-social/indigo/atproto/syntax"
 	"github.com/habitat-network/habitat/api/habitat"
 	"github.com/habitat-network/habitat/internal/authn"
 	authntest "github.com/habitat-network/habitat/internal/authn/testutil"
+	"github.com/habitat-network/habitat/internal/encrypt"
 	"github.com/habitat-network/habitat/internal/fgastore"
 	"github.com/habitat-network/habitat/internal/hive"
 	habitat_identity "github.com/habitat-network/habitat/internal/identity"
+	"github.com/habitat-network/habitat/internal/login"
 	"github.com/habitat-network/habitat/internal/org"
 	org_server "github.com/habitat-network/habitat/internal/org/server"
+	"github.com/habitat-network/habitat/internal/pdsclient"
 	"github.com/stretchr/testify/require"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
@@ -34,24 +36,30 @@ func TestMintThenLookup(t *testing.T) {
 	h, err := hive.NewHive("example.com", "pear.example.com", db)
 	require.NoError(t, err)
 
-	adminDID := syntax.DID("did:plc:admin1234")
-
 	// Create the org store and seed an org
 	dir := identity.DefaultDirectory()
 	fga, err := fgastore.NewMemory(t.Context())
 	require.NoError(t, err)
+	passwordProvider, err := login.NewPasswordProvider(
+		db,
+		"",
+		encrypt.TestKey,
+		pdsclient.NewDummyDirectory("https://pds.example.com"),
+	)
+	require.NoError(t, err)
 	orgStore, err := org.NewStore(
 		db,
 		h,
 		dir,
 		"pear.example.com",
-		nil,
+		passwordProvider,
 		fga,
 		org.NewEveryoneOrg("pear.example.com"),
 	)
 	require.NoError(t, err)
 
-	orgIdIdent, _, err := orgStore.CreateOrg(
+	// CreateOrg mints the org's first admin.
+	orgIdIdent, adminIdent, err := orgStore.CreateOrg(
 		ctx,
 		"test-org",
 		"admin",
@@ -63,9 +71,7 @@ func TestMintThenLookup(t *testing.T) {
 	)
 	require.NoError(t, err)
 	testOrgID := orgIdIdent.DID
-	testOrg, err := orgStore.GetOrg(ctx, testOrgID)
-	require.NoError(t, err)
-	require.NoError(t, testOrg.AddAdmin(ctx, adminDID))
+	adminDID := adminIdent.DID
 
 	orgServer, err := org_server.NewServer(
 		orgStore,
@@ -75,6 +81,8 @@ func TestMintThenLookup(t *testing.T) {
 		"pear.example.com",
 		identity.DefaultDirectory(),
 		nil,
+		nil,
+		nil,
 	)
 	require.NoError(t, err)
 
@@ -127,7 +135,7 @@ func TestMintThenLookup(t *testing.T) {
 
 	// Verify via hive server: resolve handle -> DID
 	handleReq := httptest.NewRequest(http.MethodGet, "/.well-known/atproto-did", http.NoBody)
-	handleReq.Host = "alice.example.com"
+	handleReq.Host = mintOut.Handle
 	handleW := httptest.NewRecorder()
 	hiveServer.ServeHandle(handleW, handleReq)
 	require.Equal(t, http.StatusOK, handleW.Code)
@@ -141,7 +149,7 @@ func TestMintThenLookup(t *testing.T) {
 	docW := httptest.NewRecorder()
 	hiveServer.ServeDIDDoc(docW, docReq)
 	require.Equal(t, http.StatusOK, docW.Code)
-	require.Equal(t, "application/did+ld+json", docW.Header().Get("Content-Type"))
+	require.Equal(t, "application/json", docW.Header().Get("Content-Type"))
 
 	var doc map[string]any
 	require.NoError(t, json.Unmarshal(docW.Body.Bytes(), &doc))
@@ -149,5 +157,5 @@ func TestMintThenLookup(t *testing.T) {
 	akaSlice, ok := doc["alsoKnownAs"].([]interface{})
 	require.True(t, ok)
 	require.Len(t, akaSlice, 1)
-	require.Equal(t, "at://alice.example.com", akaSlice[0])
+	require.Equal(t, "at://"+mintOut.Handle, akaSlice[0])
 }
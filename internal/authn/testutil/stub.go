@@ -48,6 +48,17 @@ func (s *stubOrg) IsAdmin(_ context.Context, _ syntax.DID) (bool, error)  { retu
 func (s *stubOrg) IsMember(_ context.Context, _ syntax.DID) (bool, error) { return false, nil }
 func (s *stubOrg) WithTx(_ *gorm.DB) org.Org                              { return s }
 
+func (s *stubOrg) PutRole(_ context.Context, _ org.Role, _ []org.Capability) error { return nil }
+func (s *stubOrg) DeleteRole(_ context.Context, _ org.Role) error                  { return nil }
+func (s *stubOrg) AssignRole(_ context.Context, _ syntax.DID, _ org.Role) error    { return nil }
+func (s *stubOrg) UnassignRole(_ context.Context, _ syntax.DID, _ org.Role) error  { return nil }
+func (s *stubOrg) ListRoles(_ context.Context) ([]org.RoleDefinition, error) {
+	return nil, nil
+}
+func (s *stubOrg) HasCapability(_ context.Context, _ syntax.DID, _ org.Capability) (bool, error) {
+	return false, nil
+}
+
 // success implements authn.Method for tests, always returning the given DID.
 type success struct {
 	did syntax.DID
@@ -44,6 +44,35 @@ func OrgMemberUsersetString(did syntax.DID) string {
 	return OrgObjectKey(did) + "#" + RelationMember
 }
 
+// OrgRoleObjectKey returns the FGA object key for a custom role of an org.
+func OrgRoleObjectKey(org syntax.DID, role string) string {
+	return "org_role:" + url.QueryEscape(org.String()+"/"+role)
+}
+
+// OrgRoleUsersetString returns the FGA userset string for everyone holding a
+// custom org role, e.g. "org_role:<did>/moderator#assignee". Like
+// [OrgMemberUsersetString], it can be granted a relation on a space.
+func OrgRoleUsersetString(org syntax.DID, role string) string {
+	return OrgRoleObjectKey(org, role) + "#" + RelationOrgRoleAssignee
+}
+
+// SpaceTypeObjectKey returns the FGA object key standing for every space of
+// spaceType owned by owner.
+func SpaceTypeObjectKey(owner syntax.DID, spaceType syntax.NSID) string {
+	return "space_type:" + url.QueryEscape(owner.String()+"/"+spaceType.String())
+}
+
+// SpaceTypeContextualTuple links space to its owner's [SpaceTypeObjectKey], so
+// managers of that space type manage the space. Like [OwnerContextualTuple], it
+// is derived from the URI and never stored.
+func SpaceTypeContextualTuple(space habitat_syntax.SpaceURI) Tuple {
+	return Tuple{
+		User:     SpaceTypeObjectKey(space.SpaceOwner(), space.SpaceType()),
+		Relation: RelationSpaceType,
+		Object:   SpaceObjectKey(space),
+	}
+}
+
 func OwnerContextualTuple(space habitat_syntax.SpaceURI) Tuple {
 	return Tuple{
 		User:     MemberUserString(space.SpaceOwner()),
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"io/fs"
+	"slices"
 	"strings"
 
 	"log/slog"
@@ -19,6 +20,8 @@ import (
 	"github.com/openfga/openfga/pkg/storage/sqlcommon"
 	"github.com/openfga/openfga/pkg/storage/sqlite"
 	"github.com/openfga/openfga/pkg/tuple"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
 )
 
 var _ Store = (*FGA)(nil)
@@ -172,20 +175,43 @@ func findOrCreateStore(ctx context.Context, svr *server.Server) (string, error)
 		}
 		storeID = createResp.GetId()
 	}
-	// Write the current auth model even for an existing store so model changes
-	// (e.g. new relations or type restrictions) take effect on restart rather
-	// than only when the store is first created. OpenFGA versions models and
-	// uses the latest by default, and existing tuples stay valid because the
-	// model only ever grows, so this is safe in practice.
+	if err := upgradeAuthModel(ctx, svr, storeID); err != nil {
+		return "", err
+	}
+	return storeID, nil
+}
+
+// upgradeAuthModel writes the current auth model unless it is already the
+// store's latest, so an existing store picks up model changes (e.g. new types
+// or relations) on restart rather than only when it is first created. OpenFGA
+// versions models and checks against the latest by default, and existing
+// tuples stay valid because the model only ever grows.
+func upgradeAuthModel(ctx context.Context, svr *server.Server, storeID string) error {
 	model := authModel()
-	if _, err := svr.WriteAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{
+	resp, err := svr.ReadAuthorizationModels(ctx, &openfgav1.ReadAuthorizationModelsRequest{
+		StoreId:  storeID,
+		PageSize: wrapperspb.Int32(1),
+	})
+	if err != nil {
+		return fmt.Errorf("read auth models: %w", err)
+	}
+	if latest := resp.GetAuthorizationModels(); len(latest) > 0 &&
+		latest[0].GetSchemaVersion() == model.GetSchemaVersion() &&
+		slices.EqualFunc(latest[0].GetTypeDefinitions(), model.GetTypeDefinitions(),
+			func(a, b *openfgav1.TypeDefinition) bool { return proto.Equal(a, b) }) {
+		return nil
+	}
+	written, err := svr.WriteAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{
 		StoreId:         storeID,
 		SchemaVersion:   model.GetSchemaVersion(),
 		TypeDefinitions: model.GetTypeDefinitions(),
-	}); err != nil {
-		return "", fmt.Errorf("write auth model: %w", err)
+	})
+	if err != nil {
+		return fmt.Errorf("write auth model: %w", err)
 	}
-	return storeID, nil
+	slog.InfoContext(ctx, "fgastore wrote auth model",
+		"model_id", written.GetAuthorizationModelId())
+	return nil
 }
 
 func (f *FGA) Check(
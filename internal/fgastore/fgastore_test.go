@@ -15,12 +15,28 @@ import (
 
 func newTestSQLite(t *testing.T) *FGA {
 	t.Helper()
-	f, err := NewSQLite(t.Context(), filepath.Join(t.TempDir(), "fga.db"))
+	return newTestSQLiteAt(t, filepath.Join(t.TempDir(), "fga.db"))
+}
+
+func newTestSQLiteAt(t *testing.T, path string) *FGA {
+	t.Helper()
+	f, err := NewSQLite(t.Context(), path)
 	require.NoError(t, err, "NewSQLite should succeed")
 	t.Cleanup(func() { _ = f.Close() })
 	return f
 }
 
+func latestModel(t *testing.T, f *FGA) *openfgav1.AuthorizationModel {
+	t.Helper()
+	resp, err := f.svr.ReadAuthorizationModels(
+		t.Context(),
+		&openfgav1.ReadAuthorizationModelsRequest{StoreId: f.storeID},
+	)
+	require.NoError(t, err)
+	require.NotEmpty(t, resp.GetAuthorizationModels())
+	return resp.GetAuthorizationModels()[0]
+}
+
 func TestCheck_ReturnsTrueForExistingTuple(t *testing.T) {
 	ctx := context.Background()
 	f := newTestSQLite(t)
@@ -543,6 +559,41 @@ func TestNewMemory_CreatesUsableStore(t *testing.T) {
 	require.True(t, ok)
 }
 
+func TestNewSQLite_UpgradesModelOfExistingStore(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "fga.db")
+	f, err := NewSQLite(ctx, path)
+	require.NoError(t, err)
+
+	// Stand in for a store initialized before org roles and space types by
+	// making a model without them its latest.
+	old := authModel()
+	old.TypeDefinitions = old.TypeDefinitions[:2]
+	written, err := f.svr.WriteAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{
+		StoreId:         f.storeID,
+		SchemaVersion:   old.GetSchemaVersion(),
+		TypeDefinitions: old.GetTypeDefinitions(),
+	})
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	f, err = NewSQLite(ctx, path)
+	require.NoError(t, err)
+	upgraded := latestModel(t, f)
+	require.NotEqual(t, written.GetAuthorizationModelId(), upgraded.GetId())
+	require.NoError(t, f.Write(
+		ctx,
+		OrgRoleUsersetString("did:plc:org", "organizer"),
+		RelationSpaceTypeManager,
+		SpaceTypeObjectKey("did:plc:org", "network.habitat.group"),
+	), "the upgraded model has space types")
+	require.NoError(t, f.Close())
+
+	f = newTestSQLiteAt(t, path)
+	require.Equal(t, upgraded.GetId(), latestModel(t, f).GetId(),
+		"an unchanged model is not written again")
+}
+
 func TestEncodingHelpers_RoundTripEscapedIdentifiers(t *testing.T) {
 	did := syntax.DID("did:plc:abc123")
 	user := MemberUserString(did)
@@ -694,3 +745,67 @@ func TestCheck_NestedGroupSpaces(t *testing.T) {
 	require.NoError(t, err)
 	require.False(t, ok, "member of nested group A should no longer be a member of group B")
 }
+
+func TestOrgRoleUsersetString(t *testing.T) {
+	us := OrgRoleUsersetString("did:plc:myorg", "moderator")
+	require.Equal(t, "org_role:did%3Aplc%3Amyorg%2Fmoderator#assignee", us)
+}
+
+func TestCheck_OrgRoleAsSpaceGrantee(t *testing.T) {
+	ctx := context.Background()
+	f := newTestSQLite(t)
+
+	org := syntax.DID("did:plc:org")
+	role := OrgRoleObjectKey(org, "moderator")
+	require.NoError(t, f.Write(ctx, OrgObjectKey(org), RelationOrgRoleOrg, role))
+	require.NoError(t, f.Write(ctx, "user:alice", RelationOrgRoleAssignee, role))
+	require.NoError(t, f.Write(ctx, "user:admin", RelationAdmin, OrgObjectKey(org)))
+	require.NoError(t, f.Write(
+		ctx,
+		OrgRoleUsersetString(org, "moderator"),
+		RelationSpaceWriter,
+		"space:A",
+	))
+
+	for _, user := range []string{"user:alice", "user:admin"} {
+		ok, err := f.Check(ctx, user, RelationSpaceWriter, "space:A")
+		require.NoError(t, err)
+		require.True(t, ok, "%s holds the role, so writes the space", user)
+	}
+	ok, err := f.Check(ctx, "user:bob", RelationSpaceWriter, "space:A")
+	require.NoError(t, err)
+	require.False(t, ok)
+}
+
+func TestCheck_SpaceTypeManagerManagesSpacesOfThatType(t *testing.T) {
+	ctx := context.Background()
+	f := newTestSQLite(t)
+
+	org := syntax.DID("did:plc:org")
+	require.NoError(t, f.Write(
+		ctx,
+		OrgRoleUsersetString(org, "organizer"),
+		RelationSpaceTypeManager,
+		SpaceTypeObjectKey(org, "network.habitat.group"),
+	))
+	require.NoError(t, f.Write(
+		ctx,
+		"user:alice",
+		RelationOrgRoleAssignee,
+		OrgRoleObjectKey(org, "organizer"),
+	))
+
+	group := habitat_syntax.SpaceURI("at://did:plc:org/space/network.habitat.group/board")
+	other := habitat_syntax.SpaceURI("at://did:plc:org/space/network.habitat.docs/notes")
+	for space, want := range map[habitat_syntax.SpaceURI]bool{group: true, other: false} {
+		ok, err := f.Check(
+			ctx,
+			"user:alice",
+			RelationSpaceMemberManager,
+			SpaceObjectKey(space),
+			SpaceTypeContextualTuple(space),
+		)
+		require.NoError(t, err)
+		require.Equal(t, want, ok, space)
+	}
+}
@@ -6,10 +6,16 @@ import (
 
 const (
 	TypeOrganization           = "organization"
+	TypeOrgRole                = "org_role"
 	TypeUser                   = "user"
 	TypeSpace                  = "space"
+	TypeSpaceType              = "space_type"
 	RelationAdmin              = "admin"
 	RelationMember             = "member"
+	RelationOrgRoleOrg         = "org"
+	RelationOrgRoleAssignee    = "assignee"
+	RelationSpaceTypeManager   = "manager"
+	RelationSpaceType          = "type"
 	RelationSpaceOwner         = "owner"
 	RelationSpaceReader        = "can_read"
 	RelationSpaceWriter        = "can_write"
@@ -22,7 +28,8 @@ const (
 // including a group-space — can be used as a grantee on another space. This is
 // what powers groups-as-spaces, nested groups, and cross-space role
 // inheritance: the relationship store grants "all holders of role R on space A"
-// a role on space B by writing a space userset tuple.
+// a role on space B by writing a space userset tuple. Custom org roles are
+// granted the same way, through an "org_role:X#assignee" userset.
 func spaceDirectlyRelatedUserTypes() []*openfgav1.RelationReference {
 	refs := []*openfgav1.RelationReference{
 		{Type: TypeUser},
@@ -30,6 +37,7 @@ func spaceDirectlyRelatedUserTypes() []*openfgav1.RelationReference {
 			Type:               TypeOrganization,
 			RelationOrWildcard: &openfgav1.RelationReference_Relation{Relation: RelationMember},
 		},
+		orgRoleAssigneeReference(),
 	}
 	for _, rel := range []string{
 		RelationSpaceOwner,
@@ -45,6 +53,15 @@ func spaceDirectlyRelatedUserTypes() []*openfgav1.RelationReference {
 	return refs
 }
 
+func orgRoleAssigneeReference() *openfgav1.RelationReference {
+	return &openfgav1.RelationReference{
+		Type: TypeOrgRole,
+		RelationOrWildcard: &openfgav1.RelationReference_Relation{
+			Relation: RelationOrgRoleAssignee,
+		},
+	}
+}
+
 func authModel() *openfgav1.AuthorizationModel {
 	return &openfgav1.AuthorizationModel{
 		SchemaVersion: "1.1",
@@ -84,9 +101,71 @@ func authModel() *openfgav1.AuthorizationModel {
 					},
 				},
 			},
+			// A custom org role. Its assignees are the users it was assigned to
+			// plus the admins of the org it belongs to, so admins hold every role.
+			{
+				Type: TypeOrgRole,
+				Relations: map[string]*openfgav1.Userset{
+					RelationOrgRoleOrg: {Userset: &openfgav1.Userset_This{}},
+					RelationOrgRoleAssignee: {
+						Userset: &openfgav1.Userset_Union{
+							Union: &openfgav1.Usersets{Child: []*openfgav1.Userset{
+								{Userset: &openfgav1.Userset_This{}},
+								{
+									Userset: &openfgav1.Userset_TupleToUserset{
+										TupleToUserset: &openfgav1.TupleToUserset{
+											Tupleset: &openfgav1.ObjectRelation{
+												Relation: RelationOrgRoleOrg,
+											},
+											ComputedUserset: &openfgav1.ObjectRelation{
+												Relation: RelationAdmin,
+											},
+										},
+									},
+								},
+							}},
+						},
+					},
+				},
+				Metadata: &openfgav1.Metadata{
+					Relations: map[string]*openfgav1.RelationMetadata{
+						RelationOrgRoleOrg: {
+							DirectlyRelatedUserTypes: []*openfgav1.RelationReference{
+								{Type: TypeOrganization},
+							},
+						},
+						RelationOrgRoleAssignee: {
+							DirectlyRelatedUserTypes: []*openfgav1.RelationReference{
+								{Type: TypeUser},
+							},
+						},
+					},
+				},
+			},
+			// All spaces of one type owned by one DID. Its managers manage every
+			// such space; org roles with a manageSpaces capability are written
+			// here. Spaces are linked to it by a contextual tuple (see
+			// [SpaceTypeContextualTuple]) rather than a stored one.
+			{
+				Type: TypeSpaceType,
+				Relations: map[string]*openfgav1.Userset{
+					RelationSpaceTypeManager: {Userset: &openfgav1.Userset_This{}},
+				},
+				Metadata: &openfgav1.Metadata{
+					Relations: map[string]*openfgav1.RelationMetadata{
+						RelationSpaceTypeManager: {
+							DirectlyRelatedUserTypes: []*openfgav1.RelationReference{
+								{Type: TypeUser},
+								orgRoleAssigneeReference(),
+							},
+						},
+					},
+				},
+			},
 			{
 				Type: TypeSpace,
 				Relations: map[string]*openfgav1.Userset{
+					RelationSpaceType:  {Userset: &openfgav1.Userset_This{}},
 					RelationSpaceOwner: {Userset: &openfgav1.Userset_This{}},
 					RelationSpaceReader: {
 						Userset: &openfgav1.Userset_Union{
@@ -134,12 +213,29 @@ func authModel() *openfgav1.AuthorizationModel {
 										},
 									},
 								},
+								{
+									Userset: &openfgav1.Userset_TupleToUserset{
+										TupleToUserset: &openfgav1.TupleToUserset{
+											Tupleset: &openfgav1.ObjectRelation{
+												Relation: RelationSpaceType,
+											},
+											ComputedUserset: &openfgav1.ObjectRelation{
+												Relation: RelationSpaceTypeManager,
+											},
+										},
+									},
+								},
 							}},
 						},
 					},
 				},
 				Metadata: &openfgav1.Metadata{
 					Relations: map[string]*openfgav1.RelationMetadata{
+						RelationSpaceType: {
+							DirectlyRelatedUserTypes: []*openfgav1.RelationReference{
+								{Type: TypeSpaceType},
+							},
+						},
 						RelationSpaceOwner: {
 							DirectlyRelatedUserTypes: spaceDirectlyRelatedUserTypes(),
 						},
@@ -36,3 +36,20 @@ type spentToken struct {
 	JTI        string     `gorm:"primaryKey"`
 	ConsumedAt time.Time  `gorm:"not null"`
 }
+
+// orgRole is a custom role defined by an org, on top of the built-in admin and
+// member roles.
+type orgRole struct {
+	OrgID        syntax.DID   `gorm:"primaryKey"`
+	Name         Role         `gorm:"primaryKey"`
+	Capabilities []Capability `gorm:"serializer:json"`
+	CreatedAt    time.Time
+}
+
+// roleAssignment records that a member holds a custom role.
+type roleAssignment struct {
+	OrgID     syntax.DID `gorm:"primaryKey"`
+	Did       syntax.DID `gorm:"primaryKey"`
+	Role      Role       `gorm:"primaryKey"`
+	CreatedAt time.Time
+}
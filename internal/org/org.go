@@ -34,6 +34,13 @@ type Org interface {
 	IsAdmin(ctx context.Context, did syntax.DID) (bool, error)
 	IsMember(ctx context.Context, did syntax.DID) (bool, error)
 
+	PutRole(ctx context.Context, role Role, capabilities []Capability) error
+	DeleteRole(ctx context.Context, role Role) error
+	ListRoles(ctx context.Context) ([]RoleDefinition, error)
+	AssignRole(ctx context.Context, did syntax.DID, role Role) error
+	UnassignRole(ctx context.Context, did syntax.DID, role Role) error
+	HasCapability(ctx context.Context, did syntax.DID, capability Capability) (bool, error)
+
 	GetMetadata(ctx context.Context, domain string) habitat.NetworkHabitatOrgGetMetadataOutput
 
 	LoginMethod(ctx context.Context) LoginMethod
@@ -233,6 +240,9 @@ func (s *orgImpl) RemoveAdmin(ctx context.Context, admin syntax.DID) error {
 	); err != nil {
 		return err
 	}
+	if err := s.unassignAllRoles(ctx, []syntax.DID{admin}); err != nil {
+		return err
+	}
 	return s.db.WithContext(ctx).
 		Where("org_id = ? AND did = ? AND role = ?", s.orgID, admin, AdminRole).
 		Delete(&member{}).
@@ -259,6 +269,9 @@ func (s *orgImpl) RemoveMembers(ctx context.Context, members []syntax.DID) error
 	); err != nil {
 		return err
 	}
+	if err := s.unassignAllRoles(ctx, members); err != nil {
+		return err
+	}
 	return s.db.WithContext(ctx).
 		Where("org_id = ? AND did IN ? AND role = ?", s.orgID, members, MemberRole).
 		Delete(&member{}).
@@ -0,0 +1,340 @@
+package org
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/bluesky-social/indigo/atproto/identity"
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"github.com/habitat-network/habitat/internal/fgastore"
+	"github.com/habitat-network/habitat/internal/hive"
+	habitat_syntax "github.com/habitat-network/habitat/internal/syntax"
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/pkg/tuple"
+	"gorm.io/gorm"
+)
+
+// MaxProvisionRows caps the size of one provisioning job, which runs in a
+// single transaction.
+const MaxProvisionRows = 1000
+
+// maxTuplesPerWrite matches OpenFGA's default limit on tuples per write.
+const maxTuplesPerWrite = 100
+
+type ProvisionStatus string
+
+const (
+	// ProvisionCreated: the member was minted.
+	ProvisionCreated ProvisionStatus = "created"
+	// ProvisionValid: a dry run found nothing wrong with the row.
+	ProvisionValid ProvisionStatus = "valid"
+	// ProvisionFailed: the row has a problem; see [ProvisionResult.Err].
+	ProvisionFailed ProvisionStatus = "failed"
+	// ProvisionSkipped: the row was fine, but another row failed so nothing
+	// was created.
+	ProvisionSkipped ProvisionStatus = "skipped"
+)
+
+var (
+	ErrTooManyRows = fmt.Errorf(
+		"at most %d members can be provisioned at once",
+		MaxProvisionRows,
+	)
+	ErrDuplicateHandle  = errors.New("handle appears more than once")
+	ErrHandleTaken      = errors.New("handle is already taken")
+	ErrLoginIDRequired  = errors.New("loginID is required for this org's login method")
+	ErrLoginIDForbidden = errors.New("loginID is not used by password orgs")
+	ErrLoginIDTaken     = errors.New("loginID already belongs to a member")
+)
+
+// errRollback aborts a provisioning transaction that should leave no trace:
+// a dry run, or a job with a failed row.
+var errRollback = errors.New("rollback provisioning")
+
+// ProvisionRow is one member to provision.
+type ProvisionRow struct {
+	// Handle is the handle prefix, placed under the org's handle subdomain.
+	Handle string
+	// LoginID is the provider-specific identifier the member signs in with.
+	// Password orgs identify members by DID and generate a password instead.
+	LoginID string
+	// Groups are the groups to add the member to once minted.
+	Groups []habitat_syntax.SpaceURI
+}
+
+// ProvisionResult reports what happened to one [ProvisionRow].
+type ProvisionResult struct {
+	Row    int // 1-based position in the input
+	Handle string
+	Status ProvisionStatus
+	DID    syntax.DID
+	// InitialPassword is the generated password of a member of a password org.
+	InitialPassword string
+	Err             error
+}
+
+// GroupAdder adds provisioned members to their initial groups. Groups are
+// spaces governed by perms, which the org store does not own, so callers
+// supply it.
+type GroupAdder interface {
+	// CheckGroup reports why group cannot be joined, or nil if it can.
+	CheckGroup(ctx context.Context, group habitat_syntax.SpaceURI) error
+	// AddToGroup adds did to group as part of the provisioning transaction tx.
+	AddToGroup(
+		ctx context.Context,
+		tx *gorm.DB,
+		did syntax.DID,
+		group habitat_syntax.SpaceURI,
+	) error
+}
+
+// ProvisionMembers implements [Store]. The whole job runs in one transaction:
+// either every row is created, or none is and the results say which rows
+// failed. A dry run validates every row the same way and then rolls back.
+// FGA tuples written before an error are deleted again.
+//
+// No invite tokens are issued, since the members already exist: members of
+// password orgs sign in with their [ProvisionResult.InitialPassword], and
+// members of other orgs with their login ID through the org's provider.
+func (s *storeImpl) ProvisionMembers(
+	ctx context.Context,
+	orgDID syntax.DID,
+	rows []ProvisionRow,
+	groups GroupAdder,
+	dryRun bool,
+) ([]ProvisionResult, error) {
+	if len(rows) > MaxProvisionRows {
+		return nil, ErrTooManyRows
+	}
+	var org organization
+	err := s.db.WithContext(ctx).Where("id = ?", orgDID).First(&org).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrOrgNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	// Groups are shared by many rows, so check each one once.
+	groupErrs := make(map[habitat_syntax.SpaceURI]error)
+	for _, row := range rows {
+		for _, group := range row.Groups {
+			if _, ok := groupErrs[group]; !ok {
+				groupErrs[group] = groups.CheckGroup(ctx, group)
+			}
+		}
+	}
+
+	results := make([]ProvisionResult, len(rows))
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		failed := false
+		handles := make(map[string]bool)
+		loginIDs := make(map[string]bool)
+		for i, row := range rows {
+			results[i] = ProvisionResult{Row: i + 1, Handle: row.Handle}
+			err := s.checkProvisionRow(ctx, tx, &org, row, handles, loginIDs, groupErrs)
+			if err == nil {
+				// A savepoint per row keeps one bad row from aborting the
+				// whole transaction on databases that do that.
+				err = tx.Transaction(func(rowTx *gorm.DB) error {
+					return s.provisionRow(ctx, rowTx, &org, row, &results[i])
+				})
+			}
+			if err != nil {
+				results[i].Status = ProvisionFailed
+				results[i].Err = err
+				failed = true
+				continue
+			}
+			results[i].Status = ProvisionCreated
+		}
+
+		if failed || dryRun {
+			for i := range results {
+				if results[i].Status == ProvisionFailed {
+					continue
+				}
+				results[i].Status = ProvisionSkipped
+				if dryRun {
+					results[i].Status = ProvisionValid
+				}
+				results[i].DID = ""
+				results[i].InitialPassword = ""
+			}
+			return errRollback
+		}
+
+		tuples := make([]*openfgav1.TupleKey, 0, len(results))
+		for _, r := range results {
+			tuples = append(tuples, tuple.NewTupleKey(
+				fgastore.OrgObjectKey(orgDID),
+				fgastore.RelationMember,
+				fgastore.MemberUserString(r.DID),
+			))
+		}
+		for start := 0; start < len(tuples); start += maxTuplesPerWrite {
+			end := min(start+maxTuplesPerWrite, len(tuples))
+			if err := s.fga.WriteRaw(ctx, &openfgav1.WriteRequest{
+				Writes: &openfgav1.WriteRequestWrites{
+					TupleKeys:   tuples[start:end],
+					OnDuplicate: "ignore",
+				},
+			}); err != nil {
+				return fmt.Errorf("write fga: %w", err)
+			}
+		}
+
+		for i, row := range rows {
+			for _, group := range row.Groups {
+				if err := groups.AddToGroup(ctx, tx, results[i].DID, group); err != nil {
+					return fmt.Errorf("add %s to %s: %w", results[i].Handle, group, err)
+				}
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, errRollback) {
+		return results, nil
+	} else if err != nil {
+		// FGA is not part of the transaction, so tuples written before the
+		// failure outlive the rolled-back rows. Members are only written once
+		// every row is minted, so each result names a DID this job minted.
+		minted := make([]syntax.DID, 0, len(results))
+		for _, r := range results {
+			minted = append(minted, r.DID)
+		}
+		if cleanupErr := s.deleteMemberTuples(ctx, minted); cleanupErr != nil {
+			return nil, errors.Join(err, cleanupErr)
+		}
+		return nil, err
+	}
+	return results, nil
+}
+
+// deleteMemberTuples deletes every FGA tuple naming one of dids as a user: their
+// org membership and any group roles. It undoes the tuples of a failed
+// provisioning job, whose members no longer exist.
+func (s *storeImpl) deleteMemberTuples(ctx context.Context, dids []syntax.DID) error {
+	var deletes []*openfgav1.TupleKeyWithoutCondition
+	for _, did := range dids {
+		for _, objectType := range []string{fgastore.TypeOrganization, fgastore.TypeSpace} {
+			tuples, err := s.fga.Read(ctx, fgastore.Tuple{
+				User:   fgastore.MemberUserString(did),
+				Object: objectType + ":",
+			})
+			if err != nil {
+				return fmt.Errorf("read fga: %w", err)
+			}
+			for _, t := range tuples {
+				deletes = append(deletes, &openfgav1.TupleKeyWithoutCondition{
+					User:     t.User,
+					Relation: t.Relation,
+					Object:   t.Object,
+				})
+			}
+		}
+	}
+	for start := 0; start < len(deletes); start += maxTuplesPerWrite {
+		end := min(start+maxTuplesPerWrite, len(deletes))
+		if err := s.fga.WriteRaw(ctx, &openfgav1.WriteRequest{
+			Deletes: &openfgav1.WriteRequestDeletes{
+				TupleKeys: deletes[start:end],
+				OnMissing: "ignore",
+			},
+		}); err != nil {
+			return fmt.Errorf("delete fga: %w", err)
+		}
+	}
+	return nil
+}
+
+// checkProvisionRow validates what can be checked about row before writing
+// anything, including its clashes with earlier rows of the same job.
+func (s *storeImpl) checkProvisionRow(
+	ctx context.Context,
+	tx *gorm.DB,
+	org *organization,
+	row ProvisionRow,
+	handles map[string]bool,
+	loginIDs map[string]bool,
+	groupErrs map[habitat_syntax.SpaceURI]error,
+) error {
+	if handles[row.Handle] {
+		return ErrDuplicateHandle
+	}
+	handles[row.Handle] = true
+
+	switch {
+	case org.LoginMethod == LoginMethodPassword && row.LoginID != "":
+		return ErrLoginIDForbidden
+	case org.LoginMethod != LoginMethodPassword && row.LoginID == "":
+		return ErrLoginIDRequired
+	case row.LoginID != "":
+		if loginIDs[row.LoginID] {
+			return ErrLoginIDTaken
+		}
+		loginIDs[row.LoginID] = true
+		var count int64
+		if err := tx.WithContext(ctx).
+			Model(&member{}).
+			Where("login_id = ?", row.LoginID).
+			Count(&count).
+			Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrLoginIDTaken
+		}
+	}
+
+	for _, group := range row.Groups {
+		if err := groupErrs[group]; err != nil {
+			return fmt.Errorf("group %s: %w", group, err)
+		}
+	}
+	return nil
+}
+
+// provisionRow mints the identity of one member and records its membership.
+// FGA tuples are left to the caller, which writes them only once every row has
+// succeeded.
+func (s *storeImpl) provisionRow(
+	ctx context.Context,
+	tx *gorm.DB,
+	org *organization,
+	row ProvisionRow,
+	result *ProvisionResult,
+) error {
+	id, err := s.hive.WithTx(tx).MintIdentity(ctx, row.Handle, org.HandleSubdomain)
+	if errors.Is(err, hive.ErrNotCreated) {
+		return ErrHandleTaken
+	} else if errors.Is(err, identity.ErrInvalidHandle) {
+		return fmt.Errorf("%w: %q", identity.ErrInvalidHandle, row.Handle)
+	} else if err != nil {
+		return fmt.Errorf("mint identity: %w", err)
+	}
+	result.DID = id.DID
+
+	loginID := row.LoginID
+	if org.LoginMethod == LoginMethodPassword {
+		loginID = id.DID.String()
+		b := make([]byte, 18)
+		if _, err := rand.Read(b); err != nil {
+			return err
+		}
+		result.InitialPassword = base64.RawURLEncoding.EncodeToString(b)
+		if err := s.passwordProvider.WithTx(tx).
+			AddLoginEntry(id.DID, result.InitialPassword); err != nil {
+			return fmt.Errorf("add login entry: %w", err)
+		}
+	}
+
+	return tx.Create(&member{
+		OrgID:   org.ID,
+		Did:     id.DID,
+		Role:    MemberRole,
+		LoginID: loginID,
+	}).Error
+}
@@ -0,0 +1,222 @@
+package org
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"github.com/habitat-network/habitat/internal/fgastore"
+	habitat_syntax "github.com/habitat-network/habitat/internal/syntax"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+type added struct {
+	did   syntax.DID
+	group habitat_syntax.SpaceURI
+}
+
+// fakeGroups accepts every group except those in bad. With fga set it grants
+// the writer tuple as perms does, and with failAt set that call to AddToGroup
+// fails.
+type fakeGroups struct {
+	bad    map[habitat_syntax.SpaceURI]error
+	added  []added
+	fga    fgastore.Store
+	failAt int
+}
+
+func (f *fakeGroups) CheckGroup(ctx context.Context, group habitat_syntax.SpaceURI) error {
+	return f.bad[group]
+}
+
+func (f *fakeGroups) AddToGroup(
+	ctx context.Context,
+	tx *gorm.DB,
+	did syntax.DID,
+	group habitat_syntax.SpaceURI,
+) error {
+	f.added = append(f.added, added{did, group})
+	if len(f.added) == f.failAt {
+		return errors.New("group unavailable")
+	}
+	if f.fga != nil {
+		return f.fga.Write(
+			ctx,
+			fgastore.MemberUserString(did),
+			fgastore.RelationSpaceWriter,
+			fgastore.SpaceObjectKey(group),
+		)
+	}
+	return nil
+}
+
+var testGroup = habitat_syntax.ConstructSpaceURI("test-org", "network.habitat.group", "eng")
+
+func TestProvisionMembers_Creates(t *testing.T) {
+	ctx := context.Background()
+	store, org := newTestOrg(t)
+	groups := &fakeGroups{}
+
+	results, err := store.ProvisionMembers(ctx, org.orgID, []ProvisionRow{
+		{Handle: "alice", Groups: []habitat_syntax.SpaceURI{testGroup}},
+		{Handle: "bob"},
+	}, groups, false)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+
+	for i, res := range results {
+		require.Equal(t, i+1, res.Row)
+		require.Equal(t, ProvisionCreated, res.Status)
+		require.NoError(t, res.Err)
+		require.NotEmpty(t, res.DID)
+		require.NotEmpty(t, res.InitialPassword)
+
+		ok, err := org.IsMember(ctx, res.DID)
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		tuples, err := org.fga.Read(ctx, fgastore.Tuple{
+			User:     fgastore.MemberUserString(res.DID),
+			Relation: fgastore.RelationMember,
+			Object:   fgastore.OrgObjectKey(org.orgID),
+		})
+		require.NoError(t, err)
+		require.Len(t, tuples, 1)
+	}
+	require.Equal(t, []added{{results[0].DID, testGroup}}, groups.added)
+}
+
+func TestProvisionMembers_FailedRowRollsBack(t *testing.T) {
+	ctx := context.Background()
+	store, org := newTestOrg(t)
+	addMember(t, store, org, "taken")
+	badGroup := habitat_syntax.ConstructSpaceURI("other-org", "network.habitat.group", "x")
+	groups := &fakeGroups{bad: map[habitat_syntax.SpaceURI]error{
+		badGroup: errors.New("group belongs to another org"),
+	}}
+
+	results, err := store.ProvisionMembers(ctx, org.orgID, []ProvisionRow{
+		{Handle: "alice"},
+		{Handle: "taken"},
+		{Handle: "alice"},
+		{Handle: "bob", LoginID: "bob@example.com"},
+		{Handle: "carol", Groups: []habitat_syntax.SpaceURI{badGroup}},
+		{Handle: "Not A Handle!"},
+	}, groups, false)
+	require.NoError(t, err)
+
+	statuses := make([]ProvisionStatus, len(results))
+	for i, res := range results {
+		statuses[i] = res.Status
+		require.Empty(t, res.DID)
+		require.Empty(t, res.InitialPassword)
+	}
+	require.Equal(t, []ProvisionStatus{
+		ProvisionSkipped,
+		ProvisionFailed,
+		ProvisionFailed,
+		ProvisionFailed,
+		ProvisionFailed,
+		ProvisionFailed,
+	}, statuses)
+	require.ErrorIs(t, results[1].Err, ErrHandleTaken)
+	require.ErrorIs(t, results[2].Err, ErrDuplicateHandle)
+	require.ErrorIs(t, results[3].Err, ErrLoginIDForbidden)
+	require.Empty(t, groups.added)
+
+	members, err := org.GetMembers(ctx)
+	require.NoError(t, err)
+	require.Len(t, members, 1)
+}
+
+func TestProvisionMembers_GroupFailureDeletesTuples(t *testing.T) {
+	ctx := context.Background()
+	store, org := newTestOrg(t)
+	groups := &fakeGroups{fga: store.fga, failAt: 2}
+
+	_, err := store.ProvisionMembers(ctx, org.orgID, []ProvisionRow{
+		{Handle: "alice", Groups: []habitat_syntax.SpaceURI{testGroup}},
+		{Handle: "bob", Groups: []habitat_syntax.SpaceURI{testGroup}},
+		{Handle: "carol", Groups: []habitat_syntax.SpaceURI{testGroup}},
+	}, groups, false)
+	require.Error(t, err)
+	require.Len(t, groups.added, 2)
+
+	members, err := org.GetMembers(ctx)
+	require.NoError(t, err)
+	require.Empty(t, members)
+	// When bob's group failed, alice and bob held org membership tuples and
+	// alice a group tuple.
+	for _, objectType := range []string{fgastore.TypeOrganization, fgastore.TypeSpace} {
+		for _, a := range groups.added {
+			tuples, err := store.fga.Read(ctx, fgastore.Tuple{
+				User:   fgastore.MemberUserString(a.did),
+				Object: objectType + ":",
+			})
+			require.NoError(t, err)
+			require.Empty(t, tuples)
+		}
+	}
+}
+
+func TestProvisionMembers_DryRun(t *testing.T) {
+	ctx := context.Background()
+	store, org := newTestOrg(t)
+	groups := &fakeGroups{}
+
+	rows := []ProvisionRow{{Handle: "alice", Groups: []habitat_syntax.SpaceURI{testGroup}}}
+	results, err := store.ProvisionMembers(ctx, org.orgID, rows, groups, true)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.Equal(t, ProvisionValid, results[0].Status)
+	require.Empty(t, results[0].DID)
+	require.Empty(t, groups.added)
+
+	members, err := org.GetMembers(ctx)
+	require.NoError(t, err)
+	require.Empty(t, members)
+
+	// The dry run left the handle free.
+	results, err = store.ProvisionMembers(ctx, org.orgID, rows, groups, false)
+	require.NoError(t, err)
+	require.Equal(t, ProvisionCreated, results[0].Status)
+}
+
+func TestProvisionMembers_LoginIDs(t *testing.T) {
+	ctx := context.Background()
+	store, org := newTestOrg(t)
+	require.NoError(t, store.db.Model(&organization{}).
+		Where("id = ?", org.orgID).
+		Update("login_method", LoginMethodGoogle).
+		Error)
+
+	results, err := store.ProvisionMembers(ctx, org.orgID, []ProvisionRow{
+		{Handle: "alice", LoginID: "alice@example.com"},
+		{Handle: "bob"},
+		{Handle: "carol", LoginID: "alice@example.com"},
+	}, &fakeGroups{}, false)
+	require.NoError(t, err)
+	require.Equal(t, ProvisionSkipped, results[0].Status)
+	require.ErrorIs(t, results[1].Err, ErrLoginIDRequired)
+	require.ErrorIs(t, results[2].Err, ErrLoginIDTaken)
+
+	results, err = store.ProvisionMembers(ctx, org.orgID, []ProvisionRow{
+		{Handle: "alice", LoginID: "alice@example.com"},
+	}, &fakeGroups{}, false)
+	require.NoError(t, err)
+	require.Equal(t, ProvisionCreated, results[0].Status)
+	require.Empty(t, results[0].InitialPassword)
+
+	m, err := store.GetMemberByLoginID(ctx, "alice@example.com")
+	require.NoError(t, err)
+	require.Equal(t, results[0].DID, m.DID)
+}
+
+func TestProvisionMembers_TooManyRows(t *testing.T) {
+	store, org := newTestOrg(t)
+	rows := make([]ProvisionRow, MaxProvisionRows+1)
+	_, err := store.ProvisionMembers(t.Context(), org.orgID, rows, &fakeGroups{}, false)
+	require.ErrorIs(t, err, ErrTooManyRows)
+}
@@ -84,6 +84,40 @@ func (e *everyoneOrg) IsMember(ctx context.Context, member syntax.DID) (bool, er
 	return true, nil
 }
 
+// PutRole implements Org.
+func (e *everyoneOrg) PutRole(ctx context.Context, role Role, capabilities []Capability) error {
+	return ErrNotSupportedPublic
+}
+
+// DeleteRole implements Org.
+func (e *everyoneOrg) DeleteRole(ctx context.Context, role Role) error {
+	return ErrNotSupportedPublic
+}
+
+// ListRoles implements Org.
+func (e *everyoneOrg) ListRoles(ctx context.Context) ([]RoleDefinition, error) {
+	return nil, ErrNotSupportedPublic
+}
+
+// AssignRole implements Org.
+func (e *everyoneOrg) AssignRole(ctx context.Context, did syntax.DID, role Role) error {
+	return ErrNotSupportedPublic
+}
+
+// UnassignRole implements Org.
+func (e *everyoneOrg) UnassignRole(ctx context.Context, did syntax.DID, role Role) error {
+	return ErrNotSupportedPublic
+}
+
+// HasCapability implements Org.
+func (e *everyoneOrg) HasCapability(
+	ctx context.Context,
+	did syntax.DID,
+	capability Capability,
+) (bool, error) {
+	return false, ErrNotSupportedPublic
+}
+
 // AuthenticateMember implements Org.
 func (e *everyoneOrg) AuthenticateMember(
 	ctx context.Context,
@@ -37,4 +37,13 @@ func TestEveryoneOrg_ErrorMethods(t *testing.T) {
 
 	err = o.RemoveMembers(ctx, []syntax.DID{did})
 	require.ErrorIs(t, err, ErrNotSupportedPublic)
+
+	err = o.PutRole(ctx, "moderator", nil)
+	require.ErrorIs(t, err, ErrNotSupportedPublic)
+
+	err = o.AssignRole(ctx, did, "moderator")
+	require.ErrorIs(t, err, ErrNotSupportedPublic)
+
+	_, err = o.HasCapability(ctx, did, CapabilityIssueInvites)
+	require.ErrorIs(t, err, ErrNotSupportedPublic)
 }
@@ -0,0 +1,478 @@
+package org
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"github.com/habitat-network/habitat/internal/fgastore"
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/pkg/tuple"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Capability is something a custom org role allows its holders to do. Admins
+// hold every capability.
+type Capability string
+
+const (
+	// CapabilityIssueInvites allows issuing single-use invite tokens.
+	CapabilityIssueInvites Capability = "issueInvites"
+	// CapabilityMintIdentities allows issuing reusable invite tokens, each of
+	// which can mint any number of member identities.
+	CapabilityMintIdentities Capability = "mintIdentities"
+
+	// manageSpacesPrefix prefixes the manageSpaces capabilities, one per space
+	// type; see [ManageSpacesCapability].
+	manageSpacesPrefix = "manageSpaces:"
+)
+
+var (
+	ErrRoleNotFound      = errors.New("role not found")
+	ErrInvalidRoleName   = errors.New("role names are 1-64 lowercase letters, digits or hyphens")
+	ErrReservedRoleName  = errors.New("admin and member are built-in roles")
+	ErrInvalidCapability = errors.New("unknown capability")
+	ErrRoleInUse         = errors.New("role is still granted relations on spaces")
+)
+
+// roleNamePattern restricts custom role names to something safe to embed in
+// FGA object keys and record keys.
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9-]{0,63}$`)
+
+// ManageSpacesCapability returns the capability to manage every space of
+// spaceType the org owns, as if its holders had the manager role on each.
+func ManageSpacesCapability(spaceType syntax.NSID) Capability {
+	return Capability(manageSpacesPrefix + spaceType.String())
+}
+
+// ParseCapability validates a capability string.
+func ParseCapability(raw string) (Capability, error) {
+	switch c := Capability(raw); c {
+	case CapabilityIssueInvites, CapabilityMintIdentities:
+		return c, nil
+	}
+	if spaceType, ok := strings.CutPrefix(raw, manageSpacesPrefix); ok {
+		nsid, err := syntax.ParseNSID(spaceType)
+		if err != nil {
+			return "", fmt.Errorf("%w: %q: %w", ErrInvalidCapability, raw, err)
+		}
+		return ManageSpacesCapability(nsid), nil
+	}
+	return "", fmt.Errorf("%w: %q", ErrInvalidCapability, raw)
+}
+
+// ManagedSpaceType returns the space type a manageSpaces capability covers.
+func (c Capability) ManagedSpaceType() (syntax.NSID, bool) {
+	spaceType, ok := strings.CutPrefix(string(c), manageSpacesPrefix)
+	return syntax.NSID(spaceType), ok
+}
+
+// ParseRoleName validates the name of a custom role.
+func ParseRoleName(raw string) (Role, error) {
+	role := Role(raw)
+	if role == AdminRole || role == MemberRole {
+		return "", ErrReservedRoleName
+	}
+	if !roleNamePattern.MatchString(raw) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidRoleName, raw)
+	}
+	return role, nil
+}
+
+// RoleDefinition is a custom role of an org with the members assigned to it.
+type RoleDefinition struct {
+	Name         Role
+	Capabilities []Capability
+	Assignees    []syntax.DID
+}
+
+// PutRole implements [Org]. It creates the role, or replaces the capabilities
+// of an existing one.
+func (s *orgImpl) PutRole(ctx context.Context, role Role, capabilities []Capability) error {
+	var existing orgRole
+	isNew := false
+	if err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("org_id = ? AND name = ?", s.orgID, role).First(&existing).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		isNew = errors.Is(err, gorm.ErrRecordNotFound)
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "org_id"}, {Name: "name"}},
+			DoUpdates: clause.AssignmentColumns([]string{"capabilities"}),
+		}).Create(&orgRole{
+			OrgID:        s.orgID,
+			Name:         role,
+			Capabilities: capabilities,
+			CreatedAt:    time.Now(),
+		}).Error
+	}); err != nil {
+		return err
+	}
+
+	// Sync the space types the role manages: drop the ones it lost and grant
+	// the ones it gained.
+	userset := fgastore.OrgRoleUsersetString(s.orgID, string(role))
+	var writes []*openfgav1.TupleKey
+	var deletes []*openfgav1.TupleKeyWithoutCondition
+	if isNew {
+		writes = append(writes, tuple.NewTupleKey(
+			fgastore.OrgRoleObjectKey(s.orgID, string(role)),
+			fgastore.RelationOrgRoleOrg,
+			fgastore.OrgObjectKey(s.orgID),
+		))
+	}
+	for _, c := range capabilities {
+		spaceType, ok := c.ManagedSpaceType()
+		if ok && !slices.Contains(existing.Capabilities, c) {
+			writes = append(writes, s.spaceTypeManagerTuple(spaceType, userset))
+		}
+	}
+	for _, c := range existing.Capabilities {
+		spaceType, ok := c.ManagedSpaceType()
+		if ok && !slices.Contains(capabilities, c) {
+			deletes = append(deletes, tuple.TupleKeyToTupleKeyWithoutCondition(
+				s.spaceTypeManagerTuple(spaceType, userset),
+			))
+		}
+	}
+	if err := s.writeRoleTuples(ctx, writes, deletes); err != nil {
+		// Put the row back the way it was, so it matches the tuples again.
+		var undo error
+		if isNew {
+			undo = s.db.WithContext(ctx).
+				Where("org_id = ? AND name = ?", s.orgID, role).
+				Delete(&orgRole{}).
+				Error
+		} else {
+			undo = s.db.WithContext(ctx).Save(&existing).Error
+		}
+		return errors.Join(err, undo)
+	}
+	return nil
+}
+
+// DeleteRole implements [Org]. A role still granted relations on spaces is
+// not deleted, so the grants never outlive it; they must be deleted first.
+func (s *orgImpl) DeleteRole(ctx context.Context, role Role) error {
+	roleKey := fgastore.OrgRoleObjectKey(s.orgID, string(role))
+	userset := fgastore.OrgRoleUsersetString(s.orgID, string(role))
+	var existing orgRole
+	var assignments []roleAssignment
+	if err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("org_id = ? AND name = ?", s.orgID, role).
+			First(&existing).
+			Error; errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrRoleNotFound
+		} else if err != nil {
+			return err
+		}
+
+		grants, err := s.fga.Read(ctx, fgastore.Tuple{
+			User:   userset,
+			Object: fgastore.TypeSpace + ":",
+		})
+		if err != nil {
+			return err
+		}
+		if len(grants) > 0 {
+			return ErrRoleInUse
+		}
+
+		if err := tx.Where("org_id = ? AND role = ?", s.orgID, role).
+			Find(&assignments).
+			Error; err != nil {
+			return err
+		}
+		if err := tx.Where("org_id = ? AND role = ?", s.orgID, role).
+			Delete(&roleAssignment{}).
+			Error; err != nil {
+			return err
+		}
+		return tx.Where("org_id = ? AND name = ?", s.orgID, role).
+			Delete(&orgRole{}).
+			Error
+	}); err != nil {
+		return err
+	}
+
+	deletes := []*openfgav1.TupleKeyWithoutCondition{
+		tuple.TupleKeyToTupleKeyWithoutCondition(tuple.NewTupleKey(
+			roleKey,
+			fgastore.RelationOrgRoleOrg,
+			fgastore.OrgObjectKey(s.orgID),
+		)),
+	}
+	for _, a := range assignments {
+		deletes = append(deletes, tuple.TupleKeyToTupleKeyWithoutCondition(tuple.NewTupleKey(
+			roleKey,
+			fgastore.RelationOrgRoleAssignee,
+			fgastore.MemberUserString(a.Did),
+		)))
+	}
+	for _, c := range existing.Capabilities {
+		if spaceType, ok := c.ManagedSpaceType(); ok {
+			deletes = append(deletes, tuple.TupleKeyToTupleKeyWithoutCondition(
+				s.spaceTypeManagerTuple(spaceType, userset),
+			))
+		}
+	}
+	if err := s.writeRoleTuples(ctx, nil, deletes); err != nil {
+		undo := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&existing).Error; err != nil {
+				return err
+			}
+			if len(assignments) == 0 {
+				return nil
+			}
+			return tx.Create(&assignments).Error
+		})
+		return errors.Join(err, undo)
+	}
+	return nil
+}
+
+// ListRoles implements [Org].
+func (s *orgImpl) ListRoles(ctx context.Context) ([]RoleDefinition, error) {
+	var roles []orgRole
+	if err := s.db.WithContext(ctx).
+		Where("org_id = ?", s.orgID).
+		Order("name").
+		Find(&roles).
+		Error; err != nil {
+		return nil, err
+	}
+	var assignments []roleAssignment
+	if err := s.db.WithContext(ctx).
+		Where("org_id = ?", s.orgID).
+		Order("did").
+		Find(&assignments).
+		Error; err != nil {
+		return nil, err
+	}
+	assignees := make(map[Role][]syntax.DID)
+	for _, a := range assignments {
+		assignees[a.Role] = append(assignees[a.Role], a.Did)
+	}
+	defs := make([]RoleDefinition, 0, len(roles))
+	for _, r := range roles {
+		defs = append(defs, RoleDefinition{
+			Name:         r.Name,
+			Capabilities: r.Capabilities,
+			Assignees:    assignees[r.Name],
+		})
+	}
+	return defs, nil
+}
+
+// AssignRole implements [Org].
+func (s *orgImpl) AssignRole(ctx context.Context, did syntax.DID, role Role) error {
+	if ok, err := s.IsMember(ctx, did); err != nil {
+		return err
+	} else if !ok {
+		return ErrNotMember
+	}
+	created := false
+	if err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("org_id = ? AND name = ?", s.orgID, role).
+			First(&orgRole{}).
+			Error; errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrRoleNotFound
+		} else if err != nil {
+			return err
+		}
+		res := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&roleAssignment{OrgID: s.orgID, Did: did, Role: role, CreatedAt: time.Now()})
+		created = res.RowsAffected > 0
+		return res.Error
+	}); err != nil {
+		return err
+	}
+
+	if err := s.writeRoleTuples(ctx, []*openfgav1.TupleKey{
+		tuple.NewTupleKey(
+			fgastore.OrgRoleObjectKey(s.orgID, string(role)),
+			fgastore.RelationOrgRoleAssignee,
+			fgastore.MemberUserString(did),
+		),
+	}, nil); err != nil {
+		if !created {
+			return err
+		}
+		return errors.Join(err, s.db.WithContext(ctx).
+			Where("org_id = ? AND did = ? AND role = ?", s.orgID, did, role).
+			Delete(&roleAssignment{}).
+			Error)
+	}
+	return nil
+}
+
+// UnassignRole implements [Org].
+func (s *orgImpl) UnassignRole(ctx context.Context, did syntax.DID, role Role) error {
+	var assignments []roleAssignment
+	if err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("org_id = ? AND did = ? AND role = ?", s.orgID, did, role).
+			Find(&assignments).
+			Error; err != nil {
+			return err
+		}
+		return tx.Where("org_id = ? AND did = ? AND role = ?", s.orgID, did, role).
+			Delete(&roleAssignment{}).
+			Error
+	}); err != nil {
+		return err
+	}
+
+	if err := s.writeRoleTuples(ctx, nil, []*openfgav1.TupleKeyWithoutCondition{
+		tuple.TupleKeyToTupleKeyWithoutCondition(tuple.NewTupleKey(
+			fgastore.OrgRoleObjectKey(s.orgID, string(role)),
+			fgastore.RelationOrgRoleAssignee,
+			fgastore.MemberUserString(did),
+		)),
+	}); err != nil {
+		if len(assignments) == 0 {
+			return err
+		}
+		return errors.Join(err, s.db.WithContext(ctx).Create(&assignments).Error)
+	}
+	return nil
+}
+
+// HasCapability implements [Org]. Admins hold every capability; other members
+// hold the capabilities of the roles assigned to them.
+func (s *orgImpl) HasCapability(
+	ctx context.Context,
+	did syntax.DID,
+	capability Capability,
+) (bool, error) {
+	if ok, err := s.IsAdmin(ctx, did); err != nil || ok {
+		return ok, err
+	}
+	var roles []orgRole
+	if err := s.db.WithContext(ctx).
+		Joins("JOIN role_assignments ON role_assignments.org_id = org_roles.org_id"+
+			" AND role_assignments.role = org_roles.name").
+		Where("org_roles.org_id = ? AND role_assignments.did = ?", s.orgID, did).
+		Find(&roles).
+		Error; err != nil {
+		return false, err
+	}
+	for _, r := range roles {
+		if slices.Contains(r.Capabilities, capability) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// unassignAllRoles drops every custom role of the given members, for when they
+// leave the org.
+func (s *orgImpl) unassignAllRoles(ctx context.Context, members []syntax.DID) error {
+	var assignments []roleAssignment
+	if err := s.db.WithContext(ctx).
+		Where("org_id = ? AND did IN ?", s.orgID, members).
+		Find(&assignments).
+		Error; err != nil {
+		return err
+	}
+	if len(assignments) == 0 {
+		return nil
+	}
+	deletes := make([]*openfgav1.TupleKeyWithoutCondition, 0, len(assignments))
+	for _, a := range assignments {
+		deletes = append(deletes, tuple.TupleKeyToTupleKeyWithoutCondition(tuple.NewTupleKey(
+			fgastore.OrgRoleObjectKey(s.orgID, string(a.Role)),
+			fgastore.RelationOrgRoleAssignee,
+			fgastore.MemberUserString(a.Did),
+		)))
+	}
+	if err := s.writeRoleTuples(ctx, nil, deletes); err != nil {
+		return err
+	}
+	return s.db.WithContext(ctx).
+		Where("org_id = ? AND did IN ?", s.orgID, members).
+		Delete(&roleAssignment{}).
+		Error
+}
+
+func (s *orgImpl) spaceTypeManagerTuple(spaceType syntax.NSID, userset string) *openfgav1.TupleKey {
+	return tuple.NewTupleKey(
+		fgastore.SpaceTypeObjectKey(s.orgID, spaceType),
+		fgastore.RelationSpaceTypeManager,
+		userset,
+	)
+}
+
+// writeRoleTuples applies deletes and then writes, in batches of at most
+// maxTuplesPerWrite. Role rows are committed before their tuples are written,
+// and a caller whose write fails puts its rows back; so that rows and tuples
+// match again, a failed batch also undoes the batches applied before it.
+func (s *orgImpl) writeRoleTuples(
+	ctx context.Context,
+	writes []*openfgav1.TupleKey,
+	deletes []*openfgav1.TupleKeyWithoutCondition,
+) error {
+	var applied []*openfgav1.WriteRequest
+	apply := func(req *openfgav1.WriteRequest) error {
+		if err := s.fga.WriteRaw(ctx, req); err != nil {
+			return errors.Join(fmt.Errorf("write fga: %w", err), s.undoRoleTuples(ctx, applied))
+		}
+		applied = append(applied, req)
+		return nil
+	}
+	for start := 0; start < len(deletes); start += maxTuplesPerWrite {
+		end := min(start+maxTuplesPerWrite, len(deletes))
+		if err := apply(&openfgav1.WriteRequest{
+			Deletes: &openfgav1.WriteRequestDeletes{
+				TupleKeys: deletes[start:end],
+				OnMissing: "ignore",
+			},
+		}); err != nil {
+			return err
+		}
+	}
+	for start := 0; start < len(writes); start += maxTuplesPerWrite {
+		end := min(start+maxTuplesPerWrite, len(writes))
+		if err := apply(&openfgav1.WriteRequest{
+			Writes: &openfgav1.WriteRequestWrites{
+				TupleKeys:   writes[start:end],
+				OnDuplicate: "ignore",
+			},
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// undoRoleTuples reverses the given writes, newest first: written tuples are
+// deleted and deleted ones written back.
+func (s *orgImpl) undoRoleTuples(ctx context.Context, applied []*openfgav1.WriteRequest) error {
+	for _, req := range slices.Backward(applied) {
+		undo := &openfgav1.WriteRequest{}
+		if req.Writes != nil {
+			keys := make([]*openfgav1.TupleKeyWithoutCondition, 0, len(req.Writes.TupleKeys))
+			for _, k := range req.Writes.TupleKeys {
+				keys = append(keys, tuple.TupleKeyToTupleKeyWithoutCondition(k))
+			}
+			undo.Deletes = &openfgav1.WriteRequestDeletes{TupleKeys: keys, OnMissing: "ignore"}
+		}
+		if req.Deletes != nil {
+			keys := make([]*openfgav1.TupleKey, 0, len(req.Deletes.TupleKeys))
+			for _, k := range req.Deletes.TupleKeys {
+				keys = append(keys, tuple.NewTupleKey(k.Object, k.Relation, k.User))
+			}
+			undo.Writes = &openfgav1.WriteRequestWrites{TupleKeys: keys, OnDuplicate: "ignore"}
+		}
+		if err := s.fga.WriteRaw(ctx, undo); err != nil {
+			return fmt.Errorf("undo fga write: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,302 @@
+package org
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"github.com/habitat-network/habitat/internal/fgastore"
+	habitat_syntax "github.com/habitat-network/habitat/internal/syntax"
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/stretchr/testify/require"
+)
+
+// failingFGA fails every write, as an unreachable FGA store would.
+type failingFGA struct {
+	fgastore.Store
+}
+
+func (failingFGA) WriteRaw(context.Context, *openfgav1.WriteRequest) error {
+	return errors.New("fga unavailable")
+}
+
+// failNthFGA fails the nth write and lets every other through, as a store that
+// drops a connection partway through a batched write would.
+type failNthFGA struct {
+	fgastore.Store
+	n     int
+	calls *int
+}
+
+func (f failNthFGA) WriteRaw(ctx context.Context, req *openfgav1.WriteRequest) error {
+	*f.calls++
+	if *f.calls == f.n {
+		return errors.New("fga unavailable")
+	}
+	return f.Store.WriteRaw(ctx, req)
+}
+
+func TestParseCapability(t *testing.T) {
+	c, err := ParseCapability("issueInvites")
+	require.NoError(t, err)
+	require.Equal(t, CapabilityIssueInvites, c)
+
+	c, err = ParseCapability("manageSpaces:com.example.event")
+	require.NoError(t, err)
+	spaceType, ok := c.ManagedSpaceType()
+	require.True(t, ok)
+	require.Equal(t, syntax.NSID("com.example.event"), spaceType)
+
+	_, err = ParseCapability("manageSpaces:not-an-nsid")
+	require.ErrorIs(t, err, ErrInvalidCapability)
+	_, err = ParseCapability("deleteEverything")
+	require.ErrorIs(t, err, ErrInvalidCapability)
+}
+
+func TestParseRoleName(t *testing.T) {
+	role, err := ParseRoleName("event-organizer")
+	require.NoError(t, err)
+	require.Equal(t, Role("event-organizer"), role)
+
+	_, err = ParseRoleName("admin")
+	require.ErrorIs(t, err, ErrReservedRoleName)
+	_, err = ParseRoleName("Billing")
+	require.ErrorIs(t, err, ErrInvalidRoleName)
+	_, err = ParseRoleName("a/b")
+	require.ErrorIs(t, err, ErrInvalidRoleName)
+}
+
+func TestRoles_AssignAndHasCapability(t *testing.T) {
+	ctx := context.Background()
+	store, org := newTestOrg(t)
+	alice := addMember(t, store, org, "alice")
+	bob := addMember(t, store, org, "bob")
+
+	require.NoError(t, org.PutRole(ctx, "billing", []Capability{CapabilityIssueInvites}))
+	require.NoError(t, org.AssignRole(ctx, alice.DID, "billing"))
+
+	ok, err := org.HasCapability(ctx, alice.DID, CapabilityIssueInvites)
+	require.NoError(t, err)
+	require.True(t, ok)
+	ok, err = org.HasCapability(ctx, alice.DID, CapabilityMintIdentities)
+	require.NoError(t, err)
+	require.False(t, ok)
+	ok, err = org.HasCapability(ctx, bob.DID, CapabilityIssueInvites)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	defs, err := org.ListRoles(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []RoleDefinition{{
+		Name:         "billing",
+		Capabilities: []Capability{CapabilityIssueInvites},
+		Assignees:    []syntax.DID{alice.DID},
+	}}, defs)
+
+	require.NoError(t, org.UnassignRole(ctx, alice.DID, "billing"))
+	ok, err = org.HasCapability(ctx, alice.DID, CapabilityIssueInvites)
+	require.NoError(t, err)
+	require.False(t, ok)
+}
+
+func TestRoles_AdminHasEveryCapability(t *testing.T) {
+	ctx := context.Background()
+	store, org := newTestOrg(t)
+	id := addMember(t, store, org, "alice")
+	require.NoError(t, org.AddAdmin(ctx, id.DID))
+
+	ok, err := org.HasCapability(ctx, id.DID, CapabilityMintIdentities)
+	require.NoError(t, err)
+	require.True(t, ok)
+}
+
+func TestRoles_AssignErrors(t *testing.T) {
+	ctx := context.Background()
+	store, org := newTestOrg(t)
+	id := addMember(t, store, org, "alice")
+
+	err := org.AssignRole(ctx, id.DID, "moderator")
+	require.ErrorIs(t, err, ErrRoleNotFound)
+
+	require.NoError(t, org.PutRole(ctx, "moderator", nil))
+	err = org.AssignRole(ctx, syntax.DID("did:plc:stranger"), "moderator")
+	require.ErrorIs(t, err, ErrNotMember)
+
+	require.ErrorIs(t, org.DeleteRole(ctx, "nope"), ErrRoleNotFound)
+}
+
+func TestRoles_FGATuples(t *testing.T) {
+	ctx := context.Background()
+	store, org := newTestOrg(t)
+	id := addMember(t, store, org, "alice")
+	eventType := syntax.NSID("com.example.event")
+	roleObj := fgastore.OrgRoleObjectKey(org.orgID, "organizer")
+	userset := fgastore.OrgRoleUsersetString(org.orgID, "organizer")
+	spaceTypeObj := fgastore.SpaceTypeObjectKey(org.orgID, eventType)
+
+	require.NoError(t, org.PutRole(ctx, "organizer", []Capability{
+		ManageSpacesCapability(eventType),
+	}))
+	require.NoError(t, org.AssignRole(ctx, id.DID, "organizer"))
+
+	tuples, err := org.fga.Read(ctx, fgastore.Tuple{
+		User:     fgastore.MemberUserString(id.DID),
+		Relation: fgastore.RelationOrgRoleAssignee,
+		Object:   roleObj,
+	})
+	require.NoError(t, err)
+	require.Len(t, tuples, 1)
+	tuples, err = org.fga.Read(ctx, fgastore.Tuple{
+		User:     userset,
+		Relation: fgastore.RelationSpaceTypeManager,
+		Object:   spaceTypeObj,
+	})
+	require.NoError(t, err)
+	require.Len(t, tuples, 1)
+
+	// Dropping the capability revokes management of the space type.
+	require.NoError(t, org.PutRole(ctx, "organizer", []Capability{CapabilityIssueInvites}))
+	tuples, err = org.fga.Read(ctx, fgastore.Tuple{
+		User:     userset,
+		Relation: fgastore.RelationSpaceTypeManager,
+		Object:   spaceTypeObj,
+	})
+	require.NoError(t, err)
+	require.Empty(t, tuples)
+
+	// Leaving the org drops the member's roles.
+	require.NoError(t, org.RemoveMembers(ctx, []syntax.DID{id.DID}))
+	tuples, err = org.fga.Read(ctx, fgastore.Tuple{
+		User:     fgastore.MemberUserString(id.DID),
+		Relation: fgastore.RelationOrgRoleAssignee,
+		Object:   roleObj,
+	})
+	require.NoError(t, err)
+	require.Empty(t, tuples)
+	defs, err := org.ListRoles(ctx)
+	require.NoError(t, err)
+	require.Len(t, defs, 1)
+	require.Empty(t, defs[0].Assignees)
+
+	require.NoError(t, org.DeleteRole(ctx, "organizer"))
+	defs, err = org.ListRoles(ctx)
+	require.NoError(t, err)
+	require.Empty(t, defs)
+}
+
+func TestRoles_ManageSpacesGrantsSpaceManagement(t *testing.T) {
+	ctx := context.Background()
+	store, org := newTestOrg(t)
+	alice := addMember(t, store, org, "alice")
+	bob := addMember(t, store, org, "bob")
+
+	require.NoError(t, org.PutRole(ctx, "organizer", []Capability{
+		ManageSpacesCapability("com.example.event"),
+	}))
+	require.NoError(t, org.AssignRole(ctx, alice.DID, "organizer"))
+
+	// A space is linked to its type by a contextual tuple, as the perms
+	// package links it when checking.
+	for _, tc := range []struct {
+		user      syntax.DID
+		spaceType syntax.NSID
+		want      bool
+	}{
+		{alice.DID, "com.example.event", true},
+		{alice.DID, "com.example.docs", false},
+		{bob.DID, "com.example.event", false},
+	} {
+		ok, err := org.fga.Check(
+			ctx,
+			fgastore.MemberUserString(tc.user),
+			fgastore.RelationSpaceMemberManager,
+			"space:party",
+			fgastore.Tuple{
+				User:     fgastore.SpaceTypeObjectKey(org.orgID, tc.spaceType),
+				Relation: fgastore.RelationSpaceType,
+				Object:   "space:party",
+			},
+		)
+		require.NoError(t, err)
+		require.Equal(t, tc.want, ok, "%s manages a %s space", tc.user, tc.spaceType)
+	}
+}
+
+func TestRoles_DeleteRefusedWhileGranted(t *testing.T) {
+	ctx := context.Background()
+	_, org := newTestOrg(t)
+	space := habitat_syntax.ConstructSpaceURI(org.orgID, "com.example.event", "party")
+	grant := fgastore.Tuple{
+		User:     fgastore.OrgRoleUsersetString(org.orgID, "organizer"),
+		Relation: fgastore.RelationSpaceWriter,
+		Object:   fgastore.SpaceObjectKey(space),
+	}
+
+	require.NoError(t, org.PutRole(ctx, "organizer", nil))
+	require.NoError(t, org.fga.Write(ctx, grant.User, grant.Relation, grant.Object))
+	require.ErrorIs(t, org.DeleteRole(ctx, "organizer"), ErrRoleInUse)
+	defs, err := org.ListRoles(ctx)
+	require.NoError(t, err)
+	require.Len(t, defs, 1)
+
+	require.NoError(t, org.fga.Delete(ctx, grant.User, grant.Relation, grant.Object))
+	require.NoError(t, org.DeleteRole(ctx, "organizer"))
+	defs, err = org.ListRoles(ctx)
+	require.NoError(t, err)
+	require.Empty(t, defs)
+}
+
+func TestRoles_FGAFailureRollsBack(t *testing.T) {
+	ctx := context.Background()
+	store, org := newTestOrg(t)
+	id := addMember(t, store, org, "alice")
+	require.NoError(t, org.PutRole(ctx, "billing", []Capability{CapabilityIssueInvites}))
+	require.NoError(t, org.AssignRole(ctx, id.DID, "billing"))
+	before, err := org.ListRoles(ctx)
+	require.NoError(t, err)
+
+	org.fga = failingFGA{org.fga}
+	require.Error(t, org.PutRole(ctx, "billing", []Capability{
+		ManageSpacesCapability("com.example.event"),
+	}))
+	require.Error(t, org.PutRole(ctx, "support", nil))
+	require.Error(t, org.UnassignRole(ctx, id.DID, "billing"))
+	require.Error(t, org.DeleteRole(ctx, "billing"))
+
+	after, err := org.ListRoles(ctx)
+	require.NoError(t, err)
+	require.Equal(t, before, after)
+}
+
+func TestRoles_FailedBatchIsUndone(t *testing.T) {
+	ctx := context.Background()
+	_, org := newTestOrg(t)
+	fga := org.fga
+
+	// The org tuple and 100 manager tuples take two batches; the second fails.
+	var capabilities []Capability
+	for i := range maxTuplesPerWrite {
+		capabilities = append(capabilities,
+			ManageSpacesCapability(syntax.NSID(fmt.Sprintf("com.example.type%d", i))))
+	}
+	org.fga = failNthFGA{Store: fga, n: 2, calls: new(int)}
+	require.Error(t, org.PutRole(ctx, "organizer", capabilities))
+
+	defs, err := org.ListRoles(ctx)
+	require.NoError(t, err)
+	require.Empty(t, defs)
+	tuples, err := fga.Read(ctx, fgastore.Tuple{
+		User:   fgastore.OrgRoleUsersetString(org.orgID, "organizer"),
+		Object: fgastore.TypeSpaceType + ":",
+	})
+	require.NoError(t, err)
+	require.Empty(t, tuples)
+	tuples, err = fga.Read(ctx, fgastore.Tuple{
+		User:   fgastore.OrgObjectKey(org.orgID),
+		Object: fgastore.TypeOrgRole + ":",
+	})
+	require.NoError(t, err)
+	require.Empty(t, tuples)
+}
@@ -0,0 +1,263 @@
+package server
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"github.com/habitat-network/habitat/api/habitat"
+	"github.com/habitat-network/habitat/internal/authn"
+	"github.com/habitat-network/habitat/internal/httpx"
+	orgpkg "github.com/habitat-network/habitat/internal/org"
+	"github.com/habitat-network/habitat/internal/perms"
+	"github.com/habitat-network/habitat/internal/spaces"
+	habitat_syntax "github.com/habitat-network/habitat/internal/syntax"
+	"github.com/habitat-network/habitat/internal/utils"
+	"gorm.io/gorm"
+)
+
+var (
+	errNotGroup        = errors.New("not a group")
+	errGroupOtherOrg   = errors.New("group belongs to another org")
+	errGroupNotFound   = errors.New("group not found")
+	errNotGroupManager = errors.New("caller does not manage the group")
+)
+
+// groupAdder adds provisioned members to the org's groups as writers, which
+// is how home records group membership.
+type groupAdder struct {
+	perms  perms.Store
+	spaces spaces.Store
+	org    orgpkg.Org
+	caller syntax.DID
+}
+
+var _ orgpkg.GroupAdder = (*groupAdder)(nil)
+
+// CheckGroup implements [orgpkg.GroupAdder]. The group must be one of the
+// org's, and the caller must be able to add members to it: org admins can add
+// to any group, anyone else must manage it.
+func (g *groupAdder) CheckGroup(ctx context.Context, group habitat_syntax.SpaceURI) error {
+	if group.SpaceType() != habitat_syntax.GroupSpaceType {
+		return errNotGroup
+	}
+	if group.SpaceOwner() != g.org.DID() {
+		return errGroupOtherOrg
+	}
+	if ok, err := g.spaces.CheckSpaceExists(ctx, group); err != nil {
+		return err
+	} else if !ok {
+		return errGroupNotFound
+	}
+	if ok, err := g.org.IsAdmin(ctx, g.caller); err != nil {
+		return err
+	} else if ok {
+		return nil
+	}
+	ok, err := g.perms.CheckUserHasSpaceRole(ctx, g.caller, group, habitat_syntax.SpaceRoleManager)
+	if err != nil {
+		return err
+	} else if !ok {
+		return errNotGroupManager
+	}
+	return nil
+}
+
+// AddToGroup implements [orgpkg.GroupAdder].
+func (g *groupAdder) AddToGroup(
+	ctx context.Context,
+	tx *gorm.DB,
+	did syntax.DID,
+	group habitat_syntax.SpaceURI,
+) error {
+	_, err := g.perms.WithTx(tx).SetUserRelation(ctx, did, group, habitat_syntax.SpaceRoleWriter)
+	return err
+}
+
+func (s *Server) ProvisionMembers(w http.ResponseWriter, r *http.Request) {
+	credInfo, ok := s.validator.Request(
+		authn.WithMethods(authn.ValidatorMethodOAuth),
+	).Validate(w, r)
+	if !ok {
+		return
+	}
+
+	org, err := s.store.GetOrgForDID(r.Context(), credInfo.Subject)
+	if err != nil {
+		utils.LogAndHTTPError(
+			r.Context(),
+			w,
+			err,
+			"getting organization",
+			http.StatusInternalServerError,
+		)
+		return
+	}
+
+	capability := orgpkg.CapabilityMintIdentities
+	if ok, err := org.HasCapability(r.Context(), credInfo.Subject, capability); err != nil {
+		utils.LogAndHTTPError(
+			r.Context(),
+			w,
+			err,
+			"checking capability",
+			http.StatusInternalServerError,
+		)
+		return
+	} else if !ok {
+		httpx.WriteUnauthorized(r.Context(), w, "missing capability "+string(capability))
+		return
+	}
+
+	var req habitat.NetworkHabitatOrgProvisionMembersInput
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.LogAndHTTPError(r.Context(), w, err, "reading request body", http.StatusBadRequest)
+		return
+	}
+
+	var rows []orgpkg.ProvisionRow
+	switch {
+	case req.Csv != "" && len(req.Members) > 0:
+		err = errors.New("set only one of members and csv")
+	case req.Csv != "":
+		rows, err = parseProvisionCSV(req.Csv)
+	default:
+		rows, err = parseProvisionMembers(req.Members)
+	}
+	if err == nil && len(rows) == 0 {
+		err = errors.New("no members to provision")
+	}
+	if err != nil {
+		httpx.WriteError(r.Context(), w, "InvalidInput", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	results, err := s.store.ProvisionMembers(
+		r.Context(),
+		org.DID(),
+		rows,
+		&groupAdder{perms: s.perms, spaces: s.spaces, org: org, caller: credInfo.Subject},
+		req.DryRun,
+	)
+	if errors.Is(err, orgpkg.ErrTooManyRows) {
+		httpx.WriteError(r.Context(), w, "InvalidInput", err.Error(), http.StatusBadRequest)
+		return
+	} else if err != nil {
+		utils.LogAndHTTPError(
+			r.Context(),
+			w,
+			err,
+			"provisioning members",
+			http.StatusInternalServerError,
+		)
+		return
+	}
+
+	output := habitat.NetworkHabitatOrgProvisionMembersOutput{
+		Committed: !req.DryRun,
+		Results:   make([]habitat.NetworkHabitatOrgProvisionMembersResult, len(results)),
+	}
+	for i, res := range results {
+		out := habitat.NetworkHabitatOrgProvisionMembersResult{
+			Row:             int64(res.Row),
+			Handle:          res.Handle,
+			Status:          string(res.Status),
+			Did:             res.DID.String(),
+			InitialPassword: res.InitialPassword,
+		}
+		if res.Err != nil {
+			out.Error = res.Err.Error()
+		}
+		if res.Status != orgpkg.ProvisionCreated {
+			output.Committed = false
+		}
+		output.Results[i] = out
+	}
+	httpx.WriteJSON(r.Context(), w, output)
+}
+
+func parseProvisionMembers(
+	members []habitat.NetworkHabitatOrgProvisionMembersMember,
+) ([]orgpkg.ProvisionRow, error) {
+	rows := make([]orgpkg.ProvisionRow, len(members))
+	for i, m := range members {
+		groups, err := parseGroups(m.Groups)
+		if err != nil {
+			return nil, fmt.Errorf("member %d: %w", i+1, err)
+		}
+		rows[i] = orgpkg.ProvisionRow{Handle: m.Handle, LoginID: m.LoginID, Groups: groups}
+	}
+	return rows, nil
+}
+
+// parseProvisionCSV reads members from CSV with a header row naming the
+// columns handle, loginID and groups. Only handle is required; groups holds
+// space-separated group URIs.
+func parseProvisionCSV(raw string) ([]orgpkg.ProvisionRow, error) {
+	reader := csv.NewReader(strings.NewReader(raw))
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("csv header: %w", err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		name = strings.TrimSpace(name)
+		switch name {
+		case "handle", "loginID", "groups":
+		default:
+			return nil, fmt.Errorf("csv header: unknown column %q", name)
+		}
+		if _, ok := columns[name]; ok {
+			return nil, fmt.Errorf("csv header: duplicate column %q", name)
+		}
+		columns[name] = i
+	}
+	if _, ok := columns["handle"]; !ok {
+		return nil, errors.New("csv header: missing column \"handle\"")
+	}
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var rows []orgpkg.ProvisionRow
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("csv: %w", err)
+		}
+		groups, err := parseGroups(strings.Fields(field(record, "groups")))
+		if err != nil {
+			return nil, fmt.Errorf("csv row %d: %w", line, err)
+		}
+		rows = append(rows, orgpkg.ProvisionRow{
+			Handle:  field(record, "handle"),
+			LoginID: field(record, "loginID"),
+			Groups:  groups,
+		})
+	}
+	return rows, nil
+}
+
+func parseGroups(raw []string) ([]habitat_syntax.SpaceURI, error) {
+	groups := make([]habitat_syntax.SpaceURI, len(raw))
+	for i, g := range raw {
+		uri, err := habitat_syntax.ParseSpaceURI(g)
+		if err != nil {
+			return nil, fmt.Errorf("group %q: %w", g, err)
+		}
+		groups[i] = uri
+	}
+	return groups, nil
+}
@@ -0,0 +1,113 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"github.com/habitat-network/habitat/api/habitat"
+	orgpkg "github.com/habitat-network/habitat/internal/org"
+	habitat_syntax "github.com/habitat-network/habitat/internal/syntax"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseProvisionCSV(t *testing.T) {
+	group := "at://did:plc:org/space/network.habitat.group/eng"
+	rows, err := parseProvisionCSV("loginID,handle,groups\n" +
+		"alice@example.com,alice," + group + "\n" +
+		",bob,\n")
+	require.NoError(t, err)
+	require.Equal(t, []orgpkg.ProvisionRow{
+		{
+			Handle:  "alice",
+			LoginID: "alice@example.com",
+			Groups:  []habitat_syntax.SpaceURI{habitat_syntax.SpaceURI(group)},
+		},
+		{Handle: "bob", Groups: []habitat_syntax.SpaceURI{}},
+	}, rows)
+
+	for _, bad := range []string{
+		"",
+		"loginID\nalice@example.com\n",
+		"handle,email\nalice,a@example.com\n",
+		"handle,groups\nalice,not-a-uri\n",
+		"handle\nalice,extra\n",
+	} {
+		_, err := parseProvisionCSV(bad)
+		require.Error(t, err, bad)
+	}
+}
+
+func TestProvisionMembers(t *testing.T) {
+	srv, store, orgID, _ := newTestServer(t, &fakeInstancePolicy{policy: "open"})
+
+	provision := func(input habitat.NetworkHabitatOrgProvisionMembersInput) (
+		int,
+		habitat.NetworkHabitatOrgProvisionMembersOutput,
+	) {
+		rec := postJSON(t, srv.ProvisionMembers, "network.habitat.org.provisionMembers", input)
+		var out habitat.NetworkHabitatOrgProvisionMembersOutput
+		if rec.Code == http.StatusOK {
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&out))
+		}
+		return rec.Code, out
+	}
+
+	code, out := provision(habitat.NetworkHabitatOrgProvisionMembersInput{
+		Csv:    "handle\nalice\nbob\n",
+		DryRun: true,
+	})
+	require.Equal(t, http.StatusOK, code)
+	require.False(t, out.Committed)
+	require.Len(t, out.Results, 2)
+	require.Equal(t, "valid", out.Results[0].Status)
+
+	// A group of another org fails its row, and so the whole job.
+	code, out = provision(habitat.NetworkHabitatOrgProvisionMembersInput{
+		Members: []habitat.NetworkHabitatOrgProvisionMembersMember{
+			{Handle: "alice"},
+			{
+				Handle: "bob",
+				Groups: []string{"at://did:plc:other/space/network.habitat.group/eng"},
+			},
+		},
+	})
+	require.Equal(t, http.StatusOK, code)
+	require.False(t, out.Committed)
+	require.Equal(t, "skipped", out.Results[0].Status)
+	require.Equal(t, "failed", out.Results[1].Status)
+	require.Contains(t, out.Results[1].Error, errGroupOtherOrg.Error())
+
+	code, out = provision(habitat.NetworkHabitatOrgProvisionMembersInput{
+		Csv: "handle\nalice\nbob\n",
+	})
+	require.Equal(t, http.StatusOK, code)
+	require.True(t, out.Committed)
+	for _, res := range out.Results {
+		require.Equal(t, "created", res.Status)
+		require.NotEmpty(t, res.InitialPassword)
+		did, err := syntax.ParseDID(res.Did)
+		require.NoError(t, err)
+		member, err := store.GetMember(t.Context(), did)
+		require.NoError(t, err)
+		require.Equal(t, orgID, member.Org.DID())
+	}
+
+	code, _ = provision(habitat.NetworkHabitatOrgProvisionMembersInput{})
+	require.Equal(t, http.StatusBadRequest, code)
+	code, _ = provision(habitat.NetworkHabitatOrgProvisionMembersInput{
+		Csv:     "handle\ncarol\n",
+		Members: []habitat.NetworkHabitatOrgProvisionMembersMember{{Handle: "dave"}},
+	})
+	require.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestProvisionMembers_RequiresCapability(t *testing.T) {
+	_, store, orgID, adminDID := newTestServer(t, &fakeInstancePolicy{policy: "open"})
+	memberSrv, _ := newMemberServer(t, store, orgID, adminDID)
+
+	rec := postJSON(t, memberSrv.ProvisionMembers, "network.habitat.org.provisionMembers",
+		habitat.NetworkHabitatOrgProvisionMembersInput{Csv: "handle\nalice\n"})
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+}
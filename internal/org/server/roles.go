@@ -0,0 +1,214 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/habitat-network/habitat/api/habitat"
+	"github.com/habitat-network/habitat/internal/authn"
+	"github.com/habitat-network/habitat/internal/httpx"
+	orgpkg "github.com/habitat-network/habitat/internal/org"
+	"github.com/habitat-network/habitat/internal/utils"
+)
+
+// adminOrg authenticates the caller and returns their org, writing an error
+// response and returning false unless they are one of its admins.
+func (s *Server) adminOrg(w http.ResponseWriter, r *http.Request) (orgpkg.Org, bool) {
+	credInfo, ok := s.validator.Request(
+		authn.WithMethods(authn.ValidatorMethodOAuth),
+	).Validate(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	org, err := s.store.GetOrgForDID(r.Context(), credInfo.Subject)
+	if err != nil {
+		utils.LogAndHTTPError(
+			r.Context(),
+			w,
+			err,
+			"getting organization",
+			http.StatusInternalServerError,
+		)
+		return nil, false
+	}
+
+	if ok, err := org.IsAdmin(r.Context(), credInfo.Subject); err != nil {
+		utils.LogAndHTTPError(
+			r.Context(),
+			w,
+			err,
+			"checking IsAdmin",
+			http.StatusInternalServerError,
+		)
+		return nil, false
+	} else if !ok {
+		httpx.WriteUnauthorized(r.Context(), w, orgpkg.ErrNotAdmin.Error())
+		return nil, false
+	}
+	return org, true
+}
+
+func (s *Server) PutRole(w http.ResponseWriter, r *http.Request) {
+	org, ok := s.adminOrg(w, r)
+	if !ok {
+		return
+	}
+
+	var req habitat.NetworkHabitatOrgPutRoleInput
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.LogAndHTTPError(r.Context(), w, err, "reading request body", http.StatusBadRequest)
+		return
+	}
+
+	role, err := orgpkg.ParseRoleName(req.Name)
+	if err != nil {
+		httpx.WriteError(r.Context(), w, "InvalidRoleName", err.Error(), http.StatusBadRequest)
+		return
+	}
+	capabilities := make([]orgpkg.Capability, 0, len(req.Capabilities))
+	for _, raw := range req.Capabilities {
+		c, err := orgpkg.ParseCapability(raw)
+		if err != nil {
+			httpx.WriteError(
+				r.Context(),
+				w,
+				"InvalidCapability",
+				err.Error(),
+				http.StatusBadRequest,
+			)
+			return
+		}
+		capabilities = append(capabilities, c)
+	}
+
+	if err := org.PutRole(r.Context(), role, capabilities); err != nil {
+		utils.LogAndHTTPError(r.Context(), w, err, "putting role", http.StatusInternalServerError)
+	}
+}
+
+func (s *Server) DeleteRole(w http.ResponseWriter, r *http.Request) {
+	org, ok := s.adminOrg(w, r)
+	if !ok {
+		return
+	}
+
+	var req habitat.NetworkHabitatOrgDeleteRoleInput
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.LogAndHTTPError(r.Context(), w, err, "reading request body", http.StatusBadRequest)
+		return
+	}
+
+	err := org.DeleteRole(r.Context(), orgpkg.Role(req.Name))
+	switch {
+	case errors.Is(err, orgpkg.ErrRoleNotFound):
+		httpx.WriteError(r.Context(), w, "RoleNotFound", err.Error(), http.StatusNotFound)
+	case errors.Is(err, orgpkg.ErrRoleInUse):
+		httpx.WriteError(r.Context(), w, "RoleInUse", err.Error(), http.StatusConflict)
+	case err != nil:
+		utils.LogAndHTTPError(r.Context(), w, err, "deleting role", http.StatusInternalServerError)
+	}
+}
+
+func (s *Server) ListRoles(w http.ResponseWriter, r *http.Request) {
+	credInfo, ok := s.validator.Request(
+		authn.WithMethods(authn.ValidatorMethodOAuth),
+	).Validate(w, r)
+	if !ok {
+		return
+	}
+
+	org, err := s.store.GetOrgForDID(r.Context(), credInfo.Subject)
+	if err != nil {
+		utils.LogAndHTTPError(
+			r.Context(),
+			w,
+			err,
+			"getting organization",
+			http.StatusInternalServerError,
+		)
+		return
+	}
+
+	defs, err := org.ListRoles(r.Context())
+	if err != nil {
+		utils.LogAndHTTPError(r.Context(), w, err, "listing roles", http.StatusInternalServerError)
+		return
+	}
+
+	roles := make([]habitat.NetworkHabitatOrgListRolesRole, len(defs))
+	for i, def := range defs {
+		capabilities := make([]string, len(def.Capabilities))
+		for j, c := range def.Capabilities {
+			capabilities[j] = string(c)
+		}
+		assignees := make([]string, len(def.Assignees))
+		for j, did := range def.Assignees {
+			assignees[j] = did.String()
+		}
+		roles[i] = habitat.NetworkHabitatOrgListRolesRole{
+			Name:         string(def.Name),
+			Capabilities: capabilities,
+			Assignees:    assignees,
+		}
+	}
+
+	httpx.WriteJSON(r.Context(), w, &habitat.NetworkHabitatOrgListRolesOutput{
+		Roles: roles,
+	})
+}
+
+func (s *Server) AssignRole(w http.ResponseWriter, r *http.Request) {
+	org, ok := s.adminOrg(w, r)
+	if !ok {
+		return
+	}
+
+	var req habitat.NetworkHabitatOrgAssignRoleInput
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.LogAndHTTPError(r.Context(), w, err, "reading request body", http.StatusBadRequest)
+		return
+	}
+	did, ok := httpx.ParseDIDInput(r.Context(), w, req.Did, "did")
+	if !ok {
+		return
+	}
+
+	err := org.AssignRole(r.Context(), did, orgpkg.Role(req.Role))
+	switch {
+	case errors.Is(err, orgpkg.ErrRoleNotFound):
+		httpx.WriteError(r.Context(), w, "RoleNotFound", err.Error(), http.StatusNotFound)
+	case errors.Is(err, orgpkg.ErrNotMember):
+		httpx.WriteError(r.Context(), w, "NotMember", err.Error(), http.StatusBadRequest)
+	case err != nil:
+		utils.LogAndHTTPError(r.Context(), w, err, "assigning role", http.StatusInternalServerError)
+	}
+}
+
+func (s *Server) UnassignRole(w http.ResponseWriter, r *http.Request) {
+	org, ok := s.adminOrg(w, r)
+	if !ok {
+		return
+	}
+
+	var req habitat.NetworkHabitatOrgUnassignRoleInput
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.LogAndHTTPError(r.Context(), w, err, "reading request body", http.StatusBadRequest)
+		return
+	}
+	did, ok := httpx.ParseDIDInput(r.Context(), w, req.Did, "did")
+	if !ok {
+		return
+	}
+
+	if err := org.UnassignRole(r.Context(), did, orgpkg.Role(req.Role)); err != nil {
+		utils.LogAndHTTPError(
+			r.Context(),
+			w,
+			err,
+			"unassigning role",
+			http.StatusInternalServerError,
+		)
+	}
+}
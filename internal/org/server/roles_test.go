@@ -0,0 +1,137 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bluesky-social/indigo/atproto/identity"
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"github.com/habitat-network/habitat/api/habitat"
+	orgpkg "github.com/habitat-network/habitat/internal/org"
+	"github.com/stretchr/testify/require"
+)
+
+func postJSON(
+	t *testing.T,
+	handler http.HandlerFunc,
+	nsid string,
+	input any,
+) *httptest.ResponseRecorder {
+	t.Helper()
+	body, err := json.Marshal(input)
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodPost, "/xrpc/"+nsid, bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	return rec
+}
+
+// newMemberServer adds a member to the org and returns a server that
+// authenticates requests as that member.
+func newMemberServer(
+	t *testing.T,
+	store orgpkg.Store,
+	orgID syntax.DID,
+	adminDID syntax.DID,
+) (*Server, syntax.DID) {
+	t.Helper()
+	token, err := store.IssueIdentityToken(
+		t.Context(),
+		orgID,
+		adminDID,
+		false,
+		time.Now().Add(time.Hour),
+	)
+	require.NoError(t, err)
+	id, err := store.CreateNewMemberIdentity(t.Context(), orgID, token, "bob", "password", "")
+	require.NoError(t, err)
+
+	srv, err := NewServer(
+		store,
+		successValidator(id.DID),
+		"pear.example.com",
+		identity.DefaultDirectory(),
+		&fakeInstancePolicy{policy: "open"},
+		nil,
+		nil,
+	)
+	require.NoError(t, err)
+	return srv, id.DID
+}
+
+func TestIssueInviteToken_RequiresCapability(t *testing.T) {
+	srv, store, orgID, adminDID := newTestServer(t, &fakeInstancePolicy{policy: "open"})
+	memberSrv, memberDID := newMemberServer(t, store, orgID, adminDID)
+
+	issue := func(reusable bool) int {
+		return postJSON(t, memberSrv.IssueInviteToken, "network.habitat.org.issueInviteToken",
+			habitat.NetworkHabitatOrgIssueInviteTokenInput{Reusable: reusable}).Code
+	}
+	require.Equal(t, http.StatusUnauthorized, issue(false))
+
+	rec := postJSON(t, srv.PutRole, "network.habitat.org.putRole",
+		habitat.NetworkHabitatOrgPutRoleInput{
+			Name:         "recruiter",
+			Capabilities: []string{"issueInvites"},
+		})
+	require.Equal(t, http.StatusOK, rec.Code)
+	rec = postJSON(t, srv.AssignRole, "network.habitat.org.assignRole",
+		habitat.NetworkHabitatOrgAssignRoleInput{Did: memberDID.String(), Role: "recruiter"})
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	require.Equal(t, http.StatusOK, issue(false))
+	require.Equal(t, http.StatusUnauthorized, issue(true), "reusable needs mintIdentities")
+}
+
+func TestRoles_OnlyAdminsManageRoles(t *testing.T) {
+	_, store, orgID, adminDID := newTestServer(t, &fakeInstancePolicy{policy: "open"})
+	memberSrv, memberDID := newMemberServer(t, store, orgID, adminDID)
+
+	rec := postJSON(t, memberSrv.PutRole, "network.habitat.org.putRole",
+		habitat.NetworkHabitatOrgPutRoleInput{Name: "moderator"})
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+	rec = postJSON(t, memberSrv.AssignRole, "network.habitat.org.assignRole",
+		habitat.NetworkHabitatOrgAssignRoleInput{Did: memberDID.String(), Role: "moderator"})
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestPutRole_Validation(t *testing.T) {
+	srv, _, _, adminDID := newTestServer(t, &fakeInstancePolicy{policy: "open"})
+
+	rec := postJSON(t, srv.PutRole, "network.habitat.org.putRole",
+		habitat.NetworkHabitatOrgPutRoleInput{Name: "admin"})
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.Contains(t, rec.Body.String(), "InvalidRoleName")
+
+	rec = postJSON(t, srv.PutRole, "network.habitat.org.putRole",
+		habitat.NetworkHabitatOrgPutRoleInput{Name: "mod", Capabilities: []string{"everything"}})
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.Contains(t, rec.Body.String(), "InvalidCapability")
+
+	rec = postJSON(t, srv.AssignRole, "network.habitat.org.assignRole",
+		habitat.NetworkHabitatOrgAssignRoleInput{Did: adminDID.String(), Role: "mod"})
+	require.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = postJSON(t, srv.PutRole, "network.habitat.org.putRole",
+		habitat.NetworkHabitatOrgPutRoleInput{
+			Name:         "organizer",
+			Capabilities: []string{"manageSpaces:com.example.event"},
+		})
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	req := httptest.NewRequest(http.MethodGet, "/xrpc/network.habitat.org.listRoles", nil)
+	listRec := httptest.NewRecorder()
+	srv.ListRoles(listRec, req)
+	require.Equal(t, http.StatusOK, listRec.Code)
+	var out habitat.NetworkHabitatOrgListRolesOutput
+	require.NoError(t, json.NewDecoder(listRec.Body).Decode(&out))
+	require.Len(t, out.Roles, 1)
+	require.Equal(t, "organizer", out.Roles[0].Name)
+	require.Equal(t, []string{"manageSpaces:com.example.event"}, out.Roles[0].Capabilities)
+	require.Empty(t, out.Roles[0].Assignees)
+}
@@ -18,6 +18,8 @@ import (
 	"github.com/habitat-network/habitat/internal/httpx"
 	"github.com/habitat-network/habitat/internal/instance"
 	orgpkg "github.com/habitat-network/habitat/internal/org"
+	"github.com/habitat-network/habitat/internal/perms"
+	"github.com/habitat-network/habitat/internal/spaces"
 	"github.com/habitat-network/habitat/internal/utils"
 )
 
@@ -30,6 +32,8 @@ type Server struct {
 	decoder        *schema.Decoder
 	dir            identity.Directory
 	instancePolicy instance.PolicyStore
+	perms          perms.Store
+	spaces         spaces.Store
 }
 
 func NewServer(
@@ -38,6 +42,8 @@ func NewServer(
 	domain string,
 	dir identity.Directory,
 	instancePolicy instance.PolicyStore,
+	perms perms.Store,
+	spaces spaces.Store,
 ) (*Server, error) {
 	return &Server{
 		store:          store,
@@ -46,6 +52,8 @@ func NewServer(
 		decoder:        schema.NewDecoder(),
 		dir:            dir,
 		instancePolicy: instancePolicy,
+		perms:          perms,
+		spaces:         spaces,
 	}, nil
 }
 
@@ -612,24 +620,30 @@ func (s *Server) IssueInviteToken(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if ok, err := org.IsAdmin(r.Context(), credInfo.Subject); !ok {
-		utils.LogAndHTTPError(r.Context(), w, err, "not authorized", http.StatusUnauthorized)
+	var req habitat.NetworkHabitatOrgIssueInviteTokenInput
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		utils.LogAndHTTPError(r.Context(), w, err, "reading request body", http.StatusBadRequest)
 		return
-	} else if err != nil {
+	}
+
+	// A reusable token can mint any number of identities, so it needs more than
+	// the right to invite.
+	capability := orgpkg.CapabilityIssueInvites
+	if req.Reusable {
+		capability = orgpkg.CapabilityMintIdentities
+	}
+	if ok, err := org.HasCapability(r.Context(), credInfo.Subject, capability); err != nil {
 		utils.LogAndHTTPError(
 			r.Context(),
 			w,
 			err,
-			"checking IsAdmin",
+			"checking capability",
 			http.StatusInternalServerError,
 		)
 		return
-	}
-
-	var req habitat.NetworkHabitatOrgIssueInviteTokenInput
-	err = json.NewDecoder(r.Body).Decode(&req)
-	if err != nil {
-		utils.LogAndHTTPError(r.Context(), w, err, "reading request body", http.StatusBadRequest)
+	} else if !ok {
+		httpx.WriteUnauthorized(r.Context(), w, "missing capability "+string(capability))
 		return
 	}
 
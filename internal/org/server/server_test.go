@@ -78,6 +78,8 @@ func newTestServer(
 		"pear.example.com",
 		identity.DefaultDirectory(),
 		policy,
+		nil,
+		nil,
 	)
 	require.NoError(t, err)
 	return srv, store, orgIdIdent.DID, adminIdent.DID
@@ -210,6 +212,8 @@ func newCreateTestServer(t *testing.T) *Server {
 		"domain",
 		identity.DefaultDirectory(),
 		&fakeInstancePolicy{policy: "open"},
+		nil,
+		nil,
 	)
 	require.NoError(t, err)
 	return srv
@@ -343,6 +347,8 @@ func TestCreateOrg_OpenPolicyIgnoresMissingToken(t *testing.T) {
 		"pear.example.com",
 		identity.DefaultDirectory(),
 		&fakeInstancePolicy{policy: "open"},
+		nil,
+		nil,
 	)
 	require.NoError(t, err)
 
@@ -372,6 +378,8 @@ func TestCreateOrg_InviteOnlyRejectsMissingToken(t *testing.T) {
 		"pear.example.com",
 		identity.DefaultDirectory(),
 		&fakeInstancePolicy{policy: "invite_only"},
+		nil,
+		nil,
 	)
 	require.NoError(t, err)
 
@@ -401,6 +409,8 @@ func TestCreateOrg_InviteOnlyRejectsInvalidToken(t *testing.T) {
 		"pear.example.com",
 		identity.DefaultDirectory(),
 		&fakeInstancePolicy{policy: "invite_only", validateErr: errors.New("bad token")},
+		nil,
+		nil,
 	)
 	require.NoError(t, err)
 
@@ -432,6 +442,8 @@ func TestCreateOrg_InviteOnlyAcceptsValidToken(t *testing.T) {
 		"pear.example.com",
 		identity.DefaultDirectory(),
 		policy,
+		nil,
+		nil,
 	)
 	require.NoError(t, err)
 
@@ -466,6 +478,8 @@ func TestCreateOrg_InviteOnlyDoesNotMarkUsedOnCreateFailure(t *testing.T) {
 		"pear.example.com",
 		identity.DefaultDirectory(),
 		policy,
+		nil,
+		nil,
 	)
 	require.NoError(t, err)
 
@@ -530,6 +544,8 @@ func TestCreateOrg_InviteOnlyAcceptsRealIssuedToken(t *testing.T) {
 		"pear.example.com",
 		identity.DefaultDirectory(),
 		instanceStore,
+		nil,
+		nil,
 	)
 	require.NoError(t, err)
 
@@ -67,6 +67,13 @@ type Store interface {
 		password string,
 		loginID string,
 	) (*identity.Identity, error)
+	ProvisionMembers(
+		ctx context.Context,
+		orgDID syntax.DID,
+		rows []ProvisionRow,
+		groups GroupAdder,
+		dryRun bool,
+	) ([]ProvisionResult, error)
 }
 
 // storeImpl is the Store implementation backed by gorm and the identity directory.
@@ -90,7 +97,13 @@ func NewStore(
 	fga fgastore.Store,
 	everyoneOrg *everyoneOrg,
 ) (Store, error) {
-	if err := db.AutoMigrate(&organization{}, &member{}, &spentToken{}); err != nil {
+	if err := db.AutoMigrate(
+		&organization{},
+		&member{},
+		&spentToken{},
+		&orgRole{},
+		&roleAssignment{},
+	); err != nil {
 		return nil, err
 	}
 	return &storeImpl{
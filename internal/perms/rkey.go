@@ -14,7 +14,7 @@ import (
 // hashing: this is safe only because none of our inputs (DIDs, space URIs,
 // role strings) can themselves contain a NUL byte, so no two distinct
 // part-sequences can ever collide on the same joined byte string. A leading
-// type tag (e.g. "user"/"space") disambiguates the two relation kinds.
+// type tag (e.g. "user"/"space"/"orgRole") disambiguates the relation kinds.
 func hashRkey(parts ...string) syntax.RecordKey {
 	h := sha256.New()
 	h.Write([]byte(strings.Join(parts, "\x00")))
@@ -42,3 +42,9 @@ func spaceRelationRkey(
 ) syntax.RecordKey {
 	return hashRkey("space", subject.String(), string(subjectRole))
 }
+
+// orgRoleRelationRkey deterministically derives the record key for an
+// (org, role) org-role-relation record
+func orgRoleRelationRkey(org syntax.DID, role string) syntax.RecordKey {
+	return hashRkey("orgRole", org.String(), role)
+}
@@ -42,6 +42,16 @@ type Store interface {
 		object habitat_syntax.SpaceURI,
 		objectRole habitat_syntax.SpaceRole,
 	) (habitat_syntax.SpaceRecordURI, error)
+	// Adds an org role relation (collection =
+	// network.habitat.relationship.orgRoleRelation) granting everyone holding a
+	// custom org role objectRole on object, and returns the record uri.
+	SetOrgRoleRelation(
+		ctx context.Context,
+		org syntax.DID,
+		role string,
+		object habitat_syntax.SpaceURI,
+		objectRole habitat_syntax.SpaceRole,
+	) (habitat_syntax.SpaceRecordURI, error)
 
 	// Revocations
 	RevokeUser(
@@ -55,9 +65,16 @@ type Store interface {
 		subjectRole habitat_syntax.SpaceRole,
 		objectSpace habitat_syntax.SpaceURI,
 	) error
+	RevokeOrgRole(
+		ctx context.Context,
+		org syntax.DID,
+		role string,
+		objectSpace habitat_syntax.SpaceURI,
+	) error
 
-	// DeleteRelation removes the relation record at uri (either a userRelation
-	// or a spaceRelation record) from both the governing space and FGA.
+	// DeleteRelation removes the relation record at uri (a userRelation,
+	// spaceRelation or orgRoleRelation record) from both the governing space
+	// and FGA.
 	DeleteRelation(ctx context.Context, uri habitat_syntax.SpaceRecordURI) error
 	UnsafeRevokeAllSpaceRoles(ctx context.Context, space habitat_syntax.SpaceURI) error
 
@@ -75,6 +92,13 @@ type Store interface {
 		objectSpace habitat_syntax.SpaceURI,
 		objectRole habitat_syntax.SpaceRole,
 	) (bool, error)
+	CheckOrgRoleHasSpaceRole(
+		ctx context.Context,
+		org syntax.DID,
+		role string,
+		objectSpace habitat_syntax.SpaceURI,
+		objectRole habitat_syntax.SpaceRole,
+	) (bool, error)
 
 	// List various things using relations
 	ListUserSubjects(
@@ -244,6 +268,70 @@ func (s *store) SetSpaceRoleRelation(
 	return uri, err
 }
 
+// SetOrgRoleRelation implements [Store]. It grants everyone holding the custom
+// role of org (including the org's admins, who hold every role) objectRole on
+// object.
+func (s *store) SetOrgRoleRelation(
+	ctx context.Context,
+	org syntax.DID,
+	role string,
+	object habitat_syntax.SpaceURI,
+	objectRole habitat_syntax.SpaceRole,
+) (habitat_syntax.SpaceRecordURI, error) {
+	var uri habitat_syntax.SpaceRecordURI
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		record := map[string]any{
+			"org":       org.String(),
+			"role":      role,
+			"relation":  string(objectRole),
+			"createdAt": time.Now().UTC().Format(time.RFC3339),
+			/* object is the space being written into itself */
+		}
+		var err error
+		uri, _, err = s.spaces.WithTx(tx).
+			PutRecord(ctx, object, object.SpaceOwner(), habitat_syntax.OrgRoleRelationCollection, orgRoleRelationRkey(org, role), record)
+		if err != nil {
+			return fmt.Errorf("err putting relationship record: %w", err)
+		}
+
+		userset := fgastore.OrgRoleUsersetString(org, role)
+
+		// As for space relations, leave exactly one objectRole in place.
+		var deletes []*openfgav1.TupleKeyWithoutCondition
+		for otherRole, relation := range fgaRelationFromRole {
+			if otherRole == objectRole {
+				continue
+			}
+			deletes = append(deletes, tuple.TupleKeyToTupleKeyWithoutCondition(
+				tuple.NewTupleKey(fgastore.SpaceObjectKey(object), relation, userset),
+			))
+		}
+
+		err = s.fga.WriteRaw(ctx, &openfgav1.WriteRequest{
+			Writes: &openfgav1.WriteRequestWrites{
+				TupleKeys: []*openfgav1.TupleKey{
+					tuple.NewTupleKey(
+						fgastore.SpaceObjectKey(object),
+						fgaRelationFromRole[objectRole],
+						userset,
+					),
+				},
+				OnDuplicate: "ignore",
+			},
+			Deletes: &openfgav1.WriteRequestDeletes{
+				TupleKeys: deletes,
+				OnMissing: "ignore",
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("err writing to fga: %w", err)
+		}
+		return nil
+	})
+
+	return uri, err
+}
+
 // RevokeUserRelation implements [Store].
 func (s *store) RevokeUser(
 	ctx context.Context,
@@ -325,6 +413,42 @@ func (s *store) RevokeSpaceRole(
 	})
 }
 
+// RevokeOrgRole implements [Store].
+func (s *store) RevokeOrgRole(
+	ctx context.Context,
+	org syntax.DID,
+	role string,
+	objectSpace habitat_syntax.SpaceURI,
+) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		collection := syntax.NSID(habitat_syntax.OrgRoleRelationCollection)
+		rkey := orgRoleRelationRkey(org, role)
+		if err := s.spaces.WithTx(tx).
+			DeleteRecord(ctx, objectSpace, objectSpace.SpaceOwner(), collection, rkey.String()); err != nil {
+			return fmt.Errorf("err deleting relationship record: %w", err)
+		}
+
+		userset := fgastore.OrgRoleUsersetString(org, role)
+		deletes := make([]*openfgav1.TupleKeyWithoutCondition, 0, len(fgaRelationFromRole))
+		for _, relation := range fgaRelationFromRole {
+			deletes = append(deletes, tuple.TupleKeyToTupleKeyWithoutCondition(
+				tuple.NewTupleKey(fgastore.SpaceObjectKey(objectSpace), relation, userset),
+			))
+		}
+
+		err := s.fga.WriteRaw(ctx, &openfgav1.WriteRequest{
+			Deletes: &openfgav1.WriteRequestDeletes{
+				TupleKeys: deletes,
+				OnMissing: "ignore",
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("err writing to fga: %w", err)
+		}
+		return nil
+	})
+}
+
 // UnsafeRevokeAllhabitat_syntax.SpaceRoles implements [Store]. It reads back every tuple stored
 // against space, so it doesn't need to know which roles/subjects exist, then
 // deletes them all, along with every relationship record persisted into the
@@ -342,7 +466,7 @@ func (s *store) UnsafeRevokeAllSpaceRoles(
 	}
 
 	return s.db.Transaction(func(tx *gorm.DB) error {
-		for _, collection := range []syntax.NSID{habitat_syntax.UserRelationCollection, habitat_syntax.SpaceRelationCollection} {
+		for _, collection := range []syntax.NSID{habitat_syntax.UserRelationCollection, habitat_syntax.SpaceRelationCollection, habitat_syntax.OrgRoleRelationCollection} {
 			records, err := s.spaces.WithTx(tx).
 				ListRecords(ctx, space, space.SpaceOwner(), &collection)
 			if err != nil {
@@ -394,6 +518,7 @@ func (s *store) CheckUserHasSpaceRole(
 		fgaRelationFromRole[role],
 		fgastore.SpaceObjectKey(space),
 		fgastore.OwnerContextualTuple(space),
+		fgastore.SpaceTypeContextualTuple(space),
 	)
 }
 
@@ -412,6 +537,24 @@ func (s *store) CheckSpaceRelationHasSpaceRole(
 		fgaRelationFromRole[objectRole],
 		fgastore.SpaceObjectKey(objectSpace),
 		fgastore.OwnerContextualTuple(objectSpace),
+		fgastore.SpaceTypeContextualTuple(objectSpace),
+	)
+}
+
+func (s *store) CheckOrgRoleHasSpaceRole(
+	ctx context.Context,
+	org syntax.DID,
+	role string,
+	objectSpace habitat_syntax.SpaceURI,
+	objectRole habitat_syntax.SpaceRole,
+) (bool, error) {
+	return s.fga.Check(
+		ctx,
+		fgastore.OrgRoleUsersetString(org, role),
+		fgaRelationFromRole[objectRole],
+		fgastore.SpaceObjectKey(objectSpace),
+		fgastore.OwnerContextualTuple(objectSpace),
+		fgastore.SpaceTypeContextualTuple(objectSpace),
 	)
 }
 
@@ -429,6 +572,7 @@ func (s *store) ListUserSubjects(
 		fgastore.SpaceObjectKey(space),
 		fgaRelationFromRole[role],
 		fgastore.OwnerContextualTuple(space),
+		fgastore.SpaceTypeContextualTuple(space),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("perms: list user subjects: %w", err)
@@ -520,6 +664,18 @@ func (s *store) DeleteRelation(ctx context.Context, uri habitat_syntax.SpaceReco
 		}
 		return nil
 
+	case habitat_syntax.OrgRoleRelationCollection:
+		orgStr, _ := record.Value["org"].(string)
+		role, _ := record.Value["role"].(string)
+		org, err := syntax.ParseDID(orgStr)
+		if err != nil {
+			return fmt.Errorf("perms: invalid org did in relation record: %w", err)
+		}
+		if err := s.RevokeOrgRole(ctx, org, role, space); err != nil {
+			return fmt.Errorf("revoking org role relation: %w", err)
+		}
+		return nil
+
 	default:
 	}
 	return ErrRelationNotFound
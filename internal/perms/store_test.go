@@ -377,6 +377,63 @@ func TestStoreSpaceRoleRelation(t *testing.T) {
 	})
 }
 
+func TestStoreOrgRoleRelation(t *testing.T) {
+	s := newTestStore(t)
+	ctx := t.Context()
+	doc := newSpace(t, s.spaces, docsType, "doc1")
+	require.NoError(t, s.fga.Write(
+		ctx,
+		fgastore.MemberUserString(alice),
+		fgastore.RelationOrgRoleAssignee,
+		fgastore.OrgRoleObjectKey(org, "moderator"),
+	))
+
+	uri, err := s.SetOrgRoleRelation(ctx, org, "moderator", doc, habitat_syntax.SpaceRoleWriter)
+	require.NoError(t, err)
+	require.Equal(t, habitat_syntax.OrgRoleRelationCollection, uri.Collection())
+	ok, err := s.CheckUserHasSpaceRole(ctx, alice, doc, habitat_syntax.SpaceRoleWriter)
+	require.NoError(t, err)
+	require.True(t, ok, "holders of the org role write the space")
+	ok, err = s.CheckUserHasSpaceRole(ctx, bob, doc, habitat_syntax.SpaceRoleReader)
+	require.NoError(t, err)
+	require.False(t, ok)
+	ok, err = s.CheckOrgRoleHasSpaceRole(ctx, org, "moderator", doc, habitat_syntax.SpaceRoleWriter)
+	require.NoError(t, err)
+	require.True(t, ok)
+	ok, err = s.CheckOrgRoleHasSpaceRole(ctx, org, "moderator", doc, habitat_syntax.SpaceRoleOwner)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	require.NoError(t, s.DeleteRelation(ctx, uri))
+	ok, err = s.CheckUserHasSpaceRole(ctx, alice, doc, habitat_syntax.SpaceRoleReader)
+	require.NoError(t, err)
+	require.False(t, ok)
+}
+
+func TestStoreSpaceTypeManagers(t *testing.T) {
+	s := newTestStore(t)
+	ctx := t.Context()
+	group := newSpace(t, s.spaces, groupType, "team")
+	doc := newSpace(t, s.spaces, docsType, "doc1")
+	require.NoError(t, s.fga.Write(
+		ctx,
+		fgastore.MemberUserString(alice),
+		fgastore.RelationSpaceTypeManager,
+		fgastore.SpaceTypeObjectKey(org, groupType),
+	))
+
+	ok, err := s.CheckUserHasSpaceRole(ctx, alice, group, habitat_syntax.SpaceRoleManager)
+	require.NoError(t, err)
+	require.True(t, ok, "managers of the org's group spaces manage each group")
+	ok, err = s.CheckUserHasSpaceRole(ctx, alice, doc, habitat_syntax.SpaceRoleManager)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	managers, err := s.ListUserSubjects(ctx, group, habitat_syntax.SpaceRoleManager)
+	require.NoError(t, err)
+	require.Contains(t, managers, alice)
+}
+
 func TestStoreDeleteRelation(t *testing.T) {
 	s := newTestStore(t)
 	ctx := t.Context()
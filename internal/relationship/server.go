@@ -14,6 +14,7 @@ import (
 	"github.com/habitat-network/habitat/api/habitat"
 	"github.com/habitat-network/habitat/internal/authn"
 	"github.com/habitat-network/habitat/internal/httpx"
+	"github.com/habitat-network/habitat/internal/org"
 	"github.com/habitat-network/habitat/internal/perms"
 	"github.com/habitat-network/habitat/internal/spaces"
 	habitat_syntax "github.com/habitat-network/habitat/internal/syntax"
@@ -153,6 +154,67 @@ func (s *Server) SetSpaceRelation(w http.ResponseWriter, r *http.Request) {
 		habitat.NetworkHabitatRelationshipSetSpaceRelationOutput{Uri: uri.String()})
 }
 
+// SetOrgRoleRelation grants a role on a space to everyone holding a custom org
+// role. The role need not exist yet: the grant applies once it is defined and
+// assigned.
+func (s *Server) SetOrgRoleRelation(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	var input habitat.NetworkHabitatRelationshipSetOrgRoleRelationInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		httpx.WriteInvalidRequest(ctx, w, "failed to decode request body", err)
+		return
+	}
+	orgDID, ok := httpx.ParseDIDInput(ctx, w, input.Org, "org")
+	if !ok {
+		return
+	}
+	space, ok := httpx.ParseSpaceURIInput(ctx, w, input.Space, "space")
+	if !ok {
+		return
+	}
+	credInfo, ok := s.validator.Request(
+		authn.WithMethods(authn.ValidatorMethodOAuth, authn.ValidatorMethodServiceAuth),
+		authn.WithSpace(space, habitat_syntax.SpaceRoleManager),
+	).Validate(w, r)
+	if !ok {
+		return
+	}
+	orgRole, err := org.ParseRoleName(input.Role)
+	if err != nil {
+		httpx.WriteError(ctx, w, "InvalidRelation", err.Error(), http.StatusBadRequest)
+		return
+	}
+	role, err := parseSpaceRole(input.Relation)
+	if err != nil {
+		httpx.WriteError(ctx, w, "InvalidRelation", err.Error(), http.StatusBadRequest)
+		return
+	}
+	isSubjectCurrentlyOwner, err := s.perms.CheckOrgRoleHasSpaceRole(
+		ctx,
+		orgDID,
+		string(orgRole),
+		space,
+		habitat_syntax.SpaceRoleOwner,
+	)
+	if err != nil {
+		httpx.WriteServerError(ctx, w, fmt.Errorf("check subject is owner: %w", err))
+		return
+	}
+	if !s.authorizeCanWrite(ctx, w, credInfo, isSubjectCurrentlyOwner, space, role) {
+		return
+	}
+	uri, err := s.perms.SetOrgRoleRelation(ctx, orgDID, string(orgRole), space, role)
+	if errors.Is(err, spaces.ErrSpaceNotFound) {
+		httpx.WriteSpaceNotFound(ctx, w, err)
+		return
+	} else if err != nil {
+		httpx.WriteServerError(ctx, w, fmt.Errorf("add org role relation: %w", err))
+		return
+	}
+	httpx.WriteJSON(ctx, w,
+		habitat.NetworkHabitatRelationshipSetOrgRoleRelationOutput{Uri: uri.String()})
+}
+
 func (s *Server) DeleteRelation(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	var input habitat.NetworkHabitatRelationshipDeleteRelationInput
@@ -369,14 +431,16 @@ func (s *Server) ListRelations(w http.ResponseWriter, r *http.Request) {
 	).Validate(w, r); !ok {
 		return
 	}
-	if params.SubjectType != "" && params.SubjectType != "user" && params.SubjectType != "space" {
+	switch params.SubjectType {
+	case "", "user", "space", "orgRole":
+	default:
 		httpx.WriteInvalidRequest(ctx, w, "invalid subjectType", nil)
 		return
 	}
 
 	views := make([]any, 0)
 
-	if params.SubjectType != "space" {
+	if params.SubjectType == "" || params.SubjectType == "user" {
 		userViews, err := s.listUserRelationViews(ctx, space, params)
 		if err != nil {
 			httpx.WriteServerError(ctx, w, fmt.Errorf("list user relations: %w", err))
@@ -384,7 +448,7 @@ func (s *Server) ListRelations(w http.ResponseWriter, r *http.Request) {
 		}
 		views = append(views, userViews...)
 	}
-	if params.SubjectType != "user" {
+	if params.SubjectType == "" || params.SubjectType == "space" {
 		spaceViews, err := s.listSpaceRelationViews(ctx, space, params)
 		if err != nil {
 			httpx.WriteServerError(ctx, w, fmt.Errorf("list space relations: %w", err))
@@ -392,6 +456,14 @@ func (s *Server) ListRelations(w http.ResponseWriter, r *http.Request) {
 		}
 		views = append(views, spaceViews...)
 	}
+	if params.SubjectType == "" || params.SubjectType == "orgRole" {
+		orgRoleViews, err := s.listOrgRoleRelationViews(ctx, space, params)
+		if err != nil {
+			httpx.WriteServerError(ctx, w, fmt.Errorf("list org role relations: %w", err))
+			return
+		}
+		views = append(views, orgRoleViews...)
+	}
 
 	httpx.WriteJSON(ctx, w, habitat.NetworkHabitatRelationshipListRelationsOutput{Relations: views})
 }
@@ -476,6 +548,47 @@ func (s *Server) listSpaceRelationViews(
 	return views, nil
 }
 
+// listOrgRoleRelationViews reads and filters the orgRoleRelation records
+// governing space.
+func (s *Server) listOrgRoleRelationViews(
+	ctx context.Context,
+	space habitat_syntax.SpaceURI,
+	params habitat.NetworkHabitatRelationshipListRelationsParams,
+) ([]any, error) {
+	records, err := s.spaces.ListRecords(
+		ctx,
+		space,
+		space.SpaceOwner(),
+		new(habitat_syntax.OrgRoleRelationCollection),
+	)
+	if err != nil {
+		return nil, err
+	}
+	views := make([]any, 0, len(records))
+	for _, rec := range records {
+		orgDID, _ := rec.Value["org"].(string)
+		role, _ := rec.Value["role"].(string)
+		relation, _ := rec.Value["relation"].(string)
+		// As for spaceRelations, subjectDid never matches an org role.
+		if params.SubjectDid != "" {
+			continue
+		}
+		if params.Relation != "" && relation != params.Relation {
+			continue
+		}
+		views = append(views, habitat.NetworkHabitatRelationshipListRelationsOrgRoleRelationView{
+			Uri: habitat_syntax.ConstructSpaceRecordURI(
+				space, rec.Owner, rec.Collection, rec.Rkey,
+			).String(),
+			Org:      orgDID,
+			Role:     role,
+			Relation: relation,
+			Object:   space.String(),
+		})
+	}
+	return views, nil
+}
+
 func (s *Server) authorizeCanWrite(
 	ctx context.Context,
 	w http.ResponseWriter,
@@ -197,6 +197,63 @@ func TestServer_SetSpaceRelation_InvalidSubjectRole(t *testing.T) {
 	require.Equal(t, http.StatusBadRequest, w.Code)
 }
 
+func TestServer_SetOrgRoleRelation(t *testing.T) {
+	s, ps, sp := newTestServer(t, testOrg)
+	space := newSpace(t, sp, docsType, "doc")
+
+	body := fmt.Sprintf(
+		`{"org":%q,"role":"moderator","relation":"writer","space":%q}`,
+		testOrg.String(), space.String(),
+	)
+	req := httptest.NewRequest(
+		http.MethodPost,
+		"/xrpc/network.habitat.relationship.setOrgRoleRelation",
+		strings.NewReader(body),
+	)
+	w := httptest.NewRecorder()
+	s.SetOrgRoleRelation(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	allowed, err := ps.CheckOrgRoleHasSpaceRole(
+		t.Context(),
+		testOrg, "moderator",
+		space, habitat_syntax.SpaceRoleWriter,
+	)
+	require.NoError(t, err)
+	require.True(t, allowed)
+
+	w = httptest.NewRecorder()
+	s.ListRelations(w, queryReq(
+		"/xrpc/network.habitat.relationship.listRelations",
+		url.Values{"space": {space.String()}, "subjectType": {"orgRole"}},
+	))
+	require.Equal(t, http.StatusOK, w.Code)
+	var out struct {
+		Relations []habitat.NetworkHabitatRelationshipListRelationsOrgRoleRelationView
+	}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&out))
+	require.Len(t, out.Relations, 1)
+	require.Equal(t, "moderator", out.Relations[0].Role)
+	require.Equal(t, "writer", out.Relations[0].Relation)
+}
+
+func TestServer_SetOrgRoleRelation_InvalidRole(t *testing.T) {
+	s, _, sp := newTestServer(t, testOrg)
+	space := newSpace(t, sp, docsType, "doc")
+	body := fmt.Sprintf(
+		`{"org":%q,"role":"admin","relation":"writer","space":%q}`,
+		testOrg.String(), space.String(),
+	)
+	req := httptest.NewRequest(
+		http.MethodPost,
+		"/xrpc/network.habitat.relationship.setOrgRoleRelation",
+		strings.NewReader(body),
+	)
+	w := httptest.NewRecorder()
+	s.SetOrgRoleRelation(w, req)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
+
 func TestServer_DeleteRelation_BadURI(t *testing.T) {
 	s, _, _ := newTestServer(t, testOrg)
 	req := httptest.NewRequest(
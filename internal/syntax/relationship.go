@@ -15,6 +15,7 @@ const (
 )
 
 const (
-	UserRelationCollection  syntax.NSID = "network.habitat.relationship.userRelation"
-	SpaceRelationCollection syntax.NSID = "network.habitat.relationship.spaceRelation"
+	UserRelationCollection    syntax.NSID = "network.habitat.relationship.userRelation"
+	SpaceRelationCollection   syntax.NSID = "network.habitat.relationship.spaceRelation"
+	OrgRoleRelationCollection syntax.NSID = "network.habitat.relationship.orgRoleRelation"
 )
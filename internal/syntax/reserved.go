@@ -7,7 +7,8 @@ import (
 
 var (
 	ReservedCollections = xmaps.Set[syntax.NSID]{
-		UserRelationCollection:  struct{}{},
-		SpaceRelationCollection: struct{}{},
+		UserRelationCollection:    struct{}{},
+		SpaceRelationCollection:   struct{}{},
+		OrgRoleRelationCollection: struct{}{},
 	}
 )
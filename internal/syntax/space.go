@@ -8,6 +8,10 @@ import (
 	"github.com/bluesky-social/indigo/atproto/syntax"
 )
 
+// GroupSpaceType is the space type groups are created under. The
+// network.habitat.group.profile self record holds a group's metadata.
+const GroupSpaceType syntax.NSID = "network.habitat.group"
+
 type SpaceKey string
 
 func NewSkey(tid syntax.TID) SpaceKey {
@@ -0,0 +1,210 @@
+package sap
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"gorm.io/gorm"
+
+	habitat_syntax "github.com/habitat-network/habitat/internal/syntax"
+	"github.com/habitat-network/habitat/pkg/sap/outbox"
+	"github.com/habitat-network/habitat/pkg/sap/session"
+)
+
+// backfillCheckInterval is how often sessions still backfilling are checked
+// for completion.
+const backfillCheckInterval = 2 * time.Second
+
+// ErrUnknownSession is returned by BackfillProgress for a DID sap does not
+// sync on behalf of.
+var ErrUnknownSession = session.ErrUnknownSession
+
+// BackfillProgress is how far a session's initial backfill has got.
+type BackfillProgress struct {
+	DID syntax.DID
+	// CrawlState is the session's crawl state: running, complete or errored,
+	// or empty before its first crawl starts. CrawlCompletedAt is when a
+	// crawl of the session last finished; zero until the first has.
+	CrawlState       string
+	CrawlCompletedAt time.Time
+	// SpacesDiscovered counts the spaces the session is known to access;
+	// SpacesTracked, those the space filter lets sap sync.
+	SpacesDiscovered int
+	SpacesTracked    int
+	// ReposTracked counts the repos tracked in those spaces, then by state.
+	ReposTracked  int64
+	ReposActive   int64
+	ReposPending  int64
+	ReposSyncing  int64
+	ReposDesynced int64
+	ReposErrored  int64
+	// ReposUnsynced counts the repos that have not completed a first sync, and
+	// ReposFailed those of them that failed too many passes in a row for the
+	// backfill to keep waiting on them (see syncer.FailedAfterRetries).
+	ReposUnsynced int64
+	ReposFailed   int64
+	// RecordsSynced counts the records synced from those repos so far, and
+	// RecordsRemaining estimates how many the unsynced repos still hold, from
+	// the average of the repos already synced; zero until one has.
+	RecordsSynced    int64
+	RecordsRemaining int64
+	// Complete is set once the session's first crawl finished and every repo
+	// it found has synced or failed; CompletedAt is when sap first saw that.
+	// It stays set through later crawls.
+	Complete    bool
+	CompletedAt time.Time
+}
+
+// BackfillProgress reports how far did's backfill has got. Spaces shared with
+// other sessions count towards each of them.
+func (s *Sap) BackfillProgress(ctx context.Context, did syntax.DID) (BackfillProgress, error) {
+	sess, err := s.sessions.Get(ctx, did)
+	if err != nil {
+		return BackfillProgress{}, err
+	}
+	crawl, err := s.crawler.Status(ctx, did)
+	if err != nil {
+		return BackfillProgress{}, err
+	}
+	spaces, tracked, err := s.backfillSpaces(ctx, did)
+	if err != nil {
+		return BackfillProgress{}, err
+	}
+	repos, err := s.engine.Progress(ctx, tracked)
+	if err != nil {
+		return BackfillProgress{}, err
+	}
+
+	p := BackfillProgress{
+		DID:              did,
+		CrawlState:       crawl.State,
+		CrawlCompletedAt: crawl.CompletedAt,
+		SpacesDiscovered: len(spaces),
+		SpacesTracked:    len(tracked),
+		ReposTracked:     repos.Repos,
+		ReposActive:      repos.Active,
+		ReposPending:     repos.Pending,
+		ReposSyncing:     repos.Syncing,
+		ReposDesynced:    repos.Desynced,
+		ReposErrored:     repos.Errored,
+		ReposUnsynced:    repos.Unsynced,
+		ReposFailed:      repos.Failed,
+		RecordsSynced:    repos.Records,
+		Complete:         !sess.BackfilledAt.IsZero(),
+		CompletedAt:      sess.BackfilledAt,
+	}
+	if synced := repos.Repos - repos.Unsynced; synced > 0 {
+		p.RecordsRemaining = repos.Unsynced * repos.Records / synced
+	}
+	return p, nil
+}
+
+// backfillSpaces returns the spaces did is known to access, and those of them
+// the space filter lets sap sync.
+func (s *Sap) backfillSpaces(
+	ctx context.Context,
+	did syntax.DID,
+) (spaces, tracked []habitat_syntax.SpaceURI, err error) {
+	spaces, err = s.sessions.SpacesOf(ctx, did)
+	if err != nil {
+		return nil, nil, err
+	}
+	tracked = make([]habitat_syntax.SpaceURI, 0, len(spaces))
+	for _, space := range spaces {
+		if s.filter.IncludesSpace(space) {
+			tracked = append(tracked, space)
+		}
+	}
+	return spaces, tracked, nil
+}
+
+// backfillLoop watches sessions that have not finished their initial
+// backfill until ctx ends.
+func (s *Sap) backfillLoop(ctx context.Context) {
+	ticker := time.NewTicker(backfillCheckInterval)
+	defer ticker.Stop()
+	for {
+		s.checkBackfills(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkBackfills marks each session whose backfill has just completed — its
+// crawl finished and every repo it found synced at least once or failed — and
+// emits an ActionBackfillComplete for it, in one transaction, so consumers see
+// the marker exactly once and only after every record the backfill delivered.
+// Failed repos would otherwise hold the marker back for good, so the marker
+// lists them instead.
+func (s *Sap) checkBackfills(ctx context.Context) {
+	sessions, err := s.sessions.List(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "check backfills: list sessions", "err", err)
+		return
+	}
+	for _, sess := range sessions {
+		if !sess.BackfilledAt.IsZero() {
+			continue
+		}
+		p, err := s.BackfillProgress(ctx, sess.DID)
+		if err != nil {
+			slog.ErrorContext(ctx, "check backfills: progress",
+				"session", sess.DID, "err", err)
+			continue
+		}
+		if p.CrawlCompletedAt.IsZero() || p.ReposUnsynced > p.ReposFailed {
+			continue
+		}
+		if err := s.completeBackfill(ctx, sess.DID); err != nil {
+			slog.ErrorContext(ctx, "check backfills: complete",
+				"session", sess.DID, "err", err)
+			continue
+		}
+		slog.InfoContext(ctx, "backfill complete", "session", sess.DID,
+			"repos", p.ReposTracked, "failed", p.ReposFailed, "records", p.RecordsSynced)
+	}
+}
+
+func (s *Sap) completeBackfill(ctx context.Context, did syntax.DID) error {
+	_, tracked, err := s.backfillSpaces(ctx, did)
+	if err != nil {
+		return err
+	}
+	failed, err := s.engine.FailedRepos(ctx, tracked)
+	if err != nil {
+		return err
+	}
+	var summary outbox.BackfillSummary
+	for _, r := range failed {
+		summary.Failed = append(summary.Failed, outbox.FailedRepo{
+			Space: r.Space,
+			Repo:  r.DID,
+			Error: r.ErrorMsg,
+		})
+	}
+	value, err := json.Marshal(summary)
+	if err != nil {
+		return err
+	}
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		marked, err := s.sessions.WithTx(tx).MarkBackfilled(ctx, did)
+		if err != nil || !marked {
+			return err
+		}
+		if err := s.outbox.WithTx(tx).Emit(ctx, outbox.Message{
+			Repo:   did,
+			Action: outbox.ActionBackfillComplete,
+			Value:  value,
+		}); err != nil {
+			return fmt.Errorf("emit backfill complete: %w", err)
+		}
+		return nil
+	})
+}
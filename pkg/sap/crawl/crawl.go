@@ -10,6 +10,7 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"net/http"
 	"sync"
 	"time"
 
@@ -25,6 +26,7 @@ import (
 
 	"github.com/habitat-network/habitat/api/habitat"
 	habitat_syntax "github.com/habitat-network/habitat/internal/syntax"
+	"github.com/habitat-network/habitat/internal/utils"
 )
 
 type crawlState string
@@ -43,8 +45,18 @@ type crawl struct {
 	State     crawlState
 	Cursor    string
 	ErrorMsg  string
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	// CompletedAt is when a crawl of the session last ran to the end; nil
+	// until one has.
+	CompletedAt *time.Time
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// running is a crawl in progress: cancel stops it, and done closes once Run
+// has returned.
+type running struct {
+	cancel context.CancelFunc
+	done   chan struct{}
 }
 
 // Access records which spaces a session can reach. Satisfied by session.Store.
@@ -90,6 +102,29 @@ type Notify interface {
 	EnsureRegistered(ctx context.Context, space habitat_syntax.SpaceURI) error
 }
 
+type options struct {
+	includeSpace func(habitat_syntax.SpaceURI) bool
+	httpc        *http.Client
+}
+
+// WithHTTPClient sets the client resumed sessions send their requests
+// through, in place of the *oauth.ClientApp's own.
+func WithHTTPClient(httpc *http.Client) utils.Opt[options] {
+	return func(o *options) {
+		o.httpc = httpc
+	}
+}
+
+// WithSpaceFilter limits tracking to the spaces include returns true for.
+// Access to other spaces is still recorded, so a later, broader filter knows
+// about them, but they are neither registered for notifications nor
+// enumerated.
+func WithSpaceFilter(include func(habitat_syntax.SpaceURI) bool) utils.Opt[options] {
+	return func(o *options) {
+		o.includeSpace = include
+	}
+}
+
 // Crawler runs backfill crawls.
 type Crawler struct {
 	db           *gorm.DB
@@ -98,11 +133,14 @@ type Crawler struct {
 	spaceClients SpaceClients
 	tracker      Tracker
 	notify       Notify // may be nil
+	includeSpace func(habitat_syntax.SpaceURI) bool
+	httpc        *http.Client // nil uses oauthClient's
 
 	// inFlight dedupes concurrent Runs for the same session within this
-	// process (e.g. a periodic re-crawl overlapping a still-running crawl).
+	// process (e.g. a periodic re-crawl overlapping a still-running crawl),
+	// and lets Stop cancel one.
 	mu       sync.Mutex
-	inFlight map[syntax.DID]struct{}
+	inFlight map[syntax.DID]*running
 
 	tracer          trace.Tracer
 	crawlsCompleted metric.Int64Counter
@@ -118,7 +156,9 @@ func New(
 	notify Notify,
 	meter metric.Meter,
 	tracer trace.Tracer,
+	opts ...utils.Opt[options],
 ) (*Crawler, error) {
+	o := utils.ResolveOptions(options{}, opts)
 	if err := db.AutoMigrate(&crawl{}); err != nil {
 		return nil, err
 	}
@@ -151,7 +191,9 @@ func New(
 		spaceClients:    spaceClients,
 		tracker:         tracker,
 		notify:          notify,
-		inFlight:        make(map[syntax.DID]struct{}),
+		includeSpace:    o.includeSpace,
+		httpc:           o.httpc,
+		inFlight:        make(map[syntax.DID]*running),
 		tracer:          tracer,
 		crawlsCompleted: crawlsCompleted,
 		crawlDuration:   crawlDuration,
@@ -179,16 +221,20 @@ func (c *Crawler) ResumeIncomplete(ctx context.Context) error {
 // is idempotent, and overlapping Runs for one session no-op.
 func (c *Crawler) Run(ctx context.Context, did syntax.DID, sessionID string) {
 	c.mu.Lock()
-	if _, running := c.inFlight[did]; running {
+	if _, ok := c.inFlight[did]; ok {
 		c.mu.Unlock()
 		return
 	}
-	c.inFlight[did] = struct{}{}
+	ctx, cancel := context.WithCancel(ctx)
+	run := &running{cancel: cancel, done: make(chan struct{})}
+	c.inFlight[did] = run
 	c.mu.Unlock()
 	defer func() {
 		c.mu.Lock()
 		delete(c.inFlight, did)
 		c.mu.Unlock()
+		cancel()
+		close(run.done)
 	}()
 
 	ctx, span := c.tracer.Start(ctx, "sap.crawler.crawl",
@@ -244,7 +290,8 @@ func (c *Crawler) Run(ctx context.Context, did syntax.DID, sessionID string) {
 
 	if err := c.db.WithContext(ctx).Model(&crawl{}).
 		Where("did = ?", did).
-		Update("state", stateComplete).Error; err != nil {
+		Updates(map[string]any{"state": stateComplete, "completed_at": time.Now()}).
+		Error; err != nil {
 		slog.ErrorContext(ctx, "set crawl complete", "session", did, "err", err)
 		span.RecordError(err)
 		return
@@ -253,6 +300,98 @@ func (c *Crawler) Run(ctx context.Context, did syntax.DID, sessionID string) {
 	slog.InfoContext(ctx, "crawl finished", "session", did)
 }
 
+// Stop cancels the session's crawl, if one is running, waits for it to wind
+// down, and forgets the session's crawl progress, so neither a restart nor
+// ResumeIncomplete picks the crawl back up.
+func (c *Crawler) Stop(ctx context.Context, did syntax.DID) error {
+	c.mu.Lock()
+	run := c.inFlight[did]
+	c.mu.Unlock()
+	if run != nil {
+		run.cancel()
+		select {
+		case <-run.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if err := c.db.WithContext(ctx).
+		Where("did = ?", did).
+		Delete(&crawl{}).Error; err != nil {
+		return fmt.Errorf("drop crawl state: %w", err)
+	}
+	return nil
+}
+
+// Status is one session's crawl progress, for operators.
+type Status struct {
+	DID       syntax.DID
+	SessionID string
+	// State is one of running, complete or errored.
+	State    string
+	Cursor   string
+	ErrorMsg string
+	// InFlight is set when this process is crawling the session right now; a
+	// crawl left running by a crashed process has State running without it.
+	InFlight bool
+	// CompletedAt is when a crawl of the session last ran to the end; zero
+	// until one has. Periodic re-crawls set State back to running, but not
+	// this.
+	CompletedAt time.Time
+	UpdatedAt   time.Time
+}
+
+// Statuses reports every session's crawl progress, ordered by DID.
+func (c *Crawler) Statuses(ctx context.Context) ([]Status, error) {
+	var crawls []crawl
+	if err := c.db.WithContext(ctx).Order("did").Find(&crawls).Error; err != nil {
+		return nil, fmt.Errorf("list crawls: %w", err)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	statuses := make([]Status, len(crawls))
+	for i, cr := range crawls {
+		statuses[i] = c.statusOf(cr)
+	}
+	return statuses, nil
+}
+
+// Status reports one session's crawl progress. A session that has never been
+// crawled reports an empty State.
+func (c *Crawler) Status(ctx context.Context, did syntax.DID) (Status, error) {
+	var crawls []crawl
+	if err := c.db.WithContext(ctx).
+		Where("did = ?", did).
+		Limit(1).
+		Find(&crawls).Error; err != nil {
+		return Status{}, fmt.Errorf("load crawl: %w", err)
+	}
+	if len(crawls) == 0 {
+		return Status{DID: did}, nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.statusOf(crawls[0]), nil
+}
+
+// statusOf converts a crawl row; c.mu must be held.
+func (c *Crawler) statusOf(cr crawl) Status {
+	_, inFlight := c.inFlight[cr.DID]
+	st := Status{
+		DID:       cr.DID,
+		SessionID: cr.SessionID,
+		State:     string(cr.State),
+		Cursor:    cr.Cursor,
+		ErrorMsg:  cr.ErrorMsg,
+		InFlight:  inFlight,
+		UpdatedAt: cr.UpdatedAt,
+	}
+	if cr.CompletedAt != nil {
+		st.CompletedAt = *cr.CompletedAt
+	}
+	return st
+}
+
 func (c *Crawler) crawlSession(
 	ctx context.Context,
 	did syntax.DID,
@@ -263,6 +402,9 @@ func (c *Crawler) crawlSession(
 	if err != nil {
 		return fmt.Errorf("resume session %s for %s: %w", sessionID, did, err)
 	}
+	if c.httpc != nil {
+		sess.Client = c.httpc
+	}
 	client := sess.APIClient()
 
 	for {
@@ -307,7 +449,8 @@ func (c *Crawler) crawlSession(
 // space's repos, exactly as if a crawl's listSpaces had just returned it. It
 // is the entry point for a space the caller already knows about — say, one
 // named by an out-of-band notification or an invite — without waiting for the
-// session's next crawl.
+// session's next crawl. A space the space filter excludes only has its access
+// recorded.
 func (c *Crawler) TrackSpace(
 	ctx context.Context,
 	space habitat_syntax.SpaceURI,
@@ -317,6 +460,9 @@ func (c *Crawler) TrackSpace(
 	if err := c.access.RecordSpaceAccess(ctx, space, did, sessionID); err != nil {
 		return fmt.Errorf("record space access %s: %w", space, err)
 	}
+	if c.includeSpace != nil && !c.includeSpace(space) {
+		return nil
+	}
 	// Subscribe to the space's push notifications as soon as we know about
 	// it. Best-effort: the registrar's sweep retries misses.
 	if c.notify != nil {
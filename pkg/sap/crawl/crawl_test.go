@@ -164,6 +164,11 @@ func TestCrawlerBackfillsSession(t *testing.T) {
 	var cr crawl
 	require.NoError(t, db.First(&cr, "did = ?", "did:plc:sessiondid").Error)
 	require.Equal(t, stateComplete, cr.State)
+
+	status, err := c.Status(t.Context(), "did:plc:sessiondid")
+	require.NoError(t, err)
+	require.Equal(t, string(stateComplete), status.State)
+	require.False(t, status.CompletedAt.IsZero())
 }
 
 // TestCrawlerDeduplicatesConcurrentRuns verifies that concurrent Run calls for
@@ -205,6 +210,44 @@ func TestCrawlerDeduplicatesConcurrentRuns(t *testing.T) {
 	require.Equal(t, int64(1), calls.Load())
 }
 
+// TestCrawlerStopCancelsRunAndForgetsProgress verifies that Stop cancels an
+// in-flight crawl, waits for it to return, and drops its stored progress.
+func TestCrawlerStopCancelsRunAndForgetsProgress(t *testing.T) {
+	t.Parallel()
+
+	entered := make(chan struct{})
+	var once sync.Once
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		once.Do(func() { close(entered) })
+		<-r.Context().Done()
+	}))
+	t.Cleanup(srv.Close)
+	base, _ := url.Parse(srv.URL)
+
+	db := db_testutil.NewDB(t)
+	rec := &recorder{}
+	app := newOAuthApp(t, base, "did:plc:alice", "sess1")
+	c, err := New(db, app, rec, fakeClients{base: base}, rec, nil, nil, nil)
+	require.NoError(t, err)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.Run(t.Context(), "did:plc:alice", "sess1")
+	}()
+	<-entered
+
+	require.NoError(t, c.Stop(t.Context(), "did:plc:alice"))
+	c.mu.Lock()
+	require.NotContains(t, c.inFlight, syntax.DID("did:plc:alice"))
+	c.mu.Unlock()
+	<-done
+
+	var count int64
+	require.NoError(t, db.Model(&crawl{}).Where("did = ?", "did:plc:alice").Count(&count).Error)
+	require.Zero(t, count)
+}
+
 // TestCrawlerRunCompleteThenRestart verifies that a completed crawl resets the
 // cursor and starts fresh.
 func TestCrawlerRunCompleteThenRestart(t *testing.T) {
@@ -237,6 +280,33 @@ func TestCrawlerRunCompleteThenRestart(t *testing.T) {
 	require.Empty(t, cr.Cursor)
 }
 
+// TestCrawlerStatusesReportsProgress verifies that Statuses reports each
+// session's stored crawl state and cursor.
+func TestCrawlerStatusesReportsProgress(t *testing.T) {
+	t.Parallel()
+
+	db := db_testutil.NewDB(t)
+	rec := &recorder{}
+	base, _ := url.Parse("http://unused.example")
+	c, err := New(db, newOAuthApp(t, base, "did:plc:alice", "sess1"),
+		rec, fakeClients{base: base}, rec, nil, nil, nil)
+	require.NoError(t, err)
+	require.NoError(t, db.Create(&crawl{
+		DID: "did:plc:bob", SessionID: "sess2", State: stateErrored,
+		Cursor: "page-3", ErrorMsg: "list spaces: boom",
+	}).Error)
+
+	statuses, err := c.Statuses(t.Context())
+	require.NoError(t, err)
+	require.Len(t, statuses, 1)
+	require.Equal(t, syntax.DID("did:plc:bob"), statuses[0].DID)
+	require.Equal(t, "sess2", statuses[0].SessionID)
+	require.Equal(t, string(stateErrored), statuses[0].State)
+	require.Equal(t, "page-3", statuses[0].Cursor)
+	require.Equal(t, "list spaces: boom", statuses[0].ErrorMsg)
+	require.False(t, statuses[0].InFlight)
+}
+
 // TestDetachCancel verifies that detachCancel returns a context that inherits
 // cancellation from the parent.
 func TestDetachCancel(t *testing.T) {
@@ -406,3 +476,31 @@ func TestCrawlerTrackSpace(t *testing.T) {
 	require.Contains(t, rec.checks, repoDID)
 	require.True(t, nr.called)
 }
+
+// TestCrawlerTrackSpaceHonoursSpaceFilter verifies that a space the filter
+// excludes only has its access recorded: it is neither registered for
+// notifications nor enumerated.
+func TestCrawlerTrackSpaceHonoursSpaceFilter(t *testing.T) {
+	t.Parallel()
+
+	space := habitat_syntax.SpaceURI("at://did:web:owner/space/network.habitat.space/s1")
+	srv := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %s", r.URL.Path)
+	}))
+	t.Cleanup(srv.Close)
+
+	rec := &recorder{}
+	nr := &fakeNotifyRegistrar{}
+	base := mustParseURL(t, srv.URL)
+	c, err := New(db_testutil.NewDB(t), nil, rec, fakeClients{base: base}, rec, nr, nil, nil,
+		WithSpaceFilter(func(habitat_syntax.SpaceURI) bool { return false }))
+	require.NoError(t, err)
+
+	require.NoError(t, c.TrackSpace(t.Context(), space, "did:web:bob", "sess1"))
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	require.Equal(t, []habitat_syntax.SpaceURI{space}, rec.access)
+	require.Empty(t, rec.checks)
+	require.False(t, nr.called)
+}
@@ -0,0 +1,199 @@
+// Package hostlimit keeps sap polite to the hosts it reads from. Its
+// Transport caps the requests in flight to each host, spaces requests out
+// with a per-host token bucket, and when a host answers 429 or sends
+// Retry-After, stops sending it anything until the host said to come back.
+// Callers see a throttled request as a *ThrottledError and can reschedule the
+// work rather than treat it as a failure.
+package hostlimit
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	defaultConcurrency = 4
+	defaultRate        = 10
+	defaultBurst       = 20
+	// defaultRetryAfter is how long a host that answered 429 without a
+	// Retry-After header is left alone.
+	defaultRetryAfter = 30 * time.Second
+)
+
+// Limits bounds the traffic sent to any one host. Zero fields take their
+// defaults.
+type Limits struct {
+	// Concurrency caps the requests in flight to a host, counting a request
+	// until its response body is closed (default 4).
+	Concurrency int
+	// Rate is the sustained requests per second allowed to a host (default
+	// 10).
+	Rate float64
+	// Burst is how many requests may go to a host at once after it has been
+	// idle (default 20).
+	Burst int
+}
+
+// ThrottledError reports that a host asked sap to back off, either just now
+// (a 429 or a Retry-After) or earlier and the wait has not yet passed.
+type ThrottledError struct {
+	Host       string
+	RetryAfter time.Duration
+}
+
+func (e *ThrottledError) Error() string {
+	return fmt.Sprintf("host %s throttled requests; retry after %s", e.Host, e.RetryAfter)
+}
+
+// RetryAfter reports whether err is, or wraps, a ThrottledError, and how long
+// the host asked to be left alone.
+func RetryAfter(err error) (time.Duration, bool) {
+	var throttled *ThrottledError
+	if !errors.As(err, &throttled) {
+		return 0, false
+	}
+	return throttled.RetryAfter, true
+}
+
+// host is the limiting state for one host.
+type host struct {
+	slots   chan struct{}
+	limiter *rate.Limiter
+
+	mu         sync.Mutex
+	retryAfter time.Time
+}
+
+// Transport is an http.RoundTripper that applies Limits per host. One
+// Transport should be shared by every client sap uses, so the limits hold
+// across them.
+type Transport struct {
+	base   http.RoundTripper
+	limits Limits
+
+	mu    sync.Mutex
+	hosts map[string]*host
+}
+
+// NewTransport wraps base with per-host limits. A nil base uses
+// http.DefaultTransport.
+func NewTransport(base http.RoundTripper, limits Limits) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if limits.Concurrency <= 0 {
+		limits.Concurrency = defaultConcurrency
+	}
+	if limits.Rate <= 0 {
+		limits.Rate = defaultRate
+	}
+	if limits.Burst <= 0 {
+		limits.Burst = defaultBurst
+	}
+	return &Transport{base: base, limits: limits, hosts: make(map[string]*host)}
+}
+
+// Client returns an http.Client that sends through t.
+func (t *Transport) Client() *http.Client {
+	return &http.Client{Transport: t}
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	h := t.host(req.URL.Host)
+	if wait := h.waitRemaining(); wait > 0 {
+		return nil, &ThrottledError{Host: req.URL.Host, RetryAfter: wait}
+	}
+
+	select {
+	case h.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	release := sync.OnceFunc(func() { <-h.slots })
+	if err := h.limiter.Wait(ctx); err != nil {
+		release()
+		return nil, err
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	if wait, ok := retryAfter(resp); ok {
+		h.backOff(wait)
+		if resp.StatusCode == http.StatusTooManyRequests {
+			_ = resp.Body.Close()
+			release()
+			return nil, &ThrottledError{Host: req.URL.Host, RetryAfter: wait}
+		}
+	}
+	resp.Body = &releasingBody{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+func (t *Transport) host(name string) *host {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	h, ok := t.hosts[name]
+	if !ok {
+		h = &host{
+			slots:   make(chan struct{}, t.limits.Concurrency),
+			limiter: rate.NewLimiter(rate.Limit(t.limits.Rate), t.limits.Burst),
+		}
+		t.hosts[name] = h
+	}
+	return h
+}
+
+func (h *host) waitRemaining() time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return time.Until(h.retryAfter)
+}
+
+func (h *host) backOff(wait time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if until := time.Now().Add(wait); until.After(h.retryAfter) {
+		h.retryAfter = until
+	}
+}
+
+// retryAfter reads how long a response asks the client to wait: its
+// Retry-After header (seconds or an HTTP date) on any response, or a default
+// for a 429 that carries none.
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	if raw := resp.Header.Get("Retry-After"); raw != "" {
+		if secs, err := strconv.Atoi(raw); err == nil && secs >= 0 {
+			return time.Duration(secs) * time.Second, true
+		}
+		if at, err := http.ParseTime(raw); err == nil {
+			return max(time.Until(at), 0), true
+		}
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return defaultRetryAfter, true
+	}
+	return 0, false
+}
+
+// releasingBody frees its request's concurrency slot once the caller is done
+// with the response.
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	defer b.release()
+	return b.ReadCloser.Close()
+}
@@ -0,0 +1,112 @@
+package hostlimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransportThrottlesHostAfter429(t *testing.T) {
+	t.Parallel()
+
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Header().Set("Retry-After", "120")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	t.Cleanup(srv.Close)
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(other.Close)
+
+	client := NewTransport(nil, Limits{}).Client()
+
+	_, err := client.Get(srv.URL)
+	wait, ok := RetryAfter(err)
+	require.True(t, ok, "429 should surface as a ThrottledError: %v", err)
+	require.Equal(t, 120*time.Second, wait)
+
+	// The host is left alone until its Retry-After passes.
+	_, err = client.Get(srv.URL)
+	wait, ok = RetryAfter(err)
+	require.True(t, ok)
+	require.Greater(t, wait, 100*time.Second)
+	require.EqualValues(t, 1, hits.Load())
+
+	// Other hosts are unaffected.
+	resp, err := client.Get(other.URL)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestTransportHonoursRetryAfterOnOtherResponses(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(srv.Close)
+
+	client := NewTransport(nil, Limits{}).Client()
+
+	// A non-429 response is handed back as is, but its Retry-After holds.
+	resp, err := client.Get(srv.URL)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+
+	_, err = client.Get(srv.URL)
+	wait, ok := RetryAfter(err)
+	require.True(t, ok)
+	require.Greater(t, wait, 50*time.Minute)
+}
+
+func TestTransportCapsConcurrencyUntilBodyClosed(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(srv.Close)
+
+	client := NewTransport(nil, Limits{Concurrency: 1}).Client()
+
+	first, err := client.Get(srv.URL)
+	require.NoError(t, err)
+
+	// The only slot is held by the unclosed first response.
+	ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	require.NoError(t, err)
+	_, err = client.Do(req)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	require.NoError(t, first.Body.Close())
+	second, err := client.Get(srv.URL)
+	require.NoError(t, err)
+	require.NoError(t, second.Body.Close())
+}
+
+func TestRetryAfterUnwraps(t *testing.T) {
+	t.Parallel()
+
+	err := fmt.Errorf("list repos: %w", &ThrottledError{Host: "h", RetryAfter: time.Minute})
+	wait, ok := RetryAfter(err)
+	require.True(t, ok)
+	require.Equal(t, time.Minute, wait)
+
+	_, ok = RetryAfter(errors.New("boom"))
+	require.False(t, ok)
+}
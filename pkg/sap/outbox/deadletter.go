@@ -0,0 +1,315 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrUnknownDeadLetter is returned by Replay and Discard for a dead letter
+// that does not exist (or was already replayed or discarded).
+var ErrUnknownDeadLetter = errors.New("unknown dead letter")
+
+// deliveryAttempt counts a group's failed deliveries of a message it has not
+// acked — nacks, and leases that expired without an ack — and the reason it
+// last gave for failing it. DeliveredAt is when the current lease began; zero
+// when the message is not leased, after a nack.
+type deliveryAttempt struct {
+	Consumer    string `gorm:"primaryKey"`
+	MessageID   uint   `gorm:"primaryKey"`
+	Attempts    int    `gorm:"not null;default:0"`
+	LastError   string
+	DeliveredAt time.Time
+}
+
+// deadLetter parks a message a group gave up on. The message counts as acked
+// for the group, so it stops blocking the group's stream, but it is kept in
+// the log until the dead letter is replayed or discarded.
+type deadLetter struct {
+	ID        uint   `gorm:"primaryKey;autoIncrement"`
+	Consumer  string `gorm:"uniqueIndex:idx_dead_letter_message"`
+	MessageID uint   `gorm:"uniqueIndex:idx_dead_letter_message"`
+	Attempts  int
+	Reason    string
+	CreatedAt time.Time
+}
+
+// groupPolicy overrides the store's max attempts for one consumer group.
+type groupPolicy struct {
+	Consumer    string `gorm:"primaryKey"`
+	MaxAttempts int
+}
+
+// DeadLetter is a message a consumer group gave up on.
+type DeadLetter struct {
+	ID    uint
+	Group string
+	// Message is the message as it was delivered; its Attempts is the number
+	// of deliveries made before it was dead-lettered.
+	Message Message
+	// Reason is the last reason the group gave for failing the message, or a
+	// note that it ran out of attempts without one.
+	Reason         string
+	DeadLetteredAt time.Time
+}
+
+// SetMaxAttempts sets how many times group may fail a message before it is
+// dead-lettered, overriding the store-wide WithMaxAttempts. A negative
+// n redelivers forever; zero reverts to the store-wide setting.
+func (s *Store) SetMaxAttempts(ctx context.Context, group string, n int) error {
+	db := s.db.WithContext(ctx)
+	if n == 0 {
+		if err := db.Where("consumer = ?", group).Delete(&groupPolicy{}).Error; err != nil {
+			return fmt.Errorf("clear max attempts for group %q: %w", group, err)
+		}
+		return nil
+	}
+	if err := db.Clauses(clause.OnConflict{UpdateAll: true}).
+		Create(&groupPolicy{Consumer: group, MaxAttempts: n}).Error; err != nil {
+		return fmt.Errorf("set max attempts for group %q: %w", group, err)
+	}
+	return nil
+}
+
+// groupMaxAttempts is the number of deliveries group gets per message, or zero
+// when it has no limit.
+func (s *Store) groupMaxAttempts(db *gorm.DB, group string) (int, error) {
+	var policies []groupPolicy
+	if err := db.Where("consumer = ?", group).Limit(1).Find(&policies).Error; err != nil {
+		return 0, fmt.Errorf("load max attempts: %w", err)
+	}
+	if len(policies) == 0 {
+		return max(s.maxAttempts, 0), nil
+	}
+	return max(policies[0].MaxAttempts, 0), nil
+}
+
+// poll returns up to limit messages group has not acked, leasing each one
+// that is not leased already. Polling again within a lease redelivers the
+// message without counting an attempt; a lease that expires without an ack
+// counts as a failed attempt, like a nack. Messages that have used up the
+// group's attempts are dead-lettered rather than delivered again, so one
+// message that kills its consumer every time cannot hold up the group forever.
+func (s *Store) poll(ctx context.Context, group string, limit int) ([]Message, error) {
+	db := s.db.WithContext(ctx)
+	maxAttempts, err := s.groupMaxAttempts(db, group)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		var rows []outboxMessage
+		if err := unacked(db, group).Order("id ASC").Limit(limit).Find(&rows).Error; err != nil {
+			return nil, err
+		}
+		if len(rows) == 0 {
+			return nil, nil
+		}
+		ids := make([]uint, len(rows))
+		for i, row := range rows {
+			ids[i] = row.ID
+		}
+		var prior []deliveryAttempt
+		if err := db.Where("consumer = ? AND message_id IN ?", group, ids).
+			Find(&prior).Error; err != nil {
+			return nil, fmt.Errorf("load delivery attempts: %w", err)
+		}
+		attempts := make(map[uint]deliveryAttempt, len(prior))
+		for _, a := range prior {
+			attempts[a.MessageID] = a
+		}
+
+		now := time.Now()
+		var leased, exhausted []deliveryAttempt
+		for _, row := range rows {
+			a, ok := attempts[row.ID]
+			switch {
+			case !ok:
+				a = deliveryAttempt{Consumer: group, MessageID: row.ID}
+			case a.DeliveredAt.IsZero():
+			case now.Sub(a.DeliveredAt) >= s.lease:
+				a.Attempts++
+			default:
+				continue // still leased to an earlier poll
+			}
+			if maxAttempts > 0 && a.Attempts >= maxAttempts {
+				exhausted = append(exhausted, a)
+				continue
+			}
+			a.DeliveredAt = now
+			leased = append(leased, a)
+			attempts[row.ID] = a
+		}
+		if len(exhausted) > 0 {
+			if err := db.Transaction(func(tx *gorm.DB) error {
+				for _, a := range exhausted {
+					if err := deadLetterIn(tx, a); err != nil {
+						return err
+					}
+				}
+				return nil
+			}); err != nil {
+				return nil, err
+			}
+			// Poll again: the page now has room for messages behind them.
+			continue
+		}
+
+		if len(leased) > 0 {
+			if err := db.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "consumer"}, {Name: "message_id"}},
+				DoUpdates: clause.AssignmentColumns([]string{"attempts", "delivered_at"}),
+			}).Create(&leased).Error; err != nil {
+				return nil, fmt.Errorf("lease messages: %w", err)
+			}
+		}
+		msgs := toMessages(rows)
+		for i := range msgs {
+			msgs[i].Attempts = attempts[msgs[i].ID].Attempts + 1
+		}
+		return msgs, nil
+	}
+}
+
+// nack counts a failed attempt at the message id for group, records why, and
+// ends its lease so the next poll redelivers it; it dead-letters the message
+// instead when that used up its attempts. Nacking a message the group already
+// acked does nothing.
+func (s *Store) nack(ctx context.Context, group string, id uint, reason string) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var pending int64
+		if err := unacked(tx, group).Where("id = ?", id).Count(&pending).Error; err != nil {
+			return fmt.Errorf("find message: %w", err)
+		}
+		if pending == 0 {
+			return nil
+		}
+		maxAttempts, err := s.groupMaxAttempts(tx, group)
+		if err != nil {
+			return err
+		}
+		a := deliveryAttempt{Consumer: group, MessageID: id}
+		if err := tx.Where("consumer = ? AND message_id = ?", group, id).
+			Limit(1).
+			Find(&a).Error; err != nil {
+			return fmt.Errorf("load delivery attempts: %w", err)
+		}
+		a.Attempts++
+		a.LastError = reason
+		a.DeliveredAt = time.Time{}
+		if maxAttempts > 0 && a.Attempts >= maxAttempts {
+			return deadLetterIn(tx, a)
+		}
+		return tx.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "consumer"}, {Name: "message_id"}},
+			DoUpdates: clause.AssignmentColumns(
+				[]string{"attempts", "last_error", "delivered_at"},
+			),
+		}).Create(&a).Error
+	})
+}
+
+// deadLetterIn moves the message a counts deliveries of to its group's dead
+// letters: acked for the group, with its attempts and last error kept on the
+// dead letter.
+func deadLetterIn(tx *gorm.DB, a deliveryAttempt) error {
+	reason := a.LastError
+	if reason == "" {
+		reason = fmt.Sprintf("not acked after %d deliveries", a.Attempts)
+	}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&deadLetter{
+		Consumer:  a.Consumer,
+		MessageID: a.MessageID,
+		Attempts:  a.Attempts,
+		Reason:    reason,
+	}).Error; err != nil {
+		return fmt.Errorf("dead-letter message %d: %w", a.MessageID, err)
+	}
+	if err := markAcked(tx, a.Consumer, a.MessageID); err != nil {
+		return fmt.Errorf("dead-letter message %d: %w", a.MessageID, err)
+	}
+	return tx.Where("consumer = ? AND message_id = ?", a.Consumer, a.MessageID).
+		Delete(&deliveryAttempt{}).Error
+}
+
+// DeadLetters lists group's dead letters, oldest first.
+func (s *Store) DeadLetters(ctx context.Context, group string) ([]DeadLetter, error) {
+	db := s.db.WithContext(ctx)
+	var rows []deadLetter
+	if err := db.Where("consumer = ?", group).Order("id ASC").Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("list dead letters: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	ids := make([]uint, len(rows))
+	for i, row := range rows {
+		ids[i] = row.MessageID
+	}
+	var msgRows []outboxMessage
+	if err := db.Where("id IN ?", ids).Find(&msgRows).Error; err != nil {
+		return nil, fmt.Errorf("load dead-lettered messages: %w", err)
+	}
+	byID := make(map[uint]Message, len(msgRows))
+	for _, msg := range toMessages(msgRows) {
+		byID[msg.ID] = msg
+	}
+
+	letters := make([]DeadLetter, 0, len(rows))
+	for _, row := range rows {
+		msg, ok := byID[row.MessageID]
+		if !ok {
+			continue
+		}
+		msg.Attempts = row.Attempts
+		letters = append(letters, DeadLetter{
+			ID:             row.ID,
+			Group:          row.Consumer,
+			Message:        msg,
+			Reason:         row.Reason,
+			DeadLetteredAt: row.CreatedAt,
+		})
+	}
+	return letters, nil
+}
+
+// Replay hands a dead-lettered message back to its group with a fresh set of
+// attempts. The group receives it next, ahead of anything it has not yet
+// read, but after messages it has already processed: a consumer replaying
+// an old message should expect it out of order.
+func (s *Store) Replay(ctx context.Context, id uint) error {
+	var letter deadLetter
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&letter, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrUnknownDeadLetter
+			}
+			return err
+		}
+		if err := markUnacked(tx, letter.Consumer, letter.MessageID); err != nil {
+			return err
+		}
+		return tx.Delete(&letter).Error
+	})
+	if err != nil {
+		return fmt.Errorf("replay dead letter %d: %w", id, err)
+	}
+	s.notifier(letter.Consumer).Notify()
+	return nil
+}
+
+// Discard drops a dead letter. Its message stays acked for the group, so
+// retention prunes it like any other processed message.
+func (s *Store) Discard(ctx context.Context, id uint) error {
+	res := s.db.WithContext(ctx).Delete(&deadLetter{}, id)
+	if res.Error != nil {
+		return fmt.Errorf("discard dead letter %d: %w", id, res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("discard dead letter %d: %w", id, ErrUnknownDeadLetter)
+	}
+	return nil
+}
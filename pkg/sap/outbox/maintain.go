@@ -0,0 +1,246 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// maintainInterval is how often Run prunes and compacts the log.
+	maintainInterval = 10 * time.Minute
+	// maintainBatchSize bounds the rows one prune or compaction transaction
+	// touches, so neither holds a long lock against the sync engine's emits.
+	maintainBatchSize = 500
+)
+
+// Run prunes and compacts the log on start and then on every maintenance
+// interval until ctx ends. It returns at once when neither retention nor
+// compaction is enabled.
+func (s *Store) Run(ctx context.Context) {
+	if s.retention <= 0 && !s.compact {
+		return
+	}
+	s.maintain(ctx)
+	ticker := time.NewTicker(maintainInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.maintain(ctx)
+		}
+	}
+}
+
+func (s *Store) maintain(ctx context.Context) {
+	if s.retention > 0 {
+		pruned, err := s.Prune(ctx, time.Now().Add(-s.retention))
+		if err != nil {
+			slog.ErrorContext(ctx, "outbox: prune", "err", err)
+		} else if pruned > 0 {
+			slog.InfoContext(ctx, "outbox: pruned acked messages", "count", pruned)
+		}
+	}
+	if s.compact {
+		compacted, err := s.Compact(ctx)
+		if err != nil {
+			slog.ErrorContext(ctx, "outbox: compact", "err", err)
+		} else if compacted > 0 {
+			slog.InfoContext(ctx, "outbox: compacted superseded messages", "count", compacted)
+		}
+	}
+}
+
+// Prune deletes the messages every registered consumer group acked before
+// cutoff, along with their acks, and returns how many it deleted. A message
+// some group has yet to ack is kept however old it is, so a consumer that
+// falls behind never loses messages to retention.
+func (s *Store) Prune(ctx context.Context, cutoff time.Time) (int64, error) {
+	db := s.db.WithContext(ctx)
+	var names []string
+	if err := db.Model(&consumerGroup{}).Pluck("name", &names).Error; err != nil {
+		return 0, fmt.Errorf("list consumer groups: %w", err)
+	}
+	var named int64
+	defaultActive := false
+	for _, name := range names {
+		if name == DefaultGroup {
+			defaultActive = true
+		} else {
+			named++
+		}
+	}
+	if !defaultActive {
+		// A default group that acked before groups registered themselves
+		// is still a consumer until its first poll registers it.
+		var acked int64
+		if err := db.Model(&outboxMessage{}).
+			Where("acked_at IS NOT NULL").
+			Limit(1).
+			Count(&acked).Error; err != nil {
+			return 0, fmt.Errorf("check default group acks: %w", err)
+		}
+		defaultActive = acked > 0
+	}
+
+	var total int64
+	for defaultActive || named > 0 {
+		// Dead-lettered messages count as acked for their group but are kept
+		// until the dead letter is replayed or discarded.
+		q := db.Model(&outboxMessage{}).
+			Where("id NOT IN (?)", db.Model(&deadLetter{}).Select("message_id"))
+		if defaultActive {
+			q = q.Where("acked_at IS NOT NULL AND acked_at < ?", cutoff)
+		}
+		if named > 0 {
+			q = q.Where("id IN (?)", db.Model(&consumerAck{}).
+				Select("message_id").
+				Where("consumer <> ? AND acked_at < ?", DefaultGroup, cutoff).
+				Group("message_id").
+				Having("COUNT(*) = ?", named))
+		}
+		var ids []uint
+		if err := q.Order("id ASC").Limit(maintainBatchSize).Pluck("id", &ids).Error; err != nil {
+			return total, fmt.Errorf("find prunable messages: %w", err)
+		}
+		if len(ids) == 0 {
+			break
+		}
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("message_id IN ?", ids).Delete(&consumerAck{}).Error; err != nil {
+				return err
+			}
+			return tx.Where("id IN ?", ids).Delete(&outboxMessage{}).Error
+		}); err != nil {
+			return total, fmt.Errorf("delete pruned messages: %w", err)
+		}
+		total += int64(len(ids))
+		if len(ids) < maintainBatchSize {
+			break
+		}
+	}
+
+	// A group can ack a message compaction deleted while the group was
+	// processing it; nothing else would ever remove that ack, or the
+	// delivery count kept for it.
+	if err := db.Where("message_id NOT IN (?)", db.Model(&outboxMessage{}).Select("id")).
+		Delete(&consumerAck{}).Error; err != nil {
+		return total, fmt.Errorf("delete orphaned acks: %w", err)
+	}
+	if err := db.Where("message_id NOT IN (?)", db.Model(&outboxMessage{}).Select("id")).
+		Delete(&deliveryAttempt{}).Error; err != nil {
+		return total, fmt.Errorf("delete orphaned delivery attempts: %w", err)
+	}
+	return total, nil
+}
+
+// Compact collapses the messages no consumer group has acked yet down to the
+// latest per record URI and returns how many it deleted. The surviving
+// message is rewritten to describe the net change across the collapsed run:
+// it takes the first message's previous CID and value, and its action follows
+// from whether the record existed before the run and after it. Resync markers
+// carry no URI and are never collapsed.
+//
+// Only messages untouched by every group are eligible: none has acked them
+// or been delivered them, so no consumer sees a later version of a record and
+// then an earlier one, or loses a message it is processing.
+func (s *Store) Compact(ctx context.Context) (int64, error) {
+	db := s.db.WithContext(ctx)
+	unread := func() *gorm.DB {
+		return db.Model(&outboxMessage{}).
+			Where("acked_at IS NULL AND uri <> ''").
+			Where("id NOT IN (?)", db.Model(&consumerAck{}).Select("message_id")).
+			Where("id NOT IN (?)", db.Model(&deliveryAttempt{}).Select("message_id"))
+	}
+
+	var total int64
+	for {
+		var uris []string
+		if err := unread().
+			Group("uri").
+			Having("COUNT(*) > 1").
+			Limit(maintainBatchSize).
+			Pluck("uri", &uris).Error; err != nil {
+			return total, fmt.Errorf("find compactable records: %w", err)
+		}
+		if len(uris) == 0 {
+			return total, nil
+		}
+		var rows []outboxMessage
+		if err := unread().
+			Where("uri IN ?", uris).
+			Order("uri ASC, id ASC").
+			Find(&rows).Error; err != nil {
+			return total, fmt.Errorf("load compactable messages: %w", err)
+		}
+
+		var deleted int64
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			deleted = 0
+			for start := 0; start < len(rows); {
+				end := start + 1
+				for end < len(rows) && rows[end].URI == rows[start].URI {
+					end++
+				}
+				if end-start > 1 {
+					n, err := collapse(tx, rows[start:end])
+					if err != nil {
+						return err
+					}
+					deleted += n
+				}
+				start = end
+			}
+			return nil
+		}); err != nil {
+			return total, fmt.Errorf("collapse messages: %w", err)
+		}
+		total += deleted
+		if len(uris) < maintainBatchSize {
+			return total, nil
+		}
+	}
+}
+
+// collapse folds a run of messages for one record, oldest first, into the
+// last of them, and returns how many it deleted. A run that creates the record
+// and deletes it again nets out to nothing, so all of it is deleted.
+func collapse(tx *gorm.DB, run []outboxMessage) (int64, error) {
+	first, last := run[0], run[len(run)-1]
+	existedBefore := first.PrevCid != ""
+	existsAfter := last.Action != ActionDelete
+	if !existedBefore && !existsAfter {
+		ids := make([]uint, 0, len(run))
+		for _, row := range run {
+			ids = append(ids, row.ID)
+		}
+		return int64(len(ids)), tx.Where("id IN ?", ids).Delete(&outboxMessage{}).Error
+	}
+	action := ActionDelete
+	switch {
+	case existedBefore && existsAfter:
+		action = ActionUpdate
+	case existsAfter:
+		action = ActionCreate
+	}
+
+	ids := make([]uint, 0, len(run)-1)
+	for _, row := range run[:len(run)-1] {
+		ids = append(ids, row.ID)
+	}
+	if err := tx.Where("id IN ?", ids).Delete(&outboxMessage{}).Error; err != nil {
+		return 0, err
+	}
+	return int64(len(ids)), tx.Model(&outboxMessage{}).
+		Where("id = ?", last.ID).
+		Updates(map[string]any{
+			"action":     action,
+			"prev_cid":   first.PrevCid,
+			"prev_value": first.PrevValue,
+		}).Error
+}
@@ -1,15 +1,30 @@
 // Package outbox provides durable, acknowledged delivery of synced records to
-// the library's consumer. The sync engine emits records here; the consumer
+// the library's consumers. The sync engine emits records here; each consumer
 // polls, processes, and acks them. Unacked messages are redelivered.
+//
+// Every message is delivered to every consumer group independently: a group
+// is just a name with its own ack state over the same ordered log, so several
+// services can share one sap and one sync. The default group (the Store
+// itself) keeps its acks on the message row; named groups keep theirs in a
+// side table.
+//
+// Left alone the log only grows. Run prunes messages every group acked more
+// than a retention period ago and, optionally, compacts messages no group has
+// read yet down to the latest per record.
 package outbox
 
 import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"go.opentelemetry.io/otel/metric"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	habitat_syntax "github.com/habitat-network/habitat/internal/syntax"
 	"github.com/habitat-network/habitat/internal/utils"
@@ -18,102 +33,449 @@ import (
 // outboxMessage is a single emitted outboxMessage awaiting acknowledgement.
 type outboxMessage struct {
 	ID        uint `gorm:"primaryKey;autoIncrement"`
+	Space     habitat_syntax.SpaceURI
+	Repo      syntax.DID
+	Action    Action
 	URI       habitat_syntax.SpaceRecordURI
+	Rev       syntax.TID
+	Cid       string
+	PrevCid   string
 	Value     []byte
+	PrevValue []byte
 	CreatedAt time.Time
 	AckedAt   *time.Time
 }
 
+// Action is what a message did to its record.
+type Action string
+
+const (
+	ActionCreate Action = "create"
+	ActionUpdate Action = "update"
+	ActionDelete Action = "delete"
+	// ActionResync follows a full rebuild of a repo from a snapshot. Every
+	// record the repo holds was delivered just before it with the same Rev;
+	// a consumer should drop any record it holds for the repo with an older
+	// rev.
+	ActionResync Action = "resync"
+	// ActionBackfillComplete marks the end of a session's initial backfill:
+	// every repo its first crawl found has been synced and delivered before
+	// it, except those sap gave up waiting for. Repo is the session's DID; it
+	// carries no record, and its Value is a BackfillSummary. Sent once per
+	// session, so a consumer can start serving what it has built.
+	ActionBackfillComplete Action = "backfillComplete"
+)
+
+// BackfillSummary is the Value of an ActionBackfillComplete message.
+type BackfillSummary struct {
+	// Failed lists the repos that kept failing their first sync, which the
+	// backfill completed without. They keep retrying, and their records are
+	// delivered as usual once one succeeds.
+	Failed []FailedRepo `json:"failed,omitempty"`
+}
+
+// FailedRepo is a repo a backfill completed without.
+type FailedRepo struct {
+	Space habitat_syntax.SpaceURI `json:"space"`
+	Repo  syntax.DID              `json:"repo"`
+	Error string                  `json:"error,omitempty"`
+}
+
+// consumerGroup registers a consumer group. A group sees every message still
+// in the outbox, including ones emitted before it was created, so a new
+// service joining a running sap backfills from the retained log. The default
+// group registers itself, under its empty name, on its first poll, so a
+// deployment that only uses named groups does not hold back pruning.
+type consumerGroup struct {
+	Name      string `gorm:"primaryKey"`
+	CreatedAt time.Time
+}
+
+// consumerAck records that a named group has processed a message.
+type consumerAck struct {
+	Consumer  string `gorm:"primaryKey"`
+	MessageID uint   `gorm:"primaryKey"`
+	AckedAt   time.Time
+}
+
 // Message is a single event delivered from the outbox. Ack must be called with
 // the message's ID once it has been durably processed; until then it is
 // redelivered by Poll.
 type Message struct {
 	ID    uint
-	URI   habitat_syntax.SpaceRecordURI
+	Space habitat_syntax.SpaceURI
+	// Repo is the repo the message is about; for ActionBackfillComplete, the
+	// session's DID.
+	Repo   syntax.DID
+	Action Action
+	// URI is the record the message is about; empty for ActionResync, which
+	// covers the whole repo, and ActionBackfillComplete.
+	URI habitat_syntax.SpaceRecordURI
+	// Rev is the repo revision the change landed in.
+	Rev syntax.TID
+	// Cid is the record's new CID; empty for a delete or a resync.
+	Cid string
+	// PrevCid is the CID the change replaced; empty for a create.
+	PrevCid string
+	// Value is the record's new value; nil for a delete or a resync.
 	Value json.RawMessage
+	// PrevValue is the value the change replaced, when sap keeps previous
+	// values and held one.
+	PrevValue json.RawMessage
+	// Attempts counts the polling group's failed deliveries of the message,
+	// plus this one.
+	Attempts int
 }
 
 // Outbox is the consumer-facing read side: ordered delivery with
 // redelivery-until-ack.
 type Outbox interface {
-	// Poll returns up to limit unacknowledged messages in delivery order.
+	// Poll returns up to limit unacknowledged messages in delivery order,
+	// leasing them to the group. A lease that expires without an ack counts
+	// as a failed attempt, as a Nack does; a message that has failed as many
+	// times as the group's max attempts allow is dead-lettered instead of
+	// returned.
 	Poll(ctx context.Context, limit int) ([]Message, error)
 	// Ack marks the message with the given ID as processed.
 	Ack(ctx context.Context, id uint) error
+	// Nack reports that processing the message with the given ID failed, and
+	// why. The message is redelivered, unless it has used up the group's max
+	// attempts, in which case it is dead-lettered now.
+	Nack(ctx context.Context, id uint, reason string) error
 	// Watch returns a channel notified when new messages may be available. It
-	// is a single shared hint, not a per-caller fan-out: only one consumer
-	// should drain it at a time.
+	// is a hint shared by the group, not a per-caller fan-out: only one
+	// consumer per group should drain it at a time.
 	Watch() <-chan struct{}
 }
 
+// DefaultGroup names the consumer group served by the Store itself.
+const DefaultGroup = ""
+
 // Store is the outbox backed by the sap database. It is both the Emitter used
-// by the sync engine and the Outbox read by the consumer.
+// by the sync engine and the Outbox read by the default consumer group; Group
+// returns the Outbox for any other.
 type Store struct {
 	db     *gorm.DB
 	notify *utils.PollNotifier
+	groups *groupNotifiers
+	// defaultRegistered is set once the default group's registration is
+	// known to be stored; shared by every transaction-scoped copy.
+	defaultRegistered *atomic.Bool
+
+	retention   time.Duration
+	compact     bool
+	maxAttempts int
+	lease       time.Duration
 }
 
 var _ Outbox = (*Store)(nil)
 
-func NewStore(db *gorm.DB, notify *utils.PollNotifier) (*Store, error) {
-	if err := db.AutoMigrate(&outboxMessage{}); err != nil {
+type options struct {
+	retention   time.Duration
+	compact     bool
+	maxAttempts int
+	lease       time.Duration
+	meter       metric.Meter
+}
+
+// DefaultLease is how long a polled message stays leased to its consumer
+// group unless WithLease says otherwise.
+const DefaultLease = 5 * time.Minute
+
+// WithRetention makes Run delete messages once every consumer group has acked
+// them and the acks are older than d. Zero (the default) keeps them forever.
+func WithRetention(d time.Duration) utils.Opt[options] {
+	return func(o *options) {
+		o.retention = d
+	}
+}
+
+// WithCompaction sets whether Run collapses messages no consumer group has
+// acked yet down to the latest per record.
+func WithCompaction(compact bool) utils.Opt[options] {
+	return func(o *options) {
+		o.compact = compact
+	}
+}
+
+// WithMaxAttempts sets how many failed deliveries of a message a consumer
+// group gets before it is dead-lettered, for every group without its own
+// policy (see SetMaxAttempts). Zero (the default) redelivers forever.
+func WithMaxAttempts(n int) utils.Opt[options] {
+	return func(o *options) {
+		o.maxAttempts = n
+	}
+}
+
+// WithLease sets how long a polled message is leased to its consumer group.
+// Polls within the lease redeliver it without counting an attempt; once the
+// lease expires without an ack, the delivery counts as failed. Zero or less
+// keeps DefaultLease.
+func WithLease(d time.Duration) utils.Opt[options] {
+	return func(o *options) {
+		if d > 0 {
+			o.lease = d
+		}
+	}
+}
+
+// WithMeter exports outbox depth and consumer lag per group through meter.
+func WithMeter(meter metric.Meter) utils.Opt[options] {
+	return func(o *options) {
+		o.meter = meter
+	}
+}
+
+func NewStore(
+	db *gorm.DB,
+	notify *utils.PollNotifier,
+	opts ...utils.Opt[options],
+) (*Store, error) {
+	o := utils.ResolveOptions(options{lease: DefaultLease}, opts)
+	if err := db.AutoMigrate(
+		&outboxMessage{},
+		&consumerGroup{},
+		&consumerAck{},
+		&deliveryAttempt{},
+		&deadLetter{},
+		&groupPolicy{},
+	); err != nil {
 		return nil, err
 	}
-	return &Store{db: db, notify: notify}, nil
+	s := &Store{
+		db:                db,
+		notify:            notify,
+		groups:            &groupNotifiers{byName: make(map[string]*utils.PollNotifier)},
+		defaultRegistered: &atomic.Bool{},
+		retention:         o.retention,
+		compact:           o.compact,
+		maxAttempts:       o.maxAttempts,
+		lease:             o.lease,
+	}
+	if o.meter != nil {
+		if err := s.registerMetrics(o.meter); err != nil {
+			return nil, fmt.Errorf("register outbox metrics: %w", err)
+		}
+	}
+	return s, nil
 }
 
 // WithTx returns a Store scoped to the given transaction, so emits can join a
 // caller's transaction.
 func (s *Store) WithTx(tx *gorm.DB) *Store {
-	return &Store{db: tx, notify: s.notify}
+	c := *s
+	c.db = tx
+	return &c
 }
 
-// Emit appends a record for delivery and wakes the watcher.
-func (s *Store) Emit(
-	ctx context.Context,
-	uri habitat_syntax.SpaceRecordURI,
-	value []byte,
-) error {
+// Group returns the Outbox for the named consumer group, registering the group
+// on first use. DefaultGroup returns the Store itself.
+func (s *Store) Group(ctx context.Context, name string) (Outbox, error) {
+	if name == DefaultGroup {
+		return s, nil
+	}
 	if err := s.db.WithContext(ctx).
-		Create(&outboxMessage{URI: uri, Value: value}).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&consumerGroup{Name: name}).Error; err != nil {
+		return nil, fmt.Errorf("register consumer group %q: %w", name, err)
+	}
+	return &group{store: s, name: name, notify: s.groups.get(name)}, nil
+}
+
+// Groups lists the registered named consumer groups.
+func (s *Store) Groups(ctx context.Context) ([]string, error) {
+	var names []string
+	if err := s.db.WithContext(ctx).
+		Model(&consumerGroup{}).
+		Where("name <> ?", DefaultGroup).
+		Order("name ASC").
+		Pluck("name", &names).Error; err != nil {
+		return nil, fmt.Errorf("list consumer groups: %w", err)
+	}
+	return names, nil
+}
+
+// Emit appends a message for delivery and wakes every group's watcher. The
+// message's ID is assigned by the store.
+func (s *Store) Emit(ctx context.Context, msg Message) error {
+	if err := s.db.WithContext(ctx).
+		Create(&outboxMessage{
+			Space:     msg.Space,
+			Repo:      msg.Repo,
+			Action:    msg.Action,
+			URI:       msg.URI,
+			Rev:       msg.Rev,
+			Cid:       msg.Cid,
+			PrevCid:   msg.PrevCid,
+			Value:     msg.Value,
+			PrevValue: msg.PrevValue,
+		}).
 		Error; err != nil {
 		return fmt.Errorf("emit record: %w", err)
 	}
 	s.notify.Notify()
+	s.groups.notifyAll()
 	return nil
 }
 
 // Poll implements [Outbox].
 func (s *Store) Poll(ctx context.Context, limit int) ([]Message, error) {
-	var rows []outboxMessage
-	if err := s.db.WithContext(ctx).
-		Where("acked_at IS NULL").
-		Order("id ASC").
-		Limit(limit).
-		Find(&rows).Error; err != nil {
-		return nil, fmt.Errorf("poll outbox: %w", err)
-	}
-
-	msgs := make([]Message, len(rows))
-	for i, row := range rows {
-		msgs[i] = Message{
-			ID:    row.ID,
-			URI:   row.URI,
-			Value: json.RawMessage(row.Value),
+	if !s.defaultRegistered.Load() {
+		if err := s.db.WithContext(ctx).
+			Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&consumerGroup{Name: DefaultGroup}).Error; err != nil {
+			return nil, fmt.Errorf("register default consumer group: %w", err)
 		}
+		s.defaultRegistered.Store(true)
+	}
+	msgs, err := s.poll(ctx, DefaultGroup, limit)
+	if err != nil {
+		return nil, fmt.Errorf("poll outbox: %w", err)
 	}
 	return msgs, nil
 }
 
 // Ack implements [Outbox].
 func (s *Store) Ack(ctx context.Context, id uint) error {
-	return s.db.WithContext(ctx).
-		Model(&outboxMessage{}).
-		Where("id = ?", id).
-		Update("acked_at", time.Now()).Error
+	return s.ack(ctx, DefaultGroup, id)
+}
+
+// Nack implements [Outbox].
+func (s *Store) Nack(ctx context.Context, id uint, reason string) error {
+	return s.nack(ctx, DefaultGroup, id, reason)
 }
 
 // Watch implements [Outbox].
 func (s *Store) Watch() <-chan struct{} {
 	return s.notify.Listen()
 }
+
+// group is the Outbox for one named consumer group: the same ordered log as
+// the Store, with acks tracked per group.
+type group struct {
+	store  *Store
+	name   string
+	notify *utils.PollNotifier
+}
+
+// Poll implements [Outbox].
+func (g *group) Poll(ctx context.Context, limit int) ([]Message, error) {
+	msgs, err := g.store.poll(ctx, g.name, limit)
+	if err != nil {
+		return nil, fmt.Errorf("poll outbox for group %q: %w", g.name, err)
+	}
+	return msgs, nil
+}
+
+// Ack implements [Outbox].
+func (g *group) Ack(ctx context.Context, id uint) error {
+	return g.store.ack(ctx, g.name, id)
+}
+
+// Nack implements [Outbox].
+func (g *group) Nack(ctx context.Context, id uint, reason string) error {
+	return g.store.nack(ctx, g.name, id, reason)
+}
+
+// Watch implements [Outbox].
+func (g *group) Watch() <-chan struct{} {
+	return g.notify.Listen()
+}
+
+// unacked selects the messages group has not acked. The default group keeps
+// its acks on the message row, named groups in consumerAck.
+func unacked(db *gorm.DB, group string) *gorm.DB {
+	if group == DefaultGroup {
+		return db.Model(&outboxMessage{}).Where("acked_at IS NULL")
+	}
+	return db.Model(&outboxMessage{}).
+		Where("id NOT IN (?)", db.Model(&consumerAck{}).
+			Select("message_id").
+			Where("consumer = ?", group))
+}
+
+// markAcked records that group is done with the message id.
+func markAcked(tx *gorm.DB, group string, id uint) error {
+	if group == DefaultGroup {
+		return tx.Model(&outboxMessage{}).
+			Where("id = ?", id).
+			Update("acked_at", time.Now()).Error
+	}
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&consumerAck{Consumer: group, MessageID: id, AckedAt: time.Now()}).Error
+}
+
+// markUnacked hands the message id back to group for delivery.
+func markUnacked(tx *gorm.DB, group string, id uint) error {
+	if group == DefaultGroup {
+		return tx.Model(&outboxMessage{}).
+			Where("id = ?", id).
+			Update("acked_at", nil).Error
+	}
+	return tx.Where("consumer = ? AND message_id = ?", group, id).
+		Delete(&consumerAck{}).Error
+}
+
+func (s *Store) ack(ctx context.Context, group string, id uint) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := markAcked(tx, group, id); err != nil {
+			return err
+		}
+		return tx.Where("consumer = ? AND message_id = ?", group, id).
+			Delete(&deliveryAttempt{}).Error
+	})
+}
+
+// notifier returns the watcher notifier of group.
+func (s *Store) notifier(group string) *utils.PollNotifier {
+	if group == DefaultGroup {
+		return s.notify
+	}
+	return s.groups.get(group)
+}
+
+// groupNotifiers holds one PollNotifier per named group, shared by every
+// transaction-scoped copy of a Store so an emit inside a transaction still
+// wakes every group.
+type groupNotifiers struct {
+	mu     sync.Mutex
+	byName map[string]*utils.PollNotifier
+}
+
+func (n *groupNotifiers) get(name string) *utils.PollNotifier {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	notif, ok := n.byName[name]
+	if !ok {
+		notif = utils.NewPollNotifier()
+		n.byName[name] = notif
+	}
+	return notif
+}
+
+func (n *groupNotifiers) notifyAll() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for _, notif := range n.byName {
+		notif.Notify()
+	}
+}
+
+func toMessages(rows []outboxMessage) []Message {
+	msgs := make([]Message, len(rows))
+	for i, row := range rows {
+		msgs[i] = Message{
+			ID:        row.ID,
+			Space:     row.Space,
+			Repo:      row.Repo,
+			Action:    row.Action,
+			URI:       row.URI,
+			Rev:       row.Rev,
+			Cid:       row.Cid,
+			PrevCid:   row.PrevCid,
+			Value:     json.RawMessage(row.Value),
+			PrevValue: json.RawMessage(row.PrevValue),
+		}
+	}
+	return msgs
+}
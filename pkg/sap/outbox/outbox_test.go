@@ -2,7 +2,9 @@ package outbox
 
 import (
 	"testing"
+	"time"
 
+	"github.com/bluesky-social/indigo/atproto/syntax"
 	"github.com/stretchr/testify/require"
 
 	db_testutil "github.com/habitat-network/habitat/internal/db/testutil"
@@ -19,13 +21,18 @@ func TestStoreEmitPollAck(t *testing.T) {
 	uri := habitat_syntax.SpaceRecordURI(
 		"at://did:plc:o/space/network.habitat.space/s1/did:plc:a/network.habitat.test/k1",
 	)
-	require.NoError(t, s.Emit(t.Context(), uri, []byte(`{"n":1}`)))
-	require.NoError(t, s.Emit(t.Context(), uri, []byte(`{"n":2}`)))
+	require.NoError(t, s.Emit(t.Context(), Message{
+		URI: uri, Action: ActionCreate, Value: []byte(`{"n":1}`),
+	}))
+	require.NoError(t, s.Emit(t.Context(), Message{
+		URI: uri, Action: ActionCreate, Value: []byte(`{"n":2}`),
+	}))
 
 	msgs, err := s.Poll(t.Context(), 10)
 	require.NoError(t, err)
 	require.Len(t, msgs, 2)
 	require.Equal(t, uri, msgs[0].URI)
+	require.Equal(t, ActionCreate, msgs[0].Action)
 	require.JSONEq(t, `{"n":1}`, string(msgs[0].Value))
 
 	// Acked messages are not redelivered; unacked ones are.
@@ -35,3 +42,474 @@ func TestStoreEmitPollAck(t *testing.T) {
 	require.Len(t, msgs, 1)
 	require.JSONEq(t, `{"n":2}`, string(msgs[0].Value))
 }
+
+func TestStoreGroupsAckIndependently(t *testing.T) {
+	t.Parallel()
+	db := db_testutil.NewDB(t)
+	s, err := NewStore(db, utils.NewPollNotifier())
+	require.NoError(t, err)
+
+	uri := habitat_syntax.SpaceRecordURI(
+		"at://did:plc:o/space/network.habitat.space/s1/did:plc:a/network.habitat.test/k1",
+	)
+	require.NoError(t, s.Emit(t.Context(), Message{
+		URI: uri, Action: ActionCreate, Value: []byte(`{"n":1}`),
+	}))
+
+	search, err := s.Group(t.Context(), "search")
+	require.NoError(t, err)
+	home, err := s.Group(t.Context(), "home")
+	require.NoError(t, err)
+
+	// A group created after the emit still sees the message.
+	msgs, err := search.Poll(t.Context(), 10)
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+	require.NoError(t, search.Ack(t.Context(), msgs[0].ID))
+
+	// Acking in one group leaves it pending for the others.
+	msgs, err = search.Poll(t.Context(), 10)
+	require.NoError(t, err)
+	require.Empty(t, msgs)
+	msgs, err = home.Poll(t.Context(), 10)
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+	msgs, err = s.Poll(t.Context(), 10)
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+
+	// Every group's watcher wakes on a new emit.
+	require.NoError(t, s.Emit(t.Context(), Message{
+		URI: uri, Action: ActionCreate, Value: []byte(`{"n":2}`),
+	}))
+	select {
+	case <-home.Watch():
+	default:
+		t.Fatal("expected home group to be notified")
+	}
+	select {
+	case <-search.Watch():
+	default:
+		t.Fatal("expected search group to be notified")
+	}
+
+	groups, err := s.Groups(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, []string{"home", "search"}, groups)
+}
+
+func TestStoreEmitRoundTripsEventFields(t *testing.T) {
+	t.Parallel()
+	db := db_testutil.NewDB(t)
+	s, err := NewStore(db, utils.NewPollNotifier())
+	require.NoError(t, err)
+
+	want := Message{
+		Space:     "at://did:plc:o/space/network.habitat.space/s1",
+		Repo:      "did:plc:a",
+		Action:    ActionUpdate,
+		URI:       "at://did:plc:o/space/network.habitat.space/s1/did:plc:a/network.habitat.test/k1",
+		Rev:       syntax.TID(syntax.NewTIDClock(0).Next().String()),
+		Cid:       "bafybbb",
+		PrevCid:   "bafyaaa",
+		Value:     []byte(`{"n":2}`),
+		PrevValue: []byte(`{"n":1}`),
+	}
+	require.NoError(t, s.Emit(t.Context(), want))
+	require.NoError(t, s.Emit(t.Context(), Message{
+		Space:  want.Space,
+		Repo:   want.Repo,
+		Action: ActionResync,
+		Rev:    want.Rev,
+	}))
+
+	msgs, err := s.Poll(t.Context(), 10)
+	require.NoError(t, err)
+	require.Len(t, msgs, 2)
+	want.ID = msgs[0].ID
+	want.Attempts = 1
+	require.Equal(t, want, msgs[0])
+	require.Equal(t, ActionResync, msgs[1].Action)
+	require.Empty(t, msgs[1].URI)
+	require.Nil(t, msgs[1].Value)
+}
+
+func TestStorePruneWaitsForEveryGroup(t *testing.T) {
+	t.Parallel()
+	db := db_testutil.NewDB(t)
+	s, err := NewStore(db, utils.NewPollNotifier())
+	require.NoError(t, err)
+
+	uri := habitat_syntax.SpaceRecordURI(
+		"at://did:plc:o/space/network.habitat.space/s1/did:plc:a/network.habitat.test/k1",
+	)
+	require.NoError(t, s.Emit(t.Context(), Message{
+		URI: uri, Action: ActionCreate, Value: []byte(`{"n":1}`),
+	}))
+	search, err := s.Group(t.Context(), "search")
+	require.NoError(t, err)
+	msgs, err := s.Poll(t.Context(), 10)
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+	require.NoError(t, s.Ack(t.Context(), msgs[0].ID))
+
+	// Acked by the default group only: the search group still needs it.
+	cutoff := time.Now().Add(time.Hour)
+	pruned, err := s.Prune(t.Context(), cutoff)
+	require.NoError(t, err)
+	require.Zero(t, pruned)
+
+	require.NoError(t, search.Ack(t.Context(), msgs[0].ID))
+	// Acked by everyone, but more recently than the cutoff.
+	pruned, err = s.Prune(t.Context(), time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	require.Zero(t, pruned)
+
+	pruned, err = s.Prune(t.Context(), cutoff)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), pruned)
+	var acks int64
+	require.NoError(t, db.Model(&consumerAck{}).Count(&acks).Error)
+	require.Zero(t, acks, "a pruned message's acks go with it")
+}
+
+func TestStorePruneWithOnlyNamedGroups(t *testing.T) {
+	t.Parallel()
+	db := db_testutil.NewDB(t)
+	s, err := NewStore(db, utils.NewPollNotifier())
+	require.NoError(t, err)
+
+	require.NoError(t, s.Emit(t.Context(), Message{
+		URI: habitat_syntax.SpaceRecordURI(testURIPrefix + "k1"), Action: ActionCreate,
+	}))
+	search, err := s.Group(t.Context(), "search")
+	require.NoError(t, err)
+	msgs, err := search.Poll(t.Context(), 10)
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+	require.NoError(t, search.Ack(t.Context(), msgs[0].ID))
+
+	// The default group never polled, so it does not hold the message back.
+	pruned, err := s.Prune(t.Context(), time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, int64(1), pruned)
+
+	// Once it has polled, it does.
+	require.NoError(t, s.Emit(t.Context(), Message{
+		URI: habitat_syntax.SpaceRecordURI(testURIPrefix + "k2"), Action: ActionCreate,
+	}))
+	_, err = s.Poll(t.Context(), 0)
+	require.NoError(t, err)
+	msgs, err = search.Poll(t.Context(), 10)
+	require.NoError(t, err)
+	require.NoError(t, search.Ack(t.Context(), msgs[0].ID))
+	pruned, err = s.Prune(t.Context(), time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	require.Zero(t, pruned)
+
+	groups, err := s.Groups(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, []string{"search"}, groups)
+}
+
+func TestStoreCompactCollapsesUnreadMessagesPerRecord(t *testing.T) {
+	t.Parallel()
+	db := db_testutil.NewDB(t)
+	s, err := NewStore(db, utils.NewPollNotifier())
+	require.NoError(t, err)
+
+	k1 := habitat_syntax.SpaceRecordURI(
+		"at://did:plc:o/space/network.habitat.space/s1/did:plc:a/network.habitat.test/k1",
+	)
+	k2 := habitat_syntax.SpaceRecordURI(
+		"at://did:plc:o/space/network.habitat.space/s1/did:plc:a/network.habitat.test/k2",
+	)
+	emit := func(msg Message) {
+		t.Helper()
+		require.NoError(t, s.Emit(t.Context(), msg))
+	}
+	emit(Message{URI: k1, Action: ActionCreate, Cid: "bafy1", Value: []byte(`{"n":1}`)})
+	emit(Message{URI: k2, Action: ActionUpdate, Cid: "bafy5", PrevCid: "bafy4",
+		Value: []byte(`{"m":5}`)})
+	emit(Message{URI: k1, Action: ActionUpdate, Cid: "bafy2", PrevCid: "bafy1",
+		Value: []byte(`{"n":2}`)})
+	emit(Message{URI: k1, Action: ActionUpdate, Cid: "bafy3", PrevCid: "bafy2",
+		Value: []byte(`{"n":3}`)})
+	emit(Message{Action: ActionResync, Repo: "did:plc:a"})
+	emit(Message{Action: ActionResync, Repo: "did:plc:a"})
+
+	compacted, err := s.Compact(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, int64(2), compacted)
+
+	msgs, err := s.Poll(t.Context(), 10)
+	require.NoError(t, err)
+	require.Len(t, msgs, 4, "k2, the collapsed k1, and both resync markers")
+	require.Equal(t, k2, msgs[0].URI)
+	require.Equal(t, k1, msgs[1].URI)
+	require.Equal(t, ActionCreate, msgs[1].Action, "k1 did not exist before the run")
+	require.Empty(t, msgs[1].PrevCid)
+	require.Equal(t, "bafy3", msgs[1].Cid)
+	require.JSONEq(t, `{"n":3}`, string(msgs[1].Value))
+}
+
+func TestStoreCompactLeavesMessagesAGroupHasRead(t *testing.T) {
+	t.Parallel()
+	db := db_testutil.NewDB(t)
+	s, err := NewStore(db, utils.NewPollNotifier())
+	require.NoError(t, err)
+
+	uri := habitat_syntax.SpaceRecordURI(
+		"at://did:plc:o/space/network.habitat.space/s1/did:plc:a/network.habitat.test/k1",
+	)
+	require.NoError(t, s.Emit(t.Context(), Message{
+		URI: uri, Action: ActionCreate, Value: []byte(`{"n":1}`),
+	}))
+	require.NoError(t, s.Emit(t.Context(), Message{
+		URI: uri, Action: ActionUpdate, PrevCid: "bafy1", Value: []byte(`{"n":2}`),
+	}))
+	search, err := s.Group(t.Context(), "search")
+	require.NoError(t, err)
+	msgs, err := search.Poll(t.Context(), 1)
+	require.NoError(t, err)
+	require.NoError(t, search.Ack(t.Context(), msgs[0].ID))
+
+	compacted, err := s.Compact(t.Context())
+	require.NoError(t, err)
+	require.Zero(t, compacted)
+}
+
+func TestStoreCompactLeavesMessagesBeingDelivered(t *testing.T) {
+	t.Parallel()
+	db := db_testutil.NewDB(t)
+	s, err := NewStore(db, utils.NewPollNotifier())
+	require.NoError(t, err)
+
+	uri := habitat_syntax.SpaceRecordURI(testURIPrefix + "k1")
+	require.NoError(t, s.Emit(t.Context(), Message{
+		URI: uri, Action: ActionCreate, Cid: "bafy1", Value: []byte(`{"n":1}`),
+	}))
+	search, err := s.Group(t.Context(), "search")
+	require.NoError(t, err)
+	leased, err := search.Poll(t.Context(), 1)
+	require.NoError(t, err)
+	require.Len(t, leased, 1)
+	require.NoError(t, s.Emit(t.Context(), Message{
+		URI: uri, Action: ActionUpdate, Cid: "bafy2", PrevCid: "bafy1", Value: []byte(`{"n":2}`),
+	}))
+
+	// The leased create is not acked yet, but search is processing it.
+	compacted, err := s.Compact(t.Context())
+	require.NoError(t, err)
+	require.Zero(t, compacted)
+	require.NoError(t, search.Ack(t.Context(), leased[0].ID))
+	msgs, err := search.Poll(t.Context(), 10)
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+	require.Equal(t, ActionUpdate, msgs[0].Action)
+	require.Equal(t, "bafy1", msgs[0].PrevCid)
+}
+
+func TestStoreCompactDropsRecordsCreatedAndDeleted(t *testing.T) {
+	t.Parallel()
+	db := db_testutil.NewDB(t)
+	s, err := NewStore(db, utils.NewPollNotifier())
+	require.NoError(t, err)
+
+	uri := habitat_syntax.SpaceRecordURI(testURIPrefix + "k1")
+	for _, msg := range []Message{
+		{URI: uri, Action: ActionCreate, Cid: "bafy1", Value: []byte(`{"n":1}`)},
+		{URI: uri, Action: ActionUpdate, Cid: "bafy2", PrevCid: "bafy1", Value: []byte(`{"n":2}`)},
+		{URI: uri, Action: ActionDelete, PrevCid: "bafy2"},
+	} {
+		require.NoError(t, s.Emit(t.Context(), msg))
+	}
+
+	compacted, err := s.Compact(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, int64(3), compacted)
+	msgs, err := s.Poll(t.Context(), 10)
+	require.NoError(t, err)
+	require.Empty(t, msgs, "no consumer saw the record, so it never existed for them")
+}
+
+func TestStoreStatsReportsDepthAndLagPerGroup(t *testing.T) {
+	t.Parallel()
+	db := db_testutil.NewDB(t)
+	s, err := NewStore(db, utils.NewPollNotifier())
+	require.NoError(t, err)
+
+	uri := habitat_syntax.SpaceRecordURI(
+		"at://did:plc:o/space/network.habitat.space/s1/did:plc:a/network.habitat.test/k1",
+	)
+	search, err := s.Group(t.Context(), "search")
+	require.NoError(t, err)
+	for range 2 {
+		require.NoError(t, s.Emit(t.Context(), Message{
+			URI: uri, Action: ActionCreate, Value: []byte(`{}`),
+		}))
+	}
+	msgs, err := search.Poll(t.Context(), 10)
+	require.NoError(t, err)
+	for _, msg := range msgs {
+		require.NoError(t, search.Ack(t.Context(), msg.ID))
+	}
+
+	stats, err := s.Stats(t.Context())
+	require.NoError(t, err)
+	require.Len(t, stats, 2)
+	require.Equal(t, DefaultGroup, stats[0].Group)
+	require.Equal(t, int64(2), stats[0].Depth)
+	require.Positive(t, stats[0].Lag)
+	require.Equal(t, "search", stats[1].Group)
+	require.Zero(t, stats[1].Depth)
+	require.Zero(t, stats[1].Lag)
+}
+
+const testURIPrefix = "at://did:plc:o/space/network.habitat.space/s1/did:plc:a/network.habitat.test/"
+
+func TestStoreDeadLettersAfterMaxAttempts(t *testing.T) {
+	t.Parallel()
+	db := db_testutil.NewDB(t)
+	s, err := NewStore(db, utils.NewPollNotifier(), WithMaxAttempts(2), WithLease(time.Nanosecond))
+	require.NoError(t, err)
+
+	for _, rkey := range []string{"k1", "k2"} {
+		require.NoError(t, s.Emit(t.Context(), Message{
+			URI:    habitat_syntax.SpaceRecordURI(testURIPrefix + rkey),
+			Action: ActionCreate,
+		}))
+	}
+
+	// A message that is never acked is counted each time its lease expires...
+	msgs, err := s.Poll(t.Context(), 1)
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+	require.Equal(t, 1, msgs[0].Attempts)
+	poison := msgs[0].ID
+	msgs, err = s.Poll(t.Context(), 1)
+	require.NoError(t, err)
+	require.Equal(t, poison, msgs[0].ID)
+	require.Equal(t, 2, msgs[0].Attempts)
+
+	// ...and dead-lettered once it has used them up, unblocking the stream.
+	msgs, err = s.Poll(t.Context(), 1)
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+	require.NotEqual(t, poison, msgs[0].ID)
+
+	// A nack on the last attempt dead-letters at once, keeping the reason.
+	require.NoError(t, s.Nack(t.Context(), msgs[0].ID, "first try"))
+	msgs, err = s.Poll(t.Context(), 1)
+	require.NoError(t, err)
+	require.NoError(t, s.Nack(t.Context(), msgs[0].ID, "bad value"))
+	msgs, err = s.Poll(t.Context(), 10)
+	require.NoError(t, err)
+	require.Empty(t, msgs)
+
+	letters, err := s.DeadLetters(t.Context(), DefaultGroup)
+	require.NoError(t, err)
+	require.Len(t, letters, 2)
+	require.Equal(t, poison, letters[0].Message.ID)
+	require.Equal(t, "not acked after 2 deliveries", letters[0].Reason)
+	require.Equal(t, 2, letters[0].Message.Attempts)
+	require.Equal(t, "bad value", letters[1].Reason)
+
+	stats, err := s.Stats(t.Context())
+	require.NoError(t, err)
+	require.EqualValues(t, 0, stats[0].Depth)
+	require.EqualValues(t, 2, stats[0].DeadLetters)
+
+	// Dead-lettered messages outlive retention until dealt with.
+	pruned, err := s.Prune(t.Context(), time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	require.Zero(t, pruned)
+
+	// Replay redelivers with fresh attempts; discard lets the message go.
+	require.NoError(t, s.Replay(t.Context(), letters[0].ID))
+	require.NoError(t, s.Discard(t.Context(), letters[1].ID))
+	require.ErrorIs(t, s.Discard(t.Context(), letters[1].ID), ErrUnknownDeadLetter)
+	msgs, err = s.Poll(t.Context(), 10)
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+	require.Equal(t, poison, msgs[0].ID)
+	require.Equal(t, 1, msgs[0].Attempts)
+	require.NoError(t, s.Ack(t.Context(), poison))
+
+	pruned, err = s.Prune(t.Context(), time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	require.EqualValues(t, 2, pruned)
+}
+
+func TestStorePollWithinLeaseDoesNotCountAttempts(t *testing.T) {
+	t.Parallel()
+	db := db_testutil.NewDB(t)
+	s, err := NewStore(db, utils.NewPollNotifier(), WithMaxAttempts(2))
+	require.NoError(t, err)
+	require.NoError(t, s.Emit(t.Context(), Message{
+		URI:    habitat_syntax.SpaceRecordURI(testURIPrefix + "k1"),
+		Action: ActionCreate,
+	}))
+
+	// Polling again before the consumer acks is not a failure.
+	for range 3 {
+		msgs, err := s.Poll(t.Context(), 10)
+		require.NoError(t, err)
+		require.Len(t, msgs, 1)
+		require.Equal(t, 1, msgs[0].Attempts)
+	}
+
+	// An expired lease is.
+	require.NoError(t, db.Model(&deliveryAttempt{}).Where("consumer = ?", DefaultGroup).
+		Update("delivered_at", time.Now().Add(-2*DefaultLease)).Error)
+	msgs, err := s.Poll(t.Context(), 10)
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+	require.Equal(t, 2, msgs[0].Attempts)
+
+	// The next one dead-letters it.
+	require.NoError(t, db.Model(&deliveryAttempt{}).Where("consumer = ?", DefaultGroup).
+		Update("delivered_at", time.Now().Add(-2*DefaultLease)).Error)
+	msgs, err = s.Poll(t.Context(), 10)
+	require.NoError(t, err)
+	require.Empty(t, msgs)
+	letters, err := s.DeadLetters(t.Context(), DefaultGroup)
+	require.NoError(t, err)
+	require.Len(t, letters, 1)
+	require.Equal(t, "not acked after 2 deliveries", letters[0].Reason)
+}
+
+func TestStoreMaxAttemptsPerGroup(t *testing.T) {
+	t.Parallel()
+	db := db_testutil.NewDB(t)
+	s, err := NewStore(db, utils.NewPollNotifier(), WithMaxAttempts(1))
+	require.NoError(t, err)
+	require.NoError(t, s.Emit(t.Context(), Message{
+		URI:    habitat_syntax.SpaceRecordURI(testURIPrefix + "k1"),
+		Action: ActionCreate,
+	}))
+
+	search, err := s.Group(t.Context(), "search")
+	require.NoError(t, err)
+	require.NoError(t, s.SetMaxAttempts(t.Context(), "search", -1))
+
+	// The default group gives up after one failure...
+	msgs, err := s.Poll(t.Context(), 10)
+	require.NoError(t, err)
+	require.NoError(t, s.Nack(t.Context(), msgs[0].ID, "boom"))
+	msgs, err = s.Poll(t.Context(), 10)
+	require.NoError(t, err)
+	require.Empty(t, msgs)
+
+	// ...while search, with no limit, keeps getting it.
+	for attempt := 1; attempt <= 3; attempt++ {
+		msgs, err = search.Poll(t.Context(), 10)
+		require.NoError(t, err)
+		require.Len(t, msgs, 1)
+		require.Equal(t, attempt, msgs[0].Attempts)
+		require.NoError(t, search.Nack(t.Context(), msgs[0].ID, "boom"))
+	}
+	letters, err := s.DeadLetters(t.Context(), "search")
+	require.NoError(t, err)
+	require.Empty(t, letters)
+}
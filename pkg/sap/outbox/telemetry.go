@@ -0,0 +1,143 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"gorm.io/gorm"
+)
+
+// defaultGroupLabel is the metric label for DefaultGroup, whose name is empty.
+const defaultGroupLabel = "default"
+
+// GroupStats is how far one consumer group is behind the log.
+type GroupStats struct {
+	Group string
+	// Depth is the number of messages the group has not acked.
+	Depth int64
+	// Lag is the age of the oldest message the group has not acked; zero when
+	// it is caught up.
+	Lag time.Duration
+	// DeadLetters is the number of messages the group gave up on that have
+	// been neither replayed nor discarded.
+	DeadLetters int64
+}
+
+// Stats reports depth and lag for the default group and every named group.
+func (s *Store) Stats(ctx context.Context) ([]GroupStats, error) {
+	names, err := s.Groups(ctx)
+	if err != nil {
+		return nil, err
+	}
+	db := s.db.WithContext(ctx)
+	stats := make([]GroupStats, 0, len(names)+1)
+
+	def, err := groupStats(db.Model(&outboxMessage{}).Where("acked_at IS NULL"))
+	if err != nil {
+		return nil, fmt.Errorf("stats for default group: %w", err)
+	}
+	def.Group = DefaultGroup
+	stats = append(stats, def)
+
+	for _, name := range names {
+		st, err := groupStats(db.Model(&outboxMessage{}).
+			Where("id NOT IN (?)", db.Model(&consumerAck{}).
+				Select("message_id").
+				Where("consumer = ?", name)))
+		if err != nil {
+			return nil, fmt.Errorf("stats for group %q: %w", name, err)
+		}
+		st.Group = name
+		stats = append(stats, st)
+	}
+
+	var dead []struct {
+		Consumer string
+		Count    int64
+	}
+	if err := db.Model(&deadLetter{}).
+		Select("consumer, COUNT(*) AS count").
+		Group("consumer").
+		Scan(&dead).Error; err != nil {
+		return nil, fmt.Errorf("count dead letters: %w", err)
+	}
+	for _, d := range dead {
+		for i := range stats {
+			if stats[i].Group == d.Consumer {
+				stats[i].DeadLetters = d.Count
+			}
+		}
+	}
+	return stats, nil
+}
+
+// groupStats measures the unacked messages selected by pending.
+func groupStats(pending *gorm.DB) (GroupStats, error) {
+	var st GroupStats
+	if err := pending.Session(&gorm.Session{}).Count(&st.Depth).Error; err != nil {
+		return st, err
+	}
+	if st.Depth == 0 {
+		return st, nil
+	}
+	var oldest []outboxMessage
+	if err := pending.Session(&gorm.Session{}).
+		Order("id ASC").
+		Limit(1).
+		Find(&oldest).Error; err != nil {
+		return st, err
+	}
+	if len(oldest) > 0 {
+		st.Lag = time.Since(oldest[0].CreatedAt)
+	}
+	return st, nil
+}
+
+// registerMetrics exports Stats as observable gauges, read on each collection.
+func (s *Store) registerMetrics(meter metric.Meter) error {
+	depth, err := meter.Int64ObservableGauge(
+		"sap.outbox.depth",
+		metric.WithUnit("item"),
+		metric.WithDescription("number of messages a consumer group has not acked, by group"),
+	)
+	if err != nil {
+		return err
+	}
+	lag, err := meter.Float64ObservableGauge(
+		"sap.outbox.lag",
+		metric.WithUnit("s"),
+		metric.WithDescription("age of the oldest message a consumer group has not acked, by group"),
+	)
+	if err != nil {
+		return err
+	}
+	dead, err := meter.Int64ObservableGauge(
+		"sap.outbox.dead_letters",
+		metric.WithUnit("item"),
+		metric.WithDescription("number of messages a consumer group dead-lettered, by group"),
+	)
+	if err != nil {
+		return err
+	}
+	_, err = meter.RegisterCallback(func(ctx context.Context, o metric.Observer) error {
+		stats, err := s.Stats(ctx)
+		if err != nil {
+			return err
+		}
+		for _, st := range stats {
+			label := st.Group
+			if label == DefaultGroup {
+				label = defaultGroupLabel
+			}
+			attrs := metric.WithAttributeSet(attribute.NewSet(attribute.String("group", label)))
+			o.ObserveInt64(depth, st.Depth, attrs)
+			o.ObserveFloat64(lag, st.Lag.Seconds(), attrs)
+			o.ObserveInt64(dead, st.DeadLetters, attrs)
+		}
+		return nil
+	}, depth, lag, dead)
+	return err
+}
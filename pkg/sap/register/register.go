@@ -138,6 +138,20 @@ func (r *Registrar) dueSpaces(ctx context.Context) ([]habitat_syntax.SpaceURI, e
 	return due, nil
 }
 
+// Registered lists the spaces whose registration for this endpoint has not
+// expired, i.e. whose host should be pushing notifications to sap. A space
+// the host refused to register is absent.
+func (r *Registrar) Registered(ctx context.Context) ([]habitat_syntax.SpaceURI, error) {
+	var spaces []habitat_syntax.SpaceURI
+	if err := r.db.WithContext(ctx).
+		Model(&registration{}).
+		Where("endpoint = ? AND expires_at > ?", r.endpoint, time.Now()).
+		Pluck("space", &spaces).Error; err != nil {
+		return nil, fmt.Errorf("list registrations: %w", err)
+	}
+	return spaces, nil
+}
+
 // EnsureRegistered registers a space the registrar is not tracking yet. The
 // crawler calls this for every space it lists, so each space is registered
 // exactly once at discovery; renewing existing registrations is the sweep's
@@ -181,3 +181,31 @@ func TestRegistrarRun(t *testing.T) {
 	cancel()
 	reg.Run(ctx)
 }
+
+func TestRegistrarRegisteredListsLiveRegistrations(t *testing.T) {
+	t.Parallel()
+
+	db := db_testutil.NewDB(t)
+	reg, err := New(db, fakeClients{}, fakeSpaces{}, "https://sap.example")
+	require.NoError(t, err)
+	live := habitat_syntax.SpaceURI("at://did:plc:owner/space/network.habitat.space/live")
+	for _, r := range []registration{
+		{Space: live, Endpoint: "https://sap.example", ExpiresAt: time.Now().Add(time.Hour)},
+		{
+			Space:     "at://did:plc:owner/space/network.habitat.space/expired",
+			Endpoint:  "https://sap.example",
+			ExpiresAt: time.Now().Add(-time.Hour),
+		},
+		{
+			Space:     "at://did:plc:owner/space/network.habitat.space/elsewhere",
+			Endpoint:  "https://old.example",
+			ExpiresAt: time.Now().Add(time.Hour),
+		},
+	} {
+		require.NoError(t, db.Create(&r).Error)
+	}
+
+	spaces, err := reg.Registered(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, []habitat_syntax.SpaceURI{live}, spaces)
+}
@@ -0,0 +1,96 @@
+package sap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+
+	habitat_syntax "github.com/habitat-network/habitat/internal/syntax"
+	"github.com/habitat-network/habitat/pkg/sap/syncer"
+)
+
+var (
+	// ErrReplicaDisabled is returned by the replica reads when sap was built
+	// without Config.Replica.
+	ErrReplicaDisabled = errors.New("read replica is not enabled")
+	// ErrNoAccess is returned by the replica reads when the caller has no
+	// session on record as having access to the space.
+	ErrNoAccess = errors.New("no access to space")
+)
+
+// GetRecord returns the record sap holds at a path, for a caller with access
+// to the space. A path sap holds nothing at fails with
+// syncer.ErrRecordNotFound.
+func (s *Sap) GetRecord(
+	ctx context.Context,
+	caller syntax.DID,
+	spaceURI syntax.URI,
+	repo syntax.DID,
+	collection syntax.NSID,
+	rkey syntax.RecordKey,
+) (syncer.Record, error) {
+	space, err := s.replicaSpace(ctx, caller, spaceURI)
+	if err != nil {
+		return syncer.Record{}, err
+	}
+	return s.engine.Record(ctx, space, repo, collection, rkey)
+}
+
+// ListRecords lists the records sap holds for a repo, a page at a time, for a
+// caller with access to the space; see syncer.RecordQuery.
+func (s *Sap) ListRecords(
+	ctx context.Context,
+	caller syntax.DID,
+	spaceURI syntax.URI,
+	repo syntax.DID,
+	query syncer.RecordQuery,
+) ([]syncer.Record, string, error) {
+	space, err := s.replicaSpace(ctx, caller, spaceURI)
+	if err != nil {
+		return nil, "", err
+	}
+	return s.engine.Records(ctx, space, repo, query)
+}
+
+// ListRepos lists the synced repos in a space with the rev and hash sap last
+// verified, a page at a time, for a caller with access to the space.
+func (s *Sap) ListRepos(
+	ctx context.Context,
+	caller syntax.DID,
+	spaceURI syntax.URI,
+	limit int,
+	cursor string,
+) ([]syncer.RepoHead, string, error) {
+	space, err := s.replicaSpace(ctx, caller, spaceURI)
+	if err != nil {
+		return nil, "", err
+	}
+	return s.engine.RepoHeads(ctx, space, limit, cursor)
+}
+
+// replicaSpace parses spaceURI and checks that the replica is enabled and
+// that caller may read the space. Access follows the sessions: a caller sees
+// a space only while one of its sessions is on record as able to.
+func (s *Sap) replicaSpace(
+	ctx context.Context,
+	caller syntax.DID,
+	spaceURI syntax.URI,
+) (habitat_syntax.SpaceURI, error) {
+	if !s.replica {
+		return "", ErrReplicaDisabled
+	}
+	space, err := habitat_syntax.ParseSpaceURI(spaceURI.String())
+	if err != nil {
+		return "", fmt.Errorf("parse space: %w", err)
+	}
+	ok, err := s.sessions.HasAccess(ctx, caller, space)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrNoAccess, space)
+	}
+	return space, nil
+}
@@ -11,6 +11,7 @@ package sap
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"time"
@@ -29,6 +30,7 @@ import (
 	habitat_syntax "github.com/habitat-network/habitat/internal/syntax"
 	"github.com/habitat-network/habitat/pkg/sap/crawl"
 	"github.com/habitat-network/habitat/pkg/sap/credential"
+	"github.com/habitat-network/habitat/pkg/sap/hostlimit"
 	"github.com/habitat-network/habitat/pkg/sap/outbox"
 	"github.com/habitat-network/habitat/pkg/sap/register"
 	"github.com/habitat-network/habitat/pkg/sap/session"
@@ -53,6 +55,19 @@ type Config struct {
 	// repo-host read fails for lack of a credential.
 	Directory identity.Directory
 
+	// RequireSignatures, TrustedHosts and StrictVerification make up the
+	// trust policy applied to verified commits. RequireSignatures refuses a
+	// repo whose sync reaches no signed commit. TrustedHosts, when set, are
+	// the only host DIDs whose signatures are accepted for externally
+	// authored repos; author-signed commits are unaffected.
+	// StrictVerification refuses hash-only verification. Any policy requires
+	// a Directory. A refused repo is parked in error and retried. Each repo's
+	// last verification outcome is kept (RepoStatus.Verification) and
+	// counted in the sap.syncer.verifications metric.
+	RequireSignatures  bool
+	TrustedHosts       []syntax.DID
+	StrictVerification bool
+
 	// Endpoint is sap's public base URL, registered with space hosts as the
 	// destination for notifyWrite / notifySpaceDeleted. When empty, sap does
 	// not register for notifications; the caller must route them some other
@@ -66,6 +81,75 @@ type Config struct {
 	// created since the last crawl (default 1h).
 	CrawlInterval time.Duration
 
+	// PollInterval is how often a space without a live notify registration —
+	// every space when Endpoint is empty, or one whose host rejected
+	// registerNotify — is polled for new writes while it is active (default
+	// 30s). Idle spaces back off, doubling their interval up to
+	// PollMaxInterval (default 15m). A negative PollInterval disables
+	// polling, leaving such spaces to the periodic re-crawl.
+	PollInterval    time.Duration
+	PollMaxInterval time.Duration
+	// PollHostConcurrency caps concurrent polls against one host (default 2).
+	PollHostConcurrency int
+
+	// HostConcurrency, HostRate and HostBurst bound what sap sends any one
+	// host, across sessions, crawls, sync workers and polls: at most
+	// HostConcurrency requests in flight (default 4), at HostRate requests a
+	// second sustained (default 10) with bursts of HostBurst (default 20).
+	// A host answering 429 or Retry-After is left alone for as long as it
+	// asks; repos whose sync it interrupted are rescheduled, not errored.
+	HostConcurrency int
+	HostRate        float64
+	HostBurst       int
+
+	// PrevValues makes outbox messages carry the value each change replaced
+	// (Message.PrevValue), at the cost of sap keeping a copy of every synced
+	// record. Previous CIDs are always carried.
+	PrevValues bool
+
+	// Replica keeps a copy of every synced record and serves reads from it
+	// (GetRecord, ListRecords, ListRepos), so consumers can query what sap
+	// holds instead of replaying the outbox or asking the space's host.
+	// Records indexed before the replica was enabled are filled in by
+	// re-fetching them, without emitting them again.
+	Replica bool
+
+	// OutboxRetention is how long a message is kept once every consumer group
+	// has acked it. Zero keeps acked messages forever.
+	OutboxRetention time.Duration
+
+	// OutboxMaxAttempts is how many times a consumer group may fail a message
+	// — by nacking it, or by not acking it within its lease — before it is
+	// moved to the group's dead letters (see DeadLetters), so a message its
+	// consumer can never process stops blocking the stream. Zero redelivers
+	// forever. SetMaxAttempts overrides it per group.
+	OutboxMaxAttempts int
+
+	// OutboxCompaction collapses messages no consumer group has read yet down
+	// to the latest per record, so a consumer that falls behind catches up on
+	// current state instead of replaying every intermediate version.
+	OutboxCompaction bool
+
+	// SpaceTypes limits syncing to spaces of these types; empty syncs every
+	// type.
+	SpaceTypes []syntax.NSID
+	// SpaceFilter limits syncing to the spaces it returns true for; nil syncs
+	// every space. Applied after SpaceTypes.
+	SpaceFilter func(habitat_syntax.SpaceURI) bool
+	// Collections limits the outbox to records in these collections; empty
+	// delivers every collection.
+	Collections []syntax.NSID
+	// ExcludeCollections keeps records in these collections out of the
+	// outbox. Applied after Collections.
+	//
+	// Collection filters only affect delivery: every record is still synced
+	// and verified. When a restart broadens any filter, the newly included
+	// spaces and collections are backfilled; narrowing one stops delivery,
+	// and consumers keep what they already received until a repo is rebuilt
+	// from a snapshot. A resync marker covers the whole repo, so it tells
+	// consumers to drop that repo's records in excluded collections too.
+	ExcludeCollections []syntax.NSID
+
 	Meter  metric.Meter
 	Tracer trace.Tracer
 }
@@ -74,6 +158,7 @@ type Config struct {
 // their own packages and interact only through interfaces; Sap wires them.
 type Sap struct {
 	db            *gorm.DB
+	filter        syncer.Filter
 	sessions      *session.Store
 	credentials   *credential.Manager
 	crawler       *crawl.Crawler
@@ -82,15 +167,32 @@ type Sap struct {
 	outbox        *outbox.Store
 	tracer        trace.Tracer
 	crawlInterval time.Duration
+	replica       bool
 }
 
 func New(config Config) (*Sap, error) {
+	// Every trust policy needs signatures checked, which takes a directory.
+	trustPolicy := config.RequireSignatures || len(config.TrustedHosts) > 0 ||
+		config.StrictVerification
+	if trustPolicy && config.Directory == nil {
+		return nil, errors.New("a trust policy requires a directory")
+	}
 	tracer := config.Tracer
 	if tracer == nil {
 		tracer = tracenoop.NewTracerProvider().Tracer("sap")
 	}
 
-	sessions, err := session.NewStore(config.DB, config.OAuthClient)
+	// Every request sap makes — as a session or with a space credential —
+	// goes through one rate-limited transport, so the per-host limits hold
+	// across all of them.
+	limited := hostlimit.NewTransport(httpx.NewClient().Transport, hostlimit.Limits{
+		Concurrency: config.HostConcurrency,
+		Rate:        config.HostRate,
+		Burst:       config.HostBurst,
+	}).Client()
+
+	sessions, err := session.NewStore(config.DB, config.OAuthClient,
+		session.WithHTTPClient(limited))
 	if err != nil {
 		return nil, fmt.Errorf("create session store: %w", err)
 	}
@@ -98,12 +200,44 @@ func New(config Config) (*Sap, error) {
 	// of which session was used to obtain it — a space credential authorizes
 	// the space, not the member who fetched it. It asks sessions (which
 	// implements credential.Delegator) for a delegation token on demand.
-	credentials := credential.NewManager(config.Directory, httpx.NewClient(), sessions)
-	ob, err := outbox.NewStore(config.DB, utils.NewPollNotifier())
+	credentials := credential.NewManager(config.Directory, limited, sessions)
+	filter := syncer.Filter{
+		SpaceTypes:         config.SpaceTypes,
+		Space:              config.SpaceFilter,
+		Collections:        config.Collections,
+		ExcludeCollections: config.ExcludeCollections,
+	}
+	ob, err := outbox.NewStore(
+		config.DB,
+		utils.NewPollNotifier(),
+		outbox.WithRetention(config.OutboxRetention),
+		outbox.WithCompaction(config.OutboxCompaction),
+		outbox.WithMaxAttempts(config.OutboxMaxAttempts),
+		outbox.WithMeter(config.Meter),
+	)
 	if err != nil {
 		return nil, fmt.Errorf("create outbox store: %w", err)
 	}
 
+	var registrar *register.Registrar
+	// crawl.Notify and syncer.Registrations must stay typed-nil-free
+	// interface values when registration is disabled.
+	var crawlNotify crawl.Notify
+	var registrations syncer.Registrations
+	if config.Endpoint != "" {
+		registrar, err = register.New(
+			config.DB,
+			credentials,
+			filteredSpaces{sessions: sessions, filter: filter},
+			config.Endpoint,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("create registrar: %w", err)
+		}
+		crawlNotify = registrar
+		registrations = registrar
+	}
+
 	syncMetrics, err := syncer.NewMetrics(config.Meter, config.Tracer)
 	if err != nil {
 		return nil, fmt.Errorf("create syncer metrics: %w", err)
@@ -112,26 +246,26 @@ func New(config Config) (*Sap, error) {
 		config.DB,
 		credentials,
 		outboxEmitter{store: ob},
-		syncer.NewVerifier(config.Directory),
+		syncer.NewVerifier(config.Directory, syncer.TrustPolicy{
+			RequireSignatures: config.RequireSignatures,
+			TrustedHosts:      config.TrustedHosts,
+			Strict:            config.StrictVerification,
+		}),
 		config.Parallelism,
 		syncMetrics,
+		syncer.WithValues(config.Replica),
+		syncer.WithPrevValues(config.PrevValues),
+		syncer.WithFilter(filter),
+		syncer.WithPolling(registrations, syncer.PollConfig{
+			MinInterval:     config.PollInterval,
+			MaxInterval:     config.PollMaxInterval,
+			HostConcurrency: config.PollHostConcurrency,
+		}),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("create syncer: %w", err)
 	}
 
-	var registrar *register.Registrar
-	// crawl.Notify must stay a typed-nil-free interface value when
-	// registration is disabled.
-	var crawlNotify crawl.Notify
-	if config.Endpoint != "" {
-		registrar, err = register.New(config.DB, credentials, sessions, config.Endpoint)
-		if err != nil {
-			return nil, fmt.Errorf("create registrar: %w", err)
-		}
-		crawlNotify = registrar
-	}
-
 	crawler, err := crawl.New(
 		config.DB,
 		config.OAuthClient,
@@ -141,6 +275,8 @@ func New(config Config) (*Sap, error) {
 		crawlNotify,
 		config.Meter,
 		config.Tracer,
+		crawl.WithSpaceFilter(filter.IncludesSpace),
+		crawl.WithHTTPClient(limited),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("create crawler: %w", err)
@@ -153,6 +289,7 @@ func New(config Config) (*Sap, error) {
 
 	return &Sap{
 		db:            config.DB,
+		filter:        filter,
 		sessions:      sessions,
 		credentials:   credentials,
 		crawler:       crawler,
@@ -161,11 +298,14 @@ func New(config Config) (*Sap, error) {
 		outbox:        ob,
 		crawlInterval: crawlInterval,
 		tracer:        tracer,
+		replica:       config.Replica,
 	}, nil
 }
 
 // Start runs the background loops (sync engine, crawl resumption and periodic
-// re-crawls, notify registration upkeep) until ctx ends.
+// re-crawls, notify registration upkeep, outbox retention and compaction,
+// backfill completion) until ctx ends. It first reconciles tracked spaces
+// with the space filter.
 func (s *Sap) Start(ctx context.Context) error {
 	eg, ctx := errgroup.WithContext(ctx)
 	eg.Go(func() error {
@@ -175,10 +315,22 @@ func (s *Sap) Start(ctx context.Context) error {
 	eg.Go(func() error {
 		return s.crawler.ResumeIncomplete(ctx)
 	})
+	eg.Go(func() error {
+		s.applySpaceFilter(ctx)
+		return nil
+	})
 	eg.Go(func() error {
 		s.recrawlLoop(ctx)
 		return nil
 	})
+	eg.Go(func() error {
+		s.outbox.Run(ctx)
+		return nil
+	})
+	eg.Go(func() error {
+		s.backfillLoop(ctx)
+		return nil
+	})
 	if s.registrar != nil {
 		eg.Go(func() error {
 			s.registrar.Run(ctx)
@@ -289,31 +441,269 @@ func (s *Sap) NotifySpaceDeleted(
 		if err := s.sessions.WithTx(tx).DropSpace(ctx, space); err != nil {
 			return fmt.Errorf("drop space access: %w", err)
 		}
-		if s.registrar != nil {
-			if err := s.registrar.WithTx(tx).DropSpace(ctx, space); err != nil {
-				return fmt.Errorf("drop registration: %w", err)
+		return s.dropRegistration(ctx, tx, space)
+	})
+}
+
+// RemoveSession stops syncing on behalf of did: its session and space access
+// are forgotten, its crawl is stopped, and every space no remaining session
+// can access is dropped — its repos, held records and notify registration —
+// and the syncs in flight for it are cancelled. When emitDeletes is set, a
+// delete is emitted through the outbox for each record a dropped space held,
+// so consumers purge their copies too; otherwise consumers keep what they
+// already received. It returns the spaces it dropped.
+//
+// The OAuth session itself stays in Config.OAuthClient's Store; revoking it
+// is the caller's concern, as establishing it was.
+func (s *Sap) RemoveSession(
+	ctx context.Context,
+	did syntax.DID,
+	emitDeletes bool,
+) ([]habitat_syntax.SpaceURI, error) {
+	// The session goes first, so the recrawl loop has nothing to restart once
+	// the crawl is stopped.
+	dropped, err := s.forgetSession(ctx, did, emitDeletes)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.crawler.Stop(ctx, did); err != nil {
+		return dropped, fmt.Errorf("stop crawl: %w", err)
+	}
+	// The crawl may have recorded access again before it stopped; sweep once
+	// more now that nothing can.
+	more, err := s.forgetSession(ctx, did, emitDeletes)
+	if err != nil {
+		return dropped, err
+	}
+	dropped = append(dropped, more...)
+	for _, space := range dropped {
+		s.engine.CancelSpace(space)
+		s.credentials.DropSpace(space)
+	}
+	return dropped, nil
+}
+
+// forgetSession removes did's session and space access and drops the spaces
+// that leaves orphaned, in one transaction. It returns the dropped spaces.
+func (s *Sap) forgetSession(
+	ctx context.Context,
+	did syntax.DID,
+	emitDeletes bool,
+) ([]habitat_syntax.SpaceURI, error) {
+	var dropped []habitat_syntax.SpaceURI
+	if err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		orphaned, err := s.sessions.WithTx(tx).Remove(ctx, did)
+		if err != nil {
+			return fmt.Errorf("remove session: %w", err)
+		}
+		engine := s.engine.WithTx(tx)
+		for _, space := range orphaned {
+			if emitDeletes {
+				if err := engine.TombstoneSpace(ctx, space); err != nil {
+					return fmt.Errorf("tombstone %s: %w", space, err)
+				}
+			}
+			if err := engine.DropSpace(ctx, space); err != nil {
+				return fmt.Errorf("drop repos for %s: %w", space, err)
+			}
+			if err := s.dropRegistration(ctx, tx, space); err != nil {
+				return err
 			}
 		}
+		dropped = orphaned
 		return nil
-	})
+	}); err != nil {
+		return nil, err
+	}
+	return dropped, nil
+}
+
+// dropRegistration forgets the space's notify registration, if sap registers
+// for notifications at all.
+func (s *Sap) dropRegistration(
+	ctx context.Context,
+	tx *gorm.DB,
+	space habitat_syntax.SpaceURI,
+) error {
+	if s.registrar == nil {
+		return nil
+	}
+	if err := s.registrar.WithTx(tx).DropSpace(ctx, space); err != nil {
+		return fmt.Errorf("drop registration: %w", err)
+	}
+	return nil
+}
+
+// Repos lists tracked repos and their sync state, a page at a time; see
+// syncer.RepoFilter.
+func (s *Sap) Repos(
+	ctx context.Context,
+	filter syncer.RepoFilter,
+) ([]syncer.RepoStatus, string, error) {
+	return s.engine.Repos(ctx, filter)
 }
 
-// Outbox exposes the acknowledged delivery stream of synced records.
+// Resync forces the space's repos — or only repo, when it is set — to sync
+// again now, ignoring any retry backoff. With full, each is rebuilt from a
+// full getRepo snapshot instead of synced incrementally. A repo mid-sync gets
+// the requested pass once its current one ends and is not counted. Returns
+// how many repos were requeued now.
+func (s *Sap) Resync(
+	ctx context.Context,
+	space habitat_syntax.SpaceURI,
+	repo syntax.DID,
+	full bool,
+) (int64, error) {
+	return s.engine.Requeue(ctx, space, repo, full)
+}
+
+// PauseSpace stops syncing the space's repos until ResumeSpace. Notifications
+// and crawls keep recording where the repos are, so resuming catches up.
+func (s *Sap) PauseSpace(ctx context.Context, space habitat_syntax.SpaceURI) error {
+	return s.engine.PauseSpace(ctx, space)
+}
+
+// ResumeSpace resumes syncing a space PauseSpace paused.
+func (s *Sap) ResumeSpace(ctx context.Context, space habitat_syntax.SpaceURI) error {
+	return s.engine.ResumeSpace(ctx, space)
+}
+
+// PausedSpaces lists the spaces PauseSpace paused.
+func (s *Sap) PausedSpaces(ctx context.Context) ([]habitat_syntax.SpaceURI, error) {
+	return s.engine.PausedSpaces(ctx)
+}
+
+// Crawls reports each session's backfill crawl progress.
+func (s *Sap) Crawls(ctx context.Context) ([]crawl.Status, error) {
+	return s.crawler.Statuses(ctx)
+}
+
+// Outbox exposes the acknowledged delivery stream of synced records to the
+// default consumer group.
 func (s *Sap) Outbox() outbox.Outbox {
 	return s.outbox
 }
 
+// ConsumerGroup exposes the same delivery stream to a named consumer group,
+// which keeps its own ack state: every group receives every message, so
+// several consumers can share one sap without stealing each other's
+// deliveries. An empty name is the default group, the same as Outbox.
+func (s *Sap) ConsumerGroup(ctx context.Context, name string) (outbox.Outbox, error) {
+	return s.outbox.Group(ctx, name)
+}
+
+// SetMaxAttempts sets how many failed deliveries a consumer group gets per
+// message before it is dead-lettered, overriding Config.OutboxMaxAttempts. A
+// negative n redelivers forever; zero reverts to the configured default.
+func (s *Sap) SetMaxAttempts(ctx context.Context, group string, n int) error {
+	return s.outbox.SetMaxAttempts(ctx, group, n)
+}
+
+// DeadLetters lists the messages a consumer group gave up on, oldest first.
+func (s *Sap) DeadLetters(ctx context.Context, group string) ([]outbox.DeadLetter, error) {
+	return s.outbox.DeadLetters(ctx, group)
+}
+
+// ReplayDeadLetter hands a dead-lettered message back to its group for
+// another round of deliveries.
+func (s *Sap) ReplayDeadLetter(ctx context.Context, id uint) error {
+	return s.outbox.Replay(ctx, id)
+}
+
+// DiscardDeadLetter drops a dead-lettered message for good.
+func (s *Sap) DiscardDeadLetter(ctx context.Context, id uint) error {
+	return s.outbox.Discard(ctx, id)
+}
+
+// applySpaceFilter brings tracking in line with the space filter, which may
+// have changed since the last run: every space a session is known to access
+// that the filter now includes but sap tracks no repos in is tracked (through
+// one of its recorded accessors), and every tracked space the filter now
+// excludes is dropped — its repos, held records and notify registration —
+// while the session's access to it stays on record.
+func (s *Sap) applySpaceFilter(ctx context.Context) {
+	known, err := s.sessions.Spaces(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "apply space filter: list known spaces", "err", err)
+		return
+	}
+	tracked, err := s.engine.Spaces(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "apply space filter: list tracked spaces", "err", err)
+		return
+	}
+	isTracked := make(map[habitat_syntax.SpaceURI]bool, len(tracked))
+	for _, space := range tracked {
+		isTracked[space] = true
+	}
+
+	for _, space := range known {
+		included := s.filter.IncludesSpace(space)
+		switch {
+		case included && !isTracked[space]:
+			accessors, err := s.sessions.Accessors(ctx, space)
+			if err != nil || len(accessors) == 0 {
+				continue
+			}
+			a := accessors[0]
+			if err := s.crawler.TrackSpace(ctx, space, a.DID, a.SessionID); err != nil {
+				slog.WarnContext(ctx, "apply space filter: track space",
+					"space", space, "err", err)
+			}
+		case !included && isTracked[space]:
+			if err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+				if err := s.engine.WithTx(tx).DropSpace(ctx, space); err != nil {
+					return fmt.Errorf("drop repos: %w", err)
+				}
+				return s.dropRegistration(ctx, tx, space)
+			}); err != nil {
+				slog.ErrorContext(ctx, "apply space filter: drop space",
+					"space", space, "err", err)
+				continue
+			}
+			s.credentials.DropSpace(space)
+		}
+	}
+}
+
+// filteredSpaces adapts session.Store to register.Spaces, hiding the spaces
+// the space filter excludes so the registrar never registers them.
+type filteredSpaces struct {
+	sessions *session.Store
+	filter   syncer.Filter
+}
+
+func (f filteredSpaces) Spaces(ctx context.Context) ([]habitat_syntax.SpaceURI, error) {
+	spaces, err := f.sessions.Spaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+	included := spaces[:0]
+	for _, space := range spaces {
+		if f.filter.IncludesSpace(space) {
+			included = append(included, space)
+		}
+	}
+	return included, nil
+}
+
 // outboxEmitter adapts outbox.Store to syncer.Emitter.
 type outboxEmitter struct {
 	store *outbox.Store
 }
 
-func (e outboxEmitter) Emit(
-	ctx context.Context,
-	uri habitat_syntax.SpaceRecordURI,
-	value []byte,
-) error {
-	return e.store.Emit(ctx, uri, value)
+func (e outboxEmitter) Emit(ctx context.Context, event syncer.Event) error {
+	return e.store.Emit(ctx, outbox.Message{
+		Space:     event.Space,
+		Repo:      event.Repo,
+		Action:    outbox.Action(event.Action),
+		URI:       event.URI,
+		Rev:       event.Rev,
+		Cid:       event.Cid,
+		PrevCid:   event.PrevCid,
+		Value:     event.Value,
+		PrevValue: event.PrevValue,
+	})
 }
 
 func (e outboxEmitter) InTx(tx *gorm.DB) syncer.Emitter {
@@ -183,18 +183,35 @@ func TestSap(t *testing.T) {
 	require.Equal(t, int64(30), expectedCount)
 
 	// 7. Every record lands in the outbox exactly once, each with the URI of
-	// a record actually created above.
-	var msgs []outbox.Message
+	// a record actually created above, and the session's backfill is marked
+	// complete exactly once, after the records its crawl found.
+	var msgs, records []outbox.Message
+	var backfilled []outbox.Message
 	require.Eventually(t, func() bool {
 		var err error
-		msgs, err = s.Outbox().Poll(t.Context(), int(expectedCount)+1)
+		msgs, err = s.Outbox().Poll(t.Context(), int(expectedCount)+2)
 		require.NoError(t, err)
-		t.Logf("Current outbox count: %d/%d", len(msgs), expectedCount)
-		return int64(len(msgs)) == expectedCount
+		records, backfilled = nil, nil
+		for _, msg := range msgs {
+			if msg.Action == outbox.ActionBackfillComplete {
+				backfilled = append(backfilled, msg)
+			} else {
+				records = append(records, msg)
+			}
+		}
+		t.Logf("Current outbox count: %d/%d", len(records), expectedCount)
+		return int64(len(records)) == expectedCount && len(backfilled) > 0
 	}, 15*time.Second, 100*time.Millisecond)
-	for _, msg := range msgs {
+	for _, msg := range records {
 		require.Contains(t, createdURIs, string(msg.URI))
 	}
+	require.Len(t, backfilled, 1)
+	require.Equal(t, author, backfilled[0].Repo)
+	progress, err := s.BackfillProgress(t.Context(), author)
+	require.NoError(t, err)
+	require.True(t, progress.Complete)
+	require.Zero(t, progress.ReposUnsynced)
+	require.GreaterOrEqual(t, progress.SpacesDiscovered, 3)
 
 	// 8. All 5 spaces are registered for notifications, and every tracked repo
 	// settled active with a verified hash. A repo can still bounce back to
@@ -257,6 +274,22 @@ func TestSap(t *testing.T) {
 	}
 }
 
+// TestNewTrustPolicyRequiresDirectory covers every trust policy that needs
+// signatures checked being refused without a directory to check them with.
+func TestNewTrustPolicyRequiresDirectory(t *testing.T) {
+	t.Parallel()
+
+	for _, config := range []Config{
+		{StrictVerification: true},
+		{RequireSignatures: true},
+		{TrustedHosts: []syntax.DID{"did:web:host.example"}},
+	} {
+		config.DB = db_testutil.NewDB(t)
+		_, err := New(config)
+		require.ErrorContains(t, err, "requires a directory")
+	}
+}
+
 // TestSapTrackSpace verifies that TrackSpace tracks a space the same way the
 // crawl would if its listSpaces discovered it — recording space access,
 // registering for notifications, and syncing the space's repos — without the
@@ -350,6 +383,100 @@ func TestSapTrackSpace(t *testing.T) {
 	require.Equal(t, int64(1), repoCount)
 }
 
+// TestSapRemoveSession verifies that removing the only session with access to
+// a space drops the space entirely — its repos, held records and notify
+// registration — and, when asked, tombstones the space's records through the
+// outbox so consumers purge them too.
+func TestSapRemoveSession(t *testing.T) {
+	if transport, ok := http.DefaultTransport.(*http.Transport); ok {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+
+	pear := setupPear(t)
+	t.Cleanup(func() {
+		pear.server.CloseClientConnections()
+		pear.server.Close()
+	})
+	author := pear.author.DID
+
+	groupType := syntax.NSID("network.habitat.group")
+	collection := syntax.NSID("network.habitat.test")
+	space, err := pear.store.CreateSpace(
+		t.Context(), author, author, groupType, habitat_syntax.SpaceKey("removed-space"),
+	)
+	require.NoError(t, err)
+	recURI, _, err := pear.store.PutRecord(
+		t.Context(), space, author, collection,
+		syntax.RecordKey("rkey-0"), map[string]any{"data": "removed"},
+	)
+	require.NoError(t, err)
+
+	mux := http.NewServeMux()
+	sapServer := httptest.NewTLSServer(mux)
+	t.Cleanup(sapServer.Close)
+
+	db := db_testutil.NewDB(t)
+	store, err := oauthclient.NewGormStore(db)
+	require.NoError(t, err)
+	cfg := oauth.NewPublicConfig(
+		sapServer.URL+"/client-metadata.json",
+		sapServer.URL+"/oauth-callback",
+		[]string{},
+	)
+	oauthApp := oauth.NewClientApp(&cfg, store)
+
+	s, err := New(Config{
+		DB:          db,
+		OAuthClient: oauthApp,
+		Directory:   pear.hive,
+		Endpoint:    sapServer.URL,
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	t.Cleanup(cancel)
+	go func() {
+		require.NoError(t, s.Start(ctx))
+	}()
+
+	require.NoError(t, store.SaveSession(t.Context(), oauth.ClientSessionData{
+		AccountDID:              author,
+		SessionID:               "sess1",
+		HostURL:                 pear.server.URL,
+		AccessToken:             futureJWT(t),
+		DPoPPrivateKeyMultibase: testDPoPKey(t),
+	}))
+	require.NoError(t, s.TrackSpace(t.Context(), space.URI(), author, "sess1"))
+
+	var got []outbox.Message
+	require.Eventually(t, func() bool {
+		var err error
+		got, err = s.Outbox().Poll(t.Context(), 10)
+		require.NoError(t, err)
+		return len(got) >= 1
+	}, 15*time.Second, 100*time.Millisecond)
+	for _, msg := range got {
+		require.NoError(t, s.Outbox().Ack(t.Context(), msg.ID))
+	}
+
+	dropped, err := s.RemoveSession(t.Context(), author, true)
+	require.NoError(t, err)
+	require.Equal(t, []habitat_syntax.SpaceURI{space}, dropped)
+
+	tombstones, err := s.Outbox().Poll(t.Context(), 10)
+	require.NoError(t, err)
+	require.Len(t, tombstones, 1)
+	require.Equal(t, recURI.String(), string(tombstones[0].URI))
+	require.Equal(t, outbox.ActionDelete, tombstones[0].Action)
+	require.NotEmpty(t, tombstones[0].PrevCid)
+
+	for _, table := range []string{"registrations", "repos", "repo_records", "space_accesses"} {
+		var count int64
+		require.NoError(t, db.Table(table).Count(&count).Error)
+		require.Zero(t, count, table)
+	}
+}
+
 // pearHost bundles the host-side pieces the test drives.
 type pearHost struct {
 	server *httptest.Server
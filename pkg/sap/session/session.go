@@ -21,6 +21,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/http"
 	"time"
 
 	"github.com/bluesky-social/indigo/atproto/atclient"
@@ -31,12 +32,19 @@ import (
 
 	"github.com/habitat-network/habitat/api/habitat"
 	habitat_syntax "github.com/habitat-network/habitat/internal/syntax"
+	"github.com/habitat-network/habitat/internal/utils"
 )
 
+// ErrUnknownSession is returned for a DID sap does not sync on behalf of.
+var ErrUnknownSession = errors.New("unknown session")
+
 // Session names a session sap tracks: did, resumable via sessionID.
 type Session struct {
 	DID       syntax.DID
 	SessionID string
+	// BackfilledAt is when the session's initial backfill completed; zero
+	// until it has.
+	BackfilledAt time.Time
 }
 
 // session is a DID sap tracks for backfill/sync, resumable via SessionID
@@ -44,8 +52,11 @@ type Session struct {
 type session struct {
 	DID       syntax.DID `gorm:"column:did;primaryKey"`
 	SessionID string
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	// BackfilledAt is set once, when the session's first crawl and the syncs
+	// it queued have all finished.
+	BackfilledAt *time.Time
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
 }
 
 // spaceAccess records that a session can access a space (its listSpaces
@@ -59,22 +70,40 @@ type spaceAccess struct {
 	SessionID string
 }
 
+type options struct {
+	httpc *http.Client
+}
+
+// WithHTTPClient sets the client resumed sessions send their requests
+// through, in place of the *oauth.ClientApp's own.
+func WithHTTPClient(httpc *http.Client) utils.Opt[options] {
+	return func(o *options) {
+		o.httpc = httpc
+	}
+}
+
 // Store tracks sessions and space access.
 type Store struct {
 	db          *gorm.DB
 	oauthClient *oauth.ClientApp
+	httpc       *http.Client // nil uses oauthClient's
 }
 
-func NewStore(db *gorm.DB, oauthClient *oauth.ClientApp) (*Store, error) {
+func NewStore(
+	db *gorm.DB,
+	oauthClient *oauth.ClientApp,
+	opts ...utils.Opt[options],
+) (*Store, error) {
+	o := utils.ResolveOptions(options{}, opts)
 	if err := db.AutoMigrate(&session{}, &spaceAccess{}); err != nil {
 		return nil, err
 	}
-	return &Store{db: db, oauthClient: oauthClient}, nil
+	return &Store{db: db, oauthClient: oauthClient, httpc: o.httpc}, nil
 }
 
 // WithTx returns a Store scoped to the given transaction.
 func (s *Store) WithTx(tx *gorm.DB) *Store {
-	return &Store{db: tx, oauthClient: s.oauthClient}
+	return &Store{db: tx, oauthClient: s.oauthClient, httpc: s.httpc}
 }
 
 // Add starts tracking did for backfill/sync, resumable via sessionID. Safe
@@ -95,11 +124,45 @@ func (s *Store) List(ctx context.Context) ([]Session, error) {
 	}
 	sessions := make([]Session, len(tracked))
 	for i, t := range tracked {
-		sessions[i] = Session{DID: t.DID, SessionID: t.SessionID}
+		sessions[i] = t.public()
 	}
 	return sessions, nil
 }
 
+// Get returns the session tracked for did, or ErrUnknownSession.
+func (s *Store) Get(ctx context.Context, did syntax.DID) (Session, error) {
+	var tracked session
+	err := s.db.WithContext(ctx).Where("did = ?", did).First(&tracked).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return Session{}, fmt.Errorf("%w: %s", ErrUnknownSession, did)
+	} else if err != nil {
+		return Session{}, fmt.Errorf("load session: %w", err)
+	}
+	return tracked.public(), nil
+}
+
+// MarkBackfilled records that did's initial backfill completed, and reports
+// whether this call was the one to record it: a session is only ever marked
+// once, even by concurrent callers.
+func (s *Store) MarkBackfilled(ctx context.Context, did syntax.DID) (bool, error) {
+	res := s.db.WithContext(ctx).
+		Model(&session{}).
+		Where("did = ? AND backfilled_at IS NULL", did).
+		Update("backfilled_at", time.Now())
+	if res.Error != nil {
+		return false, fmt.Errorf("mark backfilled: %w", res.Error)
+	}
+	return res.RowsAffected > 0, nil
+}
+
+func (t session) public() Session {
+	sess := Session{DID: t.DID, SessionID: t.SessionID}
+	if t.BackfilledAt != nil {
+		sess.BackfilledAt = *t.BackfilledAt
+	}
+	return sess
+}
+
 // RecordSpaceAccess records that (did, sessionID) can access the space.
 func (s *Store) RecordSpaceAccess(
 	ctx context.Context,
@@ -125,7 +188,7 @@ func (s *Store) DelegationToken(
 	ctx context.Context,
 	space habitat_syntax.SpaceURI,
 ) (string, error) {
-	candidates, err := s.candidatesForSpace(ctx, space)
+	candidates, err := s.Accessors(ctx, space)
 	if err != nil {
 		return "", err
 	}
@@ -138,6 +201,9 @@ func (s *Store) DelegationToken(
 			))
 			continue
 		}
+		if s.httpc != nil {
+			sess.Client = s.httpc
+		}
 		token, err := fetchDelegationToken(ctx, sess.APIClient(), space)
 		if err != nil {
 			errs = append(errs, err)
@@ -150,11 +216,11 @@ func (s *Store) DelegationToken(
 	)
 }
 
-// candidatesForSpace returns the (DID, session ID) pairs on record as having
+// Accessors returns the (DID, session ID) pairs on record as having
 // access to space — each one drawn directly from spaceAccess, never guessed
 // by pairing an arbitrary DID with whatever session happens to be tracked
 // for it.
-func (s *Store) candidatesForSpace(
+func (s *Store) Accessors(
 	ctx context.Context,
 	space habitat_syntax.SpaceURI,
 ) ([]Session, error) {
@@ -171,6 +237,23 @@ func (s *Store) candidatesForSpace(
 	return candidates, nil
 }
 
+// HasAccess reports whether did is on record as having access to space
+// through any of its sessions.
+func (s *Store) HasAccess(
+	ctx context.Context,
+	did syntax.DID,
+	space habitat_syntax.SpaceURI,
+) (bool, error) {
+	var count int64
+	if err := s.db.WithContext(ctx).
+		Model(&spaceAccess{}).
+		Where("did = ? AND space = ?", did, space).
+		Count(&count).Error; err != nil {
+		return false, fmt.Errorf("check space access: %w", err)
+	}
+	return count > 0, nil
+}
+
 // fetchDelegationToken calls getDelegationToken for space using client,
 // which must already authenticate its own requests (a resumed session's
 // APIClient).
@@ -187,6 +270,22 @@ func fetchDelegationToken(
 	return out.Token, nil
 }
 
+// SpacesOf returns the spaces did is on record as able to access.
+func (s *Store) SpacesOf(
+	ctx context.Context,
+	did syntax.DID,
+) ([]habitat_syntax.SpaceURI, error) {
+	var spaces []habitat_syntax.SpaceURI
+	if err := s.db.WithContext(ctx).
+		Model(&spaceAccess{}).
+		Where("did = ?", did).
+		Order("space").
+		Pluck("space", &spaces).Error; err != nil {
+		return nil, fmt.Errorf("list spaces for %s: %w", did, err)
+	}
+	return spaces, nil
+}
+
 // Spaces returns every space any session can access.
 func (s *Store) Spaces(ctx context.Context) ([]habitat_syntax.SpaceURI, error) {
 	var spaces []habitat_syntax.SpaceURI
@@ -205,3 +304,45 @@ func (s *Store) DropSpace(ctx context.Context, space habitat_syntax.SpaceURI) er
 		Where("space = ?", space).
 		Delete(&spaceAccess{}).Error
 }
+
+// Remove stops tracking did: its session and every space access it recorded
+// are deleted. It returns the spaces that were accessible through did and no
+// longer are through any remaining session, which the caller should drop.
+// Removing a DID that isn't tracked is a no-op.
+func (s *Store) Remove(ctx context.Context, did syntax.DID) ([]habitat_syntax.SpaceURI, error) {
+	db := s.db.WithContext(ctx)
+	var spaces []habitat_syntax.SpaceURI
+	if err := db.Model(&spaceAccess{}).
+		Where("did = ?", did).
+		Pluck("space", &spaces).Error; err != nil {
+		return nil, fmt.Errorf("load space access: %w", err)
+	}
+	if err := db.Where("did = ?", did).Delete(&spaceAccess{}).Error; err != nil {
+		return nil, fmt.Errorf("drop space access: %w", err)
+	}
+	if err := db.Where("did = ?", did).Delete(&session{}).Error; err != nil {
+		return nil, fmt.Errorf("drop session: %w", err)
+	}
+	if len(spaces) == 0 {
+		return nil, nil
+	}
+
+	var shared []habitat_syntax.SpaceURI
+	if err := db.Model(&spaceAccess{}).
+		Where("space IN ?", spaces).
+		Distinct("space").
+		Pluck("space", &shared).Error; err != nil {
+		return nil, fmt.Errorf("load remaining space access: %w", err)
+	}
+	stillAccessible := make(map[habitat_syntax.SpaceURI]bool, len(shared))
+	for _, space := range shared {
+		stillAccessible[space] = true
+	}
+	var orphaned []habitat_syntax.SpaceURI
+	for _, space := range spaces {
+		if !stillAccessible[space] {
+			orphaned = append(orphaned, space)
+		}
+	}
+	return orphaned, nil
+}
@@ -113,6 +113,72 @@ func TestStoreSessionsAndSpaceAccess(t *testing.T) {
 	require.Empty(t, spaces)
 }
 
+// TestStoreMarkBackfilledOnce verifies that a session is marked backfilled
+// only once, and that Get reports it.
+func TestStoreMarkBackfilledOnce(t *testing.T) {
+	t.Parallel()
+	db := db_testutil.NewDB(t)
+
+	s, err := NewStore(db, newOAuthApp(t, db))
+	require.NoError(t, err)
+	require.NoError(t, s.Add(t.Context(), "did:plc:alice", "sess1"))
+
+	sess, err := s.Get(t.Context(), "did:plc:alice")
+	require.NoError(t, err)
+	require.True(t, sess.BackfilledAt.IsZero())
+
+	marked, err := s.MarkBackfilled(t.Context(), "did:plc:alice")
+	require.NoError(t, err)
+	require.True(t, marked)
+	marked, err = s.MarkBackfilled(t.Context(), "did:plc:alice")
+	require.NoError(t, err)
+	require.False(t, marked)
+
+	// Re-adding the session (a re-authentication) keeps the mark.
+	require.NoError(t, s.Add(t.Context(), "did:plc:alice", "sess2"))
+	sess, err = s.Get(t.Context(), "did:plc:alice")
+	require.NoError(t, err)
+	require.Equal(t, "sess2", sess.SessionID)
+	require.False(t, sess.BackfilledAt.IsZero())
+
+	_, err = s.Get(t.Context(), "did:plc:bob")
+	require.ErrorIs(t, err, ErrUnknownSession)
+}
+
+// TestStoreRemoveReturnsOrphanedSpaces verifies that removing a session
+// reports only the spaces no other session can still access.
+func TestStoreRemoveReturnsOrphanedSpaces(t *testing.T) {
+	t.Parallel()
+	db := db_testutil.NewDB(t)
+
+	s, err := NewStore(db, newOAuthApp(t, db))
+	require.NoError(t, err)
+	require.NoError(t, s.Add(t.Context(), "did:plc:alice", "sess-a"))
+	require.NoError(t, s.Add(t.Context(), "did:plc:bob", "sess-b"))
+
+	shared := habitat_syntax.SpaceURI("at://did:plc:owner/space/network.habitat.space/shared")
+	private := habitat_syntax.SpaceURI("at://did:plc:owner/space/network.habitat.space/private")
+	require.NoError(t, s.RecordSpaceAccess(t.Context(), shared, "did:plc:alice", "sess-a"))
+	require.NoError(t, s.RecordSpaceAccess(t.Context(), shared, "did:plc:bob", "sess-b"))
+	require.NoError(t, s.RecordSpaceAccess(t.Context(), private, "did:plc:alice", "sess-a"))
+
+	orphaned, err := s.Remove(t.Context(), "did:plc:alice")
+	require.NoError(t, err)
+	require.Equal(t, []habitat_syntax.SpaceURI{private}, orphaned)
+
+	sessions, err := s.List(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, []Session{{DID: "did:plc:bob", SessionID: "sess-b"}}, sessions)
+	candidates, err := s.Accessors(t.Context(), shared)
+	require.NoError(t, err)
+	require.Equal(t, []Session{{DID: "did:plc:bob", SessionID: "sess-b"}}, candidates)
+
+	// Removing an untracked DID is a no-op.
+	orphaned, err = s.Remove(t.Context(), "did:plc:alice")
+	require.NoError(t, err)
+	require.Empty(t, orphaned)
+}
+
 // TestClientForSpaceUsesAccessingSessionForDelegation verifies a repo-host
 // read for a space is authorized end-to-end: DelegationToken resumes an
 // accessing session and asks it for a delegation token, which is then
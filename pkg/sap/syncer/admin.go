@@ -0,0 +1,322 @@
+package syncer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	habitat_syntax "github.com/habitat-network/habitat/internal/syntax"
+)
+
+const (
+	defaultRepoPageSize = 100
+	maxRepoPageSize     = 1000
+)
+
+// FailedAfterRetries is how many passes in a row a repo that has never synced
+// may fail before Progress counts it as failed. It keeps retrying; a backfill
+// just stops waiting for it.
+const FailedAfterRetries = 5
+
+// pausedSpace marks a space whose repos the dispatcher leaves unclaimed.
+// Notifications and crawls still update a paused space's repos, so resuming
+// it syncs straight to head.
+type pausedSpace struct {
+	Space    habitat_syntax.SpaceURI `gorm:"primaryKey"`
+	PausedAt time.Time
+}
+
+// RepoStatus is one repo's sync state, for operators.
+type RepoStatus struct {
+	Space habitat_syntax.SpaceURI
+	DID   syntax.DID
+	Rev   syntax.TID
+	// State is one of pending, syncing, active, desynced or error.
+	State string
+	// Dirty is set when a notification arrived mid-sync.
+	Dirty      bool
+	ErrorMsg   string
+	RetryCount int
+	// RetryAfter is when a parked repo is next claimable; zero when it is
+	// claimable now.
+	RetryAfter time.Time
+	// Paused is set when the repo's space is paused.
+	Paused bool
+	// Verification is the outcome of the repo's last commit verification (see
+	// Outcome); empty until one completes.
+	Verification string
+}
+
+// RepoFilter selects the repos Repos lists. Zero fields match everything.
+type RepoFilter struct {
+	Space habitat_syntax.SpaceURI
+	State string
+	// Verification matches the outcome of a repo's last verification.
+	Verification string
+	// Limit caps the page size (default 100, max 1000).
+	Limit int
+	// Cursor continues from a previous page.
+	Cursor string
+}
+
+// Repos lists tracked repos matching filter, ordered by space then DID, and
+// returns a cursor for the next page (empty on the last one).
+func (e *Engine) Repos(ctx context.Context, filter RepoFilter) ([]RepoStatus, string, error) {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultRepoPageSize
+	}
+	limit = min(limit, maxRepoPageSize)
+
+	db := e.db.WithContext(ctx)
+	q := db.Model(&repo{})
+	if filter.Space != "" {
+		q = q.Where("space = ?", filter.Space)
+	}
+	if filter.State != "" {
+		q = q.Where("state = ?", filter.State)
+	}
+	if filter.Verification != "" {
+		q = q.Where("verification = ?", filter.Verification)
+	}
+	if filter.Cursor != "" {
+		space, did, ok := strings.Cut(filter.Cursor, " ")
+		if !ok {
+			return nil, "", fmt.Errorf("invalid cursor %q", filter.Cursor)
+		}
+		q = q.Where("space > ? OR (space = ? AND did > ?)", space, space, did)
+	}
+	var rows []repo
+	if err := q.Order("space, did").Limit(limit).Find(&rows).Error; err != nil {
+		return nil, "", fmt.Errorf("list repos: %w", err)
+	}
+
+	paused, err := e.pausedSpaces(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	statuses := make([]RepoStatus, len(rows))
+	for i, r := range rows {
+		statuses[i] = RepoStatus{
+			Space:      r.Space,
+			DID:        r.DID,
+			Rev:        r.Rev,
+			State:      string(r.State),
+			Dirty:      r.Dirty,
+			ErrorMsg:   r.ErrorMsg,
+			RetryCount: r.RetryCount,
+			Paused:     paused[r.Space],
+
+			Verification: string(r.Verification),
+		}
+		if r.RetryAfter > 0 {
+			statuses[i].RetryAfter = time.Unix(r.RetryAfter, 0)
+		}
+	}
+	cursor := ""
+	if len(rows) == limit {
+		last := rows[len(rows)-1]
+		cursor = last.Space.String() + " " + last.DID.String()
+	}
+	return statuses, cursor, nil
+}
+
+// Requeue forces repos in space — every one, or only did when it is set — to
+// sync again now, clearing any retry backoff. With full, they are rebuilt
+// from a getRepo snapshot rather than synced incrementally. A repo mid-sync
+// is marked to requeue, for the kind of pass requested, once the current one
+// ends; it is not counted. Returns the number of repos requeued now.
+func (e *Engine) Requeue(
+	ctx context.Context,
+	space habitat_syntax.SpaceURI,
+	did syntax.DID,
+	full bool,
+) (int64, error) {
+	state := statePending
+	if full {
+		state = stateDesynced
+	}
+	var requeued int64
+	err := e.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		scope := func() *gorm.DB {
+			q := tx.Model(&repo{}).Where("space = ?", space)
+			if did != "" {
+				q = q.Where("did = ?", did)
+			}
+			return q
+		}
+		res := scope().
+			Where("state <> ?", stateSyncing).
+			Updates(map[string]any{
+				"state":       state,
+				"dirty":       false,
+				"retry_count": 0,
+				"retry_after": 0,
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		requeued = res.RowsAffected
+		midSync := map[string]any{"dirty": true}
+		if full {
+			midSync["full_pending"] = true
+		}
+		return scope().
+			Where("state = ?", stateSyncing).
+			Updates(midSync).Error
+	})
+	if err != nil {
+		return 0, fmt.Errorf("requeue repos: %w", err)
+	}
+	e.notif.Notify()
+	return requeued, nil
+}
+
+// PauseSpace stops the dispatcher from claiming the space's repos. A pass
+// already under way finishes. Pausing a paused space is a no-op.
+func (e *Engine) PauseSpace(ctx context.Context, space habitat_syntax.SpaceURI) error {
+	if err := e.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&pausedSpace{Space: space, PausedAt: time.Now()}).Error; err != nil {
+		return fmt.Errorf("pause space: %w", err)
+	}
+	return nil
+}
+
+// ResumeSpace lets the dispatcher claim the space's repos again and wakes it.
+func (e *Engine) ResumeSpace(ctx context.Context, space habitat_syntax.SpaceURI) error {
+	if err := e.db.WithContext(ctx).
+		Where("space = ?", space).
+		Delete(&pausedSpace{}).Error; err != nil {
+		return fmt.Errorf("resume space: %w", err)
+	}
+	e.notif.Notify()
+	return nil
+}
+
+// PausedSpaces lists the paused spaces.
+func (e *Engine) PausedSpaces(ctx context.Context) ([]habitat_syntax.SpaceURI, error) {
+	var spaces []habitat_syntax.SpaceURI
+	if err := e.db.WithContext(ctx).
+		Model(&pausedSpace{}).
+		Order("space").
+		Pluck("space", &spaces).Error; err != nil {
+		return nil, fmt.Errorf("list paused spaces: %w", err)
+	}
+	return spaces, nil
+}
+
+func (e *Engine) pausedSpaces(ctx context.Context) (map[habitat_syntax.SpaceURI]bool, error) {
+	spaces, err := e.PausedSpaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+	paused := make(map[habitat_syntax.SpaceURI]bool, len(spaces))
+	for _, space := range spaces {
+		paused[space] = true
+	}
+	return paused, nil
+}
+
+// Progress counts the repos tracked in a set of spaces by sync state, for
+// reporting how far a backfill has got.
+type Progress struct {
+	Repos    int64
+	Active   int64
+	Pending  int64
+	Syncing  int64
+	Desynced int64
+	Errored  int64
+	// Unsynced counts the repos that have not completed a first sync, and
+	// Failed those of them that have failed FailedAfterRetries passes or more.
+	Unsynced int64
+	Failed   int64
+	// Records counts the records held for the repos.
+	Records int64
+}
+
+// Progress reports the sync state of the repos tracked in spaces.
+func (e *Engine) Progress(
+	ctx context.Context,
+	spaces []habitat_syntax.SpaceURI,
+) (Progress, error) {
+	var p Progress
+	if len(spaces) == 0 {
+		return p, nil
+	}
+	db := e.db.WithContext(ctx)
+	var counts []struct {
+		State    repoState
+		Repos    int64
+		Unsynced int64
+		Failed   int64
+	}
+	if err := db.Model(&repo{}).
+		Select("state, COUNT(*) AS repos, "+
+			"SUM(CASE WHEN rev IS NULL OR rev = '' THEN 1 ELSE 0 END) AS unsynced, "+
+			"SUM(CASE WHEN (rev IS NULL OR rev = '') AND retry_count >= ? "+
+			"THEN 1 ELSE 0 END) AS failed", FailedAfterRetries).
+		Where("space IN ?", spaces).
+		Group("state").
+		Scan(&counts).Error; err != nil {
+		return Progress{}, fmt.Errorf("count repos: %w", err)
+	}
+	for _, c := range counts {
+		p.Repos += c.Repos
+		p.Unsynced += c.Unsynced
+		p.Failed += c.Failed
+		switch c.State {
+		case stateActive:
+			p.Active = c.Repos
+		case statePending:
+			p.Pending = c.Repos
+		case stateSyncing:
+			p.Syncing = c.Repos
+		case stateDesynced:
+			p.Desynced = c.Repos
+		case stateError:
+			p.Errored = c.Repos
+		}
+	}
+	if err := db.Model(&repoRecord{}).
+		Where("space IN ?", spaces).
+		Count(&p.Records).Error; err != nil {
+		return Progress{}, fmt.Errorf("count records: %w", err)
+	}
+	return p, nil
+}
+
+// FailedRepos lists the repos in spaces that Progress counts as failed:
+// never synced, and failed at least FailedAfterRetries passes in a row.
+func (e *Engine) FailedRepos(
+	ctx context.Context,
+	spaces []habitat_syntax.SpaceURI,
+) ([]RepoStatus, error) {
+	if len(spaces) == 0 {
+		return nil, nil
+	}
+	var rows []repo
+	if err := e.db.WithContext(ctx).
+		Where("space IN ?", spaces).
+		Where("(rev IS NULL OR rev = '') AND retry_count >= ?", FailedAfterRetries).
+		Order("space, did").
+		Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("list failed repos: %w", err)
+	}
+	statuses := make([]RepoStatus, len(rows))
+	for i, r := range rows {
+		statuses[i] = RepoStatus{
+			Space:      r.Space,
+			DID:        r.DID,
+			State:      string(r.State),
+			ErrorMsg:   r.ErrorMsg,
+			RetryCount: r.RetryCount,
+		}
+	}
+	return statuses, nil
+}
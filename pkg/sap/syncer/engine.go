@@ -13,6 +13,7 @@ import (
 	"fmt"
 	"log/slog"
 	"math/rand"
+	"sync"
 	"time"
 
 	"github.com/bluesky-social/indigo/atproto/atclient"
@@ -25,6 +26,7 @@ import (
 	"github.com/habitat-network/habitat/internal/spacecommit"
 	habitat_syntax "github.com/habitat-network/habitat/internal/syntax"
 	"github.com/habitat-network/habitat/internal/utils"
+	"github.com/habitat-network/habitat/pkg/sap/hostlimit"
 )
 
 type repoState string
@@ -50,10 +52,17 @@ type repo struct {
 	// Dirty records that a notification arrived while the repo was mid-sync;
 	// instead of settling active, the worker requeues it for another pass.
 	Dirty bool `gorm:"not null;default:false"`
+	// FullPending records that a full rebuild was requested while the repo
+	// was mid-sync; when the pass ends the repo is queued for one.
+	FullPending bool `gorm:"not null;default:false"`
 
 	ErrorMsg   string
 	RetryCount int   `gorm:"not null;default:0"`
 	RetryAfter int64 `gorm:"not null;default:0;index"`
+
+	// Verification is the outcome of the repo's last commit verification;
+	// empty until one completes.
+	Verification Outcome `gorm:"index"`
 }
 
 // Clients supplies an atproto API client authorized to read the space (a
@@ -65,14 +74,75 @@ type Clients interface {
 	) (*atclient.APIClient, error)
 }
 
+// Action is what an Event did to a record.
+type Action string
+
+const (
+	ActionCreate Action = "create"
+	ActionUpdate Action = "update"
+	ActionDelete Action = "delete"
+	// ActionResync closes a full getRepo rebuild of a repo. Every record the
+	// repo holds was emitted just before it with the same Rev; a consumer's
+	// copy of any record in the repo with an older rev is stale and should be
+	// dropped.
+	ActionResync Action = "resync"
+)
+
+// Event is one change to a synced repo.
+type Event struct {
+	Space  habitat_syntax.SpaceURI
+	Repo   syntax.DID
+	Action Action
+	// URI is the record changed; empty for ActionResync, which covers the
+	// whole repo.
+	URI habitat_syntax.SpaceRecordURI
+	// Rev is the repo revision the change landed in.
+	Rev syntax.TID
+	// Cid is the record's new CID; empty for a delete.
+	Cid string
+	// PrevCid is the CID the change replaced; empty for a create.
+	PrevCid string
+	// Value is the record's new JSON value; nil for a delete or a resync.
+	Value []byte
+	// PrevValue is the JSON value the change replaced. Only set when the
+	// engine keeps values (WithPrevValues) and held the previous version.
+	PrevValue []byte
+}
+
 // Emitter receives synced records for delivery to the consumer. Satisfied by
 // an outbox adapter. InTx returns an Emitter whose writes join tx, so a batch
 // of records and the repo-state advance commit atomically.
 type Emitter interface {
-	Emit(ctx context.Context, uri habitat_syntax.SpaceRecordURI, value []byte) error
+	Emit(ctx context.Context, event Event) error
 	InTx(tx *gorm.DB) Emitter
 }
 
+type options struct {
+	values     bool
+	prevValues bool
+	filter     Filter
+	poll       *poller
+}
+
+// WithValues sets whether the engine keeps each record's current value
+// alongside its CID in the path index, so the index can serve reads as a
+// local replica. Keeping values costs a copy of every synced record in sap's
+// database. WithPrevValues implies it.
+func WithValues(keep bool) utils.Opt[options] {
+	return func(o *options) {
+		o.values = keep
+	}
+}
+
+// WithPrevValues sets whether events carry PrevValue, the value of the
+// version a record replaces. It keeps values in the path index, as WithValues
+// does, to have them to hand.
+func WithPrevValues(keep bool) utils.Opt[options] {
+	return func(o *options) {
+		o.prevValues = keep
+	}
+}
+
 type job struct {
 	Space habitat_syntax.SpaceURI
 	DID   syntax.DID
@@ -91,6 +161,36 @@ type Engine struct {
 	notif       *utils.PollNotifier
 	jobs        chan job
 	metrics     *metrics
+	keepValues  bool
+	prevValues  bool
+	filter      Filter
+	poll        *poller // nil unless built WithPolling
+	running     *runningJobs
+}
+
+// runningJobs tracks the cancel func of every job a worker is running, by
+// space, so CancelSpace can stop work on a space that is being dropped.
+type runningJobs struct {
+	mu      sync.Mutex
+	bySpace map[habitat_syntax.SpaceURI]map[*job]context.CancelFunc
+}
+
+func (r *runningJobs) start(j *job, cancel context.CancelFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.bySpace[j.Space] == nil {
+		r.bySpace[j.Space] = make(map[*job]context.CancelFunc)
+	}
+	r.bySpace[j.Space][j] = cancel
+}
+
+func (r *runningJobs) finish(j *job) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.bySpace[j.Space], j)
+	if len(r.bySpace[j.Space]) == 0 {
+		delete(r.bySpace, j.Space)
+	}
 }
 
 func New(
@@ -100,8 +200,12 @@ func New(
 	verifier *Verifier,
 	parallelism int,
 	m *metrics,
+	opts ...utils.Opt[options],
 ) (*Engine, error) {
-	if err := db.AutoMigrate(&repo{}, &repoRecord{}); err != nil {
+	o := utils.ResolveOptions(options{}, opts)
+	if err := db.AutoMigrate(
+		&repo{}, &repoRecord{}, &pausedSpace{}, &collectionFilter{}, &spacePoll{},
+	); err != nil {
 		return nil, err
 	}
 	if parallelism <= 0 {
@@ -110,12 +214,19 @@ func New(
 	return &Engine{
 		db:          db,
 		clients:     clients,
-		emitter:     emitter,
+		emitter:     filteredEmitter{Emitter: emitter, filter: o.filter},
 		verifier:    verifier,
 		parallelism: parallelism,
 		notif:       utils.NewPollNotifier(),
 		jobs:        make(chan job),
 		metrics:     m,
+		keepValues:  o.values || o.prevValues,
+		prevValues:  o.prevValues,
+		filter:      o.filter,
+		poll:        o.poll,
+		running: &runningJobs{
+			bySpace: make(map[habitat_syntax.SpaceURI]map[*job]context.CancelFunc),
+		},
 	}, nil
 }
 
@@ -128,11 +239,15 @@ func (e *Engine) WithTx(tx *gorm.DB) *Engine {
 }
 
 // Track starts tracking a repo, if it isn't already, and wakes the dispatcher.
+// Repos in spaces the engine's filter excludes are ignored.
 func (e *Engine) Track(
 	ctx context.Context,
 	space habitat_syntax.SpaceURI,
 	did syntax.DID,
 ) error {
+	if !e.filter.IncludesSpace(space) {
+		return nil
+	}
 	if err := e.db.WithContext(ctx).
 		Clauses(clause.OnConflict{DoNothing: true}).
 		Create(&repo{Space: space, DID: did, State: statePending}).Error; err != nil {
@@ -159,9 +274,10 @@ func (e *Engine) NotifyWrite(
 }
 
 // observeHead applies an observation of a repo's head at the host — from a
-// pushed notification or from a Resync poll — and reports whether it left the
+// pushed notification, a crawl or a poll — and reports whether it left the
 // repo queued for a sync pass. It is the single place that decides whether
-// our copy of a repo is behind.
+// our copy of a repo is behind. Spaces the engine's filter excludes are
+// ignored.
 func (e *Engine) observeHead(
 	ctx context.Context,
 	space habitat_syntax.SpaceURI,
@@ -169,6 +285,9 @@ func (e *Engine) observeHead(
 	rev syntax.TID,
 	hash []byte,
 ) (bool, error) {
+	if !e.filter.IncludesSpace(space) {
+		return false, nil
+	}
 	queued := false
 	err := e.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		queued = false
@@ -250,16 +369,79 @@ func (e *Engine) Check(
 	return nil
 }
 
-// DropSpace stops tracking every repo in the space.
+// DropSpace stops tracking every repo in the space and forgets the records
+// sap held for them.
 func (e *Engine) DropSpace(ctx context.Context, space habitat_syntax.SpaceURI) error {
-	return e.db.WithContext(ctx).
-		Where("space = ?", space).
-		Delete(&repo{}).Error
+	db := e.db.WithContext(ctx)
+	if err := db.Where("space = ?", space).Delete(&repoRecord{}).Error; err != nil {
+		return fmt.Errorf("drop records: %w", err)
+	}
+	if err := db.Where("space = ?", space).Delete(&pausedSpace{}).Error; err != nil {
+		return fmt.Errorf("drop pause: %w", err)
+	}
+	if err := db.Where("space = ?", space).Delete(&spacePoll{}).Error; err != nil {
+		return fmt.Errorf("drop poll schedule: %w", err)
+	}
+	return db.Where("space = ?", space).Delete(&repo{}).Error
+}
+
+// TombstoneSpace emits a delete for every record sap holds in the space, each
+// at its repo's last synced rev, so consumers drop their copies before the
+// space is dropped. It leaves sap's own tracking state alone; call DropSpace
+// after it, in the same transaction, so the deletes and the drop commit
+// together.
+func (e *Engine) TombstoneSpace(ctx context.Context, space habitat_syntax.SpaceURI) error {
+	db := e.db.WithContext(ctx)
+	var repos []repo
+	if err := db.Where("space = ?", space).Find(&repos).Error; err != nil {
+		return fmt.Errorf("load repos: %w", err)
+	}
+	emitter := e.emitter.InTx(e.db)
+	for _, r := range repos {
+		var records []repoRecord
+		if err := db.Where("space = ? AND did = ?", space, r.DID).
+			Order("collection, rkey").
+			Find(&records).Error; err != nil {
+			return fmt.Errorf("load records for %s: %w", r.DID, err)
+		}
+		for _, rec := range records {
+			if err := emitter.Emit(ctx, recoveredEvent(
+				space, r.DID, rec.Collection, rec.Rkey, r.Rev,
+				"", nil, rec.Cid, e.prevValue(rec.Value),
+			)); err != nil {
+				return fmt.Errorf("emit tombstone: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// CancelSpace cancels the syncs workers are running for the space's repos.
+// Call it once the space is dropped, so no worker keeps fetching and emitting
+// records for it.
+func (e *Engine) CancelSpace(space habitat_syntax.SpaceURI) {
+	e.running.mu.Lock()
+	defer e.running.mu.Unlock()
+	for _, cancel := range e.running.bySpace[space] {
+		cancel()
+	}
 }
 
 // Run drives the dispatcher and worker pool until ctx ends.
 func (e *Engine) Run(ctx context.Context) {
+	// Queue backfills for collections the filter newly includes, and for
+	// values a replica is missing, before the first dispatch, so the sweep
+	// below picks them up.
+	if err := e.backfillIncluded(ctx); err != nil {
+		slog.ErrorContext(ctx, "backfill newly included collections", "err", err)
+	}
+	if err := e.fillValues(ctx); err != nil {
+		slog.ErrorContext(ctx, "queue record value fill", "err", err)
+	}
 	go e.runDispatcher(ctx)
+	if e.poll != nil {
+		go e.runPoller(ctx)
+	}
 	for i := 0; i < e.parallelism; i++ {
 		go e.runWorker(ctx, i)
 	}
@@ -321,6 +503,7 @@ func (e *Engine) claimAndQueue(
 			Select("space, did").
 			Where(whereStates).
 			Where("retry_after = 0 OR retry_after < ?", now).
+			Where("space NOT IN (?)", e.db.Model(&pausedSpace{}).Select("space")).
 			Order(priority).Order("space").Order("did").
 			Limit(100).
 			Scan(&candidates).Error
@@ -374,6 +557,13 @@ func (e *Engine) runWorker(ctx context.Context, workerID int) {
 }
 
 func (e *Engine) runJob(ctx context.Context, logger *slog.Logger, j job) {
+	ctx, cancel := context.WithCancel(ctx)
+	e.running.start(&j, cancel)
+	defer func() {
+		e.running.finish(&j)
+		cancel()
+	}()
+
 	ctx, span := e.metrics.tracer.Start(ctx, "sap.syncer.sync_repo",
 		trace.WithAttributes(
 			attribute.String("sap.space", j.Space.String()),
@@ -404,7 +594,8 @@ func (e *Engine) runJob(ctx context.Context, logger *slog.Logger, j job) {
 
 // settle finishes a successful sync pass, recording the repo's new rev and
 // LtHash state. If a notification arrived mid-flight (dirty), the repo is
-// requeued as pending for another pass instead of settling active.
+// requeued as pending for another pass instead of settling active, or as
+// desynced when a full rebuild was requested meanwhile.
 func (e *Engine) settle(
 	ctx context.Context,
 	tx *gorm.DB,
@@ -430,8 +621,10 @@ func (e *Engine) settle(
 	if res.RowsAffected > 0 {
 		return nil
 	}
-	updates["state"] = statePending
+	updates["state"] = gorm.Expr("CASE WHEN full_pending THEN ? ELSE ? END",
+		stateDesynced, statePending)
 	updates["dirty"] = false
+	updates["full_pending"] = false
 	if err := tx.WithContext(ctx).Model(&repo{}).
 		Where("space = ? AND did = ?", space, did).
 		Updates(updates).Error; err != nil {
@@ -441,6 +634,26 @@ func (e *Engine) settle(
 	return nil
 }
 
+// recordVerification counts a verification by outcome and records it on the
+// repo. An empty outcome (the signer could not be resolved) is not recorded.
+func (e *Engine) recordVerification(
+	ctx context.Context,
+	space habitat_syntax.SpaceURI,
+	did syntax.DID,
+	outcome Outcome,
+) {
+	if outcome == "" {
+		return
+	}
+	e.metrics.verified(ctx, string(outcome))
+	if err := e.db.WithContext(ctx).Model(&repo{}).
+		Where("space = ? AND did = ?", space, did).
+		Update("verification", outcome).Error; err != nil {
+		slog.WarnContext(ctx, "record verification outcome",
+			"space", space, "repo", did, "outcome", outcome, "err", err)
+	}
+}
+
 // scheduleRetry parks a repo in state with a retry backoff and records why.
 // It wakes the dispatcher so the retry is picked up once due.
 func (e *Engine) scheduleRetry(
@@ -475,19 +688,33 @@ func (e *Engine) scheduleRetry(
 	// host's full state is the protocol's designed self-healing path, and
 	// delaying it just leaves the repo stale for longer. Repeated desyncs mean
 	// recovery is not converging, so those back off like anything else.
+	// A full rebuild requested mid-pass still runs, whatever the pass hit.
+	if r.FullPending {
+		state = stateDesynced
+	}
 	var retryAfter int64
 	if state != stateDesynced || retryCount > 1 {
 		retryAfter = time.Now().Add(backoff(retryCount, 60)).Unix()
 	}
+	// A host that throttled us is not a fault in the repo: it goes back to
+	// pending rather than error (a desynced repo stays desynced), and is not
+	// claimed again before the host said to come back.
+	if wait, throttled := hostlimit.RetryAfter(cause); throttled {
+		if state == stateError {
+			state = statePending
+		}
+		retryAfter = max(retryAfter, time.Now().Add(wait).Unix())
+	}
 	if err := e.db.WithContext(ctx).
 		Model(&repo{}).
 		Where("space = ? AND did = ?", space, did).
 		Updates(map[string]any{
-			"state":       state,
-			"dirty":       false, // the retry re-syncs to head anyway
-			"error_msg":   errMsg,
-			"retry_count": retryCount,
-			"retry_after": retryAfter,
+			"state":        state,
+			"dirty":        false, // the retry re-syncs to head anyway
+			"full_pending": false,
+			"error_msg":    errMsg,
+			"retry_count":  retryCount,
+			"retry_after":  retryAfter,
 		}).Error; err != nil {
 		return err
 	}
@@ -0,0 +1,189 @@
+package syncer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"gorm.io/gorm"
+
+	habitat_syntax "github.com/habitat-network/habitat/internal/syntax"
+	"github.com/habitat-network/habitat/internal/utils"
+)
+
+// Filter selects which spaces sap syncs and which collections it delivers.
+// The zero Filter selects everything.
+//
+// Space filtering decides what is tracked at all. Collection filtering only
+// decides what is emitted: a repo's records are still fetched, indexed and
+// folded into its LtHash whatever their collection, since verification covers
+// the whole repo, and so that a collection included later can be backfilled
+// from what the index already knows.
+type Filter struct {
+	// SpaceTypes allows only spaces of these types; empty allows every type.
+	SpaceTypes []syntax.NSID
+	// Space allows only the spaces it returns true for; nil allows every
+	// space. Applied after SpaceTypes.
+	Space func(habitat_syntax.SpaceURI) bool
+	// Collections allows only records in these collections; empty allows
+	// every collection.
+	Collections []syntax.NSID
+	// ExcludeCollections drops records in these collections. Applied after
+	// Collections.
+	ExcludeCollections []syntax.NSID
+}
+
+// IncludesSpace reports whether the filter selects space.
+func (f Filter) IncludesSpace(space habitat_syntax.SpaceURI) bool {
+	if len(f.SpaceTypes) > 0 && !slices.Contains(f.SpaceTypes, space.SpaceType()) {
+		return false
+	}
+	return f.Space == nil || f.Space(space)
+}
+
+// IncludesCollection reports whether the filter delivers records in
+// collection.
+func (f Filter) IncludesCollection(collection syntax.NSID) bool {
+	if len(f.Collections) > 0 && !slices.Contains(f.Collections, collection) {
+		return false
+	}
+	return !slices.Contains(f.ExcludeCollections, collection)
+}
+
+// WithFilter sets which spaces the engine tracks and which records it emits.
+func WithFilter(filter Filter) utils.Opt[options] {
+	return func(o *options) {
+		o.filter = filter
+	}
+}
+
+// collectionFilter is the collection filter the engine last applied, kept so
+// a restart under a broader filter can tell which collections are newly
+// included. There is only ever one row.
+type collectionFilter struct {
+	ID                 uint `gorm:"primaryKey"`
+	Collections        string
+	ExcludeCollections string
+}
+
+// filteredEmitter drops events for records in collections the filter
+// excludes. Resync markers name no record and always pass, so a consumer
+// following one drops the repo's records in excluded collections along with
+// its stale ones.
+type filteredEmitter struct {
+	Emitter
+	filter Filter
+}
+
+func (e filteredEmitter) Emit(ctx context.Context, event Event) error {
+	if event.URI != "" && !e.filter.IncludesCollection(event.URI.Collection()) {
+		return nil
+	}
+	return e.Emitter.Emit(ctx, event)
+}
+
+func (e filteredEmitter) InTx(tx *gorm.DB) Emitter {
+	return filteredEmitter{Emitter: e.Emitter.InTx(tx), filter: e.filter}
+}
+
+// backfillIncluded reconciles the engine with a collection filter that
+// changed since it last ran. Records in collections the previous filter
+// excluded and the current one includes were indexed but never emitted, so
+// they are dropped from the index and their repos marked desynced: recovery
+// then sees them as missing, fetches only them, and emits them as creates.
+// Collections the current filter newly excludes are left as they are;
+// consumers keep what they already received until the repo's next resync
+// marker.
+func (e *Engine) backfillIncluded(ctx context.Context) error {
+	return e.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var applied collectionFilter
+		err := tx.First(&applied).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("load applied filter: %w", err)
+		}
+		// With no filter on record, everything was included before.
+		prev := Filter{
+			Collections:        splitCollections(applied.Collections),
+			ExcludeCollections: splitCollections(applied.ExcludeCollections),
+		}
+
+		var held []syntax.NSID
+		if err := tx.Model(&repoRecord{}).
+			Distinct("collection").
+			Pluck("collection", &held).Error; err != nil {
+			return fmt.Errorf("list indexed collections: %w", err)
+		}
+		var included []syntax.NSID
+		for _, collection := range held {
+			if !prev.IncludesCollection(collection) && e.filter.IncludesCollection(collection) {
+				included = append(included, collection)
+			}
+		}
+
+		if len(included) > 0 {
+			var repos []repo
+			if err := tx.Model(&repoRecord{}).
+				Distinct("space", "did").
+				Where("collection IN ?", included).
+				Find(&repos).Error; err != nil {
+				return fmt.Errorf("find repos to backfill: %w", err)
+			}
+			if err := tx.Where("collection IN ?", included).
+				Delete(&repoRecord{}).Error; err != nil {
+				return fmt.Errorf("drop newly included records: %w", err)
+			}
+			for _, r := range repos {
+				if err := tx.Model(&repo{}).
+					Where("space = ? AND did = ?", r.Space, r.DID).
+					Updates(map[string]any{
+						"state":       stateDesynced,
+						"dirty":       false,
+						"retry_count": 0,
+						"retry_after": 0,
+					}).Error; err != nil {
+					return fmt.Errorf("queue backfill for %s: %w", r.DID, err)
+				}
+			}
+		}
+
+		applied.ID = 1
+		applied.Collections = joinCollections(e.filter.Collections)
+		applied.ExcludeCollections = joinCollections(e.filter.ExcludeCollections)
+		return tx.Save(&applied).Error
+	})
+}
+
+// Spaces lists the spaces the engine tracks at least one repo in.
+func (e *Engine) Spaces(ctx context.Context) ([]habitat_syntax.SpaceURI, error) {
+	var spaces []habitat_syntax.SpaceURI
+	if err := e.db.WithContext(ctx).
+		Model(&repo{}).
+		Distinct("space").
+		Pluck("space", &spaces).Error; err != nil {
+		return nil, fmt.Errorf("list tracked spaces: %w", err)
+	}
+	return spaces, nil
+}
+
+func joinCollections(collections []syntax.NSID) string {
+	parts := make([]string, len(collections))
+	for i, c := range collections {
+		parts[i] = c.String()
+	}
+	return strings.Join(parts, ",")
+}
+
+func splitCollections(joined string) []syntax.NSID {
+	if joined == "" {
+		return nil
+	}
+	parts := strings.Split(joined, ",")
+	collections := make([]syntax.NSID, len(parts))
+	for i, p := range parts {
+		collections[i] = syntax.NSID(p)
+	}
+	return collections
+}
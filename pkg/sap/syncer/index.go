@@ -18,17 +18,21 @@ import (
 //
 // It exists so recovery can diff the host's path listing against what sap
 // already holds and re-emit only the records that actually differ, rather than
-// replaying the whole repo through the outbox. Values are not stored: they go
-// to the outbox and are the consumer's to keep.
+// replaying the whole repo through the outbox. Values go to the outbox and are
+// the consumer's to keep; Value is only filled in when the engine was built
+// WithValues, to serve reads as a replica, or WithPrevValues, so events can
+// carry the version they replace.
 type repoRecord struct {
 	Space      habitat_syntax.SpaceURI `gorm:"primaryKey"`
 	DID        syntax.DID              `gorm:"column:did;primaryKey"`
 	Collection syntax.NSID             `gorm:"primaryKey"`
 	Rkey       syntax.RecordKey        `gorm:"primaryKey"`
 	Cid        string
+	Value      []byte
 }
 
-// indexRecord records the CID now at a path, replacing any previous entry.
+// indexRecord records the CID (and, if kept, the value) now at a path,
+// replacing any previous entry.
 func indexRecord(
 	ctx context.Context,
 	tx *gorm.DB,
@@ -37,18 +41,20 @@ func indexRecord(
 	collection syntax.NSID,
 	rkey syntax.RecordKey,
 	cid string,
+	value []byte,
 ) error {
 	return tx.WithContext(ctx).Clauses(clause.OnConflict{
 		Columns: []clause.Column{
 			{Name: "space"}, {Name: "did"}, {Name: "collection"}, {Name: "rkey"},
 		},
-		DoUpdates: clause.AssignmentColumns([]string{"cid"}),
+		DoUpdates: clause.AssignmentColumns([]string{"cid", "value"}),
 	}).Create(&repoRecord{
 		Space:      space,
 		DID:        did,
 		Collection: collection,
 		Rkey:       rkey,
 		Cid:        cid,
+		Value:      value,
 	}).Error
 }
 
@@ -89,6 +95,51 @@ func recordIndex(
 	return index, nil
 }
 
+// recordValues returns the value sap holds for every path in a repo that has
+// one, keyed like recordIndex. Empty unless the engine keeps values.
+func recordValues(
+	ctx context.Context,
+	db *gorm.DB,
+	space habitat_syntax.SpaceURI,
+	did syntax.DID,
+) (map[string][]byte, error) {
+	var rows []repoRecord
+	if err := db.WithContext(ctx).
+		Where("space = ? AND did = ? AND value IS NOT NULL", space, did).
+		Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	values := make(map[string][]byte, len(rows))
+	for _, row := range rows {
+		values[recordPath(row.Collection, row.Rkey)] = row.Value
+	}
+	return values, nil
+}
+
+// recordValue returns the value sap holds for one path, or nil if it holds
+// none.
+func recordValue(
+	ctx context.Context,
+	db *gorm.DB,
+	space habitat_syntax.SpaceURI,
+	did syntax.DID,
+	collection syntax.NSID,
+	rkey syntax.RecordKey,
+) ([]byte, error) {
+	var rows []repoRecord
+	if err := db.WithContext(ctx).
+		Where("space = ? AND did = ? AND collection = ? AND rkey = ?",
+			space, did, collection, rkey).
+		Limit(1).
+		Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	return rows[0].Value, nil
+}
+
 // replaceRecordIndex swaps a repo's whole index for the given paths, for a
 // recovery that rebuilt the repo from the host's full state.
 func replaceRecordIndex(
@@ -0,0 +1,284 @@
+package syncer
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"gorm.io/gorm/clause"
+
+	"github.com/habitat-network/habitat/api/habitat"
+	habitat_syntax "github.com/habitat-network/habitat/internal/syntax"
+	"github.com/habitat-network/habitat/internal/utils"
+)
+
+const (
+	defaultPollMinInterval     = 30 * time.Second
+	defaultPollMaxInterval     = 15 * time.Minute
+	defaultPollHostConcurrency = 2
+)
+
+// Registrations reports which spaces have a live notify registration, so the
+// poller can leave them to pushed notifications. Satisfied by
+// register.Registrar.
+type Registrations interface {
+	Registered(ctx context.Context) ([]habitat_syntax.SpaceURI, error)
+}
+
+// PollConfig tunes polling. Zero fields take their defaults.
+type PollConfig struct {
+	// MinInterval is how soon a space that just showed activity is polled
+	// again (default 30s). A negative MinInterval disables polling.
+	MinInterval time.Duration
+	// MaxInterval caps how far apart an idle space's polls drift (default
+	// 15m). Each poll that finds nothing new doubles the space's interval.
+	MaxInterval time.Duration
+	// HostConcurrency caps the polls in flight against any one host
+	// (default 2).
+	HostConcurrency int
+}
+
+// WithPolling makes the engine poll every tracked space without a live
+// notify registration, as reported by registrations, with listRepos. A nil
+// registrations polls every tracked space, for when sap does not register
+// for notifications at all.
+func WithPolling(registrations Registrations, config PollConfig) utils.Opt[options] {
+	return func(o *options) {
+		if config.MinInterval < 0 {
+			o.poll = nil
+			return
+		}
+		if config.MinInterval == 0 {
+			config.MinInterval = defaultPollMinInterval
+		}
+		if config.MaxInterval < config.MinInterval {
+			config.MaxInterval = max(defaultPollMaxInterval, config.MinInterval)
+		}
+		if config.HostConcurrency <= 0 {
+			config.HostConcurrency = defaultPollHostConcurrency
+		}
+		o.poll = &poller{
+			config:        config,
+			registrations: registrations,
+			hosts:         make(map[string]chan struct{}),
+			inFlight:      make(map[habitat_syntax.SpaceURI]bool),
+		}
+	}
+}
+
+// spacePoll is one polled space's schedule. Interval adapts to the space's
+// activity: it resets to the minimum whenever a poll finds a repo behind, and
+// doubles otherwise.
+type spacePoll struct {
+	Space      habitat_syntax.SpaceURI `gorm:"primaryKey"`
+	Interval   time.Duration
+	NextPollAt time.Time
+	LastActive time.Time
+}
+
+// poller holds the engine's polling state. It is shared by an Engine and its
+// WithTx copies.
+type poller struct {
+	config        PollConfig
+	registrations Registrations
+
+	mu       sync.Mutex
+	hosts    map[string]chan struct{}
+	inFlight map[habitat_syntax.SpaceURI]bool
+}
+
+// runPoller polls due spaces until ctx ends. It wakes often enough that a
+// space at the minimum interval is not polled much later than due.
+func (e *Engine) runPoller(ctx context.Context) {
+	ticker := time.NewTicker(max(e.poll.config.MinInterval/4, time.Second))
+	defer ticker.Stop()
+	for {
+		e.pollDue(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// pollDue starts a poll of every space that is due, at most one per space at
+// a time, each waiting on its host's concurrency limit.
+func (e *Engine) pollDue(ctx context.Context) {
+	due, err := e.dueSpaces(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "poller: find due spaces", "err", err)
+		return
+	}
+	for _, space := range due {
+		e.poll.mu.Lock()
+		if e.poll.inFlight[space] {
+			e.poll.mu.Unlock()
+			continue
+		}
+		e.poll.inFlight[space] = true
+		e.poll.mu.Unlock()
+
+		go func() {
+			defer func() {
+				e.poll.mu.Lock()
+				delete(e.poll.inFlight, space)
+				e.poll.mu.Unlock()
+			}()
+			if err := e.pollSpace(ctx, space); err != nil {
+				slog.WarnContext(ctx, "poller: poll space", "space", space, "err", err)
+			}
+		}()
+	}
+}
+
+// dueSpaces returns the tracked spaces that are neither paused nor live
+// registered and whose next poll is due.
+func (e *Engine) dueSpaces(ctx context.Context) ([]habitat_syntax.SpaceURI, error) {
+	spaces, err := e.Spaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+	paused, err := e.pausedSpaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+	registered := make(map[habitat_syntax.SpaceURI]bool)
+	if e.poll.registrations != nil {
+		live, err := e.poll.registrations.Registered(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("list registered spaces: %w", err)
+		}
+		for _, space := range live {
+			registered[space] = true
+		}
+	}
+	var scheduled []spacePoll
+	if err := e.db.WithContext(ctx).
+		Where("next_poll_at > ?", time.Now()).
+		Find(&scheduled).Error; err != nil {
+		return nil, fmt.Errorf("load poll schedule: %w", err)
+	}
+	notYet := make(map[habitat_syntax.SpaceURI]bool, len(scheduled))
+	for _, p := range scheduled {
+		notYet[p.Space] = true
+	}
+
+	var due []habitat_syntax.SpaceURI
+	for _, space := range spaces {
+		if !paused[space] && !registered[space] && !notYet[space] {
+			due = append(due, space)
+		}
+	}
+	return due, nil
+}
+
+// pollSpace lists the space's repos at its host and feeds each one's head
+// through observeHead, the same staleness check a notification takes, then
+// schedules the space's next poll by whether anything was behind.
+func (e *Engine) pollSpace(ctx context.Context, space habitat_syntax.SpaceURI) error {
+	client, err := e.clients.ClientForSpace(ctx, space)
+	if err != nil {
+		e.metrics.polled(ctx, "error")
+		return e.schedulePoll(ctx, space, false, fmt.Errorf("client for space: %w", err))
+	}
+
+	release, err := e.poll.acquireHost(ctx, client.Host)
+	if err != nil {
+		return err
+	}
+	active := false
+	cursor := ""
+	for {
+		params := map[string]any{"space": space.String()}
+		if cursor != "" {
+			params["cursor"] = cursor
+		}
+		var out habitat.NetworkHabitatSpaceListReposOutput
+		if err := client.Get(ctx, "network.habitat.space.listRepos", params, &out); err != nil {
+			release()
+			e.metrics.polled(ctx, "error")
+			return e.schedulePoll(ctx, space, false, fmt.Errorf("list repos: %w", err))
+		}
+		for _, r := range out.Repos {
+			queued, err := e.observeHead(ctx, space, syntax.DID(r.Did), syntax.TID(r.Rev), r.Hash)
+			if err != nil {
+				release()
+				e.metrics.polled(ctx, "error")
+				return e.schedulePoll(ctx, space, false, fmt.Errorf("observe %s: %w", r.Did, err))
+			}
+			active = active || queued
+		}
+		if out.Cursor == "" || out.Cursor == cursor {
+			break
+		}
+		cursor = out.Cursor
+	}
+	release()
+
+	if active {
+		e.metrics.polled(ctx, "changed")
+	} else {
+		e.metrics.polled(ctx, "idle")
+	}
+	return e.schedulePoll(ctx, space, active, nil)
+}
+
+// schedulePoll records when space is next due. Activity resets the interval
+// to the minimum; a quiet poll or a failed one doubles it, up to the maximum,
+// so an unreachable host is not hammered. cause, when set, is returned after
+// the schedule is saved.
+func (e *Engine) schedulePoll(
+	ctx context.Context,
+	space habitat_syntax.SpaceURI,
+	active bool,
+	cause error,
+) error {
+	var prev []spacePoll
+	if err := e.db.WithContext(ctx).
+		Where("space = ?", space).
+		Limit(1).
+		Find(&prev).Error; err != nil {
+		return fmt.Errorf("load poll schedule: %w", err)
+	}
+	next := spacePoll{Space: space, Interval: e.poll.config.MinInterval}
+	if len(prev) > 0 {
+		next.LastActive = prev[0].LastActive
+		if !active {
+			next.Interval = min(prev[0].Interval*2, e.poll.config.MaxInterval)
+		}
+	}
+	now := time.Now()
+	if active {
+		next.LastActive = now
+	}
+	next.Interval = max(next.Interval, e.poll.config.MinInterval)
+	next.NextPollAt = now.Add(next.Interval)
+	if err := e.db.WithContext(ctx).
+		Clauses(clause.OnConflict{UpdateAll: true}).
+		Create(&next).Error; err != nil {
+		return fmt.Errorf("save poll schedule: %w", err)
+	}
+	return cause
+}
+
+// acquireHost waits for a free poll slot on host and returns its release.
+func (p *poller) acquireHost(ctx context.Context, host string) (func(), error) {
+	p.mu.Lock()
+	slots, ok := p.hosts[host]
+	if !ok {
+		slots = make(chan struct{}, p.config.HostConcurrency)
+		p.hosts[host] = slots
+	}
+	p.mu.Unlock()
+
+	select {
+	case slots <- struct{}{}:
+		return func() { <-slots }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
@@ -57,7 +57,9 @@ func (e *Engine) recoverRepo(
 // records and settles the repo active in a single transaction. held is
 // whatever sap's index holds for the repo going in (empty if none): paths it
 // names that are absent from the recovered snapshot were deleted and get a
-// tombstone emitted, the same as recoverByDiff does for its narrow diff.
+// tombstone emitted, the same as recoverByDiff does for its narrow diff. The
+// batch ends with an ActionResync event, since sap's index may not match what
+// a consumer holds and the snapshot is the repo's complete record set.
 func (e *Engine) recoverFromCAR(
 	ctx context.Context,
 	space habitat_syntax.SpaceURI,
@@ -88,14 +90,12 @@ func (e *Engine) recoverFromCAR(
 	for _, rec := range recovered.Records {
 		lt.Add(spacecommit.RecordElement(rec.Collection, rec.Rkey, rec.Cid.String()))
 	}
-	if err := e.verifier.Verify(ctx, space, repoDID, recovered.Commit, &lt); err != nil {
-		if errors.Is(err, spacecommit.ErrInvalidCommit) {
-			e.metrics.verified(ctx, "invalid")
-		}
+	outcome, err := e.verifier.Verify(ctx, space, repoDID, recovered.Commit, &lt)
+	e.recordVerification(ctx, space, repoDID, outcome)
+	if err != nil {
 		return e.scheduleRetry(ctx, space, repoDID, stateDesynced,
 			fmt.Errorf("verify recovered repo: %w", err))
 	}
-	e.metrics.verified(ctx, "verified")
 
 	present := make(map[string]struct{}, len(recovered.Records))
 	for _, rec := range recovered.Records {
@@ -107,6 +107,12 @@ func (e *Engine) recoverFromCAR(
 			deleted = append(deleted, path)
 		}
 	}
+	kept, err := e.heldValues(ctx, space, repoDID)
+	if err != nil {
+		return e.scheduleRetry(ctx, space, repoDID, stateDesynced,
+			fmt.Errorf("read record values: %w", err))
+	}
+	rev := syntax.TID(recovered.Commit.Rev)
 
 	err = e.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		emitter := e.emitter.InTx(tx)
@@ -116,8 +122,11 @@ func (e *Engine) recoverFromCAR(
 			if err != nil {
 				return fmt.Errorf("marshal record %s/%s: %w", rec.Collection, rec.Rkey, err)
 			}
-			uri := habitat_syntax.ConstructSpaceRecordURI(space, repoDID, rec.Collection, rec.Rkey)
-			if err := emitter.Emit(ctx, uri, value); err != nil {
+			path := recordPath(rec.Collection, rec.Rkey)
+			if err := emitter.Emit(ctx, recoveredEvent(
+				space, repoDID, rec.Collection, rec.Rkey, rev,
+				rec.Cid.String(), value, held[path], e.prevValue(kept[path]),
+			)); err != nil {
 				return err
 			}
 			rows = append(rows, repoRecord{
@@ -126,6 +135,7 @@ func (e *Engine) recoverFromCAR(
 				Collection: rec.Collection,
 				Rkey:       rec.Rkey,
 				Cid:        rec.Cid.String(),
+				Value:      e.keptValue(value),
 			})
 		}
 		for _, path := range deleted {
@@ -133,15 +143,25 @@ func (e *Engine) recoverFromCAR(
 			if err != nil {
 				return fmt.Errorf("parse held path %q: %w", path, err)
 			}
-			uri := habitat_syntax.ConstructSpaceRecordURI(space, repoDID, collection, rkey)
-			if err := emitter.Emit(ctx, uri, []byte("null")); err != nil {
+			if err := emitter.Emit(ctx, recoveredEvent(
+				space, repoDID, collection, rkey, rev,
+				"", nil, held[path], e.prevValue(kept[path]),
+			)); err != nil {
 				return err
 			}
 		}
+		if err := emitter.Emit(ctx, Event{
+			Space:  space,
+			Repo:   repoDID,
+			Action: ActionResync,
+			Rev:    rev,
+		}); err != nil {
+			return err
+		}
 		if err := replaceRecordIndex(ctx, tx, space, repoDID, rows); err != nil {
 			return fmt.Errorf("replace record index: %w", err)
 		}
-		return e.settle(ctx, tx, space, repoDID, syntax.TID(recovered.Commit.Rev), lt.State())
+		return e.settle(ctx, tx, space, repoDID, rev, lt.State())
 	})
 	if err != nil {
 		return e.scheduleRetry(ctx, space, repoDID, stateDesynced,
@@ -196,13 +216,16 @@ func (e *Engine) recoverByDiff(
 		}
 		lt.Add(spacecommit.RecordElement(collection, rkey, p.Cid))
 	}
-	if err := e.verifier.Verify(ctx, space, repoDID, commit, &lt); err != nil {
-		if errors.Is(err, spacecommit.ErrInvalidCommit) {
-			e.metrics.verified(ctx, "invalid")
-		}
+	outcome, err := e.verifier.Verify(ctx, space, repoDID, commit, &lt)
+	e.recordVerification(ctx, space, repoDID, outcome)
+	if err != nil {
 		return fmt.Errorf("verify listed paths: %w", err)
 	}
-	e.metrics.verified(ctx, "verified")
+
+	kept, err := e.heldValues(ctx, space, repoDID)
+	if err != nil {
+		return fmt.Errorf("read record values: %w", err)
+	}
 
 	// Fetch only the paths whose CID sap does not already hold. Paths held
 	// before but absent from this listing were deleted: they need a tombstone
@@ -213,8 +236,12 @@ func (e *Engine) recoverByDiff(
 		collection syntax.NSID
 		rkey       syntax.RecordKey
 		cid        string
+		row        int
 	}
-	var changed []pending
+	// unfilled are paths sap holds at the current CID but without a value,
+	// indexed before the engine kept values. They are fetched to fill the
+	// index in, but consumers already have them and nothing is emitted.
+	var changed, unfilled []pending
 	for _, p := range paths {
 		collection, rkey := syntax.NSID(p.Collection), syntax.RecordKey(p.Rkey)
 		path := recordPath(collection, rkey)
@@ -222,9 +249,14 @@ func (e *Engine) recoverByDiff(
 		rows = append(rows, repoRecord{
 			Space: space, DID: repoDID,
 			Collection: collection, Rkey: rkey, Cid: p.Cid,
+			Value: kept[path],
 		})
-		if held[path] != p.Cid {
-			changed = append(changed, pending{collection: collection, rkey: rkey, cid: p.Cid})
+		c := pending{collection: collection, rkey: rkey, cid: p.Cid, row: len(rows) - 1}
+		switch {
+		case held[path] != p.Cid:
+			changed = append(changed, c)
+		case e.keepValues && kept[path] == nil:
+			unfilled = append(unfilled, c)
 		}
 	}
 	var deleted []string
@@ -245,17 +277,32 @@ func (e *Engine) recoverByDiff(
 			return fmt.Errorf("marshal record %s/%s: %w", c.collection, c.rkey, err)
 		}
 		values[recordPath(c.collection, c.rkey)] = value
+		rows[c.row].Value = e.keptValue(value)
+	}
+	for _, c := range unfilled {
+		out, err := getRecord(ctx, client, space, repoDID, c.collection, c.rkey)
+		if err != nil {
+			return err
+		}
+		if rows[c.row].Value, err = json.Marshal(out.Value); err != nil {
+			return fmt.Errorf("marshal record %s/%s: %w", c.collection, c.rkey, err)
+		}
 	}
 
 	slog.InfoContext(ctx, "narrow recovery",
 		"space", space, "repo", repoDID,
-		"paths", len(paths), "refetched", len(changed), "deleted", len(deleted))
+		"paths", len(paths), "refetched", len(changed), "filled", len(unfilled),
+		"deleted", len(deleted))
 
+	rev := syntax.TID(commit.Rev)
 	err = e.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		emitter := e.emitter.InTx(tx)
 		for _, c := range changed {
-			uri := habitat_syntax.ConstructSpaceRecordURI(space, repoDID, c.collection, c.rkey)
-			if err := emitter.Emit(ctx, uri, values[recordPath(c.collection, c.rkey)]); err != nil {
+			path := recordPath(c.collection, c.rkey)
+			if err := emitter.Emit(ctx, recoveredEvent(
+				space, repoDID, c.collection, c.rkey, rev,
+				c.cid, values[path], held[path], e.prevValue(kept[path]),
+			)); err != nil {
 				return err
 			}
 		}
@@ -264,18 +311,70 @@ func (e *Engine) recoverByDiff(
 			if err != nil {
 				return fmt.Errorf("parse held path %q: %w", path, err)
 			}
-			uri := habitat_syntax.ConstructSpaceRecordURI(space, repoDID, collection, rkey)
-			if err := emitter.Emit(ctx, uri, []byte("null")); err != nil {
+			if err := emitter.Emit(ctx, recoveredEvent(
+				space, repoDID, collection, rkey, rev,
+				"", nil, held[path], e.prevValue(kept[path]),
+			)); err != nil {
 				return err
 			}
 		}
 		if err := replaceRecordIndex(ctx, tx, space, repoDID, rows); err != nil {
 			return fmt.Errorf("replace record index: %w", err)
 		}
-		return e.settle(ctx, tx, space, repoDID, syntax.TID(commit.Rev), lt.State())
+		return e.settle(ctx, tx, space, repoDID, rev, lt.State())
 	})
 	if err != nil {
 		return fmt.Errorf("apply narrow recovery: %w", err)
 	}
 	return nil
 }
+
+// heldValues returns the values sap's index holds for a repo, or nil when the
+// engine does not keep values.
+func (e *Engine) heldValues(
+	ctx context.Context,
+	space habitat_syntax.SpaceURI,
+	repoDID syntax.DID,
+) (map[string][]byte, error) {
+	if !e.keepValues {
+		return nil, nil
+	}
+	return recordValues(ctx, e.db, space, repoDID)
+}
+
+// recoveredEvent builds the event for one record a recovery reconciled. A
+// recovery sees only the repo's state before and after, not the ops between,
+// so the action follows from what sap held: nothing held is a create, no new
+// cid is a delete, and anything else an update. Every event carries the rev
+// the repo was recovered to.
+func recoveredEvent(
+	space habitat_syntax.SpaceURI,
+	repoDID syntax.DID,
+	collection syntax.NSID,
+	rkey syntax.RecordKey,
+	rev syntax.TID,
+	cid string,
+	value []byte,
+	prevCid string,
+	prevValue []byte,
+) Event {
+	event := Event{
+		Space:     space,
+		Repo:      repoDID,
+		URI:       habitat_syntax.ConstructSpaceRecordURI(space, repoDID, collection, rkey),
+		Rev:       rev,
+		Cid:       cid,
+		PrevCid:   prevCid,
+		Value:     value,
+		PrevValue: prevValue,
+	}
+	switch {
+	case cid == "":
+		event.Action = ActionDelete
+	case prevCid == "":
+		event.Action = ActionCreate
+	default:
+		event.Action = ActionUpdate
+	}
+	return event
+}
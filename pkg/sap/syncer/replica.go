@@ -0,0 +1,207 @@
+package syncer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"gorm.io/gorm"
+
+	"github.com/habitat-network/habitat/internal/spacecommit"
+	habitat_syntax "github.com/habitat-network/habitat/internal/syntax"
+)
+
+const (
+	defaultRecordPageSize = 50
+	maxRecordPageSize     = 100
+)
+
+var (
+	// ErrRecordNotFound is returned by Record for a path the index does not
+	// hold.
+	ErrRecordNotFound = errors.New("record not found")
+	// ErrNoValues is returned by the replica reads when the engine was built
+	// without WithValues, so the index holds CIDs only.
+	ErrNoValues = errors.New("engine does not keep record values")
+)
+
+// Record is one record as the index holds it.
+type Record struct {
+	Collection syntax.NSID
+	Rkey       syntax.RecordKey
+	Cid        string
+	// Value is the record's JSON, nil for a record indexed before the engine
+	// kept values and not yet filled in.
+	Value []byte
+}
+
+// RecordQuery selects the records Records lists.
+type RecordQuery struct {
+	// Collection limits the listing to one collection; empty lists every
+	// collection.
+	Collection syntax.NSID
+	// Limit caps the page size (default 50, max 100).
+	Limit int
+	// Cursor continues from a previous page.
+	Cursor string
+	// Reverse lists in descending rather than ascending path order.
+	Reverse bool
+}
+
+// RepoHead is where a synced repo stands: the rev sap last verified and the
+// LtHash sum of its records at that rev.
+type RepoHead struct {
+	DID  syntax.DID
+	Rev  syntax.TID
+	Hash []byte
+}
+
+// Record returns the record the index holds at a path.
+func (e *Engine) Record(
+	ctx context.Context,
+	space habitat_syntax.SpaceURI,
+	did syntax.DID,
+	collection syntax.NSID,
+	rkey syntax.RecordKey,
+) (Record, error) {
+	if !e.keepValues {
+		return Record{}, ErrNoValues
+	}
+	var rows []repoRecord
+	if err := e.db.WithContext(ctx).
+		Where("space = ? AND did = ? AND collection = ? AND rkey = ?",
+			space, did, collection, rkey).
+		Limit(1).
+		Find(&rows).Error; err != nil {
+		return Record{}, fmt.Errorf("read record: %w", err)
+	}
+	if len(rows) == 0 {
+		return Record{}, fmt.Errorf("%w: %s/%s", ErrRecordNotFound, collection, rkey)
+	}
+	return recordOf(rows[0]), nil
+}
+
+// Records lists the records the index holds for a repo, ordered by
+// collection then rkey, and returns a cursor for the next page (empty on the
+// last one).
+func (e *Engine) Records(
+	ctx context.Context,
+	space habitat_syntax.SpaceURI,
+	did syntax.DID,
+	query RecordQuery,
+) ([]Record, string, error) {
+	if !e.keepValues {
+		return nil, "", ErrNoValues
+	}
+	limit := query.Limit
+	if limit <= 0 {
+		limit = defaultRecordPageSize
+	}
+	limit = min(limit, maxRecordPageSize)
+
+	q := e.db.WithContext(ctx).Where("space = ? AND did = ?", space, did)
+	if query.Collection != "" {
+		q = q.Where("collection = ?", query.Collection)
+	}
+	order, after := "collection ASC, rkey ASC", ">"
+	if query.Reverse {
+		order, after = "collection DESC, rkey DESC", "<"
+	}
+	if query.Cursor != "" {
+		collection, rkey, err := parseRecordPath(query.Cursor)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid cursor %q", query.Cursor)
+		}
+		q = q.Where("collection "+after+" ? OR (collection = ? AND rkey "+after+" ?)",
+			collection, collection, rkey)
+	}
+	var rows []repoRecord
+	if err := q.Order(order).Limit(limit).Find(&rows).Error; err != nil {
+		return nil, "", fmt.Errorf("list records: %w", err)
+	}
+
+	records := make([]Record, len(rows))
+	for i, row := range rows {
+		records[i] = recordOf(row)
+	}
+	cursor := ""
+	if len(rows) == limit {
+		last := rows[len(rows)-1]
+		cursor = recordPath(last.Collection, last.Rkey)
+	}
+	return records, cursor, nil
+}
+
+// RepoHeads lists the repos in a space that have synced at least once,
+// ordered by DID, a page at a time like Repos.
+func (e *Engine) RepoHeads(
+	ctx context.Context,
+	space habitat_syntax.SpaceURI,
+	limit int,
+	cursor string,
+) ([]RepoHead, string, error) {
+	if limit <= 0 {
+		limit = defaultRepoPageSize
+	}
+	limit = min(limit, maxRepoPageSize)
+
+	q := e.db.WithContext(ctx).Where("space = ? AND rev <> ''", space)
+	if cursor != "" {
+		q = q.Where("did > ?", cursor)
+	}
+	var rows []repo
+	if err := q.Order("did").Limit(limit).Find(&rows).Error; err != nil {
+		return nil, "", fmt.Errorf("list repo heads: %w", err)
+	}
+	heads := make([]RepoHead, len(rows))
+	for i, r := range rows {
+		lt := spacecommit.Load(r.Hash)
+		heads[i] = RepoHead{DID: r.DID, Rev: r.Rev, Hash: lt.Sum()}
+	}
+	next := ""
+	if len(rows) == limit {
+		next = rows[len(rows)-1].DID.String()
+	}
+	return heads, next, nil
+}
+
+// fillValues queues repos whose index predates the engine keeping values for
+// narrow recovery, which fetches the missing values without emitting them
+// again. A no-op when the engine does not keep values.
+func (e *Engine) fillValues(ctx context.Context) error {
+	if !e.keepValues {
+		return nil
+	}
+	return e.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var repos []repo
+		if err := tx.Model(&repoRecord{}).
+			Distinct("space", "did").
+			Where("value IS NULL").
+			Find(&repos).Error; err != nil {
+			return fmt.Errorf("find repos without values: %w", err)
+		}
+		for _, r := range repos {
+			if err := tx.Model(&repo{}).
+				Where("space = ? AND did = ? AND state <> ?", r.Space, r.DID, stateSyncing).
+				Updates(map[string]any{
+					"state":       stateDesynced,
+					"dirty":       false,
+					"retry_count": 0,
+					"retry_after": 0,
+				}).Error; err != nil {
+				return fmt.Errorf("queue value fill for %s: %w", r.DID, err)
+			}
+		}
+		return nil
+	})
+}
+
+func recordOf(row repoRecord) Record {
+	return Record{
+		Collection: row.Collection,
+		Rkey:       row.Rkey,
+		Cid:        row.Cid,
+		Value:      row.Value,
+	}
+}
@@ -97,27 +97,31 @@ func (e *Engine) syncRepo(
 
 	// A nil commit means the head of the oplog was never reached (empty
 	// repo, or no signer covers the owner); there is nothing to verify
-	// against.
+	// against, which the trust policy may refuse.
+	var outcome Outcome
 	if headCommit != nil {
 		commit := spacecommit.FromXRPC(*headCommit)
-		if err := e.verifier.Verify(ctx, space, repoDID, commit, &lt); err != nil {
-			if errors.Is(err, spacecommit.ErrInvalidCommit) {
-				e.metrics.verified(ctx, "invalid")
-				return e.scheduleRetry(ctx, space, repoDID, stateDesynced,
-					fmt.Errorf("verify head commit: %w", err))
-			}
-			return e.scheduleRetry(ctx, space, repoDID, stateError, err)
+		outcome, err = e.verifier.Verify(ctx, space, repoDID, commit, &lt)
+	} else {
+		outcome, err = e.verifier.Unsigned()
+	}
+	e.recordVerification(ctx, space, repoDID, outcome)
+	if err != nil {
+		if errors.Is(err, spacecommit.ErrInvalidCommit) {
+			return e.scheduleRetry(ctx, space, repoDID, stateDesynced,
+				fmt.Errorf("verify head commit: %w", err))
 		}
-		e.metrics.verified(ctx, "verified")
+		return e.scheduleRetry(ctx, space, repoDID, stateError, err)
 	}
 
 	return e.settle(ctx, e.db, space, repoDID, since, lt.State())
 }
 
 // applyOps folds a page of oplog entries into the running LtHash and emits
-// each record carrying a value. Entries follow the lexicon's nullable cid/prev
+// an event for each. Entries follow the lexicon's nullable cid/prev
 // semantics: prev set → the previous version folds out; cid set → the new
-// version folds in (absent for deletes).
+// version folds in (absent for deletes). So a missing cid is a delete, a
+// missing prev a create, and anything else an update.
 func (e *Engine) applyOps(
 	ctx context.Context,
 	tx *gorm.DB,
@@ -149,6 +153,34 @@ func (e *Engine) applyOps(
 			lt.Add(spacecommit.RecordElement(collection, rkey, op.Cid))
 		}
 
+		event := Event{
+			Space:   space,
+			Repo:    repoDID,
+			URI:     habitat_syntax.ConstructSpaceRecordURI(space, repoDID, collection, rkey),
+			Rev:     syntax.TID(op.Rev),
+			Cid:     op.Cid,
+			PrevCid: op.Prev,
+		}
+		switch {
+		case op.Cid == "":
+			event.Action = ActionDelete
+		case op.Prev == "":
+			event.Action = ActionCreate
+		default:
+			event.Action = ActionUpdate
+		}
+		if op.Value != nil {
+			if event.Value, err = json.Marshal(op.Value); err != nil {
+				return fmt.Errorf("marshal record %s/%s: %w", collection, rkey, err)
+			}
+		}
+		if e.prevValues && op.Prev != "" {
+			if event.PrevValue, err = recordValue(
+				ctx, tx, space, repoDID, collection, rkey); err != nil {
+				return fmt.Errorf("read previous value %s/%s: %w", collection, rkey, err)
+			}
+		}
+
 		// Keep the path index in step with the hash, in the same transaction,
 		// so recovery can tell which records it already holds.
 		if op.Cid == "" {
@@ -156,31 +188,42 @@ func (e *Engine) applyOps(
 				return fmt.Errorf("forget record %s/%s: %w", collection, rkey, err)
 			}
 		} else if err := indexRecord(
-			ctx, tx, space, repoDID, collection, rkey, op.Cid); err != nil {
+			ctx, tx, space, repoDID, collection, rkey, op.Cid, e.keptValue(event.Value),
+		); err != nil {
 			return fmt.Errorf("index record %s/%s: %w", collection, rkey, err)
 		}
 
-		if op.Value == nil {
-			// A delete: emit a JSON null tombstone so consumers remove their
-			// copy. The URI identifies the deleted record.
-			uri := habitat_syntax.ConstructSpaceRecordURI(space, repoDID, collection, rkey)
-			if err := emitter.Emit(ctx, uri, []byte("null")); err != nil {
-				return err
-			}
+		// A create or update without a value was superseded by a later op in
+		// the log, which carries the record's current state; emitting it would
+		// hand consumers a version with nothing in it.
+		if event.Action != ActionDelete && event.Value == nil {
 			continue
 		}
-		value, err := json.Marshal(op.Value)
-		if err != nil {
-			return fmt.Errorf("marshal record %s/%s: %w", collection, rkey, err)
-		}
-		uri := habitat_syntax.ConstructSpaceRecordURI(space, repoDID, collection, rkey)
-		if err := emitter.Emit(ctx, uri, value); err != nil {
+		if err := emitter.Emit(ctx, event); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+// keptValue is the value to store in the path index: the value itself when
+// the engine keeps values, nil otherwise.
+func (e *Engine) keptValue(value []byte) []byte {
+	if !e.keepValues {
+		return nil
+	}
+	return value
+}
+
+// prevValue is the PrevValue to emit for a held value: the value itself when
+// the engine was built WithPrevValues, nil otherwise.
+func (e *Engine) prevValue(value []byte) []byte {
+	if !e.prevValues {
+		return nil
+	}
+	return value
+}
+
 // listRepoOps performs one network.habitat.space.listRepoOps page request.
 func listRepoOps(
 	ctx context.Context,
@@ -27,6 +27,8 @@ import (
 	"github.com/habitat-network/habitat/internal/did"
 	"github.com/habitat-network/habitat/internal/spacecommit"
 	habitat_syntax "github.com/habitat-network/habitat/internal/syntax"
+	"github.com/habitat-network/habitat/internal/utils"
+	"github.com/habitat-network/habitat/pkg/sap/hostlimit"
 )
 
 type fakeClients struct{ base *url.URL }
@@ -38,31 +40,37 @@ func (f fakeClients) ClientForSpace(
 	return &atclient.APIClient{Client: http.DefaultClient, Host: f.base.String()}, nil
 }
 
-// memEmitter collects emitted records in memory.
+// memEmitter collects emitted events in memory: record events by URI (the
+// last one per URI wins), resync markers separately.
 type memEmitter struct {
 	mu      sync.Mutex
 	emitted []habitat_syntax.SpaceRecordURI
-	values  map[habitat_syntax.SpaceRecordURI][]byte
+	events  map[habitat_syntax.SpaceRecordURI]Event
+	resyncs []Event
 }
 
-func (e *memEmitter) Emit(
-	_ context.Context,
-	uri habitat_syntax.SpaceRecordURI,
-	value []byte,
-) error {
+func (e *memEmitter) Emit(_ context.Context, event Event) error {
 	e.mu.Lock()
 	defer e.mu.Unlock()
-	e.emitted = append(e.emitted, uri)
-	if e.values == nil {
-		e.values = make(map[habitat_syntax.SpaceRecordURI][]byte)
+	if event.Action == ActionResync {
+		e.resyncs = append(e.resyncs, event)
+		return nil
 	}
-	e.values[uri] = value
+	e.emitted = append(e.emitted, event.URI)
+	if e.events == nil {
+		e.events = make(map[habitat_syntax.SpaceRecordURI]Event)
+	}
+	e.events[event.URI] = event
 	return nil
 }
 
 func (e *memEmitter) InTx(*gorm.DB) Emitter { return e }
 
-func newTestEngine(t *testing.T, hostURL string) (*Engine, *memEmitter, *gorm.DB) {
+func newTestEngine(
+	t *testing.T,
+	hostURL string,
+	opts ...utils.Opt[options],
+) (*Engine, *memEmitter, *gorm.DB) {
 	t.Helper()
 	db := db_testutil.NewDB(t)
 	base, err := url.Parse(hostURL)
@@ -70,7 +78,15 @@ func newTestEngine(t *testing.T, hostURL string) (*Engine, *memEmitter, *gorm.DB
 	m, err := NewMetrics(nil, nil)
 	require.NoError(t, err)
 	emitter := &memEmitter{}
-	e, err := New(db, fakeClients{base: base}, emitter, NewVerifier(nil), 1, m)
+	e, err := New(
+		db,
+		fakeClients{base: base},
+		emitter,
+		NewVerifier(nil, TrustPolicy{}),
+		1,
+		m,
+		opts...,
+	)
 	require.NoError(t, err)
 	e.jobs = make(chan job, 100)
 	return e, emitter, db
@@ -193,7 +209,8 @@ func TestEngineNewDefaults(t *testing.T) {
 	db := db_testutil.NewDB(t)
 	m, err := NewMetrics(nil, nil)
 	require.NoError(t, err)
-	e, err := New(db, fakeClients{base: &url.URL{}}, &memEmitter{}, NewVerifier(nil), 0, m)
+	v := NewVerifier(nil, TrustPolicy{})
+	e, err := New(db, fakeClients{base: &url.URL{}}, &memEmitter{}, v, 0, m)
 	require.NoError(t, err)
 	require.Equal(t, 5, e.parallelism)
 }
@@ -206,7 +223,8 @@ func TestEngineWithTx(t *testing.T) {
 	db := db_testutil.NewDB(t)
 	m, err := NewMetrics(nil, nil)
 	require.NoError(t, err)
-	orig, err := New(db, fakeClients{base: &url.URL{}}, &memEmitter{}, NewVerifier(nil), 1, m)
+	v := NewVerifier(nil, TrustPolicy{})
+	orig, err := New(db, fakeClients{base: &url.URL{}}, &memEmitter{}, v, 1, m)
 	require.NoError(t, err)
 
 	tx := db.Begin()
@@ -237,6 +255,36 @@ func TestEngineDropSpace(t *testing.T) {
 	require.Equal(t, int64(0), count)
 }
 
+// TestEngineCancelSpace verifies that CancelSpace stops a sync a worker is
+// running for the space.
+func TestEngineCancelSpace(t *testing.T) {
+	t.Parallel()
+
+	requested := make(chan struct{}, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested <- struct{}{}
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	space := habitat_syntax.SpaceURI("at://did:plc:owner/space/network.habitat.space/s1")
+	e, _, _ := newTestEngine(t, srv.URL)
+	require.NoError(t, e.Track(t.Context(), space, "did:plc:alice"))
+
+	done := make(chan struct{})
+	go func() {
+		e.runJob(t.Context(), slog.Default(), job{Space: space, DID: "did:plc:alice"})
+		close(done)
+	}()
+	<-requested
+	e.CancelSpace(space)
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("sync kept running after CancelSpace")
+	}
+}
+
 // TestEngineNotifyWriteSyncingMarksDirty verifies that a notification arriving
 // while a repo is syncing marks it dirty for requeue.
 func TestEngineNotifyWriteSyncingMarksDirty(t *testing.T) {
@@ -289,8 +337,10 @@ func TestVerifierNilDir(t *testing.T) {
 		Rev:  "3kzl6abcde02k",
 	}
 
-	v := NewVerifier(nil)
-	require.NoError(t, v.Verify(t.Context(), space, "did:plc:alice", commit, &lt))
+	v := NewVerifier(nil, TrustPolicy{})
+	outcome, err := v.Verify(t.Context(), space, "did:plc:alice", commit, &lt)
+	require.NoError(t, err)
+	require.Equal(t, OutcomeHashOnly, outcome)
 }
 
 // TestVerifierNilDirMismatch verifies that a hash mismatch is caught in
@@ -307,9 +357,9 @@ func TestVerifierNilDirMismatch(t *testing.T) {
 		Rev:  "3kzl6abcde02k",
 	}
 
-	v := NewVerifier(nil)
-	err := v.Verify(t.Context(), space, "did:plc:alice", commit, &lt)
-	require.Error(t, err)
+	v := NewVerifier(nil, TrustPolicy{})
+	outcome, err := v.Verify(t.Context(), space, "did:plc:alice", commit, &lt)
+	require.Equal(t, OutcomeInvalid, outcome)
 	require.ErrorIs(t, err, spacecommit.ErrInvalidCommit)
 }
 
@@ -327,7 +377,96 @@ func TestVerifierNilPointer(t *testing.T) {
 	}
 
 	var v *Verifier
-	require.NoError(t, v.Verify(t.Context(), space, "did:plc:alice", commit, &lt))
+	_, err := v.Verify(t.Context(), space, "did:plc:alice", commit, &lt)
+	require.NoError(t, err)
+}
+
+// hostSigned signs every author's commits with the host key, as for authors
+// whose identity lives on an external PDS.
+type hostSigned struct{}
+
+func (hostSigned) PrivateKeyForDID(context.Context, syntax.DID) (atcrypto.PrivateKey, error) {
+	return nil, identity.ErrDIDNotFound
+}
+
+// TestVerifierTrustedHosts covers the host allowlist: a host-signed commit is
+// accepted from a listed host and refused from any other.
+func TestVerifierTrustedHosts(t *testing.T) {
+	t.Parallel()
+
+	priv, err := atcrypto.GeneratePrivateKeyK256()
+	require.NoError(t, err)
+	pub, err := priv.PublicKey()
+	require.NoError(t, err)
+
+	hostDID := syntax.DID("did:web:host.example.com")
+	dir := identity.NewMockDirectory()
+	dir.Insert(*did.New("did:plc:owner").ATProtoSpaceHost("https://host.example.com").Build())
+	dir.Insert(*did.New(hostDID).ATProtoSpaceKey(pub.Multibase()).Build())
+
+	space := habitat_syntax.SpaceURI("at://did:plc:owner/space/network.habitat.space/s1")
+	var lt spacecommit.LtHash
+	lt.Add(spacecommit.RecordElement("net.test", "r1", "cid1"))
+	commit, err := spacecommit.NewAuthority(priv, hostSigned{}).
+		Build(t.Context(), space, "did:plc:external", "3kzl6abcde02k", lt.Sum())
+	require.NoError(t, err)
+
+	v := NewVerifier(dir, TrustPolicy{TrustedHosts: []syntax.DID{hostDID}})
+	outcome, err := v.Verify(t.Context(), space, "did:plc:external", commit, &lt)
+	require.NoError(t, err)
+	require.Equal(t, OutcomeHostSigned, outcome)
+
+	v = NewVerifier(dir, TrustPolicy{TrustedHosts: []syntax.DID{"did:web:other.example.com"}})
+	outcome, err = v.Verify(t.Context(), space, "did:plc:external", commit, &lt)
+	require.ErrorIs(t, err, ErrUntrusted)
+	require.Equal(t, OutcomeUntrusted, outcome)
+}
+
+// TestVerifierPolicyRefusesUnchecked covers the policies that refuse commits
+// whose signature was not checked: strict mode refuses hash-only
+// verification, and requiring signatures refuses unsigned commits and syncs
+// that reached no commit.
+func TestVerifierPolicyRefusesUnchecked(t *testing.T) {
+	t.Parallel()
+
+	space := habitat_syntax.SpaceURI("at://did:plc:owner/space/network.habitat.space/s1")
+	var lt spacecommit.LtHash
+	lt.Add(spacecommit.RecordElement("net.test", "r1", "cid1"))
+	commit := spacecommit.SignedCommit{
+		Ver:  spacecommit.Version,
+		Hash: lt.Sum(),
+		Rev:  "3kzl6abcde02k",
+	}
+
+	strict := NewVerifier(nil, TrustPolicy{Strict: true})
+	outcome, err := strict.Verify(t.Context(), space, "did:plc:alice", commit, &lt)
+	require.ErrorIs(t, err, ErrUntrusted)
+	require.Equal(t, OutcomeUntrusted, outcome)
+
+	signed := NewVerifier(nil, TrustPolicy{RequireSignatures: true})
+	outcome, err = signed.Verify(t.Context(), space, "did:plc:alice", commit, &lt)
+	require.ErrorIs(t, err, ErrUntrusted)
+	require.Equal(t, OutcomeUntrusted, outcome)
+
+	// Carrying a signature is not enough when there is no directory to
+	// check it with.
+	commit.Sig = []byte("unchecked")
+	outcome, err = signed.Verify(t.Context(), space, "did:plc:alice", commit, &lt)
+	require.ErrorIs(t, err, ErrUntrusted)
+	require.Equal(t, OutcomeUntrusted, outcome)
+	outcome, err = NewVerifier(nil, TrustPolicy{}).Verify(
+		t.Context(), space, "did:plc:alice", commit, &lt,
+	)
+	require.NoError(t, err)
+	require.Equal(t, OutcomeHashOnly, outcome)
+
+	outcome, err = signed.Unsigned()
+	require.ErrorIs(t, err, ErrUntrusted)
+	require.Equal(t, OutcomeUntrusted, outcome)
+
+	outcome, err = NewVerifier(nil, TrustPolicy{}).Unsigned()
+	require.NoError(t, err)
+	require.Equal(t, OutcomeUnsigned, outcome)
 }
 
 // TestEngineScheduleRetry covers the retry backoff path.
@@ -388,7 +527,7 @@ func TestEngineRecoverRepoClientError(t *testing.T) {
 	m, err := NewMetrics(nil, nil)
 	require.NoError(t, err)
 	failClient := &failClients{}
-	e, err := New(db, failClient, &memEmitter{}, NewVerifier(nil), 1, m)
+	e, err := New(db, failClient, &memEmitter{}, NewVerifier(nil, TrustPolicy{}), 1, m)
 	require.NoError(t, err)
 
 	space := habitat_syntax.SpaceURI("at://did:plc:owner/space/network.habitat.space/s1")
@@ -420,7 +559,7 @@ func TestEngineRecoverRepoNonOK(t *testing.T) {
 	db := db_testutil.NewDB(t)
 	m, err := NewMetrics(nil, nil)
 	require.NoError(t, err)
-	e, err := New(db, fakeClients{base: base}, &memEmitter{}, NewVerifier(nil), 1, m)
+	e, err := New(db, fakeClients{base: base}, &memEmitter{}, NewVerifier(nil, TrustPolicy{}), 1, m)
 	require.NoError(t, err)
 
 	space := habitat_syntax.SpaceURI("at://did:plc:owner/space/network.habitat.space/s1")
@@ -452,7 +591,7 @@ func TestEngineRecoverRepoInvalidCAR(t *testing.T) {
 	db := db_testutil.NewDB(t)
 	m, err := NewMetrics(nil, nil)
 	require.NoError(t, err)
-	e, err := New(db, fakeClients{base: base}, &memEmitter{}, NewVerifier(nil), 1, m)
+	e, err := New(db, fakeClients{base: base}, &memEmitter{}, NewVerifier(nil, TrustPolicy{}), 1, m)
 	require.NoError(t, err)
 
 	space := habitat_syntax.SpaceURI("at://did:plc:owner/space/network.habitat.space/s1")
@@ -491,7 +630,7 @@ func TestVerifierSignerWebAuthor(t *testing.T) {
 
 	dir := identity.NewMockDirectory()
 	dir.Insert(*ident)
-	v := NewVerifier(dir)
+	v := NewVerifier(dir, TrustPolicy{})
 	space := habitat_syntax.SpaceURI("at://did:plc:owner/space/network.habitat.space/s1")
 	var lt spacecommit.LtHash
 	_ = spacecommit.SignedCommit{
@@ -500,7 +639,7 @@ func TestVerifierSignerWebAuthor(t *testing.T) {
 		Rev:  "3kzl6abcde02k",
 	}
 
-	got, err := v.signer(t.Context(), space, authorDID)
+	got, _, err := v.signer(t.Context(), space, authorDID)
 	require.NoError(t, err)
 	require.Equal(t, pub.Multibase(), got.Multibase())
 }
@@ -523,10 +662,10 @@ func TestVerifierSignerExternalAuthor(t *testing.T) {
 	dir := identity.NewMockDirectory()
 	dir.Insert(*ownerIdent)
 	dir.Insert(*hostIdent)
-	v := NewVerifier(dir)
+	v := NewVerifier(dir, TrustPolicy{})
 	space := habitat_syntax.SpaceURI("at://did:plc:owner/space/network.habitat.space/s1")
 
-	got, err := v.signer(t.Context(), space, "did:plc:external")
+	got, _, err := v.signer(t.Context(), space, "did:plc:external")
 	require.NoError(t, err)
 	require.Equal(t, pub.Multibase(), got.Multibase())
 }
@@ -550,10 +689,10 @@ func TestVerifierSignerExternalAuthorFallsBackToAtproto(t *testing.T) {
 	dir := identity.NewMockDirectory()
 	dir.Insert(*ownerIdent)
 	dir.Insert(*hostIdent)
-	v := NewVerifier(dir)
+	v := NewVerifier(dir, TrustPolicy{})
 	space := habitat_syntax.SpaceURI("at://did:plc:owner/space/network.habitat.space/s1")
 
-	got, err := v.signer(t.Context(), space, "did:plc:external")
+	got, _, err := v.signer(t.Context(), space, "did:plc:external")
 	require.NoError(t, err)
 	require.Equal(t, pub.Multibase(), got.Multibase())
 }
@@ -564,10 +703,10 @@ func TestVerifierSignerAuthorLookupError(t *testing.T) {
 	t.Parallel()
 
 	dir := identity.NewMockDirectory()
-	v := NewVerifier(dir)
+	v := NewVerifier(dir, TrustPolicy{})
 	space := habitat_syntax.SpaceURI("at://did:plc:owner/space/network.habitat.space/s1")
 
-	_, err := v.signer(t.Context(), space, "did:web:missing.example.com")
+	_, _, err := v.signer(t.Context(), space, "did:web:missing.example.com")
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "lookup author")
 }
@@ -582,10 +721,10 @@ func TestVerifierSignerAuthorNoKey(t *testing.T) {
 
 	dir := identity.NewMockDirectory()
 	dir.Insert(*ident)
-	v := NewVerifier(dir)
+	v := NewVerifier(dir, TrustPolicy{})
 	space := habitat_syntax.SpaceURI("at://did:plc:owner/space/network.habitat.space/s1")
 
-	_, err := v.signer(t.Context(), space, authorDID)
+	_, _, err := v.signer(t.Context(), space, authorDID)
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "author signing key")
 }
@@ -596,10 +735,10 @@ func TestVerifierSignerExternalOwnerLookupError(t *testing.T) {
 	t.Parallel()
 
 	dir := identity.NewMockDirectory()
-	v := NewVerifier(dir)
+	v := NewVerifier(dir, TrustPolicy{})
 	space := habitat_syntax.SpaceURI("at://did:plc:owner/space/network.habitat.space/s1")
 
-	_, err := v.signer(t.Context(), space, "did:plc:external")
+	_, _, err := v.signer(t.Context(), space, "did:plc:external")
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "lookup space owner")
 }
@@ -614,10 +753,10 @@ func TestVerifierSignerExternalNoHabitatService(t *testing.T) {
 
 	dir := identity.NewMockDirectory()
 	dir.Insert(*ownerIdent)
-	v := NewVerifier(dir)
+	v := NewVerifier(dir, TrustPolicy{})
 	space := habitat_syntax.SpaceURI("at://did:plc:owner/space/network.habitat.space/s1")
 
-	_, err := v.signer(t.Context(), space, "did:plc:external")
+	_, _, err := v.signer(t.Context(), space, "did:plc:external")
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "no atproto_space_host service")
 }
@@ -632,10 +771,10 @@ func TestVerifierSignerExternalHostLookupError(t *testing.T) {
 
 	dir := identity.NewMockDirectory()
 	dir.Insert(*ownerIdent)
-	v := NewVerifier(dir)
+	v := NewVerifier(dir, TrustPolicy{})
 	space := habitat_syntax.SpaceURI("at://did:plc:owner/space/network.habitat.space/s1")
 
-	_, err := v.signer(t.Context(), space, "did:plc:external")
+	_, _, err := v.signer(t.Context(), space, "did:plc:external")
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "lookup host")
 }
@@ -653,10 +792,10 @@ func TestVerifierSignerExternalHostNoKey(t *testing.T) {
 	dir := identity.NewMockDirectory()
 	dir.Insert(*ownerIdent)
 	dir.Insert(*hostIdent)
-	v := NewVerifier(dir)
+	v := NewVerifier(dir, TrustPolicy{})
 	space := habitat_syntax.SpaceURI("at://did:plc:owner/space/network.habitat.space/s1")
 
-	_, err := v.signer(t.Context(), space, "did:plc:external")
+	_, _, err := v.signer(t.Context(), space, "did:plc:external")
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "host signing key")
 }
@@ -779,6 +918,75 @@ func TestEngineScheduleRetryBackoff(t *testing.T) {
 	require.Equal(t, stateDesynced, r.State)
 }
 
+// TestEngineScheduleRetryThrottled verifies that a repo whose host throttled
+// the sync is rescheduled as pending, no sooner than the host asked, rather
+// than marked errored.
+func TestEngineScheduleRetryThrottled(t *testing.T) {
+	t.Parallel()
+
+	space := habitat_syntax.SpaceURI("at://did:plc:owner/space/network.habitat.space/s1")
+	e, _, db := newTestEngine(t, "http://unused.example")
+	require.NoError(t, e.Track(t.Context(), space, "did:plc:alice"))
+
+	throttled := &hostlimit.ThrottledError{Host: "pds.example", RetryAfter: time.Hour}
+	require.NoError(t, e.scheduleRetry(t.Context(), space, "did:plc:alice", stateError,
+		fmt.Errorf("list repo ops: %w", throttled)))
+
+	var r repo
+	require.NoError(t, db.First(&r, "did = ?", "did:plc:alice").Error)
+	require.Equal(t, statePending, r.State)
+	require.GreaterOrEqual(t, r.RetryAfter, time.Now().Add(59*time.Minute).Unix())
+
+	// A throttled recovery stays desynced, but still waits for the host.
+	require.NoError(t, e.scheduleRetry(t.Context(), space, "did:plc:alice", stateDesynced,
+		fmt.Errorf("get repo: %w", throttled)))
+	require.NoError(t, db.First(&r, "did = ?", "did:plc:alice").Error)
+	require.Equal(t, stateDesynced, r.State)
+	require.GreaterOrEqual(t, r.RetryAfter, time.Now().Add(59*time.Minute).Unix())
+}
+
+// TestEngineProgressCountsReposByState verifies that Progress counts the
+// repos in the given spaces by state, which have never synced or keep failing
+// to, and the records held for them.
+func TestEngineProgressCountsReposByState(t *testing.T) {
+	t.Parallel()
+
+	s1 := habitat_syntax.SpaceURI("at://did:plc:owner/space/network.habitat.space/s1")
+	s2 := habitat_syntax.SpaceURI("at://did:plc:owner/space/network.habitat.space/s2")
+	e, _, db := newTestEngine(t, "http://unused.example")
+	require.NoError(t, db.Create([]repo{
+		{Space: s1, DID: "did:plc:alice", Rev: "3kaaaaaaaaaaa", State: stateActive},
+		{Space: s1, DID: "did:plc:bob", State: statePending},
+		{Space: s1, DID: "did:plc:carol", Rev: "3kaaaaaaaaaaa", State: stateError},
+		{Space: s2, DID: "did:plc:dave", State: statePending},
+		{
+			Space: s1, DID: "did:plc:erin", State: stateError,
+			RetryCount: FailedAfterRetries, ErrorMsg: "host unreachable",
+		},
+	}).Error)
+	require.NoError(t, db.Create([]repoRecord{
+		{Space: s1, DID: "did:plc:alice", Collection: "network.habitat.test", Rkey: "a"},
+		{Space: s1, DID: "did:plc:alice", Collection: "network.habitat.test", Rkey: "b"},
+		{Space: s2, DID: "did:plc:dave", Collection: "network.habitat.test", Rkey: "c"},
+	}).Error)
+
+	p, err := e.Progress(t.Context(), []habitat_syntax.SpaceURI{s1})
+	require.NoError(t, err)
+	require.Equal(t, Progress{
+		Repos: 4, Active: 1, Pending: 1, Errored: 2, Unsynced: 2, Failed: 1, Records: 2,
+	}, p)
+
+	failed, err := e.FailedRepos(t.Context(), []habitat_syntax.SpaceURI{s1, s2})
+	require.NoError(t, err)
+	require.Len(t, failed, 1)
+	require.Equal(t, syntax.DID("did:plc:erin"), failed[0].DID)
+	require.Equal(t, "host unreachable", failed[0].ErrorMsg)
+
+	p, err = e.Progress(t.Context(), nil)
+	require.NoError(t, err)
+	require.Equal(t, Progress{}, p)
+}
+
 // TestEngineBackoff verifies the exponential backoff with cap.
 func TestEngineBackoff(t *testing.T) {
 	t.Parallel()
@@ -804,7 +1012,7 @@ func TestEngineRunJobDispatchesToRecover(t *testing.T) {
 	m, err := NewMetrics(nil, nil)
 	require.NoError(t, err)
 	failClient := &failClients{}
-	e, err := New(db, failClient, &memEmitter{}, NewVerifier(nil), 1, m)
+	e, err := New(db, failClient, &memEmitter{}, NewVerifier(nil, TrustPolicy{}), 1, m)
 	require.NoError(t, err)
 
 	space := habitat_syntax.SpaceURI("at://did:plc:owner/space/network.habitat.space/s1")
@@ -887,7 +1095,7 @@ func TestEngineRecoverRepoVerifyError(t *testing.T) {
 	// Use a verifier with a mock dir that returns LookupDID errors, causing
 	// a transient error (not ErrInvalidCommit) during verification.
 	mockD := identity.NewMockDirectory()
-	v := NewVerifier(mockD)
+	v := NewVerifier(mockD, TrustPolicy{})
 	e, err := New(db, fakeClients{base: base}, &memEmitter{}, v, 1, m)
 	require.NoError(t, err)
 
@@ -923,7 +1131,7 @@ func TestEngineRecoverRepoSuccess(t *testing.T) {
 	m, err := NewMetrics(nil, nil)
 	require.NoError(t, err)
 	// nil dir makes Verify use hash-only mode: no signer resolution needed.
-	v := NewVerifier(nil)
+	v := NewVerifier(nil, TrustPolicy{})
 	e, err := New(db, fakeClients{base: base}, &memEmitter{}, v, 1, m)
 	require.NoError(t, err)
 
@@ -937,6 +1145,50 @@ func TestEngineRecoverRepoSuccess(t *testing.T) {
 	var r repo
 	require.NoError(t, db.First(&r, "did = ?", "did:plc:alice").Error)
 	require.Equal(t, stateActive, r.State)
+	require.Equal(t, OutcomeHashOnly, r.Verification)
+}
+
+// TestEngineRecoverRepoStrictRefusesHashOnly covers a trust policy refusal:
+// strict mode without a directory leaves the repo desynced and records why.
+func TestEngineRecoverRepoStrictRefusesHashOnly(t *testing.T) {
+	t.Parallel()
+
+	carBytes := buildMinimalCAR(t)
+
+	space := habitat_syntax.SpaceURI("at://did:plc:owner/space/network.habitat.space/s1")
+	repoDID := syntax.DID("did:plc:alice")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		_, _ = w.Write(carBytes)
+	}))
+	t.Cleanup(srv.Close)
+	base, _ := url.Parse(srv.URL)
+
+	db := db_testutil.NewDB(t)
+	m, err := NewMetrics(nil, nil)
+	require.NoError(t, err)
+	emitter := &memEmitter{}
+	v := NewVerifier(nil, TrustPolicy{Strict: true})
+	e, err := New(db, fakeClients{base: base}, emitter, v, 1, m)
+	require.NoError(t, err)
+
+	require.NoError(t, e.Track(t.Context(), space, repoDID))
+	require.NoError(t, db.Model(&repo{}).Where("did = ?", "did:plc:alice").
+		Update("state", stateDesynced).Error)
+
+	require.NoError(t, e.recoverRepo(t.Context(), space, repoDID))
+
+	var r repo
+	require.NoError(t, db.First(&r, "did = ?", "did:plc:alice").Error)
+	require.Equal(t, stateDesynced, r.State)
+	require.Equal(t, OutcomeUntrusted, r.Verification)
+	require.Contains(t, r.ErrorMsg, ErrUntrusted.Error())
+	require.Empty(t, emitter.emitted)
+
+	statuses, _, err := e.Repos(t.Context(), RepoFilter{Verification: string(OutcomeUntrusted)})
+	require.NoError(t, err)
+	require.Len(t, statuses, 1)
+	require.Equal(t, string(OutcomeUntrusted), statuses[0].Verification)
 }
 
 // TestEngineNotifyWriteBehindHashSameRev covers the case where the notified
@@ -1096,7 +1348,7 @@ func TestEngineRecoverByDiffRefetchesOnlyChangedRecords(t *testing.T) {
 	require.NoError(t, e.Track(t.Context(), space, repoDID))
 	// k1 is current, k2 is stale, k3 is unknown to sap.
 	for rkey, c := range map[syntax.RecordKey]string{"k1": "bafyaaa", "k2": "bafyOLD"} {
-		require.NoError(t, indexRecord(t.Context(), db, space, repoDID, coll, rkey, c))
+		require.NoError(t, indexRecord(t.Context(), db, space, repoDID, coll, rkey, c, nil))
 	}
 
 	require.NoError(t, e.recoverRepo(t.Context(), space, repoDID))
@@ -1131,7 +1383,7 @@ func TestEngineRecoverByDiffRefetchesOnlyChangedRecords(t *testing.T) {
 // longer does, rather than silently dropping it from the index. A record can
 // reach this path deleted rather than through the incremental sync path
 // (e.g. host notifications were lost and sap only reconciles via recovery),
-// so recovery must emit the same null tombstone sync.go does or the delete
+// so recovery must emit the same delete event sync.go does or the delete
 // never reaches consumers.
 func TestEngineRecoverByDiffEmitsDeleteTombstone(t *testing.T) {
 	t.Parallel()
@@ -1178,17 +1430,17 @@ func TestEngineRecoverByDiffEmitsDeleteTombstone(t *testing.T) {
 	e, emitter, db := newTestEngine(t, srv.URL)
 	require.NoError(t, e.Track(t.Context(), space, repoDID))
 	for rkey, c := range map[syntax.RecordKey]string{"k1": "bafyaaa", "k2": "bafybbb"} {
-		require.NoError(t, indexRecord(t.Context(), db, space, repoDID, coll, rkey, c))
+		require.NoError(t, indexRecord(t.Context(), db, space, repoDID, coll, rkey, c, nil))
 	}
 
 	require.NoError(t, e.recoverRepo(t.Context(), space, repoDID))
 
 	require.Len(t, emitter.emitted, 1, "only the deleted record is emitted")
-	require.Equal(
-		t,
-		[]byte("null"),
-		emitter.values["at://did:plc:owner/space/network.habitat.space/s1/did:plc:alice/network.habitat.test/k2"],
-	)
+	deleted := emitter.events["at://did:plc:owner/space/network.habitat.space/s1/did:plc:alice/network.habitat.test/k2"]
+	require.Equal(t, ActionDelete, deleted.Action)
+	require.Equal(t, "bafybbb", deleted.PrevCid)
+	require.Equal(t, syntax.TID(rev), deleted.Rev)
+	require.Empty(t, emitter.resyncs, "narrow recovery is not a full resync")
 
 	index, err := recordIndex(t.Context(), db, space, repoDID)
 	require.NoError(t, err)
@@ -1223,18 +1475,21 @@ func TestEngineRecoverFromCAREmitsDeleteTombstone(t *testing.T) {
 	// sap's index holds rec1 (still present in the CAR) and rec2 (deleted
 	// since sap last synced).
 	require.NoError(t, indexRecord(t.Context(), db, space, repoDID,
-		"network.habitat.test", "rec1", "bafyaaa"))
+		"network.habitat.test", "rec1", "bafyaaa", nil))
 	require.NoError(t, indexRecord(t.Context(), db, space, repoDID,
-		"network.habitat.test", "rec2", "bafybbb"))
+		"network.habitat.test", "rec2", "bafybbb", nil))
 
 	require.NoError(t, e.recoverRepo(t.Context(), space, repoDID))
 
 	require.Len(t, emitter.emitted, 2, "the recovered record and the deleted one are both emitted")
-	require.Equal(
-		t,
-		[]byte("null"),
-		emitter.values["at://did:plc:owner/space/network.habitat.space/s1/did:plc:alice/network.habitat.test/rec2"],
-	)
+	recovered := emitter.events["at://did:plc:owner/space/network.habitat.space/s1/did:plc:alice/network.habitat.test/rec1"]
+	require.Equal(t, ActionUpdate, recovered.Action, "rec1 was already held")
+	deleted := emitter.events["at://did:plc:owner/space/network.habitat.space/s1/did:plc:alice/network.habitat.test/rec2"]
+	require.Equal(t, ActionDelete, deleted.Action)
+	require.Equal(t, "bafybbb", deleted.PrevCid)
+	require.Len(t, emitter.resyncs, 1, "a CAR rebuild ends with a resync marker")
+	require.Equal(t, repoDID, emitter.resyncs[0].Repo)
+	require.Equal(t, recovered.Rev, emitter.resyncs[0].Rev)
 
 	index, err := recordIndex(t.Context(), db, space, repoDID)
 	require.NoError(t, err)
@@ -1270,8 +1525,8 @@ func TestEngineRecoverRepoUsesHabitatNsid(t *testing.T) {
 }
 
 // TestEngineSyncRepoEmitsDeleteTombstone pins that a delete op (cid empty,
-// value null) is emitted to the outbox as a JSON null so consumers can remove
-// their copy, rather than skipped. The op also carries prev, since the lexicon
+// value null) is emitted to the outbox as a delete event so consumers can
+// remove their copy, rather than skipped. The op also carries prev, since the lexicon
 // requires it (null only for a create); folding prev out and never folding a
 // cid in is what leaves the repo's hash consistent with the host's commit.
 func TestEngineSyncRepoEmitsDeleteTombstone(t *testing.T) {
@@ -1316,11 +1571,87 @@ func TestEngineSyncRepoEmitsDeleteTombstone(t *testing.T) {
 	require.NoError(t, e.syncRepo(t.Context(), space, repoDID))
 
 	require.Len(t, emitter.emitted, 2)
-	require.Equal(
-		t,
-		[]byte("null"),
-		emitter.values["at://did:plc:owner/space/network.habitat.space/s1/did:plc:alice/network.habitat.test/k1"],
-	)
+	deleted := emitter.events["at://did:plc:owner/space/network.habitat.space/s1/did:plc:alice/network.habitat.test/k1"]
+	require.Equal(t, ActionDelete, deleted.Action)
+	require.Equal(t, "bafyaaa", deleted.PrevCid)
+	require.Nil(t, deleted.Value)
+}
+
+// TestEngineSyncRepoEventsCarryActionsAndPrevValues pins the event shape of
+// incremental sync: a create and an update are told apart by prev, an engine
+// built WithPrevValues carries the replaced value, and an op whose value was
+// superseded later in the log is folded into the hash but not emitted.
+func TestEngineSyncRepoEventsCarryActionsAndPrevValues(t *testing.T) {
+	t.Parallel()
+
+	space := habitat_syntax.SpaceURI("at://did:plc:owner/space/network.habitat.space/s1")
+	repoDID := syntax.DID("did:plc:alice")
+	clock := syntax.NewTIDClock(0)
+	rev1, rev2, rev3, rev4 := clock.Next().String(), clock.Next().String(),
+		clock.Next().String(), clock.Next().String()
+
+	ops := []habitat.NetworkHabitatSpaceListRepoOpsOpEntry{
+		{Rev: rev1, Collection: "network.habitat.test", Rkey: "k1", Cid: "bafyaaa",
+			Value: map[string]any{"n": 1}},
+		{Rev: rev2, Collection: "network.habitat.test", Rkey: "k1", Cid: "bafybbb",
+			Prev: "bafyaaa", Value: map[string]any{"n": 2}},
+		// Superseded by rev4: no value inlined.
+		{Rev: rev3, Collection: "network.habitat.test", Rkey: "k2", Cid: "bafyccc"},
+		{Rev: rev4, Collection: "network.habitat.test", Rkey: "k2", Cid: "bafyddd",
+			Prev: "bafyccc", Value: map[string]any{"n": 4}},
+	}
+	var lt spacecommit.LtHash
+	lt.Add(spacecommit.RecordElement("network.habitat.test", "k1", "bafybbb"))
+	lt.Add(spacecommit.RecordElement("network.habitat.test", "k2", "bafyddd"))
+	commit := habitat.NetworkHabitatSpaceDefsSignedCommit{
+		Ver:  int64(spacecommit.Version),
+		Rev:  rev4,
+		Hash: atdata.Bytes(lt.Sum()),
+		Ikm:  atdata.Bytes{},
+		Mac:  atdata.Bytes{},
+		Sig:  atdata.Bytes{},
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		out := habitat.NetworkHabitatSpaceListRepoOpsOutput{Commit: &commit}
+		if r.URL.Query().Get("since") == "" {
+			out.Ops = ops
+			out.Cursor = rev4
+		}
+		_ = json.NewEncoder(w).Encode(out)
+	}))
+	t.Cleanup(srv.Close)
+
+	e, emitter, db := newTestEngine(t, srv.URL, WithPrevValues(true))
+	require.NoError(t, e.Track(t.Context(), space, repoDID))
+	require.NoError(t, db.Model(&repo{}).
+		Where("space = ? AND did = ?", space, repoDID).
+		Update("state", stateSyncing).Error)
+
+	require.NoError(t, e.syncRepo(t.Context(), space, repoDID))
+
+	k1 := habitat_syntax.SpaceRecordURI(
+		"at://did:plc:owner/space/network.habitat.space/s1/did:plc:alice/network.habitat.test/k1")
+	k2 := habitat_syntax.SpaceRecordURI(
+		"at://did:plc:owner/space/network.habitat.space/s1/did:plc:alice/network.habitat.test/k2")
+	require.Equal(t, []habitat_syntax.SpaceRecordURI{k1, k1, k2}, emitter.emitted)
+
+	update := emitter.events[k1]
+	require.Equal(t, ActionUpdate, update.Action)
+	require.Equal(t, syntax.TID(rev2), update.Rev)
+	require.Equal(t, "bafybbb", update.Cid)
+	require.Equal(t, "bafyaaa", update.PrevCid)
+	require.JSONEq(t, `{"n":2}`, string(update.Value))
+	require.JSONEq(t, `{"n":1}`, string(update.PrevValue))
+
+	superseding := emitter.events[k2]
+	require.Equal(t, ActionUpdate, superseding.Action)
+	require.Equal(t, "bafyccc", superseding.PrevCid)
+	require.Nil(t, superseding.PrevValue, "the superseded version's value was never seen")
+
+	var r repo
+	require.NoError(t, db.First(&r, "space = ? AND did = ?", space, repoDID).Error)
+	require.Equal(t, stateActive, r.State)
 }
 
 // TestEngineCheckRequeuesDriftedRepo pins that a backfill crawl's rev/hash
@@ -1395,3 +1726,513 @@ func TestEngineCheckLeavesCurrentReposActive(t *testing.T) {
 	require.NoError(t, db.First(&r, "space = ? AND did = ?", space, repoDID).Error)
 	require.Equal(t, stateActive, r.State)
 }
+
+// TestEngineReposFiltersAndPages verifies that Repos filters by state and
+// space and pages through the rest with its cursor.
+func TestEngineReposFiltersAndPages(t *testing.T) {
+	t.Parallel()
+
+	s1 := habitat_syntax.SpaceURI("at://did:plc:owner/space/network.habitat.space/s1")
+	s2 := habitat_syntax.SpaceURI("at://did:plc:owner/space/network.habitat.space/s2")
+	e, _, db := newTestEngine(t, "http://unused.example")
+	retryAfter := time.Now().Add(time.Hour).Unix()
+	for _, r := range []repo{
+		{Space: s1, DID: "did:plc:alice", State: stateActive},
+		{Space: s1, DID: "did:plc:bob", State: stateError, ErrorMsg: "boom",
+			RetryCount: 2, RetryAfter: retryAfter},
+		{Space: s2, DID: "did:plc:carol", State: stateActive},
+	} {
+		require.NoError(t, db.Create(&r).Error)
+	}
+	require.NoError(t, e.PauseSpace(t.Context(), s2))
+
+	errored, cursor, err := e.Repos(t.Context(), RepoFilter{State: string(stateError)})
+	require.NoError(t, err)
+	require.Empty(t, cursor)
+	require.Len(t, errored, 1)
+	require.Equal(t, syntax.DID("did:plc:bob"), errored[0].DID)
+	require.Equal(t, "boom", errored[0].ErrorMsg)
+	require.Equal(t, 2, errored[0].RetryCount)
+	require.Equal(t, retryAfter, errored[0].RetryAfter.Unix())
+
+	inSpace, _, err := e.Repos(t.Context(), RepoFilter{Space: s2})
+	require.NoError(t, err)
+	require.Len(t, inSpace, 1)
+	require.True(t, inSpace[0].Paused)
+
+	var seen []syntax.DID
+	filter := RepoFilter{Limit: 2}
+	for {
+		page, next, err := e.Repos(t.Context(), filter)
+		require.NoError(t, err)
+		for _, r := range page {
+			seen = append(seen, r.DID)
+		}
+		if next == "" {
+			break
+		}
+		filter.Cursor = next
+	}
+	require.Equal(t, []syntax.DID{"did:plc:alice", "did:plc:bob", "did:plc:carol"}, seen)
+}
+
+// TestEngineRequeueForcesRepos verifies that Requeue clears backoff and queues
+// settled repos for an incremental or full pass, and marks a repo mid-sync
+// dirty instead of yanking it from its worker.
+func TestEngineRequeueForcesRepos(t *testing.T) {
+	t.Parallel()
+
+	space := habitat_syntax.SpaceURI("at://did:plc:owner/space/network.habitat.space/s1")
+	e, _, db := newTestEngine(t, "http://unused.example")
+	for _, r := range []repo{
+		{Space: space, DID: "did:plc:alice", State: stateActive},
+		{Space: space, DID: "did:plc:bob", State: stateError, RetryCount: 3,
+			RetryAfter: time.Now().Add(time.Hour).Unix()},
+		{Space: space, DID: "did:plc:carol", State: stateSyncing},
+	} {
+		require.NoError(t, db.Create(&r).Error)
+	}
+
+	n, err := e.Requeue(t.Context(), space, "did:plc:alice", true)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), n)
+	var alice repo
+	require.NoError(t, db.First(&alice, "did = ?", "did:plc:alice").Error)
+	require.Equal(t, stateDesynced, alice.State)
+
+	n, err = e.Requeue(t.Context(), space, "", false)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), n)
+	var bob, carol repo
+	require.NoError(t, db.First(&bob, "did = ?", "did:plc:bob").Error)
+	require.Equal(t, statePending, bob.State)
+	require.Zero(t, bob.RetryCount)
+	require.Zero(t, bob.RetryAfter)
+	require.NoError(t, db.First(&carol, "did = ?", "did:plc:carol").Error)
+	require.Equal(t, stateSyncing, carol.State)
+	require.True(t, carol.Dirty)
+	require.False(t, carol.FullPending)
+
+	// A full rebuild requested mid-sync follows the pass instead of being
+	// downgraded to an incremental one.
+	_, err = e.Requeue(t.Context(), space, "did:plc:carol", true)
+	require.NoError(t, err)
+	var lt spacecommit.LtHash
+	require.NoError(t, e.settle(t.Context(), db, space, "did:plc:carol", "3kzl6abcde02k", lt.State()))
+	require.NoError(t, db.First(&carol, "did = ?", "did:plc:carol").Error)
+	require.Equal(t, stateDesynced, carol.State)
+	require.False(t, carol.Dirty)
+	require.False(t, carol.FullPending)
+}
+
+// TestEnginePausedSpaceIsNotClaimed verifies that dispatch skips a paused
+// space's repos until it is resumed.
+func TestEnginePausedSpaceIsNotClaimed(t *testing.T) {
+	t.Parallel()
+
+	space := habitat_syntax.SpaceURI("at://did:plc:owner/space/network.habitat.space/s1")
+	e, _, db := newTestEngine(t, "http://unused.example")
+	require.NoError(
+		t,
+		db.Create(&repo{Space: space, DID: "did:plc:alice", State: statePending}).Error,
+	)
+	require.NoError(t, e.PauseSpace(t.Context(), space))
+	require.NoError(t, e.PauseSpace(t.Context(), space)) // idempotent
+
+	e.dispatch(t.Context())
+	var r repo
+	require.NoError(t, db.First(&r, "did = ?", "did:plc:alice").Error)
+	require.Equal(t, statePending, r.State)
+
+	paused, err := e.PausedSpaces(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, []habitat_syntax.SpaceURI{space}, paused)
+
+	require.NoError(t, e.ResumeSpace(t.Context(), space))
+	e.dispatch(t.Context())
+	require.NoError(t, db.First(&r, "did = ?", "did:plc:alice").Error)
+	require.Equal(t, stateSyncing, r.State)
+}
+
+// TestEngineFilterEmitsIncludedCollectionsButVerifiesAll pins that records in
+// filtered-out collections are kept out of the outbox while still being
+// indexed and folded into the repo's LtHash, so verification passes.
+func TestEngineFilterEmitsIncludedCollectionsButVerifiesAll(t *testing.T) {
+	t.Parallel()
+
+	space := habitat_syntax.SpaceURI("at://did:plc:owner/space/network.habitat.space/s1")
+	repoDID := syntax.DID("did:plc:alice")
+	clock := syntax.NewTIDClock(0)
+	rev1, rev2 := clock.Next().String(), clock.Next().String()
+
+	ops := []habitat.NetworkHabitatSpaceListRepoOpsOpEntry{
+		{Rev: rev1, Collection: "network.habitat.test", Rkey: "k1", Cid: "bafyaaa",
+			Value: map[string]any{"n": 1}},
+		{Rev: rev2, Collection: "network.habitat.other", Rkey: "k2", Cid: "bafybbb",
+			Value: map[string]any{"n": 2}},
+	}
+	var lt spacecommit.LtHash
+	lt.Add(spacecommit.RecordElement("network.habitat.test", "k1", "bafyaaa"))
+	lt.Add(spacecommit.RecordElement("network.habitat.other", "k2", "bafybbb"))
+	commit := habitat.NetworkHabitatSpaceDefsSignedCommit{
+		Ver:  int64(spacecommit.Version),
+		Rev:  rev2,
+		Hash: atdata.Bytes(lt.Sum()),
+		Ikm:  atdata.Bytes{},
+		Mac:  atdata.Bytes{},
+		Sig:  atdata.Bytes{},
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		out := habitat.NetworkHabitatSpaceListRepoOpsOutput{Commit: &commit}
+		if r.URL.Query().Get("since") == "" {
+			out.Ops = ops
+			out.Cursor = rev2
+		}
+		_ = json.NewEncoder(w).Encode(out)
+	}))
+	t.Cleanup(srv.Close)
+
+	e, emitter, db := newTestEngine(t, srv.URL,
+		WithFilter(Filter{Collections: []syntax.NSID{"network.habitat.test"}}))
+	require.NoError(t, e.Track(t.Context(), space, repoDID))
+	require.NoError(t, db.Model(&repo{}).
+		Where("space = ? AND did = ?", space, repoDID).
+		Update("state", stateSyncing).Error)
+
+	require.NoError(t, e.syncRepo(t.Context(), space, repoDID))
+
+	require.Equal(t, []habitat_syntax.SpaceRecordURI{
+		"at://did:plc:owner/space/network.habitat.space/s1/did:plc:alice/network.habitat.test/k1",
+	}, emitter.emitted)
+	var r repo
+	require.NoError(t, db.First(&r, "space = ? AND did = ?", space, repoDID).Error)
+	require.Equal(t, stateActive, r.State)
+	var indexed int64
+	require.NoError(t, db.Model(&repoRecord{}).Count(&indexed).Error)
+	require.Equal(t, int64(2), indexed)
+}
+
+// TestEngineFilterIgnoresExcludedSpaces verifies that repos in spaces the
+// filter excludes are never tracked, whether discovered or notified.
+func TestEngineFilterIgnoresExcludedSpaces(t *testing.T) {
+	t.Parallel()
+
+	included := habitat_syntax.SpaceURI("at://did:plc:owner/space/network.habitat.group/g1")
+	excluded := habitat_syntax.SpaceURI("at://did:plc:owner/space/network.habitat.space/s1")
+	e, _, db := newTestEngine(t, "http://unused.example",
+		WithFilter(Filter{SpaceTypes: []syntax.NSID{"network.habitat.group"}}))
+
+	require.NoError(t, e.Track(t.Context(), included, "did:plc:alice"))
+	require.NoError(t, e.Track(t.Context(), excluded, "did:plc:alice"))
+	require.NoError(t, e.NotifyWrite(t.Context(), excluded, "did:plc:bob", "", nil))
+
+	spaces, err := e.Spaces(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, []habitat_syntax.SpaceURI{included}, spaces)
+	var count int64
+	require.NoError(t, db.Model(&repo{}).Count(&count).Error)
+	require.Equal(t, int64(1), count)
+}
+
+// TestEngineBackfillIncludedRequeuesNewlyIncludedCollections verifies that
+// broadening the collection filter across a restart forgets the newly
+// included records and desyncs their repos, so recovery fetches and emits
+// them, while repos holding nothing newly included are left alone.
+func TestEngineBackfillIncludedRequeuesNewlyIncludedCollections(t *testing.T) {
+	t.Parallel()
+
+	space := habitat_syntax.SpaceURI("at://did:plc:owner/space/network.habitat.space/s1")
+	narrow := Filter{ExcludeCollections: []syntax.NSID{"network.habitat.other"}}
+	e, _, db := newTestEngine(t, "http://unused.example", WithFilter(narrow))
+	for _, r := range []repo{
+		{Space: space, DID: "did:plc:alice", State: stateActive},
+		{Space: space, DID: "did:plc:bob", State: stateActive},
+	} {
+		require.NoError(t, db.Create(&r).Error)
+	}
+	require.NoError(t, indexRecord(t.Context(), db, space, "did:plc:alice",
+		"network.habitat.test", "k1", "bafyaaa", nil))
+	require.NoError(t, indexRecord(t.Context(), db, space, "did:plc:alice",
+		"network.habitat.other", "k2", "bafybbb", nil))
+	require.NoError(t, indexRecord(t.Context(), db, space, "did:plc:bob",
+		"network.habitat.test", "k3", "bafyccc", nil))
+
+	// The first run under the narrow filter has nothing newly included.
+	require.NoError(t, e.backfillIncluded(t.Context()))
+	var alice repo
+	require.NoError(t, db.First(&alice, "did = ?", "did:plc:alice").Error)
+	require.Equal(t, stateActive, alice.State)
+
+	// Restart with no filter: network.habitat.other is newly included.
+	m, err := NewMetrics(nil, nil)
+	require.NoError(t, err)
+	broad, err := New(db, failClients{}, &memEmitter{}, NewVerifier(nil, TrustPolicy{}), 1, m)
+	require.NoError(t, err)
+	require.NoError(t, broad.backfillIncluded(t.Context()))
+
+	var bob repo
+	require.NoError(t, db.First(&alice, "did = ?", "did:plc:alice").Error)
+	require.Equal(t, stateDesynced, alice.State)
+	require.NoError(t, db.First(&bob, "did = ?", "did:plc:bob").Error)
+	require.Equal(t, stateActive, bob.State)
+	index, err := recordIndex(t.Context(), db, space, "did:plc:alice")
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"network.habitat.test/k1": "bafyaaa"}, index)
+}
+
+func TestEngineReplicaReadsRecordsAndHeads(t *testing.T) {
+	t.Parallel()
+
+	space := habitat_syntax.SpaceURI("at://did:plc:owner/space/network.habitat.space/s1")
+	repoDID := syntax.DID("did:plc:alice")
+	e, _, db := newTestEngine(t, "http://unused.example", WithValues(true))
+	for _, rec := range []struct {
+		coll syntax.NSID
+		rkey syntax.RecordKey
+	}{
+		{"network.habitat.a", "k1"},
+		{"network.habitat.a", "k2"},
+		{"network.habitat.b", "k1"},
+	} {
+		require.NoError(t, indexRecord(t.Context(), db, space, repoDID, rec.coll, rec.rkey,
+			"bafy"+rec.rkey.String(), []byte(`{"rkey":"`+rec.rkey.String()+`"}`)))
+	}
+
+	rec, err := e.Record(t.Context(), space, repoDID, "network.habitat.a", "k2")
+	require.NoError(t, err)
+	require.Equal(t, "bafyk2", rec.Cid)
+	require.JSONEq(t, `{"rkey":"k2"}`, string(rec.Value))
+	_, err = e.Record(t.Context(), space, repoDID, "network.habitat.a", "k9")
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	page, cursor, err := e.Records(t.Context(), space, repoDID, RecordQuery{Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, page, 2)
+	require.Equal(t, syntax.RecordKey("k2"), page[1].Rkey)
+	page, cursor, err = e.Records(t.Context(), space, repoDID,
+		RecordQuery{Limit: 2, Cursor: cursor})
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	require.Equal(t, syntax.NSID("network.habitat.b"), page[0].Collection)
+	require.Empty(t, cursor)
+
+	page, _, err = e.Records(t.Context(), space, repoDID,
+		RecordQuery{Collection: "network.habitat.a", Reverse: true})
+	require.NoError(t, err)
+	require.Len(t, page, 2)
+	require.Equal(t, syntax.RecordKey("k2"), page[0].Rkey)
+
+	// Only repos that have synced at least once are listed.
+	var lt spacecommit.LtHash
+	lt.Add(spacecommit.RecordElement("network.habitat.a", "k1", "bafyk1"))
+	for _, r := range []repo{
+		{Space: space, DID: repoDID, Rev: "3kaaaaaaaaaaa", State: stateActive, Hash: lt.State()},
+		{Space: space, DID: "did:plc:bob", State: statePending},
+	} {
+		require.NoError(t, db.Create(&r).Error)
+	}
+	heads, next, err := e.RepoHeads(t.Context(), space, 0, "")
+	require.NoError(t, err)
+	require.Empty(t, next)
+	require.Equal(t, []RepoHead{{DID: repoDID, Rev: "3kaaaaaaaaaaa", Hash: lt.Sum()}}, heads)
+
+	plain, _, _ := newTestEngine(t, "http://unused.example")
+	_, err = plain.Record(t.Context(), space, repoDID, "network.habitat.a", "k1")
+	require.ErrorIs(t, err, ErrNoValues)
+}
+
+// TestEngineRecoverByDiffFillsMissingValues pins that a replica fills in
+// values for records indexed before it kept them, fetching only those and
+// emitting nothing, since consumers already have them.
+func TestEngineRecoverByDiffFillsMissingValues(t *testing.T) {
+	t.Parallel()
+
+	space := habitat_syntax.SpaceURI("at://did:plc:owner/space/network.habitat.space/s1")
+	repoDID := syntax.DID("did:plc:alice")
+	coll := syntax.NSID("network.habitat.test")
+	rev := syntax.NewTIDClock(0).Next().String()
+	hostCids := map[syntax.RecordKey]string{"k1": "bafyaaa", "k2": "bafybbb"}
+	var lt spacecommit.LtHash
+	for rkey, c := range hostCids {
+		lt.Add(spacecommit.RecordElement(coll, rkey, c))
+	}
+
+	var fetched sync.Map
+	mux := http.NewServeMux()
+	mux.HandleFunc("/xrpc/network.habitat.space.getLatestCommit",
+		func(w http.ResponseWriter, _ *http.Request) {
+			_ = json.NewEncoder(w).Encode(habitat.NetworkHabitatSpaceGetLatestCommitOutput{
+				Commit: &habitat.NetworkHabitatSpaceDefsSignedCommit{
+					Ver:  int64(spacecommit.Version),
+					Rev:  rev,
+					Hash: atdata.Bytes(lt.Sum()),
+					Ikm:  atdata.Bytes{},
+					Mac:  atdata.Bytes{},
+					Sig:  atdata.Bytes{},
+				},
+			})
+		})
+	mux.HandleFunc("/xrpc/network.habitat.space.listRecords",
+		func(w http.ResponseWriter, _ *http.Request) {
+			out := habitat.NetworkHabitatSpaceListRecordsOutput{}
+			for _, rkey := range []syntax.RecordKey{"k1", "k2"} {
+				out.Records = append(out.Records, habitat.NetworkHabitatSpaceListRecordsRecord{
+					Collection: coll.String(), Rkey: rkey.String(), Cid: hostCids[rkey],
+				})
+			}
+			_ = json.NewEncoder(w).Encode(out)
+		})
+	mux.HandleFunc("/xrpc/network.habitat.space.getRecord",
+		func(w http.ResponseWriter, r *http.Request) {
+			rkey := r.URL.Query().Get("rkey")
+			fetched.Store(rkey, true)
+			_ = json.NewEncoder(w).Encode(habitat.NetworkHabitatSpaceGetRecordOutput{
+				Cid: hostCids[syntax.RecordKey(rkey)], Value: map[string]any{"k": rkey},
+			})
+		})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	e, emitter, db := newTestEngine(t, srv.URL, WithValues(true))
+	require.NoError(t, e.Track(t.Context(), space, repoDID))
+	// Both records are current; only k2 is missing its value.
+	require.NoError(t, indexRecord(t.Context(), db, space, repoDID, coll, "k1", "bafyaaa",
+		[]byte(`{"k":"k1"}`)))
+	require.NoError(t, indexRecord(t.Context(), db, space, repoDID, coll, "k2", "bafybbb", nil))
+
+	require.NoError(t, e.fillValues(t.Context()))
+	var r repo
+	require.NoError(t, db.First(&r, "did = ?", repoDID).Error)
+	require.Equal(t, stateDesynced, r.State)
+
+	require.NoError(t, e.recoverRepo(t.Context(), space, repoDID))
+
+	_, gotK1 := fetched.Load("k1")
+	_, gotK2 := fetched.Load("k2")
+	require.False(t, gotK1, "a record with its value must not be refetched")
+	require.True(t, gotK2, "a record missing its value must be fetched")
+	require.Empty(t, emitter.emitted, "filling values must not re-emit records")
+
+	values, err := recordValues(t.Context(), db, space, repoDID)
+	require.NoError(t, err)
+	require.Len(t, values, 2)
+	require.JSONEq(t, `{"k":"k2"}`, string(values["network.habitat.test/k2"]))
+}
+
+type fakeRegistrations []habitat_syntax.SpaceURI
+
+func (f fakeRegistrations) Registered(context.Context) ([]habitat_syntax.SpaceURI, error) {
+	return f, nil
+}
+
+// TestEnginePollSpaceAdaptsInterval pins the adaptive schedule: a poll that
+// finds a repo behind queues it and keeps the space at the minimum interval,
+// and each quiet poll after doubles it up to the maximum.
+func TestEnginePollSpaceAdaptsInterval(t *testing.T) {
+	t.Parallel()
+
+	space := habitat_syntax.SpaceURI("at://did:plc:owner/space/network.habitat.space/s1")
+	repoDID := syntax.DID("did:plc:alice")
+	var hostRev atomic.Value
+	hostRev.Store("3kbbbbbbbbbbb")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/xrpc/network.habitat.space.listRepos", r.URL.Path)
+		_ = json.NewEncoder(w).Encode(habitat.NetworkHabitatSpaceListReposOutput{
+			Repos: []habitat.NetworkHabitatSpaceListReposRepo{
+				{Did: repoDID.String(), Rev: hostRev.Load().(string)},
+			},
+		})
+	}))
+	t.Cleanup(srv.Close)
+
+	e, _, db := newTestEngine(t, srv.URL, WithPolling(nil, PollConfig{
+		MinInterval: time.Minute,
+		MaxInterval: 4 * time.Minute,
+	}))
+	require.NoError(t, db.Create(&repo{
+		Space: space, DID: repoDID, Rev: "3kaaaaaaaaaaa", State: stateActive,
+	}).Error)
+
+	interval := func() time.Duration {
+		var p spacePoll
+		require.NoError(t, db.First(&p, "space = ?", space).Error)
+		return p.Interval
+	}
+
+	require.NoError(t, e.pollSpace(t.Context(), space))
+	var r repo
+	require.NoError(t, db.First(&r, "did = ?", repoDID).Error)
+	require.Equal(t, statePending, r.State)
+	require.Equal(t, time.Minute, interval())
+
+	// The repo syncs to the host's rev; later polls find nothing new.
+	require.NoError(t, db.Model(&repo{}).Where("did = ?", repoDID).
+		Updates(map[string]any{"state": stateActive, "rev": "3kbbbbbbbbbbb"}).Error)
+	for _, want := range []time.Duration{2 * time.Minute, 4 * time.Minute, 4 * time.Minute} {
+		require.NoError(t, e.pollSpace(t.Context(), space))
+		require.Equal(t, want, interval())
+	}
+
+	// Activity resets the space to the minimum.
+	hostRev.Store("3kccccccccccc")
+	require.NoError(t, e.pollSpace(t.Context(), space))
+	require.Equal(t, time.Minute, interval())
+}
+
+func TestEngineDueSpacesSkipsRegisteredPausedAndScheduled(t *testing.T) {
+	t.Parallel()
+
+	spaces := []habitat_syntax.SpaceURI{
+		"at://did:plc:owner/space/network.habitat.space/due",
+		"at://did:plc:owner/space/network.habitat.space/registered",
+		"at://did:plc:owner/space/network.habitat.space/paused",
+		"at://did:plc:owner/space/network.habitat.space/scheduled",
+	}
+	e, _, db := newTestEngine(t, "http://unused.example",
+		WithPolling(fakeRegistrations{spaces[1]}, PollConfig{}))
+	for _, space := range spaces {
+		require.NoError(t, db.Create(&repo{
+			Space: space, DID: "did:plc:alice", State: stateActive,
+		}).Error)
+	}
+	require.NoError(t, e.PauseSpace(t.Context(), spaces[2]))
+	require.NoError(t, db.Create(&spacePoll{
+		Space: spaces[3], Interval: time.Minute, NextPollAt: time.Now().Add(time.Minute),
+	}).Error)
+
+	due, err := e.dueSpaces(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, []habitat_syntax.SpaceURI{spaces[0]}, due)
+}
+
+func TestPollerHostConcurrencyLimit(t *testing.T) {
+	t.Parallel()
+
+	e, _, _ := newTestEngine(t, "http://unused.example",
+		WithPolling(nil, PollConfig{HostConcurrency: 1}))
+	release, err := e.poll.acquireHost(t.Context(), "https://host-a.example")
+	require.NoError(t, err)
+
+	// Another host has its own slots.
+	releaseB, err := e.poll.acquireHost(t.Context(), "https://host-b.example")
+	require.NoError(t, err)
+	releaseB()
+
+	ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+	defer cancel()
+	_, err = e.poll.acquireHost(ctx, "https://host-a.example")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	release()
+	release, err = e.poll.acquireHost(t.Context(), "https://host-a.example")
+	require.NoError(t, err)
+	release()
+}
+
+func TestWithPollingNegativeIntervalDisables(t *testing.T) {
+	t.Parallel()
+
+	e, _, _ := newTestEngine(t, "http://unused.example",
+		WithPolling(nil, PollConfig{MinInterval: -1}))
+	require.Nil(t, e.poll)
+}
@@ -23,6 +23,7 @@ type metrics struct {
 	jobDuration      metric.Float64Histogram
 	dispatchDuration metric.Float64Histogram
 	verifications    metric.Int64Counter
+	polls            metric.Int64Counter
 }
 
 // NewMetrics builds the engine's instruments. A nil meter or tracer falls back
@@ -75,6 +76,14 @@ func NewMetrics(meter metric.Meter, tracer trace.Tracer) (*metrics, error) {
 	if err != nil {
 		return nil, err
 	}
+	polls, err := meter.Int64Counter(
+		"sap.syncer.polls",
+		metric.WithUnit("item"),
+		metric.WithDescription("number of polls of unregistered spaces, by result"),
+	)
+	if err != nil {
+		return nil, err
+	}
 
 	return &metrics{
 		tracer:           tracer,
@@ -83,6 +92,7 @@ func NewMetrics(meter metric.Meter, tracer trace.Tracer) (*metrics, error) {
 		jobDuration:      jobDuration,
 		dispatchDuration: dispatchDuration,
 		verifications:    verifications,
+		polls:            polls,
 	}, nil
 }
 
@@ -110,3 +120,9 @@ func (m *metrics) verified(ctx context.Context, result string) {
 		attribute.NewSet(attribute.String("result", result)),
 	))
 }
+
+func (m *metrics) polled(ctx context.Context, result string) {
+	m.polls.Add(ctx, 1, metric.WithAttributeSet(
+		attribute.NewSet(attribute.String("result", result)),
+	))
+}
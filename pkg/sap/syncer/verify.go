@@ -3,8 +3,10 @@ package syncer
 import (
 	"context"
 	"crypto/hmac"
+	"errors"
 	"fmt"
 	"net/url"
+	"slices"
 	"strings"
 
 	"github.com/bluesky-social/indigo/atproto/atcrypto"
@@ -15,25 +17,73 @@ import (
 	habitat_syntax "github.com/habitat-network/habitat/internal/syntax"
 )
 
+// ErrUntrusted is returned (wrapped) by Verify when a commit is valid as far
+// as it could be checked but the trust policy does not accept it.
+var ErrUntrusted = errors.New("commit not trusted by policy")
+
+// Outcome is how a repo's head commit was verified, recorded on the repo and
+// counted in the verifications metric.
+type Outcome string
+
+const (
+	// OutcomeAuthorSigned: the commit's signature checked out against the
+	// author's own key.
+	OutcomeAuthorSigned Outcome = "author_signed"
+	// OutcomeHostSigned: the signature checked out against the space host's
+	// key.
+	OutcomeHostSigned Outcome = "host_signed"
+	// OutcomeHashOnly: the hash matched but, with no directory to resolve
+	// keys, the signature was not checked.
+	OutcomeHashOnly Outcome = "hash_only"
+	// OutcomeUnsigned: the sync reached no signed commit, so nothing was
+	// verified.
+	OutcomeUnsigned Outcome = "unsigned"
+	// OutcomeInvalid: the commit failed validation.
+	OutcomeInvalid Outcome = "invalid"
+	// OutcomeUntrusted: the trust policy refused the commit.
+	OutcomeUntrusted Outcome = "untrusted"
+)
+
+// TrustPolicy decides which verified commits sap accepts. The zero policy
+// accepts everything the Verifier can validate, including hash-only and
+// unsigned syncs.
+type TrustPolicy struct {
+	// RequireSignatures refuses a sync that reaches no signed commit, or whose
+	// commit carries no signature or, with no directory, could not have it
+	// checked.
+	RequireSignatures bool
+	// TrustedHosts, when non-empty, lists the only host DIDs whose signatures
+	// are accepted for externally authored repos. Author-signed commits are
+	// unaffected.
+	TrustedHosts []syntax.DID
+	// Strict refuses hash-only verification, so every accepted commit has had
+	// its signature checked. It needs a directory to accept anything.
+	Strict bool
+}
+
 // Verifier authenticates a repo's signed commit against a locally recomputed
 // LtHash, resolving signer keys by the author's identity type: habitat-managed
 // (did:web) authors sign their own commits per the proposal spec, while
 // external authors' commits are signed by the space host's key, published in
-// the host's DID document under the "habitat" verification method.
+// the host's DID document under the "habitat" verification method. Its
+// TrustPolicy then decides whether a valid commit is accepted.
 type Verifier struct {
-	dir identity.Directory
+	dir    identity.Directory
+	policy TrustPolicy
 }
 
 // NewVerifier builds a Verifier. A nil directory (or nil Verifier) degrades to
 // hash-only verification: the commit's hash is compared but its signature is
 // not checked.
-func NewVerifier(dir identity.Directory) *Verifier {
-	return &Verifier{dir: dir}
+func NewVerifier(dir identity.Directory, policy TrustPolicy) *Verifier {
+	return &Verifier{dir: dir, policy: policy}
 }
 
-// Verify checks c against the folded LtHash for (space, author). It returns an
-// error wrapping spacecommit.ErrInvalidCommit when the commit fails
-// validation, and other errors when the signer key cannot be resolved
+// Verify checks c against the folded LtHash for (space, author) and returns
+// how it was verified. It returns an error wrapping spacecommit.ErrInvalidCommit
+// (with OutcomeInvalid) when the commit fails validation, one wrapping
+// ErrUntrusted (with OutcomeUntrusted) when the trust policy refuses it, and
+// other errors, with no outcome, when the signer key cannot be resolved
 // (transient: identity lookups may fail).
 func (v *Verifier) Verify(
 	ctx context.Context,
@@ -41,39 +91,70 @@ func (v *Verifier) Verify(
 	author syntax.DID,
 	c spacecommit.SignedCommit,
 	lt *spacecommit.LtHash,
-) error {
+) (Outcome, error) {
+	var policy TrustPolicy
+	if v != nil {
+		policy = v.policy
+	}
 	if v == nil || v.dir == nil {
 		if !hmac.Equal(lt.Sum(), c.Hash) {
-			return fmt.Errorf("%w: hash mismatch", spacecommit.ErrInvalidCommit)
+			return OutcomeInvalid, fmt.Errorf("%w: hash mismatch", spacecommit.ErrInvalidCommit)
+		}
+		// A signature that was never checked does not satisfy a policy that
+		// requires one, whether or not the commit carries it.
+		if policy.Strict || policy.RequireSignatures {
+			return OutcomeUntrusted, fmt.Errorf("%w: signature not checked", ErrUntrusted)
 		}
-		return nil
+		return OutcomeHashOnly, nil
 	}
-	pub, err := v.signer(ctx, space, author)
+	if policy.RequireSignatures && len(c.Sig) == 0 {
+		return OutcomeUntrusted, fmt.Errorf("%w: commit is unsigned", ErrUntrusted)
+	}
+	pub, hostDID, err := v.signer(ctx, space, author)
 	if err != nil {
-		return fmt.Errorf("resolve signer for %s: %w", author, err)
+		return "", fmt.Errorf("resolve signer for %s: %w", author, err)
+	}
+	if err := spacecommit.Verify(c, space, author, lt.Sum(), pub); err != nil {
+		return OutcomeInvalid, err
+	}
+	if hostDID == "" {
+		return OutcomeAuthorSigned, nil
+	}
+	if len(policy.TrustedHosts) > 0 && !slices.Contains(policy.TrustedHosts, hostDID) {
+		return OutcomeUntrusted, fmt.Errorf("%w: host %s is not trusted", ErrUntrusted, hostDID)
+	}
+	return OutcomeHostSigned, nil
+}
+
+// Unsigned reports the outcome of a sync that reached no signed commit to
+// verify: accepted as OutcomeUnsigned, unless the policy requires signatures.
+func (v *Verifier) Unsigned() (Outcome, error) {
+	if v != nil && v.policy.RequireSignatures {
+		return OutcomeUntrusted, fmt.Errorf("%w: no signed commit", ErrUntrusted)
 	}
-	return spacecommit.Verify(c, space, author, lt.Sum(), pub)
+	return OutcomeUnsigned, nil
 }
 
 // signer resolves the public key that authenticated the commit, mirroring the
-// host's signing choice in spacecommit.Authority.
+// host's signing choice in spacecommit.Authority, and the DID of the host
+// whose key it is; the host DID is empty when the author signed.
 func (v *Verifier) signer(
 	ctx context.Context,
 	space habitat_syntax.SpaceURI,
 	author syntax.DID,
-) (atcrypto.PublicKey, error) {
+) (atcrypto.PublicKey, syntax.DID, error) {
 	// Habitat-managed identities are did:web accounts whose signing keys the
 	// hive holds; the host signs their commits with their own key.
 	if author.Method() == "web" {
 		ident, err := v.dir.LookupDID(ctx, author)
 		if err != nil {
-			return nil, fmt.Errorf("lookup author: %w", err)
+			return nil, "", fmt.Errorf("lookup author: %w", err)
 		}
 		pub, err := ident.PublicKey()
 		if err != nil {
-			return nil, fmt.Errorf("author signing key: %w", err)
+			return nil, "", fmt.Errorf("author signing key: %w", err)
 		}
-		return pub, nil
+		return pub, "", nil
 	}
 
 	// External authors: the space host signed with its own key. Per the
@@ -85,28 +166,28 @@ func (v *Verifier) signer(
 	owner := space.SpaceOwner()
 	ownerIdent, err := v.dir.LookupDID(ctx, owner)
 	if err != nil {
-		return nil, fmt.Errorf("lookup space owner: %w", err)
+		return nil, "", fmt.Errorf("lookup space owner: %w", err)
 	}
 	svc, ok := ownerIdent.Services["atproto_space_host"]
 	if !ok || svc.URL == "" {
-		return nil, fmt.Errorf("space owner %s has no atproto_space_host service", owner)
+		return nil, "", fmt.Errorf("space owner %s has no atproto_space_host service", owner)
 	}
 	u, err := url.Parse(svc.URL)
 	if err != nil {
-		return nil, fmt.Errorf("parse atproto_space_host service url: %w", err)
+		return nil, "", fmt.Errorf("parse atproto_space_host service url: %w", err)
 	}
 	// did:web encodes a port's colon as %3A.
 	hostDID := syntax.DID("did:web:" + strings.ReplaceAll(u.Host, ":", "%3A"))
 	hostIdent, err := v.dir.LookupDID(ctx, hostDID)
 	if err != nil {
-		return nil, fmt.Errorf("lookup host %s: %w", hostDID, err)
+		return nil, "", fmt.Errorf("lookup host %s: %w", hostDID, err)
 	}
 	pub, err := hostIdent.GetPublicKey("atproto_space")
 	if err != nil {
 		pub, err = hostIdent.GetPublicKey("atproto")
 	}
 	if err != nil {
-		return nil, fmt.Errorf("host signing key: %w", err)
+		return nil, "", fmt.Errorf("host signing key: %w", err)
 	}
-	return pub, nil
+	return pub, hostDID, nil
 }
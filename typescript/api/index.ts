@@ -43,29 +43,41 @@ import * as NetworkHabitatDocsListDocs from './types/network/habitat/docs/listDo
 import * as NetworkHabitatDocsMarkdown from './types/network/habitat/docs/markdown.js'
 import * as NetworkHabitatDocsUpdateDoc from './types/network/habitat/docs/updateDoc.js'
 import * as NetworkHabitatGrantee from './types/network/habitat/grantee.js'
+import * as NetworkHabitatGroupJoinRequest from './types/network/habitat/group/joinRequest.js'
 import * as NetworkHabitatGroupProfile from './types/network/habitat/group/profile.js'
 import * as NetworkHabitatGroupsAddMember from './types/network/habitat/groups/addMember.js'
 import * as NetworkHabitatGroupsCreateGroup from './types/network/habitat/groups/createGroup.js'
 import * as NetworkHabitatGroupsDefs from './types/network/habitat/groups/defs.js'
 import * as NetworkHabitatGroupsDeleteMember from './types/network/habitat/groups/deleteMember.js'
 import * as NetworkHabitatGroupsGetGroup from './types/network/habitat/groups/getGroup.js'
+import * as NetworkHabitatGroupsGetMembershipPath from './types/network/habitat/groups/getMembershipPath.js'
 import * as NetworkHabitatGroupsListGroups from './types/network/habitat/groups/listGroups.js'
+import * as NetworkHabitatGroupsListJoinRequests from './types/network/habitat/groups/listJoinRequests.js'
+import * as NetworkHabitatGroupsListMyGroups from './types/network/habitat/groups/listMyGroups.js'
+import * as NetworkHabitatGroupsRequestToJoin from './types/network/habitat/groups/requestToJoin.js'
+import * as NetworkHabitatGroupsResolveJoinRequest from './types/network/habitat/groups/resolveJoinRequest.js'
 import * as NetworkHabitatGroupsUpdateGroup from './types/network/habitat/groups/updateGroup.js'
 import * as NetworkHabitatInstanceDescribeInstance from './types/network/habitat/instance/describeInstance.js'
 import * as NetworkHabitatInternalNotifyOfUpdate from './types/network/habitat/internal/notifyOfUpdate.js'
 import * as NetworkHabitatListConnectedApps from './types/network/habitat/listConnectedApps.js'
 import * as NetworkHabitatOrgAddAdmin from './types/network/habitat/org/addAdmin.js'
 import * as NetworkHabitatOrgAddMembers from './types/network/habitat/org/addMembers.js'
+import * as NetworkHabitatOrgAssignRole from './types/network/habitat/org/assignRole.js'
 import * as NetworkHabitatOrgCreate from './types/network/habitat/org/create.js'
+import * as NetworkHabitatOrgDeleteRole from './types/network/habitat/org/deleteRole.js'
 import * as NetworkHabitatOrgDowngradeAdmin from './types/network/habitat/org/downgradeAdmin.js'
 import * as NetworkHabitatOrgGetAdmins from './types/network/habitat/org/getAdmins.js'
 import * as NetworkHabitatOrgGetMembers from './types/network/habitat/org/getMembers.js'
 import * as NetworkHabitatOrgGetMetadata from './types/network/habitat/org/getMetadata.js'
 import * as NetworkHabitatOrgIssueInviteToken from './types/network/habitat/org/issueInviteToken.js'
+import * as NetworkHabitatOrgListRoles from './types/network/habitat/org/listRoles.js'
 import * as NetworkHabitatOrgLoginMember from './types/network/habitat/org/loginMember.js'
 import * as NetworkHabitatOrgMintMemberIdentity from './types/network/habitat/org/mintMemberIdentity.js'
+import * as NetworkHabitatOrgProvisionMembers from './types/network/habitat/org/provisionMembers.js'
+import * as NetworkHabitatOrgPutRole from './types/network/habitat/org/putRole.js'
 import * as NetworkHabitatOrgRemoveAdmin from './types/network/habitat/org/removeAdmin.js'
 import * as NetworkHabitatOrgRemoveMembers from './types/network/habitat/org/removeMembers.js'
+import * as NetworkHabitatOrgUnassignRole from './types/network/habitat/org/unassignRole.js'
 import * as NetworkHabitatPermissionsAddPermission from './types/network/habitat/permissions/addPermission.js'
 import * as NetworkHabitatPermissionsListPermissions from './types/network/habitat/permissions/listPermissions.js'
 import * as NetworkHabitatPermissionsRemovePermission from './types/network/habitat/permissions/removePermission.js'
@@ -75,7 +87,9 @@ import * as NetworkHabitatRelationshipCheckUserRelation from './types/network/ha
 import * as NetworkHabitatRelationshipDeleteRelation from './types/network/habitat/relationship/deleteRelation.js'
 import * as NetworkHabitatRelationshipListRelatedSpaces from './types/network/habitat/relationship/listRelatedSpaces.js'
 import * as NetworkHabitatRelationshipListRelations from './types/network/habitat/relationship/listRelations.js'
+import * as NetworkHabitatRelationshipOrgRoleRelation from './types/network/habitat/relationship/orgRoleRelation.js'
 import * as NetworkHabitatRelationshipResolveRelations from './types/network/habitat/relationship/resolveRelations.js'
+import * as NetworkHabitatRelationshipSetOrgRoleRelation from './types/network/habitat/relationship/setOrgRoleRelation.js'
 import * as NetworkHabitatRelationshipSetSpaceRelation from './types/network/habitat/relationship/setSpaceRelation.js'
 import * as NetworkHabitatRelationshipSetUserRelation from './types/network/habitat/relationship/setUserRelation.js'
 import * as NetworkHabitatRelationshipSpaceRelation from './types/network/habitat/relationship/spaceRelation.js'
@@ -147,29 +161,41 @@ export * as NetworkHabitatDocsListDocs from './types/network/habitat/docs/listDo
 export * as NetworkHabitatDocsMarkdown from './types/network/habitat/docs/markdown.js'
 export * as NetworkHabitatDocsUpdateDoc from './types/network/habitat/docs/updateDoc.js'
 export * as NetworkHabitatGrantee from './types/network/habitat/grantee.js'
+export * as NetworkHabitatGroupJoinRequest from './types/network/habitat/group/joinRequest.js'
 export * as NetworkHabitatGroupProfile from './types/network/habitat/group/profile.js'
 export * as NetworkHabitatGroupsAddMember from './types/network/habitat/groups/addMember.js'
 export * as NetworkHabitatGroupsCreateGroup from './types/network/habitat/groups/createGroup.js'
 export * as NetworkHabitatGroupsDefs from './types/network/habitat/groups/defs.js'
 export * as NetworkHabitatGroupsDeleteMember from './types/network/habitat/groups/deleteMember.js'
 export * as NetworkHabitatGroupsGetGroup from './types/network/habitat/groups/getGroup.js'
+export * as NetworkHabitatGroupsGetMembershipPath from './types/network/habitat/groups/getMembershipPath.js'
 export * as NetworkHabitatGroupsListGroups from './types/network/habitat/groups/listGroups.js'
+export * as NetworkHabitatGroupsListJoinRequests from './types/network/habitat/groups/listJoinRequests.js'
+export * as NetworkHabitatGroupsListMyGroups from './types/network/habitat/groups/listMyGroups.js'
+export * as NetworkHabitatGroupsRequestToJoin from './types/network/habitat/groups/requestToJoin.js'
+export * as NetworkHabitatGroupsResolveJoinRequest from './types/network/habitat/groups/resolveJoinRequest.js'
 export * as NetworkHabitatGroupsUpdateGroup from './types/network/habitat/groups/updateGroup.js'
 export * as NetworkHabitatInstanceDescribeInstance from './types/network/habitat/instance/describeInstance.js'
 export * as NetworkHabitatInternalNotifyOfUpdate from './types/network/habitat/internal/notifyOfUpdate.js'
 export * as NetworkHabitatListConnectedApps from './types/network/habitat/listConnectedApps.js'
 export * as NetworkHabitatOrgAddAdmin from './types/network/habitat/org/addAdmin.js'
 export * as NetworkHabitatOrgAddMembers from './types/network/habitat/org/addMembers.js'
+export * as NetworkHabitatOrgAssignRole from './types/network/habitat/org/assignRole.js'
 export * as NetworkHabitatOrgCreate from './types/network/habitat/org/create.js'
+export * as NetworkHabitatOrgDeleteRole from './types/network/habitat/org/deleteRole.js'
 export * as NetworkHabitatOrgDowngradeAdmin from './types/network/habitat/org/downgradeAdmin.js'
 export * as NetworkHabitatOrgGetAdmins from './types/network/habitat/org/getAdmins.js'
 export * as NetworkHabitatOrgGetMembers from './types/network/habitat/org/getMembers.js'
 export * as NetworkHabitatOrgGetMetadata from './types/network/habitat/org/getMetadata.js'
 export * as NetworkHabitatOrgIssueInviteToken from './types/network/habitat/org/issueInviteToken.js'
+export * as NetworkHabitatOrgListRoles from './types/network/habitat/org/listRoles.js'
 export * as NetworkHabitatOrgLoginMember from './types/network/habitat/org/loginMember.js'
 export * as NetworkHabitatOrgMintMemberIdentity from './types/network/habitat/org/mintMemberIdentity.js'
+export * as NetworkHabitatOrgProvisionMembers from './types/network/habitat/org/provisionMembers.js'
+export * as NetworkHabitatOrgPutRole from './types/network/habitat/org/putRole.js'
 export * as NetworkHabitatOrgRemoveAdmin from './types/network/habitat/org/removeAdmin.js'
 export * as NetworkHabitatOrgRemoveMembers from './types/network/habitat/org/removeMembers.js'
+export * as NetworkHabitatOrgUnassignRole from './types/network/habitat/org/unassignRole.js'
 export * as NetworkHabitatPermissionsAddPermission from './types/network/habitat/permissions/addPermission.js'
 export * as NetworkHabitatPermissionsListPermissions from './types/network/habitat/permissions/listPermissions.js'
 export * as NetworkHabitatPermissionsRemovePermission from './types/network/habitat/permissions/removePermission.js'
@@ -179,7 +205,9 @@ export * as NetworkHabitatRelationshipCheckUserRelation from './types/network/ha
 export * as NetworkHabitatRelationshipDeleteRelation from './types/network/habitat/relationship/deleteRelation.js'
 export * as NetworkHabitatRelationshipListRelatedSpaces from './types/network/habitat/relationship/listRelatedSpaces.js'
 export * as NetworkHabitatRelationshipListRelations from './types/network/habitat/relationship/listRelations.js'
+export * as NetworkHabitatRelationshipOrgRoleRelation from './types/network/habitat/relationship/orgRoleRelation.js'
 export * as NetworkHabitatRelationshipResolveRelations from './types/network/habitat/relationship/resolveRelations.js'
+export * as NetworkHabitatRelationshipSetOrgRoleRelation from './types/network/habitat/relationship/setOrgRoleRelation.js'
 export * as NetworkHabitatRelationshipSetSpaceRelation from './types/network/habitat/relationship/setSpaceRelation.js'
 export * as NetworkHabitatRelationshipSetUserRelation from './types/network/habitat/relationship/setUserRelation.js'
 export * as NetworkHabitatRelationshipSpaceRelation from './types/network/habitat/relationship/spaceRelation.js'
@@ -1103,14 +1131,99 @@ export class NetworkHabitatDocsMarkdownRecord {
 
 export class NetworkHabitatGroupNS {
   _client: XrpcClient
+  joinRequest: NetworkHabitatGroupJoinRequestRecord
   profile: NetworkHabitatGroupProfileRecord
 
   constructor(client: XrpcClient) {
     this._client = client
+    this.joinRequest = new NetworkHabitatGroupJoinRequestRecord(client)
     this.profile = new NetworkHabitatGroupProfileRecord(client)
   }
 }
 
+export class NetworkHabitatGroupJoinRequestRecord {
+  _client: XrpcClient
+
+  constructor(client: XrpcClient) {
+    this._client = client
+  }
+
+  async list(
+    params: OmitKey<ComAtprotoRepoListRecords.QueryParams, 'collection'>,
+  ): Promise<{
+    cursor?: string
+    records: { uri: string; value: NetworkHabitatGroupJoinRequest.Record }[]
+  }> {
+    const res = await this._client.call('com.atproto.repo.listRecords', {
+      collection: 'network.habitat.group.joinRequest',
+      ...params,
+    })
+    return res.data
+  }
+
+  async get(
+    params: OmitKey<ComAtprotoRepoGetRecord.QueryParams, 'collection'>,
+  ): Promise<{
+    uri: string
+    cid: string
+    value: NetworkHabitatGroupJoinRequest.Record
+  }> {
+    const res = await this._client.call('com.atproto.repo.getRecord', {
+      collection: 'network.habitat.group.joinRequest',
+      ...params,
+    })
+    return res.data
+  }
+
+  async create(
+    params: OmitKey<
+      ComAtprotoRepoCreateRecord.InputSchema,
+      'collection' | 'record'
+    >,
+    record: Un$Typed<NetworkHabitatGroupJoinRequest.Record>,
+    headers?: Record<string, string>,
+  ): Promise<{ uri: string; cid: string }> {
+    const collection = 'network.habitat.group.joinRequest'
+    const res = await this._client.call(
+      'com.atproto.repo.createRecord',
+      undefined,
+      { collection, ...params, record: { ...record, $type: collection } },
+      { encoding: 'application/json', headers },
+    )
+    return res.data
+  }
+
+  async put(
+    params: OmitKey<
+      ComAtprotoRepoPutRecord.InputSchema,
+      'collection' | 'record'
+    >,
+    record: Un$Typed<NetworkHabitatGroupJoinRequest.Record>,
+    headers?: Record<string, string>,
+  ): Promise<{ uri: string; cid: string }> {
+    const collection = 'network.habitat.group.joinRequest'
+    const res = await this._client.call(
+      'com.atproto.repo.putRecord',
+      undefined,
+      { collection, ...params, record: { ...record, $type: collection } },
+      { encoding: 'application/json', headers },
+    )
+    return res.data
+  }
+
+  async delete(
+    params: OmitKey<ComAtprotoRepoDeleteRecord.InputSchema, 'collection'>,
+    headers?: Record<string, string>,
+  ): Promise<void> {
+    await this._client.call(
+      'com.atproto.repo.deleteRecord',
+      undefined,
+      { collection: 'network.habitat.group.joinRequest', ...params },
+      { headers },
+    )
+  }
+}
+
 export class NetworkHabitatGroupProfileRecord {
   _client: XrpcClient
 
@@ -1221,12 +1334,11 @@ export class NetworkHabitatGroupsNS {
     data?: NetworkHabitatGroupsCreateGroup.InputSchema,
     opts?: NetworkHabitatGroupsCreateGroup.CallOptions,
   ): Promise<NetworkHabitatGroupsCreateGroup.Response> {
-    return this._client.call(
-      'network.habitat.groups.createGroup',
-      opts?.qp,
-      data,
-      opts,
-    )
+    return this._client
+      .call('network.habitat.groups.createGroup', opts?.qp, data, opts)
+      .catch((e) => {
+        throw NetworkHabitatGroupsCreateGroup.toKnownErr(e)
+      })
   }
 
   deleteMember(
@@ -1251,6 +1363,17 @@ export class NetworkHabitatGroupsNS {
       })
   }
 
+  getMembershipPath(
+    params?: NetworkHabitatGroupsGetMembershipPath.QueryParams,
+    opts?: NetworkHabitatGroupsGetMembershipPath.CallOptions,
+  ): Promise<NetworkHabitatGroupsGetMembershipPath.Response> {
+    return this._client
+      .call('network.habitat.groups.getMembershipPath', params, undefined, opts)
+      .catch((e) => {
+        throw NetworkHabitatGroupsGetMembershipPath.toKnownErr(e)
+      })
+  }
+
   listGroups(
     params?: NetworkHabitatGroupsListGroups.QueryParams,
     opts?: NetworkHabitatGroupsListGroups.CallOptions,
@@ -1263,6 +1386,51 @@ export class NetworkHabitatGroupsNS {
     )
   }
 
+  listJoinRequests(
+    params?: NetworkHabitatGroupsListJoinRequests.QueryParams,
+    opts?: NetworkHabitatGroupsListJoinRequests.CallOptions,
+  ): Promise<NetworkHabitatGroupsListJoinRequests.Response> {
+    return this._client
+      .call('network.habitat.groups.listJoinRequests', params, undefined, opts)
+      .catch((e) => {
+        throw NetworkHabitatGroupsListJoinRequests.toKnownErr(e)
+      })
+  }
+
+  listMyGroups(
+    params?: NetworkHabitatGroupsListMyGroups.QueryParams,
+    opts?: NetworkHabitatGroupsListMyGroups.CallOptions,
+  ): Promise<NetworkHabitatGroupsListMyGroups.Response> {
+    return this._client.call(
+      'network.habitat.groups.listMyGroups',
+      params,
+      undefined,
+      opts,
+    )
+  }
+
+  requestToJoin(
+    data?: NetworkHabitatGroupsRequestToJoin.InputSchema,
+    opts?: NetworkHabitatGroupsRequestToJoin.CallOptions,
+  ): Promise<NetworkHabitatGroupsRequestToJoin.Response> {
+    return this._client
+      .call('network.habitat.groups.requestToJoin', opts?.qp, data, opts)
+      .catch((e) => {
+        throw NetworkHabitatGroupsRequestToJoin.toKnownErr(e)
+      })
+  }
+
+  resolveJoinRequest(
+    data?: NetworkHabitatGroupsResolveJoinRequest.InputSchema,
+    opts?: NetworkHabitatGroupsResolveJoinRequest.CallOptions,
+  ): Promise<NetworkHabitatGroupsResolveJoinRequest.Response> {
+    return this._client
+      .call('network.habitat.groups.resolveJoinRequest', opts?.qp, data, opts)
+      .catch((e) => {
+        throw NetworkHabitatGroupsResolveJoinRequest.toKnownErr(e)
+      })
+  }
+
   updateGroup(
     data?: NetworkHabitatGroupsUpdateGroup.InputSchema,
     opts?: NetworkHabitatGroupsUpdateGroup.CallOptions,
@@ -1346,6 +1514,17 @@ export class NetworkHabitatOrgNS {
     )
   }
 
+  assignRole(
+    data?: NetworkHabitatOrgAssignRole.InputSchema,
+    opts?: NetworkHabitatOrgAssignRole.CallOptions,
+  ): Promise<NetworkHabitatOrgAssignRole.Response> {
+    return this._client
+      .call('network.habitat.org.assignRole', opts?.qp, data, opts)
+      .catch((e) => {
+        throw NetworkHabitatOrgAssignRole.toKnownErr(e)
+      })
+  }
+
   create(
     data?: NetworkHabitatOrgCreate.InputSchema,
     opts?: NetworkHabitatOrgCreate.CallOptions,
@@ -1353,6 +1532,17 @@ export class NetworkHabitatOrgNS {
     return this._client.call('network.habitat.org.create', opts?.qp, data, opts)
   }
 
+  deleteRole(
+    data?: NetworkHabitatOrgDeleteRole.InputSchema,
+    opts?: NetworkHabitatOrgDeleteRole.CallOptions,
+  ): Promise<NetworkHabitatOrgDeleteRole.Response> {
+    return this._client
+      .call('network.habitat.org.deleteRole', opts?.qp, data, opts)
+      .catch((e) => {
+        throw NetworkHabitatOrgDeleteRole.toKnownErr(e)
+      })
+  }
+
   downgradeAdmin(
     data?: NetworkHabitatOrgDowngradeAdmin.InputSchema,
     opts?: NetworkHabitatOrgDowngradeAdmin.CallOptions,
@@ -1413,6 +1603,18 @@ export class NetworkHabitatOrgNS {
     )
   }
 
+  listRoles(
+    params?: NetworkHabitatOrgListRoles.QueryParams,
+    opts?: NetworkHabitatOrgListRoles.CallOptions,
+  ): Promise<NetworkHabitatOrgListRoles.Response> {
+    return this._client.call(
+      'network.habitat.org.listRoles',
+      params,
+      undefined,
+      opts,
+    )
+  }
+
   loginMember(
     data?: NetworkHabitatOrgLoginMember.InputSchema,
     opts?: NetworkHabitatOrgLoginMember.CallOptions,
@@ -1437,6 +1639,28 @@ export class NetworkHabitatOrgNS {
     )
   }
 
+  provisionMembers(
+    data?: NetworkHabitatOrgProvisionMembers.InputSchema,
+    opts?: NetworkHabitatOrgProvisionMembers.CallOptions,
+  ): Promise<NetworkHabitatOrgProvisionMembers.Response> {
+    return this._client
+      .call('network.habitat.org.provisionMembers', opts?.qp, data, opts)
+      .catch((e) => {
+        throw NetworkHabitatOrgProvisionMembers.toKnownErr(e)
+      })
+  }
+
+  putRole(
+    data?: NetworkHabitatOrgPutRole.InputSchema,
+    opts?: NetworkHabitatOrgPutRole.CallOptions,
+  ): Promise<NetworkHabitatOrgPutRole.Response> {
+    return this._client
+      .call('network.habitat.org.putRole', opts?.qp, data, opts)
+      .catch((e) => {
+        throw NetworkHabitatOrgPutRole.toKnownErr(e)
+      })
+  }
+
   removeAdmin(
     data?: NetworkHabitatOrgRemoveAdmin.InputSchema,
     opts?: NetworkHabitatOrgRemoveAdmin.CallOptions,
@@ -1460,6 +1684,18 @@ export class NetworkHabitatOrgNS {
       opts,
     )
   }
+
+  unassignRole(
+    data?: NetworkHabitatOrgUnassignRole.InputSchema,
+    opts?: NetworkHabitatOrgUnassignRole.CallOptions,
+  ): Promise<NetworkHabitatOrgUnassignRole.Response> {
+    return this._client.call(
+      'network.habitat.org.unassignRole',
+      opts?.qp,
+      data,
+      opts,
+    )
+  }
 }
 
 export class NetworkHabitatPermissionsNS {
@@ -1508,11 +1744,15 @@ export class NetworkHabitatPermissionsNS {
 
 export class NetworkHabitatRelationshipNS {
   _client: XrpcClient
+  orgRoleRelation: NetworkHabitatRelationshipOrgRoleRelationRecord
   spaceRelation: NetworkHabitatRelationshipSpaceRelationRecord
   userRelation: NetworkHabitatRelationshipUserRelationRecord
 
   constructor(client: XrpcClient) {
     this._client = client
+    this.orgRoleRelation = new NetworkHabitatRelationshipOrgRoleRelationRecord(
+      client,
+    )
     this.spaceRelation = new NetworkHabitatRelationshipSpaceRelationRecord(
       client,
     )
@@ -1590,6 +1830,22 @@ export class NetworkHabitatRelationshipNS {
     )
   }
 
+  setOrgRoleRelation(
+    data?: NetworkHabitatRelationshipSetOrgRoleRelation.InputSchema,
+    opts?: NetworkHabitatRelationshipSetOrgRoleRelation.CallOptions,
+  ): Promise<NetworkHabitatRelationshipSetOrgRoleRelation.Response> {
+    return this._client
+      .call(
+        'network.habitat.relationship.setOrgRoleRelation',
+        opts?.qp,
+        data,
+        opts,
+      )
+      .catch((e) => {
+        throw NetworkHabitatRelationshipSetOrgRoleRelation.toKnownErr(e)
+      })
+  }
+
   setSpaceRelation(
     data?: NetworkHabitatRelationshipSetSpaceRelation.InputSchema,
     opts?: NetworkHabitatRelationshipSetSpaceRelation.CallOptions,
@@ -1623,6 +1879,92 @@ export class NetworkHabitatRelationshipNS {
   }
 }
 
+export class NetworkHabitatRelationshipOrgRoleRelationRecord {
+  _client: XrpcClient
+
+  constructor(client: XrpcClient) {
+    this._client = client
+  }
+
+  async list(
+    params: OmitKey<ComAtprotoRepoListRecords.QueryParams, 'collection'>,
+  ): Promise<{
+    cursor?: string
+    records: {
+      uri: string
+      value: NetworkHabitatRelationshipOrgRoleRelation.Record
+    }[]
+  }> {
+    const res = await this._client.call('com.atproto.repo.listRecords', {
+      collection: 'network.habitat.relationship.orgRoleRelation',
+      ...params,
+    })
+    return res.data
+  }
+
+  async get(
+    params: OmitKey<ComAtprotoRepoGetRecord.QueryParams, 'collection'>,
+  ): Promise<{
+    uri: string
+    cid: string
+    value: NetworkHabitatRelationshipOrgRoleRelation.Record
+  }> {
+    const res = await this._client.call('com.atproto.repo.getRecord', {
+      collection: 'network.habitat.relationship.orgRoleRelation',
+      ...params,
+    })
+    return res.data
+  }
+
+  async create(
+    params: OmitKey<
+      ComAtprotoRepoCreateRecord.InputSchema,
+      'collection' | 'record'
+    >,
+    record: Un$Typed<NetworkHabitatRelationshipOrgRoleRelation.Record>,
+    headers?: Record<string, string>,
+  ): Promise<{ uri: string; cid: string }> {
+    const collection = 'network.habitat.relationship.orgRoleRelation'
+    const res = await this._client.call(
+      'com.atproto.repo.createRecord',
+      undefined,
+      { collection, ...params, record: { ...record, $type: collection } },
+      { encoding: 'application/json', headers },
+    )
+    return res.data
+  }
+
+  async put(
+    params: OmitKey<
+      ComAtprotoRepoPutRecord.InputSchema,
+      'collection' | 'record'
+    >,
+    record: Un$Typed<NetworkHabitatRelationshipOrgRoleRelation.Record>,
+    headers?: Record<string, string>,
+  ): Promise<{ uri: string; cid: string }> {
+    const collection = 'network.habitat.relationship.orgRoleRelation'
+    const res = await this._client.call(
+      'com.atproto.repo.putRecord',
+      undefined,
+      { collection, ...params, record: { ...record, $type: collection } },
+      { encoding: 'application/json', headers },
+    )
+    return res.data
+  }
+
+  async delete(
+    params: OmitKey<ComAtprotoRepoDeleteRecord.InputSchema, 'collection'>,
+    headers?: Record<string, string>,
+  ): Promise<void> {
+    await this._client.call(
+      'com.atproto.repo.deleteRecord',
+      undefined,
+      { collection: 'network.habitat.relationship.orgRoleRelation', ...params },
+      { headers },
+    )
+  }
+}
+
 export class NetworkHabitatRelationshipSpaceRelationRecord {
   _client: XrpcClient
 
@@ -1195,7 +1195,7 @@ export const schemaDict = {
       recordView: {
         type: 'object',
         description:
-          'A single record scoped to one space. The same repo/collection/rkey in a different space is a distinct record with its own version, so it appears as its own recordView. The record body is not included; fetch it on demand from pear using the space, repo, collection and rkey.',
+          'A single record scoped to one space. The same repo/collection/rkey in a different space is a distinct record with its own version, so it appears as its own recordView. The record body is the version this space holds.',
         required: ['uri', 'space', 'repo', 'collection', 'rkey'],
         properties: {
           uri: {
@@ -1224,6 +1224,16 @@ export const schemaDict = {
             format: 'record-key',
             description: 'The record key.',
           },
+          value: {
+            type: 'unknown',
+            description:
+              'The record body, as last synced. Absent for a record synced before bodies were kept, until its repo is resynced.',
+          },
+          updatedAt: {
+            type: 'string',
+            format: 'datetime',
+            description: 'When the record was last written.',
+          },
         },
       },
     },
@@ -1266,7 +1276,7 @@ export const schemaDict = {
       main: {
         type: 'query',
         description:
-          'List the records in a collection the calling user can see, each with the spaces it belongs to that the user can read. The record body is not included; fetch it on demand from pear. Implemented by the home server and reached via pear service proxying.',
+          'List the records in a collection the calling user can see, in the spaces they can read, with their bodies, most recently updated first. Implemented by the home server and reached via pear service proxying.',
         parameters: {
           type: 'params',
           required: ['collection'],
@@ -1276,6 +1286,15 @@ export const schemaDict = {
               format: 'nsid',
               description: 'The NSID of the record collection to list.',
             },
+            limit: {
+              type: 'integer',
+              minimum: 1,
+              maximum: 100,
+              default: 50,
+            },
+            cursor: {
+              type: 'string',
+            },
           },
         },
         output: {
@@ -1284,6 +1303,9 @@ export const schemaDict = {
             type: 'object',
             required: ['records'],
             properties: {
+              cursor: {
+                type: 'string',
+              },
               records: {
                 type: 'array',
                 items: {
@@ -1505,6 +1527,39 @@ export const schemaDict = {
       },
     },
   },
+  NetworkHabitatGroupJoinRequest: {
+    lexicon: 1,
+    id: 'network.habitat.group.joinRequest',
+    defs: {
+      main: {
+        type: 'record',
+        description:
+          "A user's pending request to join a group. The home server writes it into the group-space with the org credential, so it syncs like the group's other records, and deletes it once a manager approves or rejects the request.",
+        key: 'tid',
+        record: {
+          type: 'object',
+          required: ['subject', 'createdAt'],
+          properties: {
+            subject: {
+              type: 'string',
+              format: 'did',
+              description: 'DID of the user asking to join.',
+            },
+            message: {
+              type: 'string',
+              maxLength: 1024,
+              description:
+                "Optional note from the user to the group's managers.",
+            },
+            createdAt: {
+              type: 'string',
+              format: 'datetime',
+            },
+          },
+        },
+      },
+    },
+  },
   NetworkHabitatGroupProfile: {
     lexicon: 1,
     id: 'network.habitat.group.profile',
@@ -1530,6 +1585,12 @@ export const schemaDict = {
               type: 'string',
               format: 'datetime',
             },
+            joinPolicy: {
+              type: 'string',
+              knownValues: ['open', 'request'],
+              description:
+                "How users join the group: 'open' adds anyone who asks straight away, 'request' (the default) leaves each request for a manager to approve.",
+            },
           },
         },
       },
@@ -1595,6 +1656,11 @@ export const schemaDict = {
             description:
               'Exactly one of subjectDid or subjectGroup must be provided.',
           },
+          {
+            name: 'MembershipCycle',
+            description:
+              'subjectGroup already inherits members from the group, so inheriting from it would form a cycle.',
+          },
         ],
       },
     },
@@ -1621,6 +1687,12 @@ export const schemaDict = {
                 type: 'string',
                 maxLength: 2048,
               },
+              joinPolicy: {
+                type: 'string',
+                knownValues: ['open', 'request'],
+                description:
+                  "How users join the group: 'open' adds anyone who asks straight away, 'request' (the default) leaves each request for a manager to approve.",
+              },
             },
           },
         },
@@ -1638,6 +1710,12 @@ export const schemaDict = {
             },
           },
         },
+        errors: [
+          {
+            name: 'InvalidJoinPolicy',
+            description: "joinPolicy is not 'open' or 'request'.",
+          },
+        ],
       },
     },
   },
@@ -1666,6 +1744,11 @@ export const schemaDict = {
             type: 'string',
             format: 'datetime',
           },
+          joinPolicy: {
+            type: 'string',
+            knownValues: ['open', 'request'],
+            description: "How users join the group: 'open' or 'request'.",
+          },
           memberCount: {
             type: 'integer',
             description:
@@ -1723,6 +1806,34 @@ export const schemaDict = {
           },
         },
       },
+      membershipView: {
+        type: 'object',
+        description:
+          "A user's membership of a group, resolved through inherited groups.",
+        required: ['group', 'direct'],
+        properties: {
+          group: {
+            type: 'ref',
+            ref: 'lex:network.habitat.groups.defs#groupRef',
+          },
+          role: {
+            type: 'string',
+            description:
+              'Highest role held on the group-space (owner|manager|writer|reader).',
+          },
+          direct: {
+            type: 'boolean',
+            description:
+              'True if the role is granted directly on this group, false if it is inherited from another group.',
+          },
+          viaGroup: {
+            type: 'string',
+            format: 'uri',
+            description:
+              'If inherited, the URI of the group-space the membership came from.',
+          },
+        },
+      },
       groupRef: {
         type: 'object',
         required: ['uri', 'name'],
@@ -1736,6 +1847,29 @@ export const schemaDict = {
           },
         },
       },
+      joinRequestView: {
+        type: 'object',
+        description: "A user's pending request to join a group.",
+        required: ['uri', 'subject', 'createdAt'],
+        properties: {
+          uri: {
+            type: 'string',
+            description: 'URI of the network.habitat.group.joinRequest record.',
+          },
+          subject: {
+            type: 'string',
+            format: 'did',
+            description: 'DID of the user asking to join.',
+          },
+          message: {
+            type: 'string',
+          },
+          createdAt: {
+            type: 'string',
+            format: 'datetime',
+          },
+        },
+      },
     },
   },
   NetworkHabitatGroupsDeleteMember: {
@@ -1830,6 +1964,66 @@ export const schemaDict = {
       },
     },
   },
+  NetworkHabitatGroupsGetMembershipPath: {
+    lexicon: 1,
+    id: 'network.habitat.groups.getMembershipPath',
+    defs: {
+      main: {
+        type: 'query',
+        description:
+          'Explain how a user is a member of a group: the shortest chain of inherited groups from one the user is granted a role on directly to the requested group. Defaults to the calling user. Implemented by the home server and reached via pear service proxying.',
+        parameters: {
+          type: 'params',
+          required: ['group'],
+          properties: {
+            group: {
+              type: 'string',
+              format: 'uri',
+              description: 'URI of the group-space.',
+            },
+            did: {
+              type: 'string',
+              format: 'did',
+              description: 'DID of the user. Defaults to the caller.',
+            },
+          },
+        },
+        output: {
+          encoding: 'application/json',
+          schema: {
+            type: 'object',
+            required: ['path'],
+            properties: {
+              role: {
+                type: 'string',
+                description:
+                  'Role the user holds on the group (owner|manager|writer|reader).',
+              },
+              path: {
+                type: 'array',
+                description:
+                  "The groups the membership passes through: first the group the user is granted a role on directly, last the requested group. A direct member's path is the group alone.",
+                items: {
+                  type: 'ref',
+                  ref: 'lex:network.habitat.groups.defs#groupRef',
+                },
+              },
+            },
+          },
+        },
+        errors: [
+          {
+            name: 'GroupNotFound',
+            description: 'No group with the given URI is indexed.',
+          },
+          {
+            name: 'NotMember',
+            description: 'The user is not a member of the group.',
+          },
+        ],
+      },
+    },
+  },
   NetworkHabitatGroupsListGroups: {
     lexicon: 1,
     id: 'network.habitat.groups.listGroups',
@@ -1861,33 +2055,22 @@ export const schemaDict = {
       },
     },
   },
-  NetworkHabitatGroupsUpdateGroup: {
+  NetworkHabitatGroupsListJoinRequests: {
     lexicon: 1,
-    id: 'network.habitat.groups.updateGroup',
+    id: 'network.habitat.groups.listJoinRequests',
     defs: {
       main: {
-        type: 'procedure',
+        type: 'query',
         description:
-          "Update a group's profile (name and/or description). Caller must be able to manage the group.",
-        input: {
-          encoding: 'application/json',
-          schema: {
-            type: 'object',
-            required: ['group'],
-            properties: {
-              group: {
-                type: 'string',
-                format: 'uri',
-                description: 'URI of the group-space.',
-              },
-              name: {
-                type: 'string',
-                maxLength: 256,
-              },
-              description: {
-                type: 'string',
-                maxLength: 2048,
-              },
+          "List a group's pending join requests, oldest first. Caller must be able to manage the group.",
+        parameters: {
+          type: 'params',
+          required: ['group'],
+          properties: {
+            group: {
+              type: 'string',
+              format: 'uri',
+              description: 'URI of the group-space.',
             },
           },
         },
@@ -1895,11 +2078,14 @@ export const schemaDict = {
           encoding: 'application/json',
           schema: {
             type: 'object',
-            required: ['uri'],
+            required: ['requests'],
             properties: {
-              uri: {
-                type: 'string',
-                format: 'uri',
+              requests: {
+                type: 'array',
+                items: {
+                  type: 'ref',
+                  ref: 'lex:network.habitat.groups.defs#joinRequestView',
+                },
               },
             },
           },
@@ -1917,28 +2103,37 @@ export const schemaDict = {
       },
     },
   },
-  NetworkHabitatInstanceDescribeInstance: {
+  NetworkHabitatGroupsListMyGroups: {
     lexicon: 1,
-    id: 'network.habitat.instance.describeInstance',
+    id: 'network.habitat.groups.listMyGroups',
     defs: {
       main: {
         type: 'query',
         description:
-          'Get public info about this instance. Modeled on com.atproto.server.describeServer. No authentication required.',
+          'List every group a user is a member of, directly or through inherited groups, with the role they hold and how they came by it. Defaults to the calling user. Implemented by the home server and reached via pear service proxying.',
+        parameters: {
+          type: 'params',
+          properties: {
+            did: {
+              type: 'string',
+              format: 'did',
+              description:
+                'DID of the user whose groups to list. Defaults to the caller.',
+            },
+          },
+        },
         output: {
           encoding: 'application/json',
           schema: {
             type: 'object',
-            required: ['name', 'inviteRequired'],
+            required: ['groups'],
             properties: {
-              name: {
-                type: 'string',
-                description: "This instance's manager-configured display name.",
-              },
-              inviteRequired: {
-                type: 'boolean',
-                description:
-                  'Whether creating an org on this instance requires an invite token.',
+              groups: {
+                type: 'array',
+                items: {
+                  type: 'ref',
+                  ref: 'lex:network.habitat.groups.defs#membershipView',
+                },
               },
             },
           },
@@ -1946,54 +2141,271 @@ export const schemaDict = {
       },
     },
   },
-  NetworkHabitatInternalNotifyOfUpdate: {
+  NetworkHabitatGroupsRequestToJoin: {
     lexicon: 1,
-    id: 'network.habitat.internal.notifyOfUpdate',
+    id: 'network.habitat.groups.requestToJoin',
     defs: {
       main: {
         type: 'procedure',
         description:
-          'Notify another DID that there is an update for them on the fiven record.',
+          'Ask to join a group as the calling user. An open group adds the caller as a member straight away; a request-only group records a network.habitat.group.joinRequest for its managers to resolve. Asking again while a request is pending returns the pending request.',
         input: {
           encoding: 'application/json',
           schema: {
             type: 'object',
-            required: ['collection', 'rkey', 'recipient'],
+            required: ['group'],
             properties: {
-              recipient: {
+              group: {
                 type: 'string',
-                format: 'did',
-                description: 'The DID to grant permission to (URL parameter).',
+                format: 'uri',
+                description: 'URI of the group-space to join.',
               },
-              collection: {
+              message: {
                 type: 'string',
-                format: 'nsid',
+                maxLength: 1024,
+                description: "Optional note to the group's managers.",
+              },
+            },
+          },
+        },
+        output: {
+          encoding: 'application/json',
+          schema: {
+            type: 'object',
+            required: ['status', 'uri'],
+            properties: {
+              status: {
+                type: 'string',
+                knownValues: ['joined', 'pending'],
                 description:
-                  'The NSID of the record collection that the update is for.',
+                  "'joined' if the caller is now a member, 'pending' if the request awaits a manager.",
               },
-              rkey: {
+              uri: {
                 type: 'string',
-                description: 'The record key which was updated.',
+                description:
+                  'URI of the written relationship tuple when joined, or of the join request record when pending.',
               },
             },
           },
         },
+        errors: [
+          {
+            name: 'GroupNotFound',
+            description: 'No group with the given URI is indexed.',
+          },
+          {
+            name: 'AlreadyMember',
+            description: 'The caller is already a member of the group.',
+          },
+        ],
       },
     },
   },
-  NetworkHabitatListConnectedApps: {
+  NetworkHabitatGroupsResolveJoinRequest: {
     lexicon: 1,
-    id: 'network.habitat.listConnectedApps',
+    id: 'network.habitat.groups.resolveJoinRequest',
     defs: {
       main: {
-        type: 'query',
+        type: 'procedure',
         description:
-          'List apps connected to habitat for a given user. Returns connected apps for the authenticated user.',
-        output: {
+          "Approve or reject a user's pending request to join a group. Approving grants the user the writer role on the group-space through pear, making them a member; either way the request record is deleted. Caller must be able to manage the group.",
+        input: {
           encoding: 'application/json',
           schema: {
             type: 'object',
-            required: ['apps'],
+            required: ['group', 'subject', 'approve'],
+            properties: {
+              group: {
+                type: 'string',
+                format: 'uri',
+                description: 'URI of the group-space.',
+              },
+              subject: {
+                type: 'string',
+                format: 'did',
+                description: 'DID of the user who asked to join.',
+              },
+              approve: {
+                type: 'boolean',
+                description:
+                  'True to add the user as a member, false to reject the request.',
+              },
+            },
+          },
+        },
+        output: {
+          encoding: 'application/json',
+          schema: {
+            type: 'object',
+            properties: {
+              uri: {
+                type: 'string',
+                description:
+                  'URI of the written relationship tuple, when approved.',
+              },
+            },
+          },
+        },
+        errors: [
+          {
+            name: 'GroupNotFound',
+            description: 'No group with the given URI is indexed.',
+          },
+          {
+            name: 'Forbidden',
+            description: 'The caller is not allowed to manage this group.',
+          },
+          {
+            name: 'JoinRequestNotFound',
+            description: 'The user has no pending request to join the group.',
+          },
+        ],
+      },
+    },
+  },
+  NetworkHabitatGroupsUpdateGroup: {
+    lexicon: 1,
+    id: 'network.habitat.groups.updateGroup',
+    defs: {
+      main: {
+        type: 'procedure',
+        description:
+          "Update a group's profile (name and/or description). Caller must be able to manage the group.",
+        input: {
+          encoding: 'application/json',
+          schema: {
+            type: 'object',
+            required: ['group'],
+            properties: {
+              group: {
+                type: 'string',
+                format: 'uri',
+                description: 'URI of the group-space.',
+              },
+              name: {
+                type: 'string',
+                maxLength: 256,
+              },
+              description: {
+                type: 'string',
+                maxLength: 2048,
+              },
+              joinPolicy: {
+                type: 'string',
+                knownValues: ['open', 'request'],
+                description:
+                  "How users join the group: 'open' adds anyone who asks straight away, 'request' (the default) leaves each request for a manager to approve.",
+              },
+            },
+          },
+        },
+        output: {
+          encoding: 'application/json',
+          schema: {
+            type: 'object',
+            required: ['uri'],
+            properties: {
+              uri: {
+                type: 'string',
+                format: 'uri',
+              },
+            },
+          },
+        },
+        errors: [
+          {
+            name: 'GroupNotFound',
+            description: 'No group with the given URI is indexed.',
+          },
+          {
+            name: 'Forbidden',
+            description: 'The caller is not allowed to manage this group.',
+          },
+          {
+            name: 'InvalidJoinPolicy',
+            description: "joinPolicy is not 'open' or 'request'.",
+          },
+        ],
+      },
+    },
+  },
+  NetworkHabitatInstanceDescribeInstance: {
+    lexicon: 1,
+    id: 'network.habitat.instance.describeInstance',
+    defs: {
+      main: {
+        type: 'query',
+        description:
+          'Get public info about this instance. Modeled on com.atproto.server.describeServer. No authentication required.',
+        output: {
+          encoding: 'application/json',
+          schema: {
+            type: 'object',
+            required: ['name', 'inviteRequired'],
+            properties: {
+              name: {
+                type: 'string',
+                description: "This instance's manager-configured display name.",
+              },
+              inviteRequired: {
+                type: 'boolean',
+                description:
+                  'Whether creating an org on this instance requires an invite token.',
+              },
+            },
+          },
+        },
+      },
+    },
+  },
+  NetworkHabitatInternalNotifyOfUpdate: {
+    lexicon: 1,
+    id: 'network.habitat.internal.notifyOfUpdate',
+    defs: {
+      main: {
+        type: 'procedure',
+        description:
+          'Notify another DID that there is an update for them on the fiven record.',
+        input: {
+          encoding: 'application/json',
+          schema: {
+            type: 'object',
+            required: ['collection', 'rkey', 'recipient'],
+            properties: {
+              recipient: {
+                type: 'string',
+                format: 'did',
+                description: 'The DID to grant permission to (URL parameter).',
+              },
+              collection: {
+                type: 'string',
+                format: 'nsid',
+                description:
+                  'The NSID of the record collection that the update is for.',
+              },
+              rkey: {
+                type: 'string',
+                description: 'The record key which was updated.',
+              },
+            },
+          },
+        },
+      },
+    },
+  },
+  NetworkHabitatListConnectedApps: {
+    lexicon: 1,
+    id: 'network.habitat.listConnectedApps',
+    defs: {
+      main: {
+        type: 'query',
+        description:
+          'List apps connected to habitat for a given user. Returns connected apps for the authenticated user.',
+        output: {
+          encoding: 'application/json',
+          schema: {
+            type: 'object',
+            required: ['apps'],
             properties: {
               apps: {
                 type: 'array',
@@ -2088,6 +2500,45 @@ export const schemaDict = {
       },
     },
   },
+  NetworkHabitatOrgAssignRole: {
+    lexicon: 1,
+    id: 'network.habitat.org.assignRole',
+    defs: {
+      main: {
+        type: 'procedure',
+        description:
+          'Assign a custom role to an org member. Only callable by admins.',
+        input: {
+          encoding: 'application/json',
+          schema: {
+            type: 'object',
+            required: ['did', 'role'],
+            properties: {
+              did: {
+                type: 'string',
+                format: 'did',
+                description: 'DID of the member.',
+              },
+              role: {
+                type: 'string',
+                description: 'Name of the role.',
+              },
+            },
+          },
+        },
+        errors: [
+          {
+            name: 'RoleNotFound',
+            description: 'The org has no role with this name.',
+          },
+          {
+            name: 'NotMember',
+            description: 'The DID is not a member of the org.',
+          },
+        ],
+      },
+    },
+  },
   NetworkHabitatOrgCreate: {
     lexicon: 1,
     id: 'network.habitat.org.create',
@@ -2172,6 +2623,40 @@ export const schemaDict = {
       },
     },
   },
+  NetworkHabitatOrgDeleteRole: {
+    lexicon: 1,
+    id: 'network.habitat.org.deleteRole',
+    defs: {
+      main: {
+        type: 'procedure',
+        description:
+          'Delete a custom org role, unassigning it from every member. A role still granted relations on spaces cannot be deleted until those relations are. Only callable by admins.',
+        input: {
+          encoding: 'application/json',
+          schema: {
+            type: 'object',
+            required: ['name'],
+            properties: {
+              name: {
+                type: 'string',
+                description: 'Name of the role to delete.',
+              },
+            },
+          },
+        },
+        errors: [
+          {
+            name: 'RoleNotFound',
+            description: 'The org has no role with this name.',
+          },
+          {
+            name: 'RoleInUse',
+            description: 'The role is still granted relations on spaces.',
+          },
+        ],
+      },
+    },
+  },
   NetworkHabitatOrgDowngradeAdmin: {
     lexicon: 1,
     id: 'network.habitat.org.downgradeAdmin',
@@ -2334,7 +2819,7 @@ export const schemaDict = {
       main: {
         type: 'procedure',
         description:
-          'Generate an invite token that can be sent to a member to join this organization.',
+          'Generate an invite token that can be sent to a member to join this organization. Callable by admins and by holders of a role with the issueInvites capability; reusable tokens need mintIdentities.',
         input: {
           encoding: 'application/json',
           schema: {
@@ -2369,6 +2854,56 @@ export const schemaDict = {
       },
     },
   },
+  NetworkHabitatOrgListRoles: {
+    lexicon: 1,
+    id: 'network.habitat.org.listRoles',
+    defs: {
+      main: {
+        type: 'query',
+        description:
+          'List the custom roles of the org with their capabilities and assignees. Callable by any org member.',
+        output: {
+          encoding: 'application/json',
+          schema: {
+            type: 'object',
+            required: ['roles'],
+            properties: {
+              roles: {
+                type: 'array',
+                items: {
+                  type: 'ref',
+                  ref: 'lex:network.habitat.org.listRoles#role',
+                },
+              },
+            },
+          },
+        },
+      },
+      role: {
+        type: 'object',
+        required: ['name', 'capabilities', 'assignees'],
+        properties: {
+          name: {
+            type: 'string',
+          },
+          capabilities: {
+            type: 'array',
+            items: {
+              type: 'string',
+            },
+          },
+          assignees: {
+            type: 'array',
+            items: {
+              type: 'string',
+              format: 'did',
+            },
+            description: 'DIDs of the members holding the role.',
+          },
+        },
+      },
+    },
+  },
   NetworkHabitatOrgLoginMember: {
     lexicon: 1,
     id: 'network.habitat.org.loginMember',
@@ -2412,65 +2947,232 @@ export const schemaDict = {
       },
     },
   },
-  NetworkHabitatOrgMintMemberIdentity: {
+  NetworkHabitatOrgMintMemberIdentity: {
+    lexicon: 1,
+    id: 'network.habitat.org.mintMemberIdentity',
+    defs: {
+      main: {
+        type: 'procedure',
+        description:
+          'Mint a new organization member identity with the given handle and token.',
+        input: {
+          encoding: 'application/json',
+          schema: {
+            type: 'object',
+            required: ['token', 'handle'],
+            properties: {
+              orgId: {
+                type: 'string',
+                description: 'The ID of the org this member is joining.',
+              },
+              handle: {
+                type: 'string',
+                description:
+                  'The internal handle (all letters + numbers, no special characters, does not include org domain) that will be used by the member.',
+              },
+              token: {
+                type: 'string',
+                description:
+                  'The token that was issued by an org admin to allow members to join the organization.',
+              },
+              password: {
+                type: 'string',
+                description:
+                  "The password for the new member's account (required for 'password' login method).",
+              },
+              loginID: {
+                type: 'string',
+                description:
+                  "Provider-specific identifier (AT Protocol handle for 'atproto', email for 'google'). Required for non-password login methods.",
+              },
+            },
+          },
+        },
+        output: {
+          encoding: 'application/json',
+          schema: {
+            type: 'object',
+            required: ['handle', 'did'],
+            properties: {
+              handle: {
+                type: 'string',
+                description:
+                  'The full handle of the newly minted member identity.',
+              },
+              did: {
+                type: 'string',
+                description: 'The DID of the newly minted member identity.',
+              },
+            },
+          },
+        },
+      },
+    },
+  },
+  NetworkHabitatOrgProvisionMembers: {
+    lexicon: 1,
+    id: 'network.habitat.org.provisionMembers',
+    defs: {
+      main: {
+        type: 'procedure',
+        description:
+          "Provision many members at once from a JSON or CSV list. Every row is minted in one transaction: if any row fails, no member is created and each row reports why. No invites are issued: members of 'password' orgs sign in with their generated initialPassword, and members of other orgs with their loginID through the org's login provider. Callable by admins and by holders of a role with the mintIdentities capability.",
+        input: {
+          encoding: 'application/json',
+          schema: {
+            type: 'object',
+            properties: {
+              members: {
+                type: 'array',
+                items: {
+                  type: 'ref',
+                  ref: 'lex:network.habitat.org.provisionMembers#member',
+                },
+                description:
+                  'Members to provision. Exactly one of members or csv must be set.',
+              },
+              csv: {
+                type: 'string',
+                description:
+                  'Members to provision as CSV with a header row naming the handle, loginID and groups columns. Groups are separated by spaces. Exactly one of members or csv must be set.',
+              },
+              dryRun: {
+                type: 'boolean',
+                description:
+                  'Validate every row and report the results without creating anything; defaults to false.',
+              },
+            },
+          },
+        },
+        output: {
+          encoding: 'application/json',
+          schema: {
+            type: 'object',
+            required: ['committed', 'results'],
+            properties: {
+              committed: {
+                type: 'boolean',
+                description:
+                  'Whether the members were created. False for dry runs and when any row failed.',
+              },
+              results: {
+                type: 'array',
+                items: {
+                  type: 'ref',
+                  ref: 'lex:network.habitat.org.provisionMembers#result',
+                },
+              },
+            },
+          },
+        },
+        errors: [
+          {
+            name: 'InvalidInput',
+            description: 'The member list is missing, malformed, or too long.',
+          },
+        ],
+      },
+      member: {
+        type: 'object',
+        required: ['handle'],
+        properties: {
+          handle: {
+            type: 'string',
+            description:
+              "Handle prefix for the new member, placed under the org's handle subdomain.",
+          },
+          loginID: {
+            type: 'string',
+            description:
+              "Provider-specific identifier (AT Protocol handle for 'atproto', email for 'google') the member signs in with. Required for non-password login methods and not allowed for 'password'.",
+          },
+          groups: {
+            type: 'array',
+            items: {
+              type: 'string',
+              format: 'uri',
+            },
+            description:
+              "URIs of org groups to add the member to. Admins can add to any of the org's groups; anyone else must manage each group.",
+          },
+        },
+      },
+      result: {
+        type: 'object',
+        required: ['row', 'handle', 'status'],
+        properties: {
+          row: {
+            type: 'integer',
+            description: '1-based position of the row in the input.',
+          },
+          handle: {
+            type: 'string',
+          },
+          status: {
+            type: 'string',
+            knownValues: ['created', 'valid', 'failed', 'skipped'],
+            description:
+              'created when the member was minted, valid when a dry run found no problem, failed when the row has a problem, and skipped when another row failed.',
+          },
+          did: {
+            type: 'string',
+            format: 'did',
+            description: 'DID of the created member.',
+          },
+          initialPassword: {
+            type: 'string',
+            description:
+              "Generated password for members of 'password' orgs, shown only once. Hand it to the member to sign in.",
+          },
+          error: {
+            type: 'string',
+            description: 'Why the row failed.',
+          },
+        },
+      },
+    },
+  },
+  NetworkHabitatOrgPutRole: {
     lexicon: 1,
-    id: 'network.habitat.org.mintMemberIdentity',
+    id: 'network.habitat.org.putRole',
     defs: {
       main: {
         type: 'procedure',
         description:
-          'Mint a new organization member identity with the given handle and token.',
+          'Create a custom org role, or replace the capabilities of an existing one. Only callable by admins.',
         input: {
           encoding: 'application/json',
           schema: {
             type: 'object',
-            required: ['token', 'handle'],
+            required: ['name', 'capabilities'],
             properties: {
-              orgId: {
-                type: 'string',
-                description: 'The ID of the org this member is joining.',
-              },
-              handle: {
-                type: 'string',
-                description:
-                  'The internal handle (all letters + numbers, no special characters, does not include org domain) that will be used by the member.',
-              },
-              token: {
-                type: 'string',
-                description:
-                  'The token that was issued by an org admin to allow members to join the organization.',
-              },
-              password: {
+              name: {
                 type: 'string',
                 description:
-                  "The password for the new member's account (required for 'password' login method).",
+                  'Name of the role: 1-64 lowercase letters, digits or hyphens, starting with a letter. admin and member are built in and cannot be used.',
               },
-              loginID: {
-                type: 'string',
+              capabilities: {
+                type: 'array',
+                items: {
+                  type: 'string',
+                  knownValues: ['issueInvites', 'mintIdentities'],
+                },
                 description:
-                  "Provider-specific identifier (AT Protocol handle for 'atproto', email for 'google'). Required for non-password login methods.",
+                  'Capabilities granted by the role. issueInvites allows issuing single-use invite tokens; mintIdentities also allows reusable ones. manageSpaces:<space type NSID> grants the manager role on every org space of that type.',
               },
             },
           },
         },
-        output: {
-          encoding: 'application/json',
-          schema: {
-            type: 'object',
-            required: ['handle', 'did'],
-            properties: {
-              handle: {
-                type: 'string',
-                description:
-                  'The full handle of the newly minted member identity.',
-              },
-              did: {
-                type: 'string',
-                description: 'The DID of the newly minted member identity.',
-              },
-            },
+        errors: [
+          {
+            name: 'InvalidRoleName',
+            description: 'The role name is malformed or is a built-in role.',
           },
-        },
+          {
+            name: 'InvalidCapability',
+            description: 'One of the capabilities is not recognized.',
+          },
+        ],
       },
     },
   },
@@ -2526,6 +3228,35 @@ export const schemaDict = {
       },
     },
   },
+  NetworkHabitatOrgUnassignRole: {
+    lexicon: 1,
+    id: 'network.habitat.org.unassignRole',
+    defs: {
+      main: {
+        type: 'procedure',
+        description:
+          'Take a custom role away from an org member. Only callable by admins.',
+        input: {
+          encoding: 'application/json',
+          schema: {
+            type: 'object',
+            required: ['did', 'role'],
+            properties: {
+              did: {
+                type: 'string',
+                format: 'did',
+                description: 'DID of the member.',
+              },
+              role: {
+                type: 'string',
+                description: 'Name of the role.',
+              },
+            },
+          },
+        },
+      },
+    },
+  },
   NetworkHabitatPermissionsAddPermission: {
     lexicon: 1,
     id: 'network.habitat.permissions.addPermission',
@@ -2894,9 +3625,9 @@ export const schemaDict = {
             },
             subjectType: {
               type: 'string',
-              enum: ['user', 'space'],
+              enum: ['user', 'space', 'orgRole'],
               description:
-                'Optional. Restrict to relations whose subject is a user (userRelation) or a space userset (spaceRelation).',
+                'Optional. Restrict to relations whose subject is a user (userRelation), a space userset (spaceRelation), or a custom org role (orgRoleRelation).',
             },
             relation: {
               type: 'string',
@@ -2917,6 +3648,7 @@ export const schemaDict = {
                   refs: [
                     'lex:network.habitat.relationship.listRelations#userRelationView',
                     'lex:network.habitat.relationship.listRelations#spaceRelationView',
+                    'lex:network.habitat.relationship.listRelations#orgRoleRelationView',
                   ],
                 },
               },
@@ -2979,6 +3711,73 @@ export const schemaDict = {
           },
         },
       },
+      orgRoleRelationView: {
+        type: 'object',
+        description: 'An org role relation record together with its URI.',
+        required: ['uri', 'org', 'role', 'relation', 'object'],
+        properties: {
+          uri: {
+            type: 'string',
+            description: 'URI of the relation record.',
+          },
+          org: {
+            type: 'string',
+            format: 'did',
+            description: 'DID of the org defining the role.',
+          },
+          role: {
+            type: 'string',
+            description:
+              'Name of the custom org role the relation is granted to.',
+          },
+          relation: {
+            type: 'string',
+          },
+          object: {
+            type: 'string',
+            format: 'uri',
+            description: 'URI of the space the role is granted on.',
+          },
+        },
+      },
+    },
+  },
+  NetworkHabitatRelationshipOrgRoleRelation: {
+    lexicon: 1,
+    id: 'network.habitat.relationship.orgRoleRelation',
+    defs: {
+      main: {
+        type: 'record',
+        description:
+          'A relationship record granting a role to every holder of a custom org role on object, which is the space this record is written into. Owned by the org repo within the space it governs so authorized app users can manage it and other apps can read the permission structure.',
+        key: 'tid',
+        record: {
+          type: 'object',
+          required: ['org', 'role', 'relation'],
+          properties: {
+            org: {
+              type: 'string',
+              format: 'did',
+              description: 'DID of the org defining the role.',
+            },
+            role: {
+              type: 'string',
+              description:
+                'Name of the custom org role whose holders form the userset.',
+            },
+            relation: {
+              type: 'string',
+              knownValues: ['owner', 'manager', 'writer', 'reader'],
+              description:
+                'Role granted on the object space (owner|manager|writer|reader).',
+            },
+            createdAt: {
+              type: 'string',
+              format: 'datetime',
+            },
+          },
+        },
+      },
     },
   },
   NetworkHabitatRelationshipResolveRelations: {
@@ -3025,6 +3824,71 @@ export const schemaDict = {
       },
     },
   },
+  NetworkHabitatRelationshipSetOrgRoleRelation: {
+    lexicon: 1,
+    id: 'network.habitat.relationship.setOrgRoleRelation',
+    defs: {
+      main: {
+        type: 'procedure',
+        description:
+          'Grant a role on a space to every holder of a custom org role, replacing any role they held through it before. The relation record is owned by the org repo within its governing space. Caller must have the manager role on the space.',
+        input: {
+          encoding: 'application/json',
+          schema: {
+            type: 'object',
+            required: ['org', 'role', 'relation', 'space'],
+            properties: {
+              org: {
+                type: 'string',
+                format: 'did',
+                description: 'DID of the org defining the role.',
+              },
+              role: {
+                type: 'string',
+                description:
+                  'Name of the custom org role whose holders form the userset.',
+              },
+              relation: {
+                type: 'string',
+                knownValues: ['owner', 'manager', 'writer', 'reader'],
+                description:
+                  'Role granted on the space (owner|manager|writer|reader).',
+              },
+              space: {
+                type: 'string',
+                format: 'uri',
+                description: 'URI of the space to grant the role on.',
+              },
+            },
+          },
+        },
+        output: {
+          encoding: 'application/json',
+          schema: {
+            type: 'object',
+            required: ['uri'],
+            properties: {
+              uri: {
+                type: 'string',
+                description: 'URI of the written relation record.',
+              },
+            },
+          },
+        },
+        errors: [
+          {
+            name: 'SpaceNotFound',
+            description: 'The space does not exist.',
+          },
+          {
+            name: 'InvalidRelation',
+            description:
+              'The org, role, relation, and space combination is not valid.',
+          },
+        ],
+      },
+    },
+  },
   NetworkHabitatRelationshipSetSpaceRelation: {
     lexicon: 1,
     id: 'network.habitat.relationship.setSpaceRelation',
@@ -3877,6 +4741,45 @@ export const schemaDict = {
             cursor: {
               type: 'string',
             },
+            collections: {
+              type: 'array',
+              description: 'Only match records of these collections.',
+              items: {
+                type: 'string',
+                format: 'nsid',
+              },
+            },
+            spaces: {
+              type: 'array',
+              description: 'Only match records in these spaces.',
+              items: {
+                type: 'string',
+              },
+            },
+            authors: {
+              type: 'array',
+              description: 'Only match records in these repos (DIDs).',
+              items: {
+                type: 'string',
+                format: 'did',
+              },
+            },
+            since: {
+              type: 'string',
+              format: 'datetime',
+              description: 'Only match records updated at or after this time.',
+            },
+            until: {
+              type: 'string',
+              format: 'datetime',
+              description: 'Only match records updated before this time.',
+            },
+            lang: {
+              type: 'string',
+              format: 'language',
+              description:
+                'The language q is written in, to stem it by. Without it, q is matched in every language the index stems.',
+            },
           },
           required: ['q'],
         },
@@ -3896,10 +4799,49 @@ export const schemaDict = {
               cursor: {
                 type: 'string',
               },
+              facets: {
+                type: 'ref',
+                ref: 'lex:network.habitat.search.query#facets',
+              },
+            },
+          },
+        },
+      },
+      facets: {
+        type: 'object',
+        description:
+          'Counts of every record the query matches, across all pages, most common first.',
+        required: ['collections', 'spaces'],
+        properties: {
+          collections: {
+            type: 'array',
+            items: {
+              type: 'ref',
+              ref: 'lex:network.habitat.search.query#facetCount',
+            },
+          },
+          spaces: {
+            type: 'array',
+            items: {
+              type: 'ref',
+              ref: 'lex:network.habitat.search.query#facetCount',
             },
           },
         },
       },
+      facetCount: {
+        type: 'object',
+        required: ['value', 'count'],
+        properties: {
+          value: {
+            type: 'string',
+            description: 'The collection NSID or space URI counted.',
+          },
+          count: {
+            type: 'integer',
+          },
+        },
+      },
       resultView: {
         type: 'object',
         required: ['uri', 'spaceUri', 'recordType'],
@@ -5289,13 +6231,22 @@ export const ids = {
   NetworkHabitatDocsMarkdown: 'network.habitat.docs.markdown',
   NetworkHabitatDocsUpdateDoc: 'network.habitat.docs.updateDoc',
   NetworkHabitatGrantee: 'network.habitat.grantee',
+  NetworkHabitatGroupJoinRequest: 'network.habitat.group.joinRequest',
   NetworkHabitatGroupProfile: 'network.habitat.group.profile',
   NetworkHabitatGroupsAddMember: 'network.habitat.groups.addMember',
   NetworkHabitatGroupsCreateGroup: 'network.habitat.groups.createGroup',
   NetworkHabitatGroupsDefs: 'network.habitat.groups.defs',
   NetworkHabitatGroupsDeleteMember: 'network.habitat.groups.deleteMember',
   NetworkHabitatGroupsGetGroup: 'network.habitat.groups.getGroup',
+  NetworkHabitatGroupsGetMembershipPath:
+    'network.habitat.groups.getMembershipPath',
   NetworkHabitatGroupsListGroups: 'network.habitat.groups.listGroups',
+  NetworkHabitatGroupsListJoinRequests:
+    'network.habitat.groups.listJoinRequests',
+  NetworkHabitatGroupsListMyGroups: 'network.habitat.groups.listMyGroups',
+  NetworkHabitatGroupsRequestToJoin: 'network.habitat.groups.requestToJoin',
+  NetworkHabitatGroupsResolveJoinRequest:
+    'network.habitat.groups.resolveJoinRequest',
   NetworkHabitatGroupsUpdateGroup: 'network.habitat.groups.updateGroup',
   NetworkHabitatInstanceDescribeInstance:
     'network.habitat.instance.describeInstance',
@@ -5304,16 +6255,22 @@ export const ids = {
   NetworkHabitatListConnectedApps: 'network.habitat.listConnectedApps',
   NetworkHabitatOrgAddAdmin: 'network.habitat.org.addAdmin',
   NetworkHabitatOrgAddMembers: 'network.habitat.org.addMembers',
+  NetworkHabitatOrgAssignRole: 'network.habitat.org.assignRole',
   NetworkHabitatOrgCreate: 'network.habitat.org.create',
+  NetworkHabitatOrgDeleteRole: 'network.habitat.org.deleteRole',
   NetworkHabitatOrgDowngradeAdmin: 'network.habitat.org.downgradeAdmin',
   NetworkHabitatOrgGetAdmins: 'network.habitat.org.getAdmins',
   NetworkHabitatOrgGetMembers: 'network.habitat.org.getMembers',
   NetworkHabitatOrgGetMetadata: 'network.habitat.org.getMetadata',
   NetworkHabitatOrgIssueInviteToken: 'network.habitat.org.issueInviteToken',
+  NetworkHabitatOrgListRoles: 'network.habitat.org.listRoles',
   NetworkHabitatOrgLoginMember: 'network.habitat.org.loginMember',
   NetworkHabitatOrgMintMemberIdentity: 'network.habitat.org.mintMemberIdentity',
+  NetworkHabitatOrgProvisionMembers: 'network.habitat.org.provisionMembers',
+  NetworkHabitatOrgPutRole: 'network.habitat.org.putRole',
   NetworkHabitatOrgRemoveAdmin: 'network.habitat.org.removeAdmin',
   NetworkHabitatOrgRemoveMembers: 'network.habitat.org.removeMembers',
+  NetworkHabitatOrgUnassignRole: 'network.habitat.org.unassignRole',
   NetworkHabitatPermissionsAddPermission:
     'network.habitat.permissions.addPermission',
   NetworkHabitatPermissionsListPermissions:
@@ -5331,8 +6288,12 @@ export const ids = {
     'network.habitat.relationship.listRelatedSpaces',
   NetworkHabitatRelationshipListRelations:
     'network.habitat.relationship.listRelations',
+  NetworkHabitatRelationshipOrgRoleRelation:
+    'network.habitat.relationship.orgRoleRelation',
   NetworkHabitatRelationshipResolveRelations:
     'network.habitat.relationship.resolveRelations',
+  NetworkHabitatRelationshipSetOrgRoleRelation:
+    'network.habitat.relationship.setOrgRoleRelation',
   NetworkHabitatRelationshipSetSpaceRelation:
     'network.habitat.relationship.setSpaceRelation',
   NetworkHabitatRelationshipSetUserRelation:
@@ -33,7 +33,7 @@ export function validateCollectionView<V>(v: V) {
   return validate<CollectionView & V>(v, id, hashCollectionView)
 }
 
-/** A single record scoped to one space. The same repo/collection/rkey in a different space is a distinct record with its own version, so it appears as its own recordView. The record body is not included; fetch it on demand from pear using the space, repo, collection and rkey. */
+/** A single record scoped to one space. The same repo/collection/rkey in a different space is a distinct record with its own version, so it appears as its own recordView. The record body is the version this space holds. */
 export interface RecordView {
   $type?: 'network.habitat.collections.defs#recordView'
   /** The space-record URI (spaceUri/repo/collection/rkey), unique to this record in this space. */
@@ -46,6 +46,10 @@ export interface RecordView {
   collection: string
   /** The record key. */
   rkey: string
+  /** The record body, as last synced. Absent for a record synced before bodies were kept, until its repo is resynced. */
+  value?: { [_ in string]: unknown }
+  /** When the record was last written. */
+  updatedAt?: string
 }
 
 const hashRecordView = 'recordView'
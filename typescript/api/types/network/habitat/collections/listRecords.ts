@@ -19,10 +19,13 @@ const id = 'network.habitat.collections.listRecords'
 export type QueryParams = {
   /** The NSID of the record collection to list. */
   collection: string
+  limit?: number
+  cursor?: string
 }
 export type InputSchema = undefined
 
 export interface OutputSchema {
+  cursor?: string
   records: NetworkHabitatCollectionsDefs.RecordView[]
 }
 
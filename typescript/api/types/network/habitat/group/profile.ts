@@ -19,6 +19,8 @@ export interface Main {
   name: string
   description?: string
   createdAt?: string
+  /** How users join the group: 'open' adds anyone who asks straight away, 'request' (the default) leaves each request for a manager to approve. */
+  joinPolicy?: 'open' | 'request' | (string & {})
   [k: string]: unknown
 }
 
@@ -62,11 +62,18 @@ export class InvalidSubjectError extends XRPCError {
   }
 }
 
+export class MembershipCycleError extends XRPCError {
+  constructor(src: XRPCError) {
+    super(src.status, src.error, src.message, src.headers, { cause: src })
+  }
+}
+
 export function toKnownErr(e: any) {
   if (e instanceof XRPCError) {
     if (e.error === 'GroupNotFound') return new GroupNotFoundError(e)
     if (e.error === 'Forbidden') return new ForbiddenError(e)
     if (e.error === 'InvalidSubject') return new InvalidSubjectError(e)
+    if (e.error === 'MembershipCycle') return new MembershipCycleError(e)
   }
 
   return e
@@ -20,6 +20,8 @@ export type QueryParams = {}
 export interface InputSchema {
   name: string
   description?: string
+  /** How users join the group: 'open' adds anyone who asks straight away, 'request' (the default) leaves each request for a manager to approve. */
+  joinPolicy?: 'open' | 'request' | (string & {})
 }
 
 export interface OutputSchema {
@@ -40,6 +42,16 @@ export interface Response {
   data: OutputSchema
 }
 
+export class InvalidJoinPolicyError extends XRPCError {
+  constructor(src: XRPCError) {
+    super(src.status, src.error, src.message, src.headers, { cause: src })
+  }
+}
+
 export function toKnownErr(e: any) {
+  if (e instanceof XRPCError) {
+    if (e.error === 'InvalidJoinPolicy') return new InvalidJoinPolicyError(e)
+  }
+
   return e
 }
@@ -22,6 +22,8 @@ export interface GroupView {
   name: string
   description?: string
   createdAt?: string
+  /** How users join the group: 'open' or 'request'. */
+  joinPolicy?: 'open' | 'request' | (string & {})
   /** Number of distinct members after expanding inherited groups. */
   memberCount?: number
   /** Whether the calling user is a member of this group. */
@@ -64,6 +66,28 @@ export function validateMemberView<V>(v: V) {
   return validate<MemberView & V>(v, id, hashMemberView)
 }
 
+/** A user's membership of a group, resolved through inherited groups. */
+export interface MembershipView {
+  $type?: 'network.habitat.groups.defs#membershipView'
+  group: GroupRef
+  /** Highest role held on the group-space (owner|manager|writer|reader). */
+  role?: string
+  /** True if the role is granted directly on this group, false if it is inherited from another group. */
+  direct: boolean
+  /** If inherited, the URI of the group-space the membership came from. */
+  viaGroup?: string
+}
+
+const hashMembershipView = 'membershipView'
+
+export function isMembershipView<V>(v: V) {
+  return is$typed(v, id, hashMembershipView)
+}
+
+export function validateMembershipView<V>(v: V) {
+  return validate<MembershipView & V>(v, id, hashMembershipView)
+}
+
 export interface GroupRef {
   $type?: 'network.habitat.groups.defs#groupRef'
   uri: string
@@ -79,3 +103,24 @@ export function isGroupRef<V>(v: V) {
 export function validateGroupRef<V>(v: V) {
   return validate<GroupRef & V>(v, id, hashGroupRef)
 }
+
+/** A user's pending request to join a group. */
+export interface JoinRequestView {
+  $type?: 'network.habitat.groups.defs#joinRequestView'
+  /** URI of the network.habitat.group.joinRequest record. */
+  uri: string
+  /** DID of the user asking to join. */
+  subject: string
+  message?: string
+  createdAt: string
+}
+
+const hashJoinRequestView = 'joinRequestView'
+
+export function isJoinRequestView<V>(v: V) {
+  return is$typed(v, id, hashJoinRequestView)
+}
+
+export function validateJoinRequestView<V>(v: V) {
+  return validate<JoinRequestView & V>(v, id, hashJoinRequestView)
+}
@@ -0,0 +1,64 @@
+/**
+ * GENERATED CODE - DO NOT MODIFY
+ */
+import { type HeadersMap, XRPCError } from '@atproto/xrpc'
+import { type ValidationResult, BlobRef } from '@atproto/lexicon'
+import { CID } from 'multiformats/cid'
+import { validate as _validate } from '../../../../lexicons.js'
+import {
+  type $Typed,
+  is$typed as _is$typed,
+  type OmitKey,
+} from '../../../../util.js'
+import type * as NetworkHabitatGroupsDefs from './defs.js'
+
+const is$typed = _is$typed,
+  validate = _validate
+const id = 'network.habitat.groups.getMembershipPath'
+
+export type QueryParams = {
+  /** URI of the group-space. */
+  group: string
+  /** DID of the user. Defaults to the caller. */
+  did?: string
+}
+export type InputSchema = undefined
+
+export interface OutputSchema {
+  /** Role the user holds on the group (owner|manager|writer|reader). */
+  role?: string
+  /** The groups the membership passes through: first the group the user is granted a role on directly, last the requested group. A direct member's path is the group alone. */
+  path: NetworkHabitatGroupsDefs.GroupRef[]
+}
+
+export interface CallOptions {
+  signal?: AbortSignal
+  headers?: HeadersMap
+}
+
+export interface Response {
+  success: boolean
+  headers: HeadersMap
+  data: OutputSchema
+}
+
+export class GroupNotFoundError extends XRPCError {
+  constructor(src: XRPCError) {
+    super(src.status, src.error, src.message, src.headers, { cause: src })
+  }
+}
+
+export class NotMemberError extends XRPCError {
+  constructor(src: XRPCError) {
+    super(src.status, src.error, src.message, src.headers, { cause: src })
+  }
+}
+
+export function toKnownErr(e: any) {
+  if (e instanceof XRPCError) {
+    if (e.error === 'GroupNotFound') return new GroupNotFoundError(e)
+    if (e.error === 'NotMember') return new NotMemberError(e)
+  }
+
+  return e
+}
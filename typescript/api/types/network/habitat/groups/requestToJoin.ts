@@ -0,0 +1,66 @@
+/**
+ * GENERATED CODE - DO NOT MODIFY
+ */
+import { type HeadersMap, XRPCError } from '@atproto/xrpc'
+import { type ValidationResult, BlobRef } from '@atproto/lexicon'
+import { CID } from 'multiformats/cid'
+import { validate as _validate } from '../../../../lexicons.js'
+import {
+  type $Typed,
+  is$typed as _is$typed,
+  type OmitKey,
+} from '../../../../util.js'
+
+const is$typed = _is$typed,
+  validate = _validate
+const id = 'network.habitat.groups.requestToJoin'
+
+export type QueryParams = {}
+
+export interface InputSchema {
+  /** URI of the group-space to join. */
+  group: string
+  /** Optional note to the group's managers. */
+  message?: string
+}
+
+export interface OutputSchema {
+  /** 'joined' if the caller is now a member, 'pending' if the request awaits a manager. */
+  status: 'joined' | 'pending' | (string & {})
+  /** URI of the written relationship tuple when joined, or of the join request record when pending. */
+  uri: string
+}
+
+export interface CallOptions {
+  signal?: AbortSignal
+  headers?: HeadersMap
+  qp?: QueryParams
+  encoding?: 'application/json'
+}
+
+export interface Response {
+  success: boolean
+  headers: HeadersMap
+  data: OutputSchema
+}
+
+export class GroupNotFoundError extends XRPCError {
+  constructor(src: XRPCError) {
+    super(src.status, src.error, src.message, src.headers, { cause: src })
+  }
+}
+
+export class AlreadyMemberError extends XRPCError {
+  constructor(src: XRPCError) {
+    super(src.status, src.error, src.message, src.headers, { cause: src })
+  }
+}
+
+export function toKnownErr(e: any) {
+  if (e instanceof XRPCError) {
+    if (e.error === 'GroupNotFound') return new GroupNotFoundError(e)
+    if (e.error === 'AlreadyMember') return new AlreadyMemberError(e)
+  }
+
+  return e
+}
@@ -0,0 +1,73 @@
+/**
+ * GENERATED CODE - DO NOT MODIFY
+ */
+import { type HeadersMap, XRPCError } from '@atproto/xrpc'
+import { type ValidationResult, BlobRef } from '@atproto/lexicon'
+import { CID } from 'multiformats/cid'
+import { validate as _validate } from '../../../../lexicons.js'
+import {
+  type $Typed,
+  is$typed as _is$typed,
+  type OmitKey,
+} from '../../../../util.js'
+
+const is$typed = _is$typed,
+  validate = _validate
+const id = 'network.habitat.groups.resolveJoinRequest'
+
+export type QueryParams = {}
+
+export interface InputSchema {
+  /** URI of the group-space. */
+  group: string
+  /** DID of the user who asked to join. */
+  subject: string
+  /** True to add the user as a member, false to reject the request. */
+  approve: boolean
+}
+
+export interface OutputSchema {
+  /** URI of the written relationship tuple, when approved. */
+  uri?: string
+}
+
+export interface CallOptions {
+  signal?: AbortSignal
+  headers?: HeadersMap
+  qp?: QueryParams
+  encoding?: 'application/json'
+}
+
+export interface Response {
+  success: boolean
+  headers: HeadersMap
+  data: OutputSchema
+}
+
+export class GroupNotFoundError extends XRPCError {
+  constructor(src: XRPCError) {
+    super(src.status, src.error, src.message, src.headers, { cause: src })
+  }
+}
+
+export class ForbiddenError extends XRPCError {
+  constructor(src: XRPCError) {
+    super(src.status, src.error, src.message, src.headers, { cause: src })
+  }
+}
+
+export class JoinRequestNotFoundError extends XRPCError {
+  constructor(src: XRPCError) {
+    super(src.status, src.error, src.message, src.headers, { cause: src })
+  }
+}
+
+export function toKnownErr(e: any) {
+  if (e instanceof XRPCError) {
+    if (e.error === 'GroupNotFound') return new GroupNotFoundError(e)
+    if (e.error === 'Forbidden') return new ForbiddenError(e)
+    if (e.error === 'JoinRequestNotFound') return new JoinRequestNotFoundError(e)
+  }
+
+  return e
+}
@@ -22,6 +22,8 @@ export interface InputSchema {
   group: string
   name?: string
   description?: string
+  /** How users join the group: 'open' adds anyone who asks straight away, 'request' (the default) leaves each request for a manager to approve. */
+  joinPolicy?: 'open' | 'request' | (string & {})
 }
 
 export interface OutputSchema {
@@ -53,10 +55,17 @@ export class ForbiddenError extends XRPCError {
   }
 }
 
+export class InvalidJoinPolicyError extends XRPCError {
+  constructor(src: XRPCError) {
+    super(src.status, src.error, src.message, src.headers, { cause: src })
+  }
+}
+
 export function toKnownErr(e: any) {
   if (e instanceof XRPCError) {
     if (e.error === 'GroupNotFound') return new GroupNotFoundError(e)
     if (e.error === 'Forbidden') return new ForbiddenError(e)
+    if (e.error === 'InvalidJoinPolicy') return new InvalidJoinPolicyError(e)
   }
 
   return e
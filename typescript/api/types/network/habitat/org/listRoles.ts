@@ -0,0 +1,56 @@
+/**
+ * GENERATED CODE - DO NOT MODIFY
+ */
+import { type HeadersMap, XRPCError } from '@atproto/xrpc'
+import { type ValidationResult, BlobRef } from '@atproto/lexicon'
+import { CID } from 'multiformats/cid'
+import { validate as _validate } from '../../../../lexicons.js'
+import {
+  type $Typed,
+  is$typed as _is$typed,
+  type OmitKey,
+} from '../../../../util.js'
+
+const is$typed = _is$typed,
+  validate = _validate
+const id = 'network.habitat.org.listRoles'
+
+export type QueryParams = {}
+export type InputSchema = undefined
+
+export interface OutputSchema {
+  roles: Role[]
+}
+
+export interface CallOptions {
+  signal?: AbortSignal
+  headers?: HeadersMap
+}
+
+export interface Response {
+  success: boolean
+  headers: HeadersMap
+  data: OutputSchema
+}
+
+export function toKnownErr(e: any) {
+  return e
+}
+
+export interface Role {
+  $type?: 'network.habitat.org.listRoles#role'
+  name: string
+  capabilities: string[]
+  /** DIDs of the members holding the role. */
+  assignees: string[]
+}
+
+const hashRole = 'role'
+
+export function isRole<V>(v: V) {
+  return is$typed(v, id, hashRole)
+}
+
+export function validateRole<V>(v: V) {
+  return validate<Role & V>(v, id, hashRole)
+}
@@ -0,0 +1,105 @@
+/**
+ * GENERATED CODE - DO NOT MODIFY
+ */
+import { type HeadersMap, XRPCError } from '@atproto/xrpc'
+import { type ValidationResult, BlobRef } from '@atproto/lexicon'
+import { CID } from 'multiformats/cid'
+import { validate as _validate } from '../../../../lexicons.js'
+import {
+  type $Typed,
+  is$typed as _is$typed,
+  type OmitKey,
+} from '../../../../util.js'
+
+const is$typed = _is$typed,
+  validate = _validate
+const id = 'network.habitat.org.provisionMembers'
+
+export type QueryParams = {}
+
+export interface InputSchema {
+  /** Members to provision. Exactly one of members or csv must be set. */
+  members?: Member[]
+  /** Members to provision as CSV with a header row naming the handle, loginID and groups columns. Groups are separated by spaces. Exactly one of members or csv must be set. */
+  csv?: string
+  /** Validate every row and report the results without creating anything; defaults to false. */
+  dryRun?: boolean
+}
+
+export interface OutputSchema {
+  /** Whether the members were created. False for dry runs and when any row failed. */
+  committed: boolean
+  results: Result[]
+}
+
+export interface CallOptions {
+  signal?: AbortSignal
+  headers?: HeadersMap
+  qp?: QueryParams
+  encoding?: 'application/json'
+}
+
+export interface Response {
+  success: boolean
+  headers: HeadersMap
+  data: OutputSchema
+}
+
+export class InvalidInputError extends XRPCError {
+  constructor(src: XRPCError) {
+    super(src.status, src.error, src.message, src.headers, { cause: src })
+  }
+}
+
+export function toKnownErr(e: any) {
+  if (e instanceof XRPCError) {
+    if (e.error === 'InvalidInput') return new InvalidInputError(e)
+  }
+
+  return e
+}
+
+export interface Member {
+  $type?: 'network.habitat.org.provisionMembers#member'
+  /** Handle prefix for the new member, placed under the org's handle subdomain. */
+  handle: string
+  /** Provider-specific identifier (AT Protocol handle for 'atproto', email for 'google') the member signs in with. Required for non-password login methods and not allowed for 'password'. */
+  loginID?: string
+  /** URIs of org groups to add the member to. Admins can add to any of the org's groups; anyone else must manage each group. */
+  groups?: string[]
+}
+
+const hashMember = 'member'
+
+export function isMember<V>(v: V) {
+  return is$typed(v, id, hashMember)
+}
+
+export function validateMember<V>(v: V) {
+  return validate<Member & V>(v, id, hashMember)
+}
+
+export interface Result {
+  $type?: 'network.habitat.org.provisionMembers#result'
+  /** 1-based position of the row in the input. */
+  row: number
+  handle: string
+  /** created when the member was minted, valid when a dry run found no problem, failed when the row has a problem, and skipped when another row failed. */
+  status: 'created' | 'valid' | 'failed' | 'skipped' | (string & {})
+  /** DID of the created member. */
+  did?: string
+  /** Generated password for members of 'password' orgs, shown only once. Hand it to the member to sign in. */
+  initialPassword?: string
+  /** Why the row failed. */
+  error?: string
+}
+
+const hashResult = 'result'
+
+export function isResult<V>(v: V) {
+  return is$typed(v, id, hashResult)
+}
+
+export function validateResult<V>(v: V) {
+  return validate<Result & V>(v, id, hashResult)
+}
@@ -0,0 +1,58 @@
+/**
+ * GENERATED CODE - DO NOT MODIFY
+ */
+import { type HeadersMap, XRPCError } from '@atproto/xrpc'
+import { type ValidationResult, BlobRef } from '@atproto/lexicon'
+import { CID } from 'multiformats/cid'
+import { validate as _validate } from '../../../../lexicons.js'
+import {
+  type $Typed,
+  is$typed as _is$typed,
+  type OmitKey,
+} from '../../../../util.js'
+
+const is$typed = _is$typed,
+  validate = _validate
+const id = 'network.habitat.org.putRole'
+
+export type QueryParams = {}
+
+export interface InputSchema {
+  /** Name of the role: 1-64 lowercase letters, digits or hyphens, starting with a letter. admin and member are built in and cannot be used. */
+  name: string
+  /** Capabilities granted by the role. issueInvites allows issuing single-use invite tokens; mintIdentities also allows reusable ones. manageSpaces:<space type NSID> grants the manager role on every org space of that type. */
+  capabilities: ('issueInvites' | 'mintIdentities' | (string & {}))[]
+}
+
+export interface CallOptions {
+  signal?: AbortSignal
+  headers?: HeadersMap
+  qp?: QueryParams
+  encoding?: 'application/json'
+}
+
+export interface Response {
+  success: boolean
+  headers: HeadersMap
+}
+
+export class InvalidRoleNameError extends XRPCError {
+  constructor(src: XRPCError) {
+    super(src.status, src.error, src.message, src.headers, { cause: src })
+  }
+}
+
+export class InvalidCapabilityError extends XRPCError {
+  constructor(src: XRPCError) {
+    super(src.status, src.error, src.message, src.headers, { cause: src })
+  }
+}
+
+export function toKnownErr(e: any) {
+  if (e instanceof XRPCError) {
+    if (e.error === 'InvalidRoleName') return new InvalidRoleNameError(e)
+    if (e.error === 'InvalidCapability') return new InvalidCapabilityError(e)
+  }
+
+  return e
+}
@@ -20,8 +20,8 @@ export type QueryParams = {
   space: string
   /** Optional. Restrict to relations whose subject is this user DID. */
   subjectDid?: string
-  /** Optional. Restrict to relations whose subject is a user (userRelation) or a space userset (spaceRelation). */
-  subjectType?: 'user' | 'space'
+  /** Optional. Restrict to relations whose subject is a user (userRelation), a space userset (spaceRelation), or a custom org role (orgRoleRelation). */
+  subjectType?: 'user' | 'space' | 'orgRole'
   /** Optional. Restrict to relations with this role. */
   relation?: string
 }
@@ -29,7 +29,10 @@ export type InputSchema = undefined
 
 export interface OutputSchema {
   relations: (
-    $Typed<UserRelationView> | $Typed<SpaceRelationView> | { $type: string }
+    | $Typed<UserRelationView>
+    | $Typed<SpaceRelationView>
+    | $Typed<OrgRoleRelationView>
+    | { $type: string }
   )[]
 }
 
@@ -93,3 +96,27 @@ export function isSpaceRelationView<V>(v: V) {
 export function validateSpaceRelationView<V>(v: V) {
   return validate<SpaceRelationView & V>(v, id, hashSpaceRelationView)
 }
+
+/** An org role relation record together with its URI. */
+export interface OrgRoleRelationView {
+  $type?: 'network.habitat.relationship.listRelations#orgRoleRelationView'
+  /** URI of the relation record. */
+  uri: string
+  /** DID of the org defining the role. */
+  org: string
+  /** Name of the custom org role the relation is granted to. */
+  role: string
+  relation: string
+  /** URI of the space the role is granted on. */
+  object: string
+}
+
+const hashOrgRoleRelationView = 'orgRoleRelationView'
+
+export function isOrgRoleRelationView<V>(v: V) {
+  return is$typed(v, id, hashOrgRoleRelationView)
+}
+
+export function validateOrgRoleRelationView<V>(v: V) {
+  return validate<OrgRoleRelationView & V>(v, id, hashOrgRoleRelationView)
+}
@@ -0,0 +1,43 @@
+/**
+ * GENERATED CODE - DO NOT MODIFY
+ */
+import { type ValidationResult, BlobRef } from '@atproto/lexicon'
+import { CID } from 'multiformats/cid'
+import { validate as _validate } from '../../../../lexicons.js'
+import {
+  type $Typed,
+  is$typed as _is$typed,
+  type OmitKey,
+} from '../../../../util.js'
+
+const is$typed = _is$typed,
+  validate = _validate
+const id = 'network.habitat.relationship.orgRoleRelation'
+
+export interface Main {
+  $type: 'network.habitat.relationship.orgRoleRelation'
+  /** DID of the org defining the role. */
+  org: string
+  /** Name of the custom org role whose holders form the userset. */
+  role: string
+  /** Role granted on the object space (owner|manager|writer|reader). */
+  relation: 'owner' | 'manager' | 'writer' | 'reader' | (string & {})
+  createdAt?: string
+  [k: string]: unknown
+}
+
+const hashMain = 'main'
+
+export function isMain<V>(v: V) {
+  return is$typed(v, id, hashMain)
+}
+
+export function validateMain<V>(v: V) {
+  return validate<Main & V>(v, id, hashMain, true)
+}
+
+export {
+  type Main as Record,
+  isMain as isRecord,
+  validateMain as validateRecord,
+}
@@ -20,12 +20,25 @@ export type QueryParams = {
   q: string
   limit?: number
   cursor?: string
+  /** Only match records of these collections. */
+  collections?: string[]
+  /** Only match records in these spaces. */
+  spaces?: string[]
+  /** Only match records in these repos (DIDs). */
+  authors?: string[]
+  /** Only match records updated at or after this time. */
+  since?: string
+  /** Only match records updated before this time. */
+  until?: string
+  /** The language q is written in, to stem it by. Without it, q is matched in every language the index stems. */
+  lang?: string
 }
 export type InputSchema = undefined
 
 export interface OutputSchema {
   results: ResultView[]
   cursor?: string
+  facets?: Facets
 }
 
 export interface CallOptions {
@@ -43,6 +56,40 @@ export function toKnownErr(e: any) {
   return e
 }
 
+/** Counts of every record the query matches, across all pages, most common first. */
+export interface Facets {
+  $type?: 'network.habitat.search.query#facets'
+  collections: FacetCount[]
+  spaces: FacetCount[]
+}
+
+const hashFacets = 'facets'
+
+export function isFacets<V>(v: V) {
+  return is$typed(v, id, hashFacets)
+}
+
+export function validateFacets<V>(v: V) {
+  return validate<Facets & V>(v, id, hashFacets)
+}
+
+export interface FacetCount {
+  $type?: 'network.habitat.search.query#facetCount'
+  /** The collection NSID or space URI counted. */
+  value: string
+  count: number
+}
+
+const hashFacetCount = 'facetCount'
+
+export function isFacetCount<V>(v: V) {
+  return is$typed(v, id, hashFacetCount)
+}
+
+export function validateFacetCount<V>(v: V) {
+  return validate<FacetCount & V>(v, id, hashFacetCount)
+}
+
 export interface ResultView {
   $type?: 'network.habitat.search.query#resultView'
   /** URI of the matched record. */
@@ -36,6 +36,12 @@ import type {
   NetworkHabitatOrgLoginMember,
   NetworkHabitatOrgCreate,
   NetworkHabitatOrgMintMemberIdentity,
+  NetworkHabitatOrgProvisionMembers,
+  NetworkHabitatOrgPutRole,
+  NetworkHabitatOrgDeleteRole,
+  NetworkHabitatOrgListRoles,
+  NetworkHabitatOrgAssignRole,
+  NetworkHabitatOrgUnassignRole,
   NetworkHabitatSimplespaceAddMember,
   NetworkHabitatSimplespaceCreateSpace,
   NetworkHabitatSimplespaceListMembers,
@@ -53,13 +59,19 @@ import type {
   NetworkHabitatDocsListDocs,
   NetworkHabitatGroupsListGroups,
   NetworkHabitatGroupsGetGroup,
+  NetworkHabitatGroupsListMyGroups,
+  NetworkHabitatGroupsGetMembershipPath,
   NetworkHabitatGroupsCreateGroup,
   NetworkHabitatGroupsUpdateGroup,
   NetworkHabitatGroupsAddMember,
+  NetworkHabitatGroupsListJoinRequests,
+  NetworkHabitatGroupsRequestToJoin,
+  NetworkHabitatGroupsResolveJoinRequest,
   NetworkHabitatCollectionsListCollections,
   NetworkHabitatCollectionsListRecords,
   NetworkHabitatRelationshipSetUserRelation,
   NetworkHabitatRelationshipSetSpaceRelation,
+  NetworkHabitatRelationshipSetOrgRoleRelation,
 } from "api";
 import { AuthManager } from "./authManager";
 import { DPoPOptions } from "openid-client";
@@ -99,6 +111,18 @@ type QueryEndpoints = {
     NetworkHabitatGroupsGetGroup.QueryParams,
     NetworkHabitatGroupsGetGroup.OutputSchema
   >;
+  "network.habitat.groups.listMyGroups": Query<
+    NetworkHabitatGroupsListMyGroups.QueryParams,
+    NetworkHabitatGroupsListMyGroups.OutputSchema
+  >;
+  "network.habitat.groups.getMembershipPath": Query<
+    NetworkHabitatGroupsGetMembershipPath.QueryParams,
+    NetworkHabitatGroupsGetMembershipPath.OutputSchema
+  >;
+  "network.habitat.groups.listJoinRequests": Query<
+    NetworkHabitatGroupsListJoinRequests.QueryParams,
+    NetworkHabitatGroupsListJoinRequests.OutputSchema
+  >;
   // Implemented by the home server; reached via pear service proxying when
   // called with an Atproto-Proxy header.
   "network.habitat.collections.listCollections": Query<
@@ -173,6 +197,10 @@ type QueryEndpoints = {
     NetworkHabitatOrgGetMetadata.QueryParams,
     NetworkHabitatOrgGetMetadata.OutputSchema
   >;
+  "network.habitat.org.listRoles": Query<
+    NetworkHabitatOrgListRoles.QueryParams,
+    NetworkHabitatOrgListRoles.OutputSchema
+  >;
   "network.habitat.permissions.listPermissions": Query<
     NetworkHabitatPermissionsListPermissions.QueryParams,
     NetworkHabitatPermissionsListPermissions.OutputSchema
@@ -241,6 +269,14 @@ type ProcedureEndpoints = {
     NetworkHabitatGroupsAddMember.InputSchema,
     NetworkHabitatGroupsAddMember.OutputSchema
   >;
+  "network.habitat.groups.requestToJoin": Procedure<
+    NetworkHabitatGroupsRequestToJoin.InputSchema,
+    NetworkHabitatGroupsRequestToJoin.OutputSchema
+  >;
+  "network.habitat.groups.resolveJoinRequest": Procedure<
+    NetworkHabitatGroupsResolveJoinRequest.InputSchema,
+    NetworkHabitatGroupsResolveJoinRequest.OutputSchema
+  >;
   // Write a relationship tuple granting a role on a space. Implemented by pear.
   "network.habitat.relationship.setUserRelation": Procedure<
     NetworkHabitatRelationshipSetUserRelation.InputSchema,
@@ -250,6 +286,10 @@ type ProcedureEndpoints = {
     NetworkHabitatRelationshipSetSpaceRelation.InputSchema,
     NetworkHabitatRelationshipSetSpaceRelation.OutputSchema
   >;
+  "network.habitat.relationship.setOrgRoleRelation": Procedure<
+    NetworkHabitatRelationshipSetOrgRoleRelation.InputSchema,
+    NetworkHabitatRelationshipSetOrgRoleRelation.OutputSchema
+  >;
   "network.habitat.repo.putRecord": Procedure<
     NetworkHabitatRepoPutRecord.InputSchema,
     NetworkHabitatRepoPutRecord.OutputSchema
@@ -294,6 +334,26 @@ type ProcedureEndpoints = {
     NetworkHabitatOrgDowngradeAdmin.InputSchema,
     void
   >;
+  "network.habitat.org.provisionMembers": Procedure<
+    NetworkHabitatOrgProvisionMembers.InputSchema,
+    NetworkHabitatOrgProvisionMembers.OutputSchema
+  >;
+  "network.habitat.org.putRole": Procedure<
+    NetworkHabitatOrgPutRole.InputSchema,
+    void
+  >;
+  "network.habitat.org.deleteRole": Procedure<
+    NetworkHabitatOrgDeleteRole.InputSchema,
+    void
+  >;
+  "network.habitat.org.assignRole": Procedure<
+    NetworkHabitatOrgAssignRole.InputSchema,
+    void
+  >;
+  "network.habitat.org.unassignRole": Procedure<
+    NetworkHabitatOrgUnassignRole.InputSchema,
+    void
+  >;
   "network.habitat.permissions.removePermission": Procedure<
     NetworkHabitatPermissionsRemovePermission.InputSchema,
     void